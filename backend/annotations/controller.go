@@ -0,0 +1,15 @@
+package annotations
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	annotationGroup := e.Group("/annotations")
+
+	annotationGroup.GET("", HandleListAnnotations)
+	annotationGroup.POST("", HandleCreateAnnotation)
+	annotationGroup.GET("/:id", HandleGetAnnotation)
+	annotationGroup.PUT("/:id", HandleUpdateAnnotation)
+	annotationGroup.DELETE("/:id", HandleDeleteAnnotation)
+}
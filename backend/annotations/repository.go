@@ -0,0 +1,167 @@
+package annotations
+
+import (
+	"context"
+	"database/sql"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"time"
+)
+
+// timestampLayout is the format SQLite stores CURRENT_TIMESTAMP in, so time
+// range bounds compare with created_at as text.
+const timestampLayout = "2006-01-02 15:04:05"
+
+// ListFilter selects annotations. Empty fields match any value.
+type ListFilter struct {
+	ServiceName string
+	TraceID     string
+	SpanID      string
+	Start       time.Time
+	End         time.Time
+	Limit       int64
+}
+
+// CreateAnnotation stores feedback by userEmail.
+func CreateAnnotation(ctx context.Context, userEmail string, req CreateAnnotationRequest) (Annotation, error) {
+	queries := db_gen.New(db.DB)
+	annotation, err := queries.CreateAnnotation(ctx, db_gen.CreateAnnotationParams{
+		ServiceName: req.ServiceName,
+		TraceID:     req.TraceID,
+		SpanID:      req.SpanID,
+		UserEmail:   userEmail,
+		Thumb:       req.Thumb,
+		Score:       nullFloat(req.Score),
+		Comment:     req.Comment,
+	})
+	if err != nil {
+		return Annotation{}, err
+	}
+	return decode(annotation), nil
+}
+
+// UpdateAnnotation replaces the feedback of an annotation.
+func UpdateAnnotation(ctx context.Context, id int64, req UpdateAnnotationRequest) (Annotation, error) {
+	queries := db_gen.New(db.DB)
+	annotation, err := queries.UpdateAnnotation(ctx, db_gen.UpdateAnnotationParams{
+		Thumb:   req.Thumb,
+		Score:   nullFloat(req.Score),
+		Comment: req.Comment,
+		ID:      id,
+	})
+	if err != nil {
+		return Annotation{}, err
+	}
+	return decode(annotation), nil
+}
+
+// ListAnnotations retrieves the annotations matching filter, newest first.
+func ListAnnotations(ctx context.Context, filter ListFilter) ([]Annotation, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListAnnotations(ctx, db_gen.ListAnnotationsParams{
+		ServiceName: filter.ServiceName,
+		TraceID:     filter.TraceID,
+		SpanID:      filter.SpanID,
+		StartTime:   filter.Start.UTC().Format(timestampLayout),
+		EndTime:     filter.End.UTC().Format(timestampLayout),
+		RowLimit:    filter.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	annotations := []Annotation{}
+	for _, annotation := range stored {
+		annotations = append(annotations, decode(annotation))
+	}
+	return annotations, nil
+}
+
+// GetAnnotation retrieves a single annotation by id.
+func GetAnnotation(ctx context.Context, id int64) (Annotation, error) {
+	queries := db_gen.New(db.DB)
+	annotation, err := queries.GetAnnotation(ctx, id)
+	if err != nil {
+		return Annotation{}, err
+	}
+	return decode(annotation), nil
+}
+
+// DeleteAnnotation removes an annotation by id.
+func DeleteAnnotation(ctx context.Context, id int64) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteAnnotation(ctx, id)
+}
+
+func nullFloat(value *float64) sql.NullFloat64 {
+	if value == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *value, Valid: true}
+}
+
+func decode(annotation db_gen.Annotation) Annotation {
+	decoded := Annotation{
+		ID:          annotation.ID,
+		ServiceName: annotation.ServiceName,
+		TraceID:     annotation.TraceID,
+		SpanID:      annotation.SpanID,
+		UserEmail:   annotation.UserEmail,
+		Thumb:       annotation.Thumb,
+		Comment:     annotation.Comment,
+		CreatedAt:   annotation.CreatedAt,
+		UpdatedAt:   annotation.UpdatedAt,
+	}
+	if annotation.Score.Valid {
+		score := annotation.Score.Float64
+		decoded.Score = &score
+	}
+	return decoded
+}
+
+// AnnotatedTraceIDs returns the ids of the traces of a service with an
+// annotation matching thumb, when set, and scoring at least minScore, when
+// set.
+func AnnotatedTraceIDs(ctx context.Context, serviceName string, thumb string, minScore *float64) (map[string]bool, error) {
+	queries := db_gen.New(db.DB)
+	traceIDs, err := queries.ListAnnotatedTraceIDs(ctx, db_gen.ListAnnotatedTraceIDsParams{
+		ServiceName: serviceName,
+		Thumb:       thumb,
+		MinScore:    nullFloat(minScore),
+	})
+	if err != nil {
+		return nil, err
+	}
+	annotated := make(map[string]bool, len(traceIDs))
+	for _, traceID := range traceIDs {
+		annotated[traceID] = true
+	}
+	return annotated, nil
+}
+
+// TraceSummary is the feedback a trace and its spans received.
+type TraceSummary struct {
+	ScoreCount int64
+	ScoreSum   float64
+	ThumbsUp   int64
+	ThumbsDown int64
+}
+
+// TraceSummaries returns the feedback of the annotated traces of a service,
+// by trace id.
+func TraceSummaries(ctx context.Context, serviceName string) (map[string]TraceSummary, error) {
+	queries := db_gen.New(db.DB)
+	rows, err := queries.ListAnnotationTraceSummaries(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make(map[string]TraceSummary, len(rows))
+	for _, row := range rows {
+		summaries[row.TraceID] = TraceSummary{
+			ScoreCount: row.ScoreCount,
+			ScoreSum:   row.ScoreSum,
+			ThumbsUp:   row.ThumbsUp,
+			ThumbsDown: row.ThumbsDown,
+		}
+	}
+	return summaries, nil
+}
@@ -0,0 +1,36 @@
+package annotations
+
+import "time"
+
+// CreateAnnotationRequest attaches feedback to a trace, or to one of its spans
+// when span_id is set. At least one of thumb, score and comment is required.
+type CreateAnnotationRequest struct {
+	ServiceName string   `json:"service_name" validate:"required"`
+	TraceID     string   `json:"trace_id" validate:"required,len=32,hexadecimal"`
+	SpanID      string   `json:"span_id" validate:"omitempty,len=16,hexadecimal"`
+	Thumb       string   `json:"thumb" validate:"omitempty,oneof=up down"`
+	Score       *float64 `json:"score"`
+	Comment     string   `json:"comment"`
+}
+
+// UpdateAnnotationRequest replaces the feedback of an annotation.
+type UpdateAnnotationRequest struct {
+	Thumb   string   `json:"thumb" validate:"omitempty,oneof=up down"`
+	Score   *float64 `json:"score"`
+	Comment string   `json:"comment"`
+}
+
+// Annotation is feedback left by a user on a trace or span. SpanID is empty
+// for trace annotations.
+type Annotation struct {
+	ID          int64     `json:"id"`
+	ServiceName string    `json:"service_name"`
+	TraceID     string    `json:"trace_id"`
+	SpanID      string    `json:"span_id"`
+	UserEmail   string    `json:"user_email"`
+	Thumb       string    `json:"thumb"`
+	Score       *float64  `json:"score"`
+	Comment     string    `json:"comment"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
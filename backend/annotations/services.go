@@ -0,0 +1,182 @@
+package annotations
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Annotation listing limits.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// HandleListAnnotations lists annotations, newest first. They can be narrowed
+// with the service_name, trace_id and span_id query parameters, and to a time
+// range with start_time and end_time (RFC 3339). The number of annotations is
+// set with limit (default 100, max 1000).
+func HandleListAnnotations(c echo.Context) error {
+	filter := ListFilter{
+		ServiceName: c.QueryParam("service_name"),
+		TraceID:     strings.ToLower(c.QueryParam("trace_id")),
+		SpanID:      strings.ToLower(c.QueryParam("span_id")),
+		End:         time.Now().Add(time.Second),
+		Limit:       defaultListLimit,
+	}
+
+	if raw := c.QueryParam("start_time"); raw != "" {
+		start, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid start_time: must be RFC 3339")
+		}
+		filter.Start = start
+	}
+	if raw := c.QueryParam("end_time"); raw != "" {
+		end, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid end_time: must be RFC 3339")
+		}
+		filter.End = end
+	}
+	if raw := c.QueryParam("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit: must be a positive integer")
+		}
+		filter.Limit = min(limit, maxListLimit)
+	}
+
+	annotations, err := ListAnnotations(c.Request().Context(), filter)
+	if err != nil {
+		c.Logger().Error("Failed to list annotations:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve annotations")
+	}
+
+	return c.JSON(http.StatusOK, annotations)
+}
+
+// HandleCreateAnnotation attaches feedback by the signed-in user to a trace or
+// span.
+func HandleCreateAnnotation(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	var req CreateAnnotationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if req.Thumb == "" && req.Score == nil && req.Comment == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: one of thumb, score or comment is required")
+	}
+	req.TraceID = strings.ToLower(req.TraceID)
+	req.SpanID = strings.ToLower(req.SpanID)
+
+	annotation, err := CreateAnnotation(c.Request().Context(), userEmail, req)
+	if err != nil {
+		c.Logger().Error("Failed to create annotation:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create annotation")
+	}
+
+	return c.JSON(http.StatusCreated, annotation)
+}
+
+// HandleGetAnnotation retrieves an annotation by id.
+func HandleGetAnnotation(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid annotation id")
+	}
+
+	annotation, err := GetAnnotation(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Annotation not found")
+		}
+		c.Logger().Error("Failed to get annotation:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve annotation")
+	}
+
+	return c.JSON(http.StatusOK, annotation)
+}
+
+// HandleUpdateAnnotation replaces the feedback of an annotation. Only its
+// author can update it.
+func HandleUpdateAnnotation(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid annotation id")
+	}
+
+	var req UpdateAnnotationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if req.Thumb == "" && req.Score == nil && req.Comment == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: one of thumb, score or comment is required")
+	}
+
+	if err := authorizeAuthor(c, id); err != nil {
+		return err
+	}
+
+	annotation, err := UpdateAnnotation(c.Request().Context(), id, req)
+	if err != nil {
+		c.Logger().Error("Failed to update annotation:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update annotation")
+	}
+
+	return c.JSON(http.StatusOK, annotation)
+}
+
+// HandleDeleteAnnotation deletes an annotation by id. Only its author can
+// delete it.
+func HandleDeleteAnnotation(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid annotation id")
+	}
+
+	if err := authorizeAuthor(c, id); err != nil {
+		return err
+	}
+
+	if err := DeleteAnnotation(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete annotation:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete annotation")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// authorizeAuthor returns an HTTP error unless the annotation exists and was
+// created by the signed-in user.
+func authorizeAuthor(c echo.Context, id int64) error {
+	annotation, err := GetAnnotation(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Annotation not found")
+		}
+		c.Logger().Error("Failed to look up annotation:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to look up annotation")
+	}
+
+	userEmail, _ := c.Get("userEmail").(string)
+	if userEmail == "" || userEmail != annotation.UserEmail {
+		return echo.NewHTTPError(http.StatusForbidden, "Only the author can modify an annotation")
+	}
+	return nil
+}
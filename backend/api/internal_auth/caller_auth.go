@@ -0,0 +1,129 @@
+package internal_auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TokenMetadataKey is the metadata key callers send the shared token in.
+const TokenMetadataKey = "x-junjo-internal-token"
+
+// CallerPolicy restricts who may call the internal auth service. Each check
+// applies only when it is configured.
+type CallerPolicy struct {
+	token       string
+	networks    []*net.IPNet
+	clientNames []string
+}
+
+// NewCallerPolicy creates a CallerPolicy from a shared token, the CIDR ranges
+// or IP addresses callers may connect from, and the names of the client
+// certificates accepted over mutual TLS.
+func NewCallerPolicy(token string, allowedNetworks []string, allowedClientNames []string) (*CallerPolicy, error) {
+	p := &CallerPolicy{token: token, clientNames: allowedClientNames}
+	for _, raw := range allowedNetworks {
+		if !strings.Contains(raw, "/") {
+			ip := net.ParseIP(raw)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid allowed network %q", raw)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			raw = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		_, network, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %q: %w", raw, err)
+		}
+		p.networks = append(p.networks, network)
+	}
+	return p, nil
+}
+
+// Enabled reports whether any check is configured.
+func (p *CallerPolicy) Enabled() bool {
+	return p.token != "" || len(p.networks) > 0 || len(p.clientNames) > 0
+}
+
+// UnaryInterceptor rejects calls from outside the allowed networks with
+// PermissionDenied, and calls without a matching client certificate or token
+// with Unauthenticated, before they reach the service. Health checks, which
+// reveal nothing, are not restricted.
+func (p *CallerPolicy) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+			return handler(ctx, req)
+		}
+		if err := p.authorize(ctx); err != nil {
+			caller := "unknown"
+			if pr, ok := peer.FromContext(ctx); ok {
+				caller = pr.Addr.String()
+			}
+			slog.WarnContext(ctx, "Rejected internal gRPC call", "method", info.FullMethod, "caller", caller, "error", status.Convert(err).Message())
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authorize checks the caller of a call against the policy.
+func (p *CallerPolicy) authorize(ctx context.Context) error {
+	pr, ok := peer.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unknown caller")
+	}
+
+	if len(p.networks) > 0 && !p.allowsAddr(pr.Addr) {
+		return status.Error(codes.PermissionDenied, "caller network is not allowed")
+	}
+
+	if len(p.clientNames) > 0 {
+		tlsInfo, ok := pr.AuthInfo.(credentials.TLSInfo)
+		if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+			return status.Error(codes.Unauthenticated, "client certificate required")
+		}
+		leaf := tlsInfo.State.VerifiedChains[0][0]
+		names := append([]string{leaf.Subject.CommonName}, leaf.DNSNames...)
+		if !slices.ContainsFunc(names, func(name string) bool { return slices.Contains(p.clientNames, name) }) {
+			return status.Error(codes.PermissionDenied, "client certificate is not allowed")
+		}
+	}
+
+	if p.token != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(TokenMetadataKey)
+		if len(values) == 0 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(p.token)) != 1 {
+			return status.Error(codes.Unauthenticated, "invalid internal token")
+		}
+	}
+	return nil
+}
+
+// allowsAddr reports whether a caller address is in an allowed network.
+func (p *CallerPolicy) allowsAddr(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range p.networks {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -23,7 +23,7 @@ func NewInternalAuthService() *InternalAuthService {
 
 // ValidateApiKey checks if an API key is valid.
 func (s *InternalAuthService) ValidateApiKey(ctx context.Context, req *pb.ValidateApiKeyRequest) (*pb.ValidateApiKeyResponse, error) {
-	slog.Info("Validating API key", "received_key", req.ApiKey)
+	slog.InfoContext(ctx, "Validating API key")
 	_, err := api_keys.GetAPIKey(ctx, req.ApiKey)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// promptHashLength is the number of hex characters of the prompt hash kept in
+// audit entries: enough to match identical prompts without storing them.
+const promptHashLength = 16
+
+// AuditEntry describes an LLM request. Token counts are zero when the
+// provider does not report them.
+type AuditEntry struct {
+	UserEmail    string
+	Provider     string
+	Model        string
+	Action       string
+	StatusCode   int
+	Error        string
+	InputTokens  int64
+	OutputTokens int64
+	LatencyMs    int64
+	PromptHash   string
+}
+
+// AuditRecorder persists audit entries.
+type AuditRecorder func(ctx context.Context, entry AuditEntry)
+
+var (
+	auditMu       sync.RWMutex
+	auditRecorder AuditRecorder
+)
+
+// SetAuditRecorder sets the recorder called after every LLM request.
+func SetAuditRecorder(recorder AuditRecorder) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditRecorder = recorder
+}
+
+// recordAudit hands an entry to the audit recorder, if any.
+func recordAudit(entry AuditEntry) {
+	auditMu.RLock()
+	recorder := auditRecorder
+	auditMu.RUnlock()
+	if recorder != nil {
+		recorder(context.Background(), entry)
+	}
+}
+
+// hashPrompt returns the truncated SHA-256 hash of a prompt.
+func hashPrompt(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])[:promptHashLength]
+}
+
+// promptText returns the text of a request's system instruction and contents.
+func (r GeminiRequest) promptText() string {
+	var texts []string
+	if r.SystemInstruction != nil {
+		for _, part := range r.SystemInstruction.Parts {
+			texts = append(texts, part.Text)
+		}
+	}
+	for _, content := range r.Contents {
+		for _, part := range content.Parts {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
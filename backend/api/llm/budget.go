@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BudgetError reports a token budget that has been spent. Requests are
+// rejected until the budget's period resets.
+type BudgetError struct {
+	Scope    string    `json:"scope"` // "user" or "provider"
+	Key      string    `json:"key"`   // The user email or provider name
+	Period   string    `json:"period"`
+	Limit    int64     `json:"limit"`
+	Used     int64     `json:"used"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s LLM token budget of %d for %s %s exceeded (%d used), resets at %s",
+		e.Period, e.Limit, e.Scope, e.Key, e.Used, e.ResetsAt.Format(time.RFC3339))
+}
+
+// BudgetChecker returns a *BudgetError when a request by the user to the
+// provider would exceed a budget.
+type BudgetChecker func(ctx context.Context, userEmail, provider string) error
+
+var (
+	budgetMu      sync.RWMutex
+	budgetChecker BudgetChecker
+)
+
+// SetBudgetChecker sets the checker called before every LLM request.
+func SetBudgetChecker(checker BudgetChecker) {
+	budgetMu.Lock()
+	defer budgetMu.Unlock()
+	budgetChecker = checker
+}
+
+// checkBudget runs the budget checker, if any.
+func checkBudget(userEmail, provider string) error {
+	budgetMu.RLock()
+	checker := budgetChecker
+	budgetMu.RUnlock()
+	if checker == nil {
+		return nil
+	}
+	return checker(context.Background(), userEmail, provider)
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"junjo-server/apierror"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultCacheTTLSeconds is how long a context cache lives unless the request
+// sets its own TTL.
+const defaultCacheTTLSeconds = 3600
+
+// CreateCache caches a conversation prefix with the Gemini API, unless the
+// model is not allowed or a moderation rule blocks its text. Gemini rejects
+// caches below the model's minimum token count.
+func (s *GeminiService) CreateCache(req CreateCacheRequest) (Cache, error) {
+	r := GeminiRequest{Messages: req.Messages}
+	contents, err := r.translateMessages()
+	if err != nil {
+		return Cache{}, err
+	}
+
+	if !ModelAllowed(ProviderGemini, req.Model) {
+		return Cache{}, &ModelNotAllowedError{Provider: ProviderGemini, Model: req.Model}
+	}
+	r.Contents = contents
+	if _, err := moderate(ModerationPrompt, r.promptText(), s.userEmail, req.Model); err != nil {
+		return Cache{}, err
+	}
+
+	ttl := req.TTLSeconds
+	if ttl == 0 {
+		ttl = defaultCacheTTLSeconds
+	}
+	body, statusCode, err := s.send(http.MethodPost, "cachedContents", GeminiCachedContent{
+		Model:             "models/" + req.Model,
+		DisplayName:       req.DisplayName,
+		Contents:          contents,
+		SystemInstruction: r.SystemInstruction,
+		TTL:               fmt.Sprintf("%ds", ttl),
+	})
+	if err != nil {
+		return Cache{}, err
+	}
+
+	var resp GeminiCachedContent
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Cache{}, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+	if resp.Error != nil {
+		return Cache{}, fmt.Errorf("gemini API error %d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	if statusCode != http.StatusOK {
+		return Cache{}, fmt.Errorf("gemini API error %d", statusCode)
+	}
+
+	cache := Cache{
+		Name:        resp.Name,
+		Model:       strings.TrimPrefix(resp.Model, "models/"),
+		DisplayName: resp.DisplayName,
+		ExpireTime:  resp.ExpireTime,
+	}
+	if resp.UsageMetadata != nil {
+		cache.TokenCount = resp.UsageMetadata.TotalTokenCount
+	}
+	return cache, nil
+}
+
+// DeleteCache deletes a context cache, such as cachedContents/abc, before it
+// expires.
+func (s *GeminiService) DeleteCache(name string) error {
+	body, statusCode, err := s.send(http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		if message := geminiErrorMessage(body); message != "" {
+			return fmt.Errorf("gemini API error %d: %s", statusCode, message)
+		}
+		return fmt.Errorf("gemini API error %d", statusCode)
+	}
+	return nil
+}
+
+// HandleCreateCache is the handler for the /llm/caches endpoint.
+func HandleCreateCache(c echo.Context) error {
+	var req CreateCacheRequest
+	if err := c.Bind(&req); err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	if len(req.Messages) == 0 {
+		return apierror.New(http.StatusBadRequest, "messages are required")
+	}
+	if _, err := (&GeminiRequest{Messages: req.Messages}).translateMessages(); err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	if req.TTLSeconds < 0 {
+		return apierror.New(http.StatusBadRequest, "ttl_seconds must be positive")
+	}
+
+	// Set a default model if not provided
+	if req.Model == "" {
+		req.Model = "gemini-2.5-flash"
+	}
+
+	cache, err := serviceFor(c).CreateCache(req)
+	if err != nil {
+		return providerError(c, err)
+	}
+	return c.JSON(http.StatusCreated, cache)
+}
+
+// HandleDeleteCache is the handler for the /llm/caches/:id endpoint.
+func HandleDeleteCache(c echo.Context) error {
+	if err := serviceFor(c).DeleteCache("cachedContents/" + c.Param("id")); err != nil {
+		return providerError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
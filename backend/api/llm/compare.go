@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"junjo-server/apierror"
+	"junjo-server/quotas"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxCompareTargets is the number of models a comparison may run against.
+const maxCompareTargets = 8
+
+// HandleCompareModels is the handler for the /llm/compare endpoint. It runs
+// the conversation against every target in parallel and returns each output
+// with its latency and token usage. A failed target reports its error without
+// failing the others. Every target counts against the rate limit and quota.
+func HandleCompareModels(c echo.Context) error {
+	var req CompareRequest
+	if err := c.Bind(&req); err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	if len(req.Targets) == 0 || len(req.Targets) > maxCompareTargets {
+		return apierror.New(http.StatusBadRequest, fmt.Sprintf("targets must hold between 1 and %d models", maxCompareTargets))
+	}
+	for i := range req.Targets {
+		target := &req.Targets[i]
+		if target.Provider == "" {
+			target.Provider = ProviderGemini
+		}
+		target.Provider = strings.ToLower(target.Provider)
+		if !slices.Contains(Providers, target.Provider) {
+			return apierror.New(http.StatusBadRequest, fmt.Sprintf("target %d: unknown provider %q", i, target.Provider))
+		}
+		if target.Model == "" {
+			return apierror.New(http.StatusBadRequest, fmt.Sprintf("target %d: model is required", i))
+		}
+		if target.ReasoningEffort != "" {
+			if _, err := thinkingBudget(target.Model, target.ReasoningEffort); err != nil {
+				return apierror.New(http.StatusBadRequest, fmt.Sprintf("target %d: %v", i, err))
+			}
+		}
+	}
+
+	base := GeminiRequest{Messages: req.Messages}
+	if err := base.applyMessages(); err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	// Each target is a provider request, charged before any is sent
+	release, err := quotas.AdmitLLMRequests(c, len(req.Targets))
+	if err != nil {
+		return err
+	}
+	defer release()
+	if !UsesOwnKey(c) {
+		if err := quotas.ChargeLLMRequests(c, len(req.Targets)); err != nil {
+			return err
+		}
+	}
+
+	service := serviceFor(c)
+	results := make([]CompareResult, len(req.Targets))
+	var wg sync.WaitGroup
+	for i, target := range req.Targets {
+		geminiReq := base
+		geminiReq.Model = target.Model
+		geminiReq.ReasoningEffort = target.ReasoningEffort
+		if req.GenerationConfig != nil {
+			config := *req.GenerationConfig
+			geminiReq.GenerationConfig = &config
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = compareTarget(service, target, geminiReq)
+		}()
+	}
+	wg.Wait()
+
+	return c.JSON(http.StatusOK, CompareResponse{Results: results})
+}
+
+// compareTarget runs a comparison request against one target.
+func compareTarget(service *GeminiService, target CompareTarget, req GeminiRequest) CompareResult {
+	result := CompareResult{Provider: target.Provider, Model: target.Model, ReasoningEffort: target.ReasoningEffort}
+
+	start := time.Now()
+	body, err := service.GenerateContent(req)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var resp GeminiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		result.Error = fmt.Sprintf("failed to parse Gemini response: %v", err)
+		return result
+	}
+	if resp.UsageMetadata != nil {
+		result.InputTokens = resp.UsageMetadata.PromptTokenCount
+		result.OutputTokens = resp.UsageMetadata.CandidatesTokenCount
+	}
+	if resp.Error != nil {
+		result.Error = fmt.Sprintf("gemini API error %d: %s", resp.Error.Code, resp.Error.Message)
+		return result
+	}
+	if len(resp.Candidates) == 0 {
+		result.Error = "gemini returned no candidates"
+		return result
+	}
+
+	result.Output = candidateText(resp.Candidates[0])
+	result.FinishReason = resp.Candidates[0].FinishReason
+	return result
+}
@@ -1,7 +1,14 @@
 package llm
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
+
+	"junjo-server/apierror"
 
 	"github.com/labstack/echo/v4"
 )
@@ -10,19 +17,145 @@ import (
 func HandleGeminiTextRequest(c echo.Context) error {
 	var req GeminiRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	if config := req.GenerationConfig; config != nil && len(config.ResponseJSONSchema) > 0 {
+		if !json.Valid(config.ResponseJSONSchema) || config.ResponseJSONSchema[0] != '{' {
+			return apierror.New(http.StatusBadRequest, "responseJsonSchema must be a JSON object")
+		}
+		if config.ResponseMimeType != "" && config.ResponseMimeType != "application/json" {
+			return apierror.New(http.StatusBadRequest, "responseJsonSchema requires the application/json responseMimeType")
+		}
+	}
+
+	if len(req.Messages) > 0 {
+		if err := req.applyMessages(); err != nil {
+			return apierror.New(http.StatusBadRequest, err.Error())
+		}
 	}
 
 	// Set a default model if not provided
 	if req.Model == "" {
 		req.Model = "gemini-2.5-flash"
 	}
+	if req.ReasoningEffort != "" {
+		if _, err := thinkingBudget(req.Model, req.ReasoningEffort); err != nil {
+			return apierror.New(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	service := serviceFor(c)
+	if config := req.GenerationConfig; config != nil && len(config.ResponseJSONSchema) > 0 {
+		resp, validation, err := service.GenerateValidatedContent(req)
+		if err != nil {
+			return providerError(c, err)
+		}
+		if validation != nil {
+			if resp, err = withJSONValidation(resp, validation); err != nil {
+				return apierror.New(http.StatusInternalServerError, err.Error())
+			}
+		}
+		return c.JSONBlob(http.StatusOK, resp)
+	}
 
-	service := NewGeminiService()
 	resp, err := service.GenerateContent(req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return providerError(c, err)
 	}
 
 	return c.JSONBlob(http.StatusOK, resp)
 }
+
+// withJSONValidation adds the validation of a response's output to the
+// response body as jsonValidation.
+func withJSONValidation(body []byte, validation *JSONValidation) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+	encoded, err := json.Marshal(validation)
+	if err != nil {
+		return nil, err
+	}
+	fields["jsonValidation"] = encoded
+	return json.Marshal(fields)
+}
+
+// maxEmbeddingInputs is the number of texts embedded per request, the Gemini
+// batch limit.
+const maxEmbeddingInputs = 100
+
+// HandleEmbeddings is the handler for the /llm/embeddings endpoint.
+func HandleEmbeddings(c echo.Context) error {
+	var req EmbeddingsRequest
+	if err := c.Bind(&req); err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	if len(req.Input) == 0 || len(req.Input) > maxEmbeddingInputs {
+		return apierror.New(http.StatusBadRequest, fmt.Sprintf("input must hold between 1 and %d texts", maxEmbeddingInputs))
+	}
+	if req.Dimensions < 0 {
+		return apierror.New(http.StatusBadRequest, "dimensions must be positive")
+	}
+
+	// Set a default model if not provided
+	if req.Model == "" {
+		req.Model = "gemini-embedding-001"
+	}
+
+	service := serviceFor(c)
+	values, err := service.Embed(req.Model, req.Input, req.Dimensions, req.TaskType)
+	if err != nil {
+		return providerError(c, err)
+	}
+
+	resp := EmbeddingsResponse{Model: req.Model, Embeddings: make([]Embedding, 0, len(values))}
+	for i, embedding := range values {
+		resp.Embeddings = append(resp.Embeddings, Embedding{Index: i, Values: embedding})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// providerError responds to a failed provider request: 403 when the model is
+// not allowed, 422 when moderation blocked it, 429 when a budget has been
+// spent or the provider is still rate limiting, 503 while the circuit is open,
+// and 502 for other provider failures.
+func providerError(c echo.Context, err error) error {
+	var modelErr *ModelNotAllowedError
+	if errors.As(err, &modelErr) {
+		return apierror.New(http.StatusForbidden, err.Error())
+	}
+
+	var moderationErr *ModerationError
+	if errors.As(err, &moderationErr) {
+		return apierror.New(http.StatusUnprocessableEntity, err.Error()).WithDetails(map[string]any{
+			"violations": moderationErr.Violations,
+		})
+	}
+
+	var budgetErr *BudgetError
+	if errors.As(err, &budgetErr) {
+		return apierror.New(http.StatusTooManyRequests, err.Error()).WithDetails(map[string]any{
+			"budget": budgetErr,
+		})
+	}
+
+	var circuitErr *CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		retryAfter := max(int(time.Until(circuitErr.RetryAt).Seconds()), 1)
+		c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		return apierror.New(http.StatusServiceUnavailable, err.Error())
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		status := http.StatusBadGateway
+		if providerErr.StatusCode == http.StatusTooManyRequests {
+			status = http.StatusTooManyRequests
+		}
+		return apierror.New(status, err.Error())
+	}
+
+	return apierror.New(http.StatusInternalServerError, err.Error())
+}
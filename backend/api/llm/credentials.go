@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProviderGemini is the Gemini API provider.
+const ProviderGemini = "gemini"
+
+// Providers are the supported LLM providers.
+var Providers = []string{ProviderGemini}
+
+// contextKeyUserAPIKey is the echo context key holding the signed-in user's
+// own Gemini API key.
+const contextKeyUserAPIKey = "llmUserAPIKey"
+
+// UserKeyResolver returns the API key a user registered for a provider, if
+// any.
+type UserKeyResolver func(ctx context.Context, userEmail string, provider string) (string, bool)
+
+var (
+	apiKeysMu       sync.RWMutex
+	apiKeys         = map[string]string{}
+	defaultAPIKeys  = map[string]string{}
+	userKeyResolver UserKeyResolver
+)
+
+// SetAPIKeys replaces the stored API keys, by provider, used by requests made
+// afterwards. Providers without a stored key fall back to their default key.
+func SetAPIKeys(keys map[string]string) {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	apiKeys = keys
+}
+
+// apiKey returns the API key of a provider and whether it is set.
+func apiKey(provider string) (string, bool) {
+	apiKeysMu.RLock()
+	defer apiKeysMu.RUnlock()
+	key, ok := apiKeys[provider]
+	if ok && key != "" {
+		return key, true
+	}
+	key = defaultAPIKeys[provider]
+	return key, key != ""
+}
+
+// SetDefaultAPIKeys sets the configured API keys, by provider, such as
+// GEMINI_API_KEY, used when no key is stored.
+func SetDefaultAPIKeys(keys map[string]string) {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	defaultAPIKeys = keys
+}
+
+// SetUserKeyResolver sets how the API keys of individual users are looked up.
+func SetUserKeyResolver(resolver UserKeyResolver) {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	userKeyResolver = resolver
+}
+
+// UserKeys is a middleware that looks up the signed-in user's own Gemini API
+// key, which then overrides the server key for the request.
+func UserKeys() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			apiKeysMu.RLock()
+			resolver := userKeyResolver
+			apiKeysMu.RUnlock()
+
+			userEmail, _ := c.Get("userEmail").(string)
+			if resolver != nil && userEmail != "" {
+				if key, ok := resolver(c.Request().Context(), userEmail, ProviderGemini); ok {
+					c.Set(contextKeyUserAPIKey, key)
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// UsesOwnKey reports whether a request is made with the user's own API key,
+// so it is not counted against the shared LLM quota.
+func UsesOwnKey(c echo.Context) bool {
+	_, ok := c.Get(contextKeyUserAPIKey).(string)
+	return ok
+}
+
+// serviceFor returns the Gemini service of a request, attributed to the
+// signed-in user and using their own API key when they registered one.
+func serviceFor(c echo.Context) *GeminiService {
+	userEmail, _ := c.Get("userEmail").(string)
+	service := NewGeminiService().ForUser(userEmail).WithTraceParent(c.Request().Context())
+	if key, ok := c.Get(contextKeyUserAPIKey).(string); ok {
+		service.apiKey = key
+	}
+	return service
+}
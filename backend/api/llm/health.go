@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"junjo-server/apierror"
+
+	"github.com/labstack/echo/v4"
+)
+
+// healthCheckTimeout bounds the request of a provider health check.
+const healthCheckTimeout = 10 * time.Second
+
+// Health statuses of a provider.
+const (
+	HealthOK           = "ok"
+	HealthUnconfigured = "unconfigured"
+	HealthInvalidKey   = "invalid_key"
+	HealthRateLimited  = "rate_limited"
+	HealthUnreachable  = "unreachable"
+	HealthError        = "error"
+)
+
+// CheckHealth lists a single model of the provider with the service's API
+// key. The check is sent once, without retries, and does not count towards
+// the provider's circuit breaker.
+func (s *GeminiService) CheckHealth() ProviderHealth {
+	health := ProviderHealth{
+		Provider:  ProviderGemini,
+		Circuit:   breakerFor(ProviderGemini).state(time.Now()),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	key, ok := s.key()
+	if !ok {
+		health.Status = HealthUnconfigured
+		health.Error = "no Gemini API key is stored and the GEMINI_API_KEY environment variable is not set"
+		return health
+	}
+	health.KeySource = "server"
+	if s.apiKey != "" {
+		health.KeySource = "user"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	body, statusCode, err := s.do(ctx, http.MethodGet, "models?pageSize=1", key, nil)
+	health.LatencyMs = time.Since(start).Milliseconds()
+	health.StatusCode = statusCode
+	if err != nil {
+		health.Status = HealthUnreachable
+		health.Error = err.Error()
+		return health
+	}
+	health.Reachable = true
+
+	valid := statusCode == http.StatusOK
+	switch {
+	case valid:
+		health.Status = HealthOK
+		health.KeyValid = &valid
+		return health
+	case isInvalidKey(statusCode, geminiErrorMessage(body)):
+		health.Status = HealthInvalidKey
+		health.KeyValid = &valid
+	case statusCode == http.StatusTooManyRequests:
+		// Rate limiting applies to a key the provider accepted
+		valid = true
+		health.Status = HealthRateLimited
+		health.KeyValid = &valid
+	default:
+		health.Status = HealthError
+	}
+	if health.Error = geminiErrorMessage(body); health.Error == "" {
+		health.Error = http.StatusText(statusCode)
+	}
+	return health
+}
+
+// isInvalidKey reports whether a Gemini response rejected the API key. Gemini
+// answers an unknown key with 400 and a key without access with 401 or 403.
+func isInvalidKey(statusCode int, message string) bool {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	case http.StatusBadRequest:
+		return strings.Contains(message, "API key")
+	}
+	return false
+}
+
+// HandleProviderHealth is the handler for the /llm/providers/:provider/health
+// endpoint. It reports whether the provider is reachable with the user's or
+// the server's API key, how long it took to answer, and whether it accepted
+// the key.
+func HandleProviderHealth(c echo.Context) error {
+	provider := strings.ToLower(c.Param("provider"))
+	if !slices.Contains(Providers, provider) {
+		return apierror.New(http.StatusNotFound, "Unknown provider: must be one of "+strings.Join(Providers, ", "))
+	}
+	return c.JSON(http.StatusOK, serviceFor(c).CheckHealth())
+}
@@ -0,0 +1,308 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaErrors is the number of validation errors reported per document.
+const maxSchemaErrors = 20
+
+// schemaValidator validates JSON documents against the subset of JSON Schema
+// that structured output schemas use: type, enum, const, properties,
+// required, additionalProperties, items, prefixItems, min/maxItems,
+// min/maxLength, pattern, minimum/maximum, anyOf/oneOf/allOf, and local $ref
+// to $defs or definitions. Other keywords are ignored.
+type schemaValidator struct {
+	root   map[string]any
+	errors []string
+}
+
+// validateJSON validates a JSON document against a schema and returns the
+// validation errors, each prefixed with the path of the offending value.
+func validateJSON(schema json.RawMessage, document string) ([]string, error) {
+	var root map[string]any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(strings.TrimSpace(document))))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []string{"$: output is not valid JSON: " + err.Error()}, nil
+	}
+	if decoder.More() {
+		return []string{"$: output has content after the JSON value"}, nil
+	}
+
+	v := &schemaValidator{root: root}
+	v.validate(root, value, "$")
+	return v.errors, nil
+}
+
+func (v *schemaValidator) fail(path string, format string, args ...any) {
+	if len(v.errors) < maxSchemaErrors {
+		v.errors = append(v.errors, path+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+// validate validates a value against a schema, recording errors.
+func (v *schemaValidator) validate(schema map[string]any, value any, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, ok := v.resolve(ref)
+		if !ok {
+			v.fail(path, "unresolvable $ref %q", ref)
+			return
+		}
+		v.validate(resolved, value, path)
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return hasType(value, t) }) {
+		v.fail(path, "expected %s, got %s", strings.Join(types, " or "), typeName(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return jsonEqual(e, value) }) {
+		v.fail(path, "value is not one of the allowed values")
+	}
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		v.fail(path, "value does not equal the required constant")
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, value, path)
+	case []any:
+		v.validateArray(schema, value, path)
+	case string:
+		length := utf8.RuneCountInString(value)
+		if min, ok := schemaNumber(schema, "minLength"); ok && float64(length) < min {
+			v.fail(path, "string is shorter than %v characters", min)
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > max {
+			v.fail(path, "string is longer than %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+				v.fail(path, "string does not match pattern %q", pattern)
+			}
+		}
+	case json.Number:
+		n, _ := value.Float64()
+		if min, ok := schemaNumber(schema, "minimum"); ok && n < min {
+			v.fail(path, "%v is less than the minimum %v", value, min)
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && n > max {
+			v.fail(path, "%v is greater than the maximum %v", value, max)
+		}
+	}
+
+	for _, sub := range schemaList(schema, "allOf") {
+		v.validate(sub, value, path)
+	}
+	if anyOf := schemaList(schema, "anyOf"); len(anyOf) > 0 && v.matching(anyOf, value) == 0 {
+		v.fail(path, "value does not match any of the anyOf schemas")
+	}
+	if oneOf := schemaList(schema, "oneOf"); len(oneOf) > 0 && v.matching(oneOf, value) != 1 {
+		v.fail(path, "value does not match exactly one of the oneOf schemas")
+	}
+}
+
+func (v *schemaValidator) validateObject(schema map[string]any, value map[string]any, path string) {
+	properties, _ := schema["properties"].(map[string]any)
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := value[name]; !present {
+					v.fail(path, "missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		propertyPath := path + "." + name
+		if propertySchema, ok := properties[name].(map[string]any); ok {
+			v.validate(propertySchema, value[name], propertyPath)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(propertyPath, "property is not allowed")
+			}
+		case map[string]any:
+			v.validate(additional, value[name], propertyPath)
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]any, value []any, path string) {
+	if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(value)) < min {
+		v.fail(path, "array has fewer than %v items", min)
+	}
+	if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(value)) > max {
+		v.fail(path, "array has more than %v items", max)
+	}
+
+	prefixItems := schemaList(schema, "prefixItems")
+	items, _ := schema["items"].(map[string]any)
+	for i, item := range value {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if i < len(prefixItems) {
+			v.validate(prefixItems[i], item, itemPath)
+		} else if items != nil {
+			v.validate(items, item, itemPath)
+		}
+	}
+}
+
+// matching returns the number of schemas a value is valid against.
+func (v *schemaValidator) matching(schemas []map[string]any, value any) int {
+	matches := 0
+	for _, schema := range schemas {
+		sub := &schemaValidator{root: v.root}
+		sub.validate(schema, value, "$")
+		if len(sub.errors) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// resolve looks up a local reference, such as #/$defs/address.
+func (v *schemaValidator) resolve(ref string) (map[string]any, bool) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, false
+	}
+	var current any = v.root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current = object[token]
+	}
+	resolved, ok := current.(map[string]any)
+	return resolved, ok
+}
+
+// schemaTypes returns the types a schema allows, from a type string or array.
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{strings.ToLower(t)}
+	case []any:
+		types := []string{}
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, strings.ToLower(s))
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func schemaNumber(schema map[string]any, keyword string) (float64, bool) {
+	n, ok := schema[keyword].(float64)
+	return n, ok
+}
+
+func schemaList(schema map[string]any, keyword string) []map[string]any {
+	items, _ := schema[keyword].([]any)
+	schemas := []map[string]any{}
+	for _, item := range items {
+		if sub, ok := item.(map[string]any); ok {
+			schemas = append(schemas, sub)
+		}
+	}
+	return schemas
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return typeName(value) == t
+	}
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+// jsonEqual compares a schema value, decoded with float64 numbers, with a
+// document value, decoded with json.Number.
+func jsonEqual(schemaValue, value any) bool {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		s, isNumber := schemaValue.(float64)
+		return err == nil && isNumber && f == s
+	}
+	switch value := value.(type) {
+	case []any:
+		items, ok := schemaValue.([]any)
+		if !ok || len(items) != len(value) {
+			return false
+		}
+		for i := range value {
+			if !jsonEqual(items[i], value[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		object, ok := schemaValue.(map[string]any)
+		if !ok || len(object) != len(value) {
+			return false
+		}
+		for key := range value {
+			if !jsonEqual(object[key], value[key]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(schemaValue, value)
+}
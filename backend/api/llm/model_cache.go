@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// modelCacheTTL is how long a provider's model list is served before it is
+// refreshed. Stale lists are still served while the refresh runs.
+const modelCacheTTL = time.Hour
+
+// CachedModels is a provider's model list and when it was fetched.
+type CachedModels struct {
+	Models    []ModelInfo
+	FetchedAt time.Time
+}
+
+// ModelCachePersister stores a provider's fetched model list.
+type ModelCachePersister func(ctx context.Context, provider string, cached CachedModels)
+
+// ModelCache holds the model list of each provider, as seen with the server
+// API key.
+type ModelCache struct {
+	mu         sync.Mutex
+	entries    map[string]CachedModels
+	refreshing map[string]bool
+	persister  ModelCachePersister
+}
+
+// modelCache is the model cache of the server API keys.
+var modelCache = &ModelCache{
+	entries:    map[string]CachedModels{},
+	refreshing: map[string]bool{},
+}
+
+// HydrateModelCache loads model lists fetched before a restart and stores
+// lists fetched afterwards with persister.
+func HydrateModelCache(entries map[string]CachedModels, persister ModelCachePersister) {
+	modelCache.mu.Lock()
+	defer modelCache.mu.Unlock()
+	for provider, cached := range entries {
+		modelCache.entries[provider] = cached
+	}
+	modelCache.persister = persister
+}
+
+// Get returns the provider's model list, fetching it when none is cached. A
+// stale list is returned as is and refreshed in the background.
+func (m *ModelCache) Get(provider string, fetch func() ([]ModelInfo, error)) ([]ModelInfo, error) {
+	m.mu.Lock()
+	cached, ok := m.entries[provider]
+	stale := ok && time.Since(cached.FetchedAt) > modelCacheTTL
+	if stale && !m.refreshing[provider] {
+		m.refreshing[provider] = true
+		go func() {
+			if _, err := m.refresh(provider, fetch); err != nil {
+				log.Printf("Failed to refresh %s models: %v", provider, err)
+			}
+		}()
+	}
+	m.mu.Unlock()
+
+	if ok {
+		return cached.Models, nil
+	}
+	return m.refresh(provider, fetch)
+}
+
+// refresh fetches and caches the provider's model list.
+func (m *ModelCache) refresh(provider string, fetch func() ([]ModelInfo, error)) ([]ModelInfo, error) {
+	models, err := fetch()
+
+	m.mu.Lock()
+	delete(m.refreshing, provider)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	cached := CachedModels{Models: models, FetchedAt: time.Now().UTC()}
+	m.entries[provider] = cached
+	persister := m.persister
+	m.mu.Unlock()
+
+	if persister != nil {
+		persister(context.Background(), provider, cached)
+	}
+	return models, nil
+}
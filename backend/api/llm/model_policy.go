@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ModelPolicy restricts the models requests to a provider may use. Names
+// ending in * match any model with that prefix. Denied models are rejected
+// even when allowed, and an empty allow list allows every model that is not
+// denied.
+type ModelPolicy struct {
+	Allowed []string
+	Denied  []string
+}
+
+// Allows reports whether the policy lets requests use a model.
+func (p ModelPolicy) Allows(model string) bool {
+	model = strings.TrimPrefix(model, "models/")
+	if matchesModel(p.Denied, model) {
+		return false
+	}
+	return len(p.Allowed) == 0 || matchesModel(p.Allowed, model)
+}
+
+// matchesModel reports whether a model matches any of the names.
+func matchesModel(names []string, model string) bool {
+	for _, name := range names {
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			if strings.HasPrefix(model, prefix) {
+				return true
+			}
+		} else if name == model {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelNotAllowedError reports a request for a model blocked by its
+// provider's model policy.
+type ModelNotAllowedError struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+func (e *ModelNotAllowedError) Error() string {
+	return fmt.Sprintf("model %s is not allowed for %s requests", e.Model, e.Provider)
+}
+
+var (
+	modelPoliciesMu sync.RWMutex
+	modelPolicies   = map[string]ModelPolicy{}
+)
+
+// SetModelPolicies replaces the model policies, by provider, enforced on
+// requests made afterwards. Providers without a policy allow every model.
+func SetModelPolicies(policies map[string]ModelPolicy) {
+	modelPoliciesMu.Lock()
+	defer modelPoliciesMu.Unlock()
+	modelPolicies = policies
+}
+
+// ModelAllowed reports whether requests to a provider may use a model.
+func ModelAllowed(provider, model string) bool {
+	modelPoliciesMu.RLock()
+	policy, ok := modelPolicies[provider]
+	modelPoliciesMu.RUnlock()
+	return !ok || policy.Allows(model)
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// modelLimits are the token limits of a model family.
+type modelLimits struct {
+	contextWindow   int
+	maxOutputTokens int
+}
+
+// knownModelLimits are the published token limits of model families, by model
+// ID prefix. They fill in the limits a provider's model listing leaves out.
+var knownModelLimits = map[string]map[string]modelLimits{
+	ProviderGemini: {
+		"gemini-2.5-pro":        {1048576, 65536},
+		"gemini-2.5-flash":      {1048576, 65536},
+		"gemini-2.5-flash-lite": {1048576, 65536},
+		"gemini-2.0-flash":      {1048576, 8192},
+		"gemini-2.0-flash-lite": {1048576, 8192},
+		"gemini-1.5-pro":        {2097152, 8192},
+		"gemini-1.5-flash":      {1048576, 8192},
+		"gemini-embedding-001":  {2048, 0},
+		"text-embedding-004":    {2048, 0},
+	},
+}
+
+// withKnownLimits fills in the token limits a provider did not report from
+// the known limits of the model's family, matched by the longest prefix.
+func withKnownLimits(model ModelInfo) ModelInfo {
+	var (
+		limits  modelLimits
+		matched string
+	)
+	for prefix, known := range knownModelLimits[model.Provider] {
+		if strings.HasPrefix(model.ID, prefix) && len(prefix) > len(matched) {
+			limits, matched = known, prefix
+		}
+	}
+	if model.ContextWindow == 0 {
+		model.ContextWindow = limits.contextWindow
+	}
+	if model.MaxOutputTokens == 0 {
+		model.MaxOutputTokens = limits.maxOutputTokens
+	}
+	return model
+}
+
+// FetchGeminiModels lists the models available to the service's API key.
+func (s *GeminiService) FetchGeminiModels() ([]ModelInfo, error) {
+	models := []ModelInfo{}
+	pageToken := ""
+	for {
+		path := "models?pageSize=1000"
+		if pageToken != "" {
+			path += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		body, _, err := s.send(http.MethodGet, path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp GeminiListModelsResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse Gemini models: %w", err)
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("gemini API error %d: %s", resp.Error.Code, resp.Error.Message)
+		}
+
+		for _, model := range resp.Models {
+			models = append(models, withKnownLimits(ModelInfo{
+				Provider:         ProviderGemini,
+				ID:               strings.TrimPrefix(model.Name, "models/"),
+				DisplayName:      model.DisplayName,
+				Description:      model.Description,
+				ContextWindow:    model.InputTokenLimit,
+				MaxOutputTokens:  model.OutputTokenLimit,
+				SupportedActions: append([]string{}, model.SupportedGenerationMethods...),
+			}))
+		}
+
+		if resp.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// HandleListModels is the handler for the /llm/models endpoint. It lists the
+// models available to the user's or the server's API key, without the models
+// blocked by the provider's model policy. The server key's models are cached.
+func HandleListModels(c echo.Context) error {
+	service := serviceFor(c)
+	var (
+		models []ModelInfo
+		err    error
+	)
+	if UsesOwnKey(c) {
+		models, err = service.FetchGeminiModels()
+	} else {
+		models, err = modelCache.Get(ProviderGemini, service.FetchGeminiModels)
+	}
+	if err != nil {
+		return providerError(c, err)
+	}
+
+	allowed := []ModelInfo{}
+	for _, model := range models {
+		if ModelAllowed(model.Provider, model.ID) {
+			allowed = append(allowed, model)
+		}
+	}
+	return c.JSON(http.StatusOK, allowed)
+}
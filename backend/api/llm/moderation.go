@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Moderation stages, the text a rule applies to.
+const (
+	ModerationPrompt   = "prompt"
+	ModerationResponse = "response"
+	ModerationBoth     = "both"
+)
+
+// Moderation actions, what happens when a rule matches.
+const (
+	ModerationBlock = "block" // The request fails, and the prompt is not sent or the output not returned.
+	ModerationFlag  = "flag"  // The response carries the violation in its moderation field.
+	ModerationLog   = "log"   // The violation is only logged.
+)
+
+// ModerationRule matches prompts or responses that violate a content policy.
+type ModerationRule struct {
+	Name    string
+	Stage   string
+	Action  string
+	Pattern *regexp.Regexp
+}
+
+// ModerationViolation is a rule matched by a prompt or a response. The
+// matched text is not reported.
+type ModerationViolation struct {
+	Rule   string `json:"rule"`
+	Stage  string `json:"stage"`
+	Action string `json:"action"`
+}
+
+// Moderation is added to responses whose prompt or output matched flag rules.
+type Moderation struct {
+	Flagged    bool                  `json:"flagged"`
+	Violations []ModerationViolation `json:"violations"`
+}
+
+// ModerationError reports a request blocked by a moderation rule.
+type ModerationError struct {
+	Violations []ModerationViolation `json:"violations"`
+}
+
+func (e *ModerationError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	return fmt.Sprintf("the %s was blocked by moderation rules: %s", e.Violations[0].Stage, strings.Join(rules, ", "))
+}
+
+var (
+	moderationMu    sync.RWMutex
+	moderationRules []ModerationRule
+)
+
+// SetModerationRules replaces the moderation rules applied to requests made
+// afterwards.
+func SetModerationRules(rules []ModerationRule) {
+	moderationMu.Lock()
+	defer moderationMu.Unlock()
+	moderationRules = rules
+}
+
+// Moderate returns the violations of the rules that apply to a stage. The
+// request is blocked when any of them has the block action.
+func Moderate(stage string, text string) (violations []ModerationViolation, blocked bool) {
+	moderationMu.RLock()
+	rules := moderationRules
+	moderationMu.RUnlock()
+
+	for _, rule := range rules {
+		if rule.Stage != stage && rule.Stage != ModerationBoth {
+			continue
+		}
+		if !rule.Pattern.MatchString(text) {
+			continue
+		}
+		violations = append(violations, ModerationViolation{Rule: rule.Name, Stage: stage, Action: rule.Action})
+		if rule.Action == ModerationBlock {
+			blocked = true
+		}
+	}
+	return violations, blocked
+}
+
+// moderate applies the moderation rules of a stage to a request by a user. It
+// logs every violation, and returns a *ModerationError when the request is
+// blocked, or else the violations to flag.
+func moderate(stage string, text string, userEmail string, model string) ([]ModerationViolation, error) {
+	violations, blocked := Moderate(stage, text)
+	flagged := []ModerationViolation{}
+	for _, violation := range violations {
+		slog.Warn("LLM moderation rule matched",
+			"rule", violation.Rule,
+			"stage", violation.Stage,
+			"action", violation.Action,
+			"user", userEmail,
+			"model", model,
+		)
+		if violation.Action == ModerationFlag {
+			flagged = append(flagged, violation)
+		}
+	}
+	if blocked {
+		return nil, &ModerationError{Violations: violations}
+	}
+	return flagged, nil
+}
+
+// withModeration adds flagged violations to a response body as moderation.
+// Bodies that are not JSON objects are returned unchanged.
+func withModeration(body []byte, flagged []ModerationViolation) []byte {
+	if len(flagged) == 0 {
+		return body
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return body
+	}
+	encoded, err := json.Marshal(Moderation{Flagged: true, Violations: flagged})
+	if err != nil {
+		return body
+	}
+	fields["moderation"] = encoded
+	flaggedBody, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return flaggedBody
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reasoning efforts of a request, from no thinking to the most thinking.
+const (
+	ReasoningNone   = "none"
+	ReasoningLow    = "low"
+	ReasoningMedium = "medium"
+	ReasoningHigh   = "high"
+)
+
+// thinkingBudgets are the thinking budgets of each reasoning effort, by model
+// ID prefix of the models that think. A model without a budget for none
+// cannot turn thinking off.
+var thinkingBudgets = map[string]map[string]int{
+	"gemini-2.5-pro": {
+		ReasoningLow:    1024,
+		ReasoningMedium: 8192,
+		ReasoningHigh:   32768,
+	},
+	"gemini-2.5-flash": {
+		ReasoningNone:   0,
+		ReasoningLow:    1024,
+		ReasoningMedium: 8192,
+		ReasoningHigh:   24576,
+	},
+}
+
+// thinkingBudget returns the thinking budget of a reasoning effort for a
+// model, matched by the longest model ID prefix.
+func thinkingBudget(model string, effort string) (int, error) {
+	switch effort {
+	case ReasoningNone, ReasoningLow, ReasoningMedium, ReasoningHigh:
+	default:
+		return 0, fmt.Errorf("unknown reasoning effort %q: use none, low, medium or high", effort)
+	}
+
+	var (
+		budgets map[string]int
+		matched string
+	)
+	model = strings.TrimPrefix(model, "models/")
+	for prefix, known := range thinkingBudgets {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			budgets, matched = known, prefix
+		}
+	}
+	if budgets == nil {
+		return 0, fmt.Errorf("model %q does not support reasoning effort", model)
+	}
+	budget, ok := budgets[effort]
+	if !ok {
+		return 0, fmt.Errorf("model %q does not support reasoning effort %q", model, effort)
+	}
+	return budget, nil
+}
+
+// applyReasoningEffort sets the thinking budget of the request's reasoning
+// effort, unless the generation config already sets a budget.
+func (r *GeminiRequest) applyReasoningEffort() error {
+	if r.ReasoningEffort == "" {
+		return nil
+	}
+	budget, err := thinkingBudget(r.Model, r.ReasoningEffort)
+	if err != nil {
+		return err
+	}
+	r.ReasoningEffort = ""
+
+	config := GenerationConfig{}
+	if r.GenerationConfig != nil {
+		config = *r.GenerationConfig
+	}
+	if config.ThinkingConfig != nil && config.ThinkingConfig.ThinkingBudget != nil {
+		return nil
+	}
+	thinking := ThinkingConfig{}
+	if config.ThinkingConfig != nil {
+		thinking = *config.ThinkingConfig
+	}
+	thinking.ThinkingBudget = &budget
+	config.ThinkingConfig = &thinking
+	r.GenerationConfig = &config
+	return nil
+}
@@ -0,0 +1,260 @@
+package llm
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+
+	"github.com/labstack/echo/v4"
+)
+
+// queryRecordedCall selects the attributes and request parameters of a span.
+// Args: span_id, trace_id (empty for any).
+const queryRecordedCall = `
+	SELECT
+		trace_id, attributes_json::VARCHAR,
+		COALESCE(gen_ai_response_model, gen_ai_request_model, ''),
+		gen_ai_request_temperature, gen_ai_request_max_tokens
+	FROM spans
+	WHERE span_id = $1
+		AND ($2 = '' OR trace_id = $2)
+	ORDER BY start_time DESC
+	LIMIT 1;`
+
+// HandleReplaySpan re-executes the LLM call recorded by a span with another
+// Gemini model and returns the recorded and replayed outputs side by side.
+// The prompt is read from the GenAI (gen_ai.input.messages, gen_ai.prompt)
+// or OpenInference (llm.input_messages, input.value) attributes, as stored
+// after redaction.
+func HandleReplaySpan(c echo.Context) error {
+	spanID := strings.ToLower(c.Param("spanId"))
+	var req ReplayRequest
+	if err := c.Bind(&req); err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	if req.Model == "" {
+		req.Model = "gemini-2.5-flash"
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	var traceID, attributesJSON, recordedModel string
+	var temperature sql.NullFloat64
+	var maxTokens sql.NullInt64
+	err := db.QueryRowContext(c.Request().Context(), queryRecordedCall, spanID, strings.ToLower(req.TraceID)).
+		Scan(&traceID, &attributesJSON, &recordedModel, &temperature, &maxTokens)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.New(http.StatusNotFound, "span not found")
+	}
+	if err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+
+	var attributes map[string]interface{}
+	if err := json.Unmarshal([]byte(attributesJSON), &attributes); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to parse span attributes: %v", err))
+	}
+
+	messages := recordedMessages(attributes)
+	if len(messages) == 0 {
+		return apierror.New(http.StatusUnprocessableEntity, "span has no recorded prompt")
+	}
+
+	geminiReq := GeminiRequest{Model: req.Model, GenerationConfig: &GenerationConfig{}}
+	for _, message := range messages {
+		role := message.Role
+		if role == "model" {
+			role = "assistant"
+		}
+		geminiReq.Messages = append(geminiReq.Messages, ChatMessage{Role: role, Content: message.Text})
+	}
+	if err := geminiReq.applyMessages(); err != nil {
+		return apierror.New(http.StatusUnprocessableEntity, "span has no recorded user prompt")
+	}
+	if req.Temperature != nil {
+		geminiReq.GenerationConfig.Temperature = *req.Temperature
+	} else if temperature.Valid {
+		geminiReq.GenerationConfig.Temperature = temperature.Float64
+	}
+	if req.MaxOutputTokens != nil {
+		geminiReq.GenerationConfig.MaxOutputTokens = *req.MaxOutputTokens
+	} else if maxTokens.Valid {
+		geminiReq.GenerationConfig.MaxOutputTokens = int(maxTokens.Int64)
+	}
+
+	service := serviceFor(c)
+	output, err := service.GenerateText(geminiReq)
+	if err != nil {
+		return providerError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, ReplayResponse{
+		TraceID:  traceID,
+		SpanID:   spanID,
+		Messages: messages,
+		Original: ReplayOutput{Model: recordedModel, Output: recordedOutput(attributes)},
+		Replay:   ReplayOutput{Model: req.Model, Output: output},
+	})
+}
+
+// recordedMessages returns the prompt recorded in span attributes.
+func recordedMessages(attributes map[string]interface{}) []ReplayMessage {
+	var messages []ReplayMessage
+	if system := messageText(attributes["gen_ai.system_instructions"]); system != "" {
+		messages = append(messages, ReplayMessage{Role: "system", Text: system})
+	}
+
+	if value, ok := attributes["gen_ai.input.messages"]; ok {
+		return append(messages, parseMessages(value)...)
+	}
+	if indexed := indexedMessages(attributes, "llm.input_messages.", ".message.role", ".message.content"); len(indexed) > 0 {
+		return append(messages, indexed...)
+	}
+	if indexed := indexedMessages(attributes, "gen_ai.prompt.", ".role", ".content"); len(indexed) > 0 {
+		return append(messages, indexed...)
+	}
+	for _, key := range []string{"input.value", "gen_ai.prompt"} {
+		if value, ok := attributes[key]; ok {
+			if parsed := parseMessages(value); len(parsed) > 0 {
+				return append(messages, parsed...)
+			}
+			if text := messageText(value); text != "" {
+				return append(messages, ReplayMessage{Role: "user", Text: text})
+			}
+		}
+	}
+	return messages
+}
+
+// recordedOutput returns the completion recorded in span attributes.
+func recordedOutput(attributes map[string]interface{}) string {
+	for _, key := range []string{"output.value", "gen_ai.completion", "gen_ai.output.messages", "llm.output_messages.0.message.content", "gen_ai.completion.0.content"} {
+		value, ok := attributes[key]
+		if !ok {
+			continue
+		}
+		if parsed := parseMessages(value); len(parsed) > 0 {
+			texts := make([]string, 0, len(parsed))
+			for _, message := range parsed {
+				texts = append(texts, message.Text)
+			}
+			return strings.Join(texts, "\n")
+		}
+		return messageText(value)
+	}
+	return ""
+}
+
+// parseMessages reads a list of chat messages, given as a JSON string or a
+// decoded value: an array of {role, content} or {role, parts} objects, or an
+// object with such an array in messages.
+func parseMessages(value interface{}) []ReplayMessage {
+	if text, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return nil
+		}
+	}
+	if object, ok := value.(map[string]interface{}); ok {
+		value = object["messages"]
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var messages []ReplayMessage
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := object["role"].(string)
+		text := messageText(object["content"])
+		if text == "" {
+			text = messageText(object["parts"])
+		}
+		if role == "" || text == "" {
+			continue
+		}
+		messages = append(messages, ReplayMessage{Role: normalizeRole(role), Text: text})
+	}
+	return messages
+}
+
+// indexedMessages reads chat messages flattened into attributes such as
+// llm.input_messages.0.message.role and llm.input_messages.0.message.content.
+func indexedMessages(attributes map[string]interface{}, prefix, roleSuffix, contentSuffix string) []ReplayMessage {
+	var indexes []int
+	for key := range attributes {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		if number, ok := strings.CutSuffix(rest, roleSuffix); ok {
+			if index, err := strconv.Atoi(number); err == nil {
+				indexes = append(indexes, index)
+			}
+		}
+	}
+	sort.Ints(indexes)
+
+	var messages []ReplayMessage
+	for _, index := range indexes {
+		role, _ := attributes[prefix+strconv.Itoa(index)+roleSuffix].(string)
+		text := messageText(attributes[prefix+strconv.Itoa(index)+contentSuffix])
+		if text != "" {
+			messages = append(messages, ReplayMessage{Role: normalizeRole(role), Text: text})
+		}
+	}
+	return messages
+}
+
+// messageText returns the text of a message content: a string, or the text of
+// a list of parts ({type: text, content} or {text}).
+func messageText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var texts []string
+		for _, part := range v {
+			if text := messageText(part); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	case map[string]interface{}:
+		if kind, ok := v["type"].(string); ok && kind != "text" {
+			return ""
+		}
+		if text, ok := v["content"].(string); ok {
+			return text
+		}
+		if text, ok := v["text"].(string); ok {
+			return text
+		}
+	}
+	return ""
+}
+
+// normalizeRole maps chat roles to the Gemini roles, keeping system.
+func normalizeRole(role string) string {
+	switch role {
+	case "system", "developer":
+		return "system"
+	case "assistant", "model":
+		return "model"
+	}
+	return "user"
+}
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Retry and circuit breaker settings for provider requests. Rate limited
+// (429) and server error (5xx) responses and network errors are retried with
+// full jitter exponential backoff. The circuit for a provider opens after
+// breakerThreshold consecutive failed attempts; requests fail fast until the
+// cooldown has elapsed, then a single trial request decides whether it closes.
+var (
+	maxAttempts      = 3
+	retryBaseDelay   = 500 * time.Millisecond
+	retryMaxDelay    = 8 * time.Second
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// ProviderError reports a provider request that still failed after retries.
+type ProviderError struct {
+	Provider   string `json:"provider"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Attempts   int    `json:"attempts"`
+	Message    string `json:"message"`
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s request failed after %d attempts: %s", e.Provider, e.Attempts, e.Message)
+	}
+	return fmt.Sprintf("%s request failed after %d attempts with status %d: %s", e.Provider, e.Attempts, e.StatusCode, e.Message)
+}
+
+// CircuitOpenError reports that requests to a provider are paused after
+// repeated failures.
+type CircuitOpenError struct {
+	Provider string    `json:"provider"`
+	RetryAt  time.Time `json:"retry_at"`
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s requests are paused after repeated failures, retry after %s", e.Provider, e.RetryAt.Format(time.RFC3339))
+}
+
+// breaker is the circuit breaker of a provider.
+type breaker struct {
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*breaker{}
+)
+
+// breakerFor returns the circuit breaker of a provider.
+func breakerFor(provider string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[provider]
+	if !ok {
+		b = &breaker{}
+		breakers[provider] = b
+	}
+	return b
+}
+
+// allow reports whether a request may be sent, letting a single trial request
+// through once the cooldown of an open circuit has elapsed.
+func (b *breaker) allow(provider string, now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return nil
+	}
+	retryAt := b.openedAt.Add(breakerCooldown)
+	if now.Before(retryAt) || b.probing {
+		return &CircuitOpenError{Provider: provider, RetryAt: retryAt}
+	}
+	b.probing = true
+	return nil
+}
+
+// Circuit states of a provider.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// state returns the circuit state: open during the cooldown, half open once a
+// trial request may be sent, and closed otherwise.
+func (b *breaker) state(now time.Time) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return CircuitClosed
+	}
+	if now.Before(b.openedAt.Add(breakerCooldown)) {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
+
+// record records the outcome of a request. A failed trial request reopens the
+// circuit for another cooldown.
+func (b *breaker) record(ok bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openedAt = now
+	}
+}
+
+// withRetries sends a provider request with do, retrying transient failures.
+// When every attempt fails, it returns a *ProviderError whose message is taken
+// from the last response body with message, or from the network error.
+func withRetries(provider string, do func() ([]byte, int, error), message func(body []byte) string) ([]byte, int, error) {
+	b := breakerFor(provider)
+
+	var (
+		body       []byte
+		statusCode int
+		err        error
+	)
+	for attempt := 1; ; attempt++ {
+		if err := b.allow(provider, time.Now()); err != nil {
+			return nil, 0, err
+		}
+
+		body, statusCode, err = do()
+		transient := err != nil || isTransientStatus(statusCode)
+		b.record(!transient, time.Now())
+		if !transient {
+			return body, statusCode, nil
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		time.Sleep(backoff(attempt))
+	}
+
+	providerErr := &ProviderError{Provider: provider, StatusCode: statusCode, Attempts: maxAttempts}
+	if err != nil {
+		providerErr.Message = err.Error()
+	} else if providerErr.Message = message(body); providerErr.Message == "" {
+		providerErr.Message = http.StatusText(statusCode)
+	}
+	return body, statusCode, providerErr
+}
+
+// isTransientStatus reports whether a response status is worth retrying.
+func isTransientStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// backoff returns the delay before the retry following an attempt: a random
+// duration up to the exponentially growing, capped delay for that attempt.
+func backoff(attempt int) time.Duration {
+	delay := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	return rand.N(delay)
+}
@@ -1,10 +1,25 @@
 package llm
 
 import (
+	"junjo-server/quotas"
+
 	"github.com/labstack/echo/v4"
 )
 
-// RegisterRoutes registers the LLM service routes.
+// RegisterRoutes registers the LLM service routes. Requests made with the
+// user's own API key are not counted against the shared LLM quota, but every
+// request that reaches a provider is subject to the per-user rate limit.
+// Comparisons are charged by their handler, one request per target.
 func RegisterRoutes(e *echo.Echo) {
-	e.POST("/llm/generate", HandleGeminiTextRequest)
+	limit := quotas.LLMRateLimit()
+	quota := quotas.LLMQuotaWithSkipper(UsesOwnKey)
+	e.POST("/llm/generate", HandleGeminiTextRequest, limit, UserKeys(), quota)
+	e.POST("/llm/replay/:spanId", HandleReplaySpan, limit, UserKeys(), quota)
+	e.POST("/llm/embeddings", HandleEmbeddings, limit, UserKeys(), quota)
+	e.POST("/llm/compare", HandleCompareModels, UserKeys())
+	e.POST("/llm/caches", HandleCreateCache, limit, UserKeys())
+	e.DELETE("/llm/caches/:id", HandleDeleteCache, limit, UserKeys())
+	e.GET("/llm/models", HandleListModels, limit, UserKeys())
+	e.GET("/llm/providers/:provider/health", HandleProviderHealth, limit, UserKeys())
+	e.GET("/llm/stats", HandleStats)
 }
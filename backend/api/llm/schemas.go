@@ -1,8 +1,28 @@
 package llm
 
-// GeminiPart represents a part of a content message.
+import (
+	"encoding/json"
+	"time"
+)
+
+// GeminiPart represents a part of a content message: text, inline data, or a
+// file referenced by URI.
 type GeminiPart struct {
-	Text string `json:"text"`
+	Text       string          `json:"text,omitempty"`
+	InlineData *GeminiBlob     `json:"inlineData,omitempty"`
+	FileData   *GeminiFileData `json:"fileData,omitempty"`
+}
+
+// GeminiBlob is base64-encoded inline data, such as an image.
+type GeminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// GeminiFileData references a file by URI.
+type GeminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
 }
 
 // GeminiContent represents the content of a message.
@@ -12,13 +32,25 @@ type GeminiContent struct {
 }
 
 // GenerationConfig specifies the generation parameters for the model.
+// ResponseJSONSchema constrains the output to a JSON schema; it requires the
+// application/json response MIME type, which is set when omitted.
 // https://ai.google.dev/api/generate-content#v1beta.GenerationConfig
 type GenerationConfig struct {
-	StopSequences    []string `json:"stopSequences,omitempty"`
-	ResponseMimeType string   `json:"responseMimeType,omitempty"`
-	Temperature      float64  `json:"temperature,omitempty"`
-	TopP             float64  `json:"topP,omitempty"`
-	TopK             int      `json:"topK,omitempty"`
+	StopSequences      []string        `json:"stopSequences,omitempty"`
+	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+	Temperature        float64         `json:"temperature,omitempty"`
+	TopP               float64         `json:"topP,omitempty"`
+	TopK               int             `json:"topK,omitempty"`
+	MaxOutputTokens    int             `json:"maxOutputTokens,omitempty"`
+	ThinkingConfig     *ThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+// ThinkingConfig configures the thinking of Gemini 2.5 models. A budget of 0
+// disables thinking and -1 lets the model decide.
+type ThinkingConfig struct {
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
 }
 
 // SystemInstruction provides system-level instructions to the model.
@@ -26,10 +58,268 @@ type SystemInstruction struct {
 	Parts []GeminiPart `json:"parts"`
 }
 
-// GeminiRequest is the request body for the Gemini API.
+// ChatMessage is a turn of a conversation. The role is system, user or
+// assistant. User and assistant turns may include images.
+type ChatMessage struct {
+	Role    string      `json:"role"`
+	Content string      `json:"content"`
+	Images  []ChatImage `json:"images,omitempty"`
+}
+
+// ChatImage is an image of a message, given either as base64 Data (or a
+// data: URL) or as a URL the provider fetches. MimeType is required for
+// base64 data and optional for URLs.
+type ChatImage struct {
+	URL      string `json:"url,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// GeminiRequest is the request body for the Gemini API. Messages, when set,
+// hold the conversation history instead of Contents and are translated to
+// Gemini contents and a system instruction before the request is sent.
+// JSONRepairAttempts is the number of corrective follow-up requests made when
+// the output does not match the responseJsonSchema; it is not sent to Gemini.
+// ReasoningEffort, one of none, low, medium or high, sets the thinking budget
+// of the model unless the generation config sets one; it is not sent to Gemini.
+// CachedContent is the name of a context cache, such as cachedContents/abc,
+// whose system instruction and contents prefix the request; it requires the
+// model the cache was created for and no system instruction of its own.
 type GeminiRequest struct {
-	Model             string             `json:"model,omitempty"`
-	Contents          []GeminiContent    `json:"contents"`
-	GenerationConfig  *GenerationConfig  `json:"generationConfig,omitempty"`
-	SystemInstruction *SystemInstruction `json:"system_instruction,omitempty"`
+	Model              string             `json:"model,omitempty"`
+	Messages           []ChatMessage      `json:"messages,omitempty"`
+	JSONRepairAttempts int                `json:"jsonRepairAttempts,omitempty"`
+	ReasoningEffort    string             `json:"reasoningEffort,omitempty"`
+	Contents           []GeminiContent    `json:"contents"`
+	GenerationConfig   *GenerationConfig  `json:"generationConfig,omitempty"`
+	SystemInstruction  *SystemInstruction `json:"system_instruction,omitempty"`
+	CachedContent      string             `json:"cachedContent,omitempty"`
+}
+
+// GeminiCandidate is a response candidate generated by the model.
+type GeminiCandidate struct {
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+}
+
+// GeminiError is the error returned by the Gemini API.
+type GeminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// GeminiUsageMetadata is the token usage of a Gemini request. The prompt
+// token count includes the tokens read from a context cache.
+type GeminiUsageMetadata struct {
+	PromptTokenCount        int64 `json:"promptTokenCount"`
+	CandidatesTokenCount    int64 `json:"candidatesTokenCount"`
+	CachedContentTokenCount int64 `json:"cachedContentTokenCount,omitempty"`
+}
+
+// GeminiResponse is the response body of the Gemini API.
+type GeminiResponse struct {
+	Candidates    []GeminiCandidate    `json:"candidates"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
+	Error         *GeminiError         `json:"error,omitempty"`
+}
+
+// ReplayRequest re-executes the LLM call recorded by a span with another
+// model. The trace id disambiguates span ids reused across traces. The
+// recorded temperature and max tokens are used unless overridden.
+type ReplayRequest struct {
+	TraceID         string   `json:"trace_id,omitempty"`
+	Model           string   `json:"model"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens *int     `json:"max_output_tokens,omitempty"`
+}
+
+// ReplayMessage is a message of the recorded prompt. The role is system, user
+// or model.
+type ReplayMessage struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// ReplayOutput is the output of an LLM call and the model that produced it.
+type ReplayOutput struct {
+	Model  string `json:"model"`
+	Output string `json:"output"`
+}
+
+// ReplayResponse compares the recorded output of an LLM call with its replay.
+type ReplayResponse struct {
+	TraceID  string          `json:"trace_id"`
+	SpanID   string          `json:"span_id"`
+	Messages []ReplayMessage `json:"messages"`
+	Original ReplayOutput    `json:"original"`
+	Replay   ReplayOutput    `json:"replay"`
+}
+
+// EmbeddingsRequest generates an embedding of each input text. Dimensions
+// truncates the embeddings when the model supports it, and TaskType is the
+// Gemini task type, such as RETRIEVAL_DOCUMENT or SEMANTIC_SIMILARITY.
+type EmbeddingsRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+	TaskType   string   `json:"task_type,omitempty"`
+}
+
+// Embedding is the embedding of the input text at Index.
+type Embedding struct {
+	Index  int       `json:"index"`
+	Values []float64 `json:"values"`
+}
+
+// EmbeddingsResponse holds the embeddings of the input texts, in order.
+type EmbeddingsResponse struct {
+	Model      string      `json:"model"`
+	Embeddings []Embedding `json:"embeddings"`
+}
+
+// GeminiEmbedContentRequest is a request of the Gemini batchEmbedContents API.
+// https://ai.google.dev/api/embeddings#v1beta.models.batchEmbedContents
+type GeminiEmbedContentRequest struct {
+	Model                string        `json:"model"`
+	Content              GeminiContent `json:"content"`
+	TaskType             string        `json:"taskType,omitempty"`
+	OutputDimensionality int           `json:"outputDimensionality,omitempty"`
+}
+
+// GeminiBatchEmbedRequest is the request body of the batchEmbedContents API.
+type GeminiBatchEmbedRequest struct {
+	Requests []GeminiEmbedContentRequest `json:"requests"`
+}
+
+// GeminiBatchEmbedResponse is the response body of the batchEmbedContents API.
+type GeminiBatchEmbedResponse struct {
+	Embeddings []struct {
+		Values []float64 `json:"values"`
+	} `json:"embeddings"`
+	Error *GeminiError `json:"error,omitempty"`
+}
+
+// ModelInfo describes a model available to LLM requests. ContextWindow and
+// MaxOutputTokens are the input and output token limits, or 0 when unknown.
+type ModelInfo struct {
+	Provider         string   `json:"provider"`
+	ID               string   `json:"id"`
+	DisplayName      string   `json:"display_name"`
+	Description      string   `json:"description"`
+	ContextWindow    int      `json:"context_window"`
+	MaxOutputTokens  int      `json:"max_output_tokens"`
+	SupportedActions []string `json:"supported_actions"`
+}
+
+// GeminiModel is a model of the Gemini models.list API.
+// https://ai.google.dev/api/models#Model
+type GeminiModel struct {
+	Name                       string   `json:"name"`
+	DisplayName                string   `json:"displayName"`
+	Description                string   `json:"description"`
+	InputTokenLimit            int      `json:"inputTokenLimit"`
+	OutputTokenLimit           int      `json:"outputTokenLimit"`
+	SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+}
+
+// GeminiListModelsResponse is the response body of the models.list API.
+type GeminiListModelsResponse struct {
+	Models        []GeminiModel `json:"models"`
+	NextPageToken string        `json:"nextPageToken"`
+	Error         *GeminiError  `json:"error,omitempty"`
+}
+
+// JSONValidation reports whether the output of a request matches its
+// responseJsonSchema, after Attempts requests, and the errors of the last one.
+type JSONValidation struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Attempts int      `json:"attempts"`
+}
+
+// CompareTarget is a provider and model a comparison runs the prompt against,
+// with an optional reasoning effort. The provider defaults to gemini.
+type CompareTarget struct {
+	Provider        string `json:"provider"`
+	Model           string `json:"model"`
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+}
+
+// CompareRequest runs the same conversation against several models.
+type CompareRequest struct {
+	Messages         []ChatMessage     `json:"messages"`
+	GenerationConfig *GenerationConfig `json:"generationConfig,omitempty"`
+	Targets          []CompareTarget   `json:"targets"`
+}
+
+// CompareResult is the output of one target of a comparison, or the error it
+// failed with.
+type CompareResult struct {
+	Provider        string `json:"provider"`
+	Model           string `json:"model"`
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	Output          string `json:"output"`
+	FinishReason    string `json:"finish_reason,omitempty"`
+	LatencyMs       int64  `json:"latency_ms"`
+	InputTokens     int64  `json:"input_tokens"`
+	OutputTokens    int64  `json:"output_tokens"`
+	Error           string `json:"error,omitempty"`
+}
+
+// CompareResponse holds the results of a comparison, in the order of its
+// targets.
+type CompareResponse struct {
+	Results []CompareResult `json:"results"`
+}
+
+// CreateCacheRequest caches a conversation prefix, typically a large system
+// prompt, for a model. Requests then reference the cache by name instead of
+// resending it, and its tokens are billed at the cached rate. The cache
+// expires after TTLSeconds, 3600 by default.
+type CreateCacheRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	DisplayName string        `json:"display_name,omitempty"`
+	TTLSeconds  int           `json:"ttl_seconds,omitempty"`
+}
+
+// Cache is a context cache. Requests use it by setting cachedContent to Name.
+type Cache struct {
+	Name        string `json:"name"`
+	Model       string `json:"model"`
+	DisplayName string `json:"display_name"`
+	ExpireTime  string `json:"expire_time"`
+	TokenCount  int64  `json:"token_count"`
+}
+
+// GeminiCachedContent is a resource of the Gemini cachedContents API.
+// https://ai.google.dev/api/caching#CachedContent
+type GeminiCachedContent struct {
+	Name              string             `json:"name,omitempty"`
+	Model             string             `json:"model"`
+	DisplayName       string             `json:"displayName,omitempty"`
+	Contents          []GeminiContent    `json:"contents,omitempty"`
+	SystemInstruction *SystemInstruction `json:"systemInstruction,omitempty"`
+	TTL               string             `json:"ttl,omitempty"`
+	ExpireTime        string             `json:"expireTime,omitempty"`
+	UsageMetadata     *struct {
+		TotalTokenCount int64 `json:"totalTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+	Error *GeminiError `json:"error,omitempty"`
+}
+
+// ProviderHealth is the result of a health check of a provider. KeyValid is
+// null when no key is configured or the provider could not be reached.
+type ProviderHealth struct {
+	Provider   string    `json:"provider"`
+	Status     string    `json:"status"`
+	Reachable  bool      `json:"reachable"`
+	KeySource  string    `json:"key_source,omitempty"`
+	KeyValid   *bool     `json:"key_valid"`
+	LatencyMs  int64     `json:"latency_ms"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Circuit    string    `json:"circuit"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
 }
@@ -2,52 +2,465 @@ package llm
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"junjo-server/selftrace"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const geminiAPIBaseURL = "https://generativelanguage.googleapis.com/v1beta/models/"
+// defaultGeminiAPIBaseURL is the Gemini API used unless GEMINI_API_BASE_URL
+// points elsewhere, such as a proxy or a regional endpoint.
+const defaultGeminiAPIBaseURL = "https://generativelanguage.googleapis.com/v1beta"
 
-// GeminiService is a service for interacting with the Gemini API.
-type GeminiService struct{}
+// geminiBaseURL is the configured Gemini API base URL.
+var geminiBaseURL = defaultGeminiAPIBaseURL
+
+// SetGeminiBaseURL sets the Gemini API base URL of services created
+// afterwards.
+func SetGeminiBaseURL(baseURL string) {
+	geminiBaseURL = baseURL
+}
+
+// GeminiService is a service for interacting with the Gemini API. Requests use
+// the stored or environment API key unless apiKey is set.
+type GeminiService struct {
+	baseURL   string
+	apiKey    string
+	userEmail string
+
+	// traceParent is the span the service's requests are traced under.
+	traceParent trace.SpanContext
+}
 
-// NewGeminiService creates a new GeminiService.
+// NewGeminiService creates a new GeminiService for the configured API, or the
+// public Gemini API when unset.
 func NewGeminiService() *GeminiService {
-	return &GeminiService{}
+	return (&GeminiService{}).WithBaseURL(geminiBaseURL)
+}
+
+// WithBaseURL returns the service with its requests sent to another Gemini
+// API base URL, such as https://generativelanguage.googleapis.com/v1beta.
+func (s *GeminiService) WithBaseURL(baseURL string) *GeminiService {
+	s.baseURL = strings.TrimRight(baseURL, "/")
+	return s
+}
+
+// ForUser returns the service with its requests attributed to a user in the
+// audit log.
+func (s *GeminiService) ForUser(userEmail string) *GeminiService {
+	s.userEmail = userEmail
+	return s
+}
+
+// WithTraceParent returns the service with its requests traced under the
+// span of ctx. Cancelling ctx does not cancel the requests.
+func (s *GeminiService) WithTraceParent(ctx context.Context) *GeminiService {
+	s.traceParent = trace.SpanContextFromContext(ctx)
+	return s
 }
 
 // GenerateContent sends a request to the Gemini API to generate content.
 func (s *GeminiService) GenerateContent(requestBody GeminiRequest) ([]byte, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY environment variable is not set")
+	if len(requestBody.Messages) > 0 {
+		if err := requestBody.applyMessages(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := requestBody.applyReasoningEffort(); err != nil {
+		return nil, err
+	}
+
+	// A response schema requires JSON output
+	if config := requestBody.GenerationConfig; config != nil && len(config.ResponseJSONSchema) > 0 && config.ResponseMimeType == "" {
+		config.ResponseMimeType = "application/json"
+	}
+	requestBody.JSONRepairAttempts = 0
+
+	return s.post(requestBody.Model, "generateContent", requestBody.promptText(), requestBody)
+}
+
+// maxJSONRepairAttempts caps the corrective follow-up requests of a request.
+const maxJSONRepairAttempts = 3
+
+// GenerateValidatedContent sends a request with a responseJsonSchema and
+// validates the output against the schema. While the output does not match,
+// it asks the model to correct it, up to JSONRepairAttempts times. It returns
+// the last response and its validation, which is nil when the response has no
+// output to validate.
+func (s *GeminiService) GenerateValidatedContent(requestBody GeminiRequest) ([]byte, *JSONValidation, error) {
+	if len(requestBody.Messages) > 0 {
+		if err := requestBody.applyMessages(); err != nil {
+			return nil, nil, err
+		}
+	}
+	var schema json.RawMessage
+	if requestBody.GenerationConfig != nil {
+		schema = requestBody.GenerationConfig.ResponseJSONSchema
+	}
+	repairs := min(max(requestBody.JSONRepairAttempts, 0), maxJSONRepairAttempts)
+
+	for attempt := 1; ; attempt++ {
+		body, err := s.GenerateContent(requestBody)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var resp GeminiResponse
+		if json.Unmarshal(body, &resp) != nil || resp.Error != nil || len(resp.Candidates) == 0 {
+			return body, nil, nil
+		}
+		output := candidateText(resp.Candidates[0])
+		errs, err := validateJSON(schema, output)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		validation := &JSONValidation{Valid: len(errs) == 0, Errors: append([]string{}, errs...), Attempts: attempt}
+		if validation.Valid || attempt > repairs {
+			return body, validation, nil
+		}
+
+		// Ask for a corrected output, keeping the invalid one in the history
+		requestBody.Contents = append(slices.Clone(requestBody.Contents),
+			GeminiContent{Role: "model", Parts: []GeminiPart{{Text: output}}},
+			GeminiContent{Role: "user", Parts: []GeminiPart{{Text: "Your response does not match the required JSON schema:\n- " +
+				strings.Join(errs, "\n- ") + "\nRespond again with only the corrected JSON."}}},
+		)
 	}
+}
 
-	// Construct the full API URL with the model from the request
-	apiURL := fmt.Sprintf("%s%s:generateContent", geminiAPIBaseURL, requestBody.Model)
+// candidateText returns the text of a response candidate.
+func candidateText(candidate GeminiCandidate) string {
+	text := ""
+	for _, part := range candidate.Content.Parts {
+		text += part.Text
+	}
+	return text
+}
+
+// post sends a JSON request to an action of a Gemini model, such as
+// generateContent, unless the model is not allowed, a token budget has been
+// spent or a moderation rule blocks the prompt. It records the request in the
+// audit log and returns the response body, with flagged moderation violations
+// added, unless a moderation rule blocks the output.
+func (s *GeminiService) post(model string, action string, prompt string, requestBody interface{}) ([]byte, error) {
+	start := time.Now()
+	entry := AuditEntry{
+		UserEmail:  s.userEmail,
+		Provider:   ProviderGemini,
+		Model:      model,
+		Action:     action,
+		PromptHash: hashPrompt(prompt),
+	}
 
-	jsonData, err := json.Marshal(requestBody)
+	if !ModelAllowed(ProviderGemini, model) {
+		err := &ModelNotAllowedError{Provider: ProviderGemini, Model: model}
+		entry.Error = err.Error()
+		recordAudit(entry)
+		return nil, err
+	}
+	if err := checkBudget(s.userEmail, ProviderGemini); err != nil {
+		entry.Error = err.Error()
+		recordAudit(entry)
+		return nil, err
+	}
+	flagged, err := moderate(ModerationPrompt, prompt, s.userEmail, model)
 	if err != nil {
+		entry.Error = err.Error()
+		recordAudit(entry)
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
+	body, statusCode, err := s.send(http.MethodPost, "models/"+model+":"+action, requestBody)
+	latency := time.Since(start)
+	entry.StatusCode = statusCode
+	entry.LatencyMs = latency.Milliseconds()
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		var resp GeminiResponse
+		if json.Unmarshal(body, &resp) == nil {
+			if resp.Error != nil {
+				entry.Error = resp.Error.Message
+			}
+			if resp.UsageMetadata != nil {
+				entry.InputTokens = resp.UsageMetadata.PromptTokenCount
+				entry.OutputTokens = resp.UsageMetadata.CandidatesTokenCount
+			}
+			if len(resp.Candidates) > 0 {
+				var outputFlagged []ModerationViolation
+				outputFlagged, err = moderate(ModerationResponse, candidateText(resp.Candidates[0]), s.userEmail, model)
+				if err != nil {
+					entry.Error = err.Error()
+					body = nil
+				}
+				flagged = append(flagged, outputFlagged...)
+			}
+		}
+	}
+	recordAudit(entry)
+	recordStats(entry, latency)
+
 	if err != nil {
 		return nil, err
 	}
+	return withModeration(body, flagged), nil
+}
+
+// send sends a request to a path of the Gemini API, such as
+// models/gemini-2.5-flash:generateContent, with a JSON body unless requestBody
+// is nil, and returns the response body and status. Transient failures are
+// retried, and fail fast while the circuit is open.
+func (s *GeminiService) send(httpMethod string, path string, requestBody interface{}) ([]byte, int, error) {
+	key, ok := s.key()
+	if !ok {
+		return nil, 0, fmt.Errorf("no Gemini API key is stored and the GEMINI_API_KEY environment variable is not set")
+	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-goog-api-key", apiKey)
+	var jsonData []byte
+	if requestBody != nil {
+		var err error
+		if jsonData, err = json.Marshal(requestBody); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	operation, _, _ := strings.Cut(path, "?")
+	ctx, span := selftrace.Start(trace.ContextWithSpanContext(context.Background(), s.traceParent), "gemini "+httpMethod,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.GenAISystemGemini,
+			semconv.HTTPRequestMethodKey.String(httpMethod),
+			semconv.URLPath(operation),
+		),
+	)
+	defer span.End()
+
+	body, statusCode, err := withRetries(ProviderGemini, func() ([]byte, int, error) {
+		return s.do(ctx, httpMethod, path, key, jsonData)
+	}, geminiErrorMessage)
+	if statusCode > 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+	}
+	if err != nil {
+		selftrace.RecordError(span, err)
+	} else if statusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+	return body, statusCode, err
+}
+
+// key returns the API key of the service's requests and whether it is set.
+func (s *GeminiService) key() (string, bool) {
+	if s.apiKey != "" {
+		return s.apiKey, true
+	}
+	return apiKey(ProviderGemini)
+}
+
+// do makes a single request to a path of the Gemini API with an API key.
+func (s *GeminiService) do(ctx context.Context, httpMethod string, path string, key string, jsonData []byte) ([]byte, int, error) {
+	// Construct the full API URL with the path
+	apiURL := s.baseURL + "/" + path
+
+	var reqBody io.Reader
+	if jsonData != nil {
+		reqBody = bytes.NewReader(jsonData)
+	}
+	req, err := http.NewRequestWithContext(ctx, httpMethod, apiURL, reqBody)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if jsonData != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("x-goog-api-key", key)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
-	return io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
+
+// geminiErrorMessage returns the error message of a Gemini response body.
+func geminiErrorMessage(body []byte) string {
+	var resp GeminiResponse
+	if json.Unmarshal(body, &resp) != nil || resp.Error == nil {
+		return ""
+	}
+	return resp.Error.Message
+}
+
+// GenerateText sends a request to the Gemini API and returns the text of the
+// first candidate.
+func (s *GeminiService) GenerateText(requestBody GeminiRequest) (string, error) {
+	body, err := s.GenerateContent(requestBody)
+	if err != nil {
+		return "", err
+	}
+
+	var resp GeminiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+	if resp.Error != nil {
+		return "", fmt.Errorf("gemini API error %d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	if len(resp.Candidates) == 0 {
+		return "", fmt.Errorf("gemini returned no candidates")
+	}
+
+	return candidateText(resp.Candidates[0]), nil
+}
+
+// applyMessages translates the conversation history into Gemini contents:
+// system messages join the system instruction, assistant turns take the model
+// role, and images become inline data or file parts.
+func (r *GeminiRequest) applyMessages() error {
+	contents, err := r.translateMessages()
+	if err != nil {
+		return err
+	}
+	if len(contents) == 0 {
+		return fmt.Errorf("messages must include a user message")
+	}
+	r.Contents = append(r.Contents, contents...)
+	r.Messages = nil
+	return nil
+}
+
+// translateMessages adds the system messages to the system instruction and
+// returns the other messages as Gemini contents.
+func (r *GeminiRequest) translateMessages() ([]GeminiContent, error) {
+	contents := []GeminiContent{}
+	for i, message := range r.Messages {
+		parts := []GeminiPart{}
+		if message.Content != "" {
+			parts = append(parts, GeminiPart{Text: message.Content})
+		}
+		for j, image := range message.Images {
+			part, err := imagePart(image)
+			if err != nil {
+				return nil, fmt.Errorf("message %d, image %d: %w", i, j, err)
+			}
+			parts = append(parts, part)
+		}
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("message %d: content or images are required", i)
+		}
+
+		switch message.Role {
+		case "system":
+			if len(message.Images) > 0 {
+				return nil, fmt.Errorf("message %d: system messages cannot include images", i)
+			}
+			if r.SystemInstruction == nil {
+				r.SystemInstruction = &SystemInstruction{}
+			}
+			r.SystemInstruction.Parts = append(r.SystemInstruction.Parts, parts...)
+		case "user":
+			contents = append(contents, GeminiContent{Role: "user", Parts: parts})
+		case "assistant", "model":
+			contents = append(contents, GeminiContent{Role: "model", Parts: parts})
+		default:
+			return nil, fmt.Errorf("message %d: unknown role %q", i, message.Role)
+		}
+	}
+	return contents, nil
+}
+
+// imagePart translates an image into a Gemini part. Base64 data, including
+// data: URLs, is sent inline; other URLs are passed as file URIs.
+func imagePart(image ChatImage) (GeminiPart, error) {
+	if image.URL != "" && image.Data != "" {
+		return GeminiPart{}, fmt.Errorf("set either url or data")
+	}
+
+	data, mimeType := image.Data, image.MimeType
+	if rest, ok := strings.CutPrefix(image.URL, "data:"); ok {
+		header, encoded, found := strings.Cut(rest, ",")
+		declared, base64Encoded := strings.CutSuffix(header, ";base64")
+		if !found || !base64Encoded {
+			return GeminiPart{}, fmt.Errorf("data URLs must be base64-encoded")
+		}
+		data = encoded
+		if mimeType == "" {
+			mimeType = declared
+		}
+	}
+
+	if mimeType != "" && !strings.HasPrefix(mimeType, "image/") {
+		return GeminiPart{}, fmt.Errorf("unsupported MIME type %q", mimeType)
+	}
+
+	if data != "" {
+		if mimeType == "" {
+			return GeminiPart{}, fmt.Errorf("mime_type is required for base64 data")
+		}
+		if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+			return GeminiPart{}, fmt.Errorf("data is not valid base64")
+		}
+		return GeminiPart{InlineData: &GeminiBlob{MimeType: mimeType, Data: data}}, nil
+	}
+
+	parsed, err := url.Parse(image.URL)
+	if image.URL == "" || err != nil || parsed.Scheme == "" {
+		return GeminiPart{}, fmt.Errorf("url or data is required")
+	}
+	return GeminiPart{FileData: &GeminiFileData{MimeType: mimeType, FileURI: image.URL}}, nil
+}
+
+// Embed sends a batch embedding request to the Gemini API and returns the
+// embedding of each input text, in order.
+func (s *GeminiService) Embed(model string, input []string, dimensions int, taskType string) ([][]float64, error) {
+	batch := GeminiBatchEmbedRequest{}
+	for _, text := range input {
+		batch.Requests = append(batch.Requests, GeminiEmbedContentRequest{
+			Model:                "models/" + model,
+			Content:              GeminiContent{Parts: []GeminiPart{{Text: text}}},
+			TaskType:             taskType,
+			OutputDimensionality: dimensions,
+		})
+	}
+
+	data, err := s.post(model, "batchEmbedContents", strings.Join(input, "\n"), batch)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp GeminiBatchEmbedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("gemini API error %d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	if len(resp.Embeddings) != len(input) {
+		return nil, fmt.Errorf("gemini returned %d embeddings for %d inputs", len(resp.Embeddings), len(input))
+	}
+
+	embeddings := make([][]float64, 0, len(resp.Embeddings))
+	for _, embedding := range resp.Embeddings {
+		embeddings = append(embeddings, embedding.Values)
+	}
+	return embeddings, nil
 }
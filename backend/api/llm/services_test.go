@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"junjo-server/apierror"
+	"junjo-server/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newGeminiServer starts a test Gemini API that serves generateContent
+// requests of gemini-test with handler, and sets the API key its requests
+// are checked for.
+func newGeminiServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1beta/models/gemini-test:generateContent" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if key := r.Header.Get("x-goog-api-key"); key != "test-key" {
+			t.Errorf("x-goog-api-key = %q, want test-key", key)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	SetDefaultAPIKeys(map[string]string{ProviderGemini: "test-key"})
+	t.Cleanup(func() { SetDefaultAPIKeys(nil) })
+	return server
+}
+
+// testRequest is a generateContent request of gemini-test.
+func testRequest() GeminiRequest {
+	return GeminiRequest{
+		Model:    "gemini-test",
+		Contents: []GeminiContent{{Role: "user", Parts: []GeminiPart{{Text: "Say hello"}}}},
+	}
+}
+
+// writeCandidate responds with a candidate holding text.
+func writeCandidate(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GeminiResponse{
+		Candidates: []GeminiCandidate{{Content: GeminiContent{Role: "model", Parts: []GeminiPart{{Text: text}}}}},
+	})
+}
+
+func TestGeminiServiceWithBaseURL(t *testing.T) {
+	server := newGeminiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req GeminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if len(req.Contents) != 1 || req.Contents[0].Parts[0].Text != "Say hello" {
+			t.Errorf("unexpected contents %+v", req.Contents)
+		}
+		writeCandidate(w, "Hello!")
+	})
+
+	// Trailing slashes are trimmed from the base URL
+	text, err := NewGeminiService().WithBaseURL(server.URL + "/v1beta/").GenerateText(testRequest())
+	if err != nil {
+		t.Fatalf("GenerateText: %v", err)
+	}
+	if text != "Hello!" {
+		t.Errorf("GenerateText = %q, want Hello!", text)
+	}
+}
+
+func TestGeminiServiceConfiguredBaseURL(t *testing.T) {
+	server := newGeminiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeCandidate(w, "Hello from the proxy")
+	})
+
+	t.Setenv("JUNJO_CONFIG_FILE", "")
+	t.Setenv("JUNJO_SESSION_SECRET", "test-secret")
+	t.Setenv("GEMINI_API_BASE_URL", server.URL+"/v1beta")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	SetGeminiBaseURL(cfg.Gemini.BaseURL)
+	t.Cleanup(func() { SetGeminiBaseURL(defaultGeminiAPIBaseURL) })
+
+	text, err := NewGeminiService().GenerateText(testRequest())
+	if err != nil {
+		t.Fatalf("GenerateText: %v", err)
+	}
+	if text != "Hello from the proxy" {
+		t.Errorf("GenerateText = %q, want Hello from the proxy", text)
+	}
+}
+
+func TestGeminiServiceUpstreamError(t *testing.T) {
+	server := newGeminiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"code": 400, "message": "Invalid model", "status": "INVALID_ARGUMENT"}}`))
+	})
+
+	// Client errors are not retried; their body is returned as is
+	_, err := NewGeminiService().WithBaseURL(server.URL + "/v1beta").GenerateText(testRequest())
+	if err == nil || !strings.Contains(err.Error(), "gemini API error 400: Invalid model") {
+		t.Fatalf("GenerateText error = %v, want the Gemini API error", err)
+	}
+}
+
+func TestGeminiServiceRateLimited(t *testing.T) {
+	attempts := 0
+	server := newGeminiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"code": 429, "message": "Quota exceeded", "status": "RESOURCE_EXHAUSTED"}}`))
+	})
+	// Close the circuit the failed attempts count towards
+	t.Cleanup(func() { breakerFor(ProviderGemini).record(true, time.Now()) })
+
+	_, err := NewGeminiService().WithBaseURL(server.URL + "/v1beta").GenerateText(testRequest())
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("GenerateText error = %v, want a *ProviderError", err)
+	}
+	if providerErr.StatusCode != http.StatusTooManyRequests || providerErr.Message != "Quota exceeded" {
+		t.Errorf("ProviderError = %+v, want status 429 and the Gemini message", providerErr)
+	}
+	if attempts != maxAttempts {
+		t.Errorf("attempts = %d, want %d", attempts, maxAttempts)
+	}
+
+	// A provider still rate limiting after retries is reported as 429
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/llm/generate", nil), httptest.NewRecorder())
+	if status := apierror.From(providerError(c, err)).Status; status != http.StatusTooManyRequests {
+		t.Errorf("providerError status = %d, want 429", status)
+	}
+}
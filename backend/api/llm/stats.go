@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"junjo-server/metrics"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Prometheus metrics of provider requests, labelled by provider and model.
+var (
+	requestsMetric = metrics.NewCounterVec("junjo_llm_requests_total", "LLM provider requests.", "provider", "model")
+	errorsMetric   = metrics.NewCounterVec("junjo_llm_request_errors_total", "LLM provider requests that failed.", "provider", "model")
+	tokensMetric   = metrics.NewCounterVec("junjo_llm_tokens_total", "Tokens reported by LLM providers, by direction (input or output).", "provider", "model", "direction")
+	latencyMetric  = metrics.NewHistogramVec("junjo_llm_request_duration_seconds", "Latency of LLM provider requests.",
+		[]float64{0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}, "provider", "model")
+)
+
+// ModelStats summarizes the requests made to a provider's model since the
+// server started.
+type ModelStats struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs int64   `json:"max_latency_ms"`
+	totalLatency int64
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[[2]string]*ModelStats{}
+)
+
+// recordStats records a completed request in the metrics and stats.
+func recordStats(entry AuditEntry, latency time.Duration) {
+	failed := entry.Error != ""
+
+	requestsMetric.Add(1, entry.Provider, entry.Model)
+	if failed {
+		errorsMetric.Add(1, entry.Provider, entry.Model)
+	}
+	tokensMetric.Add(uint64(entry.InputTokens), entry.Provider, entry.Model, "input")
+	tokensMetric.Add(uint64(entry.OutputTokens), entry.Provider, entry.Model, "output")
+	latencyMetric.Observe(latency.Seconds(), entry.Provider, entry.Model)
+
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	key := [2]string{entry.Provider, entry.Model}
+	s, ok := stats[key]
+	if !ok {
+		s = &ModelStats{Provider: entry.Provider, Model: entry.Model}
+		stats[key] = s
+	}
+	s.Requests++
+	if failed {
+		s.Errors++
+	}
+	s.InputTokens += entry.InputTokens
+	s.OutputTokens += entry.OutputTokens
+	s.totalLatency += latency.Milliseconds()
+	s.AvgLatencyMs = float64(s.totalLatency) / float64(s.Requests)
+	s.MaxLatencyMs = max(s.MaxLatencyMs, latency.Milliseconds())
+}
+
+// HandleStats is the handler for the /llm/stats endpoint. It lists request,
+// error, token and latency totals per provider and model since the server
+// started.
+func HandleStats(c echo.Context) error {
+	statsMu.Lock()
+	resp := make([]ModelStats, 0, len(stats))
+	for _, s := range stats {
+		resp = append(resp, *s)
+	}
+	statsMu.Unlock()
+
+	sort.Slice(resp, func(i, j int) bool {
+		if resp[i].Provider != resp[j].Provider {
+			return resp[i].Provider < resp[j].Provider
+		}
+		return resp[i].Model < resp[j].Model
+	})
+	return c.JSON(http.StatusOK, resp)
+}
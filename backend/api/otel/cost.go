@@ -0,0 +1,158 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_trace_cost.sql
+var queryTraceCost string
+
+//go:embed query_service_cost.sql
+var queryServiceCost string
+
+// SpanCost is the token usage and estimated cost of an LLM span. CostUSD is nil
+// when the model has no known price.
+type SpanCost struct {
+	SpanID       string   `json:"span_id"`
+	Name         *string  `json:"name"`
+	Model        *string  `json:"model"`
+	InputTokens  int64    `json:"input_tokens"`
+	OutputTokens int64    `json:"output_tokens"`
+	CostUSD      *float64 `json:"cost_usd"`
+}
+
+// TraceCost is the token usage and estimated cost of a trace's LLM spans.
+// UnpricedSpans counts the spans left out of CostUSD for lack of a price.
+type TraceCost struct {
+	TraceID       string     `json:"trace_id"`
+	InputTokens   int64      `json:"input_tokens"`
+	OutputTokens  int64      `json:"output_tokens"`
+	CostUSD       float64    `json:"cost_usd"`
+	UnpricedSpans int64      `json:"unpriced_spans"`
+	Spans         []SpanCost `json:"spans"`
+}
+
+// ModelCost is the token usage and estimated cost of the LLM spans of a model.
+type ModelCost struct {
+	Model         string  `json:"model"`
+	SpanCount     int64   `json:"span_count"`
+	InputTokens   int64   `json:"input_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	CostUSD       float64 `json:"cost_usd"`
+	UnpricedSpans int64   `json:"unpriced_spans"`
+}
+
+// ServiceCost is the token usage and estimated cost of a service's LLM spans,
+// overall and per model.
+type ServiceCost struct {
+	ServiceName string      `json:"service_name"`
+	Total       ModelCost   `json:"total"`
+	Models      []ModelCost `json:"models"`
+}
+
+// GetTraceCost returns the token usage and estimated cost of each LLM span of a
+// trace, and their totals.
+func GetTraceCost(c echo.Context) error {
+	traceID := c.Param("traceId")
+	if traceID == "" {
+		return apierror.New(http.StatusBadRequest, "traceId parameter is required")
+	}
+	c.Logger().Printf("Running GetTraceCost function for trace: %s", traceID)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), queryTraceCost, traceID)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	cost := TraceCost{
+		TraceID: traceID,
+		Spans:   []SpanCost{},
+	}
+	for rows.Next() {
+		var span SpanCost
+		if err := rows.Scan(&span.SpanID, &span.Name, &span.Model, &span.InputTokens, &span.OutputTokens, &span.CostUSD); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		cost.InputTokens += span.InputTokens
+		cost.OutputTokens += span.OutputTokens
+		if span.CostUSD != nil {
+			cost.CostUSD += *span.CostUSD
+		} else {
+			cost.UnpricedSpans++
+		}
+		cost.Spans = append(cost.Spans, span)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, cost)
+}
+
+// GetServiceCost returns the token usage and estimated cost of a service's LLM
+// spans, grouped by model. The time range and other filters are given with the
+// span filter query parameters, e.g.
+// ?start_time=2025-01-01T00:00:00Z&end_time=2025-02-01T00:00:00Z.
+func GetServiceCost(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetServiceCost function for service: %s", serviceName)
+
+	filters, err := parseSpanFilters(c, 2)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryServiceCost), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	cost := ServiceCost{
+		ServiceName: serviceName,
+		Models:      []ModelCost{},
+	}
+	for rows.Next() {
+		var isTotal bool
+		var row ModelCost
+		if err := rows.Scan(&isTotal, &row.Model, &row.SpanCount, &row.InputTokens, &row.OutputTokens, &row.CostUSD, &row.UnpricedSpans); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		if isTotal {
+			row.Model = ""
+			cost.Total = row
+		} else {
+			cost.Models = append(cost.Models, row)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, cost)
+}
@@ -0,0 +1,179 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_end_users.sql
+var queryEndUsers string
+
+//go:embed query_end_user_traces.sql
+var queryEndUserTraces string
+
+// End-user listing limits.
+const (
+	defaultEndUserLimit = 100
+	maxEndUserLimit     = 1000
+)
+
+// EndUserStats are the activity of an end user. A trace is attributed to the
+// end user of its earliest span with an end-user identifier, and counts as an
+// error when any of its spans ended with an error status.
+type EndUserStats struct {
+	UserID          string    `json:"user_id"`
+	TraceCount      int64     `json:"trace_count"`
+	RunCount        int64     `json:"run_count"`
+	ErrorTraceCount int64     `json:"error_trace_count"`
+	ErrorRate       float64   `json:"error_rate"`
+	InputTokens     int64     `json:"input_tokens"`
+	OutputTokens    int64     `json:"output_tokens"`
+	CostUSD         float64   `json:"cost_usd"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+}
+
+// EndUserTrace is a trace attributed to an end user.
+type EndUserTrace struct {
+	TraceID      string    `json:"trace_id"`
+	RootSpanID   *string   `json:"root_span_id"`
+	RootSpanName *string   `json:"root_span_name"`
+	WorkflowName *string   `json:"workflow_name"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	DurationMs   int64     `json:"duration_ms"`
+	SpanCount    int64     `json:"span_count"`
+	ErrorCount   int64     `json:"error_count"`
+	InputTokens  int64     `json:"input_tokens"`
+	OutputTokens int64     `json:"output_tokens"`
+	CostUSD      float64   `json:"cost_usd"`
+}
+
+// parseEndUserLimit reads the limit query parameter.
+func parseEndUserLimit(c echo.Context) (int, error) {
+	limit := defaultEndUserLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return 0, fmt.Errorf("limit must be a positive integer")
+		}
+		limit = min(parsed, maxEndUserLimit)
+	}
+	return limit, nil
+}
+
+// GetEndUsers lists the end users of a service, most recently active first,
+// with their trace and workflow run counts, error rates, and token usage. The
+// end-user identifier is read from the enduser.id attribute, or those set with
+// JUNJO_ENDUSER_ATTRIBUTES. The number of users is set with the limit query
+// parameter (default 100, max 1000), and the span filter query parameters
+// apply.
+func GetEndUsers(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetEndUsers function for service: %s", serviceName)
+
+	limit, err := parseEndUserLimit(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	filters, err := parseSpanFilters(c, 3)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, limit}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryEndUsers), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	users := []EndUserStats{}
+	for rows.Next() {
+		var user EndUserStats
+		if err := rows.Scan(&user.UserID, &user.TraceCount, &user.RunCount, &user.ErrorTraceCount, &user.InputTokens, &user.OutputTokens, &user.CostUSD, &user.FirstSeen, &user.LastSeen); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		if user.TraceCount > 0 {
+			user.ErrorRate = float64(user.ErrorTraceCount) / float64(user.TraceCount)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, users)
+}
+
+// GetEndUserTraces lists the traces of a service attributed to an end user,
+// most recent first, to debug a specific customer session. The number of
+// traces is set with the limit query parameter (default 100, max 1000), and
+// the span filter query parameters apply.
+func GetEndUserTraces(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	userID := c.Param("userId")
+	if userID == "" {
+		return apierror.New(http.StatusBadRequest, "userId parameter is required")
+	}
+	c.Logger().Printf("Running GetEndUserTraces function for service %s and user %s", serviceName, userID)
+
+	limit, err := parseEndUserLimit(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	filters, err := parseSpanFilters(c, 4)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, userID, limit}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryEndUserTraces), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	traces := []EndUserTrace{}
+	for rows.Next() {
+		var trace EndUserTrace
+		if err := rows.Scan(&trace.TraceID, &trace.RootSpanID, &trace.RootSpanName, &trace.WorkflowName, &trace.StartTime, &trace.EndTime, &trace.DurationMs, &trace.SpanCount, &trace.ErrorCount, &trace.InputTokens, &trace.OutputTokens, &trace.CostUSD); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		traces = append(traces, trace)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, traces)
+}
@@ -0,0 +1,123 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_error_rate.sql
+var queryErrorRate string
+
+const (
+	defaultErrorRateBucket = time.Hour
+	minErrorRateBucket     = time.Minute
+	defaultErrorRateRange  = 24 * time.Hour
+)
+
+// ErrorRateBucket is the number of spans and errored spans that started within
+// a time bucket.
+type ErrorRateBucket struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Total       int64     `json:"total"`
+	Errors      int64     `json:"errors"`
+	ErrorRate   float64   `json:"error_rate"`
+}
+
+// WorkflowErrorRate is the error rate series of one workflow name.
+type WorkflowErrorRate struct {
+	WorkflowName string            `json:"workflow_name"`
+	Buckets      []ErrorRateBucket `json:"buckets"`
+}
+
+// ErrorRate is the error rate over time of a service's spans, and of each of
+// its workflows' runs. Buckets without spans are omitted.
+type ErrorRate struct {
+	ServiceName   string              `json:"service_name"`
+	BucketSeconds int64               `json:"bucket_seconds"`
+	Buckets       []ErrorRateBucket   `json:"buckets"`
+	Workflows     []WorkflowErrorRate `json:"workflows"`
+}
+
+// GetErrorRate returns the bucketed error rate of a service, computed from the
+// span status codes. The bucket query parameter sets the bucket width as a Go
+// duration (default 1h, minimum 1m). Without a start_time filter, the last 24
+// hours are returned.
+func GetErrorRate(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetErrorRate function for service: %s", serviceName)
+
+	bucket := defaultErrorRateBucket
+	if raw := c.QueryParam("bucket"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < minErrorRateBucket {
+			return apierror.New(http.StatusBadRequest, fmt.Sprintf("bucket must be a duration of at least %s", minErrorRateBucket))
+		}
+		bucket = parsed
+	}
+
+	filters, err := parseSpanFilters(c, 3)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	if c.QueryParam("start_time") == "" {
+		filters.add("start_time >= %s", time.Now().Add(-defaultErrorRateRange).UTC())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, bucket.Microseconds()}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryErrorRate), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	result := ErrorRate{
+		ServiceName:   serviceName,
+		BucketSeconds: int64(bucket.Seconds()),
+		Buckets:       []ErrorRateBucket{},
+		Workflows:     []WorkflowErrorRate{},
+	}
+	for rows.Next() {
+		var isWorkflow bool
+		var workflowName string
+		var b ErrorRateBucket
+		if err := rows.Scan(&isWorkflow, &workflowName, &b.BucketStart, &b.Total, &b.Errors); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		b.BucketStart = b.BucketStart.UTC()
+		if b.Total > 0 {
+			b.ErrorRate = float64(b.Errors) / float64(b.Total)
+		}
+
+		if !isWorkflow {
+			result.Buckets = append(result.Buckets, b)
+			continue
+		}
+		// Rows are ordered by workflow name.
+		if n := len(result.Workflows); n == 0 || result.Workflows[n-1].WorkflowName != workflowName {
+			result.Workflows = append(result.Workflows, WorkflowErrorRate{WorkflowName: workflowName})
+		}
+		last := &result.Workflows[len(result.Workflows)-1]
+		last.Buckets = append(last.Buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
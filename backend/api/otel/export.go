@@ -0,0 +1,205 @@
+package api_otel
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_export_spans.sql
+var queryExportSpans string
+
+// Supported export formats.
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatJSONL   = "jsonl"
+	ExportFormatParquet = "parquet"
+)
+
+// ExportRequest selects the spans to export.
+type ExportRequest struct {
+	ServiceName string    `json:"service_name"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Format      string    `json:"format"`
+}
+
+// ExportSpans streams every span of a service that started within
+// [start_time, end_time) as a CSV, JSONL, or Parquet file download.
+func ExportSpans(c echo.Context) error {
+	var req ExportRequest
+	if err := c.Bind(&req); err != nil {
+		return apierror.New(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+	}
+	if req.ServiceName == "" {
+		return apierror.New(http.StatusBadRequest, "service_name is required")
+	}
+	if req.StartTime.IsZero() || req.EndTime.IsZero() {
+		return apierror.New(http.StatusBadRequest, "start_time and end_time are required")
+	}
+	if !req.EndTime.After(req.StartTime) {
+		return apierror.New(http.StatusBadRequest, "end_time must be after start_time")
+	}
+	req.Format = strings.ToLower(req.Format)
+	c.Logger().Printf("Running ExportSpans function for service %s (%s)", req.ServiceName, req.Format)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	filename := fmt.Sprintf("%s_spans_%s.%s", sanitizeFilename(req.ServiceName), req.StartTime.UTC().Format("20060102T150405Z"), req.Format)
+
+	switch req.Format {
+	case ExportFormatParquet:
+		return exportParquet(c, req, filename)
+	case ExportFormatCSV, ExportFormatJSONL:
+	default:
+		return apierror.New(http.StatusBadRequest, fmt.Sprintf("unsupported format %q (expected csv, jsonl, or parquet)", req.Format))
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), queryExportSpans, req.ServiceName, req.StartTime, req.EndTime)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		c.Logger().Printf("Error getting columns: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to get columns: %v", err))
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range columns {
+		valuePtrs[i] = &values[i]
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+	// Rows are written as they are scanned so large exports never have to be
+	// held in memory. Once the first byte is sent the status can't change, so
+	// later errors are logged and the stream is cut short.
+	if req.Format == ExportFormatCSV {
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		res.WriteHeader(http.StatusOK)
+
+		w := csv.NewWriter(res)
+		if err := w.Write(columns); err != nil {
+			return err
+		}
+		record := make([]string, len(columns))
+		for rows.Next() {
+			if err := rows.Scan(valuePtrs...); err != nil {
+				c.Logger().Printf("Error scanning row: %v", err)
+				return err
+			}
+			for i := range values {
+				record[i] = csvValue(values[i])
+			}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(res)
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return err
+		}
+		rowMap := make(map[string]interface{}, len(columns))
+		for i, colName := range columns {
+			rowMap[colName] = values[i]
+		}
+		if err := enc.Encode(rowMap); err != nil {
+			return err
+		}
+		res.Flush()
+	}
+	return rows.Err()
+}
+
+// exportParquet has DuckDB write the spans to a temporary Parquet file, which
+// is then streamed to the client.
+func exportParquet(c echo.Context, req ExportRequest, filename string) error {
+	tmp, err := os.CreateTemp("", "junjo-export-*.parquet")
+	if err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to create export file: %v", err))
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	// DuckDB does not accept bound parameters in COPY statements, so the
+	// filter values are inlined as escaped literals.
+	query := fmt.Sprintf(
+		"COPY (SELECT * FROM spans WHERE service_name = %s AND start_time >= TIMESTAMPTZ %s AND start_time < TIMESTAMPTZ %s ORDER BY start_time ASC) TO %s (FORMAT PARQUET, COMPRESSION ZSTD)",
+		quoteLiteral(req.ServiceName),
+		quoteLiteral(req.StartTime.UTC().Format(time.RFC3339Nano)),
+		quoteLiteral(req.EndTime.UTC().Format(time.RFC3339Nano)),
+		quoteLiteral(tmpPath),
+	)
+	if _, err := db_duckdb.DB.ExecContext(c.Request().Context(), query); err != nil {
+		c.Logger().Printf("Error exporting parquet: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("parquet export failed: %v", err))
+	}
+
+	return c.Attachment(tmpPath, filename)
+}
+
+// csvValue renders a scanned DuckDB value as a CSV field. JSON columns and
+// other structured values are encoded as JSON.
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return fmt.Sprint(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// sanitizeFilename replaces characters that are unsafe in a download filename.
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// quoteLiteral quotes s as a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,93 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_workflow_failures.sql
+var queryWorkflowFailures string
+
+const (
+	defaultFailuresLimit = 50
+	maxFailuresLimit     = 500
+)
+
+// FailedWorkflowRun is a workflow run that ended with an error status, or whose
+// trace has a span with an error status or an exception event. The failing
+// node is the earliest failing node span of the trace, or the earliest failing
+// span when no node failed.
+type FailedWorkflowRun struct {
+	TraceID       string    `json:"trace_id"`
+	SpanID        string    `json:"span_id"`
+	WorkflowName  *string   `json:"workflow_name"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	DurationMs    int64     `json:"duration_ms"`
+	StatusCode    *string   `json:"status_code"`
+	FailingSpanID *string   `json:"failing_span_id"`
+	FailingNode   *string   `json:"failing_node"`
+	ErrorType     *string   `json:"error_type"`
+	ErrorMessage  string    `json:"error_message"`
+}
+
+// GetWorkflowFailures returns the failed workflow runs of a service, most
+// recent first, with the failing node and its error message. The number of
+// runs is set with the limit query parameter (default 50, max 500), and the
+// span filter query parameters apply to the workflow spans.
+func GetWorkflowFailures(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetWorkflowFailures function for service: %s", serviceName)
+
+	limit := defaultFailuresLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return apierror.New(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(parsed, maxFailuresLimit)
+	}
+
+	filters, err := parseSpanFilters(c, 3)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, limit}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryWorkflowFailures), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	runs := []FailedWorkflowRun{}
+	for rows.Next() {
+		var run FailedWorkflowRun
+		if err := rows.Scan(&run.TraceID, &run.SpanID, &run.WorkflowName, &run.StartTime, &run.EndTime, &run.DurationMs, &run.StatusCode, &run.FailingSpanID, &run.FailingNode, &run.ErrorType, &run.ErrorMessage); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
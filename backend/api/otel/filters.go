@@ -0,0 +1,115 @@
+package api_otel
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// attributeFilterPrefix marks query parameters that filter on attributes_json,
+// e.g. ?attr.llm.model_name=gpt-4o.
+const attributeFilterPrefix = "attr."
+
+// filtersMarker is replaced by the span filter predicates in listing queries.
+const filtersMarker = "/* filters */"
+
+// spanFilters are the optional filters accepted by the span listing endpoints.
+type spanFilters struct {
+	firstParam int
+	conditions []string
+	args       []interface{}
+}
+
+// add appends a condition. Each %s verb in condition is replaced with the
+// placeholder of the matching arg.
+func (f *spanFilters) add(condition string, args ...interface{}) {
+	placeholders := make([]interface{}, len(args))
+	for i := range args {
+		placeholders[i] = fmt.Sprintf("$%d", f.firstParam+len(f.args)+i)
+	}
+	f.conditions = append(f.conditions, fmt.Sprintf(condition, placeholders...))
+	f.args = append(f.args, args...)
+}
+
+// parseSpanFilters reads the filter query parameters:
+//   - start_time / end_time (RFC 3339): spans that started within [start_time, end_time)
+//   - status_code: e.g. ERROR or STATUS_CODE_ERROR
+//   - kind: e.g. SERVER
+//   - junjo_span_type: e.g. workflow
+//   - has_error (true or false): root and workflow spans of traces with an error span
+//   - attr.<key>=<value>: the attribute key equals value
+//
+// Placeholders are numbered from firstParam, following the query's own
+// positional parameters.
+func parseSpanFilters(c echo.Context, firstParam int) (*spanFilters, error) {
+	f := spanFilters{firstParam: firstParam}
+
+	for _, param := range []struct {
+		name      string
+		condition string
+	}{
+		{"start_time", "start_time >= %s"},
+		{"end_time", "start_time < %s"},
+	} {
+		raw := c.QueryParam(param.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", param.name)
+		}
+		f.add(param.condition, t)
+	}
+
+	if statusCode := strings.ToUpper(c.QueryParam("status_code")); statusCode != "" {
+		if !strings.HasPrefix(statusCode, "STATUS_CODE_") {
+			statusCode = "STATUS_CODE_" + statusCode
+		}
+		f.add("status_code = %s", statusCode)
+	}
+	if kind := strings.ToUpper(c.QueryParam("kind")); kind != "" {
+		f.add("kind = %s", kind)
+	}
+	if spanType := c.QueryParam("junjo_span_type"); spanType != "" {
+		f.add("junjo_span_type = %s", spanType)
+	}
+
+	if raw := c.QueryParam("has_error"); raw != "" {
+		hasError, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("has_error must be true or false")
+		}
+		f.add("has_error = %s", hasError)
+	}
+
+	// Sort the attribute keys so the generated SQL is stable.
+	params := c.QueryParams()
+	keys := make([]string, 0)
+	for name := range params {
+		if strings.HasPrefix(name, attributeFilterPrefix) && len(name) > len(attributeFilterPrefix) {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		for _, value := range params[name] {
+			f.add("json_extract_string(attributes_json, %s::VARCHAR) = %s::VARCHAR", strings.TrimPrefix(name, attributeFilterPrefix), value)
+		}
+	}
+
+	return &f, nil
+}
+
+// apply replaces the filters marker in query with the filter predicates.
+func (f *spanFilters) apply(query string) string {
+	where := ""
+	for _, condition := range f.conditions {
+		where += "AND " + condition + "\n  "
+	}
+	return strings.Replace(query, filtersMarker, where, 1)
+}
@@ -0,0 +1,130 @@
+package api_otel
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_executed_nodes.sql
+var queryExecutedNodes string
+
+// queryWorkflowGraph selects the declared graph of a workflow or subflow span.
+const queryWorkflowGraph = `
+	SELECT trace_id, COALESCE(junjo_wf_graph_structure::VARCHAR, '{}')
+	FROM spans
+	WHERE span_id = ? AND junjo_span_type IN ('workflow', 'subflow')
+	LIMIT 1;`
+
+// GraphNode is a node declared in a workflow's graph structure.
+type GraphNode struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// ExecutedNode is a Junjo node, subflow or concurrent group that ran within a
+// workflow.
+type ExecutedNode struct {
+	JunjoID     string  `json:"junjo_id"`
+	SpanType    string  `json:"junjo_span_type"`
+	Name        *string `json:"name"`
+	FirstSpanID string  `json:"first_span_id"`
+	Executions  int64   `json:"executions"`
+}
+
+// GraphCoverage compares the nodes a workflow declared in its graph structure
+// with the nodes that were executed.
+type GraphCoverage struct {
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+	// Unvisited nodes were declared but never executed: dead branches, or
+	// branches not taken by this execution.
+	Unvisited []GraphNode `json:"unvisited_nodes"`
+	// Unexpected nodes were executed but are missing from the graph, which
+	// usually points to an instrumentation gap.
+	Unexpected []ExecutedNode `json:"unexpected_nodes"`
+	Executed   []ExecutedNode `json:"executed_nodes"`
+	Declared   int            `json:"declared_count"`
+}
+
+// GetWorkflowGraphCoverage compares the graph structure reported by a workflow
+// or subflow span (junjo_wf_graph_structure) with the nodes executed beneath
+// it. Nodes are matched by their Junjo id.
+func GetWorkflowGraphCoverage(c echo.Context) error {
+	spanID := c.Param("spanId")
+	if spanID == "" {
+		return apierror.New(http.StatusBadRequest, "spanId parameter is required")
+	}
+	c.Logger().Printf("Running GetWorkflowGraphCoverage function for span %s", spanID)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	ctx := c.Request().Context()
+
+	var traceID, graphJSON string
+	err := db.QueryRowContext(ctx, queryWorkflowGraph, spanID).Scan(&traceID, &graphJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.New(http.StatusNotFound, "workflow span not found")
+	}
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+
+	var graph struct {
+		Nodes []GraphNode `json:"nodes"`
+	}
+	if err := json.Unmarshal([]byte(graphJSON), &graph); err != nil {
+		return apierror.New(http.StatusUnprocessableEntity, "graph structure is not valid JSON")
+	}
+
+	rows, err := db.QueryContext(ctx, queryExecutedNodes, traceID, spanID)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	coverage := GraphCoverage{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		Unvisited:  []GraphNode{},
+		Unexpected: []ExecutedNode{},
+		Executed:   []ExecutedNode{},
+		Declared:   len(graph.Nodes),
+	}
+	declared := map[string]bool{}
+	for _, node := range graph.Nodes {
+		declared[node.ID] = true
+	}
+	executed := map[string]bool{}
+	for rows.Next() {
+		var node ExecutedNode
+		if err := rows.Scan(&node.JunjoID, &node.SpanType, &node.Name, &node.FirstSpanID, &node.Executions); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		executed[node.JunjoID] = true
+		coverage.Executed = append(coverage.Executed, node)
+		if !declared[node.JunjoID] {
+			coverage.Unexpected = append(coverage.Unexpected, node)
+		}
+	}
+	for _, node := range graph.Nodes {
+		if !executed[node.ID] {
+			coverage.Unvisited = append(coverage.Unvisited, node)
+		}
+	}
+
+	return c.JSON(http.StatusOK, coverage)
+}
@@ -0,0 +1,173 @@
+package api_otel
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GraphEdge is an edge declared in a workflow's graph structure.
+type GraphEdge struct {
+	ID        string  `json:"id"`
+	Source    string  `json:"source"`
+	Target    string  `json:"target"`
+	Condition *string `json:"condition"`
+}
+
+// parsedGraph is the part of a graph structure compared by GetGraphDiff.
+type parsedGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphDiff lists the nodes and edges added and removed between two graph
+// versions. Added nodes and edges use the ids of the newer version, removed
+// ones those of the older version.
+type GraphDiff struct {
+	FromHash     string      `json:"from_hash"`
+	ToHash       string      `json:"to_hash"`
+	AddedNodes   []GraphNode `json:"added_nodes"`
+	RemovedNodes []GraphNode `json:"removed_nodes"`
+	AddedEdges   []GraphEdge `json:"added_edges"`
+	RemovedEdges []GraphEdge `json:"removed_edges"`
+}
+
+// GetGraphDiff compares two graph versions, from graphHash to otherGraphHash.
+// Node ids can be regenerated between deployments, so nodes are matched by id
+// first, then the remaining nodes by type and label. Edges are matched by their
+// matched source and target nodes and their condition.
+func GetGraphDiff(c echo.Context) error {
+	fromHash := c.Param("graphHash")
+	toHash := c.Param("otherGraphHash")
+	if fromHash == "" || toHash == "" {
+		return apierror.New(http.StatusBadRequest, "graphHash and otherGraphHash parameters are required")
+	}
+	c.Logger().Printf("Running GetGraphDiff function for graphs %s and %s", fromHash, toHash)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	from, err := loadGraph(c.Request().Context(), db, fromHash)
+	if err != nil {
+		return graphLoadError(c, fromHash, err)
+	}
+	to, err := loadGraph(c.Request().Context(), db, toHash)
+	if err != nil {
+		return graphLoadError(c, toHash, err)
+	}
+
+	return c.JSON(http.StatusOK, diffGraphs(fromHash, toHash, from, to))
+}
+
+// loadGraph reads and parses the graph structure of a graph version.
+func loadGraph(ctx context.Context, db *sql.DB, graphHash string) (parsedGraph, error) {
+	var structure string
+	if err := db.QueryRowContext(ctx, queryGraphStructure, graphHash).Scan(&structure); err != nil {
+		return parsedGraph{}, err
+	}
+	var parsed parsedGraph
+	if err := json.Unmarshal([]byte(structure), &parsed); err != nil {
+		return parsedGraph{}, fmt.Errorf("graph structure is not valid JSON: %w", err)
+	}
+	return parsed, nil
+}
+
+func graphLoadError(c echo.Context, graphHash string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.New(http.StatusNotFound, fmt.Sprintf("graph version %s not found", graphHash))
+	}
+	c.Logger().Printf("Error loading graph %s: %v", graphHash, err)
+	return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to load graph %s: %v", graphHash, err))
+}
+
+// diffGraphs compares two parsed graph structures.
+func diffGraphs(fromHash, toHash string, from, to parsedGraph) GraphDiff {
+	diff := GraphDiff{
+		FromHash:     fromHash,
+		ToHash:       toHash,
+		AddedNodes:   []GraphNode{},
+		RemovedNodes: []GraphNode{},
+		AddedEdges:   []GraphEdge{},
+		RemovedEdges: []GraphEdge{},
+	}
+
+	// Match nodes: identical ids first, then by type and label in order
+	matched := map[string]string{} // From node id to its matching to node id
+	toIDs := map[string]bool{}
+	for _, node := range to.Nodes {
+		toIDs[node.ID] = true
+	}
+	taken := map[string]bool{}
+	for _, node := range from.Nodes {
+		if toIDs[node.ID] && !taken[node.ID] {
+			matched[node.ID] = node.ID
+			taken[node.ID] = true
+		}
+	}
+	unmatchedTo := map[string][]string{}
+	for _, node := range to.Nodes {
+		if !taken[node.ID] {
+			key := node.Type + "\x00" + node.Label
+			unmatchedTo[key] = append(unmatchedTo[key], node.ID)
+		}
+	}
+	for _, node := range from.Nodes {
+		if _, ok := matched[node.ID]; ok {
+			continue
+		}
+		key := node.Type + "\x00" + node.Label
+		if candidates := unmatchedTo[key]; len(candidates) > 0 {
+			matched[node.ID] = candidates[0]
+			taken[candidates[0]] = true
+			unmatchedTo[key] = candidates[1:]
+		} else {
+			diff.RemovedNodes = append(diff.RemovedNodes, node)
+		}
+	}
+	for _, node := range to.Nodes {
+		if !taken[node.ID] {
+			diff.AddedNodes = append(diff.AddedNodes, node)
+		}
+	}
+
+	// Match edges by their endpoints, translated to the to graph's ids
+	edgeKey := func(source, target string, condition *string) string {
+		key := source + "\x00" + target + "\x00"
+		if condition != nil {
+			key += "=" + *condition
+		}
+		return key
+	}
+	remaining := map[string]int{}
+	for _, edge := range to.Edges {
+		remaining[edgeKey(edge.Source, edge.Target, edge.Condition)]++
+	}
+	for _, edge := range from.Edges {
+		source, sourceOK := matched[edge.Source]
+		target, targetOK := matched[edge.Target]
+		key := edgeKey(source, target, edge.Condition)
+		if sourceOK && targetOK && remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		diff.RemovedEdges = append(diff.RemovedEdges, edge)
+	}
+	for _, edge := range to.Edges {
+		key := edgeKey(edge.Source, edge.Target, edge.Condition)
+		if remaining[key] > 0 {
+			remaining[key]--
+			diff.AddedEdges = append(diff.AddedEdges, edge)
+		}
+	}
+
+	return diff
+}
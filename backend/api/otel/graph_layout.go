@@ -0,0 +1,307 @@
+package api_otel
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_node_run_stats.sql
+var queryNodeRunStats string
+
+// Layout spacing, in layout units. Layers run left to right.
+const (
+	layoutLayerSpacing = 240
+	layoutNodeSpacing  = 120
+)
+
+// layoutGraphNode is a graph structure node with the fields used for layout.
+type layoutGraphNode struct {
+	GraphNode
+	Children []string `json:"children"`
+}
+
+// NodeRunStats are the durations and outcomes of a node's executions within a
+// workflow run.
+type NodeRunStats struct {
+	Executions int64   `json:"executions"`
+	ErrorCount int64   `json:"error_count"`
+	TotalMs    int64   `json:"total_ms"`
+	AvgMs      float64 `json:"avg_ms"`
+	MaxMs      int64   `json:"max_ms"`
+}
+
+// LayoutNode is a graph node placed in a layer. X and Y are the node's center.
+// Stats are nil for nodes that did not run.
+type LayoutNode struct {
+	GraphNode
+	Layer int           `json:"layer"`
+	X     float64       `json:"x"`
+	Y     float64       `json:"y"`
+	Stats *NodeRunStats `json:"stats"`
+}
+
+// LayoutEdge is a graph edge. Back edges close a loop and point to an earlier
+// layer.
+type LayoutEdge struct {
+	GraphEdge
+	BackEdge bool `json:"back_edge"`
+}
+
+// GraphLayout is a layered layout of a workflow's graph structure.
+type GraphLayout struct {
+	TraceID string       `json:"trace_id"`
+	SpanID  string       `json:"span_id"`
+	Width   float64      `json:"width"`
+	Height  float64      `json:"height"`
+	Nodes   []LayoutNode `json:"nodes"`
+	Edges   []LayoutEdge `json:"edges"`
+}
+
+// GetWorkflowGraphLayout lays out the graph structure of a workflow or subflow
+// span in layers, left to right, and annotates each node with the latency and
+// errors of its executions beneath the span. Loops are broken by marking their
+// back edges; the children of concurrent groups are stacked in their group's
+// layer.
+func GetWorkflowGraphLayout(c echo.Context) error {
+	spanID := c.Param("spanId")
+	if spanID == "" {
+		return apierror.New(http.StatusBadRequest, "spanId parameter is required")
+	}
+	c.Logger().Printf("Running GetWorkflowGraphLayout function for span %s", spanID)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	ctx := c.Request().Context()
+
+	var traceID, graphJSON string
+	err := db.QueryRowContext(ctx, queryWorkflowGraph, spanID).Scan(&traceID, &graphJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.New(http.StatusNotFound, "workflow span not found")
+	}
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+
+	var structure struct {
+		Nodes []layoutGraphNode `json:"nodes"`
+		Edges []GraphEdge       `json:"edges"`
+	}
+	if err := json.Unmarshal([]byte(graphJSON), &structure); err != nil {
+		return apierror.New(http.StatusUnprocessableEntity, "graph structure is not valid JSON")
+	}
+
+	rows, err := db.QueryContext(ctx, queryNodeRunStats, traceID, spanID)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	stats := map[string]*NodeRunStats{}
+	for rows.Next() {
+		var junjoID string
+		var row NodeRunStats
+		if err := rows.Scan(&junjoID, &row.Executions, &row.ErrorCount, &row.TotalMs, &row.MaxMs); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		row.AvgMs = float64(row.TotalMs) / float64(row.Executions)
+		stats[junjoID] = &row
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	layout := layoutGraph(structure.Nodes, structure.Edges)
+	layout.TraceID = traceID
+	layout.SpanID = spanID
+	for i := range layout.Nodes {
+		layout.Nodes[i].Stats = stats[layout.Nodes[i].ID]
+	}
+
+	return c.JSON(http.StatusOK, layout)
+}
+
+// layoutGraph assigns each node to the layer of its longest path from a source
+// node, after breaking loops, and orders each layer by the average position of
+// the nodes' predecessors.
+func layoutGraph(nodes []layoutGraphNode, edges []GraphEdge) GraphLayout {
+	index := map[string]int{}
+	for i, node := range nodes {
+		if _, ok := index[node.ID]; !ok {
+			index[node.ID] = i
+		}
+	}
+	// Children of concurrent groups are placed with their group
+	group := map[string]string{}
+	for _, node := range nodes {
+		for _, child := range node.Children {
+			if _, ok := index[child]; ok && child != node.ID {
+				group[child] = node.ID
+			}
+		}
+	}
+
+	successors := map[string][]int{}
+	for i, edge := range edges {
+		successors[edge.Source] = append(successors[edge.Source], i)
+	}
+
+	// Break loops: an edge to a node on the depth-first search stack is a back
+	// edge
+	backEdges := map[int]bool{}
+	state := map[string]int{} // 1: on the stack, 2: done
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = 1
+		for _, i := range successors[id] {
+			target := edges[i].Target
+			if _, ok := index[target]; !ok {
+				continue
+			}
+			switch state[target] {
+			case 0:
+				visit(target)
+			case 1:
+				backEdges[i] = true
+			}
+		}
+		state[id] = 2
+	}
+	for _, node := range nodes {
+		if state[node.ID] == 0 {
+			visit(node.ID)
+		}
+	}
+
+	// Longest-path layering over the remaining edges, in topological order
+	inDegree := map[string]int{}
+	for i, edge := range edges {
+		_, sourceOK := index[edge.Source]
+		_, targetOK := index[edge.Target]
+		if sourceOK && targetOK && !backEdges[i] {
+			inDegree[edge.Target]++
+		}
+	}
+	layer := map[string]int{}
+	queue := []string{}
+	for _, node := range nodes {
+		if inDegree[node.ID] == 0 {
+			queue = append(queue, node.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, i := range successors[id] {
+			target := edges[i].Target
+			if _, ok := index[target]; !ok || backEdges[i] {
+				continue
+			}
+			layer[target] = max(layer[target], layer[id]+1)
+			inDegree[target]--
+			if inDegree[target] == 0 {
+				queue = append(queue, target)
+			}
+		}
+	}
+	for child, parent := range group {
+		layer[child] = layer[parent]
+	}
+
+	// Order each layer by the average position of the predecessors in earlier
+	// layers, then by declaration order, with group children after their group
+	layers := [][]string{}
+	for i, node := range nodes {
+		if index[node.ID] != i {
+			continue // Duplicate id
+		}
+		l := layer[node.ID]
+		for len(layers) <= l {
+			layers = append(layers, []string{})
+		}
+		layers[l] = append(layers[l], node.ID)
+	}
+	position := map[string]float64{}
+	predecessors := map[string][]string{}
+	for i, edge := range edges {
+		if !backEdges[i] {
+			predecessors[edge.Target] = append(predecessors[edge.Target], edge.Source)
+		}
+	}
+	for l, ids := range layers {
+		weight := func(id string) float64 {
+			sum, count := 0.0, 0
+			for _, pred := range predecessors[id] {
+				if p, ok := position[pred]; ok && layer[pred] < l {
+					sum += p
+					count++
+				}
+			}
+			if count == 0 {
+				return float64(index[id])
+			}
+			return sum / float64(count)
+		}
+		weights := map[string]float64{}
+		for _, id := range ids {
+			if _, ok := group[id]; !ok {
+				weights[id] = weight(id)
+			}
+		}
+		sort.SliceStable(ids, func(i, j int) bool {
+			return sortWeight(ids[i], group, weights) < sortWeight(ids[j], group, weights)
+		})
+		for i, id := range ids {
+			position[id] = float64(i)
+		}
+	}
+
+	layout := GraphLayout{
+		Nodes: []LayoutNode{},
+		Edges: []LayoutEdge{},
+	}
+	tallest := 0
+	for _, ids := range layers {
+		tallest = max(tallest, len(ids))
+	}
+	for l, ids := range layers {
+		offset := float64(tallest-len(ids)) * layoutNodeSpacing / 2
+		for i, id := range ids {
+			layout.Nodes = append(layout.Nodes, LayoutNode{
+				GraphNode: nodes[index[id]].GraphNode,
+				Layer:     l,
+				X:         float64(l * layoutLayerSpacing),
+				Y:         offset + float64(i*layoutNodeSpacing),
+			})
+		}
+	}
+	for i, edge := range edges {
+		layout.Edges = append(layout.Edges, LayoutEdge{GraphEdge: edge, BackEdge: backEdges[i]})
+	}
+	if len(layers) > 0 {
+		layout.Width = float64((len(layers) - 1) * layoutLayerSpacing)
+		layout.Height = float64((tallest - 1) * layoutNodeSpacing)
+	}
+	return layout
+}
+
+// sortWeight orders group children right after their group.
+func sortWeight(id string, group map[string]string, weights map[string]float64) float64 {
+	if parent, ok := group[id]; ok {
+		return weights[parent] + 0.5
+	}
+	return weights[id]
+}
@@ -0,0 +1,108 @@
+package api_otel
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_graph_versions.sql
+var queryGraphVersions string
+
+// queryGraphStructure selects a graph structure by its content hash.
+const queryGraphStructure = `
+	SELECT graph_structure::VARCHAR
+	FROM graph_versions
+	WHERE graph_hash = ?
+	LIMIT 1;`
+
+// GraphVersion is a distinct graph structure reported by runs of a workflow or
+// subflow, identified by its content hash.
+type GraphVersion struct {
+	GraphHash    string    `json:"graph_hash"`
+	WorkflowName string    `json:"workflow_name"`
+	NodeCount    *int64    `json:"node_count"`
+	EdgeCount    *int64    `json:"edge_count"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	RunCount     int64     `json:"run_count"`
+}
+
+// GraphStructure is the graph structure of a graph version.
+type GraphStructure struct {
+	GraphHash      string          `json:"graph_hash"`
+	GraphStructure json.RawMessage `json:"graph_structure"`
+}
+
+// GetGraphVersions lists the graph structure versions of a service's workflows,
+// grouped by workflow name with the most recently seen version first. The
+// workflowName path parameter, when given, limits the list to one workflow.
+func GetGraphVersions(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	workflowName := c.Param("workflowName")
+	c.Logger().Printf("Running GetGraphVersions function for service %s and workflow %q", serviceName, workflowName)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), queryGraphVersions, serviceName, workflowName)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	versions := []GraphVersion{}
+	for rows.Next() {
+		var version GraphVersion
+		if err := rows.Scan(&version.GraphHash, &version.WorkflowName, &version.NodeCount, &version.EdgeCount, &version.FirstSeen, &version.LastSeen, &version.RunCount); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, versions)
+}
+
+// GetGraphStructure returns the graph structure of a graph version.
+func GetGraphStructure(c echo.Context) error {
+	graphHash := c.Param("graphHash")
+	if graphHash == "" {
+		return apierror.New(http.StatusBadRequest, "graphHash parameter is required")
+	}
+	c.Logger().Printf("Running GetGraphStructure function for graph: %s", graphHash)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	var structure string
+	err := db.QueryRowContext(c.Request().Context(), queryGraphStructure, graphHash).Scan(&structure)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.New(http.StatusNotFound, "graph version not found")
+	}
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, GraphStructure{GraphHash: graphHash, GraphStructure: json.RawMessage(structure)})
+}
@@ -0,0 +1,116 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_duration_histogram.sql
+var queryDurationHistogram string
+
+const (
+	defaultHistogramBuckets = 20
+	maxHistogramBuckets     = 200
+)
+
+// HistogramBucket counts the runs with a duration in [LowerMs, UpperMs). The
+// last bucket includes UpperMs.
+type HistogramBucket struct {
+	LowerMs float64 `json:"lower_ms"`
+	UpperMs float64 `json:"upper_ms"`
+	Count   int64   `json:"count"`
+}
+
+// DurationHistogram is the distribution of workflow run durations. Buckets
+// have equal widths between the shortest and longest run. There are no
+// buckets when there are no runs.
+type DurationHistogram struct {
+	ServiceName string            `json:"service_name"`
+	Total       int64             `json:"total"`
+	MinMs       *int64            `json:"min_ms"`
+	MaxMs       *int64            `json:"max_ms"`
+	Buckets     []HistogramBucket `json:"buckets"`
+}
+
+// GetDurationHistogram returns a histogram of the workflow run durations of a
+// service. The buckets query parameter sets the number of buckets (default 20,
+// max 200), workflow_name restricts it to one workflow, and the time range is
+// set with the span filter query parameters.
+func GetDurationHistogram(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetDurationHistogram function for service: %s", serviceName)
+
+	buckets := defaultHistogramBuckets
+	if raw := c.QueryParam("buckets"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return apierror.New(http.StatusBadRequest, "buckets must be a positive integer")
+		}
+		buckets = min(parsed, maxHistogramBuckets)
+	}
+
+	filters, err := parseSpanFilters(c, 3)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	if workflowName := c.QueryParam("workflow_name"); workflowName != "" {
+		filters.add("name = %s", workflowName)
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, buckets}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryDurationHistogram), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	histogram := DurationHistogram{
+		ServiceName: serviceName,
+		Buckets:     []HistogramBucket{},
+	}
+	counts := make([]int64, buckets)
+	for rows.Next() {
+		var minMs, maxMs int64
+		var bucket int
+		var count int64
+		if err := rows.Scan(&minMs, &maxMs, &bucket, &count); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		histogram.MinMs, histogram.MaxMs = &minMs, &maxMs
+		counts[bucket] = count
+		histogram.Total += count
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	if histogram.MinMs != nil {
+		lower := float64(*histogram.MinMs)
+		width := float64(*histogram.MaxMs-*histogram.MinMs) / float64(buckets)
+		for i, count := range counts {
+			histogram.Buckets = append(histogram.Buckets, HistogramBucket{
+				LowerMs: lower + float64(i)*width,
+				UpperMs: lower + float64(i+1)*width,
+				Count:   count,
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, histogram)
+}
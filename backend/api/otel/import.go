@@ -0,0 +1,207 @@
+package api_otel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"junjo-server/apierror"
+	"junjo-server/importers"
+	"junjo-server/telemetry"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ImportSpans backfills spans from an OTLP file, writing them directly into
+// DuckDB. The file is sent as the request body, or as the "file" field of a
+// multipart form. Supported encodings:
+//   - OTLP/JSON (application/json): a single ExportTraceServiceRequest, or one
+//     per line as written by the OpenTelemetry Collector file exporter.
+//   - OTLP/protobuf (application/x-protobuf): a single ExportTraceServiceRequest.
+//
+// Gzip-compressed files are detected automatically. Files larger than the
+// import limit, compressed or not, are rejected with 413.
+func ImportSpans(c echo.Context) error {
+	c.Logger().Printf("Running ImportSpans function")
+
+	data, contentType, err := readImport(c)
+	if err != nil {
+		return err
+	}
+
+	var requests []*coltracepb.ExportTraceServiceRequest
+	if strings.Contains(contentType, "protobuf") {
+		request := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(data, request); err != nil {
+			return apierror.New(http.StatusBadRequest, fmt.Sprintf("invalid OTLP protobuf: %v", err))
+		}
+		requests = append(requests, request)
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return apierror.New(http.StatusBadRequest, fmt.Sprintf("invalid OTLP JSON: %v", err))
+			}
+			request, err := telemetry.UnmarshalOTLPJSON(raw)
+			if err != nil {
+				return apierror.New(http.StatusBadRequest, fmt.Sprintf("invalid OTLP JSON: %v", err))
+			}
+			requests = append(requests, request)
+		}
+	}
+
+	imported := 0
+	for _, request := range requests {
+		n, err := telemetry.ImportTraces(c.Request().Context(), request)
+		imported += n
+		if err != nil {
+			c.Logger().Printf("Error importing spans: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("import failed: %v", err)).WithDetails(map[string]int{
+				"imported_spans": imported,
+			})
+		}
+	}
+
+	c.Logger().Printf("Imported %d spans", imported)
+	return c.JSON(http.StatusOK, map[string]int{"imported_spans": imported})
+}
+
+// maxImportBytes caps the size of import files, after decompression.
+var maxImportBytes int64 = 256 << 20
+
+// errImportTooLarge is returned for files over maxImportBytes.
+var errImportTooLarge = errors.New("file too large")
+
+// SetImportLimit sets the largest import file accepted, in bytes.
+func SetImportLimit(limit int) {
+	maxImportBytes = int64(limit)
+}
+
+// readImport reads and decompresses the uploaded file, returning it with its
+// content type, or an error response.
+func readImport(c echo.Context) ([]byte, string, error) {
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxImportBytes)
+
+	body, contentType, err := importBody(c)
+	if err != nil {
+		if tooLarge(err) {
+			return nil, "", importTooLarge()
+		}
+		return nil, "", apierror.New(http.StatusBadRequest, err.Error())
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if tooLarge(err) {
+		return nil, "", importTooLarge()
+	} else if err != nil {
+		return nil, "", apierror.New(http.StatusBadRequest, fmt.Sprintf("failed to read file: %v", err))
+	}
+	data, err = gunzipIfNeeded(data, maxImportBytes)
+	if tooLarge(err) {
+		return nil, "", importTooLarge()
+	} else if err != nil {
+		return nil, "", apierror.New(http.StatusBadRequest, fmt.Sprintf("failed to decompress file: %v", err))
+	}
+	return data, contentType, nil
+}
+
+// tooLarge reports whether err is due to a file over the import limit.
+func tooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr) || errors.Is(err, errImportTooLarge)
+}
+
+func importTooLarge() error {
+	return apierror.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds the import limit of %d bytes", maxImportBytes))
+}
+
+// importBody returns the uploaded file and its content type, from either a
+// multipart form or the raw request body.
+func importBody(c echo.Context) (io.ReadCloser, string, error) {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	if !strings.HasPrefix(contentType, echo.MIMEMultipartForm) {
+		return c.Request().Body, contentType, nil
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", fmt.Errorf("file is required: %w", err)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open file: %v", err)
+	}
+
+	contentType = fileHeader.Header.Get(echo.HeaderContentType)
+	if strings.HasSuffix(fileHeader.Filename, ".pb") || strings.HasSuffix(fileHeader.Filename, ".pb.gz") {
+		contentType = "application/x-protobuf"
+	}
+	return file, contentType, nil
+}
+
+// gunzipIfNeeded decompresses data if it starts with the gzip magic number,
+// failing with errImportTooLarge past limit bytes of output.
+func gunzipIfNeeded(data []byte, limit int64) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, errImportTooLarge
+	}
+	return out, nil
+}
+
+// ImportExternalTraces converts a LangSmith or LangFuse JSON trace export into
+// Junjo spans and writes them directly into DuckDB. The source is taken from
+// the path, and the imported spans are attributed to the service_name query
+// parameter (defaulting to the source name). The file is read the same way as
+// in ImportSpans.
+func ImportExternalTraces(c echo.Context) error {
+	source := c.Param("source")
+	serviceName := c.QueryParam("service_name")
+	if serviceName == "" {
+		serviceName = source
+	}
+	c.Logger().Printf("Running ImportExternalTraces function for source %s", source)
+
+	data, _, err := readImport(c)
+	if err != nil {
+		return err
+	}
+
+	request, err := importers.Convert(source, data, serviceName)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	imported, err := telemetry.ImportTraces(c.Request().Context(), request)
+	if err != nil {
+		c.Logger().Printf("Error importing spans: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("import failed: %v", err)).WithDetails(map[string]int{
+			"imported_spans": imported,
+		})
+	}
+
+	c.Logger().Printf("Imported %d spans from %s", imported, source)
+	return c.JSON(http.StatusOK, map[string]int{"imported_spans": imported})
+}
@@ -0,0 +1,73 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_node_stats.sql
+var queryNodeStats string
+
+// NodeStats are the aggregated durations and outcomes of the runs of a graph
+// node. TotalMs is the time spent in the node across all runs.
+type NodeStats struct {
+	NodeName   string  `json:"node_name"`
+	Count      int64   `json:"count"`
+	ErrorCount int64   `json:"error_count"`
+	TotalMs    int64   `json:"total_ms"`
+	AvgMs      float64 `json:"avg_ms"`
+	P50Ms      float64 `json:"p50_ms"`
+	P90Ms      float64 `json:"p90_ms"`
+	P99Ms      float64 `json:"p99_ms"`
+	MaxMs      int64   `json:"max_ms"`
+}
+
+// GetNodeStats returns the run counts, error counts, and average and
+// percentile durations of a service's graph nodes, grouped by node name, with
+// the nodes taking the most total time first. The time range and other filters
+// are given with the span filter query parameters.
+func GetNodeStats(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetNodeStats function for service: %s", serviceName)
+
+	filters, err := parseSpanFilters(c, 2)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryNodeStats), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	nodes := []NodeStats{}
+	for rows.Next() {
+		var node NodeStats
+		if err := rows.Scan(&node.NodeName, &node.Count, &node.ErrorCount, &node.TotalMs, &node.AvgMs, &node.P50Ms, &node.P90Ms, &node.P99Ms, &node.MaxMs); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, nodes)
+}
@@ -3,6 +3,7 @@ package api_otel
 import (
 	_ "embed"
 	"fmt"
+	"junjo-server/apierror"
 	db_duckdb "junjo-server/db_duckdb"
 	"net/http"
 
@@ -39,7 +40,7 @@ func GetDistinctServiceNames(c echo.Context) error {
 	rows, err := db.Query(queryDistinctServiceNames)
 	if err != nil {
 		c.Logger().Printf("Error querying database: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("database query failed: %v", err)})
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
 	}
 	defer rows.Close()
 
@@ -59,138 +60,107 @@ func GetDistinctServiceNames(c echo.Context) error {
 func GetRootSpans(c echo.Context) error {
 	serviceName := c.Param("serviceName")
 	if serviceName == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "serviceName parameter is required"})
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
 	}
 	c.Logger().Printf("Running GetRootSpans function for service %s", serviceName)
 
+	params, err := parsePageParams(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	filters, err := parseSpanFilters(c, 5)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
 	db := db_duckdb.DB
 	if db == nil {
 		return fmt.Errorf("database connection is nil")
 	}
 
 	// Execute the query
-	rows, err := db.Query(queryRootSpans, serviceName)
+	args := append([]interface{}{serviceName}, params.args()...)
+	rows, err := db.Query(filters.apply(queryRootSpans), append(args, filters.args...)...)
 	if err != nil {
 		c.Logger().Printf("Error querying database: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("database query failed: %v", err)})
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
 	}
 	defer rows.Close()
 
-	// Get Column Names
-	columns, err := rows.Columns()
+	page, err := scanSpanPage(rows, params)
 	if err != nil {
-		c.Logger().Printf("Error getting columns: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to get columns: %v", err)})
+		c.Logger().Printf("Error reading rows: %v", err)
+		return apierror.New(http.StatusInternalServerError, err.Error())
 	}
 
-	// Prepare data structures for dynamic scanning
-	count := len(columns)
-	values := make([]interface{}, count)
-	valuePtrs := make([]interface{}, count)
-	for i := range columns {
-		valuePtrs[i] = &values[i]
-	}
-
-	// Final results storage
-	results := []map[string]interface{}{}
-
-	// Start processing each row
-	for rows.Next() {
-		if err := rows.Scan(valuePtrs...); err != nil {
-			c.Logger().Printf("Error scanning row: %v", err)
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to scan row: %v", err)})
-		}
-
-		// Create a map for the current row
-		rowMap := make(map[string]interface{})
-
-		for i, colName := range columns {
-			rowMap[colName] = values[i]
-		}
-
-		// Append this row to the results
-		results = append(results, rowMap)
-	}
-
-	return c.JSON(http.StatusOK, results)
+	return c.JSON(http.StatusOK, page)
 }
 
 func GetRootSpansFiltered(c echo.Context) error {
 	serviceName := c.Param("serviceName")
 	if serviceName == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "serviceName parameter is required"})
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
 	}
 	c.Logger().Printf("Running GetRootSpansFiltered function for service %s", serviceName)
 
+	params, err := parsePageParams(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	filters, err := parseSpanFilters(c, 5)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
 	db := db_duckdb.DB
 	if db == nil {
 		return fmt.Errorf("database connection is nil")
 	}
 
 	// Execute the query
-	rows, err := db.Query(queryRootSpansFiltered, serviceName)
+	args := append([]interface{}{serviceName}, params.args()...)
+	rows, err := db.Query(filters.apply(queryRootSpansFiltered), append(args, filters.args...)...)
 	if err != nil {
 		c.Logger().Printf("Error querying database: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("database query failed: %v", err)})
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
 	}
 	defer rows.Close()
 
-	// Get Column Names
-	columns, err := rows.Columns()
+	page, err := scanSpanPage(rows, params)
 	if err != nil {
-		c.Logger().Printf("Error getting columns: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to get columns: %v", err)})
-	}
-
-	// Prepare data structures for dynamic scanning
-	count := len(columns)
-	values := make([]interface{}, count)
-	valuePtrs := make([]interface{}, count)
-	for i := range columns {
-		valuePtrs[i] = &values[i]
+		c.Logger().Printf("Error reading rows: %v", err)
+		return apierror.New(http.StatusInternalServerError, err.Error())
 	}
 
-	// Final results storage
-	results := []map[string]interface{}{}
-
-	// Start processing each row
-	for rows.Next() {
-		if err := rows.Scan(valuePtrs...); err != nil {
-			c.Logger().Printf("Error scanning row: %v", err)
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to scan row: %v", err)})
-		}
-
-		// Create a map for the current row
-		rowMap := make(map[string]interface{})
-
-		for i, colName := range columns {
-			rowMap[colName] = values[i]
-		}
-
-		// Append this row to the results
-		results = append(results, rowMap)
-	}
-
-	return c.JSON(http.StatusOK, results)
+	return c.JSON(http.StatusOK, page)
 }
 
 func GetNestedSpans(c echo.Context) error {
 	traceId := c.Param("traceId")
 	if traceId == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "traceId parameter is required"})
+		return apierror.New(http.StatusBadRequest, "traceId parameter is required")
 	}
 	c.Logger().Printf("Running GetNestedSpans function for trace %s", traceId)
 
+	offset, scope, err := parseContinuation(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	filters, err := parseSpanFilters(c, 3)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
 	db := db_duckdb.DB
 	if db == nil {
 		return fmt.Errorf("database connection is nil")
 	}
 
 	// Execute the query
-	rows, err := db.Query(queryNestedSpans, traceId)
+	rows, err := db.Query(filters.apply(queryNestedSpans), append([]interface{}{traceId, offset}, filters.args...)...)
 	if err != nil {
 		c.Logger().Printf("Error querying database: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("database query failed: %v", err)})
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
 	}
 	defer rows.Close()
 
@@ -198,7 +168,7 @@ func GetNestedSpans(c echo.Context) error {
 	columns, err := rows.Columns()
 	if err != nil {
 		c.Logger().Printf("Error getting columns: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to get columns: %v", err)})
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to get columns: %v", err))
 	}
 
 	// Prepare data structures for dynamic scanning
@@ -216,7 +186,7 @@ func GetNestedSpans(c echo.Context) error {
 	for rows.Next() {
 		if err := rows.Scan(valuePtrs...); err != nil {
 			c.Logger().Printf("Error scanning row: %v", err)
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to scan row: %v", err)})
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
 		}
 
 		// Create a map for the current row
@@ -230,17 +200,18 @@ func GetNestedSpans(c echo.Context) error {
 		results = append(results, rowMap)
 	}
 
-	return c.JSON(http.StatusOK, results)
+	// Large traces are truncated to the response size limit
+	return writeSpansLimited(c, results, offset, scope)
 }
 
 func GetSpan(c echo.Context) error {
 	traceId := c.Param("traceId")
 	if traceId == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "traceId parameter is required"})
+		return apierror.New(http.StatusBadRequest, "traceId parameter is required")
 	}
 	spanId := c.Param("spanId")
 	if spanId == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "spanId parameter is required"})
+		return apierror.New(http.StatusBadRequest, "spanId parameter is required")
 	}
 	c.Logger().Printf("Running GetSpan function for trace %s and span %s", traceId, spanId)
 
@@ -253,7 +224,7 @@ func GetSpan(c echo.Context) error {
 	rows, err := db.Query(querySpan, traceId, spanId)
 	if err != nil {
 		c.Logger().Printf("Error querying database: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("database query failed: %v", err)})
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
 	}
 	defer rows.Close()
 
@@ -261,7 +232,7 @@ func GetSpan(c echo.Context) error {
 	columns, err := rows.Columns()
 	if err != nil {
 		c.Logger().Printf("Error getting columns: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to get columns: %v", err)})
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to get columns: %v", err))
 	}
 
 	// Prepare data structures for dynamic scanning
@@ -279,7 +250,7 @@ func GetSpan(c echo.Context) error {
 	if rows.Next() {
 		if err := rows.Scan(valuePtrs...); err != nil {
 			c.Logger().Printf("Error scanning row: %v", err)
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to scan row: %v", err)})
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
 		}
 
 		// Create a map for the current row
@@ -298,58 +269,38 @@ func GetSpan(c echo.Context) error {
 func GetSpansTypeWorkflow(c echo.Context) error {
 	serviceName := c.Param("serviceName")
 	if serviceName == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "serviceName parameter is required"})
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
 	}
 	c.Logger().Printf("Running GetSpansTypeWorkflow function for service %s", serviceName)
 
+	params, err := parsePageParams(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	filters, err := parseSpanFilters(c, 5)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
 	db := db_duckdb.DB
 	if db == nil {
 		return fmt.Errorf("database connection is nil")
 	}
 
 	// Execute the query
-	rows, err := db.Query(querySpansTypeWorkflow, serviceName)
+	args := append([]interface{}{serviceName}, params.args()...)
+	rows, err := db.Query(filters.apply(querySpansTypeWorkflow), append(args, filters.args...)...)
 	if err != nil {
 		c.Logger().Printf("Error querying database: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("database query failed: %v", err)})
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
 	}
 	defer rows.Close()
 
-	// Get Column Names
-	columns, err := rows.Columns()
+	page, err := scanSpanPage(rows, params)
 	if err != nil {
-		c.Logger().Printf("Error getting columns: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to get columns: %v", err)})
-	}
-
-	// Prepare data structures for dynamic scanning
-	count := len(columns)
-	values := make([]interface{}, count)
-	valuePtrs := make([]interface{}, count)
-	for i := range columns {
-		valuePtrs[i] = &values[i]
-	}
-
-	// Final results storage
-	results := []map[string]interface{}{}
-
-	// Start processing each row
-	for rows.Next() {
-		if err := rows.Scan(valuePtrs...); err != nil {
-			c.Logger().Printf("Error scanning row: %v", err)
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to scan row: %v", err)})
-		}
-
-		// Create a map for the current row
-		rowMap := make(map[string]interface{})
-
-		for i, colName := range columns {
-			rowMap[colName] = values[i]
-		}
-
-		// Append this row to the results
-		results = append(results, rowMap)
+		c.Logger().Printf("Error reading rows: %v", err)
+		return apierror.New(http.StatusInternalServerError, err.Error())
 	}
 
-	return c.JSON(http.StatusOK, results)
+	return c.JSON(http.StatusOK, page)
 }
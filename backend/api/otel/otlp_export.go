@@ -0,0 +1,37 @@
+package api_otel
+
+import (
+	"fmt"
+	"junjo-server/apierror"
+	"junjo-server/telemetry"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetTraceOTLP returns a trace as an OTLP/JSON ExportTraceServiceRequest so it
+// can be re-imported into another OpenTelemetry backend.
+func GetTraceOTLP(c echo.Context) error {
+	traceId := c.Param("traceId")
+	if traceId == "" {
+		return apierror.New(http.StatusBadRequest, "traceId parameter is required")
+	}
+	c.Logger().Printf("Running GetTraceOTLP function for trace %s", traceId)
+
+	request, err := telemetry.ExportTrace(c.Request().Context(), traceId)
+	if err != nil {
+		c.Logger().Printf("Error exporting trace: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to export trace: %v", err))
+	}
+	if request == nil {
+		return apierror.New(http.StatusNotFound, "trace not found")
+	}
+
+	body, err := telemetry.MarshalOTLPJSON(request)
+	if err != nil {
+		c.Logger().Printf("Error marshaling trace: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to marshal trace: %v", err))
+	}
+
+	return c.JSONBlob(http.StatusOK, body)
+}
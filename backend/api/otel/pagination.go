@@ -0,0 +1,144 @@
+package api_otel
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"junjo-server/cursor"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPageLimit = 500
+	maxPageLimit     = 1000
+)
+
+// SpanPage is the response envelope of the paginated span listing endpoints.
+// NextCursor is nil when there are no more spans.
+type SpanPage struct {
+	Spans      []map[string]interface{} `json:"spans"`
+	NextCursor *string                  `json:"next_cursor"`
+}
+
+// spanCursor marks the position of the last span of a page. Listings are
+// ordered by start_time, then span_id, both descending.
+type spanCursor struct {
+	StartTime time.Time `json:"t"`
+	SpanID    string    `json:"s"`
+}
+
+// pageParams are the parsed limit and cursor query parameters. scope
+// identifies the endpoint and filters the cursor is valid for.
+type pageParams struct {
+	limit  int
+	cursor *spanCursor
+	scope  string
+}
+
+// parsePageParams reads the limit and cursor query parameters.
+func parsePageParams(c echo.Context) (pageParams, error) {
+	params := pageParams{
+		limit: defaultPageLimit,
+		scope: cursor.Scope(c.Request().URL.Path, c.QueryParams()),
+	}
+
+	if raw := c.QueryParam("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return params, fmt.Errorf("limit must be a positive integer")
+		}
+		params.limit = min(limit, maxPageLimit)
+	}
+
+	if raw := c.QueryParam("cursor"); raw != "" {
+		var position spanCursor
+		if err := cursor.Decode(raw, params.scope, &position); err != nil {
+			if errors.Is(err, cursor.ErrMismatch) {
+				return params, err
+			}
+			return params, cursor.ErrInvalid
+		}
+		if position.SpanID == "" {
+			return params, cursor.ErrInvalid
+		}
+		params.cursor = &position
+	}
+
+	return params, nil
+}
+
+// args returns the cursor and limit query arguments. The listing queries take
+// them as: cursor start_time, cursor span_id, limit. One extra row is requested
+// to detect whether another page exists.
+func (p pageParams) args() []interface{} {
+	if p.cursor == nil {
+		return []interface{}{nil, nil, p.limit + 1}
+	}
+	return []interface{}{p.cursor.StartTime, p.cursor.SpanID, p.limit + 1}
+}
+
+// encodeCursor builds the opaque cursor pointing after the given span.
+func (p pageParams) encodeCursor(startTime time.Time, spanID string) (string, error) {
+	return cursor.Encode(spanCursor{StartTime: startTime, SpanID: spanID}, p.scope)
+}
+
+// scanSpanPage scans rows into a SpanPage, trimming the extra row requested by
+// pageParams.args and using it to set the next cursor.
+func scanSpanPage(rows *sql.Rows, params pageParams) (SpanPage, error) {
+	spans, err := scanSpans(rows)
+	if err != nil {
+		return SpanPage{Spans: []map[string]interface{}{}}, err
+	}
+	page := SpanPage{Spans: spans}
+
+	if len(page.Spans) > params.limit {
+		page.Spans = page.Spans[:params.limit]
+		last := page.Spans[len(page.Spans)-1]
+		startTime, _ := last["start_time"].(time.Time)
+		spanID, _ := last["span_id"].(string)
+		next, err := params.encodeCursor(startTime, spanID)
+		if err != nil {
+			return page, fmt.Errorf("failed to encode cursor: %w", err)
+		}
+		page.NextCursor = &next
+	}
+
+	return page, nil
+}
+
+// scanSpans scans rows into maps keyed by column name.
+func scanSpans(rows *sql.Rows) ([]map[string]interface{}, error) {
+	spans := []map[string]interface{}{}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	// Prepare data structures for dynamic scanning
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range columns {
+		valuePtrs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		rowMap := make(map[string]interface{})
+		for i, colName := range columns {
+			rowMap[colName] = values[i]
+		}
+		spans = append(spans, rowMap)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return spans, nil
+}
@@ -0,0 +1,174 @@
+package api_otel
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_prompt_versions.sql
+var queryPromptVersions string
+
+//go:embed query_prompt_version_traces.sql
+var queryPromptVersionTraces string
+
+// PromptVersionStats are the activity of a prompt version, read from the
+// junjo.prompt.id and junjo.prompt.version attributes of the spans it
+// produced. Spans without a version are grouped under an empty version. A
+// trace counts as an error when any of its spans ended with an error status.
+type PromptVersionStats struct {
+	PromptID        string    `json:"prompt_id"`
+	Version         string    `json:"version"`
+	SpanCount       int64     `json:"span_count"`
+	TraceCount      int64     `json:"trace_count"`
+	ErrorTraceCount int64     `json:"error_trace_count"`
+	ErrorRate       float64   `json:"error_rate"`
+	AvgDurationMs   float64   `json:"avg_duration_ms"`
+	InputTokens     int64     `json:"input_tokens"`
+	OutputTokens    int64     `json:"output_tokens"`
+	CostUSD         float64   `json:"cost_usd"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+}
+
+// PromptVersionTrace is a trace produced by a prompt, with the versions of the
+// prompt used within it.
+type PromptVersionTrace struct {
+	TraceID        string    `json:"trace_id"`
+	RootSpanID     *string   `json:"root_span_id"`
+	RootSpanName   *string   `json:"root_span_name"`
+	WorkflowName   *string   `json:"workflow_name"`
+	PromptVersions []string  `json:"prompt_versions"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	DurationMs     int64     `json:"duration_ms"`
+	SpanCount      int64     `json:"span_count"`
+	ErrorCount     int64     `json:"error_count"`
+	InputTokens    int64     `json:"input_tokens"`
+	OutputTokens   int64     `json:"output_tokens"`
+	CostUSD        float64   `json:"cost_usd"`
+}
+
+// GetPromptVersions lists the prompt versions of a service by prompt id,
+// newest version first, with their trace counts, error rates, latency and
+// token usage, to compare versions of a prompt. The prompt_id query parameter
+// limits the list to one prompt. The number of versions is set with the limit
+// query parameter (default 100, max 1000), and the span filter query
+// parameters apply to the spans produced by the prompts.
+func GetPromptVersions(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetPromptVersions function for service: %s", serviceName)
+
+	limit, err := parseEndUserLimit(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	filters, err := parseSpanFilters(c, 4)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, limit, c.QueryParam("prompt_id")}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryPromptVersions), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	versions := []PromptVersionStats{}
+	for rows.Next() {
+		var version PromptVersionStats
+		if err := rows.Scan(&version.PromptID, &version.Version, &version.SpanCount, &version.TraceCount, &version.ErrorTraceCount, &version.AvgDurationMs, &version.InputTokens, &version.OutputTokens, &version.CostUSD, &version.FirstSeen, &version.LastSeen); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		if version.TraceCount > 0 {
+			version.ErrorRate = float64(version.ErrorTraceCount) / float64(version.TraceCount)
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, versions)
+}
+
+// GetPromptVersionTraces lists the traces of a service produced by a prompt,
+// most recent first. The version query parameter limits them to one version of
+// the prompt; an empty version selects the spans without a version. The number
+// of traces is set with the limit query parameter (default 100, max 1000), and
+// the span filter query parameters apply to the spans produced by the prompt.
+func GetPromptVersionTraces(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	promptID := c.Param("promptId")
+	if promptID == "" {
+		return apierror.New(http.StatusBadRequest, "promptId parameter is required")
+	}
+	c.Logger().Printf("Running GetPromptVersionTraces function for service %s and prompt %s", serviceName, promptID)
+
+	var version sql.NullString
+	if c.QueryParams().Has("version") {
+		version = sql.NullString{String: c.QueryParam("version"), Valid: true}
+	}
+
+	limit, err := parseEndUserLimit(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	filters, err := parseSpanFilters(c, 5)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, promptID, version, limit}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryPromptVersionTraces), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	traces := []PromptVersionTrace{}
+	for rows.Next() {
+		var trace PromptVersionTrace
+		var versions string
+		if err := rows.Scan(&trace.TraceID, &trace.RootSpanID, &trace.RootSpanName, &trace.WorkflowName, &versions, &trace.StartTime, &trace.EndTime, &trace.DurationMs, &trace.SpanCount, &trace.ErrorCount, &trace.InputTokens, &trace.OutputTokens, &trace.CostUSD); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		trace.PromptVersions = strings.Split(versions, ",")
+		traces = append(traces, trace)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, traces)
+}
@@ -0,0 +1,33 @@
+WITH
+  durations AS (
+    SELECT
+      epoch_ms(end_time) - epoch_ms(start_time) AS duration_ms
+    FROM
+      spans
+    WHERE
+      junjo_span_type = 'workflow'
+      AND service_name = $1
+      /* filters */
+  ),
+  bounds AS (
+    SELECT
+      MIN(duration_ms) AS min_ms,
+      MAX(duration_ms) AS max_ms
+    FROM
+      durations
+  )
+SELECT
+  b.min_ms,
+  b.max_ms,
+  CASE
+    WHEN b.max_ms = b.min_ms THEN 0
+    ELSE LEAST(floor((d.duration_ms - b.min_ms) * $2 / (b.max_ms - b.min_ms)), $2 - 1)
+  END::INTEGER AS bucket,
+  COUNT(*) AS count
+FROM
+  durations d,
+  bounds b
+GROUP BY
+  ALL
+ORDER BY
+  bucket;
@@ -0,0 +1,51 @@
+WITH
+  attributed AS (
+    SELECT
+      trace_id
+    FROM
+      spans
+    WHERE
+      service_name = $1
+      AND enduser_id IS NOT NULL
+    GROUP BY
+      trace_id
+    HAVING
+      first(enduser_id ORDER BY start_time) = $2
+  )
+SELECT
+  spans.trace_id,
+  first(span_id ORDER BY start_time) FILTER (
+    WHERE
+      parent_span_id IS NULL
+  ) AS root_span_id,
+  first(name ORDER BY start_time) FILTER (
+    WHERE
+      parent_span_id IS NULL
+  ) AS root_span_name,
+  first(name ORDER BY start_time) FILTER (
+    WHERE
+      junjo_span_type = 'workflow'
+  ) AS workflow_name,
+  MIN(start_time) AS start_time,
+  MAX(end_time) AS end_time,
+  epoch_ms(MAX(end_time)) - epoch_ms(MIN(start_time)) AS duration_ms,
+  COUNT(*) AS span_count,
+  COUNT(*) FILTER (
+    WHERE
+      status_code = 'STATUS_CODE_ERROR'
+  ) AS error_count,
+  COALESCE(SUM(gen_ai_usage_input_tokens), 0) AS input_tokens,
+  COALESCE(SUM(gen_ai_usage_output_tokens), 0) AS output_tokens,
+  COALESCE(SUM(gen_ai_cost_usd), 0) AS cost_usd
+FROM
+  spans
+  JOIN attributed ON attributed.trace_id = spans.trace_id
+WHERE
+  service_name = $1
+  /* filters */
+GROUP BY
+  spans.trace_id
+ORDER BY
+  MIN(start_time) DESC
+LIMIT
+  $3;
@@ -0,0 +1,42 @@
+WITH
+  attributed AS (
+    SELECT
+      trace_id,
+      first(enduser_id ORDER BY start_time) AS user_id
+    FROM
+      spans
+    WHERE
+      service_name = $1
+      AND enduser_id IS NOT NULL
+    GROUP BY
+      trace_id
+  )
+SELECT
+  attributed.user_id,
+  COUNT(DISTINCT spans.trace_id) AS trace_count,
+  COUNT(*) FILTER (
+    WHERE
+      junjo_span_type = 'workflow'
+  ) AS run_count,
+  COUNT(DISTINCT spans.trace_id) FILTER (
+    WHERE
+      status_code = 'STATUS_CODE_ERROR'
+  ) AS error_trace_count,
+  COALESCE(SUM(gen_ai_usage_input_tokens), 0) AS input_tokens,
+  COALESCE(SUM(gen_ai_usage_output_tokens), 0) AS output_tokens,
+  COALESCE(SUM(gen_ai_cost_usd), 0) AS cost_usd,
+  MIN(start_time) AS first_seen,
+  MAX(end_time) AS last_seen
+FROM
+  spans
+  JOIN attributed ON attributed.trace_id = spans.trace_id
+WHERE
+  service_name = $1
+  /* filters */
+GROUP BY
+  attributed.user_id
+ORDER BY
+  last_seen DESC,
+  attributed.user_id
+LIMIT
+  $2;
@@ -0,0 +1,34 @@
+WITH
+  bucketed AS (
+    SELECT
+      time_bucket(to_microseconds($2), start_time::TIMESTAMP) AS bucket_start,
+      CASE
+        WHEN junjo_span_type = 'workflow' THEN name
+      END AS workflow_name,
+      status_code = 'STATUS_CODE_ERROR' AS is_error
+    FROM
+      spans
+    WHERE
+      service_name = $1
+      /* filters */
+  )
+SELECT
+  GROUPING(workflow_name) = 0 AS is_workflow,
+  COALESCE(workflow_name, '') AS workflow_name,
+  bucket_start,
+  COUNT(*) AS total,
+  COUNT(*) FILTER (
+    WHERE
+      is_error
+  ) AS errors
+FROM
+  bucketed
+GROUP BY
+  GROUPING SETS ((bucket_start), (bucket_start, workflow_name))
+HAVING
+  GROUPING(workflow_name) = 1
+  OR workflow_name IS NOT NULL
+ORDER BY
+  is_workflow,
+  workflow_name,
+  bucket_start;
@@ -0,0 +1,37 @@
+-- The Junjo nodes, subflows and concurrent groups executed under a workflow
+-- span, grouped by their Junjo id. Args: trace_id, workflow span_id.
+WITH RECURSIVE tree AS (
+  SELECT
+    span_id
+  FROM
+    spans
+  WHERE
+    trace_id = $1
+    AND parent_span_id = $2
+  UNION ALL
+  SELECT
+    s.span_id
+  FROM
+    spans s
+    JOIN tree t ON s.parent_span_id = t.span_id
+  WHERE
+    s.trace_id = $1
+)
+SELECT
+  junjo_id,
+  junjo_span_type,
+  first(name ORDER BY start_time) AS name,
+  first(span_id ORDER BY start_time) AS first_span_id,
+  count(*) AS executions
+FROM
+  spans
+WHERE
+  trace_id = $1
+  AND span_id IN (SELECT span_id FROM tree)
+  AND junjo_span_type IN ('node', 'subflow', 'run_concurrent')
+  AND COALESCE(junjo_id, '') != ''
+GROUP BY
+  junjo_id,
+  junjo_span_type
+ORDER BY
+  min(start_time);
@@ -0,0 +1,10 @@
+SELECT
+  *
+FROM
+  spans
+WHERE
+  service_name = ?
+  AND start_time >= ?
+  AND start_time < ?
+ORDER BY
+  start_time ASC;
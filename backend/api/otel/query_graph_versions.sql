@@ -0,0 +1,19 @@
+SELECT
+  graph_hash,
+  workflow_name,
+  node_count,
+  edge_count,
+  first_seen,
+  last_seen,
+  run_count
+FROM
+  graph_versions
+WHERE
+  service_name = $1
+  AND (
+    $2 = ''
+    OR workflow_name = $2
+  )
+ORDER BY
+  workflow_name,
+  last_seen DESC;
@@ -3,6 +3,10 @@ SELECT
 FROM
   spans
 WHERE
-  trace_id = ?
+  trace_id = $1
+  /* filters */
 ORDER BY
-  start_time DESC;
\ No newline at end of file
+  start_time DESC,
+  span_id DESC
+OFFSET
+  $2;
\ No newline at end of file
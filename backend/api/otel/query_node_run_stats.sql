@@ -0,0 +1,38 @@
+-- The durations and outcomes of the Junjo nodes, subflows and concurrent groups
+-- executed under a workflow span, grouped by their Junjo id. Args: trace_id,
+-- workflow span_id.
+WITH RECURSIVE tree AS (
+  SELECT
+    span_id
+  FROM
+    spans
+  WHERE
+    trace_id = $1
+    AND parent_span_id = $2
+  UNION ALL
+  SELECT
+    s.span_id
+  FROM
+    spans s
+    JOIN tree t ON s.parent_span_id = t.span_id
+  WHERE
+    s.trace_id = $1
+)
+SELECT
+  junjo_id,
+  count(*) AS executions,
+  count(*) FILTER (
+    WHERE
+      status_code = 'STATUS_CODE_ERROR'
+  ) AS error_count,
+  sum(epoch_ms(end_time) - epoch_ms(start_time)) AS total_ms,
+  max(epoch_ms(end_time) - epoch_ms(start_time)) AS max_ms
+FROM
+  spans
+WHERE
+  trace_id = $1
+  AND span_id IN (SELECT span_id FROM tree)
+  AND junjo_span_type IN ('node', 'subflow', 'run_concurrent')
+  AND COALESCE(junjo_id, '') != ''
+GROUP BY
+  junjo_id;
@@ -0,0 +1,24 @@
+SELECT
+  name AS node_name,
+  COUNT(*) AS count,
+  COUNT(*) FILTER (
+    WHERE
+      status_code = 'STATUS_CODE_ERROR'
+  ) AS error_count,
+  SUM(epoch_ms(end_time) - epoch_ms(start_time)) AS total_ms,
+  AVG(epoch_ms(end_time) - epoch_ms(start_time)) AS avg_ms,
+  quantile_cont(epoch_ms(end_time) - epoch_ms(start_time), 0.5) AS p50_ms,
+  quantile_cont(epoch_ms(end_time) - epoch_ms(start_time), 0.9) AS p90_ms,
+  quantile_cont(epoch_ms(end_time) - epoch_ms(start_time), 0.99) AS p99_ms,
+  MAX(epoch_ms(end_time) - epoch_ms(start_time)) AS max_ms
+FROM
+  spans
+WHERE
+  service_name = $1
+  AND junjo_span_type = 'node'
+  /* filters */
+GROUP BY
+  name
+ORDER BY
+  total_ms DESC,
+  node_name;
@@ -0,0 +1,55 @@
+WITH
+  tagged AS (
+    SELECT DISTINCT
+      trace_id
+    FROM
+      spans
+    WHERE
+      service_name = $1
+      AND junjo_prompt_id = $2
+      AND (
+        $3 IS NULL
+        OR COALESCE(junjo_prompt_version, '') = $3
+      )
+      /* filters */
+  )
+SELECT
+  spans.trace_id,
+  first(span_id ORDER BY start_time) FILTER (
+    WHERE
+      parent_span_id IS NULL
+  ) AS root_span_id,
+  first(name ORDER BY start_time) FILTER (
+    WHERE
+      parent_span_id IS NULL
+  ) AS root_span_name,
+  first(name ORDER BY start_time) FILTER (
+    WHERE
+      junjo_span_type = 'workflow'
+  ) AS workflow_name,
+  string_agg(DISTINCT COALESCE(junjo_prompt_version, ''), ',') FILTER (
+    WHERE
+      junjo_prompt_id = $2
+  ) AS prompt_versions,
+  MIN(start_time) AS start_time,
+  MAX(end_time) AS end_time,
+  epoch_ms(MAX(end_time)) - epoch_ms(MIN(start_time)) AS duration_ms,
+  COUNT(*) AS span_count,
+  COUNT(*) FILTER (
+    WHERE
+      status_code = 'STATUS_CODE_ERROR'
+  ) AS error_count,
+  COALESCE(SUM(gen_ai_usage_input_tokens), 0) AS input_tokens,
+  COALESCE(SUM(gen_ai_usage_output_tokens), 0) AS output_tokens,
+  COALESCE(SUM(gen_ai_cost_usd), 0) AS cost_usd
+FROM
+  spans
+  JOIN tagged ON tagged.trace_id = spans.trace_id
+WHERE
+  service_name = $1
+GROUP BY
+  spans.trace_id
+ORDER BY
+  MIN(start_time) DESC
+LIMIT
+  $4;
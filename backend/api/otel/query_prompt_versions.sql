@@ -0,0 +1,55 @@
+WITH
+  tagged AS (
+    SELECT
+      trace_id,
+      junjo_prompt_id,
+      COALESCE(junjo_prompt_version, '') AS junjo_prompt_version,
+      start_time,
+      end_time,
+      gen_ai_usage_input_tokens,
+      gen_ai_usage_output_tokens,
+      gen_ai_cost_usd
+    FROM
+      spans
+    WHERE
+      service_name = $1
+      AND junjo_prompt_id IS NOT NULL
+      AND (
+        $3 = ''
+        OR junjo_prompt_id = $3
+      )
+      /* filters */
+  ),
+  failed AS (
+    SELECT DISTINCT
+      trace_id
+    FROM
+      spans
+    WHERE
+      service_name = $1
+      AND status_code = 'STATUS_CODE_ERROR'
+  )
+SELECT
+  junjo_prompt_id,
+  junjo_prompt_version,
+  COUNT(*) AS span_count,
+  COUNT(DISTINCT tagged.trace_id) AS trace_count,
+  COUNT(DISTINCT failed.trace_id) AS error_trace_count,
+  AVG(epoch_ms(end_time) - epoch_ms(start_time)) AS avg_duration_ms,
+  COALESCE(SUM(gen_ai_usage_input_tokens), 0) AS input_tokens,
+  COALESCE(SUM(gen_ai_usage_output_tokens), 0) AS output_tokens,
+  COALESCE(SUM(gen_ai_cost_usd), 0) AS cost_usd,
+  MIN(start_time) AS first_seen,
+  MAX(end_time) AS last_seen
+FROM
+  tagged
+  LEFT JOIN failed ON failed.trace_id = tagged.trace_id
+GROUP BY
+  junjo_prompt_id,
+  junjo_prompt_version
+ORDER BY
+  junjo_prompt_id,
+  first_seen DESC,
+  junjo_prompt_version
+LIMIT
+  $2;
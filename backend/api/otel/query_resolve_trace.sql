@@ -0,0 +1,19 @@
+-- Summarizes a trace for the trace resolver: the service with the most spans,
+-- and the trace's first top-level workflow. Returns no rows for unknown traces.
+SELECT
+  service_name,
+  count(*) AS span_count,
+  min(start_time) AS start_time,
+  first(span_id ORDER BY start_time) FILTER (WHERE junjo_span_type = 'workflow') AS workflow_span_id,
+  first(name ORDER BY start_time) FILTER (WHERE junjo_span_type = 'workflow') AS workflow_name
+FROM
+  spans
+WHERE
+  trace_id = $1
+GROUP BY
+  service_name
+ORDER BY
+  span_count DESC,
+  service_name
+LIMIT
+  1;
@@ -1,11 +1,33 @@
 SELECT
-  *
+  *,
+  (
+    SELECT
+      t.status
+    FROM
+      workflow_timeouts t
+    WHERE
+      t.trace_id = spans.trace_id
+    ORDER BY
+      t.detected_at
+    LIMIT
+      1
+  ) AS sla_status
 FROM
   spans
 WHERE
-  service_name = ?
+  service_name = $1
   AND parent_span_id IS NULL
+  /* filters */
+  AND (
+    $2::TIMESTAMPTZ IS NULL
+    OR start_time < $2
+    OR (
+      start_time = $2
+      AND span_id < $3
+    )
+  )
 ORDER BY
-  start_time DESC
+  start_time DESC,
+  span_id DESC
 LIMIT
-  500;
\ No newline at end of file
+  $4;
\ No newline at end of file
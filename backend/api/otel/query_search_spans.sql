@@ -0,0 +1,45 @@
+WITH
+  matches AS (
+    SELECT
+      trace_id,
+      span_id,
+      service_name,
+      name,
+      start_time,
+      (
+        CASE WHEN name ILIKE $1 ESCAPE '\' THEN 4 ELSE 0 END
+      ) + (
+        CASE WHEN attributes_json::VARCHAR ILIKE $1 ESCAPE '\' THEN 2 ELSE 0 END
+      ) + (
+        CASE WHEN COALESCE(junjo_wf_state_start::VARCHAR, '') || COALESCE(junjo_wf_state_end::VARCHAR, '') ILIKE $1 ESCAPE '\' THEN 2 ELSE 0 END
+      ) + (
+        CASE WHEN events_json::VARCHAR ILIKE $1 ESCAPE '\' THEN 1 ELSE 0 END
+      ) AS score
+    FROM
+      spans
+    WHERE
+      (
+        $2 = ''
+        OR service_name = $2
+      )
+      /* filters */
+  )
+SELECT
+  trace_id,
+  any_value(service_name) AS service_name,
+  SUM(score) AS score,
+  COUNT(*) AS matched_spans,
+  arg_max(span_id, score) AS top_span_id,
+  arg_max(name, score) AS top_span_name,
+  MIN(start_time) AS start_time
+FROM
+  matches
+WHERE
+  score > 0
+GROUP BY
+  trace_id
+ORDER BY
+  score DESC,
+  start_time DESC
+LIMIT
+  $3;
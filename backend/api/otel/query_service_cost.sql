@@ -0,0 +1,32 @@
+SELECT
+  GROUPING(model) = 1 AS is_total,
+  COALESCE(model, '') AS model,
+  COUNT(*) AS span_count,
+  COALESCE(SUM(gen_ai_usage_input_tokens), 0) AS input_tokens,
+  COALESCE(SUM(gen_ai_usage_output_tokens), 0) AS output_tokens,
+  COALESCE(SUM(gen_ai_cost_usd), 0) AS cost_usd,
+  COUNT(*) FILTER (
+    WHERE
+      gen_ai_cost_usd IS NULL
+  ) AS unpriced_spans
+FROM
+  (
+    SELECT
+      *,
+      COALESCE(gen_ai_response_model, gen_ai_request_model, '') AS model
+    FROM
+      spans
+    WHERE
+      service_name = $1
+      AND (
+        gen_ai_usage_input_tokens IS NOT NULL
+        OR gen_ai_usage_output_tokens IS NOT NULL
+      )
+      /* filters */
+  )
+GROUP BY
+  ROLLUP (model)
+ORDER BY
+  is_total DESC,
+  cost_usd DESC,
+  model;
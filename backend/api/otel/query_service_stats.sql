@@ -0,0 +1,23 @@
+SELECT
+  GROUPING(name) = 1 AS is_total,
+  COALESCE(name, '') AS workflow_name,
+  COUNT(*) AS count,
+  COUNT(*) FILTER (
+    WHERE
+      status_code = 'STATUS_CODE_ERROR'
+  ) AS error_count,
+  quantile_cont(epoch_ms(end_time) - epoch_ms(start_time), 0.5) AS p50_ms,
+  quantile_cont(epoch_ms(end_time) - epoch_ms(start_time), 0.9) AS p90_ms,
+  quantile_cont(epoch_ms(end_time) - epoch_ms(start_time), 0.99) AS p99_ms
+FROM
+  spans
+WHERE
+  service_name = $1
+  AND junjo_span_type = 'workflow'
+  /* filters */
+GROUP BY
+  ROLLUP (name)
+ORDER BY
+  is_total DESC,
+  count DESC,
+  workflow_name;
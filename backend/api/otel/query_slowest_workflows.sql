@@ -0,0 +1,19 @@
+SELECT
+  trace_id,
+  span_id,
+  name,
+  start_time,
+  end_time,
+  epoch_ms(end_time) - epoch_ms(start_time) AS duration_ms,
+  status_code
+FROM
+  spans
+WHERE
+  junjo_span_type = 'workflow'
+  AND service_name = $1
+  /* filters */
+ORDER BY
+  duration_ms DESC,
+  start_time DESC
+LIMIT
+  $2;
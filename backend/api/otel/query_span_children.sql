@@ -0,0 +1,49 @@
+SELECT
+  s.trace_id,
+  s.span_id,
+  s.parent_span_id,
+  s.name,
+  s.kind,
+  s.start_time,
+  s.end_time,
+  s.status_code,
+  s.junjo_id,
+  s.junjo_span_type,
+  (
+    SELECT
+      COUNT(*)
+    FROM
+      spans c
+    WHERE
+      c.trace_id = s.trace_id
+      AND c.parent_span_id = s.span_id
+  ) AS child_count
+FROM
+  spans s
+WHERE
+  s.trace_id = $1
+  AND (
+    s.parent_span_id = $2
+    OR (
+      -- Without a parent, the top level: root spans, and spans whose parent
+      -- was not received.
+      $2 = ''
+      AND (
+        s.parent_span_id IS NULL
+        OR NOT EXISTS (
+          SELECT
+            1
+          FROM
+            spans p
+          WHERE
+            p.trace_id = s.trace_id
+            AND p.span_id = s.parent_span_id
+        )
+      )
+    )
+  )
+ORDER BY
+  s.start_time,
+  s.span_id
+OFFSET
+  $3;
@@ -0,0 +1,37 @@
+-- The links of a span, and the spans that link to it. Args: trace_id, span_id.
+SELECT
+  'outgoing' AS direction,
+  json_extract_string(l.link, 'traceId') AS trace_id,
+  json_extract_string(l.link, 'spanId') AS span_id,
+  json_extract(l.link, 'attributes')::VARCHAR AS attributes
+FROM
+  (
+    SELECT
+      unnest(links_json::JSON[]) AS link
+    FROM
+      spans
+    WHERE
+      trace_id = $1
+      AND span_id = $2
+  ) l
+UNION ALL
+SELECT
+  'incoming' AS direction,
+  s.trace_id,
+  s.span_id,
+  json_extract(l.link, 'attributes')::VARCHAR AS attributes
+FROM
+  (
+    SELECT
+      trace_id,
+      span_id,
+      unnest(links_json::JSON[]) AS link
+    FROM
+      spans
+    WHERE
+      json_array_length(links_json) > 0
+  ) AS l
+  JOIN spans s ON s.trace_id = l.trace_id AND s.span_id = l.span_id
+WHERE
+  json_extract_string(l.link, 'traceId') = $1
+  AND json_extract_string(l.link, 'spanId') = $2;
@@ -0,0 +1,13 @@
+SELECT
+  date_trunc($2, start_time::TIMESTAMP) AS bucket_start,
+  COUNT(*) AS spans,
+  COUNT(DISTINCT trace_id) AS traces
+FROM
+  spans
+WHERE
+  service_name = $1
+  /* filters */
+GROUP BY
+  bucket_start
+ORDER BY
+  bucket_start;
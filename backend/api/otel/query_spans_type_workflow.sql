@@ -1,9 +1,34 @@
 SELECT
-  *
+  *,
+  (
+    SELECT
+      t.status
+    FROM
+      workflow_timeouts t
+    WHERE
+      t.trace_id = spans.trace_id
+      AND t.span_id IN (spans.span_id, '')
+    ORDER BY
+      t.detected_at
+    LIMIT
+      1
+  ) AS sla_status
 FROM
   spans
 WHERE
   junjo_span_type = 'workflow'
-  AND service_name = ?
+  AND service_name = $1
+  /* filters */
+  AND (
+    $2::TIMESTAMPTZ IS NULL
+    OR start_time < $2
+    OR (
+      start_time = $2
+      AND span_id < $3
+    )
+  )
 ORDER BY
-  start_time DESC;
\ No newline at end of file
+  start_time DESC,
+  span_id DESC
+LIMIT
+  $4;
\ No newline at end of file
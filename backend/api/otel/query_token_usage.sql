@@ -0,0 +1,51 @@
+WITH
+  workflows AS (
+    SELECT
+      trace_id,
+      first(name ORDER BY start_time) AS workflow_name
+    FROM
+      spans
+    WHERE
+      junjo_span_type = 'workflow'
+    GROUP BY
+      trace_id
+  ),
+  usage AS (
+    SELECT
+      spans.service_name,
+      COALESCE(gen_ai_response_model, gen_ai_request_model, '') AS model,
+      COALESCE(workflows.workflow_name, '') AS workflow_name,
+      strftime(start_time::TIMESTAMP, '%Y-%m-%d') AS day,
+      gen_ai_usage_input_tokens,
+      gen_ai_usage_output_tokens,
+      gen_ai_cost_usd
+    FROM
+      spans
+      LEFT JOIN workflows ON workflows.trace_id = spans.trace_id
+    WHERE
+      (
+        $1 = ''
+        OR spans.service_name = $1
+      )
+      AND (
+        gen_ai_usage_input_tokens IS NOT NULL
+        OR gen_ai_usage_output_tokens IS NOT NULL
+      )
+      /* filters */
+  )
+SELECT
+  /* dimensions */
+  COUNT(*) AS span_count,
+  COALESCE(SUM(gen_ai_usage_input_tokens), 0) AS input_tokens,
+  COALESCE(SUM(gen_ai_usage_output_tokens), 0) AS output_tokens,
+  COALESCE(SUM(gen_ai_cost_usd), 0) AS cost_usd,
+  COUNT(*) FILTER (
+    WHERE
+      gen_ai_cost_usd IS NULL
+  ) AS unpriced_spans
+FROM
+  usage
+/* group by */
+ORDER BY
+  /* order by */
+  cost_usd DESC;
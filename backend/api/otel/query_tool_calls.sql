@@ -0,0 +1,19 @@
+SELECT
+  trace_id,
+  span_id,
+  name,
+  tool_name,
+  arguments::VARCHAR AS arguments,
+  result,
+  start_time,
+  end_time,
+  duration_ms,
+  status_code,
+  status_message
+FROM
+  tool_calls
+WHERE
+  trace_id = $1
+ORDER BY
+  start_time,
+  span_id;
@@ -0,0 +1,68 @@
+WITH
+  error_spans AS (
+    SELECT
+      trace_id,
+      span_id,
+      start_time,
+      status_message,
+      events_json
+    FROM
+      spans
+    WHERE
+      service_name = $1
+      AND (
+        status_code = 'STATUS_CODE_ERROR'
+        OR json_contains(events_json, '{"name":"exception"}')
+      )
+      /* filters */
+  ),
+  exceptions AS (
+    SELECT
+      trace_id,
+      span_id,
+      arg_min(json_extract_string(event, '$.attributes."exception.type"'), json_extract(event, '$.timeUnixNano')::UBIGINT) AS exception_type,
+      arg_min(json_extract_string(event, '$.attributes."exception.message"'), json_extract(event, '$.timeUnixNano')::UBIGINT) AS exception_message
+    FROM
+      (
+        SELECT
+          trace_id,
+          span_id,
+          unnest(events_json::JSON[]) AS event
+        FROM
+          error_spans
+      )
+    WHERE
+      json_extract_string(event, '$.name') = 'exception'
+    GROUP BY
+      trace_id,
+      span_id
+  ),
+  errors AS (
+    SELECT
+      s.trace_id,
+      s.start_time,
+      COALESCE(x.exception_type, '') AS error_type,
+      COALESCE(NULLIF(x.exception_message, ''), NULLIF(s.status_message, ''), '') AS message
+    FROM
+      error_spans s
+      LEFT JOIN exceptions x ON x.trace_id = s.trace_id
+      AND x.span_id = s.span_id
+  )
+SELECT
+  error_type,
+  regexp_replace(message, '[0-9]+', 'N', 'g') AS fingerprint,
+  arg_max(message, start_time) AS example_message,
+  COUNT(*) AS count,
+  MIN(start_time) AS first_seen,
+  MAX(start_time) AS last_seen,
+  array_slice(list(DISTINCT trace_id), 1, 5) AS example_trace_ids
+FROM
+  errors
+GROUP BY
+  error_type,
+  fingerprint
+ORDER BY
+  count DESC,
+  last_seen DESC
+LIMIT
+  $2;
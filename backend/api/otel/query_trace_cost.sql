@@ -0,0 +1,18 @@
+SELECT
+  span_id,
+  name,
+  COALESCE(gen_ai_response_model, gen_ai_request_model) AS model,
+  COALESCE(gen_ai_usage_input_tokens, 0) AS input_tokens,
+  COALESCE(gen_ai_usage_output_tokens, 0) AS output_tokens,
+  gen_ai_cost_usd
+FROM
+  spans
+WHERE
+  trace_id = $1
+  AND (
+    gen_ai_usage_input_tokens IS NOT NULL
+    OR gen_ai_usage_output_tokens IS NOT NULL
+  )
+ORDER BY
+  start_time,
+  span_id;
@@ -0,0 +1,105 @@
+WITH
+  workflows AS (
+    SELECT
+      trace_id,
+      span_id,
+      name,
+      start_time,
+      end_time,
+      status_code,
+      status_message,
+      events_json
+    FROM
+      spans
+    WHERE
+      junjo_span_type = 'workflow'
+      AND service_name = $1
+      /* filters */
+  ),
+  error_spans AS (
+    SELECT
+      trace_id,
+      span_id,
+      name,
+      junjo_span_type,
+      start_time,
+      status_message,
+      events_json
+    FROM
+      spans
+    WHERE
+      trace_id IN (
+        SELECT
+          trace_id
+        FROM
+          workflows
+      )
+      AND (
+        status_code = 'STATUS_CODE_ERROR'
+        OR json_contains(events_json, '{"name":"exception"}')
+      )
+  ),
+  exceptions AS (
+    SELECT
+      trace_id,
+      span_id,
+      arg_min(json_extract_string(event, '$.attributes."exception.type"'), json_extract(event, '$.timeUnixNano')::UBIGINT) AS exception_type,
+      arg_min(json_extract_string(event, '$.attributes."exception.message"'), json_extract(event, '$.timeUnixNano')::UBIGINT) AS exception_message
+    FROM
+      (
+        SELECT
+          trace_id,
+          span_id,
+          unnest(events_json::JSON[]) AS event
+        FROM
+          error_spans
+      )
+    WHERE
+      json_extract_string(event, '$.name') = 'exception'
+    GROUP BY
+      trace_id,
+      span_id
+  ),
+  failing_spans AS (
+    SELECT
+      s.trace_id,
+      s.span_id,
+      s.name,
+      COALESCE(x.exception_type, '') AS error_type,
+      COALESCE(NULLIF(x.exception_message, ''), NULLIF(s.status_message, ''), '') AS message,
+      row_number() OVER (
+        PARTITION BY
+          s.trace_id
+        ORDER BY
+          s.junjo_span_type = 'node' DESC,
+          s.start_time
+      ) AS rank
+    FROM
+      error_spans s
+      LEFT JOIN exceptions x ON x.trace_id = s.trace_id
+      AND x.span_id = s.span_id
+  )
+SELECT
+  w.trace_id,
+  w.span_id,
+  w.name,
+  w.start_time,
+  w.end_time,
+  epoch_ms(w.end_time) - epoch_ms(w.start_time) AS duration_ms,
+  w.status_code,
+  f.span_id AS failing_span_id,
+  f.name AS failing_node,
+  f.error_type,
+  COALESCE(NULLIF(f.message, ''), NULLIF(w.status_message, ''), '') AS error_message
+FROM
+  workflows w
+  LEFT JOIN failing_spans f ON f.trace_id = w.trace_id
+  AND f.rank = 1
+WHERE
+  w.status_code = 'STATUS_CODE_ERROR'
+  OR f.trace_id IS NOT NULL
+ORDER BY
+  w.start_time DESC,
+  w.span_id DESC
+LIMIT
+  $2;
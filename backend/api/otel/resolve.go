@@ -0,0 +1,121 @@
+package api_otel
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_resolve_trace.sql
+var queryResolveTrace string
+
+// traceIDPattern matches a normalized OTel trace id.
+var traceIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// frontendURL is the public URL of the Junjo frontend, if configured.
+var frontendURL string
+
+// SetFrontendURL sets the public URL of the Junjo frontend that resolved
+// traces link to.
+func SetFrontendURL(url string) {
+	frontendURL = strings.TrimSuffix(url, "/")
+}
+
+// ResolvedTrace tells other tools whether Junjo has a trace, and where to
+// open it.
+type ResolvedTrace struct {
+	TraceID        string     `json:"trace_id"`
+	Found          bool       `json:"found"`
+	ServiceName    *string    `json:"service_name,omitempty"`
+	WorkflowName   *string    `json:"workflow_name,omitempty"`
+	WorkflowSpanID *string    `json:"workflow_span_id,omitempty"`
+	SpanCount      int64      `json:"span_count"`
+	StartTime      *time.Time `json:"start_time,omitempty"`
+	// Path is the frontend route of the trace, and URL the same route on
+	// JUNJO_FRONTEND_URL when it is configured.
+	Path string `json:"path,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// resolveTrace looks up a normalized trace id.
+func resolveTrace(ctx context.Context, traceID string) (ResolvedTrace, error) {
+	db := db_duckdb.DB
+	if db == nil {
+		return ResolvedTrace{}, fmt.Errorf("database connection is nil")
+	}
+
+	resolved := ResolvedTrace{TraceID: traceID}
+	var serviceName string
+	var startTime time.Time
+	err := db.QueryRowContext(ctx, queryResolveTrace, traceID).Scan(
+		&serviceName, &resolved.SpanCount, &startTime, &resolved.WorkflowSpanID, &resolved.WorkflowName,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return resolved, nil
+	}
+	if err != nil {
+		return ResolvedTrace{}, err
+	}
+
+	resolved.Found = true
+	resolved.ServiceName = &serviceName
+	resolved.StartTime = &startTime
+
+	// Workflows open in the workflow detail page, anything else in the trace view.
+	service := url.PathEscape(serviceName)
+	if resolved.WorkflowSpanID != nil {
+		spanID := url.PathEscape(*resolved.WorkflowSpanID)
+		resolved.Path = fmt.Sprintf("/workflows/%s/%s/%s/%s", service, traceID, spanID, spanID)
+	} else {
+		resolved.Path = fmt.Sprintf("/traces/%s/%s", service, traceID)
+	}
+	if frontendURL != "" {
+		resolved.URL = frontendURL + resolved.Path
+	}
+	return resolved, nil
+}
+
+// normalizeTraceID accepts trace ids as formatted by other tools: upper case,
+// hyphenated, or 64-bit (16 hex characters, as Jaeger and Zipkin may emit),
+// and returns the 32 character lower case form Junjo stores.
+func normalizeTraceID(raw string) (string, error) {
+	traceID := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(raw), "-", ""))
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	if !traceIDPattern.MatchString(traceID) {
+		return "", fmt.Errorf("traceId must be a 16 or 32 character hex trace id")
+	}
+	return traceID, nil
+}
+
+// ResolveTrace reports whether a trace id is known to Junjo, which service and
+// workflow it belongs to, and a deep link to it in the UI. It is intended for
+// "open in Junjo" links from Grafana, Jaeger and log tooling, so unknown traces
+// return 200 with found set to false.
+func ResolveTrace(c echo.Context) error {
+	traceID, err := normalizeTraceID(c.Param("traceId"))
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	c.Logger().Printf("Running ResolveTrace function for trace: %s", traceID)
+
+	resolved, err := resolveTrace(c.Request().Context(), traceID)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, resolved)
+}
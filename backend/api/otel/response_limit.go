@@ -0,0 +1,81 @@
+package api_otel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"junjo-server/apierror"
+	"junjo-server/cursor"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderContinuationToken is set on truncated responses. Passing its value as
+// the continuation query parameter returns the remainder.
+const HeaderContinuationToken = "X-Continuation-Token"
+
+// maxResponseBytes caps the size of span list responses. Zero disables the cap.
+var maxResponseBytes = 8 << 20
+
+// SetResponseLimit sets the size cap of span list responses.
+func SetResponseLimit(limit int) {
+	maxResponseBytes = limit
+}
+
+// spanContinuation is the position to resume a truncated span list from.
+type spanContinuation struct {
+	Index int `json:"i"`
+}
+
+// parseContinuation returns the span index to resume from, given by the
+// continuation query parameter, and the scope of the request.
+func parseContinuation(c echo.Context) (int, string, error) {
+	scope := cursor.Scope(c.Request().URL.Path, c.QueryParams())
+
+	raw := c.QueryParam("continuation")
+	if raw == "" {
+		return 0, scope, nil
+	}
+	var position spanContinuation
+	if err := cursor.Decode(raw, scope, &position); err != nil {
+		return 0, scope, err
+	}
+	if position.Index < 0 {
+		return 0, scope, cursor.ErrInvalid
+	}
+	return position.Index, scope, nil
+}
+
+// writeSpansLimited writes spans as a JSON array, truncated to at most
+// maxResponseBytes. At least one span is always written. When spans are
+// left out, the continuation token of the first one is set in the
+// HeaderContinuationToken header; offset is the index of spans[0].
+func writeSpansLimited(c echo.Context, spans []map[string]interface{}, offset int, scope string) error {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, span := range spans {
+		data, err := json.Marshal(span)
+		if err != nil {
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to encode span: %v", err))
+		}
+
+		if i > 0 && maxResponseBytes > 0 && buf.Len()+len(data)+2 > maxResponseBytes {
+			token, err := cursor.Encode(spanContinuation{Index: offset + i}, scope)
+			if err != nil {
+				return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to encode continuation: %v", err))
+			}
+			c.Response().Header().Set(HeaderContinuationToken, token)
+			c.Logger().Printf("Truncated response after %d of %d spans", i, len(spans))
+			break
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte(']')
+
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, buf.Bytes())
+}
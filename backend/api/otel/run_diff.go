@@ -0,0 +1,38 @@
+package api_otel
+
+import (
+	"errors"
+	"junjo-server/apierror"
+	"junjo-server/rundiff"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetWorkflowRunDiff compares two workflow executions: run A (spanId) is the
+// reference and run B (otherSpanId) the run under investigation. The diff
+// covers node-by-node durations, the executed node set and order, final state
+// keys, graph structure, and LLM outputs.
+func GetWorkflowRunDiff(c echo.Context) error {
+	spanIDA := c.Param("spanId")
+	spanIDB := c.Param("otherSpanId")
+	if spanIDA == "" || spanIDB == "" {
+		return apierror.New(http.StatusBadRequest, "spanId and otherSpanId parameters are required")
+	}
+	c.Logger().Printf("Running GetWorkflowRunDiff function for %s and %s", spanIDA, spanIDB)
+
+	runs := make([]*rundiff.Run, 0, 2)
+	for _, spanID := range []string{spanIDA, spanIDB} {
+		run, err := rundiff.LoadRun(c.Request().Context(), spanID)
+		if errors.Is(err, rundiff.ErrRunNotFound) {
+			return apierror.New(http.StatusNotFound, "workflow execution "+spanID+" not found")
+		}
+		if err != nil {
+			c.Logger().Printf("Error loading workflow execution: %v", err)
+			return apierror.New(http.StatusInternalServerError, "failed to load workflow execution")
+		}
+		runs = append(runs, run)
+	}
+
+	return c.JSON(http.StatusOK, rundiff.Compare(runs[0], runs[1]))
+}
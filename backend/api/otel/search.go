@@ -0,0 +1,93 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_search_spans.sql
+var querySearchSpans string
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+)
+
+// SearchHit is a trace containing spans that match a search query.
+type SearchHit struct {
+	TraceID      string    `json:"trace_id"`
+	ServiceName  string    `json:"service_name"`
+	Score        int64     `json:"score"`
+	MatchedSpans int64     `json:"matched_spans"`
+	TopSpanID    string    `json:"top_span_id"`
+	TopSpanName  string    `json:"top_span_name"`
+	StartTime    time.Time `json:"start_time"`
+}
+
+// SearchSpans finds traces whose span names, attributes, events, or workflow
+// state contain the q query parameter (case-insensitive). Traces are ranked by
+// where the text matched: span names weigh the most, then attributes and
+// workflow state, then events. Results can be narrowed with the service query
+// parameter and the span filters accepted by the listing endpoints.
+func SearchSpans(c echo.Context) error {
+	q := strings.TrimSpace(c.QueryParam("q"))
+	if q == "" {
+		return apierror.New(http.StatusBadRequest, "q parameter is required")
+	}
+	serviceName := c.QueryParam("service")
+	c.Logger().Printf("Running SearchSpans function for %q", q)
+
+	limit := defaultSearchLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return apierror.New(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(parsed, maxSearchLimit)
+	}
+
+	filters, err := parseSpanFilters(c, 4)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{likePattern(q), serviceName, limit}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(querySearchSpans), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	hits := []SearchHit{}
+	for rows.Next() {
+		var hit SearchHit
+		if err := rows.Scan(&hit.TraceID, &hit.ServiceName, &hit.Score, &hit.MatchedSpans, &hit.TopSpanID, &hit.TopSpanName, &hit.StartTime); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		hits = append(hits, hit)
+	}
+
+	return c.JSON(http.StatusOK, hits)
+}
+
+// likePattern builds an ILIKE pattern matching s anywhere, escaping the LIKE
+// wildcards in s.
+func likePattern(s string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + escaped + "%"
+}
@@ -0,0 +1,85 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_slowest_workflows.sql
+var querySlowestWorkflows string
+
+const (
+	defaultSlowestLimit = 10
+	maxSlowestLimit     = 100
+)
+
+// SlowWorkflowRun is a workflow run and its duration.
+type SlowWorkflowRun struct {
+	TraceID      string    `json:"trace_id"`
+	SpanID       string    `json:"span_id"`
+	WorkflowName *string   `json:"workflow_name"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	DurationMs   int64     `json:"duration_ms"`
+	StatusCode   *string   `json:"status_code"`
+}
+
+// GetSlowestWorkflows returns the N slowest workflow runs of a service, longest
+// first. N is set with the limit query parameter (default 10, max 100), and the
+// time window with the span filter query parameters.
+func GetSlowestWorkflows(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetSlowestWorkflows function for service: %s", serviceName)
+
+	limit := defaultSlowestLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return apierror.New(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(parsed, maxSlowestLimit)
+	}
+
+	filters, err := parseSpanFilters(c, 3)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, limit}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(querySlowestWorkflows), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	runs := []SlowWorkflowRun{}
+	for rows.Next() {
+		var run SlowWorkflowRun
+		if err := rows.Scan(&run.TraceID, &run.SpanID, &run.WorkflowName, &run.StartTime, &run.EndTime, &run.DurationMs, &run.StatusCode); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
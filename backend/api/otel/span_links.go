@@ -0,0 +1,85 @@
+package api_otel
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_span_links.sql
+var querySpanLinks string
+
+// SpanLink is a link between a span and a span of another (or the same)
+// trace. Outgoing links were recorded on the span itself; incoming links were
+// recorded on other spans that point at it.
+type SpanLink struct {
+	Direction  string          `json:"direction"`
+	TraceID    string          `json:"trace_id"`
+	SpanID     string          `json:"span_id"`
+	Attributes json.RawMessage `json:"attributes"`
+	// Trace tells whether Junjo has the linked trace, and where to open it.
+	Trace ResolvedTrace `json:"trace"`
+}
+
+// GetSpanLinks lists the links of a span in both directions, resolving each
+// linked trace so producer / consumer flows can be navigated.
+func GetSpanLinks(c echo.Context) error {
+	traceID := c.Param("traceId")
+	if traceID == "" {
+		return apierror.New(http.StatusBadRequest, "traceId parameter is required")
+	}
+	spanID := c.Param("spanId")
+	if spanID == "" {
+		return apierror.New(http.StatusBadRequest, "spanId parameter is required")
+	}
+	c.Logger().Printf("Running GetSpanLinks function for trace %s and span %s", traceID, spanID)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	ctx := c.Request().Context()
+
+	rows, err := db.QueryContext(ctx, querySpanLinks, traceID, spanID)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	links := []SpanLink{}
+	for rows.Next() {
+		var link SpanLink
+		var attributes *string
+		if err := rows.Scan(&link.Direction, &link.TraceID, &link.SpanID, &attributes); err != nil {
+			rows.Close()
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		link.Attributes = json.RawMessage("{}")
+		if attributes != nil {
+			link.Attributes = json.RawMessage(*attributes)
+		}
+		links = append(links, link)
+	}
+	rows.Close()
+
+	// Resolve each linked trace once.
+	resolved := map[string]ResolvedTrace{}
+	for i, link := range links {
+		trace, ok := resolved[link.TraceID]
+		if !ok {
+			if trace, err = resolveTrace(ctx, link.TraceID); err != nil {
+				c.Logger().Printf("Error resolving linked trace: %v", err)
+				return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+			}
+			resolved[link.TraceID] = trace
+		}
+		links[i].Trace = trace
+	}
+
+	return c.JSON(http.StatusOK, links)
+}
@@ -0,0 +1,70 @@
+package api_otel
+
+import (
+	"encoding/json"
+	"errors"
+	"junjo-server/apierror"
+	"junjo-server/rundiff"
+	"junjo-server/statepatch"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// StateDiff is the difference between the initial and final state of a
+// workflow, grouped by change type. Paths are JSON paths such as $.user.name;
+// arrays are compared as a whole.
+type StateDiff struct {
+	TraceID string           `json:"trace_id"`
+	SpanID  string           `json:"span_id"`
+	Added   []rundiff.Change `json:"added"`
+	Removed []rundiff.Change `json:"removed"`
+	Changed []rundiff.Change `json:"changed"`
+}
+
+// GetWorkflowStateDiff diffs the initial state (junjo_wf_state_start) of a
+// workflow or subflow span against its final state (junjo_wf_state_end).
+func GetWorkflowStateDiff(c echo.Context) error {
+	spanID := c.Param("spanId")
+	if spanID == "" {
+		return apierror.New(http.StatusBadRequest, "spanId parameter is required")
+	}
+	c.Logger().Printf("Running GetWorkflowStateDiff function for span %s", spanID)
+
+	wf, err := statepatch.LoadWorkflow(c.Request().Context(), spanID)
+	if errors.Is(err, statepatch.ErrWorkflowNotFound) {
+		return apierror.New(http.StatusNotFound, "workflow span not found")
+	}
+	if err != nil {
+		c.Logger().Printf("Error loading workflow span: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to load workflow span")
+	}
+
+	var start, end map[string]interface{}
+	if err := json.Unmarshal([]byte(wf.StateStart), &start); err != nil {
+		return apierror.New(http.StatusUnprocessableEntity, "initial state is not a JSON object")
+	}
+	if err := json.Unmarshal([]byte(wf.StateEnd), &end); err != nil {
+		return apierror.New(http.StatusUnprocessableEntity, "final state is not a JSON object")
+	}
+
+	diff := StateDiff{
+		TraceID: wf.TraceID,
+		SpanID:  wf.SpanID,
+		Added:   []rundiff.Change{},
+		Removed: []rundiff.Change{},
+		Changed: []rundiff.Change{},
+	}
+	for _, change := range rundiff.DiffObjects(start, end) {
+		switch change.Type {
+		case rundiff.ChangeAdded:
+			diff.Added = append(diff.Added, change)
+		case rundiff.ChangeRemoved:
+			diff.Removed = append(diff.Removed, change)
+		default:
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	return c.JSON(http.StatusOK, diff)
+}
@@ -0,0 +1,126 @@
+package api_otel
+
+import (
+	"encoding/json"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"regexp"
+
+	"github.com/labstack/echo/v4"
+)
+
+// stateExpression matches `<json path> <operator> <json literal>`,
+// e.g. `$.user.tier == "pro"` or `$.retries >= 3`.
+var stateExpression = regexp.MustCompile(`^\s*(\$[^\s=!<>]*)\s*(==|!=|>=|<=|>|<)\s*(.+?)\s*$`)
+
+// stateColumns maps the state query parameter to the workflow state column.
+var stateColumns = map[string]string{
+	"start": "junjo_wf_state_start",
+	"end":   "junjo_wf_state_end",
+}
+
+// addStateCondition parses a state expression and adds the equivalent
+// predicate on column to the filters. Operators are validated against a fixed
+// set, so they can be inlined; the path and value are bound.
+func (f *spanFilters) addStateCondition(column string, expr string) error {
+	match := stateExpression.FindStringSubmatch(expr)
+	if match == nil {
+		return fmt.Errorf(`invalid expression %q: expected <path> <op> <value>, e.g. $.user.tier == "pro"`, expr)
+	}
+	path, op, rawValue := match[1], match[2], match[3]
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(rawValue), &value); err != nil {
+		return fmt.Errorf("invalid value %s: must be a JSON string, number, boolean, or null", rawValue)
+	}
+
+	sqlOp := op
+	if op == "==" {
+		sqlOp = "="
+	}
+
+	switch v := value.(type) {
+	case string:
+		f.add("json_extract_string("+column+", %s::VARCHAR) "+sqlOp+" %s::VARCHAR", path, v)
+	case float64:
+		f.add("TRY_CAST(json_extract_string("+column+", %s::VARCHAR) AS DOUBLE) "+sqlOp+" %s::DOUBLE", path, v)
+	case bool:
+		if op != "==" && op != "!=" {
+			return fmt.Errorf("operator %s is not supported for booleans", op)
+		}
+		f.add("json_extract_string("+column+", %s::VARCHAR) "+sqlOp+" %s::VARCHAR", path, fmt.Sprintf("%t", v))
+	case nil:
+		isNull := "COALESCE(json_type(json_extract(" + column + ", %s::VARCHAR)), 'NULL') = 'NULL'"
+		switch op {
+		case "==":
+			f.add(isNull, path)
+		case "!=":
+			f.add("NOT "+isNull, path)
+		default:
+			return fmt.Errorf("operator %s is not supported for null", op)
+		}
+	default:
+		return fmt.Errorf("invalid value %s: must be a JSON string, number, boolean, or null", rawValue)
+	}
+	return nil
+}
+
+// GetWorkflowsByState lists the workflow spans of a service whose state
+// matches the expr query parameter, a JSON path comparison such as
+// `$.user.tier == "pro"`. The state query parameter selects the start or end
+// (default) state. Pagination and span filters work as in GetSpansTypeWorkflow.
+func GetWorkflowsByState(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	expr := c.QueryParam("expr")
+	if expr == "" {
+		return apierror.New(http.StatusBadRequest, "expr parameter is required")
+	}
+	state := c.QueryParam("state")
+	if state == "" {
+		state = "end"
+	}
+	column, ok := stateColumns[state]
+	if !ok {
+		return apierror.New(http.StatusBadRequest, "state must be start or end")
+	}
+	c.Logger().Printf("Running GetWorkflowsByState function for service %s: %s", serviceName, expr)
+
+	params, err := parsePageParams(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	filters, err := parseSpanFilters(c, 5)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	if err := filters.addStateCondition(column, expr); err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	// Execute the query
+	args := append([]interface{}{serviceName}, params.args()...)
+	rows, err := db.Query(filters.apply(querySpansTypeWorkflow), append(args, filters.args...)...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	page, err := scanSpanPage(rows, params)
+	if err != nil {
+		c.Logger().Printf("Error reading rows: %v", err)
+		return apierror.New(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,78 @@
+package api_otel
+
+import (
+	"errors"
+	"junjo-server/apierror"
+	"junjo-server/statepatch"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WorkflowState is the store state of a workflow reconstructed at a point in
+// time.
+type WorkflowState struct {
+	TraceID        string      `json:"trace_id"`
+	SpanID         string      `json:"span_id"`
+	StoreID        string      `json:"store_id"`
+	At             *time.Time  `json:"at"`
+	PatchesApplied int         `json:"patches_applied"`
+	PatchesTotal   int         `json:"patches_total"`
+	State          interface{} `json:"state"`
+}
+
+// GetWorkflowState reconstructs the store state of a workflow (or subflow)
+// span by replaying its state patches over the initial state, in order. Only
+// patches recorded at or before the optional at query parameter (RFC 3339) are
+// applied; without it the state after every patch is returned.
+func GetWorkflowState(c echo.Context) error {
+	spanID := c.Param("spanId")
+	if spanID == "" {
+		return apierror.New(http.StatusBadRequest, "spanId parameter is required")
+	}
+	c.Logger().Printf("Running GetWorkflowState function for span %s", spanID)
+
+	var at time.Time
+	if raw := c.QueryParam("at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return apierror.New(http.StatusBadRequest, "at must be an RFC 3339 timestamp")
+		}
+		at = parsed
+	}
+
+	wf, err := statepatch.LoadWorkflow(c.Request().Context(), spanID)
+	if errors.Is(err, statepatch.ErrWorkflowNotFound) {
+		return apierror.New(http.StatusNotFound, "workflow span not found")
+	}
+	if err != nil {
+		c.Logger().Printf("Error loading workflow span: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to load workflow span")
+	}
+
+	patches, err := statepatch.LoadPatches(c.Request().Context(), wf)
+	if err != nil {
+		c.Logger().Printf("Error loading state patches: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to load state patches")
+	}
+
+	state, applied, err := statepatch.Replay(wf, patches, at)
+	if err != nil {
+		c.Logger().Printf("Error replaying state patches: %v", err)
+		return apierror.New(http.StatusUnprocessableEntity, "failed to replay state patches: "+err.Error())
+	}
+
+	result := WorkflowState{
+		TraceID:        wf.TraceID,
+		SpanID:         wf.SpanID,
+		StoreID:        wf.StoreID,
+		PatchesApplied: applied,
+		PatchesTotal:   len(patches),
+		State:          state,
+	}
+	if !at.IsZero() {
+		result.At = &at
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,87 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_service_stats.sql
+var queryServiceStats string
+
+// WorkflowStats are the aggregated durations and outcomes of workflow runs.
+// Percentiles are nil when there are no runs.
+type WorkflowStats struct {
+	WorkflowName string   `json:"workflow_name"`
+	Count        int64    `json:"count"`
+	ErrorCount   int64    `json:"error_count"`
+	P50Ms        *float64 `json:"p50_ms"`
+	P90Ms        *float64 `json:"p90_ms"`
+	P99Ms        *float64 `json:"p99_ms"`
+}
+
+// ServiceStats are the workflow statistics of a service, overall and per
+// workflow name.
+type ServiceStats struct {
+	ServiceName string          `json:"service_name"`
+	Total       WorkflowStats   `json:"total"`
+	Workflows   []WorkflowStats `json:"workflows"`
+}
+
+// GetServiceStats returns the p50/p90/p99 durations, run counts, and error
+// counts of a service's workflows, grouped by workflow name. The time range and
+// other filters are given with the span filter query parameters, e.g.
+// ?start_time=2025-01-01T00:00:00Z&end_time=2025-01-02T00:00:00Z.
+func GetServiceStats(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetServiceStats function for service: %s", serviceName)
+
+	filters, err := parseSpanFilters(c, 2)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryServiceStats), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	stats := ServiceStats{
+		ServiceName: serviceName,
+		Workflows:   []WorkflowStats{},
+	}
+	for rows.Next() {
+		var isTotal bool
+		var row WorkflowStats
+		if err := rows.Scan(&isTotal, &row.WorkflowName, &row.Count, &row.ErrorCount, &row.P50Ms, &row.P90Ms, &row.P99Ms); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		if isTotal {
+			row.WorkflowName = ""
+			stats.Total = row
+		} else {
+			stats.Workflows = append(stats.Workflows, row)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,78 @@
+package api_otel
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_tool_calls.sql
+var queryToolCalls string
+
+// ToolCall is a tool invoked by an agent, with its arguments and result.
+// Error is set when the tool span ended with an error status.
+type ToolCall struct {
+	TraceID    string          `json:"trace_id"`
+	SpanID     string          `json:"span_id"`
+	SpanName   *string         `json:"span_name"`
+	ToolName   *string         `json:"tool_name"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Result     *string         `json:"result"`
+	StartTime  time.Time       `json:"start_time"`
+	EndTime    time.Time       `json:"end_time"`
+	DurationMs int64           `json:"duration_ms"`
+	Error      *string         `json:"error"`
+}
+
+// GetTraceToolCalls lists the tool calls of a trace in the order they started.
+func GetTraceToolCalls(c echo.Context) error {
+	traceID := c.Param("traceId")
+	if traceID == "" {
+		return apierror.New(http.StatusBadRequest, "traceId parameter is required")
+	}
+	c.Logger().Printf("Running GetTraceToolCalls function for trace: %s", traceID)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), queryToolCalls, traceID)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	toolCalls := []ToolCall{}
+	for rows.Next() {
+		var call ToolCall
+		var arguments, statusCode, statusMessage *string
+		if err := rows.Scan(&call.TraceID, &call.SpanID, &call.SpanName, &call.ToolName, &arguments, &call.Result, &call.StartTime, &call.EndTime, &call.DurationMs, &statusCode, &statusMessage); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		if arguments != nil {
+			call.Arguments = json.RawMessage(*arguments)
+		}
+		if statusCode != nil && *statusCode == "STATUS_CODE_ERROR" {
+			message := "error"
+			if statusMessage != nil && *statusMessage != "" {
+				message = *statusMessage
+			}
+			call.Error = &message
+		}
+		toolCalls = append(toolCalls, call)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, toolCalls)
+}
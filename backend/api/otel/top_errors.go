@@ -0,0 +1,96 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_top_errors.sql
+var queryTopErrors string
+
+const (
+	defaultTopErrorsLimit = 20
+	maxTopErrorsLimit     = 100
+)
+
+// ErrorGroup is a group of errored spans with the same exception type and
+// message. Numbers in messages are ignored when grouping, so the fingerprint
+// replaces them with N.
+type ErrorGroup struct {
+	ErrorType       string    `json:"error_type"`
+	Fingerprint     string    `json:"fingerprint"`
+	ExampleMessage  string    `json:"example_message"`
+	Count           int64     `json:"count"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+	ExampleTraceIDs []string  `json:"example_trace_ids"`
+}
+
+// GetTopErrors returns the most frequent errors of a service. A span is an
+// error when its status code is ERROR or it recorded an exception event; the
+// error is described by its first exception event, falling back to the status
+// message. The limit query parameter sets the number of groups (default 20,
+// max 100), and the time window is set with the span filter query parameters.
+func GetTopErrors(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetTopErrors function for service: %s", serviceName)
+
+	limit := defaultTopErrorsLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return apierror.New(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(parsed, maxTopErrorsLimit)
+	}
+
+	filters, err := parseSpanFilters(c, 3)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, limit}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(queryTopErrors), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	groups := []ErrorGroup{}
+	for rows.Next() {
+		var group ErrorGroup
+		var traceIDs []interface{}
+		if err := rows.Scan(&group.ErrorType, &group.Fingerprint, &group.ExampleMessage, &group.Count, &group.FirstSeen, &group.LastSeen, &traceIDs); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		group.ExampleTraceIDs = make([]string, 0, len(traceIDs))
+		for _, id := range traceIDs {
+			if s, ok := id.(string); ok {
+				group.ExampleTraceIDs = append(group.ExampleTraceIDs, s)
+			}
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, groups)
+}
@@ -0,0 +1,133 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_token_usage.sql
+var queryTokenUsage string
+
+// defaultUsageRange is the time range of the token usage when no start_time
+// filter is given.
+const defaultUsageRange = 30 * 24 * time.Hour
+
+// usageDimensions are the columns token usage can be grouped by, in the order
+// they are grouped and sorted.
+var usageDimensions = []string{"day", "service_name", "workflow_name", "model"}
+
+// usageDimensionAliases map the group_by values to usageDimensions.
+var usageDimensionAliases = map[string]string{
+	"day":      "day",
+	"service":  "service_name",
+	"workflow": "workflow_name",
+	"model":    "model",
+}
+
+// TokenUsage is the token usage and estimated cost of the LLM spans of a group.
+// Only the dimensions the usage is grouped by are set; the day is a UTC date.
+// Spans outside a workflow have an empty workflow name.
+type TokenUsage struct {
+	Day           *string `json:"day,omitempty"`
+	ServiceName   *string `json:"service_name,omitempty"`
+	WorkflowName  *string `json:"workflow_name,omitempty"`
+	Model         *string `json:"model,omitempty"`
+	SpanCount     int64   `json:"span_count"`
+	InputTokens   int64   `json:"input_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	TotalTokens   int64   `json:"total_tokens"`
+	CostUSD       float64 `json:"cost_usd"`
+	UnpricedSpans int64   `json:"unpriced_spans"`
+}
+
+// GetTokenUsage returns the token usage and estimated cost of LLM spans,
+// aggregated by the comma-separated group_by query parameter: any of day
+// (default), service, workflow and model. Usage is limited to one service with
+// service_name, and the span filter query parameters apply. Without a
+// start_time filter, the last 30 days are returned.
+func GetTokenUsage(c echo.Context) error {
+	serviceName := c.QueryParam("service_name")
+	c.Logger().Printf("Running GetTokenUsage function for service: %s", serviceName)
+
+	groupBy := map[string]bool{}
+	rawGroupBy := c.QueryParam("group_by")
+	if rawGroupBy == "" {
+		rawGroupBy = "day"
+	}
+	for _, name := range strings.Split(rawGroupBy, ",") {
+		dimension, ok := usageDimensionAliases[strings.TrimSpace(name)]
+		if !ok {
+			return apierror.New(http.StatusBadRequest, fmt.Sprintf("invalid group_by %q: must be day, service, workflow or model", name))
+		}
+		groupBy[dimension] = true
+	}
+
+	filters, err := parseSpanFilters(c, 2)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	if c.QueryParam("start_time") == "" {
+		filters.add("start_time >= %s", time.Now().Add(-defaultUsageRange).UTC())
+	}
+
+	// Dimensions are selected in a fixed order and scanned accordingly
+	var dimensions []string
+	for _, dimension := range usageDimensions {
+		if groupBy[dimension] {
+			dimensions = append(dimensions, dimension)
+		}
+	}
+	query := filters.apply(queryTokenUsage)
+	query = strings.Replace(query, "/* dimensions */", strings.Join(dimensions, ", ")+",", 1)
+	query = strings.Replace(query, "/* group by */", "GROUP BY "+strings.Join(dimensions, ", "), 1)
+	query = strings.Replace(query, "/* order by */", strings.Join(dimensions, ", ")+",", 1)
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), query, args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	usage := []TokenUsage{}
+	for rows.Next() {
+		var row TokenUsage
+		fields := map[string]**string{
+			"day":           &row.Day,
+			"service_name":  &row.ServiceName,
+			"workflow_name": &row.WorkflowName,
+			"model":         &row.Model,
+		}
+		dest := []interface{}{}
+		for _, dimension := range dimensions {
+			value := new(string)
+			*fields[dimension] = value
+			dest = append(dest, value)
+		}
+		dest = append(dest, &row.SpanCount, &row.InputTokens, &row.OutputTokens, &row.CostUSD, &row.UnpricedSpans)
+		if err := rows.Scan(dest...); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		row.TotalTokens = row.InputTokens + row.OutputTokens
+		usage = append(usage, row)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,95 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_span_volume.sql
+var querySpanVolume string
+
+// defaultVolumeRange is the time range of the volume series when no start_time
+// filter is given.
+const defaultVolumeRange = 24 * time.Hour
+
+// VolumeBucket is the number of spans, and of distinct traces, that started
+// within a time bucket.
+type VolumeBucket struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Spans       int64     `json:"spans"`
+	Traces      int64     `json:"traces"`
+}
+
+// SpanVolume is the span volume over time of a service. Buckets without spans
+// are omitted.
+type SpanVolume struct {
+	ServiceName string         `json:"service_name"`
+	Bucket      string         `json:"bucket"`
+	Buckets     []VolumeBucket `json:"buckets"`
+}
+
+// GetSpanVolume returns the span and trace counts of a service bucketed by
+// hour (default) or minute, set with the bucket query parameter. Without a
+// start_time filter, the last 24 hours are returned.
+func GetSpanVolume(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	c.Logger().Printf("Running GetSpanVolume function for service: %s", serviceName)
+
+	bucket := c.QueryParam("bucket")
+	if bucket == "" {
+		bucket = "hour"
+	}
+	if bucket != "hour" && bucket != "minute" {
+		return apierror.New(http.StatusBadRequest, "bucket must be hour or minute")
+	}
+
+	filters, err := parseSpanFilters(c, 3)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+	if c.QueryParam("start_time") == "" {
+		filters.add("start_time >= %s", time.Now().Add(-defaultVolumeRange).UTC())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	args := append([]interface{}{serviceName, bucket}, filters.args...)
+	rows, err := db.QueryContext(c.Request().Context(), filters.apply(querySpanVolume), args...)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	volume := SpanVolume{
+		ServiceName: serviceName,
+		Bucket:      bucket,
+		Buckets:     []VolumeBucket{},
+	}
+	for rows.Next() {
+		var b VolumeBucket
+		if err := rows.Scan(&b.BucketStart, &b.Spans, &b.Traces); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to scan row: %v", err))
+		}
+		b.BucketStart = b.BucketStart.UTC()
+		volume.Buckets = append(volume.Buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("failed to read rows: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, volume)
+}
@@ -0,0 +1,53 @@
+package api_otel
+
+import (
+	_ "embed"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed query_span_children.sql
+var querySpanChildren string
+
+// GetSpanChildren returns one level of a trace's span tree, for waterfalls
+// that expand incrementally: the children of the spanId path parameter, or the
+// top-level spans when it is absent. Spans are summarized (attributes, events
+// and state are fetched with GetSpan) and include their own child_count.
+// Large levels are truncated like GetNestedSpans.
+func GetSpanChildren(c echo.Context) error {
+	traceId := c.Param("traceId")
+	if traceId == "" {
+		return apierror.New(http.StatusBadRequest, "traceId parameter is required")
+	}
+	spanId := c.Param("spanId")
+	c.Logger().Printf("Running GetSpanChildren function for trace %s and span %q", traceId, spanId)
+
+	offset, scope, err := parseContinuation(c)
+	if err != nil {
+		return apierror.New(http.StatusBadRequest, err.Error())
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), querySpanChildren, traceId, spanId, offset)
+	if err != nil {
+		c.Logger().Printf("Error querying database: %v", err)
+		return apierror.New(http.StatusInternalServerError, fmt.Sprintf("database query failed: %v", err))
+	}
+	defer rows.Close()
+
+	spans, err := scanSpans(rows)
+	if err != nil {
+		c.Logger().Printf("Error reading rows: %v", err)
+		return apierror.New(http.StatusInternalServerError, err.Error())
+	}
+
+	return writeSpansLimited(c, spans, offset, scope)
+}
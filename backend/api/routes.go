@@ -13,7 +13,42 @@ func InitRoutes(e *echo.Echo) {
 	e.GET("/otel/service/:serviceName/root-spans-filtered", otel.GetRootSpansFiltered)
 	e.GET("/otel/trace/:traceId/nested-spans", otel.GetNestedSpans)
 	e.GET("/otel/trace/:traceId/span/:spanId", otel.GetSpan)
+	e.GET("/otel/trace/:traceId/waterfall", otel.GetSpanChildren)
+	e.GET("/otel/trace/:traceId/span/:spanId/children", otel.GetSpanChildren)
+	e.GET("/otel/trace/:traceId/span/:spanId/links", otel.GetSpanLinks)
+	e.GET("/otel/trace/:traceId/otlp", otel.GetTraceOTLP)
+	e.GET("/otel/trace/:traceId/tool-calls", otel.GetTraceToolCalls)
+	e.GET("/otel/trace/:traceId/cost", otel.GetTraceCost)
 	e.GET("/otel/spans/type/workflow/:serviceName", otel.GetSpansTypeWorkflow)
+	e.GET("/otel/service/:serviceName/workflows/state", otel.GetWorkflowsByState)
+	e.GET("/otel/workflow/:spanId/state", otel.GetWorkflowState)
+	e.GET("/otel/workflow/:spanId/state-diff", otel.GetWorkflowStateDiff)
+	e.GET("/otel/workflow/:spanId/graph-coverage", otel.GetWorkflowGraphCoverage)
+	e.GET("/otel/workflow/:spanId/graph-layout", otel.GetWorkflowGraphLayout)
+	e.GET("/otel/service/:serviceName/graphs", otel.GetGraphVersions)
+	e.GET("/otel/service/:serviceName/graphs/:workflowName", otel.GetGraphVersions)
+	e.GET("/otel/graph/:graphHash", otel.GetGraphStructure)
+	e.GET("/otel/graph/:graphHash/diff/:otherGraphHash", otel.GetGraphDiff)
+	e.GET("/otel/workflow/:spanId/diff/:otherSpanId", otel.GetWorkflowRunDiff)
+	e.GET("/otel/service/:serviceName/stats", otel.GetServiceStats)
+	e.GET("/otel/service/:serviceName/nodes/stats", otel.GetNodeStats)
+	e.GET("/otel/service/:serviceName/cost", otel.GetServiceCost)
+	e.GET("/otel/service/:serviceName/users", otel.GetEndUsers)
+	e.GET("/otel/service/:serviceName/users/:userId/traces", otel.GetEndUserTraces)
+	e.GET("/otel/service/:serviceName/prompts", otel.GetPromptVersions)
+	e.GET("/otel/service/:serviceName/prompts/:promptId/traces", otel.GetPromptVersionTraces)
+	e.GET("/otel/service/:serviceName/error-rate", otel.GetErrorRate)
+	e.GET("/otel/service/:serviceName/volume", otel.GetSpanVolume)
+	e.GET("/otel/service/:serviceName/workflows/slowest", otel.GetSlowestWorkflows)
+	e.GET("/otel/service/:serviceName/failures", otel.GetWorkflowFailures)
+	e.GET("/otel/service/:serviceName/workflows/duration-histogram", otel.GetDurationHistogram)
+	e.GET("/otel/service/:serviceName/errors/top", otel.GetTopErrors)
+	e.GET("/otel/resolve/:traceId", otel.ResolveTrace)
+	e.GET("/otel/usage", otel.GetTokenUsage)
+	e.GET("/otel/search", otel.SearchSpans)
+	e.POST("/otel/export", otel.ExportSpans)
+	e.POST("/otel/import", otel.ImportSpans)
+	e.POST("/otel/import/:source", otel.ImportExternalTraces)
 
 	llm.RegisterRoutes(e)
 }
@@ -0,0 +1,63 @@
+// Package apierror defines the JSON body of every error response of the
+// backend:
+//
+//	{"code": "not_found", "message": "...", "details": {...}, "request_id": "..."}
+//
+// Handlers return an *Error, an *echo.HTTPError or any other error, and
+// Handler, installed as Echo's HTTPErrorHandler, writes it.
+package apierror
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Error is an error response.
+type Error struct {
+	// Status is the HTTP status code of the response.
+	Status int `json:"-"`
+	// Code is a stable, machine-readable identifier of the error, by default
+	// derived from the status: "bad_request", "not_found", ...
+	Code string `json:"code"`
+	// Message is a human-readable description of the error.
+	Message string `json:"message"`
+	// Details holds structured context, such as the limits of a quota.
+	Details any `json:"details,omitempty"`
+	// RequestID is the ID of the request, set by Handler.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// New creates an error response with the code of its status.
+func New(status int, message string) *Error {
+	return &Error{Status: status, Code: CodeFor(status), Message: message}
+}
+
+// WithCode replaces the code of the error.
+func (e *Error) WithCode(code string) *Error {
+	e.Code = code
+	return e
+}
+
+// WithDetails sets the details of the error.
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// CodeFor returns the code of an HTTP status: its status text in snake case,
+// such as "too_many_requests", or "error" for unknown statuses.
+func CodeFor(status int) string {
+	if status == http.StatusInternalServerError {
+		return "internal_error"
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	text = strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+	return strings.ToLower(text)
+}
@@ -0,0 +1,90 @@
+package apierror
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"junjo-server/requestid"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// FieldError describes a field that failed validation.
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+}
+
+// Handler is an echo.HTTPErrorHandler writing errors as an *Error with the
+// request ID. Errors other than *Error and *echo.HTTPError are mapped to a
+// status: validation errors to 400, missing rows to 404, timeouts to 504, and
+// anything else to a 500 whose message is logged rather than returned.
+func Handler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	resp := From(err)
+	if resp.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(c.Request().Context(), "Request failed", "method", c.Request().Method, "path", c.Path(), "status", resp.Status, "error", err)
+	}
+	resp.RequestID = requestid.FromContext(c.Request().Context())
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(resp.Status)
+	} else {
+		err = c.JSON(resp.Status, resp)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+// From converts an error to an error response.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		resp := *apiErr
+		return &resp
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		// Unwrap errors raised by middleware with an HTTP error as cause
+		if internal, ok := he.Internal.(*echo.HTTPError); ok {
+			he = internal
+		}
+		resp := New(he.Code, http.StatusText(he.Code))
+		switch message := he.Message.(type) {
+		case string:
+			resp.Message = message
+		case nil:
+		case error:
+			resp.Message = message.Error()
+		default:
+			resp.Details = message
+		}
+		return resp
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, FieldError{Field: fieldErr.Field(), Rule: fieldErr.Tag()})
+		}
+		return New(http.StatusBadRequest, "Validation failed").WithCode("validation_failed").WithDetails(fields)
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return New(http.StatusNotFound, "Not found")
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(http.StatusGatewayTimeout, "Request timed out")
+	}
+	return New(http.StatusInternalServerError, "Internal server error")
+}
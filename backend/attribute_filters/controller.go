@@ -0,0 +1,13 @@
+package attribute_filters
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	filterGroup := e.Group("/attribute-filters")
+
+	filterGroup.GET("", HandleListFilters)
+	filterGroup.PUT("", HandleUpsertFilter)
+	filterGroup.DELETE("/:id", HandleDeleteFilter)
+}
@@ -0,0 +1,94 @@
+package attribute_filters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"junjo-server/telemetry"
+)
+
+// Init loads the attribute filters into the span processor.
+func Init(ctx context.Context) error {
+	return Reload(ctx)
+}
+
+// Reload applies the stored filters to spans indexed afterwards.
+func Reload(ctx context.Context) error {
+	filters, err := ListFilters(ctx)
+	if err != nil {
+		return err
+	}
+	byService := map[string]telemetry.AttributeFilter{}
+	for _, filter := range filters {
+		byService[filter.ServiceName] = telemetry.AttributeFilter{Mode: filter.Mode, Keys: filter.Keys}
+	}
+	telemetry.SetAttributeFilters(byService)
+	return nil
+}
+
+// UpsertFilter creates or replaces the filter of a service.
+func UpsertFilter(ctx context.Context, serviceName string, mode string, keys []string) (AttributeFilter, error) {
+	encoded, err := json.Marshal(keys)
+	if err != nil {
+		return AttributeFilter{}, err
+	}
+	queries := db_gen.New(db.DB)
+	filter, err := queries.UpsertAttributeFilter(ctx, db_gen.UpsertAttributeFilterParams{
+		ServiceName: serviceName,
+		Mode:        mode,
+		Keys:        string(encoded),
+	})
+	if err != nil {
+		return AttributeFilter{}, err
+	}
+	return decode(filter)
+}
+
+// ListFilters retrieves all filters.
+func ListFilters(ctx context.Context) ([]AttributeFilter, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListAttributeFilters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filters := []AttributeFilter{}
+	for _, filter := range stored {
+		decoded, err := decode(filter)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, decoded)
+	}
+	return filters, nil
+}
+
+// GetFilter retrieves a single filter by id.
+func GetFilter(ctx context.Context, id int64) (db_gen.AttributeFilter, error) {
+	queries := db_gen.New(db.DB)
+	return queries.GetAttributeFilter(ctx, id)
+}
+
+// DeleteFilter removes a filter by id.
+func DeleteFilter(ctx context.Context, id int64) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteAttributeFilter(ctx, id)
+}
+
+func decode(filter db_gen.AttributeFilter) (AttributeFilter, error) {
+	decoded := AttributeFilter{
+		ID:          filter.ID,
+		ServiceName: filter.ServiceName,
+		Mode:        filter.Mode,
+		CreatedAt:   filter.CreatedAt,
+		UpdatedAt:   filter.UpdatedAt,
+	}
+	if err := json.Unmarshal([]byte(filter.Keys), &decoded.Keys); err != nil {
+		return decoded, fmt.Errorf("invalid keys of attribute filter %d: %w", filter.ID, err)
+	}
+	if decoded.Keys == nil {
+		decoded.Keys = []string{}
+	}
+	return decoded, nil
+}
@@ -0,0 +1,22 @@
+package attribute_filters
+
+import "time"
+
+// UpsertFilterRequest sets the attribute filter of a service. An empty
+// service_name sets the default for services without their own filter. Keys
+// ending in * match any key with that prefix.
+type UpsertFilterRequest struct {
+	ServiceName string   `json:"service_name"`
+	Mode        string   `json:"mode" validate:"required,oneof=allow deny"`
+	Keys        []string `json:"keys" validate:"dive,required"`
+}
+
+// AttributeFilter is the attribute filter of a service.
+type AttributeFilter struct {
+	ID          int64     `json:"id"`
+	ServiceName string    `json:"service_name"`
+	Mode        string    `json:"mode"`
+	Keys        []string  `json:"keys"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,75 @@
+package attribute_filters
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListFilters lists all attribute filters.
+func HandleListFilters(c echo.Context) error {
+	filters, err := ListFilters(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list attribute filters:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve attribute filters")
+	}
+
+	return c.JSON(http.StatusOK, filters)
+}
+
+// HandleUpsertFilter creates or replaces the attribute filter of a service.
+// Only spans indexed afterwards are filtered.
+func HandleUpsertFilter(c echo.Context) error {
+	var req UpsertFilterRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if req.Keys == nil {
+		req.Keys = []string{}
+	}
+
+	filter, err := UpsertFilter(c.Request().Context(), req.ServiceName, req.Mode, req.Keys)
+	if err != nil {
+		c.Logger().Error("Failed to save attribute filter:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save attribute filter")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload attribute filters:", err)
+	}
+
+	return c.JSON(http.StatusOK, filter)
+}
+
+// HandleDeleteFilter deletes an attribute filter by id.
+func HandleDeleteFilter(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid filter id")
+	}
+
+	if _, err := GetFilter(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Attribute filter not found")
+		}
+		c.Logger().Error("Failed to look up attribute filter:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete attribute filter")
+	}
+
+	if err := DeleteFilter(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete attribute filter:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete attribute filter")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload attribute filters:", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
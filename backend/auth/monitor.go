@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"junjo-server/metrics"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Session metrics, served at /metrics.
+var (
+	sessionValidations = metrics.NewCounter("junjo_auth_session_validations_total", "Requests authenticated with a valid session.")
+	sessionFailures    = metrics.NewCounter("junjo_auth_session_failures_total", "Requests rejected for lacking a valid session.")
+	csrfRejections     = metrics.NewCounter("junjo_auth_csrf_rejections_total", "Requests rejected by the CSRF check.")
+	signIns            = metrics.NewCounter("junjo_auth_sign_ins_total", "Successful sign-ins.")
+	signInFailures     = metrics.NewCounter("junjo_auth_sign_in_failures_total", "Sign-ins rejected for invalid credentials.")
+	anomalies          = metrics.NewCounter("junjo_auth_anomalies_total", "Unusual session patterns flagged in the audit log.")
+)
+
+// Audit events.
+const (
+	EventSignIn           = "auth.sign_in"
+	EventSignInFailed     = "auth.sign_in_failed"
+	EventSignOut          = "auth.sign_out"
+	EventCSRFRejected     = "auth.csrf_rejected"
+	EventSessionsPerIP    = "auth.anomaly.sessions_per_ip"
+	EventImpossibleTravel = "auth.anomaly.impossible_travel"
+)
+
+// MonitorConfig controls the detection of unusual session patterns.
+type MonitorConfig struct {
+	// SessionsPerIP is the number of sign-ins from one IP address within
+	// Window above which the address is flagged. 0 disables the check.
+	SessionsPerIP int
+	// Window is the sliding window SessionsPerIP is counted over.
+	Window time.Duration
+	// LatitudeHeader and LongitudeHeader name the request headers carrying the
+	// client location, as set by a CDN or reverse proxy. The geovelocity check
+	// is disabled unless both are set.
+	LatitudeHeader  string
+	LongitudeHeader string
+	// MaxSpeedKmh is the travel speed between two requests of the same user
+	// above which the second is flagged.
+	MaxSpeedKmh float64
+	// AuditLogPath is the file audit events are appended to as JSON lines.
+	// When empty, they are written to the default logger.
+	AuditLogPath string
+}
+
+type location struct {
+	latitude  float64
+	longitude float64
+	seenAt    time.Time
+}
+
+// monitor tracks recent sign-ins per IP address and the last known location
+// of each user.
+type monitor struct {
+	mu        sync.Mutex
+	cfg       MonitorConfig
+	audit     *slog.Logger
+	signIns   map[string][]time.Time
+	flagged   map[string]time.Time
+	locations map[string]location
+}
+
+var mon = &monitor{
+	audit:     slog.Default(),
+	signIns:   map[string][]time.Time{},
+	flagged:   map[string]time.Time{},
+	locations: map[string]location{},
+}
+
+// InitMonitor applies cfg to the session monitor and opens the audit log.
+func InitMonitor(cfg MonitorConfig) error {
+	audit := slog.Default()
+	if cfg.AuditLogPath != "" {
+		f, err := os.OpenFile(cfg.AuditLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("open audit log: %w", err)
+		}
+		audit = slog.New(slog.NewJSONHandler(f, nil))
+	}
+
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+	mon.cfg = cfg
+	mon.audit = audit
+	return nil
+}
+
+// Audit records a security event in the audit log.
+func Audit(c echo.Context, event string, attrs ...any) {
+	audit(slog.LevelInfo, c, event, attrs...)
+}
+
+func audit(level slog.Level, c echo.Context, event string, attrs ...any) {
+	mon.mu.Lock()
+	logger := mon.audit
+	mon.mu.Unlock()
+
+	attrs = append([]any{"audit", true, "event", event, "ip", c.RealIP(), "method", c.Request().Method, "path", c.Path()}, attrs...)
+	logger.Log(c.Request().Context(), level, "security audit", attrs...)
+}
+
+// RecordSessionValidation counts an authenticated request and checks the
+// user's location for implausible travel.
+func RecordSessionValidation(c echo.Context, userEmail string) {
+	sessionValidations.Inc()
+	mon.checkTravel(c, userEmail)
+}
+
+// RecordSessionFailure counts a request rejected for lacking a valid session.
+func RecordSessionFailure() {
+	sessionFailures.Inc()
+}
+
+// RecordCSRFRejection counts and audits a request rejected by the CSRF check.
+func RecordCSRFRejection(c echo.Context, err error) {
+	csrfRejections.Inc()
+	audit(slog.LevelWarn, c, EventCSRFRejected, "error", err.Error())
+}
+
+// recordSignIn counts and audits a successful sign-in, and flags the client IP
+// address when it has opened too many sessions.
+func recordSignIn(c echo.Context, userEmail string) {
+	signIns.Inc()
+	Audit(c, EventSignIn, "user_email", userEmail)
+	mon.checkSessionsPerIP(c, userEmail)
+	mon.checkTravel(c, userEmail)
+}
+
+// recordSignInFailure counts and audits a sign-in with invalid credentials.
+func recordSignInFailure(c echo.Context, email string) {
+	signInFailures.Inc()
+	audit(slog.LevelWarn, c, EventSignInFailed, "user_email", email)
+}
+
+// checkSessionsPerIP flags the client IP address when its sign-ins within the
+// window exceed the configured limit. Each address is flagged at most once per
+// window.
+func (m *monitor) checkSessionsPerIP(c echo.Context, userEmail string) {
+	ip := c.RealIP()
+	now := time.Now()
+
+	m.mu.Lock()
+	if m.cfg.SessionsPerIP == 0 {
+		m.mu.Unlock()
+		return
+	}
+	cutoff := now.Add(-m.cfg.Window)
+	m.prune(cutoff)
+
+	recent := append(m.signIns[ip], now)
+	m.signIns[ip] = recent
+	count := len(recent)
+	exceeded := count > m.cfg.SessionsPerIP
+	if exceeded {
+		if flaggedAt, ok := m.flagged[ip]; ok && flaggedAt.After(cutoff) {
+			exceeded = false
+		} else {
+			m.flagged[ip] = now
+		}
+	}
+	limit, window := m.cfg.SessionsPerIP, m.cfg.Window
+	m.mu.Unlock()
+
+	if exceeded {
+		anomalies.Inc()
+		audit(slog.LevelWarn, c, EventSessionsPerIP,
+			"user_email", userEmail,
+			"sessions", count,
+			"limit", limit,
+			"window", window.String(),
+		)
+	}
+}
+
+// prune drops the sign-ins and flags older than cutoff. The caller must hold
+// m.mu.
+func (m *monitor) prune(cutoff time.Time) {
+	for ip, times := range m.signIns {
+		i := 0
+		for i < len(times) && times[i].Before(cutoff) {
+			i++
+		}
+		if i == len(times) {
+			delete(m.signIns, ip)
+		} else if i > 0 {
+			m.signIns[ip] = times[i:]
+		}
+	}
+	for ip, flaggedAt := range m.flagged {
+		if flaggedAt.Before(cutoff) {
+			delete(m.flagged, ip)
+		}
+	}
+}
+
+// checkTravel flags a request whose location is too far from the user's
+// previous one to have been reached at the configured maximum speed. Requests
+// without a location are ignored.
+func (m *monitor) checkTravel(c echo.Context, userEmail string) {
+	m.mu.Lock()
+	latHeader, lonHeader, maxSpeed := m.cfg.LatitudeHeader, m.cfg.LongitudeHeader, m.cfg.MaxSpeedKmh
+	m.mu.Unlock()
+	if latHeader == "" {
+		return
+	}
+
+	lat, err := strconv.ParseFloat(c.Request().Header.Get(latHeader), 64)
+	if err != nil {
+		return
+	}
+	lon, err := strconv.ParseFloat(c.Request().Header.Get(lonHeader), 64)
+	if err != nil {
+		return
+	}
+	current := location{latitude: lat, longitude: lon, seenAt: time.Now()}
+
+	m.mu.Lock()
+	previous, ok := m.locations[userEmail]
+	m.locations[userEmail] = current
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	distance := haversineKm(previous, current)
+	hours := current.seenAt.Sub(previous.seenAt).Hours()
+	// Nearby locations are within the precision of IP geolocation.
+	if distance < 100 {
+		return
+	}
+	speed := math.Inf(1)
+	if hours > 0 {
+		speed = distance / hours
+	}
+	if speed <= maxSpeed {
+		return
+	}
+
+	anomalies.Inc()
+	audit(slog.LevelWarn, c, EventImpossibleTravel,
+		"user_email", userEmail,
+		"distance_km", math.Round(distance),
+		"elapsed", current.seenAt.Sub(previous.seenAt).Round(time.Second).String(),
+		"from_latitude", previous.latitude,
+		"from_longitude", previous.longitude,
+		"to_latitude", current.latitude,
+		"to_longitude", current.longitude,
+	)
+}
+
+// haversineKm returns the great-circle distance between two locations.
+func haversineKm(a, b location) float64 {
+	const earthRadiusKm = 6371
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(b.latitude - a.latitude)
+	dLon := toRad(b.longitude - a.longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(a.latitude))*math.Cos(toRad(b.latitude))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
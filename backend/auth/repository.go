@@ -2,10 +2,24 @@ package auth
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"junjo-server/db"
 	"junjo-server/db_gen"
+	"time"
 )
 
+// ErrUsersExist is returned by CreateFirstUser when a user already exists.
+var ErrUsersExist = errors.New("users already exist")
+
+// firstUserLockTimeout bounds how long CreateFirstUser waits for the write
+// lock held by a concurrent call.
+const firstUserLockTimeout = 5 * time.Second
+
+// ErrDuplicateEmail is returned by CreateUser when the email is taken.
+var ErrDuplicateEmail = errors.New("a user with this email already exists")
+
 // DbHasUsers checks if any users exist and returns a boolean.
 func DbHasUsers(ctx context.Context) (bool, error) {
 	queries := db_gen.New(db.DB)
@@ -16,12 +30,63 @@ func DbHasUsers(ctx context.Context) (bool, error) {
 	return count > 0, nil
 }
 
+// CreateFirstUser creates the first user in a transaction. The insert is
+// conditional on the users table being empty, so of several concurrent calls
+// exactly one succeeds and the others return ErrUsersExist.
+//
+// The transaction takes the write lock as it begins, waiting up to
+// firstUserLockTimeout for a concurrent call to finish, so the losing call
+// sees the winner's user rather than failing to upgrade a read lock with
+// SQLITE_BUSY.
+func CreateFirstUser(ctx context.Context, email string, password string) error {
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", firstUserLockTimeout.Milliseconds())); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		}
+	}()
+
+	queries := db_gen.New(conn)
+	_, err = queries.CreateFirstUser(ctx, db_gen.CreateFirstUserParams{
+		Email:        email,
+		PasswordHash: password,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUsersExist
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// CreateUser creates a user, returning ErrDuplicateEmail when the email is
+// taken. The unique index on email is the guard, rather than a prior lookup.
 func CreateUser(ctx context.Context, email string, password string) error {
 	queries := db_gen.New(db.DB)
 	_, err := queries.CreateUser(ctx, db_gen.CreateUserParams{
 		Email:        email,
 		PasswordHash: password,
 	})
+	if db.IsUniqueViolation(err) {
+		return ErrDuplicateEmail
+	}
 	if err != nil {
 		return err
 	}
@@ -4,16 +4,15 @@ import (
 	"errors"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 
+	"junjo-server/apierror"
 	"junjo-server/db_gen"
 
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
-	"modernc.org/sqlite"
 )
 
 // HandleDbHasUsers calls the repository to check user existence.
@@ -22,9 +21,7 @@ func HandleDbHasUsers(c echo.Context) error {
 
 	exists, err := DbHasUsers(c.Request().Context())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to fetch user existence",
-		})
+		return apierror.New(http.StatusInternalServerError, "Failed to fetch user existence")
 	}
 
 	return c.JSON(http.StatusOK, map[string]bool{
@@ -41,33 +38,21 @@ func HandleCreateFirstUser(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	// Check if any users exist
-	exists, err := DbHasUsers(c.Request().Context())
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed check authorization for first user creation.",
-		})
-	}
-	if exists {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Users already exist, cannot create first user.",
-		})
-	}
-
 	// Hash the provided password
 	hashedPassword, err := hashPassword(req.Password)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to hash password",
-		})
+		return apierror.New(http.StatusInternalServerError, "Failed to hash password")
 	}
 
-	// Create the first user
-	create_err := CreateUser(c.Request().Context(), req.Email, hashedPassword)
+	// Create the first user. The existence check happens inside the insert,
+	// so concurrent requests cannot both create a first user.
+	create_err := CreateFirstUser(c.Request().Context(), req.Email, hashedPassword)
+	if errors.Is(create_err, ErrUsersExist) {
+		return apierror.New(http.StatusConflict, "Users already exist, cannot create first user.")
+	}
 	if create_err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to create first user",
-		})
+		c.Logger().Errorf("Database error during first user creation: %v", create_err)
+		return apierror.New(http.StatusInternalServerError, "Failed to create first user")
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
@@ -84,29 +69,18 @@ func HandleCreateUser(c echo.Context) error {
 	// Hash the provided password
 	hashedPassword, err := hashPassword(req.Password)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to hash password",
-		})
+		return apierror.New(http.StatusInternalServerError, "Failed to hash password")
 	}
 
 	err = CreateUser(c.Request().Context(), req.Email, hashedPassword)
 	if err != nil {
-		var sqliteErr *sqlite.Error
-
-		if errors.As(err, &sqliteErr) {
-			// Check if the extended error code is 2067 (SQLITE_CONSTRAINT_UNIQUE)
-			if sqliteErr.Code() == 2067 {
-				return c.JSON(http.StatusConflict, map[string]string{
-					"error": "A user with this email already exists",
-				})
-			}
+		if errors.Is(err, ErrDuplicateEmail) {
+			return apierror.New(http.StatusConflict, "A user with this email already exists")
 		}
 
 		// Other errors
-		c.Logger().Errorf("Database error during user creation: %v (Code: %d)", err, sqliteErr.Code())
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to create user due to a database error",
-		})
+		c.Logger().Errorf("Database error during user creation: %v", err)
+		return apierror.New(http.StatusInternalServerError, "Failed to create user due to a database error")
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
@@ -117,9 +91,7 @@ func HandleCreateUser(c echo.Context) error {
 func HandleListUsers(c echo.Context) error {
 	users, err := ListUsers(c.Request().Context())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to fetch users",
-		})
+		return apierror.New(http.StatusInternalServerError, "Failed to fetch users")
 	}
 
 	// Return empty list instead of null if no users exist
@@ -152,6 +124,18 @@ func HandleDeleteUser(c echo.Context) error {
 	})
 }
 
+// sessionDomain is the domain session cookies are set for, if any.
+var sessionDomain string
+
+// SetSessionDomain sets the root domain session cookies are set for, covering
+// its subdomains. It is empty outside production.
+func SetSessionDomain(domain string) {
+	if domain != "" {
+		log.Printf("setting production auth domain to %v", domain)
+	}
+	sessionDomain = domain
+}
+
 func SignIn(c echo.Context) error {
 	// Log the request
 	log.Printf("request: %v", c.Request())
@@ -169,6 +153,7 @@ func SignIn(c echo.Context) error {
 	user, err := ValidateCredentials(c, req.Email, req.Password)
 	if err != nil {
 		log.Printf("failed to validate credentials: %v", err)
+		recordSignInFailure(c, req.Email)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
 	}
 
@@ -190,12 +175,8 @@ func SignIn(c echo.Context) error {
 
 	// IF IN PRODUCTION:
 	// Set the Domain to the production auth domain
-	if os.Getenv("JUNJO_ENV") == "production" {
-		log.Printf("setting production auth domain to %v", os.Getenv("JUNJO_PROD_AUTH_DOMAIN"))
-		authDomain := os.Getenv("JUNJO_PROD_AUTH_DOMAIN")
-		if authDomain != "" {
-			options.Domain = "." + authDomain // covers subdomains
-		}
+	if sessionDomain != "" {
+		options.Domain = "." + sessionDomain // covers subdomains
 	}
 
 	// Set the session options
@@ -208,6 +189,8 @@ func SignIn(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save session")
 	}
 
+	recordSignIn(c, user.Email)
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "signed in",
 	})
@@ -221,11 +204,13 @@ func SignOut(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get session")
 	}
 
+	userEmail, _ := sess.Values["userEmail"].(string)
 	sess.Options.MaxAge = -1
 	if err := sess.Save(c.Request(), c.Response()); err != nil {
 		log.Printf("failed to save session: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save session")
 	}
+	Audit(c, EventSignOut, "user_email", userEmail)
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "signed out",
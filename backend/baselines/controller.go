@@ -0,0 +1,15 @@
+package baselines
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	baselineGroup := e.Group("/workflow-baselines")
+
+	baselineGroup.GET("", HandleListBaselines)
+	baselineGroup.PUT("", HandleUpsertBaseline)
+	baselineGroup.DELETE("/:id", HandleDeleteBaseline)
+
+	e.GET("/otel/workflow/:spanId/baseline-comparison", HandleCompareToBaseline)
+}
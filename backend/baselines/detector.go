@@ -0,0 +1,114 @@
+package baselines
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/db_gen"
+	"junjo-server/rundiff"
+	"junjo-server/webhook"
+)
+
+// Config controls the drift detector.
+type Config struct {
+	// Interval is how often executions completed since the previous check are
+	// compared against their workflow's baseline.
+	Interval time.Duration
+	// Notifier is sent an event for each drifting execution.
+	Notifier *webhook.Notifier
+}
+
+// Run periodically compares newly completed workflow executions against their
+// baselines until ctx is cancelled, alerting on executions whose drift score
+// exceeds the baseline's threshold.
+func Run(ctx context.Context, cfg Config) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	since := time.Now().UTC()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		until := time.Now().UTC()
+		baselines, err := ListBaselines(ctx)
+		if err != nil {
+			slog.Error("failed to load workflow baselines", "error", err)
+			continue
+		}
+		for _, baseline := range baselines {
+			if err := Detect(ctx, baseline, since, until, cfg.Notifier); err != nil {
+				slog.Error("workflow drift check failed", "service", baseline.ServiceName, "workflow", baseline.WorkflowName, "error", err)
+			}
+		}
+		since = until
+	}
+}
+
+// queryCompletedRuns selects the executions of a workflow that ended within
+// [since, until), other than the baseline itself.
+const queryCompletedRuns = `
+	SELECT span_id
+	FROM spans
+	WHERE service_name = $1
+		AND name = $2
+		AND junjo_span_type = 'workflow'
+		AND span_id != $3
+		AND end_time >= $4
+		AND end_time < $5
+	ORDER BY end_time;`
+
+// Detect compares the executions of the baseline's workflow that ended within
+// [since, until) against the baseline, and notifies notifier of those that
+// drifted.
+func Detect(ctx context.Context, baseline db_gen.WorkflowBaseline, since time.Time, until time.Time, notifier *webhook.Notifier) error {
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(ctx, queryCompletedRuns, baseline.ServiceName, baseline.WorkflowName, baseline.SpanID, since, until)
+	if err != nil {
+		return fmt.Errorf("failed to query workflow executions: %w", err)
+	}
+	var spanIDs []string
+	for rows.Next() {
+		var spanID string
+		if err := rows.Scan(&spanID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan workflow execution: %w", err)
+		}
+		spanIDs = append(spanIDs, spanID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read workflow executions: %w", err)
+	}
+	if len(spanIDs) == 0 {
+		return nil
+	}
+
+	baselineRun, err := rundiff.LoadRun(ctx, baseline.SpanID)
+	if err != nil {
+		return fmt.Errorf("failed to load baseline execution: %w", err)
+	}
+
+	for _, spanID := range spanIDs {
+		run, err := rundiff.LoadRun(ctx, spanID)
+		if err != nil {
+			return fmt.Errorf("failed to load workflow execution %s: %w", spanID, err)
+		}
+		comparison := newBaselineComparison(baseline, rundiff.Compare(baselineRun, run))
+		if comparison.ExceedsThreshold {
+			notify(notifier, baseline, comparison)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package baselines
+
+import (
+	"context"
+	"log/slog"
+
+	"junjo-server/db_gen"
+	"junjo-server/webhook"
+)
+
+// notify alerts owners that a workflow execution drifted from its baseline.
+// The event is always logged, and is additionally POSTed as JSON to the
+// notifier's webhook when set. Delivery is best-effort and never blocks the caller.
+func notify(notifier *webhook.Notifier, baseline db_gen.WorkflowBaseline, comparison BaselineComparison) {
+	slog.Warn("workflow drifted from baseline",
+		"service", baseline.ServiceName,
+		"workflow", baseline.WorkflowName,
+		"trace_id", comparison.Run.TraceID,
+		"span_id", comparison.Run.SpanID,
+		"drift_score", comparison.DriftScore,
+		"drift_threshold", comparison.DriftThreshold,
+	)
+
+	if !notifier.Enabled() {
+		return
+	}
+	go func() {
+		err := notifier.Send(context.Background(), map[string]any{
+			"event":         "workflow.baseline_drift",
+			"service_name":  baseline.ServiceName,
+			"workflow_name": baseline.WorkflowName,
+			"comparison":    comparison,
+		})
+		if err != nil {
+			slog.Error("failed to send drift notification", "error", err)
+		}
+	}()
+}
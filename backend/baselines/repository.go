@@ -0,0 +1,40 @@
+package baselines
+
+import (
+	"context"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+)
+
+// UpsertBaseline sets the baseline execution of a service / workflow pair.
+func UpsertBaseline(ctx context.Context, params db_gen.UpsertWorkflowBaselineParams) (db_gen.WorkflowBaseline, error) {
+	queries := db_gen.New(db.DB)
+	return queries.UpsertWorkflowBaseline(ctx, params)
+}
+
+// ListBaselines retrieves all workflow baselines.
+func ListBaselines(ctx context.Context) ([]db_gen.WorkflowBaseline, error) {
+	queries := db_gen.New(db.DB)
+	return queries.ListWorkflowBaselines(ctx)
+}
+
+// GetBaseline retrieves a single baseline by id.
+func GetBaseline(ctx context.Context, id int64) (db_gen.WorkflowBaseline, error) {
+	queries := db_gen.New(db.DB)
+	return queries.GetWorkflowBaseline(ctx, id)
+}
+
+// GetBaselineForWorkflow retrieves the baseline of a service / workflow pair.
+func GetBaselineForWorkflow(ctx context.Context, serviceName string, workflowName string) (db_gen.WorkflowBaseline, error) {
+	queries := db_gen.New(db.DB)
+	return queries.GetWorkflowBaselineByWorkflow(ctx, db_gen.GetWorkflowBaselineByWorkflowParams{
+		ServiceName:  serviceName,
+		WorkflowName: workflowName,
+	})
+}
+
+// DeleteBaseline removes a baseline by id.
+func DeleteBaseline(ctx context.Context, id int64) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteWorkflowBaseline(ctx, id)
+}
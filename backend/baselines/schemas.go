@@ -0,0 +1,34 @@
+package baselines
+
+import (
+	"junjo-server/db_gen"
+	"junjo-server/rundiff"
+)
+
+// defaultDriftThreshold is used when a baseline is set without a threshold.
+const defaultDriftThreshold = 0.25
+
+// UpsertBaselineRequest marks a workflow execution as the golden baseline of
+// its workflow, replacing any previous baseline.
+type UpsertBaselineRequest struct {
+	SpanID         string   `json:"span_id" validate:"required"`
+	DriftThreshold *float64 `json:"drift_threshold" validate:"omitempty,gte=0,lte=1"`
+}
+
+// BaselineComparison is a workflow execution compared against the baseline of
+// its workflow.
+type BaselineComparison struct {
+	rundiff.Comparison
+	BaselineID       int64   `json:"baseline_id"`
+	DriftThreshold   float64 `json:"drift_threshold"`
+	ExceedsThreshold bool    `json:"exceeds_threshold"`
+}
+
+func newBaselineComparison(baseline db_gen.WorkflowBaseline, comparison rundiff.Comparison) BaselineComparison {
+	return BaselineComparison{
+		Comparison:       comparison,
+		BaselineID:       baseline.ID,
+		DriftThreshold:   baseline.DriftThreshold,
+		ExceedsThreshold: comparison.DriftScore > baseline.DriftThreshold,
+	}
+}
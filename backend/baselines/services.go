@@ -0,0 +1,132 @@
+package baselines
+
+import (
+	"database/sql"
+	"errors"
+	"junjo-server/apierror"
+	"junjo-server/db_gen"
+	"junjo-server/rundiff"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListBaselines lists all workflow baselines.
+func HandleListBaselines(c echo.Context) error {
+	baselines, err := ListBaselines(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list workflow baselines:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve workflow baselines")
+	}
+
+	// Return empty list instead of null if no baselines exist
+	if baselines == nil {
+		baselines = []db_gen.WorkflowBaseline{}
+	}
+
+	return c.JSON(http.StatusOK, baselines)
+}
+
+// HandleUpsertBaseline marks a workflow execution as the baseline of its
+// workflow.
+func HandleUpsertBaseline(c echo.Context) error {
+	var req UpsertBaselineRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	run, err := rundiff.LoadRun(c.Request().Context(), req.SpanID)
+	if errors.Is(err, rundiff.ErrRunNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Workflow execution not found")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to load workflow execution:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save workflow baseline")
+	}
+
+	threshold := defaultDriftThreshold
+	if req.DriftThreshold != nil {
+		threshold = *req.DriftThreshold
+	}
+
+	baseline, err := UpsertBaseline(c.Request().Context(), db_gen.UpsertWorkflowBaselineParams{
+		ServiceName:    run.ServiceName,
+		WorkflowName:   run.WorkflowName,
+		TraceID:        run.TraceID,
+		SpanID:         run.SpanID,
+		DriftThreshold: threshold,
+	})
+	if err != nil {
+		c.Logger().Error("Failed to save workflow baseline:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save workflow baseline")
+	}
+
+	return c.JSON(http.StatusOK, baseline)
+}
+
+// HandleDeleteBaseline deletes a workflow baseline by id.
+func HandleDeleteBaseline(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid baseline id")
+	}
+
+	if _, err := GetBaseline(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Workflow baseline not found")
+		}
+		c.Logger().Error("Failed to look up workflow baseline:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete workflow baseline")
+	}
+
+	if err := DeleteBaseline(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete workflow baseline:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete workflow baseline")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleCompareToBaseline compares a workflow execution against the baseline
+// of its workflow: the executed node set and order, node durations, the final
+// state, and the resulting drift score.
+func HandleCompareToBaseline(c echo.Context) error {
+	spanID := c.Param("spanId")
+	if spanID == "" {
+		return apierror.New(http.StatusBadRequest, "spanId parameter is required")
+	}
+	ctx := c.Request().Context()
+
+	run, err := rundiff.LoadRun(ctx, spanID)
+	if errors.Is(err, rundiff.ErrRunNotFound) {
+		return apierror.New(http.StatusNotFound, "workflow execution not found")
+	}
+	if err != nil {
+		c.Logger().Printf("Error loading workflow execution: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to load workflow execution")
+	}
+
+	baseline, err := GetBaselineForWorkflow(ctx, run.ServiceName, run.WorkflowName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.New(http.StatusNotFound, "workflow has no baseline")
+	}
+	if err != nil {
+		c.Logger().Printf("Error loading workflow baseline: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to load workflow baseline")
+	}
+
+	baselineRun, err := rundiff.LoadRun(ctx, baseline.SpanID)
+	if errors.Is(err, rundiff.ErrRunNotFound) {
+		return apierror.New(http.StatusNotFound, "baseline execution no longer exists")
+	}
+	if err != nil {
+		c.Logger().Printf("Error loading baseline execution: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to load baseline execution")
+	}
+
+	return c.JSON(http.StatusOK, newBaselineComparison(baseline, rundiff.Compare(baselineRun, run)))
+}
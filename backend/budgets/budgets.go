@@ -0,0 +1,160 @@
+package budgets
+
+import (
+	"context"
+	"fmt"
+	"junjo-server/api/llm"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"log/slog"
+	"time"
+)
+
+// timestampLayout is the format SQLite stores CURRENT_TIMESTAMP in, so period
+// bounds compare with created_at as text.
+const timestampLayout = "2006-01-02 15:04:05"
+
+// Budget scopes.
+const (
+	ScopeUser     = "user"
+	ScopeProvider = "provider"
+)
+
+// Budget periods. Both reset at 00:00 UTC.
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// Budget is the token consumption of a user or provider against a limit in
+// the current period.
+type Budget struct {
+	Scope     string    `json:"scope"`
+	Key       string    `json:"key"`
+	Period    string    `json:"period"`
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// limit is a configured token limit for every user or provider.
+type limit struct {
+	scope  string
+	period string
+	tokens int64
+}
+
+// limits are the configured token limits. Budgets are disabled when empty.
+var limits []limit
+
+// Limits are the tokens every user and every provider may consume per day
+// and per month. Zero disables a budget.
+type Limits struct {
+	UserDaily       int64
+	UserMonthly     int64
+	ProviderDaily   int64
+	ProviderMonthly int64
+}
+
+// Init sets the token budgets and enforces them on every LLM request. Usage
+// is read from the LLM audit log, so budgets count the input and output
+// tokens reported by the providers.
+func Init(budgets Limits) {
+	limits = nil
+	for _, l := range []limit{
+		{scope: ScopeUser, period: PeriodDaily, tokens: budgets.UserDaily},
+		{scope: ScopeUser, period: PeriodMonthly, tokens: budgets.UserMonthly},
+		{scope: ScopeProvider, period: PeriodDaily, tokens: budgets.ProviderDaily},
+		{scope: ScopeProvider, period: PeriodMonthly, tokens: budgets.ProviderMonthly},
+	} {
+		if l.tokens > 0 {
+			limits = append(limits, l)
+			slog.Info("LLM token budget enabled", "scope", l.scope, "period", l.period, "tokens", l.tokens)
+		}
+	}
+
+	llm.SetBudgetChecker(check)
+}
+
+// check returns a *llm.BudgetError for the first budget of the user or the
+// provider that has been spent.
+func check(ctx context.Context, userEmail, provider string) error {
+	budgets, err := budgetsFor(ctx, userEmail, []string{provider})
+	if err != nil {
+		return fmt.Errorf("failed to check LLM token budgets: %w", err)
+	}
+	for _, b := range budgets {
+		if b.Used >= b.Limit {
+			return &llm.BudgetError{
+				Scope:    b.Scope,
+				Key:      b.Key,
+				Period:   b.Period,
+				Limit:    b.Limit,
+				Used:     b.Used,
+				ResetsAt: b.ResetsAt,
+			}
+		}
+	}
+	return nil
+}
+
+// Remaining returns the budgets that apply to a user's requests: their own,
+// and those of every provider.
+func Remaining(ctx context.Context, userEmail string) ([]Budget, error) {
+	return budgetsFor(ctx, userEmail, llm.Providers)
+}
+
+// budgetsFor returns the current consumption of the user's budgets and the
+// budgets of the providers. User budgets are skipped when userEmail is empty.
+func budgetsFor(ctx context.Context, userEmail string, providers []string) ([]Budget, error) {
+	queries := db_gen.New(db.DB)
+	now := time.Now()
+
+	budgets := []Budget{}
+	for _, l := range limits {
+		keys := providers
+		if l.scope == ScopeUser {
+			if userEmail == "" {
+				continue
+			}
+			keys = []string{userEmail}
+		}
+
+		start, resetsAt := periodBounds(l.period, now)
+		for _, key := range keys {
+			params := db_gen.SumLLMAuditTokensParams{StartTime: start.Format(timestampLayout)}
+			if l.scope == ScopeUser {
+				params.UserEmail = key
+			} else {
+				params.Provider = key
+			}
+			used, err := queries.SumLLMAuditTokens(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+
+			budgets = append(budgets, Budget{
+				Scope:     l.scope,
+				Key:       key,
+				Period:    l.period,
+				Limit:     l.tokens,
+				Used:      used,
+				Remaining: max(l.tokens-used, 0),
+				ResetsAt:  resetsAt,
+			})
+		}
+	}
+	return budgets, nil
+}
+
+// periodBounds returns the UTC start and end of the period containing now.
+func periodBounds(period string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	if period == PeriodMonthly {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := now.Truncate(24 * time.Hour)
+	return start, start.AddDate(0, 0, 1)
+}
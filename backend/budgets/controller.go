@@ -0,0 +1,9 @@
+package budgets
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	e.GET("/llm/budgets", HandleGetRemaining)
+}
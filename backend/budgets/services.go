@@ -0,0 +1,24 @@
+package budgets
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleGetRemaining returns the remaining token budgets for the signed-in
+// user's LLM requests. The list is empty when no budgets are configured.
+func HandleGetRemaining(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	budgets, err := Remaining(c.Request().Context(), userEmail)
+	if err != nil {
+		c.Logger().Error("Failed to get LLM token budgets:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve LLM token budgets")
+	}
+
+	return c.JSON(http.StatusOK, budgets)
+}
@@ -0,0 +1,10 @@
+package cardinality
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	e.GET("/otel/promoted-columns", HandleListColumns)
+	e.DELETE("/otel/promoted-columns/:table/:column/demotion", HandleRestoreColumn)
+}
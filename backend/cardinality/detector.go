@@ -0,0 +1,174 @@
+// Package cardinality monitors the number of distinct values of promoted
+// attributes, those stored in a column of their own, and demotes runaway
+// columns back to attributes_json-only storage before they degrade queries.
+package cardinality
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/metrics"
+	"junjo-server/telemetry"
+)
+
+// Statuses of a promoted column.
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusDemoted = "demoted"
+)
+
+var demotions = metrics.NewCounterVec("junjo_promoted_column_demotions_total", "Promoted attribute columns demoted for reaching the cardinality cap.", "table", "column")
+
+// Config controls the background cardinality monitor.
+type Config struct {
+	// Interval is how often promoted columns are checked.
+	Interval time.Duration
+	// Window bounds the spans whose values are counted.
+	Window time.Duration
+	// WarnDistinct is the number of distinct values a warning is logged at.
+	WarnDistinct int64
+	// MaxDistinct is the number of distinct values a column is demoted at.
+	MaxDistinct int64
+}
+
+// queryDemotedColumns selects the demoted columns.
+const queryDemotedColumns = `
+	SELECT table_name, column_name
+	FROM column_cardinality
+	WHERE status = 'demoted';`
+
+// queryColumnStatus selects the status of a column. Args: table_name,
+// column_name.
+const queryColumnStatus = `
+	SELECT status
+	FROM column_cardinality
+	WHERE table_name = ? AND column_name = ?;`
+
+// queryRecordCheck records (or replaces) the check of a column. A demoted
+// column keeps the time it was first demoted at.
+const queryRecordCheck = `
+	INSERT INTO column_cardinality (table_name, column_name, distinct_values, non_null_values, status, checked_at, demoted_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (table_name, column_name) DO UPDATE SET
+		distinct_values = excluded.distinct_values,
+		non_null_values = excluded.non_null_values,
+		status = excluded.status,
+		checked_at = excluded.checked_at,
+		demoted_at = excluded.demoted_at
+	RETURNING table_name, column_name, distinct_values, non_null_values, status, checked_at, demoted_at;`
+
+// Init demotes the columns recorded as demoted. It is called after the span
+// types are registered.
+func Init(ctx context.Context) error {
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(ctx, queryDemotedColumns)
+	if err != nil {
+		return fmt.Errorf("failed to query demoted columns: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return fmt.Errorf("failed to scan demoted column: %w", err)
+		}
+		telemetry.SetColumnDemoted(table, column, true)
+	}
+	return rows.Err()
+}
+
+// Run periodically checks the promoted columns until ctx is cancelled.
+func Run(ctx context.Context, cfg Config) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := Check(ctx, cfg, time.Now().UTC()); err != nil {
+			slog.Error("column cardinality check failed", "error", err)
+		}
+	}
+}
+
+// Check counts the distinct values of each promoted column in the spans of
+// the last cfg.Window, warns about those at cfg.WarnDistinct and demotes
+// those at cfg.MaxDistinct. Demoted columns are not counted again: they stay
+// demoted until promoted with Restore.
+func Check(ctx context.Context, cfg Config, now time.Time) ([]Column, error) {
+	db := db_duckdb.DB
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	checked := []Column{}
+	for _, promoted := range telemetry.PromotedColumns() {
+		var status string
+		err := db.QueryRowContext(ctx, queryColumnStatus, promoted.Table, promoted.Column).Scan(&status)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return checked, fmt.Errorf("failed to query status of %s.%s: %w", promoted.Table, promoted.Column, err)
+		}
+		if status == StatusDemoted {
+			continue
+		}
+
+		// Table and column names are validated identifiers
+		query := fmt.Sprintf("SELECT approx_count_distinct(%[2]s), COUNT(%[2]s) FROM %[1]s WHERE start_time >= ?;", promoted.Table, promoted.Column)
+		var distinct, nonNull int64
+		if err := db.QueryRowContext(ctx, query, now.Add(-cfg.Window)).Scan(&distinct, &nonNull); err != nil {
+			return checked, fmt.Errorf("failed to count values of %s.%s: %w", promoted.Table, promoted.Column, err)
+		}
+
+		status = StatusOK
+		var demotedAt *time.Time
+		switch {
+		case distinct >= cfg.MaxDistinct:
+			status = StatusDemoted
+			demotedAt = &now
+		case distinct >= cfg.WarnDistinct:
+			status = StatusWarning
+		}
+
+		column, err := record(ctx, promoted, distinct, nonNull, status, now, demotedAt)
+		if err != nil {
+			return checked, err
+		}
+		checked = append(checked, column)
+
+		switch status {
+		case StatusDemoted:
+			telemetry.SetColumnDemoted(promoted.Table, promoted.Column, true)
+			demotions.Add(1, promoted.Table, promoted.Column)
+			slog.Warn("promoted column demoted to attributes_json: too many distinct values",
+				"table", promoted.Table, "column", promoted.Column, "distinct_values", distinct, "max_distinct", cfg.MaxDistinct)
+		case StatusWarning:
+			slog.Warn("promoted column is nearing the cardinality cap",
+				"table", promoted.Table, "column", promoted.Column, "distinct_values", distinct, "warn_distinct", cfg.WarnDistinct, "max_distinct", cfg.MaxDistinct)
+		}
+	}
+	return checked, nil
+}
+
+// record stores the check of a column.
+func record(ctx context.Context, promoted telemetry.PromotedColumn, distinct, nonNull int64, status string, now time.Time, demotedAt *time.Time) (Column, error) {
+	var column Column
+	err := db_duckdb.DB.QueryRowContext(ctx, queryRecordCheck, promoted.Table, promoted.Column, distinct, nonNull, status, now, demotedAt).
+		Scan(&column.Table, &column.Column, &column.DistinctValues, &column.NonNullValues, &column.Status, &column.CheckedAt, &column.DemotedAt)
+	if err != nil {
+		return Column{}, fmt.Errorf("failed to record cardinality of %s.%s: %w", promoted.Table, promoted.Column, err)
+	}
+	return column, nil
+}
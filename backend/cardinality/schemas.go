@@ -0,0 +1,14 @@
+package cardinality
+
+import "time"
+
+// Column is the last cardinality check of a promoted column.
+type Column struct {
+	Table          string     `json:"table"`
+	Column         string     `json:"column"`
+	DistinctValues int64      `json:"distinct_values"`
+	NonNullValues  int64      `json:"non_null_values"`
+	Status         string     `json:"status"`
+	CheckedAt      time.Time  `json:"checked_at"`
+	DemotedAt      *time.Time `json:"demoted_at"`
+}
@@ -0,0 +1,90 @@
+package cardinality
+
+import (
+	"fmt"
+	"net/http"
+
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/telemetry"
+
+	"github.com/labstack/echo/v4"
+)
+
+// queryListColumns lists the last check of every checked column.
+const queryListColumns = `
+	SELECT table_name, column_name, distinct_values, non_null_values, status, checked_at, demoted_at
+	FROM column_cardinality
+	ORDER BY table_name, column_name;`
+
+// queryRestoreColumn promotes a demoted column again. Args: table_name,
+// column_name.
+const queryRestoreColumn = `
+	UPDATE column_cardinality
+	SET status = 'ok', demoted_at = NULL
+	WHERE table_name = ? AND column_name = ? AND status = 'demoted';`
+
+// HandleListColumns lists the promoted columns with their last cardinality
+// check. Columns that were not checked yet are listed without counts.
+func HandleListColumns(c echo.Context) error {
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), queryListColumns)
+	if err != nil {
+		c.Logger().Printf("Error listing promoted columns: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to list promoted columns")
+	}
+	defer rows.Close()
+
+	checked := map[telemetry.PromotedColumn]Column{}
+	for rows.Next() {
+		var column Column
+		if err := rows.Scan(&column.Table, &column.Column, &column.DistinctValues, &column.NonNullValues, &column.Status, &column.CheckedAt, &column.DemotedAt); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, "failed to list promoted columns")
+		}
+		checked[telemetry.PromotedColumn{Table: column.Table, Column: column.Column}] = column
+	}
+
+	columns := []Column{}
+	for _, promoted := range telemetry.PromotedColumns() {
+		column, ok := checked[promoted]
+		if !ok {
+			column = Column{Table: promoted.Table, Column: promoted.Column, Status: StatusOK}
+		}
+		columns = append(columns, column)
+	}
+
+	return c.JSON(http.StatusOK, columns)
+}
+
+// HandleRestoreColumn promotes a demoted column again. It is demoted again
+// by the next check if it still has too many distinct values.
+func HandleRestoreColumn(c echo.Context) error {
+	table, column := c.Param("table"), c.Param("column")
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	result, err := db.ExecContext(c.Request().Context(), queryRestoreColumn, table, column)
+	if err != nil {
+		c.Logger().Printf("Error restoring promoted column: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to restore promoted column")
+	}
+	restored, err := result.RowsAffected()
+	if err != nil {
+		c.Logger().Printf("Error restoring promoted column: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to restore promoted column")
+	}
+	if restored == 0 {
+		return apierror.New(http.StatusNotFound, "demoted column not found")
+	}
+	telemetry.SetColumnDemoted(table, column, false)
+
+	return c.NoContent(http.StatusNoContent)
+}
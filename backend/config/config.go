@@ -0,0 +1,701 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Running environments of the server.
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Config is the configuration of the server. It is read from the YAML file at
+// JUNJO_CONFIG_FILE, when set, and from the environment variable named by each
+// field's env tag, which takes precedence over the file.
+type Config struct {
+	// Env is the running environment, development or production.
+	Env string `yaml:"env" env:"JUNJO_ENV"`
+
+	// ProdAuthDomain is the root domain session cookies are set for in
+	// production, covering its subdomains.
+	ProdAuthDomain string `yaml:"prod_auth_domain" env:"JUNJO_PROD_AUTH_DOMAIN"`
+
+	// SessionSecret signs session cookies and pagination cursors.
+	SessionSecret string `yaml:"session_secret" env:"JUNJO_SESSION_SECRET"`
+
+	// CredentialsKey encrypts stored provider credentials. It defaults to the
+	// session secret.
+	CredentialsKey string `yaml:"credentials_key" env:"JUNJO_CREDENTIALS_KEY"`
+
+	// AllowOrigins are the origins allowed by CORS. Any origin is allowed
+	// when empty.
+	AllowOrigins []string `yaml:"allow_origins" env:"JUNJO_ALLOW_ORIGINS"`
+
+	// FrontendURL is the public URL of the Junjo frontend, used to build
+	// absolute links.
+	FrontendURL string `yaml:"frontend_url" env:"JUNJO_FRONTEND_URL"`
+
+	// SpanTypesPath is a JSON file of custom span types.
+	SpanTypesPath string `yaml:"span_types_path" env:"JUNJO_SPAN_TYPES_PATH"`
+
+	// ModelPricingPath is a JSON file of model prices seeded at startup.
+	ModelPricingPath string `yaml:"model_pricing_path" env:"JUNJO_MODEL_PRICING_PATH"`
+
+	// EndUserAttributes are the span attributes naming the end user of a
+	// trace, comma separated.
+	EndUserAttributes string `yaml:"enduser_attributes" env:"JUNJO_ENDUSER_ATTRIBUTES"`
+
+	// ImportMaxBytes caps the size of span import files, after gzip
+	// decompression. Larger files are rejected with 413.
+	ImportMaxBytes int `yaml:"import_max_bytes" env:"JUNJO_IMPORT_MAX_BYTES"`
+
+	// DebugEndpoints serves pprof profiles and runtime stats under /debug to
+	// signed-in users.
+	DebugEndpoints bool `yaml:"debug_endpoints" env:"JUNJO_DEBUG_ENDPOINTS"`
+
+	// MetricsToken is the bearer token /metrics is scraped with. Without it,
+	// /metrics requires a session like any other route.
+	MetricsToken string `yaml:"metrics_token" env:"JUNJO_METRICS_TOKEN"`
+
+	Listen ListenConfig `yaml:"listen"`
+
+	// IngestionAddr is the internal gRPC address of the ingestion service
+	// spans are read from.
+	IngestionAddr string `yaml:"ingestion_addr" env:"JUNJO_INGESTION_ADDR"`
+
+	InternalTLS InternalTLSConfig `yaml:"internal_tls"`
+
+	InternalAuth InternalAuthConfig `yaml:"internal_auth"`
+
+	GRPC GRPCConfig `yaml:"grpc"`
+
+	SelfTrace SelfTraceConfig `yaml:"self_trace"`
+
+	Gemini GeminiConfig `yaml:"gemini"`
+
+	Poller PollerConfig `yaml:"poller"`
+
+	SpanWriter SpanWriterConfig `yaml:"span_writer"`
+
+	TailSampling TailSamplingConfig `yaml:"tail_sampling"`
+
+	ContentRedaction ContentRedactionConfig `yaml:"content_redaction"`
+
+	// MaxResponseBytes caps the size of span list responses, which are
+	// truncated with a continuation token beyond it. Zero disables the cap.
+	MaxResponseBytes int `yaml:"max_response_bytes" env:"JUNJO_MAX_RESPONSE_BYTES"`
+
+	AuthMonitor AuthMonitorConfig `yaml:"auth_monitor"`
+
+	Retention RetentionConfig `yaml:"retention"`
+
+	Quotas QuotasConfig `yaml:"quotas"`
+
+	LLMRateLimit LLMRateLimitConfig `yaml:"llm_rate_limit"`
+
+	LLMBudgets LLMBudgetsConfig `yaml:"llm_budgets"`
+
+	SLA SLAConfig `yaml:"sla"`
+
+	Baselines BaselinesConfig `yaml:"baselines"`
+
+	PatchCheck PatchCheckConfig `yaml:"patch_check"`
+
+	Cardinality CardinalityConfig `yaml:"cardinality"`
+}
+
+// InternalTLSConfig configures mutual TLS between the backend and the
+// ingestion service. The certificate is served by the internal gRPC server
+// and presented to the ingestion service; peers must present a certificate
+// signed by the CA. It is disabled unless every file is set.
+type InternalTLSConfig struct {
+	CertFile string `yaml:"cert_file" env:"JUNJO_INTERNAL_TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"JUNJO_INTERNAL_TLS_KEY_FILE"`
+	CAFile   string `yaml:"ca_file" env:"JUNJO_INTERNAL_TLS_CA_FILE"`
+
+	// ServerName is the name the ingestion service's certificate is
+	// verified for. It defaults to the host of IngestionAddr.
+	ServerName string `yaml:"server_name" env:"JUNJO_INTERNAL_TLS_SERVER_NAME"`
+}
+
+// ListenConfig holds the host:port addresses the servers bind to. An empty
+// host binds every interface.
+type ListenConfig struct {
+	// HTTP serves the web UI API.
+	HTTP string `yaml:"http" env:"JUNJO_HTTP_ADDR"`
+
+	// InternalGRPC serves the internal auth service the ingestion service
+	// validates API keys with.
+	InternalGRPC string `yaml:"internal_grpc" env:"JUNJO_INTERNAL_GRPC_ADDR"`
+}
+
+// InternalAuthConfig restricts the callers of the internal auth gRPC service.
+// Each check applies only when it is configured.
+type InternalAuthConfig struct {
+	// Token is a shared secret callers send in the x-junjo-internal-token
+	// metadata.
+	Token string `yaml:"token" env:"JUNJO_INTERNAL_AUTH_TOKEN"`
+
+	// AllowedNetworks are the CIDR ranges, or single IP addresses, callers
+	// may connect from.
+	AllowedNetworks []string `yaml:"allowed_networks" env:"JUNJO_INTERNAL_ALLOWED_NETWORKS"`
+
+	// AllowedClientNames are the names, matched against the common name and
+	// DNS names, of the client certificates accepted over mutual TLS.
+	AllowedClientNames []string `yaml:"allowed_client_names" env:"JUNJO_INTERNAL_ALLOWED_CLIENT_NAMES"`
+}
+
+// GRPCConfig tunes the internal gRPC server and the ingestion service client.
+// A zero value keeps gRPC's default.
+type GRPCConfig struct {
+	// MaxRecvMsgSize and MaxSendMsgSize are the largest messages, in bytes,
+	// received and sent. gRPC receives up to 4 MiB by default, which large
+	// span batches read from the ingestion service can exceed.
+	MaxRecvMsgSize int `yaml:"max_recv_msg_size" env:"JUNJO_GRPC_MAX_RECV_MSG_SIZE"`
+	MaxSendMsgSize int `yaml:"max_send_msg_size" env:"JUNJO_GRPC_MAX_SEND_MSG_SIZE"`
+
+	// KeepaliveTime is how long a connection is idle before it is pinged, and
+	// KeepaliveTimeout how long to wait for the ping's acknowledgement before
+	// closing it.
+	KeepaliveTime    time.Duration `yaml:"keepalive_time" env:"JUNJO_GRPC_KEEPALIVE_TIME"`
+	KeepaliveTimeout time.Duration `yaml:"keepalive_timeout" env:"JUNJO_GRPC_KEEPALIVE_TIMEOUT"`
+
+	// KeepaliveMinTime is the shortest ping interval the server accepts from
+	// clients before closing the connection.
+	KeepaliveMinTime time.Duration `yaml:"keepalive_min_time" env:"JUNJO_GRPC_KEEPALIVE_MIN_TIME"`
+
+	// ConnectTimeout bounds the handshake of connections accepted by the
+	// server and each connection attempt of the client.
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"JUNJO_GRPC_CONNECT_TIMEOUT"`
+}
+
+// SelfTraceConfig configures the backend's own traces, which are exported
+// to the ingestion service so the server can be debugged in its own UI.
+type SelfTraceConfig struct {
+	Enabled bool `yaml:"enabled" env:"JUNJO_SELF_TRACE"`
+
+	// Endpoint is the host:port address of the ingestion service's public
+	// OTLP gRPC server.
+	Endpoint string `yaml:"endpoint" env:"JUNJO_SELF_TRACE_ENDPOINT"`
+
+	// APIKey is a Junjo API key the traces are exported with.
+	APIKey string `yaml:"api_key" env:"JUNJO_SELF_TRACE_API_KEY"`
+
+	// TLS verifies the endpoint against the system roots.
+	TLS bool `yaml:"tls" env:"JUNJO_SELF_TRACE_TLS"`
+}
+
+// GeminiConfig configures the Gemini API provider.
+type GeminiConfig struct {
+	// APIKey is used when no key is stored.
+	APIKey string `yaml:"api_key" env:"GEMINI_API_KEY"`
+
+	// BaseURL is the Gemini API requests are sent to, such as a proxy or a
+	// regional endpoint.
+	BaseURL string `yaml:"base_url" env:"GEMINI_API_BASE_URL"`
+}
+
+// PollerConfig controls how often spans are read from the ingestion service,
+// and how many are read at a time.
+type PollerConfig struct {
+	Interval  time.Duration `yaml:"interval" env:"JUNJO_POLL_INTERVAL"`
+	BatchSize int           `yaml:"batch_size" env:"JUNJO_POLL_BATCH_SIZE"`
+}
+
+// SpanWriterConfig controls how polled spans are batched into DuckDB
+// commits: a batch is written once it holds MaxRows spans, or once its oldest
+// span has waited MaxLatency.
+type SpanWriterConfig struct {
+	MaxRows    int           `yaml:"max_rows" env:"JUNJO_INDEX_FLUSH_MAX_ROWS"`
+	MaxLatency time.Duration `yaml:"max_latency" env:"JUNJO_INDEX_FLUSH_MAX_LATENCY"`
+}
+
+// TailSamplingConfig configures tail-based sampling, which keeps errored and
+// slow traces and a fraction of the others once they are complete.
+type TailSamplingConfig struct {
+	Enabled bool `yaml:"enabled" env:"JUNJO_TAIL_SAMPLING"`
+
+	// DecisionWait is how long a trace must go without new spans before it
+	// is sampled.
+	DecisionWait time.Duration `yaml:"decision_wait" env:"JUNJO_TAIL_SAMPLING_DECISION_WAIT"`
+
+	// LatencyThreshold keeps traces lasting at least this long. Zero disables
+	// the latency check.
+	LatencyThreshold time.Duration `yaml:"latency_threshold" env:"JUNJO_TAIL_SAMPLING_LATENCY_THRESHOLD"`
+
+	// KeepRate is the fraction of healthy traces kept, between 0 and 1.
+	KeepRate float64 `yaml:"keep_rate" env:"JUNJO_TAIL_SAMPLING_KEEP_RATE"`
+
+	// MaxTraces bounds the number of buffered traces.
+	MaxTraces int `yaml:"max_traces" env:"JUNJO_TAIL_SAMPLING_MAX_TRACES"`
+}
+
+// ContentRedactionConfig controls how prompt and completion content is
+// stored: as received (off), as its SHA-256 hash (hash), or cut to
+// TruncateLength characters (truncate).
+type ContentRedactionConfig struct {
+	Mode           string `yaml:"mode" env:"JUNJO_CONTENT_REDACTION"`
+	TruncateLength int    `yaml:"truncate_length" env:"JUNJO_CONTENT_TRUNCATE_LENGTH"`
+}
+
+// AuthMonitorConfig controls the detection of unusual session patterns.
+type AuthMonitorConfig struct {
+	// SessionsPerIP is the number of sign-ins from one IP address within
+	// Window above which the address is flagged. Zero disables the check.
+	SessionsPerIP int           `yaml:"sessions_per_ip" env:"JUNJO_AUTH_SESSIONS_PER_IP"`
+	Window        time.Duration `yaml:"anomaly_window" env:"JUNJO_AUTH_ANOMALY_WINDOW"`
+
+	// GeoLatitudeHeader and GeoLongitudeHeader name the request headers
+	// carrying the client location, as set by a CDN or reverse proxy. The
+	// geovelocity check is disabled unless both are set.
+	GeoLatitudeHeader  string  `yaml:"geo_latitude_header" env:"JUNJO_AUTH_GEO_LATITUDE_HEADER"`
+	GeoLongitudeHeader string  `yaml:"geo_longitude_header" env:"JUNJO_AUTH_GEO_LONGITUDE_HEADER"`
+	GeoMaxSpeedKmh     float64 `yaml:"geo_max_speed_kmh" env:"JUNJO_AUTH_GEO_MAX_SPEED_KMH"`
+
+	// AuditLogPath is the file audit events are appended to as JSON lines.
+	// They are written to the default logger when empty.
+	AuditLogPath string `yaml:"audit_log_path" env:"JUNJO_AUDIT_LOG_PATH"`
+}
+
+// RetentionConfig controls how long spans are kept in DuckDB.
+type RetentionConfig struct {
+	// MaxAge is how long spans are kept. Zero disables retention.
+	MaxAge time.Duration `yaml:"max_age" env:"JUNJO_SPAN_RETENTION"`
+
+	// Interval is how often expired spans are deleted.
+	Interval time.Duration `yaml:"interval" env:"JUNJO_SPAN_RETENTION_INTERVAL"`
+
+	// ArchivePath is a local directory or an s3:// URI expired spans are
+	// exported to before they are deleted.
+	ArchivePath string `yaml:"archive_path" env:"JUNJO_SPAN_ARCHIVE_PATH"`
+}
+
+// QuotasConfig sets the daily quotas of spans indexed per service and of LLM
+// requests per user. A quota is disabled unless one of its limits is set;
+// consumers over the soft limit are rejected once the grace period ends, or
+// at the hard limit.
+type QuotasConfig struct {
+	SpansSoftLimit int64 `yaml:"spans_soft_limit" env:"JUNJO_QUOTA_SPANS_SOFT_LIMIT"`
+	SpansHardLimit int64 `yaml:"spans_hard_limit" env:"JUNJO_QUOTA_SPANS_HARD_LIMIT"`
+	LLMSoftLimit   int64 `yaml:"llm_soft_limit" env:"JUNJO_QUOTA_LLM_SOFT_LIMIT"`
+	LLMHardLimit   int64 `yaml:"llm_hard_limit" env:"JUNJO_QUOTA_LLM_HARD_LIMIT"`
+
+	GracePeriod time.Duration `yaml:"grace_period" env:"JUNJO_QUOTA_GRACE_PERIOD"`
+
+	// WebhookURL is POSTed an event when a soft limit is reached.
+	WebhookURL string `yaml:"webhook_url" env:"JUNJO_QUOTA_WEBHOOK_URL"`
+}
+
+// LLMRateLimitConfig limits the LLM requests of each user. Zero disables a
+// limit; Burst defaults to PerMinute.
+type LLMRateLimitConfig struct {
+	PerMinute     int64 `yaml:"per_minute" env:"JUNJO_LLM_RATE_LIMIT_PER_MINUTE"`
+	Burst         int64 `yaml:"burst" env:"JUNJO_LLM_RATE_LIMIT_BURST"`
+	MaxConcurrent int64 `yaml:"max_concurrent" env:"JUNJO_LLM_MAX_CONCURRENT"`
+}
+
+// LLMBudgetsConfig sets the tokens every user and every provider may consume
+// per day and per month. Zero disables a budget.
+type LLMBudgetsConfig struct {
+	UserDailyTokens       int64 `yaml:"user_daily_tokens" env:"JUNJO_LLM_BUDGET_USER_DAILY_TOKENS"`
+	UserMonthlyTokens     int64 `yaml:"user_monthly_tokens" env:"JUNJO_LLM_BUDGET_USER_MONTHLY_TOKENS"`
+	ProviderDailyTokens   int64 `yaml:"provider_daily_tokens" env:"JUNJO_LLM_BUDGET_PROVIDER_DAILY_TOKENS"`
+	ProviderMonthlyTokens int64 `yaml:"provider_monthly_tokens" env:"JUNJO_LLM_BUDGET_PROVIDER_MONTHLY_TOKENS"`
+}
+
+// SLAConfig controls the detection of workflow executions missing their SLA.
+type SLAConfig struct {
+	CheckInterval time.Duration `yaml:"check_interval" env:"JUNJO_SLA_CHECK_INTERVAL"`
+
+	// Lookback bounds how far back, beyond the SLA itself, executions are
+	// checked.
+	Lookback time.Duration `yaml:"lookback" env:"JUNJO_SLA_LOOKBACK"`
+
+	// WebhookURL is POSTed an event for each missed SLA.
+	WebhookURL string `yaml:"webhook_url" env:"JUNJO_SLA_WEBHOOK_URL"`
+}
+
+// BaselinesConfig controls the detection of workflow executions drifting
+// from their baseline.
+type BaselinesConfig struct {
+	CheckInterval time.Duration `yaml:"check_interval" env:"JUNJO_BASELINE_CHECK_INTERVAL"`
+
+	// WebhookURL is POSTed an event for each drifting execution.
+	WebhookURL string `yaml:"webhook_url" env:"JUNJO_BASELINE_WEBHOOK_URL"`
+}
+
+// PatchCheckConfig controls the verification of workflow state patch chains.
+type PatchCheckConfig struct {
+	Interval  time.Duration `yaml:"interval" env:"JUNJO_PATCH_CHECK_INTERVAL"`
+	Lookback  time.Duration `yaml:"lookback" env:"JUNJO_PATCH_CHECK_LOOKBACK"`
+	BatchSize int           `yaml:"batch_size" env:"JUNJO_PATCH_CHECK_BATCH_SIZE"`
+}
+
+// CardinalityConfig controls the monitoring of promoted attribute columns,
+// which are demoted to attributes_json-only storage at MaxDistinct distinct
+// values.
+type CardinalityConfig struct {
+	Interval     time.Duration `yaml:"interval" env:"JUNJO_CARDINALITY_CHECK_INTERVAL"`
+	Window       time.Duration `yaml:"window" env:"JUNJO_CARDINALITY_WINDOW"`
+	WarnDistinct int64         `yaml:"warn_distinct" env:"JUNJO_CARDINALITY_WARN_DISTINCT"`
+	MaxDistinct  int64         `yaml:"max_distinct" env:"JUNJO_CARDINALITY_MAX_DISTINCT"`
+}
+
+// Default returns the configuration used for settings that are not set.
+func Default() *Config {
+	return &Config{
+		Env: EnvDevelopment,
+		Listen: ListenConfig{
+			HTTP:         "0.0.0.0:1323",
+			InternalGRPC: ":50053",
+		},
+		IngestionAddr:  "junjo-server-ingestion:50052",
+		ImportMaxBytes: 256 << 20,
+		GRPC: GRPCConfig{
+			KeepaliveMinTime: 10 * time.Second,
+		},
+		SelfTrace: SelfTraceConfig{
+			Endpoint: "junjo-server-ingestion:50051",
+		},
+		Gemini: GeminiConfig{
+			BaseURL: "https://generativelanguage.googleapis.com/v1beta",
+		},
+		Poller: PollerConfig{
+			Interval:  5 * time.Second,
+			BatchSize: 100,
+		},
+		SpanWriter: SpanWriterConfig{
+			MaxRows:    1000,
+			MaxLatency: 2 * time.Second,
+		},
+		TailSampling: TailSamplingConfig{
+			DecisionWait:     30 * time.Second,
+			LatencyThreshold: 10 * time.Second,
+			KeepRate:         0.1,
+			MaxTraces:        10000,
+		},
+		ContentRedaction: ContentRedactionConfig{
+			Mode:           "off",
+			TruncateLength: 256,
+		},
+		MaxResponseBytes: 8 << 20,
+		AuthMonitor: AuthMonitorConfig{
+			SessionsPerIP:  20,
+			Window:         time.Hour,
+			GeoMaxSpeedKmh: 1000,
+		},
+		Retention: RetentionConfig{
+			Interval: time.Hour,
+		},
+		Quotas: QuotasConfig{
+			GracePeriod: time.Hour,
+		},
+		SLA: SLAConfig{
+			CheckInterval: time.Minute,
+			Lookback:      24 * time.Hour,
+		},
+		Baselines: BaselinesConfig{
+			CheckInterval: 5 * time.Minute,
+		},
+		PatchCheck: PatchCheckConfig{
+			Interval:  10 * time.Minute,
+			Lookback:  24 * time.Hour,
+			BatchSize: 500,
+		},
+		Cardinality: CardinalityConfig{
+			Interval:     time.Hour,
+			Window:       24 * time.Hour,
+			WarnDistinct: 50000,
+			MaxDistinct:  200000,
+		},
+	}
+}
+
+// Load reads the configuration from the JUNJO_CONFIG_FILE YAML file, when
+// set, and the environment, and validates it.
+func Load() (*Config, error) {
+	cfg := Default()
+	if path := os.Getenv("JUNJO_CONFIG_FILE"); path != "" {
+		if err := readFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := readEnv(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting of the configuration.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		invalid("JUNJO_ENV (env) must be %s or %s, got %q", EnvDevelopment, EnvProduction, c.Env)
+	}
+	if c.SessionSecret == "" {
+		invalid("JUNJO_SESSION_SECRET (session_secret) is required; generate one with: openssl rand -base64 48")
+	}
+	for _, origin := range c.AllowOrigins {
+		if !isHTTPURL(origin) {
+			invalid("JUNJO_ALLOW_ORIGINS (allow_origins) must hold http or https origins, got %q", origin)
+		}
+	}
+	if c.FrontendURL != "" && !isHTTPURL(c.FrontendURL) {
+		invalid("JUNJO_FRONTEND_URL (frontend_url) must be an http or https URL, got %q", c.FrontendURL)
+	}
+	if c.ImportMaxBytes <= 0 {
+		invalid("JUNJO_IMPORT_MAX_BYTES (import_max_bytes) must be a positive number of bytes, got %d", c.ImportMaxBytes)
+	}
+	for _, addr := range []struct{ name, addr string }{
+		{"JUNJO_HTTP_ADDR (listen.http)", c.Listen.HTTP},
+		{"JUNJO_INTERNAL_GRPC_ADDR (listen.internal_grpc)", c.Listen.InternalGRPC},
+		{"JUNJO_INGESTION_ADDR (ingestion_addr)", c.IngestionAddr},
+	} {
+		if !isHostPort(addr.addr) {
+			invalid("%s must be a host:port address, got %q", addr.name, addr.addr)
+		}
+	}
+	if c.Listen.HTTP == c.Listen.InternalGRPC {
+		invalid("JUNJO_HTTP_ADDR and JUNJO_INTERNAL_GRPC_ADDR must differ, both are %q", c.Listen.HTTP)
+	}
+	for _, network := range c.InternalAuth.AllowedNetworks {
+		if !isNetwork(network) {
+			invalid("JUNJO_INTERNAL_ALLOWED_NETWORKS (internal_auth.allowed_networks) must hold CIDR ranges or IP addresses, got %q", network)
+		}
+	}
+	if len(c.InternalAuth.AllowedClientNames) > 0 && !c.InternalTLS.Enabled() {
+		invalid("JUNJO_INTERNAL_ALLOWED_CLIENT_NAMES (internal_auth.allowed_client_names) requires internal mutual TLS")
+	}
+	if c.GRPC.MaxRecvMsgSize < 0 || c.GRPC.MaxSendMsgSize < 0 {
+		invalid("JUNJO_GRPC_MAX_RECV_MSG_SIZE and JUNJO_GRPC_MAX_SEND_MSG_SIZE (grpc) must not be negative")
+	}
+	if c.GRPC.KeepaliveTime < 0 || c.GRPC.KeepaliveTimeout < 0 || c.GRPC.KeepaliveMinTime < 0 || c.GRPC.ConnectTimeout < 0 {
+		invalid("JUNJO_GRPC_KEEPALIVE_TIME, JUNJO_GRPC_KEEPALIVE_TIMEOUT, JUNJO_GRPC_KEEPALIVE_MIN_TIME and JUNJO_GRPC_CONNECT_TIMEOUT (grpc) must not be negative")
+	}
+	if c.SelfTrace.Enabled {
+		if !isHostPort(c.SelfTrace.Endpoint) {
+			invalid("JUNJO_SELF_TRACE_ENDPOINT (self_trace.endpoint) must be a host:port address, got %q", c.SelfTrace.Endpoint)
+		}
+		if c.SelfTrace.APIKey == "" {
+			invalid("JUNJO_SELF_TRACE_API_KEY (self_trace.api_key) is required when JUNJO_SELF_TRACE is enabled")
+		}
+	}
+	if !isHTTPURL(c.Gemini.BaseURL) {
+		invalid("GEMINI_API_BASE_URL (gemini.base_url) must be an http or https URL, got %q", c.Gemini.BaseURL)
+	}
+	c.validateBackgroundJobs(invalid)
+	c.validateLimits(invalid)
+	if !c.InternalTLS.Enabled() && (c.InternalTLS.CertFile != "" || c.InternalTLS.KeyFile != "" || c.InternalTLS.CAFile != "") {
+		invalid("JUNJO_INTERNAL_TLS_CERT_FILE, JUNJO_INTERNAL_TLS_KEY_FILE and JUNJO_INTERNAL_TLS_CA_FILE (internal_tls) must be set together")
+	}
+	for _, file := range []struct{ name, path string }{
+		{"JUNJO_SPAN_TYPES_PATH (span_types_path)", c.SpanTypesPath},
+		{"JUNJO_MODEL_PRICING_PATH (model_pricing_path)", c.ModelPricingPath},
+		{"JUNJO_INTERNAL_TLS_CERT_FILE (internal_tls.cert_file)", c.InternalTLS.CertFile},
+		{"JUNJO_INTERNAL_TLS_KEY_FILE (internal_tls.key_file)", c.InternalTLS.KeyFile},
+		{"JUNJO_INTERNAL_TLS_CA_FILE (internal_tls.ca_file)", c.InternalTLS.CAFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			invalid("%s: %v", file.name, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validateBackgroundJobs checks the settings of span polling and indexing and
+// of the periodic jobs.
+func (c *Config) validateBackgroundJobs(invalid func(format string, args ...any)) {
+	if c.Poller.Interval < 100*time.Millisecond || c.Poller.Interval > 10*time.Minute {
+		invalid("JUNJO_POLL_INTERVAL (poller.interval) must be between 100ms and 10m, got %s", c.Poller.Interval)
+	}
+	if c.Poller.BatchSize < 1 || c.Poller.BatchSize > 10000 {
+		invalid("JUNJO_POLL_BATCH_SIZE (poller.batch_size) must be between 1 and 10000, got %d", c.Poller.BatchSize)
+	}
+	if c.SpanWriter.MaxRows <= 0 {
+		invalid("JUNJO_INDEX_FLUSH_MAX_ROWS (span_writer.max_rows) must be positive, got %d", c.SpanWriter.MaxRows)
+	}
+	if c.TailSampling.LatencyThreshold < 0 {
+		invalid("JUNJO_TAIL_SAMPLING_LATENCY_THRESHOLD (tail_sampling.latency_threshold) must not be negative, got %s", c.TailSampling.LatencyThreshold)
+	}
+	if c.TailSampling.KeepRate < 0 || c.TailSampling.KeepRate > 1 {
+		invalid("JUNJO_TAIL_SAMPLING_KEEP_RATE (tail_sampling.keep_rate) must be between 0 and 1, got %g", c.TailSampling.KeepRate)
+	}
+	if c.TailSampling.MaxTraces <= 0 {
+		invalid("JUNJO_TAIL_SAMPLING_MAX_TRACES (tail_sampling.max_traces) must be positive, got %d", c.TailSampling.MaxTraces)
+	}
+	switch c.ContentRedaction.Mode {
+	case "off", "hash", "truncate":
+	default:
+		invalid("JUNJO_CONTENT_REDACTION (content_redaction.mode) must be off, hash or truncate, got %q", c.ContentRedaction.Mode)
+	}
+	if c.ContentRedaction.TruncateLength < 0 {
+		invalid("JUNJO_CONTENT_TRUNCATE_LENGTH (content_redaction.truncate_length) must not be negative, got %d", c.ContentRedaction.TruncateLength)
+	}
+	if c.Retention.MaxAge < 0 {
+		invalid("JUNJO_SPAN_RETENTION (retention.max_age) must not be negative, got %s", c.Retention.MaxAge)
+	}
+	if c.PatchCheck.BatchSize <= 0 {
+		invalid("JUNJO_PATCH_CHECK_BATCH_SIZE (patch_check.batch_size) must be positive, got %d", c.PatchCheck.BatchSize)
+	}
+	if c.Cardinality.WarnDistinct <= 0 {
+		invalid("JUNJO_CARDINALITY_WARN_DISTINCT (cardinality.warn_distinct) must be positive, got %d", c.Cardinality.WarnDistinct)
+	}
+	if c.Cardinality.MaxDistinct < c.Cardinality.WarnDistinct {
+		invalid("JUNJO_CARDINALITY_MAX_DISTINCT (cardinality.max_distinct) must be at least JUNJO_CARDINALITY_WARN_DISTINCT (%d), got %d", c.Cardinality.WarnDistinct, c.Cardinality.MaxDistinct)
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"JUNJO_INDEX_FLUSH_MAX_LATENCY (span_writer.max_latency)", c.SpanWriter.MaxLatency},
+		{"JUNJO_TAIL_SAMPLING_DECISION_WAIT (tail_sampling.decision_wait)", c.TailSampling.DecisionWait},
+		{"JUNJO_AUTH_ANOMALY_WINDOW (auth_monitor.anomaly_window)", c.AuthMonitor.Window},
+		{"JUNJO_SPAN_RETENTION_INTERVAL (retention.interval)", c.Retention.Interval},
+		{"JUNJO_SLA_CHECK_INTERVAL (sla.check_interval)", c.SLA.CheckInterval},
+		{"JUNJO_SLA_LOOKBACK (sla.lookback)", c.SLA.Lookback},
+		{"JUNJO_BASELINE_CHECK_INTERVAL (baselines.check_interval)", c.Baselines.CheckInterval},
+		{"JUNJO_PATCH_CHECK_INTERVAL (patch_check.interval)", c.PatchCheck.Interval},
+		{"JUNJO_PATCH_CHECK_LOOKBACK (patch_check.lookback)", c.PatchCheck.Lookback},
+		{"JUNJO_CARDINALITY_CHECK_INTERVAL (cardinality.interval)", c.Cardinality.Interval},
+		{"JUNJO_CARDINALITY_WINDOW (cardinality.window)", c.Cardinality.Window},
+	} {
+		if d.value <= 0 {
+			invalid("%s must be a positive duration, got %s", d.name, d.value)
+		}
+	}
+	for _, webhook := range []struct{ name, url string }{
+		{"JUNJO_QUOTA_WEBHOOK_URL (quotas.webhook_url)", c.Quotas.WebhookURL},
+		{"JUNJO_SLA_WEBHOOK_URL (sla.webhook_url)", c.SLA.WebhookURL},
+		{"JUNJO_BASELINE_WEBHOOK_URL (baselines.webhook_url)", c.Baselines.WebhookURL},
+	} {
+		if webhook.url != "" && !isHTTPURL(webhook.url) {
+			invalid("%s must be an http or https URL, got %q", webhook.name, webhook.url)
+		}
+	}
+}
+
+// validateLimits checks the size limits, quotas, rate limits and budgets.
+func (c *Config) validateLimits(invalid func(format string, args ...any)) {
+	if c.MaxResponseBytes < 0 {
+		invalid("JUNJO_MAX_RESPONSE_BYTES (max_response_bytes) must not be negative, got %d", c.MaxResponseBytes)
+	}
+	if c.AuthMonitor.SessionsPerIP < 0 {
+		invalid("JUNJO_AUTH_SESSIONS_PER_IP (auth_monitor.sessions_per_ip) must not be negative, got %d", c.AuthMonitor.SessionsPerIP)
+	}
+	if c.AuthMonitor.GeoMaxSpeedKmh <= 0 {
+		invalid("JUNJO_AUTH_GEO_MAX_SPEED_KMH (auth_monitor.geo_max_speed_kmh) must be positive, got %g", c.AuthMonitor.GeoMaxSpeedKmh)
+	}
+	if (c.AuthMonitor.GeoLatitudeHeader == "") != (c.AuthMonitor.GeoLongitudeHeader == "") {
+		invalid("JUNJO_AUTH_GEO_LATITUDE_HEADER and JUNJO_AUTH_GEO_LONGITUDE_HEADER (auth_monitor) must be set together")
+	}
+	if c.Quotas.GracePeriod < 0 {
+		invalid("JUNJO_QUOTA_GRACE_PERIOD (quotas.grace_period) must not be negative, got %s", c.Quotas.GracePeriod)
+	}
+	for _, limit := range []struct {
+		name  string
+		value int64
+	}{
+		{"JUNJO_QUOTA_SPANS_SOFT_LIMIT (quotas.spans_soft_limit)", c.Quotas.SpansSoftLimit},
+		{"JUNJO_QUOTA_SPANS_HARD_LIMIT (quotas.spans_hard_limit)", c.Quotas.SpansHardLimit},
+		{"JUNJO_QUOTA_LLM_SOFT_LIMIT (quotas.llm_soft_limit)", c.Quotas.LLMSoftLimit},
+		{"JUNJO_QUOTA_LLM_HARD_LIMIT (quotas.llm_hard_limit)", c.Quotas.LLMHardLimit},
+		{"JUNJO_LLM_RATE_LIMIT_PER_MINUTE (llm_rate_limit.per_minute)", c.LLMRateLimit.PerMinute},
+		{"JUNJO_LLM_RATE_LIMIT_BURST (llm_rate_limit.burst)", c.LLMRateLimit.Burst},
+		{"JUNJO_LLM_MAX_CONCURRENT (llm_rate_limit.max_concurrent)", c.LLMRateLimit.MaxConcurrent},
+		{"JUNJO_LLM_BUDGET_USER_DAILY_TOKENS (llm_budgets.user_daily_tokens)", c.LLMBudgets.UserDailyTokens},
+		{"JUNJO_LLM_BUDGET_USER_MONTHLY_TOKENS (llm_budgets.user_monthly_tokens)", c.LLMBudgets.UserMonthlyTokens},
+		{"JUNJO_LLM_BUDGET_PROVIDER_DAILY_TOKENS (llm_budgets.provider_daily_tokens)", c.LLMBudgets.ProviderDailyTokens},
+		{"JUNJO_LLM_BUDGET_PROVIDER_MONTHLY_TOKENS (llm_budgets.provider_monthly_tokens)", c.LLMBudgets.ProviderMonthlyTokens},
+	} {
+		if limit.value < 0 {
+			invalid("%s must not be negative, got %d", limit.name, limit.value)
+		}
+	}
+	for _, quota := range []struct {
+		soft, hard           string
+		softValue, hardValue int64
+	}{
+		{"JUNJO_QUOTA_SPANS_SOFT_LIMIT (quotas.spans_soft_limit)", "JUNJO_QUOTA_SPANS_HARD_LIMIT", c.Quotas.SpansSoftLimit, c.Quotas.SpansHardLimit},
+		{"JUNJO_QUOTA_LLM_SOFT_LIMIT (quotas.llm_soft_limit)", "JUNJO_QUOTA_LLM_HARD_LIMIT", c.Quotas.LLMSoftLimit, c.Quotas.LLMHardLimit},
+	} {
+		if quota.softValue > 0 && quota.hardValue > 0 && quota.softValue > quota.hardValue {
+			invalid("%s must not exceed %s (%d), got %d", quota.soft, quota.hard, quota.hardValue, quota.softValue)
+		}
+	}
+}
+
+// CredentialsSecret returns the secret provider credentials are encrypted
+// with: the credentials key, or the session secret when it is not set.
+func (c *Config) CredentialsSecret() string {
+	if c.CredentialsKey != "" {
+		return c.CredentialsKey
+	}
+	return c.SessionSecret
+}
+
+// SessionDomain returns the domain session cookies are set for, which is
+// empty outside production.
+func (c *Config) SessionDomain() string {
+	if c.Env != EnvProduction {
+		return ""
+	}
+	return c.ProdAuthDomain
+}
+
+// isHTTPURL reports whether raw is an absolute http or https URL.
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isHostPort reports whether raw is a host:port address with a valid port.
+// The host may be empty.
+func isHostPort(raw string) bool {
+	_, port, err := net.SplitHostPort(raw)
+	if err != nil {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// isNetwork reports whether raw is a CIDR range or an IP address.
+func isNetwork(raw string) bool {
+	if _, _, err := net.ParseCIDR(raw); err == nil {
+		return true
+	}
+	return net.ParseIP(raw) != nil
+}
+
+// trimList trims the entries of a comma separated list, dropping empty ones.
+func trimList(raw string) []string {
+	values := []string{}
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+)
+
+// Enabled reports whether mutual TLS is configured.
+func (c InternalTLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != "" && c.CAFile != ""
+}
+
+// ServerTLS returns the TLS configuration of the internal gRPC server, which
+// requires clients to present a certificate signed by the CA.
+func (c InternalTLSConfig) ServerTLS() (*tls.Config, error) {
+	cert, pool, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
+// ClientTLS returns the TLS configuration of connections to the internal
+// server at addr, which present the certificate and verify the server's
+// against the CA.
+func (c InternalTLSConfig) ClientTLS(addr string) (*tls.Config, error) {
+	cert, pool, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	serverName := c.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(addr)
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   serverName,
+	}, nil
+}
+
+// load reads the certificate and key pair and the CA certificates.
+func (c InternalTLSConfig) load() (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to load JUNJO_INTERNAL_TLS_CERT_FILE and JUNJO_INTERNAL_TLS_KEY_FILE: %w", err)
+	}
+	pem, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to read JUNJO_INTERNAL_TLS_CA_FILE: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return tls.Certificate{}, nil, fmt.Errorf("JUNJO_INTERNAL_TLS_CA_FILE %s holds no PEM certificates", c.CAFile)
+	}
+	return cert, pool, nil
+}
@@ -0,0 +1,17 @@
+package credentials
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	credentialGroup := e.Group("/llm/credentials")
+
+	credentialGroup.GET("", HandleListCredentials)
+	credentialGroup.GET("/me", HandleListUserCredentials)
+	credentialGroup.PUT("/me/:provider", HandleSetUserCredential)
+	credentialGroup.DELETE("/me/:provider", HandleDeleteUserCredential)
+	credentialGroup.GET("/:provider", HandleGetCredential)
+	credentialGroup.PUT("/:provider", HandleSetCredential)
+	credentialGroup.DELETE("/:provider", HandleDeleteCredential)
+}
@@ -0,0 +1,73 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// secret is the configured secret credentials are encrypted with.
+var secret string
+
+// SetSecret sets the secret the encryption key is derived from:
+// JUNJO_CREDENTIALS_KEY, or JUNJO_SESSION_SECRET when it is not set.
+func SetSecret(s string) {
+	secret = s
+}
+
+// loadKey derives the AES-256 encryption key from the configured secret.
+// Changing the secret makes the stored credentials unreadable; they must then
+// be stored again.
+func loadKey() ([]byte, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("JUNJO_CREDENTIALS_KEY or JUNJO_SESSION_SECRET must be set to store provider credentials")
+	}
+	sum := sha256.Sum256([]byte("junjo-provider-credentials:" + secret))
+	return sum[:], nil
+}
+
+// encrypt seals plaintext with AES-GCM and returns the base64 nonce and
+// ciphertext.
+func encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value sealed by encrypt.
+func decrypt(key []byte, encoded string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted value is too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: the encryption secret may have changed")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,127 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"junjo-server/api/llm"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"log"
+)
+
+// hintLength is the number of trailing characters of an API key kept in
+// clear to tell keys apart.
+const hintLength = 4
+
+// Init loads the stored provider credentials into the LLM service and lets
+// users' own keys override them for their requests.
+func Init(ctx context.Context) error {
+	if err := Reload(ctx); err != nil {
+		return err
+	}
+	llm.SetUserKeyResolver(resolveUserKey)
+	return nil
+}
+
+// Reload decrypts the stored credentials and applies them to LLM requests
+// made afterwards. Credentials that cannot be decrypted are skipped, so the
+// provider falls back to its environment variable.
+func Reload(ctx context.Context) error {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListProviderCredentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]string{}
+	if len(stored) > 0 {
+		key, err := loadKey()
+		if err != nil {
+			return err
+		}
+		for _, credential := range stored {
+			apiKey, err := decrypt(key, credential.EncryptedApiKey)
+			if err != nil {
+				log.Printf("Skipping %s credentials: %v", credential.Provider, err)
+				continue
+			}
+			keys[credential.Provider] = apiKey
+		}
+	}
+	llm.SetAPIKeys(keys)
+	return nil
+}
+
+// SetCredential encrypts and stores the API key of a provider, replacing the
+// previous one.
+func SetCredential(ctx context.Context, provider string, apiKey string, userEmail string) (Credential, error) {
+	key, err := loadKey()
+	if err != nil {
+		return Credential{}, err
+	}
+	encrypted, err := encrypt(key, apiKey)
+	if err != nil {
+		return Credential{}, fmt.Errorf("failed to encrypt API key: %w", err)
+	}
+
+	queries := db_gen.New(db.DB)
+	credential, err := queries.UpsertProviderCredential(ctx, db_gen.UpsertProviderCredentialParams{
+		Provider:        provider,
+		EncryptedApiKey: encrypted,
+		KeyHint:         hint(apiKey),
+		UpdatedBy:       userEmail,
+	})
+	if err != nil {
+		return Credential{}, err
+	}
+	return decode(credential), nil
+}
+
+// ListCredentials retrieves the stored credentials, masked.
+func ListCredentials(ctx context.Context) ([]Credential, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListProviderCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	credentials := []Credential{}
+	for _, credential := range stored {
+		credentials = append(credentials, decode(credential))
+	}
+	return credentials, nil
+}
+
+// GetCredential retrieves the masked credential of a provider.
+func GetCredential(ctx context.Context, provider string) (Credential, error) {
+	queries := db_gen.New(db.DB)
+	credential, err := queries.GetProviderCredential(ctx, provider)
+	if err != nil {
+		return Credential{}, err
+	}
+	return decode(credential), nil
+}
+
+// DeleteCredential removes the credential of a provider.
+func DeleteCredential(ctx context.Context, provider string) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteProviderCredential(ctx, provider)
+}
+
+// hint returns the last characters of an API key, or nothing when the key is
+// too short to reveal any of it.
+func hint(apiKey string) string {
+	if len(apiKey) <= 2*hintLength {
+		return ""
+	}
+	return apiKey[len(apiKey)-hintLength:]
+}
+
+func decode(credential db_gen.ProviderCredential) Credential {
+	return Credential{
+		Provider:  credential.Provider,
+		APIKey:    "****" + credential.KeyHint,
+		UpdatedBy: credential.UpdatedBy,
+		CreatedAt: credential.CreatedAt,
+		UpdatedAt: credential.UpdatedAt,
+	}
+}
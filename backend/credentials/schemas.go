@@ -0,0 +1,18 @@
+package credentials
+
+import "time"
+
+// SetCredentialRequest stores the API key of a provider.
+type SetCredentialRequest struct {
+	APIKey string `json:"api_key" validate:"required"`
+}
+
+// Credential is a stored provider credential. The API key itself is never
+// returned; APIKey shows only its last characters.
+type Credential struct {
+	Provider  string    `json:"provider"`
+	APIKey    string    `json:"api_key"`
+	UpdatedBy string    `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
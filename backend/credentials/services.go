@@ -0,0 +1,192 @@
+package credentials
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"junjo-server/api/llm"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListCredentials lists the stored provider credentials with their API
+// keys masked.
+func HandleListCredentials(c echo.Context) error {
+	credentials, err := ListCredentials(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list provider credentials:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve provider credentials")
+	}
+
+	return c.JSON(http.StatusOK, credentials)
+}
+
+// HandleGetCredential retrieves the masked credential of a provider.
+func HandleGetCredential(c echo.Context) error {
+	provider, err := parseProvider(c)
+	if err != nil {
+		return err
+	}
+
+	credential, err := GetCredential(c.Request().Context(), provider)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "No credentials stored for this provider")
+		}
+		c.Logger().Error("Failed to get provider credential:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve provider credentials")
+	}
+
+	return c.JSON(http.StatusOK, credential)
+}
+
+// HandleSetCredential stores the API key of a provider, encrypted, and uses it
+// for LLM requests without a restart.
+func HandleSetCredential(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	provider, err := parseProvider(c)
+	if err != nil {
+		return err
+	}
+
+	var req SetCredentialRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	credential, err := SetCredential(c.Request().Context(), provider, strings.TrimSpace(req.APIKey), userEmail)
+	if err != nil {
+		c.Logger().Error("Failed to store provider credential:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save provider credentials")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload provider credentials:", err)
+	}
+
+	return c.JSON(http.StatusOK, credential)
+}
+
+// HandleDeleteCredential removes the credential of a provider, which then
+// falls back to its environment variable.
+func HandleDeleteCredential(c echo.Context) error {
+	provider, err := parseProvider(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := GetCredential(c.Request().Context(), provider); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "No credentials stored for this provider")
+		}
+		c.Logger().Error("Failed to look up provider credential:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete provider credentials")
+	}
+
+	if err := DeleteCredential(c.Request().Context(), provider); err != nil {
+		c.Logger().Error("Failed to delete provider credential:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete provider credentials")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload provider credentials:", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleListUserCredentials lists the signed-in user's own provider
+// credentials with their API keys masked.
+func HandleListUserCredentials(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	credentials, err := ListUserCredentials(c.Request().Context(), userEmail)
+	if err != nil {
+		c.Logger().Error("Failed to list user provider credentials:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve provider credentials")
+	}
+
+	return c.JSON(http.StatusOK, credentials)
+}
+
+// HandleSetUserCredential stores the signed-in user's own API key for a
+// provider. It overrides the server key for the user's LLM requests, which
+// are then not counted against the shared LLM quota.
+func HandleSetUserCredential(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	provider, err := parseProvider(c)
+	if err != nil {
+		return err
+	}
+
+	var req SetCredentialRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	credential, err := SetUserCredential(c.Request().Context(), userEmail, provider, strings.TrimSpace(req.APIKey))
+	if err != nil {
+		c.Logger().Error("Failed to store user provider credential:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save provider credentials")
+	}
+
+	return c.JSON(http.StatusOK, credential)
+}
+
+// HandleDeleteUserCredential removes the signed-in user's own API key for a
+// provider, so their requests use the server key again.
+func HandleDeleteUserCredential(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	provider, err := parseProvider(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := GetUserCredential(c.Request().Context(), userEmail, provider); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "No credentials stored for this provider")
+		}
+		c.Logger().Error("Failed to look up user provider credential:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete provider credentials")
+	}
+
+	if err := DeleteUserCredential(c.Request().Context(), userEmail, provider); err != nil {
+		c.Logger().Error("Failed to delete user provider credential:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete provider credentials")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// parseProvider reads the provider path parameter.
+func parseProvider(c echo.Context) (string, error) {
+	provider := strings.ToLower(c.Param("provider"))
+	if !slices.Contains(llm.Providers, provider) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Unknown provider: must be one of "+strings.Join(llm.Providers, ", "))
+	}
+	return provider, nil
+}
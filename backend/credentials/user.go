@@ -0,0 +1,102 @@
+package credentials
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"log"
+)
+
+// SetUserCredential encrypts and stores a user's own API key for a provider,
+// replacing the previous one.
+func SetUserCredential(ctx context.Context, userEmail string, provider string, apiKey string) (Credential, error) {
+	key, err := loadKey()
+	if err != nil {
+		return Credential{}, err
+	}
+	encrypted, err := encrypt(key, apiKey)
+	if err != nil {
+		return Credential{}, fmt.Errorf("failed to encrypt API key: %w", err)
+	}
+
+	queries := db_gen.New(db.DB)
+	credential, err := queries.UpsertUserProviderCredential(ctx, db_gen.UpsertUserProviderCredentialParams{
+		UserEmail:       userEmail,
+		Provider:        provider,
+		EncryptedApiKey: encrypted,
+		KeyHint:         hint(apiKey),
+	})
+	if err != nil {
+		return Credential{}, err
+	}
+	return decodeUser(credential), nil
+}
+
+// ListUserCredentials retrieves a user's own credentials, masked.
+func ListUserCredentials(ctx context.Context, userEmail string) ([]Credential, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListUserProviderCredentials(ctx, userEmail)
+	if err != nil {
+		return nil, err
+	}
+	credentials := []Credential{}
+	for _, credential := range stored {
+		credentials = append(credentials, decodeUser(credential))
+	}
+	return credentials, nil
+}
+
+// GetUserCredential retrieves a user's own masked credential for a provider.
+func GetUserCredential(ctx context.Context, userEmail string, provider string) (Credential, error) {
+	queries := db_gen.New(db.DB)
+	credential, err := queries.GetUserProviderCredential(ctx, db_gen.GetUserProviderCredentialParams{UserEmail: userEmail, Provider: provider})
+	if err != nil {
+		return Credential{}, err
+	}
+	return decodeUser(credential), nil
+}
+
+// DeleteUserCredential removes a user's own credential for a provider.
+func DeleteUserCredential(ctx context.Context, userEmail string, provider string) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteUserProviderCredential(ctx, db_gen.DeleteUserProviderCredentialParams{UserEmail: userEmail, Provider: provider})
+}
+
+// resolveUserKey returns the decrypted API key a user registered for a
+// provider. Lookup failures are logged and fall back to the server key.
+func resolveUserKey(ctx context.Context, userEmail string, provider string) (string, bool) {
+	queries := db_gen.New(db.DB)
+	credential, err := queries.GetUserProviderCredential(ctx, db_gen.GetUserProviderCredentialParams{UserEmail: userEmail, Provider: provider})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false
+	}
+	if err != nil {
+		log.Printf("Failed to look up %s credentials of %s: %v", provider, userEmail, err)
+		return "", false
+	}
+
+	key, err := loadKey()
+	if err != nil {
+		log.Printf("Failed to load credentials key: %v", err)
+		return "", false
+	}
+	apiKey, err := decrypt(key, credential.EncryptedApiKey)
+	if err != nil {
+		log.Printf("Skipping %s credentials of %s: %v", provider, userEmail, err)
+		return "", false
+	}
+	return apiKey, true
+}
+
+func decodeUser(credential db_gen.UserProviderCredential) Credential {
+	return Credential{
+		Provider:  credential.Provider,
+		APIKey:    "****" + credential.KeyHint,
+		UpdatedBy: credential.UserEmail,
+		CreatedAt: credential.CreatedAt,
+		UpdatedAt: credential.UpdatedAt,
+	}
+}
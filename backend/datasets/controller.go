@@ -0,0 +1,15 @@
+package datasets
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	datasetGroup := e.Group("/datasets")
+
+	datasetGroup.GET("", HandleListDatasets)
+	datasetGroup.POST("", HandleCreateDataset)
+	datasetGroup.GET("/:id", HandleGetDataset)
+	datasetGroup.GET("/:id/export", HandleExportDataset)
+	datasetGroup.DELETE("/:id", HandleDeleteDataset)
+}
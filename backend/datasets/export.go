@@ -0,0 +1,165 @@
+package datasets
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"junjo-server/annotations"
+	db_duckdb "junjo-server/db_duckdb"
+)
+
+// queryLLMPairs selects the prompt and completion of the LLM spans of a
+// service. Args: service_name, workflow name (empty for any), start, end.
+const queryLLMPairs = `
+	SELECT
+		trace_id, span_id,
+		COALESCE(gen_ai_response_model, gen_ai_request_model, json_extract_string(attributes_json, 'llm.model_name'), ''),
+		COALESCE(json_extract_string(attributes_json, 'input.value'), json_extract_string(attributes_json, 'gen_ai.prompt'), json_extract_string(attributes_json, 'gen_ai.input.messages')),
+		COALESCE(json_extract_string(attributes_json, 'output.value'), json_extract_string(attributes_json, 'gen_ai.completion'), json_extract_string(attributes_json, 'gen_ai.output.messages'))
+	FROM spans
+	WHERE service_name = $1
+		AND ($2 = '' OR trace_id IN (SELECT trace_id FROM spans WHERE service_name = $1 AND junjo_span_type = 'workflow' AND name = $2))
+		AND start_time >= $3
+		AND start_time < $4
+		AND (
+			json_extract_string(attributes_json, 'openinference.span.kind') = 'LLM'
+			OR gen_ai_system IS NOT NULL
+			OR gen_ai_operation_name IS NOT NULL
+		)
+	ORDER BY start_time, span_id;`
+
+// queryStateSnapshots selects the start and end state of the workflow runs of
+// a service. Args: service_name, workflow name (empty for any), start, end.
+const queryStateSnapshots = `
+	SELECT
+		trace_id, span_id, COALESCE(name, ''),
+		junjo_wf_state_start::VARCHAR, junjo_wf_state_end::VARCHAR
+	FROM spans
+	WHERE service_name = $1
+		AND junjo_span_type = 'workflow'
+		AND ($2 = '' OR name = $2)
+		AND start_time >= $3
+		AND start_time < $4
+	ORDER BY start_time, span_id;`
+
+// Export writes the dataset to w as JSONL in format, and returns the number of
+// lines written. In the jsonl format each line is an LLMPair or a
+// StateSnapshot; in the eval framework formats each is an Example labelled
+// with the annotations of its trace. LLM spans without a prompt or
+// completion are skipped.
+func Export(ctx context.Context, dataset Dataset, format string, w io.Writer) (int, error) {
+	duck := db_duckdb.DB
+	if duck == nil {
+		return 0, fmt.Errorf("database connection is nil")
+	}
+
+	var traceIDs map[string]bool
+	if dataset.annotated() {
+		var err error
+		traceIDs, err = annotations.AnnotatedTraceIDs(ctx, dataset.ServiceName, dataset.AnnotationThumb, dataset.MinAnnotationScore)
+		if err != nil {
+			return 0, fmt.Errorf("failed to select annotated traces: %w", err)
+		}
+	}
+
+	var labels map[string]annotations.TraceSummary
+	if format != FormatJSONL {
+		var err error
+		labels, err = annotations.TraceSummaries(ctx, dataset.ServiceName)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read annotations: %w", err)
+		}
+	}
+
+	start := time.Unix(0, 0).UTC()
+	if dataset.StartTime != nil {
+		start = *dataset.StartTime
+	}
+	end := time.Now().Add(time.Minute)
+	if dataset.EndTime != nil {
+		end = *dataset.EndTime
+	}
+
+	query := queryLLMPairs
+	if dataset.Kind == KindStateSnapshots {
+		query = queryStateSnapshots
+	}
+	rows, err := duck.QueryContext(ctx, query, dataset.ServiceName, dataset.WorkflowName, start, end)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	count := 0
+	for rows.Next() {
+		var line interface{}
+		var traceID string
+		var example Example
+		switch dataset.Kind {
+		case KindStateSnapshots:
+			var snapshot StateSnapshot
+			var input, output sql.NullString
+			if err := rows.Scan(&snapshot.TraceID, &snapshot.SpanID, &snapshot.WorkflowName, &input, &output); err != nil {
+				return count, err
+			}
+			if input.Valid {
+				snapshot.Input = json.RawMessage(input.String)
+			}
+			if output.Valid {
+				snapshot.Output = json.RawMessage(output.String)
+			}
+			traceID, line = snapshot.TraceID, snapshot
+			example = Example{
+				Input:  snapshot.Input,
+				Output: snapshot.Output,
+				Labels: map[string]any{"trace_id": snapshot.TraceID, "span_id": snapshot.SpanID, "workflow_name": snapshot.WorkflowName},
+			}
+		default:
+			var pair LLMPair
+			var prompt, completion sql.NullString
+			if err := rows.Scan(&pair.TraceID, &pair.SpanID, &pair.Model, &prompt, &completion); err != nil {
+				return count, err
+			}
+			if !prompt.Valid || !completion.Valid {
+				continue
+			}
+			pair.Prompt, pair.Completion = prompt.String, completion.String
+			traceID, line = pair.TraceID, pair
+			example = Example{
+				Input:  pair.Prompt,
+				Output: pair.Completion,
+				Labels: map[string]any{"trace_id": pair.TraceID, "span_id": pair.SpanID, "model": pair.Model},
+			}
+		}
+
+		if traceIDs != nil && !traceIDs[traceID] {
+			continue
+		}
+		if format == FormatJSONL {
+			err = enc.Encode(line)
+		} else {
+			addAnnotationLabels(example.Labels, labels[traceID])
+			err = EncodeExample(enc, format, example)
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// addAnnotationLabels adds the feedback a trace received to the labels of its
+// example.
+func addAnnotationLabels(labels map[string]any, summary annotations.TraceSummary) {
+	labels["thumbs_up"] = summary.ThumbsUp
+	labels["thumbs_down"] = summary.ThumbsDown
+	if summary.ScoreCount > 0 {
+		labels["annotation_score"] = summary.ScoreSum / float64(summary.ScoreCount)
+	}
+}
@@ -0,0 +1,112 @@
+package datasets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Export formats.
+const (
+	FormatJSONL       = "jsonl"        // One LLMPair or StateSnapshot per line.
+	FormatOpenAIEvals = "openai_evals" // Items of an OpenAI Evals JSONL data source.
+	FormatLangSmith   = "langsmith"    // Examples of a LangSmith dataset.
+)
+
+// Example is a labelled input and output exported to an eval framework.
+// Input and Output are strings, or JSON values as json.RawMessage. Labels
+// holds the ids of the run and its feedback, such as annotations or judge
+// scores.
+type Example struct {
+	Input  any
+	Output any
+	Labels map[string]any
+}
+
+// openAIEvalsLine is a line of an OpenAI Evals JSONL file. The item holds
+// the input, the output and the labels, which graders reference as
+// {{item.<name>}}; the sample holds the output as generated, so it can be
+// graded without being generated again.
+type openAIEvalsLine struct {
+	Item   map[string]any `json:"item"`
+	Sample struct {
+		OutputText string `json:"output_text"`
+	} `json:"sample"`
+}
+
+// langSmithLine is a line of a LangSmith dataset upload. Inputs and outputs
+// must be JSON objects.
+type langSmithLine struct {
+	Inputs   map[string]any `json:"inputs"`
+	Outputs  map[string]any `json:"outputs"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+// ValidFormat reports whether format is an export format.
+func ValidFormat(format string) bool {
+	return format == FormatJSONL || format == FormatOpenAIEvals || format == FormatLangSmith
+}
+
+// FileExtension returns the file name suffix of an export in format.
+func FileExtension(format string) string {
+	if format == FormatJSONL {
+		return ".jsonl"
+	}
+	return "_" + format + ".jsonl"
+}
+
+// EncodeExample writes an example to enc in the OpenAI Evals or LangSmith
+// format.
+func EncodeExample(enc *json.Encoder, format string, example Example) error {
+	switch format {
+	case FormatOpenAIEvals:
+		line := openAIEvalsLine{Item: map[string]any{}}
+		for name, value := range example.Labels {
+			line.Item[name] = value
+		}
+		line.Item["input"] = example.Input
+		line.Item["output"] = example.Output
+		line.Sample.OutputText = text(example.Output)
+		return enc.Encode(line)
+	case FormatLangSmith:
+		return enc.Encode(langSmithLine{
+			Inputs:   object(example.Input, "input"),
+			Outputs:  object(example.Output, "output"),
+			Metadata: example.Labels,
+		})
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// object returns value when it is a JSON object, and otherwise an object
+// holding it under key.
+func object(value any, key string) map[string]any {
+	if raw, ok := value.(json.RawMessage); ok && bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		var fields map[string]any
+		if err := json.Unmarshal(raw, &fields); err == nil {
+			return fields
+		}
+	}
+	return map[string]any{key: value}
+}
+
+// text returns value as a string: itself for strings and JSON strings, and
+// its JSON encoding otherwise.
+func text(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case json.RawMessage:
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			return s
+		}
+		return string(value)
+	case nil:
+		return ""
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	}
+}
@@ -0,0 +1,118 @@
+package datasets
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"time"
+)
+
+// ErrDuplicateName is returned when another dataset has the same name.
+var ErrDuplicateName = errors.New("a dataset with this name already exists")
+
+// CreateDataset stores a dataset definition by userEmail.
+func CreateDataset(ctx context.Context, userEmail string, req CreateDatasetRequest) (Dataset, error) {
+	params := db_gen.CreateDatasetParams{
+		Name:            req.Name,
+		Kind:            req.Kind,
+		ServiceName:     req.ServiceName,
+		WorkflowName:    req.WorkflowName,
+		StartTime:       formatTime(req.StartTime),
+		EndTime:         formatTime(req.EndTime),
+		AnnotationThumb: req.AnnotationThumb,
+		CreatedBy:       userEmail,
+	}
+	if req.MinAnnotationScore != nil {
+		params.MinAnnotationScore = sql.NullFloat64{Float64: *req.MinAnnotationScore, Valid: true}
+	}
+
+	queries := db_gen.New(db.DB)
+	dataset, err := queries.CreateDataset(ctx, params)
+	if db.IsUniqueViolation(err) {
+		return Dataset{}, ErrDuplicateName
+	}
+	if err != nil {
+		return Dataset{}, err
+	}
+	return decode(dataset)
+}
+
+// ListDatasets retrieves all dataset definitions.
+func ListDatasets(ctx context.Context) ([]Dataset, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListDatasets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	datasets := []Dataset{}
+	for _, dataset := range stored {
+		decoded, err := decode(dataset)
+		if err != nil {
+			return nil, err
+		}
+		datasets = append(datasets, decoded)
+	}
+	return datasets, nil
+}
+
+// GetDataset retrieves a single dataset definition by id.
+func GetDataset(ctx context.Context, id int64) (Dataset, error) {
+	queries := db_gen.New(db.DB)
+	dataset, err := queries.GetDataset(ctx, id)
+	if err != nil {
+		return Dataset{}, err
+	}
+	return decode(dataset)
+}
+
+// DeleteDataset removes a dataset definition by id.
+func DeleteDataset(ctx context.Context, id int64) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteDataset(ctx, id)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(raw string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func decode(dataset db_gen.Dataset) (Dataset, error) {
+	decoded := Dataset{
+		ID:              dataset.ID,
+		Name:            dataset.Name,
+		Kind:            dataset.Kind,
+		ServiceName:     dataset.ServiceName,
+		WorkflowName:    dataset.WorkflowName,
+		AnnotationThumb: dataset.AnnotationThumb,
+		CreatedBy:       dataset.CreatedBy,
+		CreatedAt:       dataset.CreatedAt,
+		UpdatedAt:       dataset.UpdatedAt,
+	}
+	var err error
+	if decoded.StartTime, err = parseTime(dataset.StartTime); err != nil {
+		return decoded, err
+	}
+	if decoded.EndTime, err = parseTime(dataset.EndTime); err != nil {
+		return decoded, err
+	}
+	if dataset.MinAnnotationScore.Valid {
+		score := dataset.MinAnnotationScore.Float64
+		decoded.MinAnnotationScore = &score
+	}
+	return decoded, nil
+}
@@ -0,0 +1,67 @@
+package datasets
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Dataset kinds.
+const (
+	KindLLMPairs       = "llm_pairs"       // Prompt and completion of each LLM span.
+	KindStateSnapshots = "state_snapshots" // Start and end state of each workflow run.
+)
+
+// CreateDatasetRequest defines a dataset from a trace filter. Spans are
+// selected by service, workflow name and start time range; when
+// annotation_thumb or min_annotation_score is set, only traces with a
+// matching annotation are included.
+type CreateDatasetRequest struct {
+	Name               string     `json:"name" validate:"required"`
+	Kind               string     `json:"kind" validate:"required,oneof=llm_pairs state_snapshots"`
+	ServiceName        string     `json:"service_name" validate:"required"`
+	WorkflowName       string     `json:"workflow_name"`
+	StartTime          *time.Time `json:"start_time"`
+	EndTime            *time.Time `json:"end_time"`
+	AnnotationThumb    string     `json:"annotation_thumb" validate:"omitempty,oneof=up down"`
+	MinAnnotationScore *float64   `json:"min_annotation_score"`
+}
+
+// Dataset is a saved dataset definition.
+type Dataset struct {
+	ID                 int64      `json:"id"`
+	Name               string     `json:"name"`
+	Kind               string     `json:"kind"`
+	ServiceName        string     `json:"service_name"`
+	WorkflowName       string     `json:"workflow_name"`
+	StartTime          *time.Time `json:"start_time"`
+	EndTime            *time.Time `json:"end_time"`
+	AnnotationThumb    string     `json:"annotation_thumb"`
+	MinAnnotationScore *float64   `json:"min_annotation_score"`
+	CreatedBy          string     `json:"created_by"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// annotated reports whether the dataset only includes annotated traces.
+func (d Dataset) annotated() bool {
+	return d.AnnotationThumb != "" || d.MinAnnotationScore != nil
+}
+
+// LLMPair is a line of an llm_pairs export.
+type LLMPair struct {
+	TraceID    string `json:"trace_id"`
+	SpanID     string `json:"span_id"`
+	Model      string `json:"model"`
+	Prompt     string `json:"prompt"`
+	Completion string `json:"completion"`
+}
+
+// StateSnapshot is a line of a state_snapshots export. Input and Output are
+// the workflow's start and end state.
+type StateSnapshot struct {
+	TraceID      string          `json:"trace_id"`
+	SpanID       string          `json:"span_id"`
+	WorkflowName string          `json:"workflow_name"`
+	Input        json.RawMessage `json:"input"`
+	Output       json.RawMessage `json:"output"`
+}
@@ -0,0 +1,132 @@
+package datasets
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListDatasets lists all dataset definitions.
+func HandleListDatasets(c echo.Context) error {
+	datasets, err := ListDatasets(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list datasets:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve datasets")
+	}
+
+	return c.JSON(http.StatusOK, datasets)
+}
+
+// HandleCreateDataset saves a dataset definition.
+func HandleCreateDataset(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	var req CreateDatasetRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if req.StartTime != nil && req.EndTime != nil && !req.EndTime.After(*req.StartTime) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: end_time must be after start_time")
+	}
+
+	dataset, err := CreateDataset(c.Request().Context(), userEmail, req)
+	if errors.Is(err, ErrDuplicateName) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to create dataset:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create dataset")
+	}
+
+	return c.JSON(http.StatusCreated, dataset)
+}
+
+// HandleGetDataset retrieves a dataset definition by id.
+func HandleGetDataset(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid dataset id")
+	}
+
+	dataset, err := GetDataset(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Dataset not found")
+		}
+		c.Logger().Error("Failed to get dataset:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve dataset")
+	}
+
+	return c.JSON(http.StatusOK, dataset)
+}
+
+// HandleExportDataset streams the dataset as a JSONL file download. The
+// format query parameter selects jsonl (default), openai_evals or langsmith.
+func HandleExportDataset(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid dataset id")
+	}
+	format := c.QueryParam("format")
+	if format == "" {
+		format = FormatJSONL
+	}
+	if !ValidFormat(format) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid format: must be jsonl, openai_evals or langsmith")
+	}
+
+	dataset, err := GetDataset(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Dataset not found")
+		}
+		c.Logger().Error("Failed to get dataset:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export dataset")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("dataset_%d_%s%s", dataset.ID, dataset.Kind, FileExtension(format))))
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.WriteHeader(http.StatusOK)
+
+	// Once the first line is sent the status can't change, so errors are
+	// logged and the stream is cut short.
+	if _, err := Export(c.Request().Context(), dataset, format, res); err != nil {
+		c.Logger().Error("Failed to export dataset:", err)
+		return err
+	}
+	return nil
+}
+
+// HandleDeleteDataset deletes a dataset definition by id.
+func HandleDeleteDataset(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid dataset id")
+	}
+
+	if _, err := GetDataset(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Dataset not found")
+		}
+		c.Logger().Error("Failed to look up dataset:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete dataset")
+	}
+
+	if err := DeleteDataset(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete dataset:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete dataset")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
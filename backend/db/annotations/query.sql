@@ -0,0 +1,96 @@
+-- name: CreateAnnotation :one
+INSERT INTO
+  annotations (
+    service_name,
+    trace_id,
+    span_id,
+    user_email,
+    thumb,
+    score,
+    comment
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: UpdateAnnotation :one
+UPDATE
+  annotations
+SET
+  thumb = ?,
+  score = ?,
+  comment = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = ? RETURNING *;
+
+-- name: ListAnnotations :many
+SELECT
+  *
+FROM
+  annotations
+WHERE
+  (
+    sqlc.arg(service_name) = ''
+    OR service_name = sqlc.arg(service_name)
+  )
+  AND (
+    sqlc.arg(trace_id) = ''
+    OR trace_id = sqlc.arg(trace_id)
+  )
+  AND (
+    sqlc.arg(span_id) = ''
+    OR span_id = sqlc.arg(span_id)
+  )
+  AND created_at >= sqlc.arg(start_time)
+  AND created_at < sqlc.arg(end_time)
+ORDER BY
+  created_at DESC,
+  id DESC
+LIMIT
+  sqlc.arg(row_limit);
+
+-- name: GetAnnotation :one
+SELECT
+  *
+FROM
+  annotations
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: DeleteAnnotation :exec
+DELETE FROM
+  annotations
+WHERE
+  id = ?;
+
+-- name: ListAnnotatedTraceIDs :many
+SELECT
+  DISTINCT trace_id
+FROM
+  annotations
+WHERE
+  service_name = sqlc.arg(service_name)
+  AND (
+    sqlc.arg(thumb) = ''
+    OR thumb = sqlc.arg(thumb)
+  )
+  AND (
+    sqlc.narg(min_score) IS NULL
+    OR score >= sqlc.narg(min_score)
+  );
+
+-- name: ListAnnotationTraceSummaries :many
+SELECT
+  trace_id,
+  COUNT(score) AS score_count,
+  CAST(COALESCE(SUM(score), 0) AS REAL) AS score_sum,
+  CAST(SUM(thumb = 'up') AS INTEGER) AS thumbs_up,
+  CAST(SUM(thumb = 'down') AS INTEGER) AS thumbs_down
+FROM
+  annotations
+WHERE
+  service_name = ?
+GROUP BY
+  trace_id;
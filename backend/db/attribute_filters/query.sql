@@ -0,0 +1,34 @@
+-- name: UpsertAttributeFilter :one
+INSERT INTO
+  attribute_filters (service_name, mode, keys)
+VALUES
+  (?, ?, ?) ON CONFLICT(service_name) DO
+UPDATE
+SET
+  mode = excluded.mode,
+  keys = excluded.keys,
+  updated_at = CURRENT_TIMESTAMP RETURNING *;
+
+-- name: ListAttributeFilters :many
+SELECT
+  *
+FROM
+  attribute_filters
+ORDER BY
+  service_name;
+
+-- name: GetAttributeFilter :one
+SELECT
+  *
+FROM
+  attribute_filters
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: DeleteAttributeFilter :exec
+DELETE FROM
+  attribute_filters
+WHERE
+  id = ?;
@@ -0,0 +1,74 @@
+-- name: UpsertProviderCredential :one
+INSERT INTO
+  provider_credentials (provider, encrypted_api_key, key_hint, updated_by)
+VALUES
+  (?, ?, ?, ?) ON CONFLICT (provider) DO
+UPDATE
+SET
+  encrypted_api_key = excluded.encrypted_api_key,
+  key_hint = excluded.key_hint,
+  updated_by = excluded.updated_by,
+  updated_at = CURRENT_TIMESTAMP RETURNING *;
+
+-- name: ListProviderCredentials :many
+SELECT
+  *
+FROM
+  provider_credentials
+ORDER BY
+  provider;
+
+-- name: GetProviderCredential :one
+SELECT
+  *
+FROM
+  provider_credentials
+WHERE
+  provider = ?
+LIMIT
+  1;
+
+-- name: DeleteProviderCredential :exec
+DELETE FROM
+  provider_credentials
+WHERE
+  provider = ?;
+
+-- name: UpsertUserProviderCredential :one
+INSERT INTO
+  user_provider_credentials (user_email, provider, encrypted_api_key, key_hint)
+VALUES
+  (?, ?, ?, ?) ON CONFLICT (user_email, provider) DO
+UPDATE
+SET
+  encrypted_api_key = excluded.encrypted_api_key,
+  key_hint = excluded.key_hint,
+  updated_at = CURRENT_TIMESTAMP RETURNING *;
+
+-- name: ListUserProviderCredentials :many
+SELECT
+  *
+FROM
+  user_provider_credentials
+WHERE
+  user_email = ?
+ORDER BY
+  provider;
+
+-- name: GetUserProviderCredential :one
+SELECT
+  *
+FROM
+  user_provider_credentials
+WHERE
+  user_email = ?
+  AND provider = ?
+LIMIT
+  1;
+
+-- name: DeleteUserProviderCredential :exec
+DELETE FROM
+  user_provider_credentials
+WHERE
+  user_email = ?
+  AND provider = ?;
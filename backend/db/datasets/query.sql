@@ -0,0 +1,39 @@
+-- name: CreateDataset :one
+INSERT INTO
+  datasets (
+    name,
+    kind,
+    service_name,
+    workflow_name,
+    start_time,
+    end_time,
+    annotation_thumb,
+    min_annotation_score,
+    created_by
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: ListDatasets :many
+SELECT
+  *
+FROM
+  datasets
+ORDER BY
+  name;
+
+-- name: GetDataset :one
+SELECT
+  *
+FROM
+  datasets
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: DeleteDataset :exec
+DELETE FROM
+  datasets
+WHERE
+  id = ?;
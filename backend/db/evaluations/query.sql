@@ -0,0 +1,128 @@
+-- name: CreateEvaluationRun :one
+INSERT INTO
+  evaluation_runs (
+    name,
+    service_name,
+    workflow_name,
+    model,
+    judge_prompt,
+    trace_count,
+    created_by,
+    parent_run_id,
+    version
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: FinishEvaluationRun :exec
+UPDATE
+  evaluation_runs
+SET
+  status = ?,
+  error = ?,
+  finished_at = CURRENT_TIMESTAMP
+WHERE
+  id = ?;
+
+-- name: FailRunningEvaluationRuns :exec
+UPDATE
+  evaluation_runs
+SET
+  status = 'failed',
+  error = sqlc.arg(error_message),
+  finished_at = CURRENT_TIMESTAMP
+WHERE
+  status = 'running';
+
+-- name: ListEvaluationRuns :many
+SELECT
+  *
+FROM
+  evaluation_runs
+ORDER BY
+  id DESC;
+
+-- name: GetEvaluationRun :one
+SELECT
+  *
+FROM
+  evaluation_runs
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: DeleteEvaluationRun :exec
+DELETE FROM
+  evaluation_runs
+WHERE
+  id = ?;
+
+-- name: ClearEvaluationRunParent :exec
+UPDATE
+  evaluation_runs
+SET
+  parent_run_id = NULL
+WHERE
+  parent_run_id = ?;
+
+-- name: CreateEvaluationResult :one
+INSERT INTO
+  evaluation_results (run_id, trace_id, span_id, score, reasoning, error)
+VALUES
+  (?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: ListEvaluationResults :many
+SELECT
+  *
+FROM
+  evaluation_results
+WHERE
+  run_id = ?
+ORDER BY
+  id;
+
+-- name: SummarizeEvaluationResults :one
+SELECT
+  COUNT(*) AS evaluated_count,
+  CAST(COALESCE(SUM(error != ''), 0) AS INTEGER) AS failed_count,
+  AVG(score) AS average_score
+FROM
+  evaluation_results
+WHERE
+  run_id = ?;
+
+-- name: CompareEvaluationResults :many
+SELECT
+  run.trace_id,
+  run.span_id,
+  baseline.score AS baseline_score,
+  run.score
+FROM
+  evaluation_results AS run
+  LEFT JOIN evaluation_results AS baseline ON baseline.run_id = sqlc.arg(baseline_run_id)
+  AND baseline.span_id = run.span_id
+WHERE
+  run.run_id = sqlc.arg(run_id)
+ORDER BY
+  run.id;
+
+-- name: DeleteEvaluationResults :exec
+DELETE FROM
+  evaluation_results
+WHERE
+  run_id = ?;
+
+-- name: ListEvaluationTraceScores :many
+SELECT
+  evaluation_results.trace_id,
+  COUNT(evaluation_results.score) AS score_count,
+  CAST(SUM(evaluation_results.score) AS REAL) AS score_sum
+FROM
+  evaluation_results
+  JOIN evaluation_runs ON evaluation_runs.id = evaluation_results.run_id
+WHERE
+  evaluation_runs.service_name = ?
+  AND evaluation_results.score IS NOT NULL
+GROUP BY
+  evaluation_results.trace_id;
@@ -0,0 +1,47 @@
+-- name: CreateExperiment :one
+INSERT INTO
+  experiments (
+    name,
+    service_name,
+    description,
+    variants,
+    enabled,
+    created_by
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: UpdateExperiment :one
+UPDATE
+  experiments
+SET
+  description = ?,
+  variants = ?,
+  enabled = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = ? RETURNING *;
+
+-- name: ListExperiments :many
+SELECT
+  *
+FROM
+  experiments
+ORDER BY
+  name;
+
+-- name: GetExperiment :one
+SELECT
+  *
+FROM
+  experiments
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: DeleteExperiment :exec
+DELETE FROM
+  experiments
+WHERE
+  id = ?;
@@ -0,0 +1,62 @@
+-- name: CreateLLMAuditEntry :exec
+INSERT INTO
+  llm_audit_log (
+    user_email,
+    provider,
+    model,
+    action,
+    status_code,
+    error,
+    input_tokens,
+    output_tokens,
+    latency_ms,
+    prompt_hash
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListLLMAuditEntries :many
+SELECT
+  *
+FROM
+  llm_audit_log
+WHERE
+  (
+    sqlc.arg(user_email) = ''
+    OR user_email = sqlc.arg(user_email)
+  )
+  AND (
+    sqlc.arg(model) = ''
+    OR model = sqlc.arg(model)
+  )
+  AND (
+    sqlc.arg(prompt_hash) = ''
+    OR prompt_hash = sqlc.arg(prompt_hash)
+  )
+  AND (
+    NOT sqlc.arg(errors_only)
+    OR error != ''
+  )
+  AND created_at >= sqlc.arg(start_time)
+  AND created_at < sqlc.arg(end_time)
+ORDER BY
+  created_at DESC,
+  id DESC
+LIMIT
+  sqlc.arg(row_limit);
+
+-- name: SumLLMAuditTokens :one
+SELECT
+  CAST(COALESCE(SUM(input_tokens + output_tokens), 0) AS INTEGER) AS tokens
+FROM
+  llm_audit_log
+WHERE
+  (
+    sqlc.arg(user_email) = ''
+    OR user_email = sqlc.arg(user_email)
+  )
+  AND (
+    sqlc.arg(provider) = ''
+    OR provider = sqlc.arg(provider)
+  )
+  AND created_at >= sqlc.arg(start_time);
@@ -0,0 +1,16 @@
+-- File: db/migrations/00002_workflow_slas.sql
+-- +goose Up
+-- Expected durations for workflow executions. An empty workflow_name is the
+-- default SLA for every workflow of the service.
+CREATE TABLE workflow_slas (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  service_name TEXT NOT NULL,
+  workflow_name TEXT NOT NULL DEFAULT '',
+  expected_duration_ms INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (service_name, workflow_name)
+);
+
+-- +goose Down
+DROP TABLE workflow_slas;
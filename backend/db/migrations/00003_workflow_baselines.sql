@@ -0,0 +1,18 @@
+-- File: db/migrations/00003_workflow_baselines.sql
+-- +goose Up
+-- The golden execution of each workflow. Other executions of the workflow are
+-- compared against it, and alert when their drift score exceeds drift_threshold.
+CREATE TABLE workflow_baselines (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  service_name TEXT NOT NULL,
+  workflow_name TEXT NOT NULL,
+  trace_id TEXT NOT NULL,
+  span_id TEXT NOT NULL,
+  drift_threshold REAL NOT NULL DEFAULT 0.25,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (service_name, workflow_name)
+);
+
+-- +goose Down
+DROP TABLE workflow_baselines;
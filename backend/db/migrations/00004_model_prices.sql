@@ -0,0 +1,19 @@
+-- File: db/migrations/00004_model_prices.sql
+-- +goose Up
+-- LLM list prices, in USD per 1M tokens, used to estimate the cost of LLM spans.
+-- A price applies to spans that started on or after its effective_date
+-- (YYYY-MM-DD, UTC), until the next price of the same model takes effect.
+CREATE TABLE model_prices (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  provider TEXT NOT NULL,
+  model TEXT NOT NULL,
+  input_per_million REAL NOT NULL,
+  output_per_million REAL NOT NULL,
+  effective_date TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (provider, model, effective_date)
+);
+
+-- +goose Down
+DROP TABLE model_prices;
@@ -0,0 +1,28 @@
+-- File: db/migrations/00005_redaction_rules.sql
+-- +goose Up
+-- Rules masking sensitive data in span attributes before they are stored.
+-- kind 'value': pattern (RE2) matches within string values, and matches are
+-- replaced. kind 'key': pattern matches attribute keys, and the whole value is
+-- replaced.
+CREATE TABLE redaction_rules (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  kind TEXT NOT NULL CHECK (kind IN ('value', 'key')),
+  pattern TEXT NOT NULL,
+  replacement TEXT NOT NULL DEFAULT '[REDACTED]',
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Built-in rules, disabled until an operator enables them.
+INSERT INTO
+  redaction_rules (name, kind, pattern, replacement, enabled)
+VALUES
+  ('email', 'value', '[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}', '[EMAIL]', FALSE),
+  ('phone', 'value', '(\+?\d{1,3}[ .-]?)?\(?\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b', '[PHONE]', FALSE),
+  ('api-key', 'value', '\b(sk-[A-Za-z0-9_-]{20,}|AIza[0-9A-Za-z_-]{35}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abpr]-[A-Za-z0-9-]{10,})', '[API_KEY]', FALSE),
+  ('authorization-header', 'key', '(?i)(^|\.)(authorization|api[_-]?key|x-api-key)$', '[REDACTED]', FALSE);
+
+-- +goose Down
+DROP TABLE redaction_rules;
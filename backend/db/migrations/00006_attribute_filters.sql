@@ -0,0 +1,17 @@
+-- File: db/migrations/00006_attribute_filters.sql
+-- +goose Up
+-- Span attribute keys kept ('allow') or dropped ('deny') at ingest, per service.
+-- keys is a JSON array of attribute keys; a key ending in * matches any key
+-- with that prefix. An empty service_name applies to services without their
+-- own filter.
+CREATE TABLE attribute_filters (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  service_name TEXT NOT NULL DEFAULT '' UNIQUE,
+  mode TEXT NOT NULL CHECK (mode IN ('allow', 'deny')),
+  keys TEXT NOT NULL DEFAULT '[]',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE attribute_filters;
@@ -0,0 +1,23 @@
+-- File: db/migrations/00007_annotations.sql
+-- +goose Up
+-- Human feedback on a trace, or on a span when span_id is set, by a signed-in
+-- user. thumb is 'up', 'down' or empty; score and comment are optional.
+CREATE TABLE annotations (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  service_name TEXT NOT NULL,
+  trace_id TEXT NOT NULL,
+  span_id TEXT NOT NULL DEFAULT '',
+  user_email TEXT NOT NULL,
+  thumb TEXT NOT NULL DEFAULT '' CHECK (thumb IN ('', 'up', 'down')),
+  score REAL,
+  comment TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_annotations_service_created ON annotations (service_name, created_at);
+
+CREATE INDEX idx_annotations_trace ON annotations (trace_id, span_id);
+
+-- +goose Down
+DROP TABLE annotations;
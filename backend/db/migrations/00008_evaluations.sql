@@ -0,0 +1,35 @@
+-- File: db/migrations/00008_evaluations.sql
+-- +goose Up
+-- LLM-as-judge evaluation runs over the workflow runs of a service, and the
+-- score the judge gave each of them.
+CREATE TABLE evaluation_runs (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  service_name TEXT NOT NULL,
+  workflow_name TEXT NOT NULL DEFAULT '',
+  model TEXT NOT NULL,
+  judge_prompt TEXT NOT NULL,
+  trace_count INTEGER NOT NULL DEFAULT 0,
+  status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+  error TEXT NOT NULL DEFAULT '',
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP
+);
+
+CREATE TABLE evaluation_results (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  run_id INTEGER NOT NULL,
+  trace_id TEXT NOT NULL,
+  span_id TEXT NOT NULL,
+  score REAL,
+  reasoning TEXT NOT NULL DEFAULT '',
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (run_id, span_id)
+);
+
+-- +goose Down
+DROP TABLE evaluation_results;
+
+DROP TABLE evaluation_runs;
@@ -0,0 +1,23 @@
+-- File: db/migrations/00009_datasets.sql
+-- +goose Up
+-- Datasets select spans of a service to export as JSONL: LLM prompt and
+-- completion pairs, or workflow state snapshots. start_time and end_time are
+-- RFC 3339 bounds, empty when open. When annotation_thumb or
+-- min_annotation_score is set, only annotated traces are included.
+CREATE TABLE datasets (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  kind TEXT NOT NULL CHECK (kind IN ('llm_pairs', 'state_snapshots')),
+  service_name TEXT NOT NULL,
+  workflow_name TEXT NOT NULL DEFAULT '',
+  start_time TEXT NOT NULL DEFAULT '',
+  end_time TEXT NOT NULL DEFAULT '',
+  annotation_thumb TEXT NOT NULL DEFAULT '' CHECK (annotation_thumb IN ('', 'up', 'down')),
+  min_annotation_score REAL,
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE datasets;
@@ -0,0 +1,19 @@
+-- File: db/migrations/00010_experiments.sql
+-- +goose Up
+-- A/B experiments of a service. variants is a JSON array of
+-- {name, prompt_id, prompt_version, model} objects that spans are matched
+-- against at ingest while the experiment is enabled.
+CREATE TABLE experiments (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  service_name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  variants TEXT NOT NULL DEFAULT '[]',
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE experiments;
@@ -0,0 +1,40 @@
+-- File: db/migrations/00011_scoring.sql
+-- +goose Up
+-- Scoring rules evaluated against the final state of workflow runs, and the
+-- score each rule gave a run. field is a dotted path into the final state;
+-- pattern is the regex, or the JSON value compared for equality.
+CREATE TABLE scoring_rules (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  service_name TEXT NOT NULL,
+  workflow_name TEXT NOT NULL DEFAULT '',
+  kind TEXT NOT NULL CHECK (kind IN ('regex', 'json_equals', 'threshold')),
+  field TEXT NOT NULL DEFAULT '',
+  pattern TEXT NOT NULL DEFAULT '',
+  operator TEXT NOT NULL DEFAULT '',
+  threshold REAL,
+  on_ingest BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE scoring_results (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  rule_id INTEGER NOT NULL,
+  service_name TEXT NOT NULL,
+  trace_id TEXT NOT NULL,
+  span_id TEXT NOT NULL,
+  score REAL NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (rule_id, span_id)
+);
+
+CREATE INDEX idx_scoring_results_service ON scoring_results (service_name, trace_id);
+
+-- +goose Down
+DROP INDEX idx_scoring_results_service;
+
+DROP TABLE scoring_results;
+
+DROP TABLE scoring_rules;
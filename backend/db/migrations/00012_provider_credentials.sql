@@ -0,0 +1,17 @@
+-- File: db/migrations/00012_provider_credentials.sql
+-- +goose Up
+-- LLM provider API keys, encrypted with AES-256-GCM. encrypted_api_key is the
+-- base64 nonce and ciphertext; key_hint is the last characters of the key,
+-- shown instead of the key.
+CREATE TABLE provider_credentials (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  provider TEXT NOT NULL UNIQUE,
+  encrypted_api_key TEXT NOT NULL,
+  key_hint TEXT NOT NULL DEFAULT '',
+  updated_by TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE provider_credentials;
@@ -0,0 +1,18 @@
+-- File: db/migrations/00013_user_provider_credentials.sql
+-- +goose Up
+-- LLM provider API keys of individual users, encrypted like
+-- provider_credentials. They override the server keys for the user's own
+-- playground requests.
+CREATE TABLE user_provider_credentials (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_email TEXT NOT NULL,
+  provider TEXT NOT NULL,
+  encrypted_api_key TEXT NOT NULL,
+  key_hint TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (user_email, provider)
+);
+
+-- +goose Down
+DROP TABLE user_provider_credentials;
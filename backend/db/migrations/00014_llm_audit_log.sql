@@ -0,0 +1,30 @@
+-- File: db/migrations/00014_llm_audit_log.sql
+-- +goose Up
+-- Every LLM request made by the server, for compliance and misuse
+-- investigation. Prompts are not stored; prompt_hash is the truncated SHA-256
+-- of the prompt text so identical prompts can be matched.
+CREATE TABLE llm_audit_log (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_email TEXT NOT NULL DEFAULT '',
+  provider TEXT NOT NULL,
+  model TEXT NOT NULL,
+  action TEXT NOT NULL,
+  status_code INTEGER NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  input_tokens INTEGER NOT NULL DEFAULT 0,
+  output_tokens INTEGER NOT NULL DEFAULT 0,
+  latency_ms INTEGER NOT NULL DEFAULT 0,
+  prompt_hash TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_llm_audit_log_created ON llm_audit_log (created_at);
+
+CREATE INDEX idx_llm_audit_log_user ON llm_audit_log (user_email, created_at);
+
+-- +goose Down
+DROP INDEX idx_llm_audit_log_user;
+
+DROP INDEX idx_llm_audit_log_created;
+
+DROP TABLE llm_audit_log;
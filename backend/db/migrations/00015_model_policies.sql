@@ -0,0 +1,18 @@
+-- File: db/migrations/00015_model_policies.sql
+-- +goose Up
+-- Models LLM requests may use, per provider. allowed_models and denied_models
+-- are JSON arrays of model names; a name ending in * matches any model with
+-- that prefix. Denied models are rejected even when allowed, and an empty
+-- allow list allows every model that is not denied.
+CREATE TABLE model_policies (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  provider TEXT NOT NULL UNIQUE,
+  allowed_models TEXT NOT NULL DEFAULT '[]',
+  denied_models TEXT NOT NULL DEFAULT '[]',
+  updated_by TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE model_policies;
@@ -0,0 +1,12 @@
+-- File: db/migrations/00016_model_cache.sql
+-- +goose Up
+-- The model list of each LLM provider, kept across restarts. models is a JSON
+-- array of the models as returned by /llm/models.
+CREATE TABLE model_cache (
+  provider TEXT PRIMARY KEY,
+  models TEXT NOT NULL DEFAULT '[]',
+  fetched_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE model_cache;
@@ -0,0 +1,21 @@
+-- File: db/migrations/00017_moderation_rules.sql
+-- +goose Up
+-- Content policy rules applied to LLM prompts and outputs. kind 'keyword':
+-- pattern matches case-insensitively anywhere in the text. kind 'regex':
+-- pattern is an RE2 regular expression. stage is the text checked ('prompt',
+-- 'response' or 'both'); action is what a match does: 'block' rejects the
+-- request, 'flag' marks the response, and 'log' only logs the match.
+CREATE TABLE moderation_rules (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  kind TEXT NOT NULL CHECK (kind IN ('keyword', 'regex')),
+  pattern TEXT NOT NULL,
+  stage TEXT NOT NULL DEFAULT 'both' CHECK (stage IN ('prompt', 'response', 'both')),
+  action TEXT NOT NULL DEFAULT 'flag' CHECK (action IN ('block', 'flag', 'log')),
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE moderation_rules;
@@ -0,0 +1,12 @@
+-- File: db/migrations/00018_evaluation_versions.sql
+-- +goose Up
+-- Re-evaluations of a run with a new judge prompt. A run re-evaluating another
+-- (parent_run_id) judges the same workflow runs again, as the next version.
+ALTER TABLE evaluation_runs ADD COLUMN parent_run_id INTEGER;
+
+ALTER TABLE evaluation_runs ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE evaluation_runs DROP COLUMN version;
+
+ALTER TABLE evaluation_runs DROP COLUMN parent_run_id;
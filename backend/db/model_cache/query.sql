@@ -0,0 +1,17 @@
+-- name: UpsertModelCache :exec
+INSERT INTO
+  model_cache (provider, models, fetched_at)
+VALUES
+  (?, ?, ?) ON CONFLICT (provider) DO
+UPDATE
+SET
+  models = excluded.models,
+  fetched_at = excluded.fetched_at;
+
+-- name: ListModelCache :many
+SELECT
+  *
+FROM
+  model_cache
+ORDER BY
+  provider;
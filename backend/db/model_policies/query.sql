@@ -0,0 +1,35 @@
+-- name: UpsertModelPolicy :one
+INSERT INTO
+  model_policies (provider, allowed_models, denied_models, updated_by)
+VALUES
+  (?, ?, ?, ?) ON CONFLICT (provider) DO
+UPDATE
+SET
+  allowed_models = excluded.allowed_models,
+  denied_models = excluded.denied_models,
+  updated_by = excluded.updated_by,
+  updated_at = CURRENT_TIMESTAMP RETURNING *;
+
+-- name: ListModelPolicies :many
+SELECT
+  *
+FROM
+  model_policies
+ORDER BY
+  provider;
+
+-- name: GetModelPolicy :one
+SELECT
+  *
+FROM
+  model_policies
+WHERE
+  provider = ?
+LIMIT
+  1;
+
+-- name: DeleteModelPolicy :exec
+DELETE FROM
+  model_policies
+WHERE
+  provider = ?;
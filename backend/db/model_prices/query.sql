@@ -0,0 +1,50 @@
+-- name: CreateModelPrice :one
+INSERT INTO
+  model_prices (provider, model, input_per_million, output_per_million, effective_date)
+VALUES
+  (?, ?, ?, ?, ?) RETURNING *;
+
+-- name: UpdateModelPrice :one
+UPDATE
+  model_prices
+SET
+  provider = ?,
+  model = ?,
+  input_per_million = ?,
+  output_per_million = ?,
+  effective_date = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = ? RETURNING *;
+
+-- name: ListModelPrices :many
+SELECT
+  *
+FROM
+  model_prices
+ORDER BY
+  provider,
+  model,
+  effective_date;
+
+-- name: GetModelPrice :one
+SELECT
+  *
+FROM
+  model_prices
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: CountModelPrices :one
+SELECT
+  COUNT(*)
+FROM
+  model_prices;
+
+-- name: DeleteModelPrice :exec
+DELETE FROM
+  model_prices
+WHERE
+  id = ?;
@@ -0,0 +1,43 @@
+-- name: CreateModerationRule :one
+INSERT INTO
+  moderation_rules (name, kind, pattern, stage, action, enabled)
+VALUES
+  (?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: UpdateModerationRule :one
+UPDATE
+  moderation_rules
+SET
+  name = ?,
+  kind = ?,
+  pattern = ?,
+  stage = ?,
+  action = ?,
+  enabled = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = ? RETURNING *;
+
+-- name: ListModerationRules :many
+SELECT
+  *
+FROM
+  moderation_rules
+ORDER BY
+  id;
+
+-- name: GetModerationRule :one
+SELECT
+  *
+FROM
+  moderation_rules
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: DeleteModerationRule :exec
+DELETE FROM
+  moderation_rules
+WHERE
+  id = ?;
@@ -0,0 +1,42 @@
+-- name: CreateRedactionRule :one
+INSERT INTO
+  redaction_rules (name, kind, pattern, replacement, enabled)
+VALUES
+  (?, ?, ?, ?, ?) RETURNING *;
+
+-- name: UpdateRedactionRule :one
+UPDATE
+  redaction_rules
+SET
+  name = ?,
+  kind = ?,
+  pattern = ?,
+  replacement = ?,
+  enabled = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = ? RETURNING *;
+
+-- name: ListRedactionRules :many
+SELECT
+  *
+FROM
+  redaction_rules
+ORDER BY
+  id;
+
+-- name: GetRedactionRule :one
+SELECT
+  *
+FROM
+  redaction_rules
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: DeleteRedactionRule :exec
+DELETE FROM
+  redaction_rules
+WHERE
+  id = ?;
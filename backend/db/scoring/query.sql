@@ -0,0 +1,89 @@
+-- name: CreateScoringRule :one
+INSERT INTO
+  scoring_rules (
+    name,
+    service_name,
+    workflow_name,
+    kind,
+    field,
+    pattern,
+    operator,
+    threshold,
+    on_ingest,
+    created_by
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: ListScoringRules :many
+SELECT
+  *
+FROM
+  scoring_rules
+ORDER BY
+  name;
+
+-- name: GetScoringRule :one
+SELECT
+  *
+FROM
+  scoring_rules
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: DeleteScoringRule :exec
+DELETE FROM
+  scoring_rules
+WHERE
+  id = ?;
+
+-- name: UpsertScoringResult :exec
+INSERT INTO
+  scoring_results (rule_id, service_name, trace_id, span_id, score)
+VALUES
+  (?, ?, ?, ?, ?) ON CONFLICT (rule_id, span_id) DO
+UPDATE
+SET
+  score = excluded.score,
+  created_at = CURRENT_TIMESTAMP;
+
+-- name: ListScoringResults :many
+SELECT
+  *
+FROM
+  scoring_results
+WHERE
+  rule_id = ?
+ORDER BY
+  id DESC
+LIMIT
+  ?;
+
+-- name: SummarizeScoringResults :one
+SELECT
+  COUNT(*) AS scored_count,
+  AVG(score) AS average_score
+FROM
+  scoring_results
+WHERE
+  rule_id = ?;
+
+-- name: DeleteScoringResults :exec
+DELETE FROM
+  scoring_results
+WHERE
+  rule_id = ?;
+
+-- name: ListScoringTraceScores :many
+SELECT
+  trace_id,
+  COUNT(*) AS score_count,
+  CAST(SUM(score) AS REAL) AS score_sum
+FROM
+  scoring_results
+WHERE
+  service_name = ?
+GROUP BY
+  trace_id;
@@ -4,6 +4,22 @@ INSERT INTO
 VALUES
   (?, ?) RETURNING *;
 
+-- name: CreateFirstUser :one
+-- Inserts the user only when no users exist, so concurrent first-user
+-- requests cannot both succeed. Returns no rows when users already exist.
+INSERT INTO
+  users (email, password_hash)
+SELECT
+  ?,
+  ?
+WHERE
+  NOT EXISTS (
+    SELECT
+      1
+    FROM
+      users
+  ) RETURNING *;
+
 -- name: GetUserByEmail :one
 SELECT
   *
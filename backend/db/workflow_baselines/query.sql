@@ -0,0 +1,47 @@
+-- name: UpsertWorkflowBaseline :one
+INSERT INTO
+  workflow_baselines (service_name, workflow_name, trace_id, span_id, drift_threshold)
+VALUES
+  (?, ?, ?, ?, ?) ON CONFLICT(service_name, workflow_name) DO
+UPDATE
+SET
+  trace_id = excluded.trace_id,
+  span_id = excluded.span_id,
+  drift_threshold = excluded.drift_threshold,
+  updated_at = CURRENT_TIMESTAMP RETURNING *;
+
+-- name: ListWorkflowBaselines :many
+SELECT
+  *
+FROM
+  workflow_baselines
+ORDER BY
+  service_name,
+  workflow_name;
+
+-- name: GetWorkflowBaseline :one
+SELECT
+  *
+FROM
+  workflow_baselines
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: GetWorkflowBaselineByWorkflow :one
+SELECT
+  *
+FROM
+  workflow_baselines
+WHERE
+  service_name = ?
+  AND workflow_name = ?
+LIMIT
+  1;
+
+-- name: DeleteWorkflowBaseline :exec
+DELETE FROM
+  workflow_baselines
+WHERE
+  id = ?;
@@ -0,0 +1,34 @@
+-- name: UpsertWorkflowSLA :one
+INSERT INTO
+  workflow_slas (service_name, workflow_name, expected_duration_ms)
+VALUES
+  (?, ?, ?) ON CONFLICT(service_name, workflow_name) DO
+UPDATE
+SET
+  expected_duration_ms = excluded.expected_duration_ms,
+  updated_at = CURRENT_TIMESTAMP RETURNING *;
+
+-- name: ListWorkflowSLAs :many
+SELECT
+  *
+FROM
+  workflow_slas
+ORDER BY
+  service_name,
+  workflow_name;
+
+-- name: GetWorkflowSLA :one
+SELECT
+  *
+FROM
+  workflow_slas
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: DeleteWorkflowSLA :exec
+DELETE FROM
+  workflow_slas
+WHERE
+  id = ?;
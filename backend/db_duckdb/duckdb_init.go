@@ -16,6 +16,91 @@ var spansSchema string
 //go:embed otel_spans/state_patches_schema.sql
 var statePatchesSchema string
 
+//go:embed otel_spans/workflow_timeouts_schema.sql
+var workflowTimeoutsSchema string
+
+//go:embed otel_spans/patch_chain_checks_schema.sql
+var patchChainChecksSchema string
+
+//go:embed otel_spans/graph_versions_schema.sql
+var graphVersionsSchema string
+
+//go:embed otel_spans/column_cardinality_schema.sql
+var columnCardinalitySchema string
+
+// spansMigrations bring spans tables created by earlier versions up to date
+// with spans_schema.sql.
+var spansMigrations = []string{
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_system VARCHAR",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_operation_name VARCHAR",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_request_model VARCHAR",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_response_model VARCHAR",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_response_id VARCHAR",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_request_temperature DOUBLE",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_request_max_tokens BIGINT",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_usage_input_tokens BIGINT",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_usage_output_tokens BIGINT",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS gen_ai_cost_usd DOUBLE",
+	"CREATE INDEX IF NOT EXISTS idx_gen_ai_request_model ON spans (gen_ai_request_model)",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS enduser_id VARCHAR",
+	"CREATE INDEX IF NOT EXISTS idx_enduser_id ON spans (enduser_id)",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS has_error BOOLEAN DEFAULT FALSE",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS junjo_prompt_id VARCHAR",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS junjo_prompt_version VARCHAR",
+	"CREATE INDEX IF NOT EXISTS idx_junjo_prompt ON spans (junjo_prompt_id, junjo_prompt_version)",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS junjo_experiment_id VARCHAR",
+	"ALTER TABLE spans ADD COLUMN IF NOT EXISTS junjo_experiment_variant VARCHAR",
+	"CREATE INDEX IF NOT EXISTS idx_junjo_experiment ON spans (junjo_experiment_id, junjo_experiment_variant)",
+}
+
+// hasErrorBackfill sets has_error on spans stored before the column was added.
+const hasErrorBackfill = `
+UPDATE spans SET has_error = TRUE
+WHERE (parent_span_id IS NULL OR junjo_span_type = 'workflow')
+  AND trace_id IN (SELECT trace_id FROM spans WHERE status_code = 'STATUS_CODE_ERROR')`
+
+// promptVersionBackfill reads the prompt version of spans stored before the
+// prompt columns were added from their attributes.
+const promptVersionBackfill = `
+UPDATE spans SET
+  junjo_prompt_id = json_extract_string(attributes_json, 'junjo.prompt.id'),
+  junjo_prompt_version = json_extract_string(attributes_json, 'junjo.prompt.version')
+WHERE json_extract_string(attributes_json, 'junjo.prompt.id') <> ''`
+
+// experimentVariantBackfill reads the explicit experiment variant of spans
+// stored before the experiment columns were added from their attributes.
+const experimentVariantBackfill = `
+UPDATE spans SET
+  junjo_experiment_id = json_extract_string(attributes_json, 'junjo.experiment.id'),
+  junjo_experiment_variant = json_extract_string(attributes_json, 'junjo.experiment.variant')
+WHERE json_extract_string(attributes_json, 'junjo.experiment.id') <> ''
+  AND json_extract_string(attributes_json, 'junjo.experiment.variant') <> ''`
+
+// graphVersionsBackfill catalogs the graph structures of spans stored before
+// the graph_versions table was added.
+const graphVersionsBackfill = `
+INSERT OR IGNORE INTO graph_versions
+SELECT
+  sha256(junjo_wf_graph_structure::VARCHAR),
+  service_name,
+  name,
+  any_value(junjo_wf_graph_structure),
+  any_value(json_array_length(junjo_wf_graph_structure, '$.nodes')),
+  any_value(json_array_length(junjo_wf_graph_structure, '$.edges')),
+  MIN(start_time),
+  MAX(start_time),
+  COUNT(*)
+FROM spans
+WHERE junjo_span_type IN ('workflow', 'subflow')
+  AND name IS NOT NULL
+  AND json_valid(junjo_wf_graph_structure::VARCHAR)
+  AND junjo_wf_graph_structure::VARCHAR NOT IN ('', '{}')
+GROUP BY 1, 2, 3`
+
+// CoreTables are the tables created by Connect. Tables created from
+// configuration, such as span type side tables, must not reuse their names.
+var CoreTables = []string{"spans", "state_patches", "workflow_timeouts", "patch_chain_checks", "graph_versions", "column_cardinality"}
+
 // DB is a global variable to hold the database connection.
 var DB *sql.DB
 
@@ -55,11 +140,74 @@ func initializeTables(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize spans table: %w", err)
 	}
 
+	// Columns added to spans after its initial release
+	hasErrorExists, err := columnExists(ctx, "spans", "has_error")
+	if err != nil {
+		return err
+	}
+	promptVersionExists, err := columnExists(ctx, "spans", "junjo_prompt_id")
+	if err != nil {
+		return err
+	}
+	experimentVariantExists, err := columnExists(ctx, "spans", "junjo_experiment_id")
+	if err != nil {
+		return err
+	}
+	for _, migration := range spansMigrations {
+		if _, err := DB.ExecContext(ctx, migration); err != nil {
+			return fmt.Errorf("failed to migrate spans table: %w", err)
+		}
+	}
+	if !hasErrorExists {
+		if _, err := DB.ExecContext(ctx, hasErrorBackfill); err != nil {
+			return fmt.Errorf("failed to backfill has_error: %w", err)
+		}
+	}
+	if !promptVersionExists {
+		if _, err := DB.ExecContext(ctx, promptVersionBackfill); err != nil {
+			return fmt.Errorf("failed to backfill prompt versions: %w", err)
+		}
+	}
+	if !experimentVariantExists {
+		if _, err := DB.ExecContext(ctx, experimentVariantBackfill); err != nil {
+			return fmt.Errorf("failed to backfill experiment variants: %w", err)
+		}
+	}
+
 	// state_patches_schema.sql
 	if err := initTable("state_patches", statePatchesSchema); err != nil {
 		return fmt.Errorf("failed to initialize state_patches table: %w", err)
 	}
 
+	// workflow_timeouts_schema.sql
+	if err := initTable("workflow_timeouts", workflowTimeoutsSchema); err != nil {
+		return fmt.Errorf("failed to initialize workflow_timeouts table: %w", err)
+	}
+
+	// patch_chain_checks_schema.sql
+	if err := initTable("patch_chain_checks", patchChainChecksSchema); err != nil {
+		return fmt.Errorf("failed to initialize patch_chain_checks table: %w", err)
+	}
+
+	// graph_versions_schema.sql
+	graphVersionsExists, err := tableExists(ctx, "graph_versions")
+	if err != nil {
+		return err
+	}
+	if err := initTable("graph_versions", graphVersionsSchema); err != nil {
+		return fmt.Errorf("failed to initialize graph_versions table: %w", err)
+	}
+	if !graphVersionsExists {
+		if _, err := DB.ExecContext(ctx, graphVersionsBackfill); err != nil {
+			return fmt.Errorf("failed to backfill graph_versions: %w", err)
+		}
+	}
+
+	// column_cardinality_schema.sql
+	if err := initTable("column_cardinality", columnCardinalitySchema); err != nil {
+		return fmt.Errorf("failed to initialize column_cardinality table: %w", err)
+	}
+
 	return nil
 }
 
@@ -77,10 +225,9 @@ func initTable(tableName string, schema string) error {
 	ctx := context.Background()
 
 	// Check if the table exists
-	var exists bool
-	err := DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = $1)", tableName).Scan(&exists)
+	exists, err := tableExists(ctx, tableName)
 	if err != nil {
-		return fmt.Errorf("failed to check if table '%s' exists: %w", tableName, err)
+		return err
 	}
 
 	if !exists {
@@ -92,3 +239,23 @@ func initTable(tableName string, schema string) error {
 	}
 	return nil
 }
+
+// tableExists reports whether a table exists.
+func tableExists(ctx context.Context, tableName string) (bool, error) {
+	var exists bool
+	err := DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = $1)", tableName).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if table '%s' exists: %w", tableName, err)
+	}
+	return exists, nil
+}
+
+// columnExists reports whether a table has a column.
+func columnExists(ctx context.Context, tableName string, columnName string) (bool, error) {
+	var exists bool
+	err := DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)", tableName, columnName).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if column '%s.%s' exists: %w", tableName, columnName, err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,15 @@
+CREATE TABLE column_cardinality (
+  -- The promoted column: a span attribute stored in a column of its own
+  table_name VARCHAR NOT NULL,
+  column_name VARCHAR NOT NULL,
+  -- Approximate distinct and non-null values within the checked window
+  distinct_values BIGINT NOT NULL,
+  non_null_values BIGINT NOT NULL,
+  -- 'ok': below the warning threshold
+  -- 'warning': at or above the warning threshold
+  -- 'demoted': reached the cap; the attribute is only stored in attributes_json
+  status VARCHAR NOT NULL,
+  checked_at TIMESTAMPTZ NOT NULL,
+  demoted_at TIMESTAMPTZ,
+  PRIMARY KEY (table_name, column_name)
+);
@@ -0,0 +1,17 @@
+CREATE TABLE graph_versions (
+  -- SHA-256 of the graph structure JSON
+  graph_hash VARCHAR(64) NOT NULL,
+  service_name VARCHAR NOT NULL,
+  -- Name of the workflow or subflow span
+  workflow_name VARCHAR NOT NULL,
+  graph_structure JSON NOT NULL,
+  node_count INTEGER,
+  edge_count INTEGER,
+  -- Start times of the earliest and latest runs with this graph
+  first_seen TIMESTAMPTZ NOT NULL,
+  last_seen TIMESTAMPTZ NOT NULL,
+  run_count BIGINT NOT NULL,
+  PRIMARY KEY (graph_hash, service_name, workflow_name)
+);
+
+CREATE INDEX idx_graph_versions_workflow ON graph_versions (service_name, workflow_name);
@@ -0,0 +1,18 @@
+CREATE TABLE patch_chain_checks (
+  trace_id VARCHAR(32) NOT NULL,
+  span_id VARCHAR(16) NOT NULL,
+  service_name VARCHAR NOT NULL,
+  workflow_name VARCHAR,
+  -- 'ok': replaying the patches over the initial state gives the final state
+  -- 'mismatch': the patches applied, but the result differs from the final state
+  -- 'invalid': a patch could not be applied
+  status VARCHAR NOT NULL,
+  patch_count INTEGER NOT NULL,
+  -- JSON paths where the replayed state differs from the final state
+  mismatched_paths JSON NOT NULL,
+  detail VARCHAR,
+  checked_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (trace_id, span_id)
+);
+
+CREATE INDEX idx_patch_chain_checks_service_name ON patch_chain_checks (service_name);
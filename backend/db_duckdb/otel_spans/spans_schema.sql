@@ -24,6 +24,28 @@ CREATE TABLE spans (
   junjo_wf_state_end JSON,
   junjo_wf_graph_structure JSON,
   junjo_wf_store_id VARCHAR,
+  -- GenAI semantic conventions (LLM spans only)
+  gen_ai_system VARCHAR,
+  gen_ai_operation_name VARCHAR,
+  gen_ai_request_model VARCHAR,
+  gen_ai_response_model VARCHAR,
+  gen_ai_response_id VARCHAR,
+  gen_ai_request_temperature DOUBLE,
+  gen_ai_request_max_tokens BIGINT,
+  gen_ai_usage_input_tokens BIGINT,
+  gen_ai_usage_output_tokens BIGINT,
+  -- Estimated at ingest time from the token counts and model pricing
+  gen_ai_cost_usd DOUBLE,
+  -- End-user identifier (enduser.id or a configured attribute)
+  enduser_id VARCHAR,
+  -- Set on the root and workflow spans of traces with an error span
+  has_error BOOLEAN DEFAULT FALSE,
+  -- Prompt version (junjo.prompt.id / junjo.prompt.version)
+  junjo_prompt_id VARCHAR,
+  junjo_prompt_version VARCHAR,
+  -- A/B experiment variant (junjo.experiment.id / junjo.experiment.variant)
+  junjo_experiment_id VARCHAR,
+  junjo_experiment_variant VARCHAR,
   PRIMARY KEY (trace_id, span_id)
 );
 
@@ -38,4 +60,8 @@ CREATE INDEX idx_end_time ON spans (end_time);
 
 CREATE INDEX idx_junjo_id ON spans (junjo_id);
 
-CREATE INDEX idx_junjo_span_type ON spans (junjo_span_type);
\ No newline at end of file
+CREATE INDEX idx_junjo_span_type ON spans (junjo_span_type);
+
+CREATE INDEX idx_gen_ai_request_model ON spans (gen_ai_request_model);
+
+CREATE INDEX idx_enduser_id ON spans (enduser_id);
\ No newline at end of file
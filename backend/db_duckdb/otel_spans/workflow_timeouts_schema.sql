@@ -0,0 +1,18 @@
+CREATE TABLE workflow_timeouts (
+  trace_id VARCHAR(32) NOT NULL,
+  -- Empty for executions whose workflow span never arrived
+  span_id VARCHAR(16) NOT NULL,
+  service_name VARCHAR NOT NULL,
+  workflow_name VARCHAR,
+  -- 'timed_out': the workflow span had not closed by the deadline
+  -- 'exceeded': the workflow span closed, but took longer than its SLA
+  status VARCHAR NOT NULL,
+  started_at TIMESTAMPTZ NOT NULL,
+  deadline TIMESTAMPTZ NOT NULL,
+  detected_at TIMESTAMPTZ NOT NULL,
+  -- Set when a timed-out execution's workflow span eventually arrives
+  resolved_at TIMESTAMPTZ,
+  PRIMARY KEY (trace_id, span_id)
+);
+
+CREATE INDEX idx_workflow_timeouts_service_name ON workflow_timeouts (service_name);
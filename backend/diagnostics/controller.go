@@ -0,0 +1,32 @@
+package diagnostics
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/labstack/echo/v4"
+)
+
+// InitRoutes registers the net/http/pprof profiles under /debug/pprof and the
+// runtime stats at /debug/runtime. The routes are behind the session auth
+// middleware, and are only registered when debug endpoints are enabled.
+func InitRoutes(e *echo.Echo) {
+	debugGroup := e.Group("/debug")
+
+	debugGroup.GET("/runtime", HandleGetStats)
+
+	debugGroup.GET("/pprof/", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+	debugGroup.GET("/pprof/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	debugGroup.GET("/pprof/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)))
+	debugGroup.GET("/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	debugGroup.POST("/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	debugGroup.GET("/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+	// Named profiles such as heap, goroutine, allocs, block and mutex
+	debugGroup.GET("/pprof/:profile", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+}
+
+// HandleGetStats returns the Go runtime stats and the SQLite and DuckDB
+// internals.
+func HandleGetStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, GetStats(c.Request().Context()))
+}
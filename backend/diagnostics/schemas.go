@@ -0,0 +1,60 @@
+package diagnostics
+
+import "time"
+
+// RuntimeStats reports the Go runtime of the server.
+type RuntimeStats struct {
+	GoVersion  string    `json:"go_version"`
+	StartedAt  time.Time `json:"started_at"`
+	Uptime     string    `json:"uptime"`
+	Goroutines int       `json:"goroutines"`
+	NumCPU     int       `json:"num_cpu"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Heap       HeapStats `json:"heap"`
+	GC         GCStats   `json:"gc"`
+}
+
+// HeapStats reports the heap, in bytes.
+type HeapStats struct {
+	Alloc    uint64 `json:"alloc_bytes"`
+	InUse    uint64 `json:"inuse_bytes"`
+	Idle     uint64 `json:"idle_bytes"`
+	Released uint64 `json:"released_bytes"`
+	Sys      uint64 `json:"sys_bytes"`
+	Objects  uint64 `json:"objects"`
+}
+
+// GCStats reports the garbage collector.
+type GCStats struct {
+	NumGC      uint32     `json:"num_gc"`
+	PauseTotal string     `json:"pause_total"`
+	LastGC     *time.Time `json:"last_gc"`
+	NextGC     uint64     `json:"next_gc_bytes"`
+}
+
+// DatabaseStats reports a database's connection pool, and for DuckDB its
+// storage and memory use as reported by PRAGMA database_size and
+// duckdb_memory().
+type DatabaseStats struct {
+	Pool   PoolStats        `json:"pool"`
+	Size   []map[string]any `json:"size,omitempty"`
+	Memory []map[string]any `json:"memory,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// PoolStats reports a database connection pool.
+type PoolStats struct {
+	MaxOpen      int    `json:"max_open"`
+	Open         int    `json:"open"`
+	InUse        int    `json:"in_use"`
+	Idle         int    `json:"idle"`
+	WaitCount    int64  `json:"wait_count"`
+	WaitDuration string `json:"wait_duration"`
+}
+
+// Stats is the response of the runtime stats endpoint.
+type Stats struct {
+	Runtime RuntimeStats  `json:"runtime"`
+	SQLite  DatabaseStats `json:"sqlite"`
+	DuckDB  DatabaseStats `json:"duckdb"`
+}
@@ -0,0 +1,117 @@
+package diagnostics
+
+import (
+	"context"
+	"database/sql"
+	"runtime"
+	"time"
+
+	"junjo-server/db"
+	"junjo-server/db_duckdb"
+)
+
+// startedAt is when the server process started.
+var startedAt = time.Now()
+
+// GetStats collects the runtime and database stats.
+func GetStats(ctx context.Context) Stats {
+	stats := Stats{Runtime: runtimeStats()}
+	if db.DB != nil {
+		stats.SQLite.Pool = newPoolStats(db.DB.Stats())
+	}
+	if db_duckdb.DB != nil {
+		stats.DuckDB = duckDBStats(ctx, db_duckdb.DB)
+	}
+	return stats
+}
+
+// runtimeStats reads the Go runtime stats. It briefly stops the world to
+// read the memory stats.
+func runtimeStats() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		GoVersion:  runtime.Version(),
+		StartedAt:  startedAt.UTC(),
+		Uptime:     time.Since(startedAt).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Heap: HeapStats{
+			Alloc:    mem.HeapAlloc,
+			InUse:    mem.HeapInuse,
+			Idle:     mem.HeapIdle,
+			Released: mem.HeapReleased,
+			Sys:      mem.HeapSys,
+			Objects:  mem.HeapObjects,
+		},
+		GC: GCStats{
+			NumGC:      mem.NumGC,
+			PauseTotal: time.Duration(mem.PauseTotalNs).String(),
+			NextGC:     mem.NextGC,
+		},
+	}
+	if mem.LastGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC)).UTC()
+		stats.GC.LastGC = &lastGC
+	}
+	return stats
+}
+
+// duckDBStats reads the connection pool, storage and memory stats of DuckDB.
+func duckDBStats(ctx context.Context, database *sql.DB) DatabaseStats {
+	stats := DatabaseStats{Pool: newPoolStats(database.Stats())}
+	var err error
+	if stats.Size, err = queryMaps(ctx, database, "PRAGMA database_size"); err != nil {
+		stats.Error = err.Error()
+		return stats
+	}
+	if stats.Memory, err = queryMaps(ctx, database, "SELECT * FROM duckdb_memory() WHERE memory_usage_bytes > 0 OR temporary_storage_bytes > 0"); err != nil {
+		stats.Error = err.Error()
+	}
+	return stats
+}
+
+// newPoolStats converts the stats of a database handle.
+func newPoolStats(stats sql.DBStats) PoolStats {
+	return PoolStats{
+		MaxOpen:      stats.MaxOpenConnections,
+		Open:         stats.OpenConnections,
+		InUse:        stats.InUse,
+		Idle:         stats.Idle,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: stats.WaitDuration.String(),
+	}
+}
+
+// queryMaps runs a query and returns its rows as column name to value maps.
+func queryMaps(ctx context.Context, database *sql.DB, query string) ([]map[string]any, error) {
+	rows, err := database.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	results := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,18 @@
+package evaluations
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	evaluationGroup := e.Group("/evaluations")
+
+	evaluationGroup.GET("/runs", HandleListRuns)
+	evaluationGroup.POST("/runs", HandleCreateRun)
+	evaluationGroup.GET("/runs/:id", HandleGetRun)
+	evaluationGroup.GET("/runs/:id/results", HandleListResults)
+	evaluationGroup.GET("/runs/:id/export", HandleExportRun)
+	evaluationGroup.POST("/runs/:id/reevaluate", HandleReevaluateRun)
+	evaluationGroup.GET("/runs/:id/comparison", HandleCompareRun)
+	evaluationGroup.DELETE("/runs/:id", HandleDeleteRun)
+}
@@ -0,0 +1,79 @@
+package evaluations
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"junjo-server/datasets"
+	db_duckdb "junjo-server/db_duckdb"
+)
+
+// queryWorkflowState selects the name and the start and end state of a
+// workflow span. Args: trace_id, span_id.
+const queryWorkflowState = `
+	SELECT COALESCE(name, ''), junjo_wf_state_start::VARCHAR, junjo_wf_state_end::VARCHAR
+	FROM spans
+	WHERE trace_id = $1 AND span_id = $2 AND junjo_span_type = 'workflow';`
+
+// Export writes the scored results of a run to w as JSONL in an eval
+// framework format of the datasets package, and returns the number of lines
+// written. Each line is a workflow run's start and end state, labelled with
+// the judge's score and reasoning. Results the judge failed on, and those
+// whose workflow span no longer exists, are skipped.
+func Export(ctx context.Context, run Run, format string, w io.Writer) (int, error) {
+	duck := db_duckdb.DB
+	if duck == nil {
+		return 0, fmt.Errorf("database connection is nil")
+	}
+
+	results, err := ListResults(ctx, run.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	enc := json.NewEncoder(w)
+	count := 0
+	for _, result := range results {
+		if result.Score == nil {
+			continue
+		}
+
+		var workflowName string
+		var input, output sql.NullString
+		err := duck.QueryRowContext(ctx, queryWorkflowState, result.TraceID, result.SpanID).Scan(&workflowName, &input, &output)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+
+		example := datasets.Example{
+			Labels: map[string]any{
+				"trace_id":          result.TraceID,
+				"span_id":           result.SpanID,
+				"workflow_name":     workflowName,
+				"evaluation_run_id": run.ID,
+				"evaluation_run":    run.Name,
+				"judge_model":       run.Model,
+				"score":             *result.Score,
+				"reasoning":         result.Reasoning,
+			},
+		}
+		if input.Valid {
+			example.Input = json.RawMessage(input.String)
+		}
+		if output.Valid {
+			example.Output = json.RawMessage(output.String)
+		}
+		if err := datasets.EncodeExample(enc, format, example); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,199 @@
+package evaluations
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"junjo-server/api/llm"
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/db_gen"
+	"junjo-server/quotas"
+)
+
+// defaultRunLimit is the number of workflow runs evaluated when no limit is
+// given.
+const defaultRunLimit = 50
+
+// defaultJudgeModel is the Gemini model used when no model is given.
+const defaultJudgeModel = "gemini-2.5-flash"
+
+// judgeInstruction makes the judge answer in a format the score can be read
+// from.
+const judgeInstruction = `You are evaluating the output of an AI workflow. Follow the evaluation instructions, then respond only with a JSON object of the form {"score": <number>, "reasoning": "<one or two sentences>"}.`
+
+// queryWorkflowRuns selects the most recent workflow spans of a service.
+// Args: service_name, workflow name (empty for any), start, end, limit.
+const queryWorkflowRuns = `
+	SELECT
+		trace_id, span_id, COALESCE(name, ''),
+		COALESCE(junjo_wf_state_start::VARCHAR, ''), COALESCE(junjo_wf_state_end::VARCHAR, '')
+	FROM spans
+	WHERE service_name = $1
+		AND junjo_span_type = 'workflow'
+		AND ($2 = '' OR name = $2)
+		AND start_time >= $3
+		AND start_time < $4
+	ORDER BY start_time DESC, span_id
+	LIMIT $5;`
+
+// workflowRun is a workflow run to be judged.
+type workflowRun struct {
+	TraceID string
+	SpanID  string
+	Name    string
+	Input   string
+	Output  string
+}
+
+// judgement is the answer of the judge.
+type judgement struct {
+	Score     *float64 `json:"score"`
+	Reasoning string   `json:"reasoning"`
+}
+
+// selectWorkflowRuns returns the workflow runs evaluated by req.
+func selectWorkflowRuns(ctx context.Context, req CreateRunRequest) ([]workflowRun, error) {
+	duck := db_duckdb.DB
+	if duck == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	start := time.Unix(0, 0).UTC()
+	if req.StartTime != nil {
+		start = *req.StartTime
+	}
+	end := time.Now().Add(time.Minute)
+	if req.EndTime != nil {
+		end = *req.EndTime
+	}
+
+	rows, err := duck.QueryContext(ctx, queryWorkflowRuns, req.ServiceName, req.WorkflowName, start, end, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []workflowRun{}
+	for rows.Next() {
+		var run workflowRun
+		if err := rows.Scan(&run.TraceID, &run.SpanID, &run.Name, &run.Input, &run.Output); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// resultWorkflowRuns returns the workflow runs judged by an evaluation run,
+// in evaluation order. Workflow spans that no longer exist, such as those
+// deleted by span retention, are skipped.
+func resultWorkflowRuns(ctx context.Context, runID int64) ([]workflowRun, error) {
+	duck := db_duckdb.DB
+	if duck == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	results, err := ListResults(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []workflowRun{}
+	for _, result := range results {
+		run := workflowRun{TraceID: result.TraceID, SpanID: result.SpanID}
+		var input, output sql.NullString
+		err := duck.QueryRowContext(ctx, queryWorkflowState, result.TraceID, result.SpanID).Scan(&run.Name, &input, &output)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		run.Input = input.String
+		run.Output = output.String
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// renderPrompt fills the judge prompt placeholders for a workflow run.
+func renderPrompt(prompt string, run workflowRun) string {
+	if !strings.Contains(prompt, "{{input}}") && !strings.Contains(prompt, "{{output}}") {
+		prompt += "\n\nInput:\n{{input}}\n\nOutput:\n{{output}}"
+	}
+	return strings.NewReplacer(
+		"{{workflow_name}}", run.Name,
+		"{{input}}", run.Input,
+		"{{output}}", run.Output,
+	).Replace(prompt)
+}
+
+// parseJudgement reads the judge's answer, tolerating a Markdown code fence.
+func parseJudgement(text string) (judgement, error) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+
+	var answer judgement
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &answer); err != nil {
+		return answer, fmt.Errorf("judge answer is not valid JSON: %w", err)
+	}
+	if answer.Score == nil {
+		return answer, fmt.Errorf("judge answer has no score")
+	}
+	return answer, nil
+}
+
+// execute judges each workflow run of an evaluation run in turn and stores the
+// results. The LLM calls count against the quota of the user who started the
+// run; the run fails once the hard limit is enforced.
+func execute(run db_gen.EvaluationRun, workflowRuns []workflowRun) {
+	ctx := context.Background()
+	service := llm.NewGeminiService().ForUser(run.CreatedBy)
+
+	status, runError := StatusCompleted, ""
+	for _, workflowRun := range workflowRuns {
+		if quotas.LLM != nil && quotas.LLM.Limits().Enabled() {
+			if usage := quotas.LLM.Add(run.CreatedBy, 1); usage.Status == quotas.StatusHard {
+				status, runError = StatusFailed, "LLM request quota exceeded"
+				break
+			}
+		}
+
+		params := db_gen.CreateEvaluationResultParams{RunID: run.ID, TraceID: workflowRun.TraceID, SpanID: workflowRun.SpanID}
+		text, err := service.GenerateText(llm.GeminiRequest{
+			Model:             run.Model,
+			Contents:          []llm.GeminiContent{{Role: "user", Parts: []llm.GeminiPart{{Text: renderPrompt(run.JudgePrompt, workflowRun)}}}},
+			GenerationConfig:  &llm.GenerationConfig{ResponseMimeType: "application/json"},
+			SystemInstruction: &llm.SystemInstruction{Parts: []llm.GeminiPart{{Text: judgeInstruction}}},
+		})
+		if err == nil {
+			var answer judgement
+			answer, err = parseJudgement(text)
+			if err == nil {
+				params.Score = sql.NullFloat64{Float64: *answer.Score, Valid: true}
+				params.Reasoning = answer.Reasoning
+			}
+		}
+		if err != nil {
+			params.Error = err.Error()
+		}
+
+		if err := CreateResult(ctx, params); err != nil {
+			log.Printf("failed to store result of evaluation run %d: %v", run.ID, err)
+			status, runError = StatusFailed, "failed to store results"
+			break
+		}
+	}
+
+	if err := FinishRun(ctx, run.ID, status, runError); err != nil {
+		log.Printf("failed to finish evaluation run %d: %v", run.ID, err)
+	}
+}
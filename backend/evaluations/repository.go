@@ -0,0 +1,223 @@
+package evaluations
+
+import (
+	"context"
+	"database/sql"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+)
+
+// Run statuses.
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Init fails the runs left running by a previous process.
+func Init(ctx context.Context) error {
+	queries := db_gen.New(db.DB)
+	return queries.FailRunningEvaluationRuns(ctx, "interrupted by a server restart")
+}
+
+// CreateRun stores a new running evaluation run.
+func CreateRun(ctx context.Context, params db_gen.CreateEvaluationRunParams) (db_gen.EvaluationRun, error) {
+	queries := db_gen.New(db.DB)
+	return queries.CreateEvaluationRun(ctx, params)
+}
+
+// FinishRun sets the final status of a run.
+func FinishRun(ctx context.Context, id int64, status string, errorMessage string) error {
+	queries := db_gen.New(db.DB)
+	return queries.FinishEvaluationRun(ctx, db_gen.FinishEvaluationRunParams{Status: status, Error: errorMessage, ID: id})
+}
+
+// ListRuns retrieves all runs, newest first, with their progress.
+func ListRuns(ctx context.Context) ([]Run, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListEvaluationRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	runs := []Run{}
+	for _, run := range stored {
+		decoded, err := decodeRun(ctx, queries, run)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, decoded)
+	}
+	return runs, nil
+}
+
+// GetRun retrieves a single run by id with its progress.
+func GetRun(ctx context.Context, id int64) (Run, error) {
+	queries := db_gen.New(db.DB)
+	run, err := queries.GetEvaluationRun(ctx, id)
+	if err != nil {
+		return Run{}, err
+	}
+	return decodeRun(ctx, queries, run)
+}
+
+// DeleteRun removes a run and its results. Its re-evaluations are kept,
+// without a parent.
+func DeleteRun(ctx context.Context, id int64) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := db_gen.New(tx)
+	if err := queries.ClearEvaluationRunParent(ctx, sql.NullInt64{Int64: id, Valid: true}); err != nil {
+		return err
+	}
+	if err := queries.DeleteEvaluationResults(ctx, id); err != nil {
+		return err
+	}
+	if err := queries.DeleteEvaluationRun(ctx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateResult stores the judge's result for a workflow run.
+func CreateResult(ctx context.Context, params db_gen.CreateEvaluationResultParams) error {
+	queries := db_gen.New(db.DB)
+	_, err := queries.CreateEvaluationResult(ctx, params)
+	return err
+}
+
+// ListResults retrieves the results of a run in evaluation order.
+func ListResults(ctx context.Context, runID int64) ([]Result, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListEvaluationResults(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	results := []Result{}
+	for _, result := range stored {
+		results = append(results, Result{
+			ID:        result.ID,
+			RunID:     result.RunID,
+			TraceID:   result.TraceID,
+			SpanID:    result.SpanID,
+			Score:     floatPointer(result.Score),
+			Reasoning: result.Reasoning,
+			Error:     result.Error,
+			CreatedAt: result.CreatedAt,
+		})
+	}
+	return results, nil
+}
+
+func decodeRun(ctx context.Context, queries *db_gen.Queries, run db_gen.EvaluationRun) (Run, error) {
+	summary, err := queries.SummarizeEvaluationResults(ctx, run.ID)
+	if err != nil {
+		return Run{}, err
+	}
+	decoded := Run{
+		ID:             run.ID,
+		Version:        run.Version,
+		Name:           run.Name,
+		ServiceName:    run.ServiceName,
+		WorkflowName:   run.WorkflowName,
+		Model:          run.Model,
+		JudgePrompt:    run.JudgePrompt,
+		Status:         run.Status,
+		Error:          run.Error,
+		TraceCount:     run.TraceCount,
+		EvaluatedCount: summary.EvaluatedCount,
+		FailedCount:    summary.FailedCount,
+		AverageScore:   floatPointer(summary.AverageScore),
+		CreatedBy:      run.CreatedBy,
+		CreatedAt:      run.CreatedAt,
+	}
+	if run.FinishedAt.Valid {
+		finishedAt := run.FinishedAt.Time
+		decoded.FinishedAt = &finishedAt
+	}
+	if run.ParentRunID.Valid {
+		parentRunID := run.ParentRunID.Int64
+		decoded.ParentRunID = &parentRunID
+	}
+	return decoded, nil
+}
+
+// Compare compares the scores of a run with those of a baseline run.
+func Compare(ctx context.Context, baseline Run, run Run) (Comparison, error) {
+	queries := db_gen.New(db.DB)
+	rows, err := queries.CompareEvaluationResults(ctx, db_gen.CompareEvaluationResultsParams{BaselineRunID: baseline.ID, RunID: run.ID})
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	comparison := Comparison{
+		BaselineRunID:        baseline.ID,
+		RunID:                run.ID,
+		BaselineAverageScore: baseline.AverageScore,
+		AverageScore:         run.AverageScore,
+		Results:              []ScoreComparison{},
+	}
+	var deltaSum float64
+	var deltaCount int
+	for _, row := range rows {
+		result := ScoreComparison{
+			TraceID:       row.TraceID,
+			SpanID:        row.SpanID,
+			BaselineScore: floatPointer(row.BaselineScore),
+			Score:         floatPointer(row.Score),
+		}
+		if row.BaselineScore.Valid && row.Score.Valid {
+			delta := row.Score.Float64 - row.BaselineScore.Float64
+			result.Delta = &delta
+			deltaSum += delta
+			deltaCount++
+			switch {
+			case delta > 0:
+				comparison.Improved++
+			case delta < 0:
+				comparison.Regressed++
+			default:
+				comparison.Unchanged++
+			}
+		}
+		comparison.Results = append(comparison.Results, result)
+	}
+	if deltaCount > 0 {
+		averageDelta := deltaSum / float64(deltaCount)
+		comparison.AverageDelta = &averageDelta
+	}
+	return comparison, nil
+}
+
+func floatPointer(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	score := value.Float64
+	return &score
+}
+
+// TraceScore is the sum of the judge scores a trace received across the
+// evaluation runs of its service.
+type TraceScore struct {
+	Count int64
+	Sum   float64
+}
+
+// TraceScores returns the judge scores of the traces of a service, by trace
+// id.
+func TraceScores(ctx context.Context, serviceName string) (map[string]TraceScore, error) {
+	queries := db_gen.New(db.DB)
+	rows, err := queries.ListEvaluationTraceScores(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]TraceScore, len(rows))
+	for _, row := range rows {
+		scores[row.TraceID] = TraceScore{Count: row.ScoreCount, Sum: row.ScoreSum}
+	}
+	return scores, nil
+}
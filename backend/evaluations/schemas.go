@@ -0,0 +1,91 @@
+package evaluations
+
+import "time"
+
+// CreateRunRequest starts an evaluation of the most recent workflow runs of a
+// service, optionally limited to one workflow and a time range. The judge
+// prompt may reference {{workflow_name}}, {{input}} and {{output}}, which are
+// replaced with the workflow name and its start and end state; when it
+// references neither input nor output, both are appended.
+type CreateRunRequest struct {
+	Name         string     `json:"name" validate:"required"`
+	ServiceName  string     `json:"service_name" validate:"required"`
+	WorkflowName string     `json:"workflow_name"`
+	StartTime    *time.Time `json:"start_time"`
+	EndTime      *time.Time `json:"end_time"`
+	Limit        int        `json:"limit" validate:"omitempty,min=1,max=500"`
+	Model        string     `json:"model"`
+	JudgePrompt  string     `json:"judge_prompt" validate:"required"`
+}
+
+// ReevaluateRequest judges the workflow runs of an evaluation run again with
+// a new judge prompt, and optionally another model, as its next version. The
+// name defaults to the run's name with the version.
+type ReevaluateRequest struct {
+	Name        string `json:"name"`
+	Model       string `json:"model"`
+	JudgePrompt string `json:"judge_prompt" validate:"required"`
+}
+
+// Run is an evaluation run. Status is running, completed or failed; a run
+// fails when it could not be carried out, not when the judge fails on some
+// workflow runs, which are counted in FailedCount. A re-evaluation has the
+// run it re-evaluates as parent, and the next version.
+type Run struct {
+	ID             int64      `json:"id"`
+	ParentRunID    *int64     `json:"parent_run_id"`
+	Version        int64      `json:"version"`
+	Name           string     `json:"name"`
+	ServiceName    string     `json:"service_name"`
+	WorkflowName   string     `json:"workflow_name"`
+	Model          string     `json:"model"`
+	JudgePrompt    string     `json:"judge_prompt"`
+	Status         string     `json:"status"`
+	Error          string     `json:"error"`
+	TraceCount     int64      `json:"trace_count"`
+	EvaluatedCount int64      `json:"evaluated_count"`
+	FailedCount    int64      `json:"failed_count"`
+	AverageScore   *float64   `json:"average_score"`
+	CreatedBy      string     `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+}
+
+// Result is the judge's score of a workflow run. Error is set, and Score is
+// null, when the judge could not score it.
+type Result struct {
+	ID        int64     `json:"id"`
+	RunID     int64     `json:"run_id"`
+	TraceID   string    `json:"trace_id"`
+	SpanID    string    `json:"span_id"`
+	Score     *float64  `json:"score"`
+	Reasoning string    `json:"reasoning"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Comparison compares the scores of a run with those of a baseline run, by
+// default the run it re-evaluates. Deltas are the score minus the baseline
+// score, for the workflow runs both runs scored.
+type Comparison struct {
+	BaselineRunID        int64             `json:"baseline_run_id"`
+	RunID                int64             `json:"run_id"`
+	BaselineAverageScore *float64          `json:"baseline_average_score"`
+	AverageScore         *float64          `json:"average_score"`
+	AverageDelta         *float64          `json:"average_delta"`
+	Improved             int               `json:"improved"`
+	Regressed            int               `json:"regressed"`
+	Unchanged            int               `json:"unchanged"`
+	Results              []ScoreComparison `json:"results"`
+}
+
+// ScoreComparison is the baseline score and the score of a workflow run. A
+// score is null when the run failed to score it, or, for the baseline, when
+// it was not part of the baseline run.
+type ScoreComparison struct {
+	TraceID       string   `json:"trace_id"`
+	SpanID        string   `json:"span_id"`
+	BaselineScore *float64 `json:"baseline_score"`
+	Score         *float64 `json:"score"`
+	Delta         *float64 `json:"delta"`
+}
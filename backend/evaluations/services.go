@@ -0,0 +1,321 @@
+package evaluations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"junjo-server/datasets"
+	"junjo-server/db_gen"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListRuns lists all evaluation runs, newest first.
+func HandleListRuns(c echo.Context) error {
+	runs, err := ListRuns(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list evaluation runs:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve evaluation runs")
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
+
+// HandleCreateRun selects the workflow runs to evaluate and starts judging
+// them in the background. The run is returned with status running; its
+// progress is followed with HandleGetRun.
+func HandleCreateRun(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	var req CreateRunRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultRunLimit
+	}
+	if req.Model == "" {
+		req.Model = defaultJudgeModel
+	}
+
+	workflowRuns, err := selectWorkflowRuns(c.Request().Context(), req)
+	if err != nil {
+		c.Logger().Error("Failed to select workflow runs:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to select workflow runs")
+	}
+	if len(workflowRuns) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "No workflow runs match the selection")
+	}
+
+	run, err := CreateRun(c.Request().Context(), db_gen.CreateEvaluationRunParams{
+		Name:         req.Name,
+		ServiceName:  req.ServiceName,
+		WorkflowName: req.WorkflowName,
+		Model:        req.Model,
+		JudgePrompt:  req.JudgePrompt,
+		TraceCount:   int64(len(workflowRuns)),
+		Version:      1,
+		CreatedBy:    userEmail,
+	})
+	if err != nil {
+		c.Logger().Error("Failed to create evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create evaluation run")
+	}
+
+	go execute(run, workflowRuns)
+
+	created, err := GetRun(c.Request().Context(), run.ID)
+	if err != nil {
+		c.Logger().Error("Failed to get evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve evaluation run")
+	}
+
+	return c.JSON(http.StatusAccepted, created)
+}
+
+// HandleReevaluateRun judges the workflow runs of a finished evaluation run
+// again with a new judge prompt, as a new run holding the next version. Its
+// scores are compared with the original run's with HandleCompareRun.
+func HandleReevaluateRun(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid run id")
+	}
+
+	var req ReevaluateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	parent, err := GetRun(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Evaluation run not found")
+		}
+		c.Logger().Error("Failed to get evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to re-evaluate evaluation run")
+	}
+	if parent.Status == StatusRunning {
+		return echo.NewHTTPError(http.StatusConflict, "Evaluation run is still running")
+	}
+	version := parent.Version + 1
+	if req.Model == "" {
+		req.Model = parent.Model
+	}
+	if req.Name == "" {
+		req.Name = fmt.Sprintf("%s v%d", parent.Name, version)
+	}
+
+	workflowRuns, err := resultWorkflowRuns(c.Request().Context(), parent.ID)
+	if err != nil {
+		c.Logger().Error("Failed to load workflow runs:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load workflow runs")
+	}
+	if len(workflowRuns) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "None of the evaluated workflow runs exist anymore")
+	}
+
+	run, err := CreateRun(c.Request().Context(), db_gen.CreateEvaluationRunParams{
+		Name:         req.Name,
+		ServiceName:  parent.ServiceName,
+		WorkflowName: parent.WorkflowName,
+		Model:        req.Model,
+		JudgePrompt:  req.JudgePrompt,
+		TraceCount:   int64(len(workflowRuns)),
+		ParentRunID:  sql.NullInt64{Int64: parent.ID, Valid: true},
+		Version:      version,
+		CreatedBy:    userEmail,
+	})
+	if err != nil {
+		c.Logger().Error("Failed to create evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create evaluation run")
+	}
+
+	go execute(run, workflowRuns)
+
+	created, err := GetRun(c.Request().Context(), run.ID)
+	if err != nil {
+		c.Logger().Error("Failed to get evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve evaluation run")
+	}
+
+	return c.JSON(http.StatusAccepted, created)
+}
+
+// HandleCompareRun compares the scores of an evaluation run with those of a
+// baseline run: the run given by the baseline query parameter, by default
+// the run it re-evaluates.
+func HandleCompareRun(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid run id")
+	}
+
+	run, err := GetRun(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Evaluation run not found")
+		}
+		c.Logger().Error("Failed to get evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compare evaluation runs")
+	}
+
+	var baselineID int64
+	if param := c.QueryParam("baseline"); param != "" {
+		baselineID, err = strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid baseline run id")
+		}
+	} else if run.ParentRunID != nil {
+		baselineID = *run.ParentRunID
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, "Evaluation run is not a re-evaluation: a baseline run is required")
+	}
+
+	baseline, err := GetRun(c.Request().Context(), baselineID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Baseline evaluation run not found")
+		}
+		c.Logger().Error("Failed to get baseline evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compare evaluation runs")
+	}
+
+	comparison, err := Compare(c.Request().Context(), baseline, run)
+	if err != nil {
+		c.Logger().Error("Failed to compare evaluation runs:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compare evaluation runs")
+	}
+
+	return c.JSON(http.StatusOK, comparison)
+}
+
+// HandleGetRun retrieves an evaluation run by id with its progress and
+// average score.
+func HandleGetRun(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid run id")
+	}
+
+	run, err := GetRun(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Evaluation run not found")
+		}
+		c.Logger().Error("Failed to get evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve evaluation run")
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+// HandleListResults lists the per-trace results of an evaluation run.
+func HandleListResults(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid run id")
+	}
+
+	if _, err := GetRun(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Evaluation run not found")
+		}
+		c.Logger().Error("Failed to get evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve evaluation results")
+	}
+
+	results, err := ListResults(c.Request().Context(), id)
+	if err != nil {
+		c.Logger().Error("Failed to list evaluation results:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve evaluation results")
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
+
+// HandleExportRun streams the scored results of a finished evaluation run as
+// a JSONL file download. The format query parameter selects openai_evals
+// (default) or langsmith.
+func HandleExportRun(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid run id")
+	}
+	format := c.QueryParam("format")
+	if format == "" {
+		format = datasets.FormatOpenAIEvals
+	}
+	if format != datasets.FormatOpenAIEvals && format != datasets.FormatLangSmith {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid format: must be openai_evals or langsmith")
+	}
+
+	run, err := GetRun(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Evaluation run not found")
+		}
+		c.Logger().Error("Failed to get evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export evaluation run")
+	}
+	if run.Status == StatusRunning {
+		return echo.NewHTTPError(http.StatusConflict, "Evaluation run is still running")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("evaluation_run_%d%s", run.ID, datasets.FileExtension(format))))
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.WriteHeader(http.StatusOK)
+
+	// Once the first line is sent the status can't change, so errors are
+	// logged and the stream is cut short.
+	if _, err := Export(c.Request().Context(), run, format, res); err != nil {
+		c.Logger().Error("Failed to export evaluation run:", err)
+		return err
+	}
+	return nil
+}
+
+// HandleDeleteRun deletes a finished evaluation run and its results.
+func HandleDeleteRun(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid run id")
+	}
+
+	run, err := GetRun(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Evaluation run not found")
+		}
+		c.Logger().Error("Failed to look up evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete evaluation run")
+	}
+	if run.Status == StatusRunning {
+		return echo.NewHTTPError(http.StatusConflict, "Evaluation run is still running")
+	}
+
+	if err := DeleteRun(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete evaluation run:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete evaluation run")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
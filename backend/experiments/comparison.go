@@ -0,0 +1,171 @@
+package experiments
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"junjo-server/annotations"
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/evaluations"
+)
+
+// queryVariantTraces selects the traces of an experiment with their variant,
+// duration, error state, and token usage. Args: service_name, experiment name,
+// start, end.
+const queryVariantTraces = `
+	WITH tagged AS (
+		SELECT trace_id, first(junjo_experiment_variant ORDER BY start_time) AS variant
+		FROM spans
+		WHERE service_name = $1
+			AND junjo_experiment_id = $2
+			AND junjo_experiment_variant IS NOT NULL
+			AND start_time >= $3
+			AND start_time < $4
+		GROUP BY trace_id
+	)
+	SELECT
+		spans.trace_id, tagged.variant,
+		epoch_ms(MAX(end_time)) - epoch_ms(MIN(start_time)),
+		bool_or(status_code = 'STATUS_CODE_ERROR'),
+		COALESCE(SUM(gen_ai_usage_input_tokens), 0),
+		COALESCE(SUM(gen_ai_usage_output_tokens), 0),
+		COALESCE(SUM(gen_ai_cost_usd), 0)
+	FROM spans
+	JOIN tagged ON tagged.trace_id = spans.trace_id
+	WHERE service_name = $1
+	GROUP BY spans.trace_id, tagged.variant;`
+
+// variantTrace is a trace of an experiment.
+type variantTrace struct {
+	TraceID      string
+	Variant      string
+	DurationMs   int64
+	Failed       bool
+	InputTokens  int64
+	OutputTokens int64
+	CostUSD      float64
+}
+
+// CompareVariants aggregates the traces of each variant of an experiment that
+// started within [start, end).
+func CompareVariants(ctx context.Context, experiment Experiment, start time.Time, end time.Time) (ExperimentComparison, error) {
+	duck := db_duckdb.DB
+	if duck == nil {
+		return ExperimentComparison{}, fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := duck.QueryContext(ctx, queryVariantTraces, experiment.ServiceName, experiment.Name, start, end)
+	if err != nil {
+		return ExperimentComparison{}, err
+	}
+	defer rows.Close()
+
+	byVariant := map[string][]variantTrace{}
+	for rows.Next() {
+		var trace variantTrace
+		if err := rows.Scan(&trace.TraceID, &trace.Variant, &trace.DurationMs, &trace.Failed, &trace.InputTokens, &trace.OutputTokens, &trace.CostUSD); err != nil {
+			return ExperimentComparison{}, err
+		}
+		byVariant[trace.Variant] = append(byVariant[trace.Variant], trace)
+	}
+	if err := rows.Err(); err != nil {
+		return ExperimentComparison{}, err
+	}
+
+	evalScores, err := evaluations.TraceScores(ctx, experiment.ServiceName)
+	if err != nil {
+		return ExperimentComparison{}, fmt.Errorf("failed to load eval scores: %w", err)
+	}
+	feedback, err := annotations.TraceSummaries(ctx, experiment.ServiceName)
+	if err != nil {
+		return ExperimentComparison{}, fmt.Errorf("failed to load annotations: %w", err)
+	}
+
+	// Defined variants first, in order, then those only seen in tagged spans
+	names := []string{}
+	defined := map[string]bool{}
+	for _, variant := range experiment.Variants {
+		names = append(names, variant.Name)
+		defined[variant.Name] = true
+	}
+	undefined := []string{}
+	for name := range byVariant {
+		if !defined[name] {
+			undefined = append(undefined, name)
+		}
+	}
+	sort.Strings(undefined)
+	names = append(names, undefined...)
+
+	comparison := ExperimentComparison{
+		ExperimentID: experiment.ID,
+		Name:         experiment.Name,
+		ServiceName:  experiment.ServiceName,
+		Variants:     []VariantComparison{},
+	}
+	for _, name := range names {
+		comparison.Variants = append(comparison.Variants, aggregate(name, byVariant[name], evalScores, feedback))
+	}
+	return comparison, nil
+}
+
+// aggregate computes the comparison of a variant from its traces.
+func aggregate(name string, traces []variantTrace, evalScores map[string]evaluations.TraceScore, feedback map[string]annotations.TraceSummary) VariantComparison {
+	result := VariantComparison{Variant: name, TraceCount: int64(len(traces))}
+	if len(traces) == 0 {
+		return result
+	}
+
+	durations := make([]int64, 0, len(traces))
+	var durationSum int64
+	var evalSum, annotationSum float64
+	for _, trace := range traces {
+		durations = append(durations, trace.DurationMs)
+		durationSum += trace.DurationMs
+		if trace.Failed {
+			result.ErrorTraceCount++
+		}
+		result.InputTokens += trace.InputTokens
+		result.OutputTokens += trace.OutputTokens
+		result.CostUSD += trace.CostUSD
+
+		if score, ok := evalScores[trace.TraceID]; ok {
+			result.EvalScoreCount += score.Count
+			evalSum += score.Sum
+		}
+		if summary, ok := feedback[trace.TraceID]; ok {
+			result.AnnotationScoreCount += summary.ScoreCount
+			annotationSum += summary.ScoreSum
+			result.ThumbsUp += summary.ThumbsUp
+			result.ThumbsDown += summary.ThumbsDown
+		}
+	}
+
+	count := float64(len(traces))
+	result.ErrorRate = float64(result.ErrorTraceCount) / count
+	result.AvgDurationMs = float64(durationSum) / count
+	result.AvgCostUSD = result.CostUSD / count
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	result.P50DurationMs = percentile(durations, 0.5)
+	result.P95DurationMs = percentile(durations, 0.95)
+
+	if result.EvalScoreCount > 0 {
+		avg := evalSum / float64(result.EvalScoreCount)
+		result.AvgEvalScore = &avg
+	}
+	if result.AnnotationScoreCount > 0 {
+		avg := annotationSum / float64(result.AnnotationScoreCount)
+		result.AvgAnnotationScore = &avg
+	}
+	return result
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
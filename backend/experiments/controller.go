@@ -0,0 +1,16 @@
+package experiments
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	experimentGroup := e.Group("/experiments")
+
+	experimentGroup.GET("", HandleListExperiments)
+	experimentGroup.POST("", HandleCreateExperiment)
+	experimentGroup.GET("/:id", HandleGetExperiment)
+	experimentGroup.PUT("/:id", HandleUpdateExperiment)
+	experimentGroup.DELETE("/:id", HandleDeleteExperiment)
+	experimentGroup.GET("/:id/comparison", HandleCompareVariants)
+}
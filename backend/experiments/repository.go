@@ -0,0 +1,145 @@
+package experiments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"junjo-server/telemetry"
+)
+
+// ErrDuplicateName is returned when another experiment has the same name.
+var ErrDuplicateName = errors.New("an experiment with this name already exists")
+
+// Init loads the enabled experiments into the span processor.
+func Init(ctx context.Context) error {
+	return Reload(ctx)
+}
+
+// Reload tags spans indexed afterwards with the variants of the enabled
+// experiments.
+func Reload(ctx context.Context) error {
+	experiments, err := ListExperiments(ctx)
+	if err != nil {
+		return err
+	}
+	byService := map[string][]telemetry.Experiment{}
+	for _, experiment := range experiments {
+		if !experiment.Enabled {
+			continue
+		}
+		variants := make([]telemetry.ExperimentVariant, 0, len(experiment.Variants))
+		for _, variant := range experiment.Variants {
+			variants = append(variants, telemetry.ExperimentVariant{
+				Name:          variant.Name,
+				PromptID:      variant.PromptID,
+				PromptVersion: variant.PromptVersion,
+				Model:         variant.Model,
+			})
+		}
+		byService[experiment.ServiceName] = append(byService[experiment.ServiceName], telemetry.Experiment{Name: experiment.Name, Variants: variants})
+	}
+	telemetry.SetExperiments(byService)
+	return nil
+}
+
+// CreateExperiment stores a new experiment by userEmail.
+func CreateExperiment(ctx context.Context, userEmail string, req CreateExperimentRequest) (Experiment, error) {
+	variants, err := json.Marshal(req.Variants)
+	if err != nil {
+		return Experiment{}, err
+	}
+	queries := db_gen.New(db.DB)
+	experiment, err := queries.CreateExperiment(ctx, db_gen.CreateExperimentParams{
+		Name:        req.Name,
+		ServiceName: req.ServiceName,
+		Description: req.Description,
+		Variants:    string(variants),
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		CreatedBy:   userEmail,
+	})
+	if db.IsUniqueViolation(err) {
+		return Experiment{}, ErrDuplicateName
+	}
+	if err != nil {
+		return Experiment{}, err
+	}
+	return decode(experiment)
+}
+
+// UpdateExperiment replaces the description, variants and state of an
+// experiment.
+func UpdateExperiment(ctx context.Context, id int64, req UpdateExperimentRequest) (Experiment, error) {
+	variants, err := json.Marshal(req.Variants)
+	if err != nil {
+		return Experiment{}, err
+	}
+	queries := db_gen.New(db.DB)
+	experiment, err := queries.UpdateExperiment(ctx, db_gen.UpdateExperimentParams{
+		Description: req.Description,
+		Variants:    string(variants),
+		Enabled:     *req.Enabled,
+		ID:          id,
+	})
+	if err != nil {
+		return Experiment{}, err
+	}
+	return decode(experiment)
+}
+
+// ListExperiments retrieves all experiments.
+func ListExperiments(ctx context.Context) ([]Experiment, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListExperiments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	experiments := []Experiment{}
+	for _, experiment := range stored {
+		decoded, err := decode(experiment)
+		if err != nil {
+			return nil, err
+		}
+		experiments = append(experiments, decoded)
+	}
+	return experiments, nil
+}
+
+// GetExperiment retrieves a single experiment by id.
+func GetExperiment(ctx context.Context, id int64) (Experiment, error) {
+	queries := db_gen.New(db.DB)
+	experiment, err := queries.GetExperiment(ctx, id)
+	if err != nil {
+		return Experiment{}, err
+	}
+	return decode(experiment)
+}
+
+// DeleteExperiment removes an experiment by id. Spans already indexed keep
+// their variant.
+func DeleteExperiment(ctx context.Context, id int64) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteExperiment(ctx, id)
+}
+
+func decode(experiment db_gen.Experiment) (Experiment, error) {
+	decoded := Experiment{
+		ID:          experiment.ID,
+		Name:        experiment.Name,
+		ServiceName: experiment.ServiceName,
+		Description: experiment.Description,
+		Enabled:     experiment.Enabled,
+		CreatedBy:   experiment.CreatedBy,
+		CreatedAt:   experiment.CreatedAt,
+		UpdatedAt:   experiment.UpdatedAt,
+	}
+	if err := json.Unmarshal([]byte(experiment.Variants), &decoded.Variants); err != nil {
+		return decoded, fmt.Errorf("invalid variants of experiment %d: %w", experiment.ID, err)
+	}
+	if decoded.Variants == nil {
+		decoded.Variants = []Variant{}
+	}
+	return decoded, nil
+}
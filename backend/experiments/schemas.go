@@ -0,0 +1,80 @@
+package experiments
+
+import "time"
+
+// Variant is a variant of an experiment. While the experiment is enabled,
+// spans of its service whose prompt id, prompt version and request model
+// match every non-empty field are tagged with the variant at ingest. Spans
+// can also be tagged explicitly with the junjo.experiment.id (the experiment
+// name) and junjo.experiment.variant attributes.
+type Variant struct {
+	Name          string `json:"name" validate:"required"`
+	PromptID      string `json:"prompt_id"`
+	PromptVersion string `json:"prompt_version"`
+	Model         string `json:"model"`
+}
+
+// CreateExperimentRequest defines an experiment. Enabled defaults to true.
+type CreateExperimentRequest struct {
+	Name        string    `json:"name" validate:"required"`
+	ServiceName string    `json:"service_name" validate:"required"`
+	Description string    `json:"description"`
+	Variants    []Variant `json:"variants" validate:"required,min=2,dive"`
+	Enabled     *bool     `json:"enabled"`
+}
+
+// UpdateExperimentRequest replaces the description, variants and state of an
+// experiment. Spans already indexed keep their variant.
+type UpdateExperimentRequest struct {
+	Description string    `json:"description"`
+	Variants    []Variant `json:"variants" validate:"required,min=2,dive"`
+	Enabled     *bool     `json:"enabled" validate:"required"`
+}
+
+// Experiment is an A/B experiment of a service.
+type Experiment struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	ServiceName string    `json:"service_name"`
+	Description string    `json:"description"`
+	Variants    []Variant `json:"variants"`
+	Enabled     bool      `json:"enabled"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// VariantComparison are the aggregates of the traces tagged with a variant.
+// A trace belongs to the variant of its earliest tagged span. Durations and
+// costs cover the whole trace; eval scores are the judge scores of the
+// evaluation runs of the service, and annotation scores and thumbs the
+// feedback on the trace and its spans.
+type VariantComparison struct {
+	Variant              string   `json:"variant"`
+	TraceCount           int64    `json:"trace_count"`
+	ErrorTraceCount      int64    `json:"error_trace_count"`
+	ErrorRate            float64  `json:"error_rate"`
+	AvgDurationMs        float64  `json:"avg_duration_ms"`
+	P50DurationMs        int64    `json:"p50_duration_ms"`
+	P95DurationMs        int64    `json:"p95_duration_ms"`
+	InputTokens          int64    `json:"input_tokens"`
+	OutputTokens         int64    `json:"output_tokens"`
+	CostUSD              float64  `json:"cost_usd"`
+	AvgCostUSD           float64  `json:"avg_cost_usd"`
+	EvalScoreCount       int64    `json:"eval_score_count"`
+	AvgEvalScore         *float64 `json:"avg_eval_score"`
+	AnnotationScoreCount int64    `json:"annotation_score_count"`
+	AvgAnnotationScore   *float64 `json:"avg_annotation_score"`
+	ThumbsUp             int64    `json:"thumbs_up"`
+	ThumbsDown           int64    `json:"thumbs_down"`
+}
+
+// ExperimentComparison compares the variants of an experiment. Variants
+// defined on the experiment are listed first, in order, followed by variants
+// only seen in explicitly tagged spans.
+type ExperimentComparison struct {
+	ExperimentID int64               `json:"experiment_id"`
+	Name         string              `json:"name"`
+	ServiceName  string              `json:"service_name"`
+	Variants     []VariantComparison `json:"variants"`
+}
@@ -0,0 +1,191 @@
+package experiments
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListExperiments lists all experiments.
+func HandleListExperiments(c echo.Context) error {
+	experiments, err := ListExperiments(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list experiments:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve experiments")
+	}
+
+	return c.JSON(http.StatusOK, experiments)
+}
+
+// HandleCreateExperiment defines an experiment. Only spans indexed afterwards
+// are tagged with its variants.
+func HandleCreateExperiment(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	var req CreateExperimentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if err := validateVariants(req.Variants); err != nil {
+		return err
+	}
+
+	experiment, err := CreateExperiment(c.Request().Context(), userEmail, req)
+	if errors.Is(err, ErrDuplicateName) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to create experiment:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save experiment")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload experiments:", err)
+	}
+
+	return c.JSON(http.StatusCreated, experiment)
+}
+
+// HandleGetExperiment retrieves an experiment by id.
+func HandleGetExperiment(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid experiment id")
+	}
+
+	experiment, err := GetExperiment(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Experiment not found")
+		}
+		c.Logger().Error("Failed to get experiment:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve experiment")
+	}
+
+	return c.JSON(http.StatusOK, experiment)
+}
+
+// HandleUpdateExperiment replaces the description, variants and state of an
+// experiment.
+func HandleUpdateExperiment(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid experiment id")
+	}
+
+	var req UpdateExperimentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if err := validateVariants(req.Variants); err != nil {
+		return err
+	}
+
+	experiment, err := UpdateExperiment(c.Request().Context(), id, req)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Experiment not found")
+		}
+		c.Logger().Error("Failed to update experiment:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save experiment")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload experiments:", err)
+	}
+
+	return c.JSON(http.StatusOK, experiment)
+}
+
+// HandleDeleteExperiment deletes an experiment by id.
+func HandleDeleteExperiment(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid experiment id")
+	}
+
+	if _, err := GetExperiment(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Experiment not found")
+		}
+		c.Logger().Error("Failed to look up experiment:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete experiment")
+	}
+
+	if err := DeleteExperiment(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete experiment:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete experiment")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload experiments:", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleCompareVariants compares the latency, cost, and eval and annotation
+// scores of the traces of each variant of an experiment. The traces can be
+// limited to a time range with the start_time and end_time query parameters
+// (RFC 3339).
+func HandleCompareVariants(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid experiment id")
+	}
+
+	start := time.Unix(0, 0).UTC()
+	if raw := c.QueryParam("start_time"); raw != "" {
+		if start, err = time.Parse(time.RFC3339, raw); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid start_time: must be RFC 3339")
+		}
+	}
+	end := time.Now().Add(time.Minute)
+	if raw := c.QueryParam("end_time"); raw != "" {
+		if end, err = time.Parse(time.RFC3339, raw); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid end_time: must be RFC 3339")
+		}
+	}
+
+	experiment, err := GetExperiment(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Experiment not found")
+		}
+		c.Logger().Error("Failed to get experiment:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compare variants")
+	}
+
+	comparison, err := CompareVariants(c.Request().Context(), experiment, start, end)
+	if err != nil {
+		c.Logger().Error("Failed to compare variants:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compare variants")
+	}
+
+	return c.JSON(http.StatusOK, comparison)
+}
+
+// validateVariants rejects variants sharing a name.
+func validateVariants(variants []Variant) error {
+	seen := map[string]bool{}
+	for _, variant := range variants {
+		if seen[variant.Name] {
+			return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: duplicate variant "+variant.Name)
+		}
+		seen[variant.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo, h *Checker) {
+	e.GET("/healthz", HandleHealthz(h))
+	e.GET("/readyz", HandleReadyz(h))
+}
+
+// HandleHealthz reports whether the server is alive: 200 when the local
+// databases respond, and 503 otherwise.
+func HandleHealthz(h *Checker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return respond(c, h.Liveness(c.Request().Context()))
+	}
+}
+
+// HandleReadyz reports whether the server can serve traffic: 200 when every
+// dependency, including the ingestion service, responds, and 503 otherwise.
+func HandleReadyz(h *Checker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return respond(c, h.Readiness(c.Request().Context()))
+	}
+}
+
+func respond(c echo.Context, report Report) error {
+	if report.Status != StatusOK {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,24 @@
+package health
+
+import "time"
+
+// Statuses of a component and of a report.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// ComponentStatus is the result of checking one dependency of the server.
+type ComponentStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the result of a health or readiness check. Status is ok only when
+// every component is.
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+}
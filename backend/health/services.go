@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"junjo-server/db"
+	"junjo-server/db_duckdb"
+	"junjo-server/ingestion_client"
+)
+
+// checkTimeout bounds each component check.
+const checkTimeout = 3 * time.Second
+
+// check verifies one component.
+type check func(ctx context.Context) error
+
+// Checker checks the server's dependencies. Liveness covers the local
+// databases, whose failure needs a restart; readiness adds the ingestion
+// service, without which spans cannot be read.
+type Checker struct {
+	liveness  map[string]check
+	readiness map[string]check
+}
+
+// NewChecker creates a Checker for the SQLite and DuckDB databases and the
+// ingestion service reached through ingestionClient.
+func NewChecker(ingestionClient *ingestion_client.Client) *Checker {
+	liveness := map[string]check{
+		"sqlite": func(ctx context.Context) error { return pingDB(ctx, db.DB) },
+		"duckdb": func(ctx context.Context) error { return pingDB(ctx, db_duckdb.DB) },
+	}
+	readiness := map[string]check{
+		"ingestion": ingestionClient.CheckHealth,
+	}
+	for name, c := range liveness {
+		readiness[name] = c
+	}
+	return &Checker{liveness: liveness, readiness: readiness}
+}
+
+// Liveness checks the local databases.
+func (h *Checker) Liveness(ctx context.Context) Report {
+	return run(ctx, h.liveness)
+}
+
+// Readiness checks every dependency.
+func (h *Checker) Readiness(ctx context.Context) Report {
+	return run(ctx, h.readiness)
+}
+
+// run runs the checks concurrently.
+func run(ctx context.Context, checks map[string]check) Report {
+	report := Report{
+		Status:     StatusOK,
+		Components: make(map[string]ComponentStatus, len(checks)),
+		CheckedAt:  time.Now().UTC(),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c(checkCtx)
+			status := ComponentStatus{Status: StatusOK, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				status.Status = StatusFail
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = status
+			if err != nil {
+				report.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// pingDB runs a query against a database, which unlike a ping also fails when
+// the database file cannot be read.
+func pingDB(ctx context.Context, database *sql.DB) error {
+	if database == nil {
+		return errors.New("database is not connected")
+	}
+	var one int
+	return database.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
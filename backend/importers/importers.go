@@ -0,0 +1,213 @@
+// Package importers converts trace exports from other LLM observability tools
+// into OTLP spans that carry Junjo's attributes, so they can be written with
+// telemetry.ImportTraces.
+package importers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// Supported import sources.
+const (
+	SourceLangSmith = "langsmith"
+	SourceLangFuse  = "langfuse"
+)
+
+// Convert parses an export from the named source and returns the equivalent
+// OTLP request, with every span attributed to serviceName.
+func Convert(source string, data []byte, serviceName string) (*coltracepb.ExportTraceServiceRequest, error) {
+	var spans []*tracepb.Span
+	var err error
+
+	switch source {
+	case SourceLangSmith:
+		spans, err = convertLangSmith(data)
+	case SourceLangFuse:
+		spans, err = convertLangFuse(data)
+	default:
+		return nil, fmt.Errorf("unknown import source %q (expected %q or %q)", source, SourceLangSmith, SourceLangFuse)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{stringAttr("service.name", serviceName)},
+			},
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Scope: &commonpb.InstrumentationScope{Name: "junjo-import-" + source},
+				Spans: spans,
+			}},
+		}},
+	}, nil
+}
+
+// decodeList decodes data as a JSON array of T, a single T, or an API list
+// response of the form {"data": [...]}.
+func decodeList[T any](data []byte) ([]T, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+
+	var envelope struct {
+		Data []T `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Data != nil {
+		return envelope.Data, nil
+	}
+
+	var item T
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	return []T{item}, nil
+}
+
+// traceID maps an external trace id to a 16 byte OTLP trace id. UUIDs are used
+// as-is; any other id is hashed.
+func traceID(id string) []byte {
+	if b, err := hex.DecodeString(strings.ReplaceAll(id, "-", "")); err == nil && len(b) == 16 {
+		return b
+	}
+	sum := sha256.Sum256([]byte(id))
+	return sum[:16]
+}
+
+// spanID maps an external run or observation id to an 8 byte OTLP span id.
+func spanID(id string) []byte {
+	if id == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(id))
+	return sum[:8]
+}
+
+func unixNano(t *time.Time) uint64 {
+	if t == nil || t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+// openInferenceKind maps a run or observation type to the
+// openinference.span.kind attribute value.
+func openInferenceKind(kind string) string {
+	switch strings.ToLower(kind) {
+	case "llm", "generation":
+		return "LLM"
+	case "tool":
+		return "TOOL"
+	case "retriever":
+		return "RETRIEVER"
+	case "embedding":
+		return "EMBEDDING"
+	case "prompt", "parser", "chain", "span":
+		return "CHAIN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// junjoAttrs marks root spans as Junjo workflows (carrying their inputs and
+// outputs as the start and end state) and every other span as a node.
+func junjoAttrs(id, parentID string, isRoot bool, input, output json.RawMessage) []*commonpb.KeyValue {
+	attrs := []*commonpb.KeyValue{stringAttr("junjo.id", id)}
+	if parentID != "" {
+		attrs = append(attrs, stringAttr("junjo.parent_id", parentID))
+	}
+	if !isRoot {
+		return append(attrs, stringAttr("junjo.span_type", "node"))
+	}
+	return append(attrs,
+		stringAttr("junjo.span_type", "workflow"),
+		stringAttr("junjo.workflow.state.start", jsonObject(input)),
+		stringAttr("junjo.workflow.state.end", jsonObject(output)),
+		stringAttr("junjo.workflow.graph_structure", "{}"),
+	)
+}
+
+// jsonObject returns raw if it is a JSON object, otherwise wraps the value as
+// {"value": raw} so it can be used as workflow state.
+func jsonObject(raw json.RawMessage) string {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return "{}"
+	}
+	if strings.HasPrefix(trimmed, "{") {
+		return trimmed
+	}
+	return `{"value":` + trimmed + `}`
+}
+
+// ioAttrs records inputs, outputs, and metadata as OpenInference attributes.
+func ioAttrs(input, output, metadata json.RawMessage) []*commonpb.KeyValue {
+	var attrs []*commonpb.KeyValue
+	for _, kv := range []struct {
+		key   string
+		value json.RawMessage
+	}{
+		{"input.value", input},
+		{"output.value", output},
+		{"metadata", metadata},
+	} {
+		trimmed := strings.TrimSpace(string(kv.value))
+		if trimmed == "" || trimmed == "null" {
+			continue
+		}
+		// Plain strings are stored unquoted; anything else stays JSON.
+		var str string
+		if err := json.Unmarshal(kv.value, &str); err == nil {
+			trimmed = str
+		}
+		attrs = append(attrs, stringAttr(kv.key, trimmed))
+	}
+	return attrs
+}
+
+// tokenAttrs records token usage, skipping counts that are not reported.
+func tokenAttrs(prompt, completion, total int64) []*commonpb.KeyValue {
+	var attrs []*commonpb.KeyValue
+	if prompt > 0 {
+		attrs = append(attrs, intAttr("llm.token_count.prompt", prompt))
+	}
+	if completion > 0 {
+		attrs = append(attrs, intAttr("llm.token_count.completion", completion))
+	}
+	if total > 0 {
+		attrs = append(attrs, intAttr("llm.token_count.total", total))
+	}
+	return attrs
+}
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intAttr(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}}
+}
+
+func stringsAttr(key string, values []string) *commonpb.KeyValue {
+	items := make([]*commonpb.AnyValue, 0, len(values))
+	for _, v := range values {
+		items = append(items, &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}})
+	}
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: items}}}}
+}
@@ -0,0 +1,145 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// langFuseTrace is the subset of a LangFuse trace export that is imported.
+type langFuseTrace struct {
+	ID           string                `json:"id"`
+	Name         string                `json:"name"`
+	Timestamp    *time.Time            `json:"timestamp"`
+	UserID       string                `json:"userId"`
+	SessionID    string                `json:"sessionId"`
+	Input        json.RawMessage       `json:"input"`
+	Output       json.RawMessage       `json:"output"`
+	Metadata     json.RawMessage       `json:"metadata"`
+	Tags         []string              `json:"tags"`
+	Observations []langFuseObservation `json:"observations"`
+}
+
+// langFuseObservation is a span, generation, or event within a LangFuse trace.
+type langFuseObservation struct {
+	ID                  string          `json:"id"`
+	Type                string          `json:"type"`
+	Name                string          `json:"name"`
+	StartTime           *time.Time      `json:"startTime"`
+	EndTime             *time.Time      `json:"endTime"`
+	ParentObservationID string          `json:"parentObservationId"`
+	Input               json.RawMessage `json:"input"`
+	Output              json.RawMessage `json:"output"`
+	Metadata            json.RawMessage `json:"metadata"`
+	Model               string          `json:"model"`
+	Level               string          `json:"level"`
+	StatusMessage       string          `json:"statusMessage"`
+	Usage               struct {
+		Input  int64 `json:"input"`
+		Output int64 `json:"output"`
+		Total  int64 `json:"total"`
+	} `json:"usage"`
+}
+
+// convertLangFuse converts a LangFuse trace export: a list of traces with their
+// observations. Each trace becomes a root span, and each observation a child
+// span under its parent observation (or the trace itself).
+func convertLangFuse(data []byte) ([]*tracepb.Span, error) {
+	traces, err := decodeList[langFuseTrace](data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LangFuse export: %w", err)
+	}
+
+	var spans []*tracepb.Span
+	for _, trace := range traces {
+		if trace.ID == "" {
+			return nil, fmt.Errorf("invalid LangFuse export: trace without id")
+		}
+		if trace.Timestamp == nil {
+			return nil, fmt.Errorf("invalid LangFuse export: trace %s has no timestamp", trace.ID)
+		}
+
+		// The trace has no end time of its own; it ends with its last observation.
+		end := *trace.Timestamp
+		for _, obs := range trace.Observations {
+			for _, t := range []*time.Time{obs.StartTime, obs.EndTime} {
+				if t != nil && t.After(end) {
+					end = *t
+				}
+			}
+		}
+
+		root := &tracepb.Span{
+			TraceId:           traceID(trace.ID),
+			SpanId:            spanID(trace.ID),
+			Name:              trace.Name,
+			Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+			StartTimeUnixNano: unixNano(trace.Timestamp),
+			EndTimeUnixNano:   unixNano(&end),
+			Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK},
+		}
+		root.Attributes = append(root.Attributes,
+			stringAttr("openinference.span.kind", "CHAIN"),
+			stringAttr("langfuse.trace_id", trace.ID),
+		)
+		if trace.UserID != "" {
+			root.Attributes = append(root.Attributes, stringAttr("user.id", trace.UserID))
+		}
+		if trace.SessionID != "" {
+			root.Attributes = append(root.Attributes, stringAttr("session.id", trace.SessionID))
+		}
+		if len(trace.Tags) > 0 {
+			root.Attributes = append(root.Attributes, stringsAttr("tag.tags", trace.Tags))
+		}
+		root.Attributes = append(root.Attributes, ioAttrs(trace.Input, trace.Output, trace.Metadata)...)
+		root.Attributes = append(root.Attributes, junjoAttrs(trace.ID, "", true, trace.Input, trace.Output)...)
+		spans = append(spans, root)
+
+		for _, obs := range trace.Observations {
+			if obs.ID == "" || obs.StartTime == nil {
+				return nil, fmt.Errorf("invalid LangFuse export: observation in trace %s is missing id or startTime", trace.ID)
+			}
+
+			parentID := obs.ParentObservationID
+			if parentID == "" {
+				parentID = trace.ID
+			}
+			obsEnd := obs.EndTime
+			if obsEnd == nil {
+				obsEnd = obs.StartTime
+			}
+
+			span := &tracepb.Span{
+				TraceId:           traceID(trace.ID),
+				SpanId:            spanID(obs.ID),
+				ParentSpanId:      spanID(parentID),
+				Name:              obs.Name,
+				Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+				StartTimeUnixNano: unixNano(obs.StartTime),
+				EndTimeUnixNano:   unixNano(obsEnd),
+				Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK},
+			}
+			if obs.Level == "ERROR" {
+				span.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: obs.StatusMessage}
+				span.Events = errorEvents(obs.StatusMessage, obsEnd)
+			}
+
+			span.Attributes = append(span.Attributes,
+				stringAttr("openinference.span.kind", openInferenceKind(obs.Type)),
+				stringAttr("langfuse.observation_id", obs.ID),
+				stringAttr("langfuse.observation_type", obs.Type),
+			)
+			if obs.Model != "" {
+				span.Attributes = append(span.Attributes, stringAttr("llm.model_name", obs.Model))
+			}
+			span.Attributes = append(span.Attributes, ioAttrs(obs.Input, obs.Output, obs.Metadata)...)
+			span.Attributes = append(span.Attributes, tokenAttrs(obs.Usage.Input, obs.Usage.Output, obs.Usage.Total)...)
+			span.Attributes = append(span.Attributes, junjoAttrs(obs.ID, parentID, false, nil, nil)...)
+
+			spans = append(spans, span)
+		}
+	}
+	return spans, nil
+}
@@ -0,0 +1,138 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// langSmithRun is the subset of a LangSmith run export that is imported.
+type langSmithRun struct {
+	ID               string          `json:"id"`
+	TraceID          string          `json:"trace_id"`
+	ParentRunID      string          `json:"parent_run_id"`
+	Name             string          `json:"name"`
+	RunType          string          `json:"run_type"`
+	StartTime        *time.Time      `json:"start_time"`
+	EndTime          *time.Time      `json:"end_time"`
+	Inputs           json.RawMessage `json:"inputs"`
+	Outputs          json.RawMessage `json:"outputs"`
+	Error            string          `json:"error"`
+	Tags             []string        `json:"tags"`
+	PromptTokens     int64           `json:"prompt_tokens"`
+	CompletionTokens int64           `json:"completion_tokens"`
+	TotalTokens      int64           `json:"total_tokens"`
+	Extra            struct {
+		Metadata         json.RawMessage `json:"metadata"`
+		InvocationParams struct {
+			Model     string `json:"model"`
+			ModelName string `json:"model_name"`
+		} `json:"invocation_params"`
+	} `json:"extra"`
+	ChildRuns []langSmithRun `json:"child_runs"`
+}
+
+// convertLangSmith converts a LangSmith run export: a list of runs (flat, or
+// nested through child_runs), each becoming one span.
+func convertLangSmith(data []byte) ([]*tracepb.Span, error) {
+	runs, err := decodeList[langSmithRun](data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LangSmith export: %w", err)
+	}
+
+	var spans []*tracepb.Span
+	var walk func(run langSmithRun, traceIDHint string) error
+	walk = func(run langSmithRun, traceIDHint string) error {
+		if run.ID == "" {
+			return fmt.Errorf("invalid LangSmith export: run without id")
+		}
+		if run.StartTime == nil {
+			return fmt.Errorf("invalid LangSmith export: run %s has no start_time", run.ID)
+		}
+
+		trace := run.TraceID
+		if trace == "" {
+			trace = traceIDHint
+		}
+		if trace == "" {
+			trace = run.ID // A root run's id doubles as its trace id.
+		}
+		end := run.EndTime
+		if end == nil {
+			end = run.StartTime
+		}
+
+		span := &tracepb.Span{
+			TraceId:           traceID(trace),
+			SpanId:            spanID(run.ID),
+			ParentSpanId:      spanID(run.ParentRunID),
+			Name:              run.Name,
+			Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+			StartTimeUnixNano: unixNano(run.StartTime),
+			EndTimeUnixNano:   unixNano(end),
+			Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK},
+		}
+		if run.Error != "" {
+			span.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: run.Error}
+		}
+
+		span.Attributes = append(span.Attributes,
+			stringAttr("openinference.span.kind", openInferenceKind(run.RunType)),
+			stringAttr("langsmith.run_id", run.ID),
+			stringAttr("langsmith.run_type", run.RunType),
+		)
+		if model := firstNonEmpty(run.Extra.InvocationParams.ModelName, run.Extra.InvocationParams.Model); model != "" {
+			span.Attributes = append(span.Attributes, stringAttr("llm.model_name", model))
+		}
+		if len(run.Tags) > 0 {
+			span.Attributes = append(span.Attributes, stringsAttr("tag.tags", run.Tags))
+		}
+		span.Attributes = append(span.Attributes, ioAttrs(run.Inputs, run.Outputs, run.Extra.Metadata)...)
+		span.Attributes = append(span.Attributes, tokenAttrs(run.PromptTokens, run.CompletionTokens, run.TotalTokens)...)
+		span.Attributes = append(span.Attributes, junjoAttrs(run.ID, run.ParentRunID, run.ParentRunID == "", run.Inputs, run.Outputs)...)
+		span.Events = errorEvents(run.Error, end)
+
+		spans = append(spans, span)
+
+		for _, child := range run.ChildRuns {
+			if child.ParentRunID == "" {
+				child.ParentRunID = run.ID
+			}
+			if err := walk(child, trace); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, run := range runs {
+		if err := walk(run, ""); err != nil {
+			return nil, err
+		}
+	}
+	return spans, nil
+}
+
+// errorEvents records an error message as an OpenTelemetry exception event.
+func errorEvents(message string, at *time.Time) []*tracepb.Span_Event {
+	if message == "" {
+		return nil
+	}
+	return []*tracepb.Span_Event{{
+		Name:         "exception",
+		TimeUnixNano: unixNano(at),
+		Attributes:   []*commonpb.KeyValue{stringAttr("exception.message", message)},
+	}}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
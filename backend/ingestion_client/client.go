@@ -2,12 +2,17 @@ package ingestion_client
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"io"
 
 	pb "junjo-server/proto_gen"
+	"junjo-server/requestid"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 // SpanWithResource holds a span and its associated resource.
@@ -23,11 +28,21 @@ type Client struct {
 	client pb.InternalIngestionServiceClient
 }
 
-// NewClient creates a new gRPC client for the ingestion service.
-func NewClient() (*Client, error) {
-	addr := "junjo-server-ingestion:50052"
-
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewClient creates a new gRPC client for the ingestion service's internal
+// server at addr, such as junjo-server-ingestion:50052. The connection uses
+// tlsConfig when it is not nil, and plaintext otherwise, and is tuned with
+// opts.
+func NewClient(addr string, tlsConfig *tls.Config, opts ...grpc.DialOption) (*Client, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	opts = append(opts,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(requestid.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(requestid.StreamClientInterceptor()),
+	)
+	conn, err := grpc.NewClient(addr, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +60,19 @@ func (c *Client) Close() {
 	}
 }
 
+// CheckHealth checks that the ingestion service's internal server is
+// reachable and serving.
+func (c *Client) CheckHealth(ctx context.Context) error {
+	res, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("ingestion service is %s", res.Status)
+	}
+	return nil
+}
+
 // ReadSpans reads a batch of spans from the ingestion service.
 func (c *Client) ReadSpans(ctx context.Context, startKey []byte, batchSize uint32) ([]*SpanWithResource, error) {
 	req := &pb.ReadSpansRequest{
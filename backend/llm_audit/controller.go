@@ -0,0 +1,9 @@
+package llm_audit
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	e.GET("/llm/audit", HandleListEntries)
+}
@@ -0,0 +1,86 @@
+package llm_audit
+
+import (
+	"context"
+	"junjo-server/api/llm"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"log"
+	"time"
+)
+
+// timestampLayout is the format SQLite stores CURRENT_TIMESTAMP in, so time
+// range bounds compare with created_at as text.
+const timestampLayout = "2006-01-02 15:04:05"
+
+// ListFilter selects audit entries. Empty fields match any value.
+type ListFilter struct {
+	UserEmail  string
+	Model      string
+	PromptHash string
+	ErrorsOnly bool
+	Start      time.Time
+	End        time.Time
+	Limit      int64
+}
+
+// Init records every LLM request made afterwards in the audit log.
+func Init() {
+	llm.SetAuditRecorder(record)
+}
+
+// record stores an audit entry. Failures are logged; they never fail the LLM
+// request.
+func record(ctx context.Context, entry llm.AuditEntry) {
+	queries := db_gen.New(db.DB)
+	err := queries.CreateLLMAuditEntry(ctx, db_gen.CreateLLMAuditEntryParams{
+		UserEmail:    entry.UserEmail,
+		Provider:     entry.Provider,
+		Model:        entry.Model,
+		Action:       entry.Action,
+		StatusCode:   int64(entry.StatusCode),
+		Error:        entry.Error,
+		InputTokens:  entry.InputTokens,
+		OutputTokens: entry.OutputTokens,
+		LatencyMs:    entry.LatencyMs,
+		PromptHash:   entry.PromptHash,
+	})
+	if err != nil {
+		log.Printf("Failed to record LLM audit entry: %v", err)
+	}
+}
+
+// ListEntries retrieves the audit entries matching filter, most recent first.
+func ListEntries(ctx context.Context, filter ListFilter) ([]Entry, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListLLMAuditEntries(ctx, db_gen.ListLLMAuditEntriesParams{
+		UserEmail:  filter.UserEmail,
+		Model:      filter.Model,
+		PromptHash: filter.PromptHash,
+		ErrorsOnly: filter.ErrorsOnly,
+		StartTime:  filter.Start.UTC().Format(timestampLayout),
+		EndTime:    filter.End.UTC().Format(timestampLayout),
+		RowLimit:   filter.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := []Entry{}
+	for _, entry := range stored {
+		entries = append(entries, Entry{
+			ID:           entry.ID,
+			UserEmail:    entry.UserEmail,
+			Provider:     entry.Provider,
+			Model:        entry.Model,
+			Action:       entry.Action,
+			StatusCode:   entry.StatusCode,
+			Error:        entry.Error,
+			InputTokens:  entry.InputTokens,
+			OutputTokens: entry.OutputTokens,
+			LatencyMs:    entry.LatencyMs,
+			PromptHash:   entry.PromptHash,
+			CreatedAt:    entry.CreatedAt,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,21 @@
+package llm_audit
+
+import "time"
+
+// Entry is an audited LLM request. StatusCode is the provider's HTTP status,
+// or 0 when the request was not sent. Token counts are 0 when the provider did
+// not report them.
+type Entry struct {
+	ID           int64     `json:"id"`
+	UserEmail    string    `json:"user_email"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Action       string    `json:"action"`
+	StatusCode   int64     `json:"status_code"`
+	Error        string    `json:"error"`
+	InputTokens  int64     `json:"input_tokens"`
+	OutputTokens int64     `json:"output_tokens"`
+	LatencyMs    int64     `json:"latency_ms"`
+	PromptHash   string    `json:"prompt_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
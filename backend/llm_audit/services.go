@@ -0,0 +1,66 @@
+package llm_audit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Audit entry listing limits.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// HandleListEntries lists audited LLM requests, most recent first. They can be
+// filtered with the user_email, model, prompt_hash, errors_only, start_time
+// and end_time (RFC 3339) query parameters. The number of entries is set with
+// the limit query parameter (default 100, max 1000).
+func HandleListEntries(c echo.Context) error {
+	filter := ListFilter{
+		UserEmail:  c.QueryParam("user_email"),
+		Model:      c.QueryParam("model"),
+		PromptHash: c.QueryParam("prompt_hash"),
+		End:        time.Now().Add(time.Second),
+		Limit:      defaultListLimit,
+	}
+
+	if raw := c.QueryParam("errors_only"); raw != "" {
+		errorsOnly, err := strconv.ParseBool(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid errors_only: must be a boolean")
+		}
+		filter.ErrorsOnly = errorsOnly
+	}
+	if raw := c.QueryParam("start_time"); raw != "" {
+		start, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid start_time: must be RFC 3339")
+		}
+		filter.Start = start
+	}
+	if raw := c.QueryParam("end_time"); raw != "" {
+		end, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid end_time: must be RFC 3339")
+		}
+		filter.End = end
+	}
+	if raw := c.QueryParam("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit: must be a positive integer")
+		}
+		filter.Limit = min(limit, maxListLimit)
+	}
+
+	entries, err := ListEntries(c.Request().Context(), filter)
+	if err != nil {
+		c.Logger().Error("Failed to list LLM audit entries:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve LLM audit entries")
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
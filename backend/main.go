@@ -1,54 +1,101 @@
 package main
 
 import (
-	"database/sql"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 
 	"context"
+	"junjo-server/annotations"
 	"junjo-server/api"
 	"junjo-server/api/internal_auth"
+	"junjo-server/api/llm"
+	api_otel "junjo-server/api/otel"
 	"junjo-server/api_keys"
+	"junjo-server/apierror"
+	"junjo-server/attribute_filters"
 	"junjo-server/auth"
+	"junjo-server/baselines"
+	"junjo-server/budgets"
+	"junjo-server/cardinality"
+	"junjo-server/config"
+	"junjo-server/credentials"
+	"junjo-server/cursor"
+	"junjo-server/datasets"
 	"junjo-server/db"
 	"junjo-server/db_duckdb"
-	"junjo-server/db_gen"
+	"junjo-server/diagnostics"
+	"junjo-server/evaluations"
+	"junjo-server/experiments"
+	"junjo-server/health"
 	"junjo-server/ingestion_client"
+	"junjo-server/llm_audit"
+	"junjo-server/metrics"
 	m "junjo-server/middleware"
+	"junjo-server/model_cache"
+	"junjo-server/model_policies"
+	"junjo-server/moderation"
+	"junjo-server/patchchain"
+	"junjo-server/poller"
+	"junjo-server/pricing"
 	pb "junjo-server/proto_gen"
+	"junjo-server/quotas"
+	"junjo-server/redaction"
+	"junjo-server/requestid"
+	"junjo-server/retention"
+	"junjo-server/scoring"
+	"junjo-server/selftrace"
+	"junjo-server/sla"
 	"junjo-server/telemetry"
 	u "junjo-server/utils"
+	"junjo-server/webhook"
 	"net"
-	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
-	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
-	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 	"google.golang.org/grpc"
-	"google.golang.org/protobuf/proto"
+	grpc_credentials "google.golang.org/grpc/credentials"
+	grpc_health "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func main() {
 	fmt.Println("Running main.go function")
 
+	// Add request IDs to slog records
+	requestid.SetDefaultLogger()
+
 	// Load environment variables
 	err := godotenv.Load()
 	if err != nil {
 		fmt.Printf("%v\n", err)
 	}
 
-	// Host
-	port := "1323"
-	host := "0.0.0.0"
-	serverHostPort := host + ":" + port
+	// Configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	llm.SetDefaultAPIKeys(map[string]string{llm.ProviderGemini: cfg.Gemini.APIKey})
+	llm.SetGeminiBaseURL(cfg.Gemini.BaseURL)
+	credentials.SetSecret(cfg.CredentialsSecret())
+	auth.SetSessionDomain(cfg.SessionDomain())
+	api_otel.SetFrontendURL(cfg.FrontendURL)
+	api_otel.SetImportLimit(cfg.ImportMaxBytes)
+
+	// Self-tracing
+	if cfg.SelfTrace.Enabled {
+		shutdownTracing, err := selftrace.Init(cfg.SelfTrace, cfg.GRPC.DialOptions()...)
+		if err != nil {
+			log.Fatalf("Failed to start self-tracing: %v", err)
+		}
+		defer shutdownTracing(context.Background())
+	}
 
 	// SQLite DB
 	db.Connect()
@@ -61,129 +108,224 @@ func main() {
 	}
 	defer db_duckdb.Close()
 
+	// Custom Span Types
+	spanTypes, err := telemetry.LoadSpanTypes(cfg.SpanTypesPath)
+	if err != nil {
+		log.Fatalf("Invalid span type configuration: %v", err)
+	}
+	spanTypes = append([]telemetry.SpanType{telemetry.ToolCallSpanType}, spanTypes...)
+	if err := telemetry.RegisterSpanTypes(context.Background(), spanTypes...); err != nil {
+		log.Fatalf("Failed to register span types: %v", err)
+	}
+
+	// Attribute Allow / Deny Filters
+	if err := attribute_filters.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to load attribute filters: %v", err)
+	}
+
+	// Evaluation Runs
+	if err := evaluations.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to initialize evaluation runs: %v", err)
+	}
+
+	// A/B Experiments
+	if err := experiments.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to load experiments: %v", err)
+	}
+
+	// LLM Provider Credentials
+	if err := credentials.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to load provider credentials: %v", err)
+	}
+
+	// LLM Model Policies
+	if err := model_policies.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to load model policies: %v", err)
+	}
+
+	// LLM Model Cache
+	if err := model_cache.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to load the model cache: %v", err)
+	}
+
+	// LLM Moderation Rules
+	if err := moderation.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to load moderation rules: %v", err)
+	}
+
+	// LLM Request Audit Log
+	llm_audit.Init()
+
+	// LLM Token Budgets
+	budgets.Init(budgets.Limits{
+		UserDaily:       cfg.LLMBudgets.UserDailyTokens,
+		UserMonthly:     cfg.LLMBudgets.UserMonthlyTokens,
+		ProviderDaily:   cfg.LLMBudgets.ProviderDailyTokens,
+		ProviderMonthly: cfg.LLMBudgets.ProviderMonthlyTokens,
+	})
+
+	// Scoring Rules
+	if err := scoring.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to load scoring rules: %v", err)
+	}
+
+	// PII Redaction Rules
+	if err := redaction.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to load redaction rules: %v", err)
+	}
+
+	// End-User Attribution
+	telemetry.SetEndUserAttributes(cfg.EndUserAttributes)
+
+	// Prompt / Completion Redaction
+	telemetry.SetRedactionConfig(telemetry.RedactionConfig{
+		Mode:           cfg.ContentRedaction.Mode,
+		TruncateLength: cfg.ContentRedaction.TruncateLength,
+	})
+
+	// Model Pricing
+	seedPrices, err := pricing.Load(cfg.ModelPricingPath)
+	if err != nil {
+		log.Fatalf("Invalid model pricing configuration: %v", err)
+	}
+	if err := pricing.Init(context.Background(), seedPrices); err != nil {
+		log.Fatalf("Failed to load model prices: %v", err)
+	}
+
+	// Quotas
+	quotas.Init(
+		quotas.Limits{Soft: cfg.Quotas.SpansSoftLimit, Hard: cfg.Quotas.SpansHardLimit, Grace: cfg.Quotas.GracePeriod},
+		quotas.Limits{Soft: cfg.Quotas.LLMSoftLimit, Hard: cfg.Quotas.LLMHardLimit, Grace: cfg.Quotas.GracePeriod},
+		quotas.RateLimits{PerMinute: cfg.LLMRateLimit.PerMinute, Burst: cfg.LLMRateLimit.Burst, MaxConcurrent: cfg.LLMRateLimit.MaxConcurrent},
+		webhook.New(cfg.Quotas.WebhookURL),
+	)
+
+	// Session Monitoring
+	monitorConfig := auth.MonitorConfig{
+		SessionsPerIP:   cfg.AuthMonitor.SessionsPerIP,
+		Window:          cfg.AuthMonitor.Window,
+		LatitudeHeader:  cfg.AuthMonitor.GeoLatitudeHeader,
+		LongitudeHeader: cfg.AuthMonitor.GeoLongitudeHeader,
+		MaxSpeedKmh:     cfg.AuthMonitor.GeoMaxSpeedKmh,
+		AuditLogPath:    cfg.AuthMonitor.AuditLogPath,
+	}
+	if err := auth.InitMonitor(monitorConfig); err != nil {
+		log.Fatalf("Failed to initialize session monitor: %v", err)
+	}
+
+	// Span Retention
+	go retention.Run(context.Background(), retention.Config{
+		MaxAge:      cfg.Retention.MaxAge,
+		Interval:    cfg.Retention.Interval,
+		ArchivePath: strings.TrimSuffix(cfg.Retention.ArchivePath, "/"),
+	})
+
+	// Workflow SLA Timeout Detection
+	go sla.Run(context.Background(), sla.Config{
+		Interval: cfg.SLA.CheckInterval,
+		Lookback: cfg.SLA.Lookback,
+		Notifier: webhook.New(cfg.SLA.WebhookURL),
+	})
+
+	// Workflow Baseline Drift Detection
+	go baselines.Run(context.Background(), baselines.Config{
+		Interval: cfg.Baselines.CheckInterval,
+		Notifier: webhook.New(cfg.Baselines.WebhookURL),
+	})
+
+	// State Patch Chain Verification
+	go patchchain.Run(context.Background(), patchchain.Config{
+		Interval:  cfg.PatchCheck.Interval,
+		Lookback:  cfg.PatchCheck.Lookback,
+		BatchSize: cfg.PatchCheck.BatchSize,
+	})
+
+	// Promoted Column Cardinality Monitoring
+	if err := cardinality.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to load demoted columns: %v", err)
+	}
+	go cardinality.Run(context.Background(), cardinality.Config{
+		Interval:     cfg.Cardinality.Interval,
+		Window:       cfg.Cardinality.Window,
+		WarnDistinct: cfg.Cardinality.WarnDistinct,
+		MaxDistinct:  cfg.Cardinality.MaxDistinct,
+	})
+
+	// Response Size Limit
+	api_otel.SetResponseLimit(cfg.MaxResponseBytes)
+
 	// Ingestion Client
-	ingestionClient, err := ingestion_client.NewClient()
+	var ingestionTLS *tls.Config
+	if cfg.InternalTLS.Enabled() {
+		if ingestionTLS, err = cfg.InternalTLS.ClientTLS(cfg.IngestionAddr); err != nil {
+			log.Fatalf("Invalid internal TLS configuration: %v", err)
+		}
+	}
+	ingestionClient, err := ingestion_client.NewClient(cfg.IngestionAddr, ingestionTLS, cfg.GRPC.DialOptions()...)
 	if err != nil {
 		log.Fatalf("Failed to create ingestion client: %v", err)
 	}
 	defer ingestionClient.Close()
 
-	// Start a background goroutine to poll for spans
-	go func() {
-		ticker := time.NewTicker(5 * time.Second) // Poll every 3 seconds
-		defer ticker.Stop()
-
-		queries := db_gen.New(db.DB)
-		var lastKey []byte
-		// At startup, try to load the last processed key from the database.
-		retrievedKey, err := queries.GetPollerState(context.Background())
-		if err != nil && err != sql.ErrNoRows {
-			log.Fatalf("Failed to load poller state: %v", err)
-		} else if err == sql.ErrNoRows {
-			log.Println("No previous poller state found. Starting from the beginning.")
-		} else {
-			lastKey = retrievedKey
-			log.Printf("Resuming poller from last key: %x", lastKey)
-		}
+	// Span Writer
+	writerConfig := telemetry.WriterConfig{
+		MaxRows:    cfg.SpanWriter.MaxRows,
+		MaxLatency: cfg.SpanWriter.MaxLatency,
+	}
 
-		for range ticker.C {
-			log.Println("Polling for new spans...")
-			spans, err := ingestionClient.ReadSpans(context.Background(), lastKey, 100)
-			if err != nil {
-				log.Printf("Error reading spans: %v", err)
-				continue
-			}
+	// Tail Sampling
+	tailConfig := telemetry.TailSamplingConfig{
+		Enabled:          cfg.TailSampling.Enabled,
+		DecisionWait:     cfg.TailSampling.DecisionWait,
+		LatencyThreshold: cfg.TailSampling.LatencyThreshold,
+		KeepRate:         cfg.TailSampling.KeepRate,
+		MaxTraces:        cfg.TailSampling.MaxTraces,
+	}
+	if tailConfig.Enabled {
+		log.Printf("Tail sampling enabled: keeping errored traces, traces over %s, and %.0f%% of other traces", tailConfig.LatencyThreshold, tailConfig.KeepRate*100)
+	}
 
-			if len(spans) > 0 {
-				lastKey = spans[len(spans)-1].KeyUlid
-				log.Printf("Received %d spans. Last key: %x", len(spans), lastKey)
-				var processedSpans []*tracepb.Span
-				for _, receivedSpan := range spans {
-					var span tracepb.Span
-					if err := proto.Unmarshal(receivedSpan.SpanBytes, &span); err != nil {
-						log.Printf("Error unmarshaling span: %v", err)
-						continue // Skip to the next span
-					}
-					processedSpans = append(processedSpans, &span)
-				}
-
-				if len(processedSpans) > 0 {
-					// Extract the service name from the first span's resource
-					// All spans in a batch should have the same service name
-					var serviceName string
-					if len(spans) > 0 {
-						// Unmarshal the resource bytes
-						var resource resourcepb.Resource
-						if err := proto.Unmarshal(spans[0].ResourceBytes, &resource); err != nil {
-							log.Printf("Error unmarshaling resource: %v", err)
-							serviceName = "NO_SERVICE_NAME"
-						} else {
-							// Extract service name from resource attributes
-							for _, attr := range resource.Attributes {
-								if attr.Key == "service.name" {
-									if stringValue, ok := attr.Value.Value.(*commonpb.AnyValue_StringValue); ok {
-										serviceName = stringValue.StringValue
-										break
-									}
-								}
-							}
-							if serviceName == "" {
-								serviceName = "NO_SERVICE_NAME"
-							}
-						}
-					} else {
-						serviceName = "NO_SERVICE_NAME"
-					}
-
-					if err := telemetry.BatchProcessSpans(context.Background(), serviceName, processedSpans); err != nil {
-						log.Printf("Error processing spans batch: %v", err)
-					} else {
-						// If the batch was processed successfully, update the last key in the database.
-						err := queries.UpsertPollerState(context.Background(), lastKey)
-						if err != nil {
-							log.Printf("Failed to save poller state: %v", err)
-						}
-					}
-				}
-			} else {
-				log.Println("No new spans found.")
-			}
-		}
-	}()
+	// Start a background goroutine to poll for spans
+	pollerConfig := poller.Config{
+		Interval:  cfg.Poller.Interval,
+		BatchSize: uint32(cfg.Poller.BatchSize),
+	}
+	spanPoller := poller.New(pollerConfig, ingestionClient, writerConfig, tailConfig)
+	go spanPoller.Run(context.Background())
 
 	// Initialize Echo
 	e := echo.New()
-	e.Logger.Printf("initialized echo with host:port %s", serverHostPort)
+	e.Logger.Printf("initialized echo with host:port %s", cfg.Listen.HTTP)
 	e.Validator = u.NewCustomValidator()
+	e.HTTPErrorHandler = apierror.Handler
 
 	// Middleware
 	e.Pre(middleware.Recover()) // Recover must be first
+	e.Use(requestid.Middleware())
 	e.Use(middleware.Logger())
+	e.Use(selftrace.Middleware())
 
 	// CORS middleware
 	// Must be registered with `Pre` to run before the router, which allows it to handle
 	// OPTIONS requests for routes that don't have an explicit OPTIONS handler.
-	allowedOriginsEnv := os.Getenv("JUNJO_ALLOW_ORIGINS")
-	config := middleware.CORSConfig{
+	corsConfig := middleware.CORSConfig{
 		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderXCSRFToken},
+		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderXCSRFToken, echo.HeaderXRequestID},
+		ExposeHeaders:    []string{echo.HeaderXRequestID, quotas.HeaderQuotaLimit, quotas.HeaderQuotaSoftLimit, quotas.HeaderQuotaUsed, quotas.HeaderQuotaRemaining, quotas.HeaderQuotaReset, quotas.HeaderQuotaStatus, api_otel.HeaderContinuationToken},
 		AllowCredentials: true,
 	}
 
 	// AllowOriginFunc is a custom function to validate the origin.
 	// It's used here to provide more robust logging and explicit control over the CORS logic.
 	// According to Echo docs, if this option is set, the AllowOrigins array is ignored.
-	config.AllowOriginFunc = func(origin string) (bool, error) {
-		allowedOriginsEnv := os.Getenv("JUNJO_ALLOW_ORIGINS")
-		if len(allowedOriginsEnv) == 0 {
+	corsConfig.AllowOriginFunc = func(origin string) (bool, error) {
+		if len(cfg.AllowOrigins) == 0 {
 			e.Logger.Infof("CORS check: JUNJO_ALLOW_ORIGINS not set. Allowing origin for local dev: %s", origin)
 			return true, nil
 		}
 
-		allowedOrigins := strings.Split(allowedOriginsEnv, ",")
-		for _, allowed := range allowedOrigins {
-			trimmedAllowed := strings.TrimSpace(allowed)
-			if trimmedAllowed == origin {
+		for _, allowed := range cfg.AllowOrigins {
+			if allowed == origin {
 				e.Logger.Infof("CORS check: Allowing origin (exact match): %s", origin)
 				return true, nil
 			}
@@ -194,19 +336,18 @@ func main() {
 	}
 
 	// Log the configured origins for clarity on startup
-	if len(allowedOriginsEnv) > 0 {
-		e.Logger.Printf("CORS Allowed Origins configured via JUNJO_ALLOW_ORIGINS: %s", allowedOriginsEnv)
+	if len(cfg.AllowOrigins) > 0 {
+		e.Logger.Printf("CORS Allowed Origins configured via JUNJO_ALLOW_ORIGINS: %s", strings.Join(cfg.AllowOrigins, ","))
 	} else {
 		e.Logger.Printf("CORS Allowed Origins not set. Reflecting any origin.")
 	}
-	e.Pre(middleware.CORSWithConfig(config))
+	e.Pre(middleware.CORSWithConfig(corsConfig))
 
 	// Session Middleware
-	sessionSecret := os.Getenv("JUNJO_SESSION_SECRET")
-	if sessionSecret == "" {
-		log.Fatal("JUNJO_SESSION_SECRET environment variable is not set")
-	}
-	e.Use(session.Middleware(sessions.NewCookieStore([]byte(sessionSecret))))
+	e.Use(session.Middleware(sessions.NewCookieStore([]byte(cfg.SessionSecret))))
+
+	// Pagination cursors are signed with the session secret
+	cursor.SetSecret(cfg.SessionSecret)
 
 	// CSRF Middleware (Echo's built-in CSRF)
 	e.Use(middleware.CSRFWithConfig(middleware.CSRFConfig{
@@ -222,15 +363,50 @@ func main() {
 			}
 			return false
 		},
+		ErrorHandler: func(err error, c echo.Context) error {
+			auth.RecordCSRFRejection(c, err)
+			return err
+		},
 	}))
 
 	// Auth Middleware
-	e.Use(m.Auth()) // Auth guard all routes by default
+	e.Use(m.Auth(cfg.MetricsToken)) // Auth guard all routes by default
 
 	// ROUTES
 	auth.InitRoutes(e)
 	api.InitRoutes(e)
 	api_keys.InitRoutes(e)
+	quotas.InitRoutes(e)
+	sla.InitRoutes(e)
+	baselines.InitRoutes(e)
+	patchchain.InitRoutes(e)
+	cardinality.InitRoutes(e)
+	pricing.InitRoutes(e)
+	redaction.InitRoutes(e)
+	attribute_filters.InitRoutes(e)
+	annotations.InitRoutes(e)
+	evaluations.InitRoutes(e)
+	datasets.InitRoutes(e)
+	experiments.InitRoutes(e)
+	scoring.InitRoutes(e)
+	credentials.InitRoutes(e)
+	llm_audit.InitRoutes(e)
+	budgets.InitRoutes(e)
+	model_policies.InitRoutes(e)
+	moderation.InitRoutes(e)
+	poller.InitRoutes(e, spanPoller)
+
+	// Metrics route (Prometheus text format)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+
+	// Debug routes (pprof and runtime stats)
+	if cfg.DebugEndpoints {
+		diagnostics.InitRoutes(e)
+		log.Printf("Debug endpoints enabled under /debug")
+	}
+
+	// Health and readiness routes
+	health.InitRoutes(e, health.NewChecker(ingestionClient))
 
 	// Ping route
 	e.GET("/ping", func(c echo.Context) error {
@@ -239,16 +415,40 @@ func main() {
 
 	// --- Internal gRPC Server Setup ---
 	go func() {
-		internalGrpcAddr := ":50053"
-		lis, err := net.Listen("tcp", internalGrpcAddr)
+		lis, err := net.Listen("tcp", cfg.Listen.InternalGRPC)
 		if err != nil {
 			log.Fatalf("Failed to listen for internal gRPC: %v", err)
 		}
 
-		grpcServer := grpc.NewServer()
+		opts := cfg.GRPC.ServerOptions()
+		if cfg.InternalTLS.Enabled() {
+			tlsConfig, err := cfg.InternalTLS.ServerTLS()
+			if err != nil {
+				log.Fatalf("Invalid internal TLS configuration: %v", err)
+			}
+			opts = append(opts, grpc.Creds(grpc_credentials.NewTLS(tlsConfig)))
+		}
+
+		callers, err := internal_auth.NewCallerPolicy(cfg.InternalAuth.Token, cfg.InternalAuth.AllowedNetworks, cfg.InternalAuth.AllowedClientNames)
+		if err != nil {
+			log.Fatalf("Invalid internal auth configuration: %v", err)
+		}
+		interceptors := []grpc.UnaryServerInterceptor{requestid.UnaryServerInterceptor()}
+		if callers.Enabled() {
+			interceptors = append(interceptors, callers.UnaryInterceptor())
+		} else if !cfg.InternalTLS.Enabled() {
+			log.Printf("Warning: the internal gRPC server accepts any caller; set JUNJO_INTERNAL_AUTH_TOKEN, JUNJO_INTERNAL_ALLOWED_NETWORKS or internal TLS to restrict it")
+		}
+
+		opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...))
+
+		grpcServer := grpc.NewServer(opts...)
 		internalAuthSvc := internal_auth.NewInternalAuthService()
 		pb.RegisterInternalAuthServiceServer(grpcServer, internalAuthSvc)
 
+		// Register health server for the ingestion service and grpc_health_probe
+		healthpb.RegisterHealthServer(grpcServer, grpc_health.NewServer())
+
 		log.Printf("Internal gRPC server listening at %v", lis.Addr())
 		if err := grpcServer.Serve(lis); err != nil {
 			log.Fatalf("Failed to serve internal gRPC: %v", err)
@@ -256,5 +456,5 @@ func main() {
 	}()
 
 	// Start the server
-	e.Logger.Fatal(e.Start(serverHostPort))
+	e.Logger.Fatal(e.Start(cfg.Listen.HTTP))
 }
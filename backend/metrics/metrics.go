@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// collector writes a metric family in the Prometheus text exposition format.
+type collector interface {
+	write(w io.Writer)
+}
+
+// Counter is a monotonically increasing metric.
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Uint64
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add increments the counter by n.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current counter value.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+func (c *Counter) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", c.name, c.help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+	fmt.Fprintf(w, "%s %d\n", c.name, c.Value())
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+// register adds a metric to the registry.
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// NewCounter creates and registers a new counter.
+func NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	register(c)
+	return c
+}
+
+// CounterVec is a counter partitioned by label values.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]uint64 // Keyed by the formatted label set
+}
+
+// NewCounterVec creates and registers a new counter with the given labels.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]uint64)}
+	register(v)
+	return v
+}
+
+// Add increments the counter for the label values, given in label order, by n.
+func (v *CounterVec) Add(n uint64, labelValues ...string) {
+	key := formatLabels(v.labels, labelValues, "", "")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[key] += n
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", v.name)
+	for _, key := range sortedKeys(v.values) {
+		fmt.Fprintf(w, "%s%s %d\n", v.name, key, v.values[key])
+	}
+}
+
+// HistogramVec is a histogram partitioned by label values.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64 // Ascending upper bounds, excluding +Inf
+
+	mu     sync.Mutex
+	series map[string]*histogram // Keyed by the label values joined with \xff
+}
+
+// histogram is one series of a HistogramVec.
+type histogram struct {
+	labelValues []string
+	counts      []uint64 // Per bucket, not cumulative; the last is +Inf
+	sum         float64
+	count       uint64
+}
+
+// NewHistogramVec creates and registers a new histogram with the given bucket
+// upper bounds and labels.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogram)}
+	register(v)
+	return v
+}
+
+// Observe records a value for the label values, given in label order.
+func (v *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	h, ok := v.series[key]
+	if !ok {
+		h = &histogram{labelValues: labelValues, counts: make([]uint64, len(v.buckets)+1)}
+		v.series[key] = h
+	}
+	h.counts[sort.SearchFloat64s(v.buckets, value)]++
+	h.sum += value
+	h.count++
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", v.name)
+	for _, key := range sortedKeys(v.series) {
+		h := v.series[key]
+		var cumulative uint64
+		for i, count := range h.counts {
+			cumulative += count
+			bound := "+Inf"
+			if i < len(v.buckets) {
+				bound = strconv.FormatFloat(v.buckets[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, h.labelValues, "le", bound), cumulative)
+		}
+		labels := formatLabels(v.labels, h.labelValues, "", "")
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, labels, h.count)
+	}
+}
+
+// formatLabels formats a label set, such as {provider="gemini",model="x"},
+// with an optional extra label appended.
+func formatLabels(labels, values []string, extraLabel, extraValue string) string {
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, label+"="+strconv.Quote(value))
+	}
+	if extraLabel != "" {
+		pairs = append(pairs, extraLabel+"="+strconv.Quote(extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Handler serves all registered metrics in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryMu.Lock()
+		collectors := append([]collector(nil), registry...)
+		registryMu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, c := range collectors {
+			c.write(w)
+		}
+	})
+}
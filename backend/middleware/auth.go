@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"strings"
 
 	"junjo-server/auth"
 
@@ -9,10 +11,15 @@ import (
 )
 
 // Auth Routes To Skip
-var authRoutesToSkip = []string{"/ping", "/sign-in", "/csrf", "/users/create-first-user", "/users/db-has-users", "/.well-known/jwks.json"}
+var authRoutesToSkip = []string{"/ping", "/healthz", "/readyz", "/sign-in", "/csrf", "/users/create-first-user", "/users/db-has-users", "/.well-known/jwks.json"}
 
-// Auth is a middleware function that checks for a valid session.
-func Auth() echo.MiddlewareFunc {
+// metricsRoute is authorized by the metrics token, when set, rather than a
+// session, so Prometheus can scrape it.
+const metricsRoute = "/metrics"
+
+// Auth is a middleware function that checks for a valid session. When
+// metricsToken is set, /metrics is checked for it as a bearer token instead.
+func Auth(metricsToken string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// --- Check for Skipped Routes ---
@@ -22,16 +29,33 @@ func Auth() echo.MiddlewareFunc {
 				}
 			}
 
+			// --- Check for Metrics Token ---
+			if metricsToken != "" && c.Path() == metricsRoute {
+				if !hasBearerToken(c.Request(), metricsToken) {
+					c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+					return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: Invalid metrics token")
+				}
+				return next(c)
+			}
+
 			// --- Check for Session ---
 			userEmail, err := auth.GetUserEmailFromSession(c) // Use the GetUserEmailFromSession function
 			if err != nil {
 				// No valid session.  Return an unauthorized error.
+				auth.RecordSessionFailure()
 				return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
 			}
 
 			// --- Session is Valid: Set User ID in Context ---
 			c.Set("userEmail", userEmail) // Set the user ID (email in this case) in the context
+			auth.RecordSessionValidation(c, userEmail)
 			return next(c)
 		}
 	}
 }
+
+// hasBearerToken reports whether the request is authorized with the token.
+func hasBearerToken(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get(echo.HeaderAuthorization), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
@@ -0,0 +1,52 @@
+package model_cache
+
+import (
+	"context"
+	"encoding/json"
+	"junjo-server/api/llm"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"log"
+)
+
+// Init loads the model lists stored before a restart into the LLM model cache
+// and stores the lists fetched afterwards. Lists that cannot be decoded are
+// skipped and fetched again.
+func Init(ctx context.Context) error {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListModelCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := map[string]llm.CachedModels{}
+	for _, cached := range stored {
+		var models []llm.ModelInfo
+		if err := json.Unmarshal([]byte(cached.Models), &models); err != nil {
+			log.Printf("Skipping cached %s models: %v", cached.Provider, err)
+			continue
+		}
+		entries[cached.Provider] = llm.CachedModels{Models: models, FetchedAt: cached.FetchedAt}
+	}
+	llm.HydrateModelCache(entries, persist)
+	return nil
+}
+
+// persist stores a provider's model list. Failures are logged; the list stays
+// cached in memory.
+func persist(ctx context.Context, provider string, cached llm.CachedModels) {
+	encoded, err := json.Marshal(cached.Models)
+	if err != nil {
+		log.Printf("Failed to encode %s models: %v", provider, err)
+		return
+	}
+	queries := db_gen.New(db.DB)
+	err = queries.UpsertModelCache(ctx, db_gen.UpsertModelCacheParams{
+		Provider:  provider,
+		Models:    string(encoded),
+		FetchedAt: cached.FetchedAt,
+	})
+	if err != nil {
+		log.Printf("Failed to store %s models: %v", provider, err)
+	}
+}
@@ -0,0 +1,14 @@
+package model_policies
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	policyGroup := e.Group("/llm/model-policies")
+
+	policyGroup.GET("", HandleListPolicies)
+	policyGroup.GET("/:provider", HandleGetPolicy)
+	policyGroup.PUT("/:provider", HandleSetPolicy)
+	policyGroup.DELETE("/:provider", HandleDeletePolicy)
+}
@@ -0,0 +1,111 @@
+package model_policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"junjo-server/api/llm"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+)
+
+// Init loads the model policies into the LLM service.
+func Init(ctx context.Context) error {
+	return Reload(ctx)
+}
+
+// Reload applies the stored policies to LLM requests made afterwards.
+func Reload(ctx context.Context) error {
+	policies, err := ListPolicies(ctx)
+	if err != nil {
+		return err
+	}
+	byProvider := map[string]llm.ModelPolicy{}
+	for _, policy := range policies {
+		byProvider[policy.Provider] = llm.ModelPolicy{Allowed: policy.AllowedModels, Denied: policy.DeniedModels}
+	}
+	llm.SetModelPolicies(byProvider)
+	return nil
+}
+
+// SetPolicy creates or replaces the model policy of a provider.
+func SetPolicy(ctx context.Context, provider string, allowed []string, denied []string, userEmail string) (Policy, error) {
+	encodedAllowed, err := json.Marshal(allowed)
+	if err != nil {
+		return Policy{}, err
+	}
+	encodedDenied, err := json.Marshal(denied)
+	if err != nil {
+		return Policy{}, err
+	}
+	queries := db_gen.New(db.DB)
+	policy, err := queries.UpsertModelPolicy(ctx, db_gen.UpsertModelPolicyParams{
+		Provider:      provider,
+		AllowedModels: string(encodedAllowed),
+		DeniedModels:  string(encodedDenied),
+		UpdatedBy:     userEmail,
+	})
+	if err != nil {
+		return Policy{}, err
+	}
+	return decode(policy)
+}
+
+// ListPolicies retrieves all policies.
+func ListPolicies(ctx context.Context) ([]Policy, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListModelPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policies := []Policy{}
+	for _, policy := range stored {
+		decoded, err := decode(policy)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, decoded)
+	}
+	return policies, nil
+}
+
+// GetPolicy retrieves the policy of a provider.
+func GetPolicy(ctx context.Context, provider string) (Policy, error) {
+	queries := db_gen.New(db.DB)
+	policy, err := queries.GetModelPolicy(ctx, provider)
+	if err != nil {
+		return Policy{}, err
+	}
+	return decode(policy)
+}
+
+// DeletePolicy removes the policy of a provider, which then allows every
+// model.
+func DeletePolicy(ctx context.Context, provider string) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteModelPolicy(ctx, provider)
+}
+
+func decode(policy db_gen.ModelPolicy) (Policy, error) {
+	decoded := Policy{
+		Provider:      policy.Provider,
+		AllowedModels: []string{},
+		DeniedModels:  []string{},
+		UpdatedBy:     policy.UpdatedBy,
+		CreatedAt:     policy.CreatedAt,
+		UpdatedAt:     policy.UpdatedAt,
+	}
+	if err := json.Unmarshal([]byte(policy.AllowedModels), &decoded.AllowedModels); err != nil {
+		return decoded, fmt.Errorf("invalid allowed models of %s model policy: %w", policy.Provider, err)
+	}
+	if err := json.Unmarshal([]byte(policy.DeniedModels), &decoded.DeniedModels); err != nil {
+		return decoded, fmt.Errorf("invalid denied models of %s model policy: %w", policy.Provider, err)
+	}
+	if decoded.AllowedModels == nil {
+		decoded.AllowedModels = []string{}
+	}
+	if decoded.DeniedModels == nil {
+		decoded.DeniedModels = []string{}
+	}
+	return decoded, nil
+}
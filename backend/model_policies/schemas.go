@@ -0,0 +1,22 @@
+package model_policies
+
+import "time"
+
+// SetPolicyRequest sets the models requests to a provider may use. Names
+// ending in * match any model with that prefix. Denied models are rejected
+// even when allowed, and an empty allow list allows every model that is not
+// denied.
+type SetPolicyRequest struct {
+	AllowedModels []string `json:"allowed_models" validate:"dive,required"`
+	DeniedModels  []string `json:"denied_models" validate:"dive,required"`
+}
+
+// Policy is the model policy of a provider.
+type Policy struct {
+	Provider      string    `json:"provider"`
+	AllowedModels []string  `json:"allowed_models"`
+	DeniedModels  []string  `json:"denied_models"`
+	UpdatedBy     string    `json:"updated_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
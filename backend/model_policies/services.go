@@ -0,0 +1,114 @@
+package model_policies
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"junjo-server/api/llm"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListPolicies lists the model policies of every provider that has one.
+func HandleListPolicies(c echo.Context) error {
+	policies, err := ListPolicies(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list model policies:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve model policies")
+	}
+
+	return c.JSON(http.StatusOK, policies)
+}
+
+// HandleGetPolicy retrieves the model policy of a provider.
+func HandleGetPolicy(c echo.Context) error {
+	provider, err := parseProvider(c)
+	if err != nil {
+		return err
+	}
+
+	policy, err := GetPolicy(c.Request().Context(), provider)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "No model policy set for this provider")
+		}
+		c.Logger().Error("Failed to get model policy:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve model policy")
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// HandleSetPolicy sets the models requests to a provider may use, enforced
+// without a restart.
+func HandleSetPolicy(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	provider, err := parseProvider(c)
+	if err != nil {
+		return err
+	}
+
+	var req SetPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	policy, err := SetPolicy(c.Request().Context(), provider, req.AllowedModels, req.DeniedModels, userEmail)
+	if err != nil {
+		c.Logger().Error("Failed to store model policy:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save model policy")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload model policies:", err)
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// HandleDeletePolicy removes the model policy of a provider, which then
+// allows every model.
+func HandleDeletePolicy(c echo.Context) error {
+	provider, err := parseProvider(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := GetPolicy(c.Request().Context(), provider); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "No model policy set for this provider")
+		}
+		c.Logger().Error("Failed to look up model policy:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete model policy")
+	}
+
+	if err := DeletePolicy(c.Request().Context(), provider); err != nil {
+		c.Logger().Error("Failed to delete model policy:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete model policy")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload model policies:", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// parseProvider reads the provider path parameter.
+func parseProvider(c echo.Context) (string, error) {
+	provider := strings.ToLower(c.Param("provider"))
+	if !slices.Contains(llm.Providers, provider) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Unknown provider: must be one of "+strings.Join(llm.Providers, ", "))
+	}
+	return provider, nil
+}
@@ -0,0 +1,16 @@
+package moderation
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	ruleGroup := e.Group("/moderation-rules")
+
+	ruleGroup.GET("", HandleListRules)
+	ruleGroup.POST("", HandleCreateRule)
+	ruleGroup.POST("/test", HandleTestRules)
+	ruleGroup.GET("/:id", HandleGetRule)
+	ruleGroup.PUT("/:id", HandleUpdateRule)
+	ruleGroup.DELETE("/:id", HandleDeleteRule)
+}
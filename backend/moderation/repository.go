@@ -0,0 +1,125 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"junjo-server/api/llm"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"regexp"
+)
+
+// ErrDuplicateName is returned when another rule has the same name.
+var ErrDuplicateName = errors.New("a moderation rule with this name already exists")
+
+// Init loads the enabled moderation rules into the LLM service.
+func Init(ctx context.Context) error {
+	return Reload(ctx)
+}
+
+// Reload compiles the enabled stored rules and applies them to LLM requests
+// made afterwards.
+func Reload(ctx context.Context) error {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListModerationRules(ctx)
+	if err != nil {
+		return err
+	}
+	rules := []llm.ModerationRule{}
+	for _, rule := range stored {
+		if !rule.Enabled {
+			continue
+		}
+		pattern, err := compile(rule.Kind, rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern for moderation rule %q: %w", rule.Name, err)
+		}
+		rules = append(rules, llm.ModerationRule{
+			Name:    rule.Name,
+			Stage:   rule.Stage,
+			Action:  rule.Action,
+			Pattern: pattern,
+		})
+	}
+	llm.SetModerationRules(rules)
+	return nil
+}
+
+// compile compiles the pattern of a rule. Keywords match case-insensitively.
+func compile(kind string, pattern string) (*regexp.Regexp, error) {
+	if kind == "keyword" {
+		return regexp.Compile("(?i)" + regexp.QuoteMeta(pattern))
+	}
+	return regexp.Compile(pattern)
+}
+
+// ListRules retrieves all stored rules.
+func ListRules(ctx context.Context) ([]Rule, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListModerationRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := []Rule{}
+	for _, rule := range stored {
+		rules = append(rules, decode(rule))
+	}
+	return rules, nil
+}
+
+// GetRule retrieves a single rule by id.
+func GetRule(ctx context.Context, id int64) (Rule, error) {
+	queries := db_gen.New(db.DB)
+	rule, err := queries.GetModerationRule(ctx, id)
+	if err != nil {
+		return Rule{}, err
+	}
+	return decode(rule), nil
+}
+
+// CreateRule stores a new rule.
+func CreateRule(ctx context.Context, arg db_gen.CreateModerationRuleParams) (Rule, error) {
+	queries := db_gen.New(db.DB)
+	rule, err := queries.CreateModerationRule(ctx, arg)
+	if db.IsUniqueViolation(err) {
+		return Rule{}, ErrDuplicateName
+	}
+	if err != nil {
+		return Rule{}, err
+	}
+	return decode(rule), nil
+}
+
+// UpdateRule replaces a stored rule.
+func UpdateRule(ctx context.Context, arg db_gen.UpdateModerationRuleParams) (Rule, error) {
+	queries := db_gen.New(db.DB)
+	rule, err := queries.UpdateModerationRule(ctx, arg)
+	if db.IsUniqueViolation(err) {
+		return Rule{}, ErrDuplicateName
+	}
+	if err != nil {
+		return Rule{}, err
+	}
+	return decode(rule), nil
+}
+
+// DeleteRule removes a rule by id.
+func DeleteRule(ctx context.Context, id int64) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteModerationRule(ctx, id)
+}
+
+func decode(rule db_gen.ModerationRule) Rule {
+	return Rule{
+		ID:        rule.ID,
+		Name:      rule.Name,
+		Kind:      rule.Kind,
+		Pattern:   rule.Pattern,
+		Stage:     rule.Stage,
+		Action:    rule.Action,
+		Enabled:   rule.Enabled,
+		CreatedAt: rule.CreatedAt,
+		UpdatedAt: rule.UpdatedAt,
+	}
+}
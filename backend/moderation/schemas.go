@@ -0,0 +1,48 @@
+package moderation
+
+import (
+	"time"
+
+	"junjo-server/api/llm"
+)
+
+// RuleRequest creates or replaces a moderation rule. A keyword Pattern
+// matches case-insensitively anywhere in the text; a regex Pattern is an RE2
+// regular expression. Stage defaults to both and Action to flag: block
+// rejects the request, flag marks the response with the violation, and log
+// only logs it. Enabled defaults to true.
+type RuleRequest struct {
+	Name    string `json:"name" validate:"required"`
+	Kind    string `json:"kind" validate:"required,oneof=keyword regex"`
+	Pattern string `json:"pattern" validate:"required"`
+	Stage   string `json:"stage" validate:"omitempty,oneof=prompt response both"`
+	Action  string `json:"action" validate:"omitempty,oneof=block flag log"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// Rule is a moderation rule.
+type Rule struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Stage     string    `json:"stage"`
+	Action    string    `json:"action"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TestRequest is a text to check with the enabled rules of a stage, which
+// defaults to prompt.
+type TestRequest struct {
+	Stage string `json:"stage" validate:"omitempty,oneof=prompt response"`
+	Text  string `json:"text"`
+}
+
+// TestResponse lists the rules the text matches and whether it would be
+// blocked.
+type TestResponse struct {
+	Blocked    bool                      `json:"blocked"`
+	Violations []llm.ModerationViolation `json:"violations"`
+}
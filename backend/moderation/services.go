@@ -0,0 +1,185 @@
+package moderation
+
+import (
+	"database/sql"
+	"errors"
+	"junjo-server/api/llm"
+	"junjo-server/db_gen"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListRules lists all moderation rules.
+func HandleListRules(c echo.Context) error {
+	rules, err := ListRules(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list moderation rules:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve moderation rules")
+	}
+
+	return c.JSON(http.StatusOK, rules)
+}
+
+// HandleGetRule returns a moderation rule by id.
+func HandleGetRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	rule, err := GetRule(c.Request().Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return echo.NewHTTPError(http.StatusNotFound, "Moderation rule not found")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to get moderation rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve moderation rule")
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// bindRule reads and validates a rule request.
+func bindRule(c echo.Context) (RuleRequest, error) {
+	var req RuleRequest
+	if err := c.Bind(&req); err != nil {
+		return req, echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return req, echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if _, err := compile(req.Kind, req.Pattern); err != nil {
+		return req, echo.NewHTTPError(http.StatusBadRequest, "Invalid pattern: "+err.Error())
+	}
+	if req.Stage == "" {
+		req.Stage = llm.ModerationBoth
+	}
+	if req.Action == "" {
+		req.Action = llm.ModerationFlag
+	}
+	if req.Enabled == nil {
+		enabled := true
+		req.Enabled = &enabled
+	}
+	return req, nil
+}
+
+// HandleCreateRule adds a moderation rule, applied to LLM requests made
+// afterwards.
+func HandleCreateRule(c echo.Context) error {
+	req, err := bindRule(c)
+	if err != nil {
+		return err
+	}
+
+	rule, err := CreateRule(c.Request().Context(), db_gen.CreateModerationRuleParams{
+		Name:    req.Name,
+		Kind:    req.Kind,
+		Pattern: req.Pattern,
+		Stage:   req.Stage,
+		Action:  req.Action,
+		Enabled: *req.Enabled,
+	})
+	if errors.Is(err, ErrDuplicateName) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to create moderation rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save moderation rule")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload moderation rules:", err)
+	}
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// HandleUpdateRule replaces a moderation rule by id.
+func HandleUpdateRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	req, err := bindRule(c)
+	if err != nil {
+		return err
+	}
+
+	rule, err := UpdateRule(c.Request().Context(), db_gen.UpdateModerationRuleParams{
+		Name:    req.Name,
+		Kind:    req.Kind,
+		Pattern: req.Pattern,
+		Stage:   req.Stage,
+		Action:  req.Action,
+		Enabled: *req.Enabled,
+		ID:      id,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return echo.NewHTTPError(http.StatusNotFound, "Moderation rule not found")
+	}
+	if errors.Is(err, ErrDuplicateName) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to update moderation rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save moderation rule")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload moderation rules:", err)
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// HandleDeleteRule deletes a moderation rule by id.
+func HandleDeleteRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	if _, err := GetRule(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Moderation rule not found")
+		}
+		c.Logger().Error("Failed to look up moderation rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete moderation rule")
+	}
+
+	if err := DeleteRule(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete moderation rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete moderation rule")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload moderation rules:", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleTestRules checks a text with the enabled rules, to try out rules
+// before relying on them.
+func HandleTestRules(c echo.Context) error {
+	var req TestRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if req.Stage == "" {
+		req.Stage = llm.ModerationPrompt
+	}
+
+	violations, blocked := llm.Moderate(req.Stage, req.Text)
+	if violations == nil {
+		violations = []llm.ModerationViolation{}
+	}
+	return c.JSON(http.StatusOK, TestResponse{Blocked: blocked, Violations: violations})
+}
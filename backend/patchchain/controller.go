@@ -0,0 +1,10 @@
+package patchchain
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	e.GET("/otel/workflow/:spanId/patch-chain", HandleCheckWorkflow)
+	e.GET("/otel/service/:serviceName/patch-chain-issues", HandleListIssues)
+}
@@ -0,0 +1,141 @@
+// Package patchchain verifies that the state patches recorded for each
+// workflow replay to its recorded final state, flagging executions whose
+// telemetry is incomplete or out of order.
+package patchchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/statepatch"
+)
+
+// Config controls the background verifier.
+type Config struct {
+	// Interval is how often unverified workflow spans are checked.
+	Interval time.Duration
+	// Lookback bounds how far back unverified workflow spans are looked for.
+	Lookback time.Duration
+	// BatchSize is the maximum number of workflow spans checked per run.
+	BatchSize int
+}
+
+// Run periodically verifies the patch chains of recently completed workflows
+// until ctx is cancelled.
+func Run(ctx context.Context, cfg Config) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := CheckPending(ctx, cfg, time.Now().UTC()); err != nil {
+			slog.Error("patch chain check failed", "error", err)
+		}
+	}
+}
+
+// queryUncheckedWorkflows selects workflow and subflow spans that ended within
+// the lookback window and have not been verified. Args: since, limit.
+const queryUncheckedWorkflows = `
+	SELECT s.span_id
+	FROM spans s
+	WHERE s.junjo_span_type IN ('workflow', 'subflow')
+		AND s.end_time >= $1
+		AND NOT EXISTS (SELECT 1 FROM patch_chain_checks c WHERE c.trace_id = s.trace_id AND c.span_id = s.span_id)
+	ORDER BY s.end_time
+	LIMIT $2;`
+
+// queryRecordCheck records (or replaces) the verification of a workflow span.
+const queryRecordCheck = `
+	INSERT OR REPLACE INTO patch_chain_checks (trace_id, span_id, service_name, workflow_name, status, patch_count, mismatched_paths, detail, checked_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING trace_id, span_id, service_name, workflow_name, status, patch_count, mismatched_paths::VARCHAR, detail, checked_at;`
+
+// CheckPending verifies up to cfg.BatchSize unverified workflow spans.
+func CheckPending(ctx context.Context, cfg Config, now time.Time) error {
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(ctx, queryUncheckedWorkflows, now.Add(-cfg.Lookback), cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to query unverified workflows: %w", err)
+	}
+	var spanIDs []string
+	for rows.Next() {
+		var spanID string
+		if err := rows.Scan(&spanID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan workflow span: %w", err)
+		}
+		spanIDs = append(spanIDs, spanID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read unverified workflows: %w", err)
+	}
+
+	for _, spanID := range spanIDs {
+		check, err := CheckWorkflow(ctx, spanID, now)
+		if err != nil {
+			return err
+		}
+		if check.Status != statepatch.StatusOK {
+			slog.Warn("workflow state patch chain is inconsistent",
+				"status", check.Status,
+				"service", check.ServiceName,
+				"trace_id", check.TraceID,
+				"span_id", check.SpanID,
+				"mismatched_paths", string(check.MismatchedPaths),
+			)
+		}
+	}
+	return nil
+}
+
+// CheckWorkflow verifies the patch chain of a workflow or subflow span and
+// records the result.
+func CheckWorkflow(ctx context.Context, spanID string, now time.Time) (Check, error) {
+	wf, err := statepatch.LoadWorkflow(ctx, spanID)
+	if err != nil {
+		return Check{}, err
+	}
+	patches, err := statepatch.LoadPatches(ctx, wf)
+	if err != nil {
+		return Check{}, err
+	}
+	v := statepatch.Verify(wf, patches)
+
+	mismatchedPaths, err := json.Marshal(v.MismatchedPaths)
+	if err != nil {
+		return Check{}, fmt.Errorf("failed to marshal mismatched paths: %w", err)
+	}
+	var workflowName, detail *string
+	if wf.Name != "" {
+		workflowName = &wf.Name
+	}
+	if v.Detail != "" {
+		detail = &v.Detail
+	}
+
+	var check Check
+	var paths string
+	err = db_duckdb.DB.QueryRowContext(ctx, queryRecordCheck,
+		wf.TraceID, wf.SpanID, wf.ServiceName, workflowName, v.Status, v.PatchCount, string(mismatchedPaths), detail, now,
+	).Scan(&check.TraceID, &check.SpanID, &check.ServiceName, &check.WorkflowName, &check.Status, &check.PatchCount, &paths, &check.Detail, &check.CheckedAt)
+	if err != nil {
+		return Check{}, fmt.Errorf("failed to record patch chain check: %w", err)
+	}
+	check.MismatchedPaths = json.RawMessage(paths)
+	return check, nil
+}
@@ -0,0 +1,20 @@
+package patchchain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Check is the recorded patch chain verification of a workflow or subflow
+// span.
+type Check struct {
+	TraceID         string          `json:"trace_id"`
+	SpanID          string          `json:"span_id"`
+	ServiceName     string          `json:"service_name"`
+	WorkflowName    *string         `json:"workflow_name"`
+	Status          string          `json:"status"`
+	PatchCount      int             `json:"patch_count"`
+	MismatchedPaths json.RawMessage `json:"mismatched_paths"`
+	Detail          *string         `json:"detail"`
+	CheckedAt       time.Time       `json:"checked_at"`
+}
@@ -0,0 +1,77 @@
+package patchchain
+
+import (
+	"errors"
+	"fmt"
+	"junjo-server/apierror"
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/statepatch"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleCheckWorkflow verifies the patch chain of a workflow or subflow span
+// now, and records the result.
+func HandleCheckWorkflow(c echo.Context) error {
+	spanID := c.Param("spanId")
+	if spanID == "" {
+		return apierror.New(http.StatusBadRequest, "spanId parameter is required")
+	}
+
+	check, err := CheckWorkflow(c.Request().Context(), spanID, time.Now().UTC())
+	if errors.Is(err, statepatch.ErrWorkflowNotFound) {
+		return apierror.New(http.StatusNotFound, "workflow span not found")
+	}
+	if err != nil {
+		c.Logger().Printf("Error checking patch chain: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to check patch chain")
+	}
+
+	return c.JSON(http.StatusOK, check)
+}
+
+// queryListIssues lists the inconsistent patch chains of a service, most
+// recently checked first.
+const queryListIssues = `
+	SELECT trace_id, span_id, service_name, workflow_name, status, patch_count, mismatched_paths::VARCHAR, detail, checked_at
+	FROM patch_chain_checks
+	WHERE service_name = ? AND status != 'ok'
+	ORDER BY checked_at DESC
+	LIMIT 500;`
+
+// HandleListIssues lists the workflow executions of a service whose patch
+// chain did not replay to the recorded final state.
+func HandleListIssues(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), queryListIssues, serviceName)
+	if err != nil {
+		c.Logger().Printf("Error listing patch chain issues: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to list patch chain issues")
+	}
+	defer rows.Close()
+
+	checks := []Check{}
+	for rows.Next() {
+		var check Check
+		var paths string
+		if err := rows.Scan(&check.TraceID, &check.SpanID, &check.ServiceName, &check.WorkflowName, &check.Status, &check.PatchCount, &paths, &check.Detail, &check.CheckedAt); err != nil {
+			c.Logger().Printf("Error scanning row: %v", err)
+			return apierror.New(http.StatusInternalServerError, "failed to list patch chain issues")
+		}
+		check.MismatchedPaths = []byte(paths)
+		checks = append(checks, check)
+	}
+
+	return c.JSON(http.StatusOK, checks)
+}
@@ -0,0 +1,12 @@
+package poller
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo, p *Poller) {
+	pollerGroup := e.Group("/poller")
+
+	pollerGroup.GET("/status", HandleGetStatus(p))
+	pollerGroup.PUT("/settings", HandleUpdateSettings(p))
+}
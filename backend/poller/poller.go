@@ -0,0 +1,327 @@
+package poller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"junjo-server/ingestion_client"
+	"junjo-server/quotas"
+	"junjo-server/selftrace"
+	"junjo-server/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Bounds of the poller settings.
+const (
+	minInterval  = 100 * time.Millisecond
+	maxInterval  = 10 * time.Minute
+	maxBatchSize = 10000
+)
+
+// throughputWindow is the period over which the poll throughput is averaged.
+const throughputWindow = time.Minute
+
+// Config controls how often spans are read from the ingestion service, and
+// how many are read at a time.
+type Config struct {
+	Interval  time.Duration
+	BatchSize uint32
+}
+
+// Validate checks that the settings are within bounds.
+func (cfg Config) Validate() error {
+	if cfg.Interval < minInterval || cfg.Interval > maxInterval {
+		return fmt.Errorf("interval must be between %s and %s", minInterval, maxInterval)
+	}
+	if cfg.BatchSize == 0 || cfg.BatchSize > maxBatchSize {
+		return fmt.Errorf("batch size must be between 1 and %d", maxBatchSize)
+	}
+	return nil
+}
+
+// sample is the number of spans read by one poll.
+type sample struct {
+	at    time.Time
+	spans int
+}
+
+// spanSink receives polled spans: the span writer, or the tail sampler in
+// front of it.
+type spanSink interface {
+	Add(ctx context.Context, serviceName string, spans []*tracepb.Span, lastKey []byte) error
+	Advance(lastKey []byte)
+	FlushIfDue(ctx context.Context) error
+	Flush(ctx context.Context) error
+	MaxLatency() time.Duration
+}
+
+// Poller reads spans from the ingestion service's WAL and hands them to the
+// span writer. Its settings can be changed while it runs.
+type Poller struct {
+	client *ingestion_client.Client
+	writer spanSink
+
+	mu        sync.Mutex
+	cfg       Config
+	reload    chan struct{}
+	lastKey   []byte
+	lastPoll  time.Time
+	polls     int64
+	spansRead int64
+	samples   []sample
+}
+
+// New creates a poller that reads from client with the given settings. When
+// tail sampling is enabled, spans pass through a tail sampler before the span
+// writer.
+func New(cfg Config, client *ingestion_client.Client, writerConfig telemetry.WriterConfig, tailConfig telemetry.TailSamplingConfig) *Poller {
+	queries := db_gen.New(db.DB)
+	writer := telemetry.NewSpanWriter(writerConfig, func(ctx context.Context, lastKey []byte) error {
+		return queries.UpsertPollerState(ctx, lastKey)
+	})
+
+	var sink spanSink = writer
+	if tailConfig.Enabled {
+		sink = telemetry.NewTailSampler(tailConfig, writer)
+	}
+	return &Poller{
+		client: client,
+		writer: sink,
+		cfg:    cfg,
+		reload: make(chan struct{}, 1),
+	}
+}
+
+// Config returns the current settings.
+func (p *Poller) Config() Config {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.cfg
+}
+
+// SetConfig validates and applies new settings. A new interval takes effect
+// immediately, a new batch size on the next poll.
+func (p *Poller) SetConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.cfg = cfg
+	p.mu.Unlock()
+
+	select {
+	case p.reload <- struct{}{}:
+	default:
+	}
+	log.Printf("Poller settings updated: interval %s, batch size %d", cfg.Interval, cfg.BatchSize)
+	return nil
+}
+
+// Status returns the current settings and the observed throughput.
+func (p *Poller) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := Status{
+		IntervalMs: p.cfg.Interval.Milliseconds(),
+		BatchSize:  p.cfg.BatchSize,
+		Polls:      p.polls,
+		SpansRead:  p.spansRead,
+		LastKey:    fmt.Sprintf("%x", p.lastKey),
+	}
+	if !p.lastPoll.IsZero() {
+		lastPoll := p.lastPoll
+		status.LastPollAt = &lastPoll
+	}
+
+	var spans int
+	cutoff := time.Now().Add(-throughputWindow)
+	for _, s := range p.samples {
+		if s.at.After(cutoff) {
+			spans += s.spans
+		}
+	}
+	status.SpansPerSecond = float64(spans) / throughputWindow.Seconds()
+	return status
+}
+
+// Run polls for spans until ctx is cancelled, resuming from the last key
+// saved in the poller state.
+func (p *Poller) Run(ctx context.Context) {
+	queries := db_gen.New(db.DB)
+
+	// At startup, try to load the last processed key from the database.
+	retrievedKey, err := queries.GetPollerState(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		log.Fatalf("Failed to load poller state: %v", err)
+	} else if err == sql.ErrNoRows {
+		log.Println("No previous poller state found. Starting from the beginning.")
+	} else {
+		p.mu.Lock()
+		p.lastKey = retrievedKey
+		p.mu.Unlock()
+		log.Printf("Resuming poller from last key: %x", p.lastKey)
+	}
+
+	ticker := time.NewTicker(p.Config().Interval)
+	defer ticker.Stop()
+	flushTicker := time.NewTicker(p.writer.MaxLatency() / 2)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := p.writer.Flush(context.Background()); err != nil {
+				log.Printf("Error flushing spans: %v", err)
+			}
+			return
+		case <-p.reload:
+			ticker.Reset(p.Config().Interval)
+			continue
+		case <-flushTicker.C:
+			if err := p.writer.FlushIfDue(ctx); err != nil {
+				log.Printf("Error flushing spans: %v", err)
+				p.rewindIfFailed(ctx, err)
+			}
+			continue
+		case <-ticker.C:
+		}
+
+		p.poll(ctx)
+
+		if err := p.writer.FlushIfDue(ctx); err != nil {
+			log.Printf("Error flushing spans: %v", err)
+			p.rewindIfFailed(ctx, err)
+		}
+	}
+}
+
+// poll reads one batch of spans and queues them for writing. Only polls that
+// read spans of other services than the backend itself are traced.
+func (p *Poller) poll(ctx context.Context) {
+	start := time.Now()
+	log.Println("Polling for new spans...")
+	spans, err := p.client.ReadSpans(ctx, p.lastKey, p.Config().BatchSize)
+	if err != nil {
+		log.Printf("Error reading spans: %v", err)
+		return
+	}
+	p.record(len(spans))
+
+	if len(spans) == 0 {
+		log.Println("No new spans found.")
+		return
+	}
+
+	lastKey := spans[len(spans)-1].KeyUlid
+	p.mu.Lock()
+	p.lastKey = lastKey
+	p.mu.Unlock()
+	log.Printf("Received %d spans. Last key: %x", len(spans), lastKey)
+
+	var processedSpans []*tracepb.Span
+	for _, receivedSpan := range spans {
+		var span tracepb.Span
+		if err := proto.Unmarshal(receivedSpan.SpanBytes, &span); err != nil {
+			log.Printf("Error unmarshaling span: %v", err)
+			continue // Skip to the next span
+		}
+		processedSpans = append(processedSpans, &span)
+	}
+	if len(processedSpans) == 0 {
+		return
+	}
+
+	// Extract the service name from the first span's resource
+	// All spans in a batch should have the same service name
+	serviceName := serviceNameOf(spans[0].ResourceBytes)
+
+	if selftrace.IsSelf(serviceName) {
+		ctx = selftrace.Suppress(ctx)
+	}
+	ctx, span := selftrace.Start(ctx, "poller.poll", trace.WithTimestamp(start), trace.WithAttributes(
+		attribute.String("junjo.service_name", serviceName),
+		attribute.Int("junjo.spans", len(processedSpans)),
+	))
+	defer span.End()
+
+	// Enforce the per-service span quota. Dropped batches still advance
+	// the poller state so they are not re-read from the WAL.
+	if usage := quotas.Spans.Add(serviceName, int64(len(processedSpans))); usage.Status == quotas.StatusHard {
+		log.Printf("Span quota exceeded for service %s (%d/%d). Dropping %d spans.", serviceName, usage.Used, usage.HardLimit, len(processedSpans))
+		p.writer.Advance(lastKey)
+	} else if err := p.writer.Add(ctx, serviceName, processedSpans, lastKey); err != nil {
+		// The poller state is only saved once the spans are written.
+		log.Printf("Error processing spans batch: %v", err)
+		selftrace.RecordError(span, err)
+		p.rewindIfFailed(ctx, err)
+	}
+}
+
+// rewindIfFailed resumes polling from the last committed key after a flush
+// that wrote nothing, so the discarded spans are read again.
+func (p *Poller) rewindIfFailed(ctx context.Context, err error) {
+	if !errors.Is(err, telemetry.ErrWriteFailed) {
+		return
+	}
+	committedKey, err := db_gen.New(db.DB).GetPollerState(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error loading poller state to rewind: %v", err)
+		return
+	}
+	p.mu.Lock()
+	p.lastKey = committedKey
+	p.mu.Unlock()
+	log.Printf("Rewinding poller to last committed key: %x", committedKey)
+}
+
+// record updates the poll counters and throughput samples.
+func (p *Poller) record(spans int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.lastPoll = now
+	p.polls++
+	p.spansRead += int64(spans)
+
+	cutoff := now.Add(-throughputWindow)
+	for len(p.samples) > 0 && !p.samples[0].at.After(cutoff) {
+		p.samples = p.samples[1:]
+	}
+	p.samples = append(p.samples, sample{at: now, spans: spans})
+}
+
+// serviceNameOf returns the service.name attribute of a serialized resource.
+func serviceNameOf(resourceBytes []byte) string {
+	var resource resourcepb.Resource
+	if err := proto.Unmarshal(resourceBytes, &resource); err != nil {
+		log.Printf("Error unmarshaling resource: %v", err)
+		return "NO_SERVICE_NAME"
+	}
+
+	// Extract service name from resource attributes
+	for _, attr := range resource.Attributes {
+		if attr.Key == "service.name" {
+			if stringValue, ok := attr.Value.Value.(*commonpb.AnyValue_StringValue); ok && stringValue.StringValue != "" {
+				return stringValue.StringValue
+			}
+		}
+	}
+	return "NO_SERVICE_NAME"
+}
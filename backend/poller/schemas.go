@@ -0,0 +1,21 @@
+package poller
+
+import "time"
+
+// Status reports the poller settings and throughput. SpansPerSecond is
+// averaged over the last minute.
+type Status struct {
+	IntervalMs     int64      `json:"interval_ms"`
+	BatchSize      uint32     `json:"batch_size"`
+	Polls          int64      `json:"polls"`
+	SpansRead      int64      `json:"spans_read"`
+	SpansPerSecond float64    `json:"spans_per_second"`
+	LastPollAt     *time.Time `json:"last_poll_at"`
+	LastKey        string     `json:"last_key"`
+}
+
+// UpdateSettingsRequest changes the poller settings.
+type UpdateSettingsRequest struct {
+	IntervalMs int64  `json:"interval_ms" validate:"required,gt=0"`
+	BatchSize  uint32 `json:"batch_size" validate:"required,gt=0"`
+}
@@ -0,0 +1,39 @@
+package poller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleGetStatus returns the poller settings and throughput.
+func HandleGetStatus(p *Poller) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, p.Status())
+	}
+}
+
+// HandleUpdateSettings changes the poller interval and batch size without a
+// restart.
+func HandleUpdateSettings(p *Poller) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req UpdateSettingsRequest
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+		}
+		if err := c.Validate(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+		}
+
+		cfg := Config{
+			Interval:  time.Duration(req.IntervalMs) * time.Millisecond,
+			BatchSize: req.BatchSize,
+		}
+		if err := p.SetConfig(cfg); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid settings: "+err.Error())
+		}
+
+		return c.JSON(http.StatusOK, p.Status())
+	}
+}
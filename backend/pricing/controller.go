@@ -0,0 +1,15 @@
+package pricing
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	priceGroup := e.Group("/model-prices")
+
+	priceGroup.GET("", HandleListPrices)
+	priceGroup.POST("", HandleCreatePrice)
+	priceGroup.GET("/:id", HandleGetPrice)
+	priceGroup.PUT("/:id", HandleUpdatePrice)
+	priceGroup.DELETE("/:id", HandleDeletePrice)
+}
@@ -0,0 +1,139 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DateLayout is the format of effective dates.
+const DateLayout = "2006-01-02"
+
+// Price is the list price of a model, in USD per 1M tokens. It applies to calls
+// made on or after EffectiveDate (UTC); an empty EffectiveDate applies to all
+// calls.
+type Price struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+	EffectiveDate    string  `json:"effective_date,omitempty"`
+}
+
+// DefaultPrices seed the pricing table when no pricing file is configured.
+var DefaultPrices = []Price{
+	{Provider: "gemini", Model: "gemini-2.5-pro", InputPerMillion: 1.25, OutputPerMillion: 10},
+	{Provider: "gemini", Model: "gemini-2.5-flash", InputPerMillion: 0.30, OutputPerMillion: 2.50},
+	{Provider: "gemini", Model: "gemini-2.0-flash", InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	{Provider: "gemini", Model: "gemini-2.0-flash-lite", InputPerMillion: 0.075, OutputPerMillion: 0.30},
+	{Provider: "gemini", Model: "gemini-1.5-pro", InputPerMillion: 1.25, OutputPerMillion: 5},
+	{Provider: "gemini", Model: "gemini-1.5-flash", InputPerMillion: 0.075, OutputPerMillion: 0.30},
+	{Provider: "openai", Model: "gpt-4o", InputPerMillion: 2.50, OutputPerMillion: 10},
+	{Provider: "openai", Model: "gpt-4o-mini", InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	{Provider: "openai", Model: "gpt-4.1", InputPerMillion: 2, OutputPerMillion: 8},
+	{Provider: "openai", Model: "gpt-4.1-mini", InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	{Provider: "openai", Model: "gpt-4.1-nano", InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	{Provider: "openai", Model: "o3-mini", InputPerMillion: 1.10, OutputPerMillion: 4.40},
+	{Provider: "anthropic", Model: "claude-3-5-sonnet", InputPerMillion: 3, OutputPerMillion: 15},
+	{Provider: "anthropic", Model: "claude-3-5-haiku", InputPerMillion: 0.80, OutputPerMillion: 4},
+	{Provider: "anthropic", Model: "claude-3-opus", InputPerMillion: 15, OutputPerMillion: 75},
+	{Provider: "anthropic", Model: "claude-sonnet-4", InputPerMillion: 3, OutputPerMillion: 15},
+	{Provider: "anthropic", Model: "claude-opus-4", InputPerMillion: 15, OutputPerMillion: 75},
+}
+
+var (
+	mu     sync.RWMutex
+	prices = DefaultPrices
+)
+
+// Load reads the prices that seed an empty pricing table from a JSON file
+// containing a list of Price. An empty path loads DefaultPrices.
+func Load(path string) ([]Price, error) {
+	if path == "" {
+		return DefaultPrices, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pricing file: %w", err)
+	}
+
+	var loaded []Price
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("parse pricing file: %w", err)
+	}
+	for _, price := range loaded {
+		if price.Model == "" {
+			return nil, fmt.Errorf("pricing entry without a model")
+		}
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return nil, fmt.Errorf("negative price for model %q", price.Model)
+		}
+		if price.EffectiveDate != "" {
+			if _, err := time.Parse(DateLayout, price.EffectiveDate); err != nil {
+				return nil, fmt.Errorf("invalid effective_date %q for model %q", price.EffectiveDate, price.Model)
+			}
+		}
+	}
+	return loaded, nil
+}
+
+// SetPrices replaces the in-memory pricing table used by Lookup and Estimate.
+func SetPrices(table []Price) {
+	mu.Lock()
+	defer mu.Unlock()
+	prices = table
+}
+
+// Lookup returns the price of model in effect at the given time. Model names
+// are matched case-insensitively, ignoring any "provider/" or "models/" prefix,
+// against the longest table entry the name starts with, so that dated or
+// versioned names such as gpt-4o-2024-08-06 use the price of their base model.
+// Of the prices of that entry, the one with the latest effective date not after
+// at applies.
+func Lookup(model string, at time.Time) (Price, bool) {
+	name := normalizeModel(model)
+	if name == "" {
+		return Price{}, false
+	}
+	day := at.UTC().Format(DateLayout)
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var best Price
+	found := false
+	for _, price := range prices {
+		candidate := normalizeModel(price.Model)
+		if !strings.HasPrefix(name, candidate) || price.EffectiveDate > day {
+			continue
+		}
+		bestName := normalizeModel(best.Model)
+		if !found || len(candidate) > len(bestName) || (candidate == bestName && price.EffectiveDate > best.EffectiveDate) {
+			best, found = price, true
+		}
+	}
+	return best, found
+}
+
+// Estimate returns the estimated cost in USD of a call to model made at the
+// given time. It reports false when the model has no known price.
+func Estimate(model string, at time.Time, inputTokens, outputTokens int64) (float64, bool) {
+	price, ok := Lookup(model, at)
+	if !ok {
+		return 0, false
+	}
+	cost := (float64(inputTokens)*price.InputPerMillion + float64(outputTokens)*price.OutputPerMillion) / 1_000_000
+	return cost, true
+}
+
+func normalizeModel(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return model
+}
@@ -0,0 +1,108 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+)
+
+// seedEffectiveDate is stored for seed prices without an effective date, so
+// that they apply to all calls.
+const seedEffectiveDate = "1970-01-01"
+
+// ErrDuplicatePrice is returned when the model already has a price with the
+// same effective date.
+var ErrDuplicatePrice = errors.New("the model already has a price with this effective date")
+
+// Init seeds the pricing table with seed when it is empty, and loads it into
+// memory.
+func Init(ctx context.Context, seed []Price) error {
+	queries := db_gen.New(db.DB)
+	count, err := queries.CountModelPrices(ctx)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		for _, price := range seed {
+			if price.EffectiveDate == "" {
+				price.EffectiveDate = seedEffectiveDate
+			}
+			if _, err := CreatePrice(ctx, price); err != nil {
+				return err
+			}
+		}
+	}
+	return Reload(ctx)
+}
+
+// Reload replaces the in-memory pricing table with the stored prices.
+func Reload(ctx context.Context) error {
+	stored, err := ListPrices(ctx)
+	if err != nil {
+		return err
+	}
+	table := make([]Price, 0, len(stored))
+	for _, price := range stored {
+		table = append(table, Price{
+			Provider:         price.Provider,
+			Model:            price.Model,
+			InputPerMillion:  price.InputPerMillion,
+			OutputPerMillion: price.OutputPerMillion,
+			EffectiveDate:    price.EffectiveDate,
+		})
+	}
+	SetPrices(table)
+	return nil
+}
+
+// ListPrices retrieves all stored prices.
+func ListPrices(ctx context.Context) ([]db_gen.ModelPrice, error) {
+	queries := db_gen.New(db.DB)
+	return queries.ListModelPrices(ctx)
+}
+
+// GetPrice retrieves a single price by id.
+func GetPrice(ctx context.Context, id int64) (db_gen.ModelPrice, error) {
+	queries := db_gen.New(db.DB)
+	return queries.GetModelPrice(ctx, id)
+}
+
+// CreatePrice stores a new price.
+func CreatePrice(ctx context.Context, price Price) (db_gen.ModelPrice, error) {
+	queries := db_gen.New(db.DB)
+	created, err := queries.CreateModelPrice(ctx, db_gen.CreateModelPriceParams{
+		Provider:         price.Provider,
+		Model:            price.Model,
+		InputPerMillion:  price.InputPerMillion,
+		OutputPerMillion: price.OutputPerMillion,
+		EffectiveDate:    price.EffectiveDate,
+	})
+	if db.IsUniqueViolation(err) {
+		return created, ErrDuplicatePrice
+	}
+	return created, err
+}
+
+// UpdatePrice replaces a stored price.
+func UpdatePrice(ctx context.Context, id int64, price Price) (db_gen.ModelPrice, error) {
+	queries := db_gen.New(db.DB)
+	updated, err := queries.UpdateModelPrice(ctx, db_gen.UpdateModelPriceParams{
+		Provider:         price.Provider,
+		Model:            price.Model,
+		InputPerMillion:  price.InputPerMillion,
+		OutputPerMillion: price.OutputPerMillion,
+		EffectiveDate:    price.EffectiveDate,
+		ID:               id,
+	})
+	if db.IsUniqueViolation(err) {
+		return updated, ErrDuplicatePrice
+	}
+	return updated, err
+}
+
+// DeletePrice removes a price by id.
+func DeletePrice(ctx context.Context, id int64) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteModelPrice(ctx, id)
+}
@@ -0,0 +1,21 @@
+package pricing
+
+// PriceRequest creates or replaces a model price. Prices are in USD per 1M
+// tokens, and effective_date is a YYYY-MM-DD date (UTC).
+type PriceRequest struct {
+	Provider         string   `json:"provider" validate:"required"`
+	Model            string   `json:"model" validate:"required"`
+	InputPerMillion  *float64 `json:"input_per_million" validate:"required,gte=0"`
+	OutputPerMillion *float64 `json:"output_per_million" validate:"required,gte=0"`
+	EffectiveDate    string   `json:"effective_date" validate:"required,datetime=2006-01-02"`
+}
+
+func (r PriceRequest) price() Price {
+	return Price{
+		Provider:         r.Provider,
+		Model:            r.Model,
+		InputPerMillion:  *r.InputPerMillion,
+		OutputPerMillion: *r.OutputPerMillion,
+		EffectiveDate:    r.EffectiveDate,
+	}
+}
@@ -0,0 +1,134 @@
+package pricing
+
+import (
+	"database/sql"
+	"errors"
+	"junjo-server/db_gen"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListPrices lists all model prices.
+func HandleListPrices(c echo.Context) error {
+	prices, err := ListPrices(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list model prices:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve model prices")
+	}
+
+	// Return empty list instead of null if no prices exist
+	if prices == nil {
+		prices = []db_gen.ModelPrice{}
+	}
+
+	return c.JSON(http.StatusOK, prices)
+}
+
+// HandleGetPrice returns a model price by id.
+func HandleGetPrice(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid price id")
+	}
+
+	price, err := GetPrice(c.Request().Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return echo.NewHTTPError(http.StatusNotFound, "Model price not found")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to get model price:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve model price")
+	}
+
+	return c.JSON(http.StatusOK, price)
+}
+
+// HandleCreatePrice adds a model price. Spans ingested afterwards are priced
+// with it; the estimated cost of stored spans is not recomputed.
+func HandleCreatePrice(c echo.Context) error {
+	var req PriceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	price, err := CreatePrice(c.Request().Context(), req.price())
+	if errors.Is(err, ErrDuplicatePrice) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to create model price:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save model price")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload model prices:", err)
+	}
+
+	return c.JSON(http.StatusCreated, price)
+}
+
+// HandleUpdatePrice replaces a model price by id.
+func HandleUpdatePrice(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid price id")
+	}
+
+	var req PriceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	price, err := UpdatePrice(c.Request().Context(), id, req.price())
+	if errors.Is(err, sql.ErrNoRows) {
+		return echo.NewHTTPError(http.StatusNotFound, "Model price not found")
+	}
+	if errors.Is(err, ErrDuplicatePrice) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to update model price:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save model price")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload model prices:", err)
+	}
+
+	return c.JSON(http.StatusOK, price)
+}
+
+// HandleDeletePrice deletes a model price by id.
+func HandleDeletePrice(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid price id")
+	}
+
+	if _, err := GetPrice(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Model price not found")
+		}
+		c.Logger().Error("Failed to look up model price:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete model price")
+	}
+
+	if err := DeletePrice(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete model price:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete model price")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload model prices:", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
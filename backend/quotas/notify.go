@@ -0,0 +1,35 @@
+package quotas
+
+import (
+	"context"
+	"log/slog"
+
+	"junjo-server/webhook"
+)
+
+// notify alerts owners that a consumer has crossed its soft limit.
+// The event is always logged, and is additionally POSTed as JSON to the
+// notifier's webhook when set. Delivery is best-effort and never blocks the caller.
+func notify(notifier *webhook.Notifier, usage Usage) {
+	slog.Warn("quota soft limit reached",
+		"quota", usage.Quota,
+		"key", usage.Key,
+		"used", usage.Used,
+		"soft_limit", usage.SoftLimit,
+		"hard_limit", usage.HardLimit,
+		"grace_ends_at", usage.GraceEndsAt,
+	)
+
+	if !notifier.Enabled() {
+		return
+	}
+	go func() {
+		err := notifier.Send(context.Background(), map[string]any{
+			"event": "quota.soft_limit_reached",
+			"usage": usage,
+		})
+		if err != nil {
+			slog.Error("failed to send quota notification", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,268 @@
+package quotas
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"junjo-server/webhook"
+)
+
+// Status describes where a consumer sits relative to its quota limits.
+type Status string
+
+const (
+	StatusOK    Status = "ok"
+	StatusSoft  Status = "soft_limit" // Soft limit reached, owners notified, requests still accepted.
+	StatusGrace Status = "grace"      // Hard limit reached, but the grace period has not yet elapsed.
+	StatusHard  Status = "hard_limit" // Hard limit reached and enforced.
+)
+
+// Limits configures the soft and hard thresholds for a quota.
+// A value of 0 disables the corresponding threshold.
+type Limits struct {
+	Soft  int64         `json:"soft_limit"`
+	Hard  int64         `json:"hard_limit"`
+	Grace time.Duration `json:"-"`
+}
+
+// Enabled reports whether any threshold is configured.
+func (l Limits) Enabled() bool {
+	return l.Soft > 0 || l.Hard > 0
+}
+
+// Usage is a point-in-time snapshot of a single consumer's quota consumption.
+type Usage struct {
+	Quota         string     `json:"quota"`
+	Key           string     `json:"key"`
+	Used          int64      `json:"used"`
+	SoftLimit     int64      `json:"soft_limit"`
+	HardLimit     int64      `json:"hard_limit"`
+	Remaining     int64      `json:"remaining"`
+	Status        Status     `json:"status"`
+	SoftReachedAt *time.Time `json:"soft_reached_at,omitempty"`
+	GraceEndsAt   *time.Time `json:"grace_ends_at,omitempty"`
+	PeriodStart   time.Time  `json:"period_start"`
+	ResetsAt      time.Time  `json:"resets_at"`
+}
+
+// counter tracks consumption for one key within the current period.
+type counter struct {
+	used          int64
+	softReachedAt time.Time
+}
+
+// Tracker counts consumption for a named quota, keyed by consumer
+// (a service name for spans, a user email for LLM requests).
+// Counters reset at the start of each UTC day.
+type Tracker struct {
+	mu          sync.Mutex
+	name        string
+	limits      Limits
+	periodStart time.Time
+	counters    map[string]*counter
+	notifier    *webhook.Notifier
+	now         func() time.Time
+}
+
+// NewTracker creates a new Tracker for the named quota. Consumers crossing
+// the soft limit are reported to notifier.
+func NewTracker(name string, limits Limits, notifier *webhook.Notifier) *Tracker {
+	t := &Tracker{
+		name:     name,
+		limits:   limits,
+		counters: make(map[string]*counter),
+		notifier: notifier,
+		now:      time.Now,
+	}
+	t.periodStart = startOfDay(t.now())
+	return t
+}
+
+// Name returns the quota name.
+func (t *Tracker) Name() string {
+	return t.name
+}
+
+// Limits returns the configured limits.
+func (t *Tracker) Limits() Limits {
+	return t.limits
+}
+
+// Check reports the current usage for key without consuming any quota.
+func (t *Tracker) Check(key string) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollPeriod()
+	return t.usage(key, t.counterFor(key))
+}
+
+// Add records n units of consumption for key, unless the hard limit is
+// enforced, in which case nothing is recorded. The returned Usage reflects
+// the state after the call; callers should reject the work when its Status
+// is StatusHard.
+func (t *Tracker) Add(key string, n int64) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollPeriod()
+	c := t.counterFor(key)
+
+	if t.status(c) == StatusHard {
+		return t.usage(key, c)
+	}
+	return t.add(key, c, n)
+}
+
+// add records n units of consumption for key, notifying owners the first
+// time the soft limit is crossed. Must be called with the lock held.
+func (t *Tracker) add(key string, c *counter, n int64) Usage {
+	c.used += n
+
+	// Start the grace period the first time the soft limit (or the hard limit,
+	// when no soft limit is configured) is crossed within this period.
+	threshold := t.limits.Soft
+	if threshold == 0 {
+		threshold = t.limits.Hard
+	}
+	if threshold > 0 && c.used >= threshold && c.softReachedAt.IsZero() {
+		c.softReachedAt = t.now()
+		usage := t.usage(key, c)
+		notify(t.notifier, usage)
+		return usage
+	}
+
+	return t.usage(key, c)
+}
+
+// AddAll records n units of consumption for key that are admitted as a
+// whole, such as the requests a single call fans out to. Unlike Add, nothing
+// is recorded, and ok is false, when the remaining quota cannot cover n,
+// unless the grace period is running.
+func (t *Tracker) AddAll(key string, n int64) (usage Usage, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollPeriod()
+	c := t.counterFor(key)
+
+	status := t.status(c)
+	if status == StatusHard || (status != StatusGrace && t.limits.Hard > 0 && c.used+n > t.limits.Hard) {
+		return t.usage(key, c), false
+	}
+	return t.add(key, c, n), true
+}
+
+// Snapshot returns the usage of every key seen in the current period.
+func (t *Tracker) Snapshot() []Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollPeriod()
+	usages := make([]Usage, 0, len(t.counters))
+	for key, c := range t.counters {
+		usages = append(usages, t.usage(key, c))
+	}
+	return usages
+}
+
+// rollPeriod clears all counters when a new day has started.
+// Must be called with the lock held.
+func (t *Tracker) rollPeriod() {
+	current := startOfDay(t.now())
+	if current.After(t.periodStart) {
+		t.periodStart = current
+		t.counters = make(map[string]*counter)
+	}
+}
+
+// counterFor returns the counter for key, creating it if needed.
+// Must be called with the lock held.
+func (t *Tracker) counterFor(key string) *counter {
+	c, ok := t.counters[key]
+	if !ok {
+		c = &counter{}
+		t.counters[key] = c
+	}
+	return c
+}
+
+// status computes the quota status for a counter.
+// Must be called with the lock held.
+func (t *Tracker) status(c *counter) Status {
+	if t.limits.Hard > 0 && c.used >= t.limits.Hard {
+		if !c.softReachedAt.IsZero() && t.now().Before(c.softReachedAt.Add(t.limits.Grace)) {
+			return StatusGrace
+		}
+		return StatusHard
+	}
+	if t.limits.Soft > 0 && c.used >= t.limits.Soft {
+		return StatusSoft
+	}
+	return StatusOK
+}
+
+// usage builds a Usage snapshot for a counter.
+// Must be called with the lock held.
+func (t *Tracker) usage(key string, c *counter) Usage {
+	u := Usage{
+		Quota:       t.name,
+		Key:         key,
+		Used:        c.used,
+		SoftLimit:   t.limits.Soft,
+		HardLimit:   t.limits.Hard,
+		Remaining:   -1, // Unlimited
+		Status:      t.status(c),
+		PeriodStart: t.periodStart,
+		ResetsAt:    t.periodStart.Add(24 * time.Hour),
+	}
+	if t.limits.Hard > 0 {
+		u.Remaining = max(t.limits.Hard-c.used, 0)
+	}
+	if !c.softReachedAt.IsZero() {
+		softReachedAt := c.softReachedAt
+		graceEndsAt := softReachedAt.Add(t.limits.Grace)
+		u.SoftReachedAt = &softReachedAt
+		u.GraceEndsAt = &graceEndsAt
+	}
+	return u
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// --- Configured Quotas ---
+
+var (
+	// Spans limits the number of spans indexed per service, per day.
+	Spans *Tracker
+
+	// LLM limits the number of LLM playground requests per user, per day.
+	LLM *Tracker
+)
+
+// Init sets the span and LLM request quotas, reporting soft limits reached to
+// notifier, and the LLM rate limits. Quotas are disabled unless their limits
+// are set.
+func Init(spans, llm Limits, rate RateLimits, notifier *webhook.Notifier) {
+	Spans = NewTracker("spans", spans, notifier)
+	LLM = NewTracker("llm_requests", llm, notifier)
+
+	for _, t := range []*Tracker{Spans, LLM} {
+		if t.Limits().Enabled() {
+			slog.Info("quota enabled", "quota", t.Name(), "soft_limit", t.Limits().Soft, "hard_limit", t.Limits().Hard, "grace", t.Limits().Grace)
+		}
+	}
+	initRateLimits(rate)
+}
+
+// headerValue formats a limit for a response header, using "unlimited" for 0.
+func headerValue(limit int64) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d", limit)
+}
@@ -0,0 +1,195 @@
+package quotas
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"junjo-server/apierror"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTimeout is how long the limiter state of a user without
+// requests is kept.
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+// RateLimits configures a per-user request rate and concurrency cap.
+// A value of 0 disables the corresponding limit.
+type RateLimits struct {
+	PerMinute     int64 `json:"requests_per_minute"`
+	Burst         int64 `json:"burst"`
+	MaxConcurrent int64 `json:"max_concurrent"`
+}
+
+// Enabled reports whether any limit is configured.
+func (l RateLimits) Enabled() bool {
+	return l.PerMinute > 0 || l.MaxConcurrent > 0
+}
+
+// userLimit is the rate limiter and in-flight requests of one user.
+type userLimit struct {
+	limiter  *rate.Limiter
+	inFlight int64
+	lastSeen time.Time
+}
+
+// RateLimiter limits the request rate and the requests in flight of each
+// consumer, keyed by user email.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limits    RateLimits
+	users     map[string]*userLimit
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter. Burst defaults to the per-minute
+// rate.
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	if limits.Burst <= 0 {
+		limits.Burst = limits.PerMinute
+	}
+	return &RateLimiter{
+		limits: limits,
+		users:  make(map[string]*userLimit),
+		now:    time.Now,
+	}
+}
+
+// Limits returns the configured limits.
+func (r *RateLimiter) Limits() RateLimits {
+	return r.limits
+}
+
+// Acquire admits a request of a consumer. It returns a release function to
+// call when the request completes, or, when the request is rejected, how long
+// to wait before retrying and whether the concurrency cap was hit.
+func (r *RateLimiter) Acquire(key string) (release func(), retryAfter time.Duration, concurrent bool) {
+	return r.AcquireN(key, 1)
+}
+
+// AcquireN admits n requests of a consumer at once, such as those a single
+// call fans out to, or none of them. A rejection without retryAfter means n
+// exceeds the burst or the concurrency cap, and can never be admitted.
+func (r *RateLimiter) AcquireN(key string, n int) (release func(), retryAfter time.Duration, concurrent bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.prune(now)
+	u, ok := r.users[key]
+	if !ok {
+		u = &userLimit{}
+		if r.limits.PerMinute > 0 {
+			u.limiter = rate.NewLimiter(rate.Limit(float64(r.limits.PerMinute)/60), int(r.limits.Burst))
+		}
+		r.users[key] = u
+	}
+	u.lastSeen = now
+
+	if r.limits.MaxConcurrent > 0 && int64(n) > r.limits.MaxConcurrent {
+		return nil, 0, true
+	}
+	if r.limits.MaxConcurrent > 0 && u.inFlight+int64(n) > r.limits.MaxConcurrent {
+		return nil, time.Second, true
+	}
+	if u.limiter != nil {
+		reservation := u.limiter.ReserveN(now, n)
+		if !reservation.OK() {
+			return nil, 0, false
+		}
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			return nil, delay, false
+		}
+	}
+
+	u.inFlight += int64(n)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			u.inFlight -= int64(n)
+		})
+	}, 0, false
+}
+
+// prune drops the state of users idle for rateLimiterIdleTimeout, at most once
+// a minute.
+func (r *RateLimiter) prune(now time.Time) {
+	if now.Sub(r.lastPrune) < time.Minute {
+		return
+	}
+	r.lastPrune = now
+	for key, u := range r.users {
+		if u.inFlight == 0 && now.Sub(u.lastSeen) > rateLimiterIdleTimeout {
+			delete(r.users, key)
+		}
+	}
+}
+
+// LLMRate limits the rate and concurrency of each user's LLM requests.
+var LLMRate *RateLimiter
+
+// initRateLimits sets the LLM rate limits.
+func initRateLimits(limits RateLimits) {
+	LLMRate = NewRateLimiter(limits)
+	if limits := LLMRate.Limits(); limits.Enabled() {
+		slog.Info("LLM rate limit enabled", "requests_per_minute", limits.PerMinute, "burst", limits.Burst, "max_concurrent", limits.MaxConcurrent)
+	}
+}
+
+// LLMRateLimit is a middleware that limits the signed-in user's LLM requests
+// per minute and in flight, rejecting the excess with 429 and a Retry-After
+// header. Unlike the daily quota, it applies to every request, including
+// those made with the user's own API key.
+func LLMRateLimit() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			release, err := AdmitLLMRequests(c, 1)
+			if err != nil {
+				return err
+			}
+			defer release()
+
+			return next(c)
+		}
+	}
+}
+
+// AdmitLLMRequests applies the LLM rate limit to the n provider requests a
+// call of the signed-in user fans out to, admitting all of them or rejecting
+// the call with 429. The returned release function is called when they
+// complete.
+func AdmitLLMRequests(c echo.Context, n int) (release func(), err error) {
+	if LLMRate == nil || !LLMRate.Limits().Enabled() {
+		return func() {}, nil
+	}
+
+	userEmail, _ := c.Get("userEmail").(string)
+	release, retryAfter, concurrent := LLMRate.AcquireN(userEmail, n)
+	if release != nil {
+		return release, nil
+	}
+
+	message := "LLM request rate limit exceeded"
+	switch {
+	case retryAfter == 0:
+		message = fmt.Sprintf("%d LLM requests exceed the rate limit of a single call", n)
+	case concurrent:
+		message = "Too many concurrent LLM requests"
+	}
+	if retryAfter > 0 {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	return nil, apierror.New(http.StatusTooManyRequests, message).WithDetails(map[string]any{
+		"limits": LLMRate.Limits(),
+	})
+}
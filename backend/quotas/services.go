@@ -0,0 +1,124 @@
+package quotas
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"junjo-server/apierror"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Response headers used to annotate quota-limited responses.
+const (
+	HeaderQuotaLimit     = "X-Junjo-Quota-Limit"
+	HeaderQuotaSoftLimit = "X-Junjo-Quota-Soft-Limit"
+	HeaderQuotaUsed      = "X-Junjo-Quota-Used"
+	HeaderQuotaRemaining = "X-Junjo-Quota-Remaining"
+	HeaderQuotaReset     = "X-Junjo-Quota-Reset"
+	HeaderQuotaStatus    = "X-Junjo-Quota-Status"
+)
+
+// LLMQuota is a middleware that counts LLM requests against the signed-in
+// user's quota, annotates the response with quota headers, and rejects the
+// request once the hard limit is enforced.
+func LLMQuota() echo.MiddlewareFunc {
+	return LLMQuotaWithSkipper(middleware.DefaultSkipper)
+}
+
+// LLMQuotaWithSkipper is LLMQuota for the requests skipper does not skip.
+func LLMQuotaWithSkipper(skipper middleware.Skipper) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if LLM == nil || !LLM.Limits().Enabled() || skipper(c) {
+				return next(c)
+			}
+
+			userEmail, _ := c.Get("userEmail").(string)
+			usage := LLM.Add(userEmail, 1)
+			setHeaders(c, usage)
+
+			if usage.Status == StatusHard {
+				return apierror.New(http.StatusTooManyRequests, "LLM request quota exceeded").WithDetails(map[string]any{
+					"usage": usage,
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// ChargeLLMRequests counts the n provider requests a call of the signed-in
+// user fans out to against the LLM quota, and annotates the response with
+// quota headers. The call is rejected with 429, and nothing is counted, when
+// the remaining quota cannot cover all of them.
+func ChargeLLMRequests(c echo.Context, n int) error {
+	if LLM == nil || !LLM.Limits().Enabled() {
+		return nil
+	}
+
+	userEmail, _ := c.Get("userEmail").(string)
+	usage, ok := LLM.AddAll(userEmail, int64(n))
+	setHeaders(c, usage)
+
+	if !ok {
+		return apierror.New(http.StatusTooManyRequests, fmt.Sprintf("LLM request quota cannot cover %d requests", n)).WithDetails(map[string]any{
+			"usage": usage,
+		})
+	}
+	return nil
+}
+
+// setHeaders annotates the response with the consumer's quota usage.
+func setHeaders(c echo.Context, usage Usage) {
+	h := c.Response().Header()
+	h.Set(HeaderQuotaLimit, headerValue(usage.HardLimit))
+	h.Set(HeaderQuotaSoftLimit, headerValue(usage.SoftLimit))
+	h.Set(HeaderQuotaUsed, strconv.FormatInt(usage.Used, 10))
+	if usage.Remaining >= 0 {
+		h.Set(HeaderQuotaRemaining, strconv.FormatInt(usage.Remaining, 10))
+	}
+	h.Set(HeaderQuotaReset, strconv.FormatInt(usage.ResetsAt.Unix(), 10))
+	h.Set(HeaderQuotaStatus, string(usage.Status))
+}
+
+// QuotaReport describes a single quota's limits and current consumption.
+type QuotaReport struct {
+	Quota     string  `json:"quota"`
+	Enabled   bool    `json:"enabled"`
+	SoftLimit int64   `json:"soft_limit"`
+	HardLimit int64   `json:"hard_limit"`
+	Grace     string  `json:"grace_period"`
+	Usage     []Usage `json:"usage"`
+}
+
+// HandleGetQuotas returns the current consumption vs. limits for every quota.
+func HandleGetQuotas(c echo.Context) error {
+	reports := []QuotaReport{}
+	for _, t := range []*Tracker{Spans, LLM} {
+		if t == nil {
+			continue
+		}
+
+		usage := t.Snapshot()
+		sort.Slice(usage, func(i, j int) bool {
+			return usage[i].Key < usage[j].Key
+		})
+
+		limits := t.Limits()
+		reports = append(reports, QuotaReport{
+			Quota:     t.Name(),
+			Enabled:   limits.Enabled(),
+			SoftLimit: limits.Soft,
+			HardLimit: limits.Hard,
+			Grace:     limits.Grace.String(),
+			Usage:     usage,
+		})
+	}
+
+	return c.JSON(http.StatusOK, reports)
+}
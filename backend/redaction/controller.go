@@ -0,0 +1,16 @@
+package redaction
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	ruleGroup := e.Group("/redaction-rules")
+
+	ruleGroup.GET("", HandleListRules)
+	ruleGroup.POST("", HandleCreateRule)
+	ruleGroup.POST("/test", HandleTestRules)
+	ruleGroup.GET("/:id", HandleGetRule)
+	ruleGroup.PUT("/:id", HandleUpdateRule)
+	ruleGroup.DELETE("/:id", HandleDeleteRule)
+}
@@ -0,0 +1,93 @@
+package redaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"junjo-server/telemetry"
+	"regexp"
+)
+
+// ErrDuplicateName is returned when another rule has the same name.
+var ErrDuplicateName = errors.New("a redaction rule with this name already exists")
+
+// Init loads the enabled redaction rules into the span processor.
+func Init(ctx context.Context) error {
+	return Reload(ctx)
+}
+
+// Reload compiles the enabled stored rules and applies them to spans indexed
+// afterwards.
+func Reload(ctx context.Context) error {
+	stored, err := ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	rules := []telemetry.RedactionRule{}
+	for _, rule := range stored {
+		if !rule.Enabled {
+			continue
+		}
+		compiled, err := compile(rule)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, compiled)
+	}
+	telemetry.SetRedactionRules(rules)
+	return nil
+}
+
+// compile compiles the pattern of a stored rule.
+func compile(rule db_gen.RedactionRule) (telemetry.RedactionRule, error) {
+	pattern, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return telemetry.RedactionRule{}, fmt.Errorf("invalid pattern for redaction rule %q: %w", rule.Name, err)
+	}
+	return telemetry.RedactionRule{
+		Name:        rule.Name,
+		Kind:        rule.Kind,
+		Pattern:     pattern,
+		Replacement: rule.Replacement,
+	}, nil
+}
+
+// ListRules retrieves all stored rules.
+func ListRules(ctx context.Context) ([]db_gen.RedactionRule, error) {
+	queries := db_gen.New(db.DB)
+	return queries.ListRedactionRules(ctx)
+}
+
+// GetRule retrieves a single rule by id.
+func GetRule(ctx context.Context, id int64) (db_gen.RedactionRule, error) {
+	queries := db_gen.New(db.DB)
+	return queries.GetRedactionRule(ctx, id)
+}
+
+// CreateRule stores a new rule.
+func CreateRule(ctx context.Context, arg db_gen.CreateRedactionRuleParams) (db_gen.RedactionRule, error) {
+	queries := db_gen.New(db.DB)
+	rule, err := queries.CreateRedactionRule(ctx, arg)
+	if db.IsUniqueViolation(err) {
+		return rule, ErrDuplicateName
+	}
+	return rule, err
+}
+
+// UpdateRule replaces a stored rule.
+func UpdateRule(ctx context.Context, arg db_gen.UpdateRedactionRuleParams) (db_gen.RedactionRule, error) {
+	queries := db_gen.New(db.DB)
+	rule, err := queries.UpdateRedactionRule(ctx, arg)
+	if db.IsUniqueViolation(err) {
+		return rule, ErrDuplicateName
+	}
+	return rule, err
+}
+
+// DeleteRule removes a rule by id.
+func DeleteRule(ctx context.Context, id int64) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteRedactionRule(ctx, id)
+}
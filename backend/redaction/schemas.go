@@ -0,0 +1,24 @@
+package redaction
+
+// RuleRequest creates or replaces a redaction rule. Pattern is an RE2 regular
+// expression matched against string values (kind "value") or attribute keys
+// (kind "key"). Replacement defaults to [REDACTED] and Enabled to true.
+type RuleRequest struct {
+	Name        string `json:"name" validate:"required"`
+	Kind        string `json:"kind" validate:"required,oneof=value key"`
+	Pattern     string `json:"pattern" validate:"required"`
+	Replacement string `json:"replacement"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// TestRequest is an attribute to redact with the enabled rules.
+type TestRequest struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// TestResponse is the attribute value as it would be stored.
+type TestResponse struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
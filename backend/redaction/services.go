@@ -0,0 +1,182 @@
+package redaction
+
+import (
+	"database/sql"
+	"errors"
+	"junjo-server/db_gen"
+	"junjo-server/telemetry"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultReplacement replaces redacted data when a rule sets no replacement.
+const defaultReplacement = "[REDACTED]"
+
+// HandleListRules lists all redaction rules.
+func HandleListRules(c echo.Context) error {
+	rules, err := ListRules(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list redaction rules:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve redaction rules")
+	}
+
+	// Return empty list instead of null if no rules exist
+	if rules == nil {
+		rules = []db_gen.RedactionRule{}
+	}
+
+	return c.JSON(http.StatusOK, rules)
+}
+
+// HandleGetRule returns a redaction rule by id.
+func HandleGetRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	rule, err := GetRule(c.Request().Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return echo.NewHTTPError(http.StatusNotFound, "Redaction rule not found")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to get redaction rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve redaction rule")
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// bindRule reads and validates a rule request.
+func bindRule(c echo.Context) (RuleRequest, error) {
+	var req RuleRequest
+	if err := c.Bind(&req); err != nil {
+		return req, echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return req, echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if _, err := regexp.Compile(req.Pattern); err != nil {
+		return req, echo.NewHTTPError(http.StatusBadRequest, "Invalid pattern: "+err.Error())
+	}
+	if req.Replacement == "" {
+		req.Replacement = defaultReplacement
+	}
+	if req.Enabled == nil {
+		enabled := true
+		req.Enabled = &enabled
+	}
+	return req, nil
+}
+
+// HandleCreateRule adds a redaction rule. Only spans indexed afterwards are
+// redacted.
+func HandleCreateRule(c echo.Context) error {
+	req, err := bindRule(c)
+	if err != nil {
+		return err
+	}
+
+	rule, err := CreateRule(c.Request().Context(), db_gen.CreateRedactionRuleParams{
+		Name:        req.Name,
+		Kind:        req.Kind,
+		Pattern:     req.Pattern,
+		Replacement: req.Replacement,
+		Enabled:     *req.Enabled,
+	})
+	if errors.Is(err, ErrDuplicateName) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to create redaction rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save redaction rule")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload redaction rules:", err)
+	}
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// HandleUpdateRule replaces a redaction rule by id.
+func HandleUpdateRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	req, err := bindRule(c)
+	if err != nil {
+		return err
+	}
+
+	rule, err := UpdateRule(c.Request().Context(), db_gen.UpdateRedactionRuleParams{
+		Name:        req.Name,
+		Kind:        req.Kind,
+		Pattern:     req.Pattern,
+		Replacement: req.Replacement,
+		Enabled:     *req.Enabled,
+		ID:          id,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return echo.NewHTTPError(http.StatusNotFound, "Redaction rule not found")
+	}
+	if errors.Is(err, ErrDuplicateName) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to update redaction rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save redaction rule")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload redaction rules:", err)
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// HandleDeleteRule deletes a redaction rule by id.
+func HandleDeleteRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	if _, err := GetRule(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Redaction rule not found")
+		}
+		c.Logger().Error("Failed to look up redaction rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete redaction rule")
+	}
+
+	if err := DeleteRule(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete redaction rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete redaction rule")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload redaction rules:", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleTestRules returns an attribute value as it would be stored with the
+// enabled rules, to try out rules before relying on them.
+func HandleTestRules(c echo.Context) error {
+	var req TestRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+
+	return c.JSON(http.StatusOK, TestResponse{
+		Key:   req.Key,
+		Value: telemetry.RedactAttribute(req.Key, req.Value),
+	})
+}
@@ -0,0 +1,22 @@
+package requestid
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware reuses the caller's X-Request-ID header when valid, or
+// generates an ID, and sets it on the response and the request context.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !Valid(id) {
+				id = New()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			c.SetRequest(req.WithContext(NewContext(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
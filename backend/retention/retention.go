@@ -0,0 +1,196 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/selftrace"
+	"junjo-server/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config controls how long spans are kept in DuckDB and where expired spans
+// are archived before they are deleted.
+type Config struct {
+	// MaxAge is how long spans are kept. Zero disables retention entirely.
+	MaxAge time.Duration
+	// Interval is how often the retention job runs.
+	Interval time.Duration
+	// ArchivePath is a local directory or an s3:// URI. When empty, expired
+	// spans are deleted without being archived.
+	ArchivePath string
+}
+
+// Run periodically archives and deletes expired spans until ctx is cancelled.
+// It returns immediately when retention is disabled.
+func Run(ctx context.Context, cfg Config) {
+	if cfg.MaxAge <= 0 {
+		slog.Info("span retention disabled")
+		return
+	}
+	slog.Info("span retention enabled", "max_age", cfg.MaxAge, "interval", cfg.Interval, "archive_path", cfg.ArchivePath)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().Add(-cfg.MaxAge).UTC()
+		if err := ArchiveAndDelete(ctx, cutoff, cfg.ArchivePath); err != nil {
+			slog.Error("span retention run failed", "cutoff", cutoff, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ArchiveAndDelete exports every span (and its state patches) that started
+// before cutoff to Parquet files under archivePath, then deletes them from
+// DuckDB. Nothing is deleted unless the export succeeds.
+func ArchiveAndDelete(ctx context.Context, cutoff time.Time, archivePath string) (err error) {
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	var expired int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM spans WHERE start_time < ?", cutoff).Scan(&expired); err != nil {
+		return fmt.Errorf("failed to count expired spans: %w", err)
+	}
+	if expired == 0 {
+		return nil
+	}
+
+	ctx, span := selftrace.Start(ctx, "duckdb.archive_and_delete", trace.WithAttributes(
+		attribute.Int64("junjo.spans", expired),
+		attribute.String("junjo.archive_path", archivePath),
+	))
+	defer func() {
+		selftrace.RecordError(span, err)
+		span.End()
+	}()
+
+	if archivePath != "" {
+		if err := prepareArchivePath(ctx, archivePath); err != nil {
+			return err
+		}
+	}
+
+	// The expired spans are listed once, in a temporary table of the
+	// connection, so that spans written during the run, such as backfills,
+	// are neither archived nor deleted.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback on error
+
+	if _, err := tx.ExecContext(ctx, "CREATE OR REPLACE TEMP TABLE expired_spans AS SELECT trace_id, span_id FROM spans WHERE start_time < ?", cutoff); err != nil {
+		return fmt.Errorf("failed to list expired spans: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "DROP TABLE IF EXISTS expired_spans")
+
+	if archivePath != "" {
+		// DuckDB does not accept bound parameters in COPY statements, so the
+		// file paths are inlined as escaped literals.
+		suffix := cutoff.Format("20060102T150405Z")
+		exports := []struct {
+			table string
+			query string
+		}{
+			{"spans", "SELECT * FROM spans s WHERE " + expiredFilter("s")},
+			{"state_patches", "SELECT * FROM state_patches p WHERE " + expiredFilter("p")},
+		}
+
+		for _, export := range exports {
+			file := fmt.Sprintf("%s/%s_%s.parquet", archivePath, export.table, suffix)
+			copyQuery := fmt.Sprintf("COPY (%s) TO %s (FORMAT PARQUET, COMPRESSION ZSTD)", export.query, quoteLiteral(file))
+			if _, err := tx.ExecContext(ctx, copyQuery); err != nil {
+				return fmt.Errorf("failed to archive %s to %s: %w", export.table, file, err)
+			}
+			slog.Info("archived expired rows", "table", export.table, "file", file)
+		}
+	}
+
+	// State patches reference spans, and DuckDB checks foreign keys against
+	// committed data only, so spans with patches can't be deleted in the same
+	// transaction as their patches. The spans without patches are deleted
+	// first, while the patches still exclude the others.
+	if _, err := tx.ExecContext(ctx, "DELETE FROM spans s WHERE "+expiredFilter("s")+
+		" AND NOT EXISTS (SELECT 1 FROM state_patches p WHERE p.trace_id = s.trace_id AND p.span_id = s.span_id)"); err != nil {
+		return fmt.Errorf("failed to delete expired spans: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM state_patches p WHERE "+expiredFilter("p")); err != nil {
+		return fmt.Errorf("failed to delete expired state patches: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// The remaining archived spans are those whose patches were just deleted.
+	if _, err := conn.ExecContext(ctx, "DELETE FROM spans s WHERE "+expiredFilter("s")); err != nil {
+		return fmt.Errorf("failed to delete expired spans: %w", err)
+	}
+
+	// Side tables of custom span types are not archived.
+	for _, table := range telemetry.SpanTypeTables() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE start_time < ?", cutoff); err != nil {
+			return fmt.Errorf("failed to delete expired rows from %s: %w", table, err)
+		}
+	}
+
+	slog.Info("deleted expired spans", "count", expired, "cutoff", cutoff)
+	return nil
+}
+
+// expiredFilter matches the rows, of the spans or state_patches table
+// aliased as alias, of the spans listed in the expired_spans table.
+func expiredFilter(alias string) string {
+	return "EXISTS (SELECT 1 FROM expired_spans e WHERE e.trace_id = " + alias + ".trace_id AND e.span_id = " + alias + ".span_id)"
+}
+
+// prepareArchivePath makes sure the archive destination is writable: local
+// directories are created, and the httpfs extension is loaded for S3.
+func prepareArchivePath(ctx context.Context, archivePath string) error {
+	if !strings.HasPrefix(archivePath, "s3://") {
+		if err := os.MkdirAll(filepath.Clean(archivePath), 0755); err != nil {
+			return fmt.Errorf("failed to create archive directory %s: %w", archivePath, err)
+		}
+		return nil
+	}
+
+	db := db_duckdb.DB
+	if _, err := db.ExecContext(ctx, "INSTALL httpfs; LOAD httpfs; INSTALL aws; LOAD aws;"); err != nil {
+		return fmt.Errorf("failed to load DuckDB S3 extensions: %w", err)
+	}
+
+	// Credentials are resolved from the standard AWS environment variables,
+	// config files, or instance metadata.
+	if _, err := db.ExecContext(ctx, "CREATE SECRET IF NOT EXISTS junjo_archive_s3 (TYPE S3, PROVIDER CREDENTIAL_CHAIN)"); err != nil {
+		return fmt.Errorf("failed to configure S3 credentials: %w", err)
+	}
+	return nil
+}
+
+// quoteLiteral quotes s as a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,297 @@
+package rundiff
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// RunSummary identifies a compared run.
+type RunSummary struct {
+	TraceID    string `json:"trace_id"`
+	SpanID     string `json:"span_id"`
+	DurationMs int64  `json:"duration_ms"`
+	StatusCode string `json:"status_code"`
+}
+
+// NodeDuration compares the total duration of the nodes with the same name.
+type NodeDuration struct {
+	Name       string `json:"name"`
+	BaselineMs *int64 `json:"baseline_ms"`
+	RunMs      *int64 `json:"run_ms"`
+	DeltaMs    int64  `json:"delta_ms"`
+}
+
+// Change types.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change is a JSON value that differs between the runs. Before or After is nil
+// when the path is absent from that run.
+type Change struct {
+	Type   string      `json:"type"`
+	Path   string      `json:"path"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// LLMOutputDiff is an LLM call whose model or output differs between the runs.
+// Calls are paired by span name and occurrence, and the fields of the run that
+// lacks the call are empty.
+type LLMOutputDiff struct {
+	Name           string `json:"name"`
+	Occurrence     int    `json:"occurrence"`
+	BaselineSpanID string `json:"baseline_span_id"`
+	RunSpanID      string `json:"run_span_id"`
+	BaselineModel  string `json:"baseline_model"`
+	RunModel       string `json:"run_model"`
+	BaselineOutput string `json:"baseline_output"`
+	RunOutput      string `json:"run_output"`
+}
+
+// Comparison is the difference between a baseline run and another run of the
+// same workflow.
+type Comparison struct {
+	Baseline      RunSummary      `json:"baseline"`
+	Run           RunSummary      `json:"run"`
+	MissingNodes  []string        `json:"missing_nodes"`
+	ExtraNodes    []string        `json:"extra_nodes"`
+	OrderMatches  bool            `json:"order_matches"`
+	BaselineOrder []string        `json:"baseline_order"`
+	RunOrder      []string        `json:"run_order"`
+	Durations     []NodeDuration  `json:"durations"`
+	StateChanges  []Change        `json:"state_changes"`
+	GraphChanges  []Change        `json:"graph_changes"`
+	LLMOutputs    []LLMOutputDiff `json:"llm_outputs"`
+	// DriftScore summarizes the difference from 0 (identical) to 1, as the mean
+	// of the node set, node order, duration and final state differences.
+	DriftScore float64 `json:"drift_score"`
+}
+
+// Compare compares run against baseline.
+func Compare(baseline, run *Run) Comparison {
+	comparison := Comparison{
+		Baseline:      summarize(baseline),
+		Run:           summarize(run),
+		BaselineOrder: nodeOrder(baseline),
+		RunOrder:      nodeOrder(run),
+		MissingNodes:  []string{},
+		ExtraNodes:    []string{},
+		Durations:     []NodeDuration{},
+	}
+
+	baselineDurations := nodeDurations(baseline)
+	runDurations := nodeDurations(run)
+
+	names := map[string]bool{}
+	for name := range baselineDurations {
+		names[name] = true
+		if _, ok := runDurations[name]; !ok {
+			comparison.MissingNodes = append(comparison.MissingNodes, name)
+		}
+	}
+	for name := range runDurations {
+		names[name] = true
+		if _, ok := baselineDurations[name]; !ok {
+			comparison.ExtraNodes = append(comparison.ExtraNodes, name)
+		}
+	}
+	sort.Strings(comparison.MissingNodes)
+	sort.Strings(comparison.ExtraNodes)
+
+	for _, name := range sortedKeys(names) {
+		d := NodeDuration{Name: name}
+		if ms, ok := baselineDurations[name]; ok {
+			d.BaselineMs = &ms
+			d.DeltaMs -= ms
+		}
+		if ms, ok := runDurations[name]; ok {
+			d.RunMs = &ms
+			d.DeltaMs += ms
+		}
+		comparison.Durations = append(comparison.Durations, d)
+	}
+
+	commonLength := longestCommonSubsequence(comparison.BaselineOrder, comparison.RunOrder)
+	comparison.OrderMatches = commonLength == len(comparison.BaselineOrder) && commonLength == len(comparison.RunOrder)
+
+	comparison.StateChanges = DiffObjects(baseline.StateEnd, run.StateEnd)
+	comparison.GraphChanges = DiffObjects(baseline.GraphStructure, run.GraphStructure)
+	comparison.LLMOutputs = diffLLMCalls(baseline.LLMCalls, run.LLMCalls)
+	statePaths := map[string]bool{}
+	for path := range flatten("$", baseline.StateEnd) {
+		statePaths[path] = true
+	}
+	for path := range flatten("$", run.StateEnd) {
+		statePaths[path] = true
+	}
+
+	// Each component ranges from 0 (identical) to 1.
+	nodeDrift := 0.0
+	if len(names) > 0 {
+		nodeDrift = float64(len(comparison.MissingNodes)+len(comparison.ExtraNodes)) / float64(len(names))
+	}
+	orderDrift := 0.0
+	if longest := max(len(comparison.BaselineOrder), len(comparison.RunOrder)); longest > 0 {
+		orderDrift = 1 - float64(commonLength)/float64(longest)
+	}
+	durationDrift := math.Min(1, math.Abs(float64(run.DurationMs-baseline.DurationMs))/math.Max(1, float64(baseline.DurationMs)))
+	stateDrift := 0.0
+	if len(statePaths) > 0 {
+		stateDrift = float64(len(comparison.StateChanges)) / float64(len(statePaths))
+	}
+	comparison.DriftScore = math.Round((nodeDrift+orderDrift+durationDrift+stateDrift)/4*1000) / 1000
+
+	return comparison
+}
+
+// DiffObjects returns the leaf values that differ between two JSON objects,
+// sorted by path. Arrays are compared as a whole.
+func DiffObjects(before, after map[string]interface{}) []Change {
+	beforeValues := flatten("$", before)
+	afterValues := flatten("$", after)
+
+	paths := map[string]bool{}
+	for path := range beforeValues {
+		paths[path] = true
+	}
+	for path := range afterValues {
+		paths[path] = true
+	}
+
+	changes := []Change{}
+	for _, path := range sortedKeys(paths) {
+		b, inBefore := beforeValues[path]
+		a, inAfter := afterValues[path]
+		if inBefore && inAfter && reflect.DeepEqual(a, b) {
+			continue
+		}
+		change := Change{Type: ChangeChanged, Path: path, Before: b, After: a}
+		if !inBefore {
+			change.Type = ChangeAdded
+		} else if !inAfter {
+			change.Type = ChangeRemoved
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// diffLLMCalls pairs the LLM calls of both runs by name and occurrence, and
+// returns the pairs whose model or output differ.
+func diffLLMCalls(baseline, run []LLMCall) []LLMOutputDiff {
+	type key struct {
+		name       string
+		occurrence int
+	}
+	pairs := map[key]*LLMOutputDiff{}
+	var order []key
+	pair := func(call LLMCall, seen map[string]int) *LLMOutputDiff {
+		k := key{call.Name, seen[call.Name]}
+		seen[call.Name]++
+		if _, ok := pairs[k]; !ok {
+			pairs[k] = &LLMOutputDiff{Name: k.name, Occurrence: k.occurrence}
+			order = append(order, k)
+		}
+		return pairs[k]
+	}
+
+	seen := map[string]int{}
+	for _, call := range baseline {
+		d := pair(call, seen)
+		d.BaselineSpanID, d.BaselineModel, d.BaselineOutput = call.SpanID, call.Model, call.Output
+	}
+	seen = map[string]int{}
+	for _, call := range run {
+		d := pair(call, seen)
+		d.RunSpanID, d.RunModel, d.RunOutput = call.SpanID, call.Model, call.Output
+	}
+
+	diffs := []LLMOutputDiff{}
+	for _, k := range order {
+		d := pairs[k]
+		if d.BaselineSpanID != "" && d.RunSpanID != "" && d.BaselineModel == d.RunModel && d.BaselineOutput == d.RunOutput {
+			continue
+		}
+		diffs = append(diffs, *d)
+	}
+	return diffs
+}
+
+// flatten maps the JSON path of every leaf value of object to the value.
+func flatten(prefix string, object map[string]interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	for key, value := range object {
+		path := prefix + "." + key
+		if strings.ContainsAny(key, ".[]\" ") {
+			path = prefix + `["` + key + `"]`
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			for p, v := range flatten(path, nested) {
+				values[p] = v
+			}
+			continue
+		}
+		values[path] = value
+	}
+	return values
+}
+
+func summarize(run *Run) RunSummary {
+	return RunSummary{
+		TraceID:    run.TraceID,
+		SpanID:     run.SpanID,
+		DurationMs: run.DurationMs,
+		StatusCode: run.StatusCode,
+	}
+}
+
+// nodeOrder returns the node names in execution order.
+func nodeOrder(run *Run) []string {
+	order := make([]string, 0, len(run.Nodes))
+	for _, node := range run.Nodes {
+		order = append(order, node.Name)
+	}
+	return order
+}
+
+// nodeDurations sums the durations of the nodes by name, as nodes can run more
+// than once.
+func nodeDurations(run *Run) map[string]int64 {
+	durations := map[string]int64{}
+	for _, node := range run.Nodes {
+		durations[node.Name] += node.DurationMs
+	}
+	return durations
+}
+
+// longestCommonSubsequence returns the length of the longest common
+// subsequence of a and b.
+func longestCommonSubsequence(a, b []string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				current[j+1] = previous[j] + 1
+			} else {
+				current[j+1] = max(current[j], previous[j+1])
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,144 @@
+// Package rundiff loads workflow runs from DuckDB and compares them node by
+// node.
+package rundiff
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+)
+
+// ErrRunNotFound is returned when no workflow span has the requested span id.
+var ErrRunNotFound = errors.New("workflow run not found")
+
+// Run is a workflow execution and the node spans executed within it.
+type Run struct {
+	TraceID        string
+	SpanID         string
+	ServiceName    string
+	WorkflowName   string
+	StartTime      time.Time
+	DurationMs     int64
+	StatusCode     string
+	StateEnd       map[string]interface{}
+	GraphStructure map[string]interface{}
+	Nodes          []Node
+	LLMCalls       []LLMCall
+}
+
+// Node is a node span executed by a workflow run.
+type Node struct {
+	SpanID     string
+	Name       string
+	StartTime  time.Time
+	DurationMs int64
+	StatusCode string
+}
+
+// LLMCall is an LLM span (openinference.span.kind = LLM) executed by a
+// workflow run.
+type LLMCall struct {
+	SpanID    string
+	Name      string
+	StartTime time.Time
+	Model     string
+	Output    string
+}
+
+// queryWorkflowRun selects a workflow span by span id.
+const queryWorkflowRun = `
+	SELECT
+		trace_id, span_id, service_name, COALESCE(name, ''), start_time,
+		epoch_ms(end_time) - epoch_ms(start_time), COALESCE(status_code, ''),
+		COALESCE(junjo_wf_state_end::VARCHAR, '{}'), COALESCE(junjo_wf_graph_structure::VARCHAR, '{}')
+	FROM spans
+	WHERE span_id = ? AND junjo_span_type = 'workflow'
+	LIMIT 1;`
+
+// queryRunSpans selects the node and LLM spans nested under a workflow span,
+// including those of its subflows. Args: trace_id, span_id.
+const queryRunSpans = `
+	WITH RECURSIVE tree AS (
+		SELECT span_id FROM spans WHERE trace_id = $1 AND parent_span_id = $2
+		UNION ALL
+		SELECT s.span_id FROM spans s JOIN tree t ON s.parent_span_id = t.span_id WHERE s.trace_id = $1
+	)
+	SELECT
+		span_id, COALESCE(name, ''), start_time, epoch_ms(end_time) - epoch_ms(start_time), COALESCE(status_code, ''),
+		COALESCE(junjo_span_type = 'node', false),
+		COALESCE(json_extract_string(attributes_json, 'llm.model_name'), ''),
+		COALESCE(json_extract_string(attributes_json, 'output.value'), '')
+	FROM spans
+	WHERE trace_id = $1
+		AND (junjo_span_type = 'node' OR json_extract_string(attributes_json, 'openinference.span.kind') = 'LLM')
+		AND span_id IN (SELECT span_id FROM tree)
+	ORDER BY start_time, span_id;`
+
+// LoadRun loads the workflow run whose workflow span has the given span id.
+func LoadRun(ctx context.Context, spanID string) (*Run, error) {
+	db := db_duckdb.DB
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var run Run
+	var stateEnd, graphStructure string
+	err := db.QueryRowContext(ctx, queryWorkflowRun, spanID).Scan(
+		&run.TraceID, &run.SpanID, &run.ServiceName, &run.WorkflowName, &run.StartTime,
+		&run.DurationMs, &run.StatusCode, &stateEnd, &graphStructure,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow span: %w", err)
+	}
+	run.StateEnd = decodeObject(stateEnd)
+	run.GraphStructure = decodeObject(graphStructure)
+
+	rows, err := db.QueryContext(ctx, queryRunSpans, run.TraceID, run.SpanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load run spans: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var node Node
+		var isNode bool
+		var model, output string
+		if err := rows.Scan(&node.SpanID, &node.Name, &node.StartTime, &node.DurationMs, &node.StatusCode, &isNode, &model, &output); err != nil {
+			return nil, fmt.Errorf("failed to scan run span: %w", err)
+		}
+		if isNode {
+			run.Nodes = append(run.Nodes, node)
+			continue
+		}
+		run.LLMCalls = append(run.LLMCalls, LLMCall{
+			SpanID:    node.SpanID,
+			Name:      node.Name,
+			StartTime: node.StartTime,
+			Model:     model,
+			Output:    output,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read run spans: %w", err)
+	}
+
+	return &run, nil
+}
+
+// decodeObject decodes a JSON object, returning an empty map for anything
+// else.
+func decodeObject(s string) map[string]interface{} {
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &object); err != nil || object == nil {
+		return map[string]interface{}{}
+	}
+	return object
+}
@@ -0,0 +1,16 @@
+package scoring
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	scoringGroup := e.Group("/scoring")
+
+	scoringGroup.GET("/rules", HandleListRules)
+	scoringGroup.POST("/rules", HandleCreateRule)
+	scoringGroup.GET("/rules/:id", HandleGetRule)
+	scoringGroup.DELETE("/rules/:id", HandleDeleteRule)
+	scoringGroup.POST("/rules/:id/run", HandleRunRule)
+	scoringGroup.GET("/rules/:id/results", HandleListResults)
+}
@@ -0,0 +1,210 @@
+package scoring
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+	"junjo-server/telemetry"
+)
+
+// ErrDuplicateName is returned when another scoring rule has the same name.
+var ErrDuplicateName = errors.New("a scoring rule with this name already exists")
+
+// Init loads the scoring rules evaluated at ingest and scores the workflow
+// runs indexed afterwards.
+func Init(ctx context.Context) error {
+	if err := Reload(ctx); err != nil {
+		return err
+	}
+	telemetry.SetWorkflowRunHandler(scoreIngested)
+	return nil
+}
+
+// Reload replaces the scoring rules evaluated at ingest.
+func Reload(ctx context.Context) error {
+	rules, err := ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	compiled := []compiledRule{}
+	for _, rule := range rules {
+		if !rule.OnIngest {
+			continue
+		}
+		c, err := compile(rule)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, c)
+	}
+	setIngestRules(compiled)
+	return nil
+}
+
+// CreateRule stores a new scoring rule by userEmail.
+func CreateRule(ctx context.Context, userEmail string, req CreateRuleRequest) (Rule, error) {
+	onIngest := true
+	if req.OnIngest != nil {
+		onIngest = *req.OnIngest
+	}
+	threshold := sql.NullFloat64{}
+	if req.Threshold != nil {
+		threshold = sql.NullFloat64{Float64: *req.Threshold, Valid: true}
+	}
+
+	queries := db_gen.New(db.DB)
+	rule, err := queries.CreateScoringRule(ctx, db_gen.CreateScoringRuleParams{
+		Name:         req.Name,
+		ServiceName:  req.ServiceName,
+		WorkflowName: req.WorkflowName,
+		Kind:         req.Kind,
+		Field:        req.Field,
+		Pattern:      req.Pattern,
+		Operator:     req.Operator,
+		Threshold:    threshold,
+		OnIngest:     onIngest,
+		CreatedBy:    userEmail,
+	})
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return Rule{}, ErrDuplicateName
+		}
+		return Rule{}, err
+	}
+	return decodeRule(ctx, queries, rule)
+}
+
+// ListRules retrieves all scoring rules, sorted by name, with their scores.
+func ListRules(ctx context.Context) ([]Rule, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListScoringRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := []Rule{}
+	for _, rule := range stored {
+		decoded, err := decodeRule(ctx, queries, rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, decoded)
+	}
+	return rules, nil
+}
+
+// GetRule retrieves a single scoring rule by id with its scores.
+func GetRule(ctx context.Context, id int64) (Rule, error) {
+	queries := db_gen.New(db.DB)
+	rule, err := queries.GetScoringRule(ctx, id)
+	if err != nil {
+		return Rule{}, err
+	}
+	return decodeRule(ctx, queries, rule)
+}
+
+// DeleteRule removes a scoring rule and its results.
+func DeleteRule(ctx context.Context, id int64) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := db_gen.New(tx)
+	if err := queries.DeleteScoringResults(ctx, id); err != nil {
+		return err
+	}
+	if err := queries.DeleteScoringRule(ctx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveResult stores the score a rule gave a workflow run, replacing an earlier
+// one.
+func saveResult(ctx context.Context, rule compiledRule, run telemetry.WorkflowRun, score float64) error {
+	queries := db_gen.New(db.DB)
+	return queries.UpsertScoringResult(ctx, db_gen.UpsertScoringResultParams{
+		RuleID:      rule.ID,
+		ServiceName: run.ServiceName,
+		TraceID:     run.TraceID,
+		SpanID:      run.SpanID,
+		Score:       score,
+	})
+}
+
+// ListResults retrieves the most recent results of a rule.
+func ListResults(ctx context.Context, ruleID int64, limit int) ([]Result, error) {
+	queries := db_gen.New(db.DB)
+	stored, err := queries.ListScoringResults(ctx, db_gen.ListScoringResultsParams{RuleID: ruleID, Limit: int64(limit)})
+	if err != nil {
+		return nil, err
+	}
+	results := []Result{}
+	for _, result := range stored {
+		results = append(results, Result{
+			ID:        result.ID,
+			RuleID:    result.RuleID,
+			TraceID:   result.TraceID,
+			SpanID:    result.SpanID,
+			Score:     result.Score,
+			CreatedAt: result.CreatedAt,
+		})
+	}
+	return results, nil
+}
+
+// TraceScore is the sum of the scores a trace received from the scoring rules
+// of its service.
+type TraceScore struct {
+	Count int64
+	Sum   float64
+}
+
+// TraceScores returns the rule scores of the traces of a service, by trace id.
+func TraceScores(ctx context.Context, serviceName string) (map[string]TraceScore, error) {
+	queries := db_gen.New(db.DB)
+	rows, err := queries.ListScoringTraceScores(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]TraceScore, len(rows))
+	for _, row := range rows {
+		scores[row.TraceID] = TraceScore{Count: row.ScoreCount, Sum: row.ScoreSum}
+	}
+	return scores, nil
+}
+
+func decodeRule(ctx context.Context, queries *db_gen.Queries, rule db_gen.ScoringRule) (Rule, error) {
+	summary, err := queries.SummarizeScoringResults(ctx, rule.ID)
+	if err != nil {
+		return Rule{}, err
+	}
+	return Rule{
+		ID:           rule.ID,
+		Name:         rule.Name,
+		ServiceName:  rule.ServiceName,
+		WorkflowName: rule.WorkflowName,
+		Kind:         rule.Kind,
+		Field:        rule.Field,
+		Pattern:      rule.Pattern,
+		Operator:     rule.Operator,
+		Threshold:    floatPointer(rule.Threshold),
+		OnIngest:     rule.OnIngest,
+		ScoredCount:  summary.ScoredCount,
+		AverageScore: floatPointer(summary.AverageScore),
+		CreatedBy:    rule.CreatedBy,
+		CreatedAt:    rule.CreatedAt,
+		UpdatedAt:    rule.UpdatedAt,
+	}, nil
+}
+
+func floatPointer(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	number := value.Float64
+	return &number
+}
@@ -0,0 +1,166 @@
+package scoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule kinds.
+const (
+	KindRegex      = "regex"
+	KindJSONEquals = "json_equals"
+	KindThreshold  = "threshold"
+)
+
+// compiledRule is a rule ready to score final states.
+type compiledRule struct {
+	ID           int64
+	ServiceName  string
+	WorkflowName string
+	Kind         string
+	Field        string
+	Pattern      *regexp.Regexp
+	Expected     interface{}
+	Operator     string
+	Threshold    float64
+}
+
+// compile validates a rule definition.
+func compile(rule Rule) (compiledRule, error) {
+	compiled := compiledRule{
+		ID:           rule.ID,
+		ServiceName:  rule.ServiceName,
+		WorkflowName: rule.WorkflowName,
+		Kind:         rule.Kind,
+		Field:        rule.Field,
+		Operator:     rule.Operator,
+	}
+
+	switch rule.Kind {
+	case KindRegex:
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid pattern: %w", err)
+		}
+		compiled.Pattern = pattern
+	case KindJSONEquals:
+		if rule.Field == "" {
+			return compiled, fmt.Errorf("json_equals rules require a field")
+		}
+		if err := json.Unmarshal([]byte(rule.Pattern), &compiled.Expected); err != nil {
+			return compiled, fmt.Errorf("pattern must be a JSON value: %w", err)
+		}
+	case KindThreshold:
+		if rule.Field == "" || rule.Operator == "" || rule.Threshold == nil {
+			return compiled, fmt.Errorf("threshold rules require a field, an operator and a threshold")
+		}
+		compiled.Threshold = *rule.Threshold
+	default:
+		return compiled, fmt.Errorf("unknown rule kind %q", rule.Kind)
+	}
+	return compiled, nil
+}
+
+// appliesTo reports whether the rule scores the runs of a workflow.
+func (r compiledRule) appliesTo(serviceName, workflowName string) bool {
+	return r.ServiceName == serviceName && (r.WorkflowName == "" || r.WorkflowName == workflowName)
+}
+
+// score scores the final state of a workflow run, 1 when the rule holds and 0
+// otherwise.
+func (r compiledRule) score(finalState string) float64 {
+	if r.Kind == KindRegex && r.Field == "" {
+		return boolScore(r.Pattern.MatchString(finalState))
+	}
+
+	var state interface{}
+	if err := json.Unmarshal([]byte(finalState), &state); err != nil {
+		return 0
+	}
+	value, ok := lookup(state, r.Field)
+	if !ok {
+		return 0
+	}
+
+	switch r.Kind {
+	case KindRegex:
+		text, isString := value.(string)
+		if !isString {
+			encoded, _ := json.Marshal(value)
+			text = string(encoded)
+		}
+		return boolScore(r.Pattern.MatchString(text))
+	case KindJSONEquals:
+		return boolScore(reflect.DeepEqual(value, r.Expected))
+	case KindThreshold:
+		number, ok := toNumber(value)
+		if !ok {
+			return 0
+		}
+		return boolScore(compare(number, r.Operator, r.Threshold))
+	}
+	return 0
+}
+
+// lookup returns the value at a dotted path, where numeric segments index
+// arrays.
+func lookup(value interface{}, path string) (interface{}, bool) {
+	for _, segment := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// toNumber reads a number, or a string holding one.
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func compare(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case "gt":
+		return value > threshold
+	case "gte":
+		return value >= threshold
+	case "lt":
+		return value < threshold
+	case "lte":
+		return value <= threshold
+	case "eq":
+		return value == threshold
+	}
+	return false
+}
+
+func boolScore(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
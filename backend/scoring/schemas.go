@@ -0,0 +1,64 @@
+package scoring
+
+import "time"
+
+// CreateRuleRequest defines a scoring rule for the workflow runs of a service,
+// optionally limited to one workflow. Field is a dotted path into the final
+// state, such as answer.confidence or items.0.label.
+//
+//   - regex scores 1 when Pattern matches the field, or the whole final state
+//     when Field is empty.
+//   - json_equals scores 1 when the field equals Pattern, a JSON value.
+//   - threshold scores 1 when the numeric field compares to Threshold with
+//     Operator (gt, gte, lt, lte or eq).
+//
+// Otherwise, including when the field is missing, the run scores 0. OnIngest
+// defaults to true.
+type CreateRuleRequest struct {
+	Name         string   `json:"name" validate:"required"`
+	ServiceName  string   `json:"service_name" validate:"required"`
+	WorkflowName string   `json:"workflow_name"`
+	Kind         string   `json:"kind" validate:"required,oneof=regex json_equals threshold"`
+	Field        string   `json:"field"`
+	Pattern      string   `json:"pattern"`
+	Operator     string   `json:"operator" validate:"omitempty,oneof=gt gte lt lte eq"`
+	Threshold    *float64 `json:"threshold"`
+	OnIngest     *bool    `json:"on_ingest"`
+}
+
+// RunRuleRequest scores the most recent stored workflow runs matching a rule,
+// optionally limited to a time range.
+type RunRuleRequest struct {
+	StartTime *time.Time `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+	Limit     int        `json:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+// Rule is a scoring rule with the number and average of the scores it gave.
+type Rule struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	ServiceName  string    `json:"service_name"`
+	WorkflowName string    `json:"workflow_name"`
+	Kind         string    `json:"kind"`
+	Field        string    `json:"field"`
+	Pattern      string    `json:"pattern"`
+	Operator     string    `json:"operator"`
+	Threshold    *float64  `json:"threshold"`
+	OnIngest     bool      `json:"on_ingest"`
+	ScoredCount  int64     `json:"scored_count"`
+	AverageScore *float64  `json:"average_score"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Result is the score a rule gave a workflow run.
+type Result struct {
+	ID        int64     `json:"id"`
+	RuleID    int64     `json:"rule_id"`
+	TraceID   string    `json:"trace_id"`
+	SpanID    string    `json:"span_id"`
+	Score     float64   `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,113 @@
+package scoring
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/telemetry"
+)
+
+// defaultRunLimit is the number of stored workflow runs scored on demand when
+// no limit is given.
+const defaultRunLimit = 100
+
+// queryWorkflowRuns selects the most recent workflow spans of a service with
+// their final state. Args: service_name, workflow name (empty for any), start,
+// end, limit.
+const queryWorkflowRuns = `
+	SELECT
+		trace_id, span_id, COALESCE(name, ''), COALESCE(junjo_wf_state_end::VARCHAR, '{}')
+	FROM spans
+	WHERE service_name = $1
+		AND junjo_span_type = 'workflow'
+		AND ($2 = '' OR name = $2)
+		AND start_time >= $3
+		AND start_time < $4
+	ORDER BY start_time DESC, span_id
+	LIMIT $5;`
+
+var (
+	ingestRulesMu sync.RWMutex
+	ingestRules   []compiledRule
+)
+
+func setIngestRules(rules []compiledRule) {
+	ingestRulesMu.Lock()
+	defer ingestRulesMu.Unlock()
+	ingestRules = rules
+}
+
+// scoreIngested scores newly indexed workflow runs with the rules evaluated at
+// ingest. Failures are logged; they never fail ingestion.
+func scoreIngested(ctx context.Context, runs []telemetry.WorkflowRun) {
+	ingestRulesMu.RLock()
+	rules := ingestRules
+	ingestRulesMu.RUnlock()
+
+	for _, run := range runs {
+		for _, rule := range rules {
+			if !rule.appliesTo(run.ServiceName, run.Name) {
+				continue
+			}
+			if err := saveResult(ctx, rule, run, rule.score(run.FinalState)); err != nil {
+				log.Printf("Failed to save score of rule %d for span %s: %v", rule.ID, run.SpanID, err)
+			}
+		}
+	}
+}
+
+// RunRule scores the most recent stored workflow runs matching a rule and
+// returns the number of runs scored.
+func RunRule(ctx context.Context, rule Rule, req RunRuleRequest) (int, error) {
+	compiled, err := compile(rule)
+	if err != nil {
+		return 0, err
+	}
+
+	duck := db_duckdb.DB
+	if duck == nil {
+		return 0, fmt.Errorf("database connection is nil")
+	}
+
+	start := time.Unix(0, 0).UTC()
+	if req.StartTime != nil {
+		start = *req.StartTime
+	}
+	end := time.Now().Add(time.Minute)
+	if req.EndTime != nil {
+		end = *req.EndTime
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultRunLimit
+	}
+
+	rows, err := duck.QueryContext(ctx, queryWorkflowRuns, rule.ServiceName, rule.WorkflowName, start, end, limit)
+	if err != nil {
+		return 0, err
+	}
+	var runs []telemetry.WorkflowRun
+	for rows.Next() {
+		run := telemetry.WorkflowRun{ServiceName: rule.ServiceName}
+		if err := rows.Scan(&run.TraceID, &run.SpanID, &run.Name, &run.FinalState); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		runs = append(runs, run)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, run := range runs {
+		if err := saveResult(ctx, compiled, run, compiled.score(run.FinalState)); err != nil {
+			return 0, err
+		}
+	}
+	return len(runs), nil
+}
@@ -0,0 +1,182 @@
+package scoring
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Result listing limits.
+const (
+	defaultResultLimit = 100
+	maxResultLimit     = 1000
+)
+
+// HandleListRules lists all scoring rules.
+func HandleListRules(c echo.Context) error {
+	rules, err := ListRules(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list scoring rules:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve scoring rules")
+	}
+
+	return c.JSON(http.StatusOK, rules)
+}
+
+// HandleCreateRule defines a scoring rule. When evaluated at ingest, it scores
+// the workflow runs indexed afterwards.
+func HandleCreateRule(c echo.Context) error {
+	userEmail, ok := c.Get("userEmail").(string)
+	if !ok || userEmail == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized: No valid session")
+	}
+
+	var req CreateRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+	if _, err := compile(Rule{Kind: req.Kind, Field: req.Field, Pattern: req.Pattern, Operator: req.Operator, Threshold: req.Threshold}); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	rule, err := CreateRule(c.Request().Context(), userEmail, req)
+	if errors.Is(err, ErrDuplicateName) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to create scoring rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save scoring rule")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload scoring rules:", err)
+	}
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// HandleGetRule retrieves a scoring rule by id.
+func HandleGetRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	rule, err := GetRule(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Scoring rule not found")
+		}
+		c.Logger().Error("Failed to get scoring rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve scoring rule")
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// HandleDeleteRule deletes a scoring rule and its results.
+func HandleDeleteRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	if _, err := GetRule(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Scoring rule not found")
+		}
+		c.Logger().Error("Failed to look up scoring rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete scoring rule")
+	}
+
+	if err := DeleteRule(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete scoring rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete scoring rule")
+	}
+
+	if err := Reload(c.Request().Context()); err != nil {
+		c.Logger().Error("Failed to reload scoring rules:", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleRunRule scores stored workflow runs with a rule on demand and returns
+// the rule with its updated scores. Earlier scores of the same runs are
+// replaced.
+func HandleRunRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	var req RunRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	rule, err := GetRule(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Scoring rule not found")
+		}
+		c.Logger().Error("Failed to get scoring rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to run scoring rule")
+	}
+
+	if _, err := RunRule(c.Request().Context(), rule, req); err != nil {
+		c.Logger().Error("Failed to run scoring rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to run scoring rule")
+	}
+
+	rule, err = GetRule(c.Request().Context(), id)
+	if err != nil {
+		c.Logger().Error("Failed to get scoring rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve scoring rule")
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// HandleListResults lists the most recent scores of a rule. The number of
+// results is set with the limit query parameter (default 100, max 1000).
+func HandleListResults(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule id")
+	}
+
+	limit := defaultResultLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(parsed, maxResultLimit)
+	}
+
+	if _, err := GetRule(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Scoring rule not found")
+		}
+		c.Logger().Error("Failed to get scoring rule:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve scoring results")
+	}
+
+	results, err := ListResults(c.Request().Context(), id, limit)
+	if err != nil {
+		c.Logger().Error("Failed to list scoring results:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve scoring results")
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,193 @@
+package selftrace
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// apiKeyMetadataKey is the metadata key the ingestion service reads the API
+// key of an export from.
+const apiKeyMetadataKey = "x-junjo-api-key"
+
+// exporter sends spans to an OTLP gRPC trace service.
+type exporter struct {
+	conn   *grpc.ClientConn
+	client coltracepb.TraceServiceClient
+	apiKey string
+}
+
+// ExportSpans converts the spans to OTLP and exports them in one request.
+func (e *exporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, apiKeyMetadataKey, e.apiKey)
+	res, err := e.client.Export(ctx, &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: toResourceSpans(spans),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export %d spans: %w", len(spans), err)
+	}
+	if partial := res.GetPartialSuccess(); partial.GetRejectedSpans() > 0 {
+		return fmt.Errorf("ingestion service rejected %d spans: %s", partial.GetRejectedSpans(), partial.GetErrorMessage())
+	}
+	return nil
+}
+
+// Shutdown closes the connection.
+func (e *exporter) Shutdown(context.Context) error {
+	return e.conn.Close()
+}
+
+// toResourceSpans groups spans by instrumentation scope under the resource
+// of the first span, which is shared by every span of the provider.
+func toResourceSpans(spans []sdktrace.ReadOnlySpan) []*tracepb.ResourceSpans {
+	resourceSpans := &tracepb.ResourceSpans{
+		Resource: &resourcepb.Resource{Attributes: toKeyValues(spans[0].Resource().Attributes())},
+	}
+	if spans[0].Resource() != nil {
+		resourceSpans.SchemaUrl = spans[0].Resource().SchemaURL()
+	}
+
+	scopes := map[string]*tracepb.ScopeSpans{}
+	for _, span := range spans {
+		scope := span.InstrumentationScope()
+		scopeSpans, ok := scopes[scope.Name+"@"+scope.Version]
+		if !ok {
+			scopeSpans = &tracepb.ScopeSpans{
+				Scope:     &commonpb.InstrumentationScope{Name: scope.Name, Version: scope.Version},
+				SchemaUrl: scope.SchemaURL,
+			}
+			scopes[scope.Name+"@"+scope.Version] = scopeSpans
+			resourceSpans.ScopeSpans = append(resourceSpans.ScopeSpans, scopeSpans)
+		}
+		scopeSpans.Spans = append(scopeSpans.Spans, toSpan(span))
+	}
+	return []*tracepb.ResourceSpans{resourceSpans}
+}
+
+// toSpan converts a span to OTLP.
+func toSpan(span sdktrace.ReadOnlySpan) *tracepb.Span {
+	sc := span.SpanContext()
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	out := &tracepb.Span{
+		TraceId:                traceID[:],
+		SpanId:                 spanID[:],
+		TraceState:             sc.TraceState().String(),
+		Name:                   span.Name(),
+		Kind:                   toSpanKind(span.SpanKind()),
+		StartTimeUnixNano:      uint64(span.StartTime().UnixNano()),
+		EndTimeUnixNano:        uint64(span.EndTime().UnixNano()),
+		Attributes:             toKeyValues(span.Attributes()),
+		DroppedAttributesCount: uint32(span.DroppedAttributes()),
+		DroppedEventsCount:     uint32(span.DroppedEvents()),
+		DroppedLinksCount:      uint32(span.DroppedLinks()),
+		Status:                 &tracepb.Status{Message: span.Status().Description},
+	}
+	if parent := span.Parent(); parent.IsValid() {
+		parentID := parent.SpanID()
+		out.ParentSpanId = parentID[:]
+	}
+	switch span.Status().Code {
+	case codes.Ok:
+		out.Status.Code = tracepb.Status_STATUS_CODE_OK
+	case codes.Error:
+		out.Status.Code = tracepb.Status_STATUS_CODE_ERROR
+	}
+	for _, event := range span.Events() {
+		out.Events = append(out.Events, &tracepb.Span_Event{
+			TimeUnixNano:           uint64(event.Time.UnixNano()),
+			Name:                   event.Name,
+			Attributes:             toKeyValues(event.Attributes),
+			DroppedAttributesCount: uint32(event.DroppedAttributeCount),
+		})
+	}
+	for _, link := range span.Links() {
+		linkTraceID, linkSpanID := link.SpanContext.TraceID(), link.SpanContext.SpanID()
+		out.Links = append(out.Links, &tracepb.Span_Link{
+			TraceId:                linkTraceID[:],
+			SpanId:                 linkSpanID[:],
+			TraceState:             link.SpanContext.TraceState().String(),
+			Attributes:             toKeyValues(link.Attributes),
+			DroppedAttributesCount: uint32(link.DroppedAttributeCount),
+		})
+	}
+	return out
+}
+
+func toSpanKind(kind trace.SpanKind) tracepb.Span_SpanKind {
+	switch kind {
+	case trace.SpanKindInternal:
+		return tracepb.Span_SPAN_KIND_INTERNAL
+	case trace.SpanKindServer:
+		return tracepb.Span_SPAN_KIND_SERVER
+	case trace.SpanKindClient:
+		return tracepb.Span_SPAN_KIND_CLIENT
+	case trace.SpanKindProducer:
+		return tracepb.Span_SPAN_KIND_PRODUCER
+	case trace.SpanKindConsumer:
+		return tracepb.Span_SPAN_KIND_CONSUMER
+	default:
+		return tracepb.Span_SPAN_KIND_UNSPECIFIED
+	}
+}
+
+func toKeyValues(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	out := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		out = append(out, &commonpb.KeyValue{Key: string(attr.Key), Value: toAnyValue(attr.Value)})
+	}
+	return out
+}
+
+func toAnyValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.BOOLSLICE:
+		values := []*commonpb.AnyValue{}
+		for _, b := range v.AsBoolSlice() {
+			values = append(values, &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: b}})
+		}
+		return arrayValue(values)
+	case attribute.INT64SLICE:
+		values := []*commonpb.AnyValue{}
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}})
+		}
+		return arrayValue(values)
+	case attribute.FLOAT64SLICE:
+		values := []*commonpb.AnyValue{}
+		for _, f := range v.AsFloat64Slice() {
+			values = append(values, &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}})
+		}
+		return arrayValue(values)
+	case attribute.STRINGSLICE:
+		values := []*commonpb.AnyValue{}
+		for _, s := range v.AsStringSlice() {
+			values = append(values, &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}})
+		}
+		return arrayValue(values)
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+	}
+}
+
+func arrayValue(values []*commonpb.AnyValue) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+}
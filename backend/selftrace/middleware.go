@@ -0,0 +1,64 @@
+package selftrace
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// untracedPaths are probed by orchestrators and scrapers, and not traced.
+var untracedPaths = []string{"/ping", "/healthz", "/readyz", "/metrics"}
+
+// Middleware traces each request with a server span named after its method
+// and route. Probes and the debug endpoints are not traced.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := c.Path()
+			if route == "" || strings.HasPrefix(route, "/debug/") {
+				return next(c)
+			}
+			for _, path := range untracedPaths {
+				if route == path {
+					return next(c)
+				}
+			}
+
+			req := c.Request()
+			ctx, span := Start(req.Context(), req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(req.URL.Path),
+				),
+			)
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response so the status is known
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, fmt.Sprintf("%d %s", status, http.StatusText(status)))
+			}
+			if err != nil {
+				span.RecordError(err)
+			}
+			if userEmail, ok := c.Get("userEmail").(string); ok && userEmail != "" {
+				span.SetAttributes(semconv.EnduserID(userEmail))
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package selftrace instruments the backend with OpenTelemetry and exports
+// its traces to the ingestion service, so that junjo-server can be debugged
+// in its own UI. Until Init is called, the tracer records nothing.
+//
+// Exported traces come back through the poller, so work done on the
+// backend's own spans is not traced: otherwise every flush would be traced,
+// ingested and flushed again, forever.
+package selftrace
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+
+	"junjo-server/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	grpc_credentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ServiceName is the service.name of the backend's own spans.
+const ServiceName = "junjo-server-backend"
+
+// tracerName is the instrumentation scope of the backend's spans.
+const tracerName = "junjo-server"
+
+// Init exports the backend's traces to the configured ingestion endpoint.
+// The returned function flushes pending spans and stops the exporter.
+func Init(cfg config.SelfTraceConfig, dialOpts ...grpc.DialOption) (func(context.Context) error, error) {
+	creds := insecure.NewCredentials()
+	if cfg.TLS {
+		creds = grpc_credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(cfg.Endpoint, append(dialOpts, grpc.WithTransportCredentials(creds))...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(&exporter{
+			conn:   conn,
+			client: coltracepb.NewTraceServiceClient(conn),
+			apiKey: cfg.APIKey,
+		}),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	log.Printf("Self-tracing enabled: exporting to %s as %s", cfg.Endpoint, ServiceName)
+	return provider.Shutdown, nil
+}
+
+// suppressKey marks contexts whose work is not traced.
+type suppressKey struct{}
+
+// Suppress returns a context under which Start records nothing.
+func Suppress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, suppressKey{}, true)
+}
+
+// IsSelf reports whether spans of a service are the backend's own.
+func IsSelf(serviceName string) bool {
+	return serviceName == ServiceName
+}
+
+// Start starts a span, unless the context is suppressed.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if suppressed, _ := ctx.Value(suppressKey{}).(bool); suppressed {
+		return ctx, noop.Span{}
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// RecordError records an error on a span and marks it failed.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
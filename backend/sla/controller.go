@@ -0,0 +1,15 @@
+package sla
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func InitRoutes(e *echo.Echo) {
+	slaGroup := e.Group("/workflow-slas")
+
+	slaGroup.GET("", HandleListSLAs)
+	slaGroup.PUT("", HandleUpsertSLA)
+	slaGroup.DELETE("/:id", HandleDeleteSLA)
+
+	e.GET("/otel/service/:serviceName/workflow-timeouts", HandleListTimeouts)
+}
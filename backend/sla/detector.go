@@ -0,0 +1,211 @@
+package sla
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/db_gen"
+	"junjo-server/webhook"
+)
+
+// Timeout statuses recorded in the workflow_timeouts table.
+const (
+	StatusTimedOut = "timed_out" // The workflow span had not closed by the deadline.
+	StatusExceeded = "exceeded"  // The workflow span closed, but took longer than its SLA.
+)
+
+// Config controls the timeout detector.
+type Config struct {
+	// Interval is how often the detector runs.
+	Interval time.Duration
+	// Lookback bounds how far back (beyond the SLA itself) executions are
+	// checked, so each run only scans recent spans.
+	Lookback time.Duration
+	// Notifier is sent an event for each missed SLA.
+	Notifier *webhook.Notifier
+}
+
+// Run periodically checks workflow executions against their SLAs until ctx
+// is cancelled.
+func Run(ctx context.Context, cfg Config) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		slas, err := ListSLAs(ctx)
+		if err != nil {
+			slog.Error("failed to load workflow SLAs", "error", err)
+			continue
+		}
+		if err := Detect(ctx, slas, cfg.Lookback, time.Now().UTC(), cfg.Notifier); err != nil {
+			slog.Error("workflow SLA check failed", "error", err)
+		}
+	}
+}
+
+// Detect records every workflow execution that missed its SLA as of now, and
+// resolves timed-out executions whose workflow span has since arrived.
+//
+// Spans are only exported when they end, so a hung workflow is seen as a trace
+// whose node spans have arrived but whose root span has not. The workflow name
+// of such an execution is unknown, so only the service default SLA (an empty
+// workflow name) applies to it. Completed workflow spans are checked against
+// their named SLA, falling back to the service default.
+func Detect(ctx context.Context, slas []db_gen.WorkflowSla, lookback time.Duration, now time.Time, notifier *webhook.Notifier) error {
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	named := map[string][]string{}
+	for _, sla := range slas {
+		if sla.WorkflowName != "" {
+			named[sla.ServiceName] = append(named[sla.ServiceName], sla.WorkflowName)
+		}
+	}
+
+	var detected []WorkflowTimeout
+	for _, sla := range slas {
+		limit := time.Duration(sla.ExpectedDurationMs) * time.Millisecond
+		since := now.Add(-limit - lookback)
+
+		if sla.WorkflowName == "" {
+			timeouts, err := insertTimeouts(ctx, queryDetectHung, sla.ExpectedDurationMs, sla.ServiceName, since, now.Add(-limit), now)
+			if err != nil {
+				return fmt.Errorf("failed to detect hung workflows for %s: %w", sla.ServiceName, err)
+			}
+			detected = append(detected, timeouts...)
+		}
+
+		// The service default must not override the named SLAs of the service.
+		nameFilter := "AND name = ?"
+		args := []any{sla.ExpectedDurationMs, now, sla.ServiceName, since, sla.ExpectedDurationMs}
+		if sla.WorkflowName == "" {
+			if excluded := named[sla.ServiceName]; len(excluded) > 0 {
+				nameFilter = "AND name NOT IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(excluded)), ", ") + ")"
+				for _, name := range excluded {
+					args = append(args, name)
+				}
+			} else {
+				nameFilter = ""
+			}
+		} else {
+			args = append(args, sla.WorkflowName)
+		}
+
+		timeouts, err := insertTimeouts(ctx, strings.Replace(queryDetectExceeded, "/* name_filter */", nameFilter, 1), args...)
+		if err != nil {
+			return fmt.Errorf("failed to detect slow workflows for %s: %w", sla.ServiceName, err)
+		}
+		detected = append(detected, timeouts...)
+	}
+
+	if _, err := db.ExecContext(ctx, queryResolveTimeouts, now, StatusTimedOut); err != nil {
+		return fmt.Errorf("failed to resolve workflow timeouts: %w", err)
+	}
+
+	for _, timeout := range detected {
+		notify(notifier, timeout)
+	}
+	return nil
+}
+
+// queryDetectHung finds Junjo traces that started before the deadline cutoff
+// and still have no root span. Args: sla_ms, service_name, since, cutoff, now.
+const queryDetectHung = `
+	INSERT INTO workflow_timeouts (trace_id, span_id, service_name, workflow_name, status, started_at, deadline, detected_at)
+	SELECT
+		trace_id, '', service_name, NULL, 'timed_out', MIN(start_time), (MIN(start_time)::TIMESTAMP + to_milliseconds($1))::TIMESTAMPTZ, $5::TIMESTAMPTZ
+	FROM spans
+	WHERE service_name = $2 AND start_time >= $3
+	GROUP BY trace_id, service_name
+	HAVING COUNT(*) FILTER (WHERE parent_span_id IS NULL) = 0
+		AND COUNT(*) FILTER (WHERE junjo_span_type IS NOT NULL AND junjo_span_type <> '') > 0
+		AND MIN(start_time) < $4
+	ON CONFLICT DO NOTHING
+	RETURNING trace_id, span_id, service_name, workflow_name, status, started_at, deadline, detected_at, resolved_at;`
+
+// queryDetectExceeded finds completed workflow spans that ran longer than the
+// SLA. Args: sla_ms, now, service_name, since, sla_ms, then the name filter.
+const queryDetectExceeded = `
+	INSERT INTO workflow_timeouts (trace_id, span_id, service_name, workflow_name, status, started_at, deadline, detected_at)
+	SELECT
+		trace_id, span_id, service_name, name, 'exceeded', start_time, (start_time::TIMESTAMP + to_milliseconds(?))::TIMESTAMPTZ, ?::TIMESTAMPTZ
+	FROM spans
+	WHERE service_name = ?
+		AND junjo_span_type = 'workflow'
+		AND start_time >= ?
+		AND epoch_ms(end_time) - epoch_ms(start_time) > ?
+		/* name_filter */
+	ON CONFLICT DO NOTHING
+	RETURNING trace_id, span_id, service_name, workflow_name, status, started_at, deadline, detected_at, resolved_at;`
+
+// queryResolveTimeouts marks hung executions as resolved once their root span
+// has arrived. Args: now, status.
+const queryResolveTimeouts = `
+	UPDATE workflow_timeouts t
+	SET resolved_at = ?
+	WHERE t.status = ?
+		AND t.resolved_at IS NULL
+		AND EXISTS (SELECT 1 FROM spans s WHERE s.trace_id = t.trace_id AND s.parent_span_id IS NULL);`
+
+// insertTimeouts runs an INSERT ... RETURNING detection query and returns the
+// newly recorded timeouts.
+func insertTimeouts(ctx context.Context, query string, args ...any) ([]WorkflowTimeout, error) {
+	rows, err := db_duckdb.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var timeouts []WorkflowTimeout
+	for rows.Next() {
+		var t WorkflowTimeout
+		if err := rows.Scan(&t.TraceID, &t.SpanID, &t.ServiceName, &t.WorkflowName, &t.Status, &t.StartedAt, &t.Deadline, &t.DetectedAt, &t.ResolvedAt); err != nil {
+			return nil, err
+		}
+		timeouts = append(timeouts, t)
+	}
+	return timeouts, rows.Err()
+}
+
+// ListTimeouts returns the recorded timeouts of a service, newest first.
+func ListTimeouts(ctx context.Context, serviceName string, includeResolved bool) ([]WorkflowTimeout, error) {
+	db := db_duckdb.DB
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	query := `
+		SELECT trace_id, span_id, service_name, workflow_name, status, started_at, deadline, detected_at, resolved_at
+		FROM workflow_timeouts
+		WHERE service_name = ? AND (? OR resolved_at IS NULL)
+		ORDER BY started_at DESC
+		LIMIT 500;`
+
+	rows, err := db.QueryContext(ctx, query, serviceName, includeResolved)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timeouts := []WorkflowTimeout{}
+	for rows.Next() {
+		var t WorkflowTimeout
+		if err := rows.Scan(&t.TraceID, &t.SpanID, &t.ServiceName, &t.WorkflowName, &t.Status, &t.StartedAt, &t.Deadline, &t.DetectedAt, &t.ResolvedAt); err != nil {
+			return nil, err
+		}
+		timeouts = append(timeouts, t)
+	}
+	return timeouts, rows.Err()
+}
@@ -0,0 +1,40 @@
+package sla
+
+import (
+	"context"
+	"log/slog"
+
+	"junjo-server/webhook"
+)
+
+// notify alerts owners that a workflow execution missed its SLA.
+// The event is always logged, and is additionally POSTed as JSON to the
+// notifier's webhook when set. Delivery is best-effort and never blocks the caller.
+func notify(notifier *webhook.Notifier, timeout WorkflowTimeout) {
+	workflowName := ""
+	if timeout.WorkflowName != nil {
+		workflowName = *timeout.WorkflowName
+	}
+
+	slog.Warn("workflow SLA missed",
+		"status", timeout.Status,
+		"service", timeout.ServiceName,
+		"workflow", workflowName,
+		"trace_id", timeout.TraceID,
+		"started_at", timeout.StartedAt,
+		"deadline", timeout.Deadline,
+	)
+
+	if !notifier.Enabled() {
+		return
+	}
+	go func() {
+		err := notifier.Send(context.Background(), map[string]any{
+			"event":   "workflow.sla_missed",
+			"timeout": timeout,
+		})
+		if err != nil {
+			slog.Error("failed to send SLA notification", "error", err)
+		}
+	}()
+}
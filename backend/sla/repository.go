@@ -0,0 +1,35 @@
+package sla
+
+import (
+	"context"
+	"junjo-server/db"
+	"junjo-server/db_gen"
+)
+
+// UpsertSLA creates or updates the SLA for a service / workflow pair.
+func UpsertSLA(ctx context.Context, serviceName string, workflowName string, expectedDurationMs int64) (db_gen.WorkflowSla, error) {
+	queries := db_gen.New(db.DB)
+	return queries.UpsertWorkflowSLA(ctx, db_gen.UpsertWorkflowSLAParams{
+		ServiceName:        serviceName,
+		WorkflowName:       workflowName,
+		ExpectedDurationMs: expectedDurationMs,
+	})
+}
+
+// ListSLAs retrieves all configured SLAs.
+func ListSLAs(ctx context.Context) ([]db_gen.WorkflowSla, error) {
+	queries := db_gen.New(db.DB)
+	return queries.ListWorkflowSLAs(ctx)
+}
+
+// GetSLA retrieves a single SLA by id.
+func GetSLA(ctx context.Context, id int64) (db_gen.WorkflowSla, error) {
+	queries := db_gen.New(db.DB)
+	return queries.GetWorkflowSLA(ctx, id)
+}
+
+// DeleteSLA removes an SLA by id.
+func DeleteSLA(ctx context.Context, id int64) error {
+	queries := db_gen.New(db.DB)
+	return queries.DeleteWorkflowSLA(ctx, id)
+}
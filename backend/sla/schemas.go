@@ -0,0 +1,24 @@
+package sla
+
+import "time"
+
+// UpsertSLARequest sets the expected duration of a workflow. An empty
+// workflow_name sets the default for every workflow of the service.
+type UpsertSLARequest struct {
+	ServiceName        string `json:"service_name" validate:"required"`
+	WorkflowName       string `json:"workflow_name"`
+	ExpectedDurationMs int64  `json:"expected_duration_ms" validate:"required,gt=0"`
+}
+
+// WorkflowTimeout is a workflow execution that missed its SLA.
+type WorkflowTimeout struct {
+	TraceID      string     `json:"trace_id"`
+	SpanID       string     `json:"span_id"`
+	ServiceName  string     `json:"service_name"`
+	WorkflowName *string    `json:"workflow_name"`
+	Status       string     `json:"status"`
+	StartedAt    time.Time  `json:"started_at"`
+	Deadline     time.Time  `json:"deadline"`
+	DetectedAt   time.Time  `json:"detected_at"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+}
@@ -0,0 +1,88 @@
+package sla
+
+import (
+	"database/sql"
+	"errors"
+	"junjo-server/apierror"
+	"junjo-server/db_gen"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleListSLAs lists all configured workflow SLAs.
+func HandleListSLAs(c echo.Context) error {
+	slas, err := ListSLAs(c.Request().Context())
+	if err != nil {
+		c.Logger().Error("Failed to list workflow SLAs:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve workflow SLAs")
+	}
+
+	// Return empty list instead of null if no SLAs exist
+	if slas == nil {
+		slas = []db_gen.WorkflowSla{}
+	}
+
+	return c.JSON(http.StatusOK, slas)
+}
+
+// HandleUpsertSLA creates or updates a workflow SLA.
+func HandleUpsertSLA(c echo.Context) error {
+	var req UpsertSLARequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed: "+err.Error())
+	}
+
+	sla, err := UpsertSLA(c.Request().Context(), req.ServiceName, req.WorkflowName, req.ExpectedDurationMs)
+	if err != nil {
+		c.Logger().Error("Failed to save workflow SLA:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save workflow SLA")
+	}
+
+	return c.JSON(http.StatusOK, sla)
+}
+
+// HandleDeleteSLA deletes a workflow SLA by id.
+func HandleDeleteSLA(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid SLA id")
+	}
+
+	if _, err := GetSLA(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "Workflow SLA not found")
+		}
+		c.Logger().Error("Failed to look up workflow SLA:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete workflow SLA")
+	}
+
+	if err := DeleteSLA(c.Request().Context(), id); err != nil {
+		c.Logger().Error("Failed to delete workflow SLA:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete workflow SLA")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleListTimeouts lists the workflow executions of a service that missed
+// their SLA. Resolved timeouts are included when include_resolved=true.
+func HandleListTimeouts(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	if serviceName == "" {
+		return apierror.New(http.StatusBadRequest, "serviceName parameter is required")
+	}
+	includeResolved := c.QueryParam("include_resolved") == "true"
+
+	timeouts, err := ListTimeouts(c.Request().Context(), serviceName, includeResolved)
+	if err != nil {
+		c.Logger().Printf("Error listing workflow timeouts: %v", err)
+		return apierror.New(http.StatusInternalServerError, "failed to list workflow timeouts")
+	}
+
+	return c.JSON(http.StatusOK, timeouts)
+}
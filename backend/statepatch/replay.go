@@ -0,0 +1,125 @@
+package statepatch
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+)
+
+// ErrWorkflowNotFound is returned when no workflow or subflow span has the
+// requested span id.
+var ErrWorkflowNotFound = errors.New("workflow span not found")
+
+// Workflow is a workflow or subflow span and the store state it recorded.
+type Workflow struct {
+	TraceID     string
+	SpanID      string
+	ServiceName string
+	Name        string
+	StoreID     string
+	StartTime   time.Time
+	EndTime     time.Time
+	StateStart  string
+	StateEnd    string
+}
+
+// Patch is a set_state patch applied to a workflow's store.
+type Patch struct {
+	PatchID   string    `json:"patch_id"`
+	SpanID    string    `json:"span_id"`
+	EventTime time.Time `json:"event_time"`
+	PatchJSON string    `json:"-"`
+}
+
+// queryWorkflow selects a workflow or subflow span by span id.
+const queryWorkflow = `
+	SELECT trace_id, span_id, service_name, COALESCE(name, ''), COALESCE(junjo_wf_store_id, ''), start_time, end_time,
+		COALESCE(junjo_wf_state_start::VARCHAR, '{}'), COALESCE(junjo_wf_state_end::VARCHAR, '{}')
+	FROM spans
+	WHERE span_id = ? AND junjo_span_type IN ('workflow', 'subflow')
+	LIMIT 1;`
+
+// queryPatches selects the patches applied to a store, in the order they were
+// applied. Patches are matched by store rather than span so that subflows
+// acting on their parent's store are included.
+const queryPatches = `
+	SELECT patch_id, span_id, event_time, patch_json::VARCHAR
+	FROM state_patches
+	WHERE trace_id = $1 AND patch_store_id = $2
+	ORDER BY event_time, patch_id;`
+
+// LoadWorkflow loads the workflow or subflow span with the given span id.
+func LoadWorkflow(ctx context.Context, spanID string) (*Workflow, error) {
+	db := db_duckdb.DB
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var wf Workflow
+	err := db.QueryRowContext(ctx, queryWorkflow, spanID).Scan(
+		&wf.TraceID, &wf.SpanID, &wf.ServiceName, &wf.Name, &wf.StoreID, &wf.StartTime, &wf.EndTime, &wf.StateStart, &wf.StateEnd,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkflowNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow span: %w", err)
+	}
+	return &wf, nil
+}
+
+// LoadPatches loads the patches applied to the workflow's store.
+func LoadPatches(ctx context.Context, wf *Workflow) ([]Patch, error) {
+	db := db_duckdb.DB
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.QueryContext(ctx, queryPatches, wf.TraceID, wf.StoreID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state patches: %w", err)
+	}
+	defer rows.Close()
+
+	patches := []Patch{}
+	for rows.Next() {
+		var patch Patch
+		if err := rows.Scan(&patch.PatchID, &patch.SpanID, &patch.EventTime, &patch.PatchJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan state patch: %w", err)
+		}
+		patches = append(patches, patch)
+	}
+	return patches, rows.Err()
+}
+
+// Replay applies the patches to the workflow's initial state, stopping after
+// the last patch recorded at or before at (all patches when at is zero). It
+// returns the reconstructed state and the number of patches applied.
+func Replay(wf *Workflow, patches []Patch, at time.Time) (interface{}, int, error) {
+	var state interface{}
+	if err := json.Unmarshal([]byte(wf.StateStart), &state); err != nil {
+		return nil, 0, fmt.Errorf("invalid initial state: %w", err)
+	}
+
+	applied := 0
+	for _, patch := range patches {
+		if !at.IsZero() && patch.EventTime.After(at) {
+			break
+		}
+		ops, err := DecodePatch(patch.PatchJSON)
+		if err != nil {
+			return nil, applied, fmt.Errorf("patch %s: %w", patch.PatchID, err)
+		}
+		state, err = Apply(state, ops)
+		if err != nil {
+			return nil, applied, fmt.Errorf("patch %s: %w", patch.PatchID, err)
+		}
+		applied++
+	}
+	return state, applied, nil
+}
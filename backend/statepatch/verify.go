@@ -0,0 +1,61 @@
+package statepatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"junjo-server/rundiff"
+)
+
+// Verification statuses.
+const (
+	StatusOK       = "ok"       // Replaying the patches gives the final state.
+	StatusMismatch = "mismatch" // The patches applied, but the result differs from the final state.
+	StatusInvalid  = "invalid"  // A patch could not be applied.
+)
+
+// Verification is the result of checking a workflow's patch chain.
+type Verification struct {
+	Status          string
+	PatchCount      int
+	MismatchedPaths []string
+	Detail          string
+}
+
+// Verify replays every patch over the workflow's initial state and checks that
+// the result matches its recorded final state. A mismatch or a patch that
+// cannot be applied means patches are missing or were recorded out of order.
+func Verify(wf *Workflow, patches []Patch) Verification {
+	v := Verification{Status: StatusOK, PatchCount: len(patches), MismatchedPaths: []string{}}
+
+	replayed, applied, err := Replay(wf, patches, time.Time{})
+	if err != nil {
+		v.Status = StatusInvalid
+		v.Detail = fmt.Sprintf("replay stopped after %d of %d patches: %v", applied, len(patches), err)
+		return v
+	}
+
+	var final interface{}
+	if err := json.Unmarshal([]byte(wf.StateEnd), &final); err != nil {
+		v.Status = StatusInvalid
+		v.Detail = fmt.Sprintf("invalid final state: %v", err)
+		return v
+	}
+	if reflect.DeepEqual(replayed, final) {
+		return v
+	}
+
+	v.Status = StatusMismatch
+	replayedObject, replayedOK := replayed.(map[string]interface{})
+	finalObject, finalOK := final.(map[string]interface{})
+	if !replayedOK || !finalOK {
+		v.MismatchedPaths = append(v.MismatchedPaths, "$")
+		return v
+	}
+	for _, change := range rundiff.DiffObjects(replayedObject, finalObject) {
+		v.MismatchedPaths = append(v.MismatchedPaths, change.Path)
+	}
+	return v
+}
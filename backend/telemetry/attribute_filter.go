@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"strings"
+	"sync"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Attribute filter modes.
+const (
+	FilterAllow = "allow" // Only the listed attribute keys are kept.
+	FilterDeny  = "deny"  // The listed attribute keys are dropped.
+)
+
+// AttributeFilter keeps or drops span and event attributes by key at ingest. A
+// key ending in * matches any key with that prefix. junjo.* attributes are
+// always kept, as workflows depend on them.
+type AttributeFilter struct {
+	Mode string
+	Keys []string
+}
+
+// matches reports whether key is listed by the filter.
+func (f AttributeFilter) matches(key string) bool {
+	for _, pattern := range f.Keys {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// keep reports whether the attribute with key is stored.
+func (f AttributeFilter) keep(key string) bool {
+	if strings.HasPrefix(key, "junjo.") {
+		return true
+	}
+	if f.Mode == FilterAllow {
+		return f.matches(key)
+	}
+	return !f.matches(key)
+}
+
+var (
+	attributeFiltersMu sync.RWMutex
+	attributeFilters   map[string]AttributeFilter
+)
+
+// SetAttributeFilters replaces the attribute filters applied to spans indexed
+// afterwards, by service name. The filter of the empty service name applies to
+// services without their own.
+func SetAttributeFilters(filters map[string]AttributeFilter) {
+	attributeFiltersMu.Lock()
+	defer attributeFiltersMu.Unlock()
+	attributeFilters = filters
+}
+
+// filterAttributes returns span without the attributes dropped by the filter
+// of its service. The span is not modified; a filtered copy is returned when
+// needed.
+func filterAttributes(serviceName string, span *tracepb.Span) *tracepb.Span {
+	attributeFiltersMu.RLock()
+	filter, ok := attributeFilters[serviceName]
+	if !ok {
+		filter, ok = attributeFilters[""]
+	}
+	attributeFiltersMu.RUnlock()
+	if !ok {
+		return span
+	}
+
+	span = proto.Clone(span).(*tracepb.Span)
+	span.Attributes = filter.apply(span.Attributes)
+	for _, event := range span.Events {
+		event.Attributes = filter.apply(event.Attributes)
+	}
+	return span
+}
+
+func (f AttributeFilter) apply(attributes []*commonpb.KeyValue) []*commonpb.KeyValue {
+	kept := make([]*commonpb.KeyValue, 0, len(attributes))
+	for _, attr := range attributes {
+		if f.keep(attr.Key) {
+			kept = append(kept, attr)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,60 @@
+package telemetry
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// defaultEndUserAttributes are the OpenTelemetry and OpenInference end-user
+// identifier keys.
+var defaultEndUserAttributes = []string{"enduser.id", "user.id"}
+
+var (
+	endUserMu         sync.RWMutex
+	endUserAttributes = defaultEndUserAttributes
+)
+
+// SetEndUserAttributes sets the comma-separated attribute keys the end-user
+// identifier of a span is read from, in order of preference. An empty list
+// restores the defaults.
+func SetEndUserAttributes(keys string) {
+	attributes := []string{}
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			attributes = append(attributes, key)
+		}
+	}
+	if len(attributes) == 0 {
+		attributes = defaultEndUserAttributes
+	}
+
+	endUserMu.Lock()
+	defer endUserMu.Unlock()
+	endUserAttributes = attributes
+}
+
+// extractEndUserID reads the end-user identifier of a span. Integer
+// identifiers are stored in their decimal form.
+func extractEndUserID(attributes []*commonpb.KeyValue) sql.NullString {
+	if columnDemoted("spans", "enduser_id") {
+		return sql.NullString{}
+	}
+
+	endUserMu.RLock()
+	keys := endUserAttributes
+	endUserMu.RUnlock()
+
+	switch value := findAttribute(attributes, keys...).GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		if value.StringValue != "" {
+			return sql.NullString{String: value.StringValue, Valid: true}
+		}
+	case *commonpb.AnyValue_IntValue:
+		return sql.NullString{String: strconv.FormatInt(value.IntValue, 10), Valid: true}
+	}
+	return sql.NullString{}
+}
@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"database/sql"
+	"sync"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// Experiment is an A/B experiment of a service. Spans are assigned to a
+// variant at ingest.
+type Experiment struct {
+	Name     string
+	Variants []ExperimentVariant
+}
+
+// ExperimentVariant is a variant of an experiment. A span matches the variant
+// when every non-empty field equals the span's prompt id, prompt version and
+// request model; a variant without any of them only matches spans tagged with
+// it explicitly.
+type ExperimentVariant struct {
+	Name          string
+	PromptID      string
+	PromptVersion string
+	Model         string
+}
+
+var (
+	experimentsMu sync.RWMutex
+	experiments   map[string][]Experiment
+)
+
+// SetExperiments replaces the experiments spans indexed afterwards are
+// assigned to, by service name.
+func SetExperiments(byService map[string][]Experiment) {
+	experimentsMu.Lock()
+	defer experimentsMu.Unlock()
+	experiments = byService
+}
+
+func (v ExperimentVariant) matches(promptID, promptVersion, model sql.NullString) bool {
+	if v.PromptID == "" && v.PromptVersion == "" && v.Model == "" {
+		return false
+	}
+	if v.PromptID != "" && (!promptID.Valid || promptID.String != v.PromptID) {
+		return false
+	}
+	if v.PromptVersion != "" && (!promptVersion.Valid || promptVersion.String != v.PromptVersion) {
+		return false
+	}
+	if v.Model != "" && (!model.Valid || model.String != v.Model) {
+		return false
+	}
+	return true
+}
+
+// assignExperimentVariant returns the experiment and variant of a span. The
+// junjo.experiment.id and junjo.experiment.variant attributes take precedence;
+// otherwise the span is assigned to the first variant of the service's
+// experiments it matches.
+func assignExperimentVariant(serviceName string, attributes []*commonpb.KeyValue, promptID, promptVersion, model sql.NullString) (sql.NullString, sql.NullString) {
+	experimentID := firstStringAttribute(attributes, "junjo.experiment.id")
+	variant := firstStringAttribute(attributes, "junjo.experiment.variant")
+	if experimentID.Valid && variant.Valid {
+		return experimentID, variant
+	}
+
+	experimentsMu.RLock()
+	defer experimentsMu.RUnlock()
+	for _, experiment := range experiments[serviceName] {
+		for _, v := range experiment.Variants {
+			if v.matches(promptID, promptVersion, model) {
+				return sql.NullString{String: experiment.Name, Valid: true}, sql.NullString{String: v.Name, Valid: true}
+			}
+		}
+	}
+	return sql.NullString{}, sql.NullString{}
+}
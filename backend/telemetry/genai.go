@@ -0,0 +1,110 @@
+package telemetry
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"junjo-server/pricing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// genAIAttributes are the GenAI semantic convention attributes extracted into
+// dedicated span columns. Each is read from the first of its attribute keys
+// present on the span: the OpenTelemetry gen_ai.* key, then its OpenInference
+// equivalent.
+type genAIAttributes struct {
+	System             sql.NullString
+	OperationName      sql.NullString
+	RequestModel       sql.NullString
+	ResponseModel      sql.NullString
+	ResponseID         sql.NullString
+	RequestTemperature sql.NullFloat64
+	RequestMaxTokens   sql.NullInt64
+	UsageInputTokens   sql.NullInt64
+	UsageOutputTokens  sql.NullInt64
+}
+
+// extractGenAIAttributes reads the GenAI attributes of a span.
+func extractGenAIAttributes(attributes []*commonpb.KeyValue) genAIAttributes {
+	return genAIAttributes{
+		System:             firstStringAttribute(attributes, "gen_ai.system", "gen_ai.provider.name", "llm.system", "llm.provider"),
+		OperationName:      firstStringAttribute(attributes, "gen_ai.operation.name"),
+		RequestModel:       firstStringAttribute(attributes, "gen_ai.request.model", "llm.model_name"),
+		ResponseModel:      firstStringAttribute(attributes, "gen_ai.response.model"),
+		ResponseID:         firstStringAttribute(attributes, "gen_ai.response.id"),
+		RequestTemperature: firstFloatAttribute(attributes, "gen_ai.request.temperature"),
+		RequestMaxTokens:   firstIntAttribute(attributes, "gen_ai.request.max_tokens"),
+		UsageInputTokens:   firstIntAttribute(attributes, "gen_ai.usage.input_tokens", "gen_ai.usage.prompt_tokens", "llm.token_count.prompt"),
+		UsageOutputTokens:  firstIntAttribute(attributes, "gen_ai.usage.output_tokens", "gen_ai.usage.completion_tokens", "llm.token_count.completion"),
+	}
+}
+
+// estimateCost returns the estimated cost in USD of an LLM call made at the
+// given time, priced by the response model (or the request model when the
+// response does not name one). It is NULL when the span has no token counts or
+// the model has no known price.
+func (a genAIAttributes) estimateCost(at time.Time) sql.NullFloat64 {
+	if !a.UsageInputTokens.Valid && !a.UsageOutputTokens.Valid {
+		return sql.NullFloat64{}
+	}
+	model := a.ResponseModel.String
+	if !a.ResponseModel.Valid {
+		model = a.RequestModel.String
+	}
+	cost, ok := pricing.Estimate(model, at, a.UsageInputTokens.Int64, a.UsageOutputTokens.Int64)
+	if !ok {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: cost, Valid: true}
+}
+
+// findAttribute returns the value of the first key present in attributes.
+func findAttribute(attributes []*commonpb.KeyValue, keys ...string) *commonpb.AnyValue {
+	for _, key := range keys {
+		for _, attr := range attributes {
+			if attr.Key == key && attr.Value != nil {
+				return attr.Value
+			}
+		}
+	}
+	return nil
+}
+
+func firstStringAttribute(attributes []*commonpb.KeyValue, keys ...string) sql.NullString {
+	if value, ok := findAttribute(attributes, keys...).GetValue().(*commonpb.AnyValue_StringValue); ok && value.StringValue != "" {
+		return sql.NullString{String: value.StringValue, Valid: true}
+	}
+	return sql.NullString{}
+}
+
+// firstIntAttribute reads an integer attribute, accepting doubles and numeric
+// strings as some SDKs record token counts that way.
+func firstIntAttribute(attributes []*commonpb.KeyValue, keys ...string) sql.NullInt64 {
+	switch value := findAttribute(attributes, keys...).GetValue().(type) {
+	case *commonpb.AnyValue_IntValue:
+		return sql.NullInt64{Int64: value.IntValue, Valid: true}
+	case *commonpb.AnyValue_DoubleValue:
+		return sql.NullInt64{Int64: int64(value.DoubleValue), Valid: true}
+	case *commonpb.AnyValue_StringValue:
+		if parsed, err := strconv.ParseInt(value.StringValue, 10, 64); err == nil {
+			return sql.NullInt64{Int64: parsed, Valid: true}
+		}
+	}
+	return sql.NullInt64{}
+}
+
+func firstFloatAttribute(attributes []*commonpb.KeyValue, keys ...string) sql.NullFloat64 {
+	switch value := findAttribute(attributes, keys...).GetValue().(type) {
+	case *commonpb.AnyValue_DoubleValue:
+		return sql.NullFloat64{Float64: value.DoubleValue, Valid: true}
+	case *commonpb.AnyValue_IntValue:
+		return sql.NullFloat64{Float64: float64(value.IntValue), Valid: true}
+	case *commonpb.AnyValue_StringValue:
+		if parsed, err := strconv.ParseFloat(value.StringValue, 64); err == nil {
+			return sql.NullFloat64{Float64: parsed, Valid: true}
+		}
+	}
+	return sql.NullFloat64{}
+}
@@ -0,0 +1,45 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// graphVersionUpsertQuery records a run of a workflow with a graph structure.
+const graphVersionUpsertQuery = `
+	INSERT INTO graph_versions (
+		graph_hash, service_name, workflow_name, graph_structure, node_count, edge_count,
+		first_seen, last_seen, run_count
+	) VALUES (?, ?, ?, ?, json_array_length(?::JSON, '$.nodes'), json_array_length(?::JSON, '$.edges'), ?, ?, 1)
+	ON CONFLICT (graph_hash, service_name, workflow_name) DO UPDATE SET
+		first_seen = LEAST(graph_versions.first_seen, excluded.first_seen),
+		last_seen = GREATEST(graph_versions.last_seen, excluded.last_seen),
+		run_count = graph_versions.run_count + 1;`
+
+// GraphHash returns the content hash identifying a graph structure version.
+func GraphHash(graphStructure string) string {
+	sum := sha256.Sum256([]byte(graphStructure))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordGraphVersion adds a workflow or subflow run to the graph structure
+// catalog. Runs without a graph structure are skipped.
+func recordGraphVersion(ctx context.Context, tx *sql.Tx, serviceName, workflowName, graphStructure string, startTime time.Time) error {
+	if workflowName == "" || graphStructure == "" || graphStructure == "{}" || !json.Valid([]byte(graphStructure)) {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, graphVersionUpsertQuery,
+		GraphHash(graphStructure), serviceName, workflowName, graphStructure, graphStructure, graphStructure,
+		startTime, startTime,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record graph version: %w", err)
+	}
+	return nil
+}
@@ -9,8 +9,6 @@ import (
 	"log"
 	"time"
 
-	db_duckdb "junjo-server/db_duckdb"
-
 	"github.com/google/uuid"
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1" // Import the common package
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
@@ -144,9 +142,54 @@ func convertEventsToJson(events []*tracepb.Span_Event) (string, error) {
 	return string(jsonBytes), nil
 }
 
+// convertLinksToJson converts protobuf span links to JSON, with hex encoded
+// trace and span IDs so linked spans can be looked up directly.
+func convertLinksToJson(links []*tracepb.Span_Link) (string, error) {
+	linkList := []map[string]interface{}{}
+	for _, link := range links {
+		linkMap := make(map[string]interface{})
+		linkMap["traceId"] = hex.EncodeToString(link.TraceId)
+		linkMap["spanId"] = hex.EncodeToString(link.SpanId)
+		linkMap["traceState"] = link.TraceState
+		linkMap["flags"] = link.Flags
+		linkMap["droppedAttributesCount"] = link.DroppedAttributesCount
+
+		attributesJSON, err := convertAttributesToJson(link.Attributes)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal link attributes to JSON: %w", err)
+		}
+		linkMap["attributes"] = json.RawMessage(attributesJSON)
+
+		linkList = append(linkList, linkMap)
+	}
+
+	jsonBytes, err := json.Marshal(linkList)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// errorRollupQuery sets has_error on the root and workflow spans of a trace.
+const errorRollupQuery = `
+	UPDATE spans SET has_error = TRUE
+	WHERE trace_id = ? AND (parent_span_id IS NULL OR junjo_span_type = 'workflow') AND NOT has_error;`
+
+// errorRollupSpanQuery sets has_error on a root or workflow span when its trace
+// already has an error span.
+const errorRollupSpanQuery = `
+	UPDATE spans SET has_error = TRUE
+	WHERE trace_id = $1 AND span_id = $2
+	  AND EXISTS (SELECT 1 FROM spans WHERE trace_id = $1 AND status_code = 'STATUS_CODE_ERROR');`
+
 // processSpan processes a single OpenTelemetry span and prepares it for insertion.
 // It is designed to be called within a transaction.
 func processSpan(tx *sql.Tx, ctx context.Context, service_name string, span *tracepb.Span) error {
+	// 0. Drop filtered attributes, then redact prompt and completion content
+	// and sensitive data matched by the redaction rules, before it is stored
+	span = filterAttributes(service_name, span)
+	span = redactSpan(span)
+
 	// 1. Encode IDs CORRECTLY
 	traceID := hex.EncodeToString(span.TraceId)
 	spanID := hex.EncodeToString(span.SpanId)
@@ -199,6 +242,18 @@ func processSpan(tx *sql.Tx, ctx context.Context, service_name string, span *tra
 		junjoWfStoreId = extractJSONAttribute(span.Attributes, "junjo.workflow.store.id")
 	}
 
+	// GenAI semantic convention attributes, also kept in attributes_json
+	genAI := extractGenAIAttributes(span.Attributes)
+
+	// End-user identifier, also kept in attributes_json
+	endUserID := extractEndUserID(span.Attributes)
+
+	// Prompt version, also kept in attributes_json
+	promptID, promptVersion := extractPromptVersion(span.Attributes)
+
+	// A/B experiment variant, explicit or matched by prompt version and model
+	experimentID, experimentVariant := assignExperimentVariant(service_name, span.Attributes, promptID, promptVersion, genAI.RequestModel)
+
 	// Filter out attributes_json elements that we are extracting to dedicated columns
 	filteredAttributes := []*commonpb.KeyValue{}
 	for _, attr := range span.Attributes {
@@ -220,6 +275,11 @@ func processSpan(tx *sql.Tx, ctx context.Context, service_name string, span *tra
 		return fmt.Errorf("failed to marshal events to JSON: %w", err)
 	}
 
+	linksJSON, err := convertLinksToJson(span.Links)
+	if err != nil {
+		return fmt.Errorf("failed to marshal links to JSON: %w", err)
+	}
+
 	// Handle potentially missing trace_state
 	var traceState sql.NullString
 	if span.TraceState != "" {
@@ -234,8 +294,11 @@ func processSpan(tx *sql.Tx, ctx context.Context, service_name string, span *tra
 			trace_id, span_id, parent_span_id, service_name, name, kind, start_time, end_time,
 			status_code, status_message, attributes_json, events_json, links_json,
 			trace_flags, trace_state, junjo_id, junjo_parent_id, junjo_span_type,
-			junjo_wf_state_start, junjo_wf_state_end, junjo_wf_graph_structure, junjo_wf_store_id
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+			junjo_wf_state_start, junjo_wf_state_end, junjo_wf_graph_structure, junjo_wf_store_id,
+			gen_ai_system, gen_ai_operation_name, gen_ai_request_model, gen_ai_response_model, gen_ai_response_id,
+			gen_ai_request_temperature, gen_ai_request_max_tokens, gen_ai_usage_input_tokens, gen_ai_usage_output_tokens,
+			gen_ai_cost_usd, enduser_id, junjo_prompt_id, junjo_prompt_version, junjo_experiment_id, junjo_experiment_variant
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
 
 	// log.Printf("Executing query: %s with parameters: %v", spanInsertQuery, []interface{}{
 	// 	traceID, spanID, parentSpanID, service_name, span.Name, kindStr, startTime, endTime,
@@ -244,17 +307,52 @@ func processSpan(tx *sql.Tx, ctx context.Context, service_name string, span *tra
 	// 	junjoInitialState, junjoFinalState,
 	// })
 
-	_, err = tx.ExecContext(ctx, spanInsertQuery,
+	result, err := tx.ExecContext(ctx, spanInsertQuery,
 		traceID, spanID, parentSpanID, service_name, span.Name, kindStr, startTime, endTime,
-		statusCode, statusMessage, attributesJSON, eventsJSON, "[]",
+		statusCode, statusMessage, attributesJSON, eventsJSON, linksJSON,
 		span.Flags, traceState, junjoID, junjoParentID, junjoSpanType,
 		junjoInitialState, junjoFinalState, junjoGraphStructure, junjoWfStoreId,
+		genAI.System, genAI.OperationName, genAI.RequestModel, genAI.ResponseModel, genAI.ResponseID,
+		genAI.RequestTemperature, genAI.RequestMaxTokens, genAI.UsageInputTokens, genAI.UsageOutputTokens,
+		genAI.estimateCost(startTime), endUserID, promptID, promptVersion, experimentID, experimentVariant,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert span into DuckDB: %w", err)
 	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to insert span into DuckDB: %w", err)
+	}
+
+	// Catalog the graph structure of new workflow and subflow runs
+	if inserted > 0 && (junjoSpanType == "workflow" || junjoSpanType == "subflow") {
+		if err := recordGraphVersion(ctx, tx, service_name, span.Name, junjoGraphStructure, startTime); err != nil {
+			return err
+		}
+	}
+
+	// Roll errors up to the root and workflow spans of the trace, whichever
+	// arrives first, so failing runs are listed without a join
+	if statusCode == tracepb.Status_STATUS_CODE_ERROR.String() {
+		_, err = tx.ExecContext(ctx, errorRollupQuery, traceID)
+	} else if !parentSpanID.Valid || junjoSpanType == "workflow" {
+		_, err = tx.ExecContext(ctx, errorRollupSpanQuery, traceID, spanID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to roll up trace error: %w", err)
+	}
 
-	// Insert State Patches
+	// Extract spans of registered span types into their side tables. A failed
+	// statement aborts the transaction, so the batch is rewound and retried.
+	if err := insertSpanTypes(ctx, tx, service_name, traceID, spanID, junjoSpanType, span); err != nil {
+		return err
+	}
+
+	// Insert State Patches. A span already stored had its patches inserted with
+	// it, and patch IDs are random, so inserting them again would duplicate them.
+	if inserted == 0 {
+		return nil
+	}
 	patchInsertQuery := `
 		INSERT OR IGNORE INTO state_patches (patch_id, service_name, trace_id, span_id, workflow_id, node_id, event_time, patch_json, patch_store_id)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
@@ -279,27 +377,5 @@ func processSpan(tx *sql.Tx, ctx context.Context, service_name string, span *tra
 
 // BatchProcessSpans processes a batch of OpenTelemetry spans in a single transaction.
 func BatchProcessSpans(ctx context.Context, serviceName string, spans []*tracepb.Span) error {
-	db := db_duckdb.DB
-	if db == nil {
-		return fmt.Errorf("database connection is nil")
-	}
-
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback() // Rollback on error
-
-	for _, span := range spans {
-		if err := processSpan(tx, ctx, serviceName, span); err != nil {
-			// The error is already logged in processSpan, so we just need to rollback
-			return err
-		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
+	return writeSpans(ctx, map[string][]*tracepb.Span{serviceName: spans})
 }
@@ -0,0 +1,405 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// queryTraceForExport selects the stored columns needed to rebuild OTLP spans.
+// JSON columns are cast to VARCHAR so they can be decoded without losing
+// integer precision.
+const queryTraceForExport = `
+	SELECT
+		span_id, parent_span_id, service_name, name, kind, start_time, end_time,
+		status_code, status_message, attributes_json::VARCHAR, events_json::VARCHAR, links_json::VARCHAR,
+		trace_flags, trace_state, junjo_id, junjo_parent_id, junjo_span_type,
+		junjo_wf_state_start::VARCHAR, junjo_wf_state_end::VARCHAR,
+		junjo_wf_graph_structure::VARCHAR, junjo_wf_store_id
+	FROM spans
+	WHERE trace_id = ?
+	ORDER BY start_time ASC;`
+
+// ExportTrace rebuilds an OTLP ExportTraceServiceRequest for every span of a
+// trace, grouping spans into one ResourceSpans per service. It reverses the
+// conversion done by processSpan, restoring the Junjo attributes that were
+// moved into dedicated columns. Returns nil if the trace has no spans.
+func ExportTrace(ctx context.Context, traceID string) (*coltracepb.ExportTraceServiceRequest, error) {
+	db := db_duckdb.DB
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	traceIDBytes, err := hex.DecodeString(traceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace id %q: %w", traceID, err)
+	}
+
+	rows, err := db.QueryContext(ctx, queryTraceForExport, traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace: %w", err)
+	}
+	defer rows.Close()
+
+	request := &coltracepb.ExportTraceServiceRequest{}
+	scopeSpansByService := map[string]*tracepb.ScopeSpans{}
+
+	for rows.Next() {
+		var (
+			spanID, serviceName                                 string
+			parentSpanID, name, kind, statusCode, statusMessage sql.NullString
+			attributesJSON, eventsJSON, linksJSON, traceState   sql.NullString
+			junjoID, junjoParentID, junjoSpanType               sql.NullString
+			stateStart, stateEnd, graphStructure, wfStoreID     sql.NullString
+			startTime, endTime                                  time.Time
+			traceFlags                                          sql.NullInt64
+		)
+		if err := rows.Scan(
+			&spanID, &parentSpanID, &serviceName, &name, &kind, &startTime, &endTime,
+			&statusCode, &statusMessage, &attributesJSON, &eventsJSON, &linksJSON,
+			&traceFlags, &traceState, &junjoID, &junjoParentID, &junjoSpanType,
+			&stateStart, &stateEnd, &graphStructure, &wfStoreID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan span: %w", err)
+		}
+
+		span := &tracepb.Span{
+			TraceId:           traceIDBytes,
+			Name:              name.String,
+			Kind:              restoreKind(kind.String),
+			StartTimeUnixNano: uint64(startTime.UnixNano()),
+			EndTimeUnixNano:   uint64(endTime.UnixNano()),
+			TraceState:        traceState.String,
+			Flags:             uint32(traceFlags.Int64),
+			Status: &tracepb.Status{
+				Code:    tracepb.Status_StatusCode(tracepb.Status_StatusCode_value[statusCode.String]),
+				Message: statusMessage.String,
+			},
+		}
+		if span.SpanId, err = hex.DecodeString(spanID); err != nil {
+			return nil, fmt.Errorf("invalid span id %q: %w", spanID, err)
+		}
+		if parentSpanID.Valid && parentSpanID.String != "" {
+			if span.ParentSpanId, err = hex.DecodeString(parentSpanID.String); err != nil {
+				return nil, fmt.Errorf("invalid parent span id %q: %w", parentSpanID.String, err)
+			}
+		}
+
+		if span.Attributes, err = convertJsonToAttributes(attributesJSON.String); err != nil {
+			return nil, fmt.Errorf("failed to decode attributes of span %s: %w", spanID, err)
+		}
+		span.Attributes = append(span.Attributes, junjoAttributes(junjoID, junjoParentID, junjoSpanType, stateStart, stateEnd, graphStructure, wfStoreID)...)
+
+		if span.Events, err = convertJsonToEvents(eventsJSON.String); err != nil {
+			return nil, fmt.Errorf("failed to decode events of span %s: %w", spanID, err)
+		}
+
+		if span.Links, err = convertJsonToLinks(linksJSON.String); err != nil {
+			return nil, fmt.Errorf("failed to decode links of span %s: %w", spanID, err)
+		}
+
+		scopeSpans, ok := scopeSpansByService[serviceName]
+		if !ok {
+			scopeSpans = &tracepb.ScopeSpans{}
+			scopeSpansByService[serviceName] = scopeSpans
+			request.ResourceSpans = append(request.ResourceSpans, &tracepb.ResourceSpans{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{stringKeyValue("service.name", serviceName)},
+				},
+				ScopeSpans: []*tracepb.ScopeSpans{scopeSpans},
+			})
+		}
+		scopeSpans.Spans = append(scopeSpans.Spans, span)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read spans: %w", err)
+	}
+
+	if len(request.ResourceSpans) == 0 {
+		return nil, nil
+	}
+	return request, nil
+}
+
+// restoreKind is the inverse of convertKind.
+func restoreKind(kind string) tracepb.Span_SpanKind {
+	switch kind {
+	case "CLIENT":
+		return 1
+	case "SERVER":
+		return 2
+	case "INTERNAL":
+		return 3
+	case "PRODUCER":
+		return 4
+	case "CONSUMER":
+		return 5
+	default:
+		return 0
+	}
+}
+
+// junjoAttributes restores the Junjo attributes that processSpan extracts into
+// dedicated columns.
+func junjoAttributes(junjoID, junjoParentID, junjoSpanType, stateStart, stateEnd, graphStructure, wfStoreID sql.NullString) []*commonpb.KeyValue {
+	attrs := []*commonpb.KeyValue{}
+	for _, attr := range []struct {
+		key   string
+		value sql.NullString
+	}{
+		{"junjo.id", junjoID},
+		{"junjo.parent_id", junjoParentID},
+		{"junjo.span_type", junjoSpanType},
+	} {
+		if attr.value.Valid && attr.value.String != "" {
+			attrs = append(attrs, stringKeyValue(attr.key, attr.value.String))
+		}
+	}
+
+	if junjoSpanType.String != "workflow" && junjoSpanType.String != "subflow" {
+		return attrs
+	}
+	for _, attr := range []struct {
+		key   string
+		value sql.NullString
+	}{
+		{"junjo.workflow.state.start", stateStart},
+		{"junjo.workflow.state.end", stateEnd},
+		{"junjo.workflow.graph_structure", graphStructure},
+		{"junjo.workflow.store.id", wfStoreID},
+	} {
+		if attr.value.Valid {
+			attrs = append(attrs, stringKeyValue(attr.key, attr.value.String))
+		}
+	}
+	return attrs
+}
+
+// convertJsonToAttributes is the inverse of convertAttributesToJson.
+// Byte values were hex encoded on the way in and are restored as strings.
+func convertJsonToAttributes(attributesJSON string) ([]*commonpb.KeyValue, error) {
+	if attributesJSON == "" {
+		return nil, nil
+	}
+
+	var attrMap map[string]interface{}
+	if err := decodeJSON(attributesJSON, &attrMap); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(attrMap))
+	for key := range attrMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]*commonpb.KeyValue, 0, len(attrMap))
+	for _, key := range keys {
+		attrs = append(attrs, &commonpb.KeyValue{Key: key, Value: convertJsonToAnyValue(attrMap[key])})
+	}
+	return attrs, nil
+}
+
+// convertJsonToAnyValue converts a decoded JSON value into an OTLP AnyValue.
+func convertJsonToAnyValue(value interface{}) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		}
+		f, _ := v.Float64()
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, 0, len(v))
+		for _, item := range v {
+			values = append(values, convertJsonToAnyValue(item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]interface{}:
+		values := make([]*commonpb.KeyValue, 0, len(v))
+		for key, item := range v {
+			values = append(values, &commonpb.KeyValue{Key: key, Value: convertJsonToAnyValue(item)})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: values}}}
+	default:
+		return &commonpb.AnyValue{}
+	}
+}
+
+// convertJsonToEvents is the inverse of convertEventsToJson.
+func convertJsonToEvents(eventsJSON string) ([]*tracepb.Span_Event, error) {
+	if eventsJSON == "" {
+		return nil, nil
+	}
+
+	var eventList []struct {
+		Name                   string          `json:"name"`
+		TimeUnixNano           uint64          `json:"timeUnixNano"`
+		DroppedAttributesCount uint32          `json:"droppedAttributesCount"`
+		Attributes             json.RawMessage `json:"attributes"`
+	}
+	if err := json.Unmarshal([]byte(eventsJSON), &eventList); err != nil {
+		return nil, err
+	}
+
+	events := make([]*tracepb.Span_Event, 0, len(eventList))
+	for _, e := range eventList {
+		attrs, err := convertJsonToAttributes(string(e.Attributes))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &tracepb.Span_Event{
+			Name:                   e.Name,
+			TimeUnixNano:           e.TimeUnixNano,
+			DroppedAttributesCount: e.DroppedAttributesCount,
+			Attributes:             attrs,
+		})
+	}
+	return events, nil
+}
+
+// convertJsonToLinks is the inverse of convertLinksToJson.
+func convertJsonToLinks(linksJSON string) ([]*tracepb.Span_Link, error) {
+	if linksJSON == "" {
+		return nil, nil
+	}
+
+	var linkList []struct {
+		TraceID                string          `json:"traceId"`
+		SpanID                 string          `json:"spanId"`
+		TraceState             string          `json:"traceState"`
+		Flags                  uint32          `json:"flags"`
+		DroppedAttributesCount uint32          `json:"droppedAttributesCount"`
+		Attributes             json.RawMessage `json:"attributes"`
+	}
+	if err := json.Unmarshal([]byte(linksJSON), &linkList); err != nil {
+		return nil, err
+	}
+
+	links := make([]*tracepb.Span_Link, 0, len(linkList))
+	for _, l := range linkList {
+		traceID, err := hex.DecodeString(l.TraceID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link trace id %q: %w", l.TraceID, err)
+		}
+		spanID, err := hex.DecodeString(l.SpanID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link span id %q: %w", l.SpanID, err)
+		}
+		attrs, err := convertJsonToAttributes(string(l.Attributes))
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, &tracepb.Span_Link{
+			TraceId:                traceID,
+			SpanId:                 spanID,
+			TraceState:             l.TraceState,
+			Flags:                  l.Flags,
+			DroppedAttributesCount: l.DroppedAttributesCount,
+			Attributes:             attrs,
+		})
+	}
+	return links, nil
+}
+
+// MarshalOTLPJSON encodes an ExportTraceServiceRequest using the OTLP/JSON
+// encoding: lowerCamelCase field names, integer enums, and hex (rather than
+// base64) trace and span IDs.
+func MarshalOTLPJSON(request *coltracepb.ExportTraceServiceRequest) ([]byte, error) {
+	raw, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if err := rewriteIDs(doc, base64ToHex); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// rewriteIDs applies convert to every trace, span, and parent span ID in a
+// decoded OTLP/JSON trace document, including span links.
+func rewriteIDs(doc map[string]interface{}, convert func(string) (string, error)) error {
+	rewrite := func(obj map[string]interface{}) error {
+		for _, key := range []string{"traceId", "spanId", "parentSpanId"} {
+			if id, ok := obj[key].(string); ok {
+				converted, err := convert(id)
+				if err != nil {
+					return fmt.Errorf("invalid %s %q: %w", key, id, err)
+				}
+				obj[key] = converted
+			}
+		}
+		return nil
+	}
+
+	for _, rs := range asList(doc["resourceSpans"]) {
+		for _, ss := range asList(asMap(rs)["scopeSpans"]) {
+			for _, s := range asList(asMap(ss)["spans"]) {
+				span := asMap(s)
+				if err := rewrite(span); err != nil {
+					return err
+				}
+				for _, link := range asList(span["links"]) {
+					if err := rewrite(asMap(link)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func base64ToHex(id string) (string, error) {
+	var b []byte
+	if err := json.Unmarshal([]byte(`"`+id+`"`), &b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
+
+// decodeJSON decodes s into v, keeping numbers as json.Number.
+func decodeJSON(s string, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
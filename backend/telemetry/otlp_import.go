@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// importBatchSize caps the number of spans written per transaction during an
+// import, so a large file doesn't hold one huge DuckDB transaction open.
+const importBatchSize = 500
+
+// UnmarshalOTLPJSON decodes an OTLP/JSON ExportTraceServiceRequest, which
+// encodes trace and span IDs as hex rather than protobuf JSON's base64.
+func UnmarshalOTLPJSON(data []byte) (*coltracepb.ExportTraceServiceRequest, error) {
+	var doc map[string]interface{}
+	if err := decodeJSON(string(data), &doc); err != nil {
+		return nil, err
+	}
+	if err := rewriteIDs(doc, hexToBase64); err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &coltracepb.ExportTraceServiceRequest{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// ImportTraces writes every span of an ExportTraceServiceRequest directly into
+// DuckDB with BatchProcessSpans, bypassing the ingestion WAL. It returns the
+// number of spans imported. Spans that already exist are left unchanged, so
+// re-running an import is safe.
+func ImportTraces(ctx context.Context, request *coltracepb.ExportTraceServiceRequest) (int, error) {
+	imported := 0
+	for _, resourceSpans := range request.ResourceSpans {
+		serviceName := ""
+		if resourceSpans.Resource != nil {
+			serviceName = extractStringAttribute(resourceSpans.Resource.Attributes, "service.name")
+		}
+		if serviceName == "" {
+			serviceName = "NO_SERVICE_NAME"
+		}
+
+		var spans []*tracepb.Span
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			spans = append(spans, scopeSpans.Spans...)
+		}
+
+		for start := 0; start < len(spans); start += importBatchSize {
+			end := min(start+importBatchSize, len(spans))
+			if err := BatchProcessSpans(ctx, serviceName, spans[start:end]); err != nil {
+				return imported, fmt.Errorf("failed to import spans for service %s: %w", serviceName, err)
+			}
+			imported += end - start
+		}
+	}
+	return imported, nil
+}
+
+func hexToBase64(id string) (string, error) {
+	b, err := hex.DecodeString(id)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
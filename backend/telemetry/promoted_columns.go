@@ -0,0 +1,48 @@
+package telemetry
+
+import "sync"
+
+// PromotedColumn is a span attribute stored in a column of its own, in
+// addition to attributes_json: the end-user identifier and the columns of
+// span types.
+type PromotedColumn struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+var (
+	demotedMu      sync.RWMutex
+	demotedColumns = map[PromotedColumn]bool{}
+)
+
+// PromotedColumns returns the promoted columns of the spans table and of the
+// registered span types.
+func PromotedColumns() []PromotedColumn {
+	columns := []PromotedColumn{{Table: "spans", Column: "enduser_id"}}
+	for _, t := range spanTypes {
+		for _, column := range t.Columns {
+			columns = append(columns, PromotedColumn{Table: t.Table, Column: column.Name})
+		}
+	}
+	return columns
+}
+
+// SetColumnDemoted demotes a promoted column, or promotes it again. The
+// column of a demoted attribute is left empty on new spans; the attribute is
+// still stored in attributes_json.
+func SetColumnDemoted(table, column string, demoted bool) {
+	demotedMu.Lock()
+	defer demotedMu.Unlock()
+	if demoted {
+		demotedColumns[PromotedColumn{Table: table, Column: column}] = true
+	} else {
+		delete(demotedColumns, PromotedColumn{Table: table, Column: column})
+	}
+}
+
+// columnDemoted reports whether a promoted column is demoted.
+func columnDemoted(table, column string) bool {
+	demotedMu.RLock()
+	defer demotedMu.RUnlock()
+	return demotedColumns[PromotedColumn{Table: table, Column: column}]
+}
@@ -0,0 +1,28 @@
+package telemetry
+
+import (
+	"database/sql"
+	"strconv"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// extractPromptVersion reads the prompt id and version a span was produced
+// with. Integer versions are stored in their decimal form. The version is only
+// kept with a prompt id.
+func extractPromptVersion(attributes []*commonpb.KeyValue) (sql.NullString, sql.NullString) {
+	promptID := firstStringAttribute(attributes, "junjo.prompt.id")
+	if !promptID.Valid {
+		return promptID, sql.NullString{}
+	}
+
+	switch value := findAttribute(attributes, "junjo.prompt.version").GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		if value.StringValue != "" {
+			return promptID, sql.NullString{String: value.StringValue, Valid: true}
+		}
+	case *commonpb.AnyValue_IntValue:
+		return promptID, sql.NullString{String: strconv.FormatInt(value.IntValue, 10), Valid: true}
+	}
+	return promptID, sql.NullString{}
+}
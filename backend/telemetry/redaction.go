@@ -0,0 +1,188 @@
+package telemetry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content redaction modes.
+const (
+	RedactionOff      = "off"      // Prompts and completions are stored as received.
+	RedactionHash     = "hash"     // Replaced with their SHA-256 hash.
+	RedactionTruncate = "truncate" // Cut to the configured length.
+)
+
+// RedactionConfig controls how prompt and completion content is stored.
+type RedactionConfig struct {
+	Mode string
+	// TruncateLength is the number of characters kept in truncate mode.
+	TruncateLength int
+}
+
+// Redaction rule kinds.
+const (
+	RuleKindValue = "value" // Pattern matches within string values; matches are replaced.
+	RuleKindKey   = "key"   // Pattern matches attribute keys; the whole value is replaced.
+)
+
+// RedactionRule masks sensitive data, such as emails, phone numbers or API
+// keys, in span and event attributes before they are stored.
+type RedactionRule struct {
+	Name        string
+	Kind        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+var (
+	redactionMu    sync.RWMutex
+	redaction      = RedactionConfig{Mode: RedactionOff}
+	redactionRules []RedactionRule
+)
+
+// SetRedactionConfig sets how prompt and completion content of spans indexed
+// afterwards is stored.
+func SetRedactionConfig(cfg RedactionConfig) {
+	redactionMu.Lock()
+	defer redactionMu.Unlock()
+	redaction = cfg
+}
+
+// SetRedactionRules replaces the redaction rules applied to spans indexed
+// afterwards.
+func SetRedactionRules(rules []RedactionRule) {
+	redactionMu.Lock()
+	defer redactionMu.Unlock()
+	redactionRules = rules
+}
+
+// contentAttributePrefixes are the GenAI and OpenInference attributes holding
+// prompt or completion content. An attribute holds content when its key equals
+// a prefix or continues it with a dot.
+var contentAttributePrefixes = []string{
+	"gen_ai.prompt",
+	"gen_ai.completion",
+	"gen_ai.input.messages",
+	"gen_ai.output.messages",
+	"gen_ai.system_instructions",
+	"input.value",
+	"output.value",
+	"llm.prompts",
+	"llm.input_messages",
+	"llm.output_messages",
+}
+
+// isContentAttribute reports whether an attribute holds prompt or completion
+// content. GenAI events (gen_ai.user.message, gen_ai.choice, ...) also carry
+// it in their content attribute.
+func isContentAttribute(key string, genAIEvent bool) bool {
+	if genAIEvent && key == "content" {
+		return true
+	}
+	for _, prefix := range contentAttributePrefixes {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSpan returns span with its prompt and completion content redacted
+// according to the configured mode, and the redaction rules applied to its
+// attributes and event attributes. Token counts and other metadata are kept.
+// The span is not modified; a redacted copy is returned when needed.
+func redactSpan(span *tracepb.Span) *tracepb.Span {
+	redactionMu.RLock()
+	cfg, rules := redaction, redactionRules
+	redactionMu.RUnlock()
+	if cfg.Mode == RedactionOff && len(rules) == 0 {
+		return span
+	}
+
+	span = proto.Clone(span).(*tracepb.Span)
+	redactAttributes(cfg, rules, span.Attributes, false)
+	for _, event := range span.Events {
+		redactAttributes(cfg, rules, event.Attributes, strings.HasPrefix(event.Name, "gen_ai."))
+	}
+	return span
+}
+
+// RedactAttribute returns a string span attribute value as it would be
+// stored.
+func RedactAttribute(key, value string) string {
+	redactionMu.RLock()
+	cfg, rules := redaction, redactionRules
+	redactionMu.RUnlock()
+
+	attributes := []*commonpb.KeyValue{{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}}
+	redactAttributes(cfg, rules, attributes, false)
+	return attributes[0].Value.GetStringValue()
+}
+
+func redactAttributes(cfg RedactionConfig, rules []RedactionRule, attributes []*commonpb.KeyValue, genAIEvent bool) {
+	for _, attr := range attributes {
+		if attr.Value == nil {
+			continue
+		}
+		if cfg.Mode != RedactionOff && isContentAttribute(attr.Key, genAIEvent) {
+			attr.Value = mapStrings(attr.Value, func(content string) string {
+				return redactString(cfg, content)
+			})
+		}
+		for _, rule := range rules {
+			switch rule.Kind {
+			case RuleKindKey:
+				if rule.Pattern.MatchString(attr.Key) {
+					attr.Value = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: rule.Replacement}}
+				}
+			case RuleKindValue:
+				attr.Value = mapStrings(attr.Value, func(value string) string {
+					return rule.Pattern.ReplaceAllLiteralString(value, rule.Replacement)
+				})
+			}
+		}
+	}
+}
+
+// mapStrings applies fn to string values, including those nested in array and
+// key-value list values. Other values are kept.
+func mapStrings(value *commonpb.AnyValue, fn func(string) string) *commonpb.AnyValue {
+	switch v := value.Value.(type) {
+	case *commonpb.AnyValue_StringValue:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fn(v.StringValue)}}
+	case *commonpb.AnyValue_ArrayValue:
+		for i, item := range v.ArrayValue.GetValues() {
+			v.ArrayValue.Values[i] = mapStrings(item, fn)
+		}
+	case *commonpb.AnyValue_KvlistValue:
+		for _, kv := range v.KvlistValue.GetValues() {
+			if kv.Value != nil {
+				kv.Value = mapStrings(kv.Value, fn)
+			}
+		}
+	}
+	return value
+}
+
+func redactString(cfg RedactionConfig, content string) string {
+	switch cfg.Mode {
+	case RedactionHash:
+		sum := sha256.Sum256([]byte(content))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	case RedactionTruncate:
+		runes := []rune(content)
+		if len(runes) <= cfg.TruncateLength {
+			return content
+		}
+		return fmt.Sprintf("%s…[truncated %d characters]", string(runes[:cfg.TruncateLength]), len(runes)-cfg.TruncateLength)
+	}
+	return content
+}
@@ -0,0 +1,312 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// identifierPattern restricts span type table and column names, which are
+// inlined into SQL.
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// reservedTablePrefixes are used by DuckDB's catalog functions and views.
+var reservedTablePrefixes = []string{"duckdb_", "sqlite_", "pragma_", "pg_"}
+
+// reservedTableNames are DuckDB schema and catalog names.
+var reservedTableNames = map[string]bool{
+	"information_schema": true,
+	"main":               true,
+	"temp":               true,
+	"system":             true,
+}
+
+// spanTypeColumnTypes are the DuckDB types a span type column can have.
+var spanTypeColumnTypes = map[string]bool{
+	"VARCHAR": true,
+	"BIGINT":  true,
+	"DOUBLE":  true,
+	"BOOLEAN": true,
+	"JSON":    true,
+}
+
+// spanTypeBaseColumns are stored for every span of every span type.
+const spanTypeBaseColumns = `
+	trace_id VARCHAR(32) NOT NULL,
+	span_id VARCHAR(16) NOT NULL,
+	service_name VARCHAR NOT NULL,
+	name VARCHAR,
+	start_time TIMESTAMPTZ NOT NULL,
+	end_time TIMESTAMPTZ NOT NULL,
+	duration_ms BIGINT,
+	status_code VARCHAR,
+	status_message VARCHAR`
+
+// SpanType describes a custom span type whose spans are extracted into a side
+// table, in addition to the spans table. A span belongs to the type when its
+// junjo.span_type attribute equals Name, or when it has the Match attribute.
+type SpanType struct {
+	Name    string           `json:"name"`
+	Table   string           `json:"table"`
+	Match   *AttributeMatch  `json:"match,omitempty"`
+	Columns []SpanTypeColumn `json:"columns"`
+
+	insertQuery string
+}
+
+// AttributeMatch matches spans with an attribute equal to Value.
+type AttributeMatch struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+}
+
+// SpanTypeColumn is a side table column extracted from the first of
+// Attributes present on the span.
+type SpanTypeColumn struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Attributes []string `json:"attributes"`
+}
+
+// spanTypes are the registered span types.
+var spanTypes []*SpanType
+
+// LoadSpanTypes reads span type definitions from a JSON file containing a list
+// of SpanType. An empty path loads none.
+func LoadSpanTypes(path string) ([]SpanType, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read span types from %s: %w", path, err)
+	}
+	var types []SpanType
+	if err := json.Unmarshal(data, &types); err != nil {
+		return nil, fmt.Errorf("failed to parse span types from %s: %w", path, err)
+	}
+	return types, nil
+}
+
+// RegisterSpanTypes validates the span types, creates or extends their side
+// tables, and extracts matching spans into them from then on. Every span type
+// is validated before any table is touched: a span type naming a core table,
+// or the name or table of another span type, fails them all.
+func RegisterSpanTypes(ctx context.Context, types ...SpanType) error {
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	names := map[string]bool{}
+	tables := map[string]bool{}
+	for _, t := range spanTypes {
+		names[t.Name] = true
+		tables[t.Table] = true
+	}
+	for i := range types {
+		t := &types[i]
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("invalid span type %q: %w", t.Name, err)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("invalid span type %q: duplicate span type name", t.Name)
+		}
+		if tables[t.Table] {
+			return fmt.Errorf("invalid span type %q: table %q belongs to another span type", t.Name, t.Table)
+		}
+		names[t.Name] = true
+		tables[t.Table] = true
+	}
+
+	for _, t := range types {
+		t := t
+		columns := []string{}
+		for _, column := range t.Columns {
+			columns = append(columns, column.Name+" "+column.Type)
+		}
+		schema := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s, %s, PRIMARY KEY (trace_id, span_id));", t.Table, spanTypeBaseColumns, strings.Join(columns, ", "))
+		if _, err := db.ExecContext(ctx, schema); err != nil {
+			return fmt.Errorf("failed to create table for span type %q: %w", t.Name, err)
+		}
+		// Columns added to the definition after the table was created.
+		for _, column := range columns {
+			if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s;", t.Table, column)); err != nil {
+				return fmt.Errorf("failed to add column to span type %q: %w", t.Name, err)
+			}
+		}
+
+		columnNames := []string{"trace_id", "span_id", "service_name", "name", "start_time", "end_time", "duration_ms", "status_code", "status_message"}
+		for _, column := range t.Columns {
+			columnNames = append(columnNames, column.Name)
+		}
+		t.insertQuery = fmt.Sprintf("INSERT OR IGNORE INTO %s (%s) VALUES (%s);", t.Table, strings.Join(columnNames, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columnNames)), ", "))
+
+		spanTypes = append(spanTypes, &t)
+	}
+	return nil
+}
+
+// SpanTypeTables returns the side tables of the registered span types.
+func SpanTypeTables() []string {
+	tables := make([]string, 0, len(spanTypes))
+	for _, t := range spanTypes {
+		tables = append(tables, t.Table)
+	}
+	return tables
+}
+
+func (t *SpanType) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !identifierPattern.MatchString(t.Table) {
+		return fmt.Errorf("table must match %s", identifierPattern)
+	}
+	if slices.Contains(db_duckdb.CoreTables, t.Table) || reservedTableNames[t.Table] {
+		return fmt.Errorf("table %q is reserved", t.Table)
+	}
+	for _, prefix := range reservedTablePrefixes {
+		if strings.HasPrefix(t.Table, prefix) {
+			return fmt.Errorf("table %q has the reserved prefix %s", t.Table, prefix)
+		}
+	}
+	if t.Match != nil && t.Match.Attribute == "" {
+		return fmt.Errorf("match.attribute is required")
+	}
+	seen := map[string]bool{}
+	for i, column := range t.Columns {
+		if !identifierPattern.MatchString(column.Name) {
+			return fmt.Errorf("column name must match %s", identifierPattern)
+		}
+		if strings.Contains(spanTypeBaseColumns, "\t"+column.Name+" ") || seen[column.Name] {
+			return fmt.Errorf("duplicate column %q", column.Name)
+		}
+		seen[column.Name] = true
+		t.Columns[i].Type = strings.ToUpper(column.Type)
+		if !spanTypeColumnTypes[t.Columns[i].Type] {
+			return fmt.Errorf("unsupported type %q for column %q", column.Type, column.Name)
+		}
+		if len(column.Attributes) == 0 {
+			return fmt.Errorf("column %q has no attributes", column.Name)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the span belongs to the span type.
+func (t *SpanType) matches(junjoSpanType string, span *tracepb.Span) bool {
+	if junjoSpanType == t.Name {
+		return true
+	}
+	return t.Match != nil && extractStringAttribute(span.Attributes, t.Match.Attribute) == t.Match.Value
+}
+
+// insertSpanTypes extracts the span into the side table of every span type it
+// belongs to.
+func insertSpanTypes(ctx context.Context, tx *sql.Tx, serviceName, traceID, spanID, junjoSpanType string, span *tracepb.Span) error {
+	var attributes map[string]interface{}
+	for _, t := range spanTypes {
+		if !t.matches(junjoSpanType, span) {
+			continue
+		}
+
+		if attributes == nil {
+			var err error
+			if attributes, err = attributeMap(span.Attributes); err != nil {
+				return err
+			}
+		}
+
+		startTime := time.Unix(0, int64(span.StartTimeUnixNano)).UTC()
+		endTime := time.Unix(0, int64(span.EndTimeUnixNano)).UTC()
+		var statusCode, statusMessage string
+		if span.Status != nil {
+			statusCode = span.Status.Code.String()
+			statusMessage = span.Status.Message
+		}
+		args := []interface{}{traceID, spanID, serviceName, span.Name, startTime, endTime, endTime.Sub(startTime).Milliseconds(), statusCode, statusMessage}
+		for _, column := range t.Columns {
+			if columnDemoted(t.Table, column.Name) {
+				args = append(args, nil)
+				continue
+			}
+			args = append(args, columnValue(column, attributes))
+		}
+
+		if _, err := tx.ExecContext(ctx, t.insertQuery, args...); err != nil {
+			return fmt.Errorf("failed to insert span into %s: %w", t.Table, err)
+		}
+	}
+	return nil
+}
+
+// attributeMap converts attributes to Go values the same way they are stored
+// in attributes_json.
+func attributeMap(attributes []*commonpb.KeyValue) (map[string]interface{}, error) {
+	attributesJSON, err := convertAttributesToJson(attributes)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err := decodeJSON(attributesJSON, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// columnValue returns the value of the first attribute of the column present
+// in attributes, converted to the column type, or nil.
+func columnValue(column SpanTypeColumn, attributes map[string]interface{}) interface{} {
+	for _, key := range column.Attributes {
+		value, ok := attributes[key]
+		if !ok {
+			continue
+		}
+
+		switch column.Type {
+		case "JSON":
+			// JSON documents are sent as string attributes by the SDKs.
+			if s, ok := value.(string); ok && json.Valid([]byte(s)) {
+				return s
+			}
+			data, _ := json.Marshal(value)
+			return string(data)
+		case "BIGINT":
+			if n, err := strconv.ParseInt(fmt.Sprint(value), 10, 64); err == nil {
+				return n
+			}
+			return nil
+		case "DOUBLE":
+			if f, err := strconv.ParseFloat(fmt.Sprint(value), 64); err == nil {
+				return f
+			}
+			return nil
+		case "BOOLEAN":
+			if b, err := strconv.ParseBool(fmt.Sprint(value)); err == nil {
+				return b
+			}
+			return nil
+		default:
+			if s, ok := value.(string); ok {
+				return s
+			}
+			data, _ := json.Marshal(value)
+			return string(data)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	db_duckdb "junjo-server/db_duckdb"
+	"junjo-server/metrics"
+	"junjo-server/selftrace"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// ErrWriteFailed is returned by a flush that wrote nothing because DuckDB is
+// unavailable. The pending spans are discarded and their key is not
+// committed, so the poller must resume from the last committed key to read
+// them again.
+var ErrWriteFailed = errors.New("span write failed")
+
+var skippedSpans = metrics.NewCounter("junjo_span_writer_skipped_spans_total", "Spans skipped because they could not be written.")
+
+// WriterConfig controls how polled spans are batched into DuckDB commits.
+type WriterConfig struct {
+	// MaxRows flushes the pending spans once this many have accumulated.
+	MaxRows int
+	// MaxLatency flushes the pending spans once the oldest has waited this
+	// long, bounding the time until a span is queryable.
+	MaxLatency time.Duration
+}
+
+// SpanWriter accumulates polled spans and writes them to DuckDB in a single
+// transaction once MaxRows spans are pending or the oldest pending span is
+// MaxLatency old, independently of how many spans each poll returns.
+//
+// Every batch of spans is added with the WAL key of its last span. After a
+// successful flush, the commit callback is called with the latest key so the
+// poller only resumes past spans that are durably indexed.
+type SpanWriter struct {
+	cfg    WriterConfig
+	commit func(ctx context.Context, lastKey []byte) error
+
+	mu      sync.Mutex
+	pending map[string][]*tracepb.Span // Spans by service name
+	rows    int
+	oldest  time.Time
+	lastKey []byte
+	dirty   bool // lastKey has not been committed yet
+}
+
+// NewSpanWriter creates a SpanWriter that calls commit after every flush.
+func NewSpanWriter(cfg WriterConfig, commit func(ctx context.Context, lastKey []byte) error) *SpanWriter {
+	return &SpanWriter{
+		cfg:     cfg,
+		commit:  commit,
+		pending: map[string][]*tracepb.Span{},
+	}
+}
+
+// Add queues spans of a service read up to lastKey, flushing if MaxRows is
+// reached. A nil lastKey keeps the current position, for spans released while
+// earlier spans are still buffered by the tail sampler.
+func (w *SpanWriter) Add(ctx context.Context, serviceName string, spans []*tracepb.Span, lastKey []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.rows == 0 {
+		w.oldest = time.Now()
+	}
+	w.pending[serviceName] = append(w.pending[serviceName], spans...)
+	w.rows += len(spans)
+	if lastKey != nil {
+		w.lastKey = lastKey
+		w.dirty = true
+	}
+
+	if w.rows >= w.cfg.MaxRows {
+		return w.flush(ctx)
+	}
+	return nil
+}
+
+// Advance moves the committed position past spans that were read but
+// intentionally not indexed, such as spans dropped by a quota.
+func (w *SpanWriter) Advance(lastKey []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastKey = lastKey
+	w.dirty = true
+}
+
+// FlushIfDue flushes when the oldest pending span has reached MaxLatency, or
+// when only an advanced key is pending.
+func (w *SpanWriter) FlushIfDue(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if (!w.dirty && w.rows == 0) || (w.rows > 0 && time.Since(w.oldest) < w.cfg.MaxLatency) {
+		return nil
+	}
+	return w.flush(ctx)
+}
+
+// Flush writes all pending spans.
+func (w *SpanWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.flush(ctx)
+}
+
+// flush writes the pending spans in one transaction and commits the last key.
+// When the transaction fails, the spans are written one by one so that only
+// the spans that can't be written are skipped. If DuckDB itself is failing,
+// nothing is committed and ErrWriteFailed is returned.
+func (w *SpanWriter) flush(ctx context.Context) error {
+	if !w.dirty && w.rows == 0 {
+		return nil
+	}
+
+	pending, rows, lastKey, dirty := w.pending, w.rows, w.lastKey, w.dirty
+	w.pending = map[string][]*tracepb.Span{}
+	w.rows = 0
+	w.dirty = false
+
+	if rows > 0 {
+		start := time.Now()
+		if err := writeSpans(ctx, pending); err != nil {
+			if err := writeEach(ctx, pending, err); err != nil {
+				return fmt.Errorf("%w: %d spans: %w", ErrWriteFailed, rows, err)
+			}
+		}
+		slog.Debug("flushed spans", "rows", rows, "services", len(pending), "duration", time.Since(start))
+	}
+
+	if !dirty {
+		return nil
+	}
+	if err := w.commit(ctx, lastKey); err != nil {
+		return fmt.Errorf("failed to commit poller position: %w", err)
+	}
+	return nil
+}
+
+// writeEach writes spans one by one after their batch failed with batchErr,
+// skipping the spans that fail. It returns an error, writing nothing, when
+// the database is unavailable rather than some spans invalid.
+func writeEach(ctx context.Context, spansByService map[string][]*tracepb.Span, batchErr error) error {
+	if db_duckdb.DB == nil {
+		return batchErr
+	}
+	if _, err := db_duckdb.DB.ExecContext(ctx, "SELECT 1"); err != nil {
+		return errors.Join(batchErr, err)
+	}
+
+	slog.Warn("span batch failed, writing spans one by one", "error", batchErr)
+	for serviceName, spans := range spansByService {
+		for _, span := range spans {
+			if err := writeSpans(ctx, map[string][]*tracepb.Span{serviceName: {span}}); err != nil {
+				skippedSpans.Inc()
+				slog.Error("skipping span that could not be written", "service", serviceName,
+					"trace_id", hex.EncodeToString(span.TraceId), "span_id", hex.EncodeToString(span.SpanId), "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeSpans writes the spans of every service in a single transaction.
+func writeSpans(ctx context.Context, spansByService map[string][]*tracepb.Span) (err error) {
+	db := db_duckdb.DB
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	// Writing only the backend's own spans is not traced, which would loop
+	rows, onlySelf := 0, true
+	for serviceName, spans := range spansByService {
+		rows += len(spans)
+		onlySelf = onlySelf && selftrace.IsSelf(serviceName)
+	}
+	if onlySelf {
+		ctx = selftrace.Suppress(ctx)
+	}
+	ctx, span := selftrace.Start(ctx, "duckdb.write_spans", trace.WithAttributes(
+		attribute.Int("junjo.spans", rows),
+		attribute.Int("junjo.services", len(spansByService)),
+	))
+	defer func() {
+		selftrace.RecordError(span, err)
+		span.End()
+	}()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback on error
+
+	for serviceName, spans := range spansByService {
+		for _, span := range spans {
+			if err := processSpan(tx, ctx, serviceName, span); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if handler, runs := collectWorkflowRuns(spansByService); len(runs) > 0 {
+		handler(ctx, runs)
+	}
+	return nil
+}
+
+// MaxLatency returns the configured maximum flush latency.
+func (w *SpanWriter) MaxLatency() time.Duration {
+	return w.cfg.MaxLatency
+}
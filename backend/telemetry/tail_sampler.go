@@ -0,0 +1,273 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"junjo-server/metrics"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// maxKeptTraces bounds the number of remembered kept trace ids.
+const maxKeptTraces = 10000
+
+var (
+	tailKeptTraces    = metrics.NewCounter("junjo_tail_sampling_kept_traces_total", "Traces kept by tail sampling.")
+	tailDroppedTraces = metrics.NewCounter("junjo_tail_sampling_dropped_traces_total", "Healthy traces dropped by tail sampling.")
+	tailDroppedSpans  = metrics.NewCounter("junjo_tail_sampling_dropped_spans_total", "Spans of traces dropped by tail sampling.")
+)
+
+// TailSamplingConfig controls tail-based sampling of complete traces.
+type TailSamplingConfig struct {
+	Enabled bool
+	// DecisionWait is how long a trace must go without new spans before it is
+	// considered complete and sampled.
+	DecisionWait time.Duration
+	// LatencyThreshold keeps traces lasting at least this long. Zero disables
+	// the latency check.
+	LatencyThreshold time.Duration
+	// KeepRate is the fraction of healthy traces kept.
+	KeepRate float64
+	// MaxTraces bounds the number of buffered traces. When exceeded, the least
+	// recently updated traces are sampled early.
+	MaxTraces int
+}
+
+// walBatch is a batch of polled spans, in WAL order, with the number of its
+// spans still buffered.
+type walBatch struct {
+	key         []byte
+	outstanding int
+}
+
+type bufferedSpan struct {
+	serviceName string
+	span        *tracepb.Span
+	batch       *walBatch
+}
+
+type bufferedTrace struct {
+	spans    []bufferedSpan
+	lastSeen time.Time
+}
+
+// TailSampler buffers polled spans by trace and hands them to the span writer
+// once their trace is complete, keeping every trace with an error span or
+// lasting at least LatencyThreshold, and KeepRate of the other traces. The
+// decision for healthy traces is derived from the trace id, so it is the same
+// for spans arriving after their trace was sampled.
+//
+// The poller position only advances past spans that are no longer buffered,
+// so buffered traces are re-read from the WAL after a restart.
+type TailSampler struct {
+	cfg    TailSamplingConfig
+	writer *SpanWriter
+
+	mu        sync.Mutex
+	traces    map[string]*bufferedTrace
+	batches   []*walBatch
+	kept      map[string]struct{}
+	keptOrder []string
+}
+
+// NewTailSampler creates a TailSampler writing kept traces to writer.
+func NewTailSampler(cfg TailSamplingConfig, writer *SpanWriter) *TailSampler {
+	return &TailSampler{
+		cfg:    cfg,
+		writer: writer,
+		traces: map[string]*bufferedTrace{},
+		kept:   map[string]struct{}{},
+	}
+}
+
+// Add buffers spans of a service read up to lastKey. When more than MaxTraces
+// traces are buffered, the least recently updated ones are sampled.
+func (s *TailSampler) Add(ctx context.Context, serviceName string, spans []*tracepb.Span, lastKey []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	batch := &walBatch{key: lastKey, outstanding: len(spans)}
+	s.batches = append(s.batches, batch)
+	for _, span := range spans {
+		traceID := hex.EncodeToString(span.TraceId)
+		trace, ok := s.traces[traceID]
+		if !ok {
+			trace = &bufferedTrace{}
+			s.traces[traceID] = trace
+		}
+		trace.spans = append(trace.spans, bufferedSpan{serviceName: serviceName, span: span, batch: batch})
+		trace.lastSeen = now
+	}
+
+	if len(s.traces) <= s.cfg.MaxTraces {
+		return nil
+	}
+	traceIDs := make([]string, 0, len(s.traces))
+	for traceID := range s.traces {
+		traceIDs = append(traceIDs, traceID)
+	}
+	sort.Slice(traceIDs, func(i, j int) bool {
+		return s.traces[traceIDs[i]].lastSeen.Before(s.traces[traceIDs[j]].lastSeen)
+	})
+	return s.release(ctx, traceIDs[:len(traceIDs)-s.cfg.MaxTraces])
+}
+
+// Advance moves the committed position past spans that were read but
+// intentionally not indexed, once every earlier span is no longer buffered.
+func (s *TailSampler) Advance(lastKey []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, &walBatch{key: lastKey})
+	if key := s.watermark(); key != nil {
+		s.writer.Advance(key)
+	}
+}
+
+// FlushIfDue samples the traces that received no span for DecisionWait, then
+// flushes the span writer if due.
+func (s *TailSampler) FlushIfDue(ctx context.Context) error {
+	s.mu.Lock()
+	var due []string
+	cutoff := time.Now().Add(-s.cfg.DecisionWait)
+	for traceID, trace := range s.traces {
+		if trace.lastSeen.Before(cutoff) {
+			due = append(due, traceID)
+		}
+	}
+	err := s.release(ctx, due)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return s.writer.FlushIfDue(ctx)
+}
+
+// Flush writes the spans already released to the span writer. Buffered traces
+// are not sampled early; they are re-read from the WAL after a restart.
+func (s *TailSampler) Flush(ctx context.Context) error {
+	return s.writer.Flush(ctx)
+}
+
+// MaxLatency returns the configured maximum flush latency of the span writer.
+func (s *TailSampler) MaxLatency() time.Duration {
+	return s.writer.MaxLatency()
+}
+
+// release samples the given traces, hands the spans of kept traces to the span
+// writer and advances the committed position.
+func (s *TailSampler) release(ctx context.Context, traceIDs []string) error {
+	if len(traceIDs) == 0 {
+		return nil
+	}
+
+	keptSpans := map[string][]*tracepb.Span{}
+	for _, traceID := range traceIDs {
+		trace := s.traces[traceID]
+		delete(s.traces, traceID)
+
+		keep := s.keep(traceID, trace)
+		if keep {
+			tailKeptTraces.Inc()
+		} else {
+			tailDroppedTraces.Inc()
+			tailDroppedSpans.Add(uint64(len(trace.spans)))
+		}
+		for _, buffered := range trace.spans {
+			buffered.batch.outstanding--
+			if keep {
+				keptSpans[buffered.serviceName] = append(keptSpans[buffered.serviceName], buffered.span)
+			}
+		}
+	}
+
+	// The position is passed with the last service's spans, so it is not
+	// committed before every kept span is queued.
+	key := s.watermark()
+	remaining := len(keptSpans)
+	for serviceName, spans := range keptSpans {
+		remaining--
+		var lastKey []byte
+		if remaining == 0 {
+			lastKey = key
+		}
+		if err := s.writer.Add(ctx, serviceName, spans, lastKey); err != nil {
+			return err
+		}
+	}
+	if len(keptSpans) == 0 && key != nil {
+		s.writer.Advance(key)
+	}
+	return nil
+}
+
+// keep reports whether a complete trace is kept.
+func (s *TailSampler) keep(traceID string, trace *bufferedTrace) bool {
+	if _, ok := s.kept[traceID]; ok {
+		return true
+	}
+
+	var start, end uint64
+	hasError := false
+	for i, buffered := range trace.spans {
+		span := buffered.span
+		if span.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+			hasError = true
+		}
+		if i == 0 || span.StartTimeUnixNano < start {
+			start = span.StartTimeUnixNano
+		}
+		if span.EndTimeUnixNano > end {
+			end = span.EndTimeUnixNano
+		}
+	}
+	slow := s.cfg.LatencyThreshold > 0 && end > start && time.Duration(end-start) >= s.cfg.LatencyThreshold
+
+	if hasError || slow {
+		s.markKept(traceID)
+		return true
+	}
+	return traceRatio(trace.spans[0].span.TraceId) < s.cfg.KeepRate
+}
+
+// markKept remembers a trace kept for an error or its latency, so its late
+// spans are kept too.
+func (s *TailSampler) markKept(traceID string) {
+	if _, ok := s.kept[traceID]; ok {
+		return
+	}
+	if len(s.keptOrder) >= maxKeptTraces {
+		delete(s.kept, s.keptOrder[0])
+		s.keptOrder = s.keptOrder[1:]
+	}
+	s.kept[traceID] = struct{}{}
+	s.keptOrder = append(s.keptOrder, traceID)
+}
+
+// watermark drops the leading batches without buffered spans and returns the
+// key of the last one dropped, or nil when none was.
+func (s *TailSampler) watermark() []byte {
+	var key []byte
+	for len(s.batches) > 0 && s.batches[0].outstanding == 0 {
+		key = s.batches[0].key
+		s.batches = s.batches[1:]
+	}
+	return key
+}
+
+// traceRatio maps a trace id to [0, 1) using its lower 8 bytes, which are
+// random in W3C trace ids. Malformed trace ids are sampled at random.
+func traceRatio(traceID []byte) float64 {
+	if len(traceID) != 16 {
+		return rand.Float64()
+	}
+	return float64(binary.BigEndian.Uint64(traceID[8:])>>11) / (1 << 53)
+}
@@ -0,0 +1,15 @@
+package telemetry
+
+// ToolCallSpanType extracts the tool calls of LLM agents into the tool_calls
+// table. Tool spans are recognized by junjo.span_type "tool_call" or the
+// OpenInference TOOL span kind.
+var ToolCallSpanType = SpanType{
+	Name:  "tool_call",
+	Table: "tool_calls",
+	Match: &AttributeMatch{Attribute: "openinference.span.kind", Value: "TOOL"},
+	Columns: []SpanTypeColumn{
+		{Name: "tool_name", Type: "VARCHAR", Attributes: []string{"tool.name", "tool_call.function.name", "gen_ai.tool.name"}},
+		{Name: "arguments", Type: "JSON", Attributes: []string{"tool_call.function.arguments", "gen_ai.tool.call.arguments", "input.value"}},
+		{Name: "result", Type: "VARCHAR", Attributes: []string{"gen_ai.tool.call.result", "output.value"}},
+	},
+}
@@ -0,0 +1,64 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// WorkflowRun is a workflow span as it was stored.
+type WorkflowRun struct {
+	ServiceName string
+	TraceID     string
+	SpanID      string
+	Name        string
+	FinalState  string
+}
+
+// WorkflowRunHandler is called with the workflow runs of each batch of spans
+// once the batch is committed.
+type WorkflowRunHandler func(ctx context.Context, runs []WorkflowRun)
+
+var (
+	workflowRunMu      sync.RWMutex
+	workflowRunHandler WorkflowRunHandler
+)
+
+// SetWorkflowRunHandler sets the handler called with the workflow runs indexed
+// afterwards. A nil handler disables it.
+func SetWorkflowRunHandler(handler WorkflowRunHandler) {
+	workflowRunMu.Lock()
+	defer workflowRunMu.Unlock()
+	workflowRunHandler = handler
+}
+
+// collectWorkflowRuns returns the workflow spans of a batch, with their final
+// state filtered and redacted as it is stored, when a handler is set.
+func collectWorkflowRuns(spansByService map[string][]*tracepb.Span) (WorkflowRunHandler, []WorkflowRun) {
+	workflowRunMu.RLock()
+	handler := workflowRunHandler
+	workflowRunMu.RUnlock()
+	if handler == nil {
+		return nil, nil
+	}
+
+	var runs []WorkflowRun
+	for serviceName, spans := range spansByService {
+		for _, span := range spans {
+			if extractStringAttribute(span.Attributes, "junjo.span_type") != "workflow" {
+				continue
+			}
+			stored := redactSpan(filterAttributes(serviceName, span))
+			runs = append(runs, WorkflowRun{
+				ServiceName: serviceName,
+				TraceID:     hex.EncodeToString(span.TraceId),
+				SpanID:      hex.EncodeToString(span.SpanId),
+				Name:        span.Name,
+				FinalState:  extractJSONAttribute(stored.Attributes, "junjo.workflow.state.end"),
+			})
+		}
+	}
+	return handler, runs
+}
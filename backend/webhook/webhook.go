@@ -0,0 +1,65 @@
+// Package webhook delivers alert events, such as missed SLAs, as JSON POSTs
+// to the webhook URLs owners configure.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Timeout bounds the delivery of an event, including reading the response.
+const Timeout = 10 * time.Second
+
+// Notifier POSTs events to a webhook URL. A nil Notifier, or one without a
+// URL, sends nothing.
+type Notifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// New creates a Notifier for url whose deliveries time out after Timeout.
+func New(url string) *Notifier {
+	return &Notifier{URL: url, Client: &http.Client{Timeout: Timeout}}
+}
+
+// Enabled reports whether the notifier has a URL to send events to.
+func (n *Notifier) Enabled() bool {
+	return n != nil && n.URL != ""
+}
+
+// Send POSTs payload as JSON to the webhook URL. It fails unless the webhook
+// responds with a 2xx status.
+func (n *Notifier) Send(ctx context.Context, payload any) error {
+	if !n.Enabled() {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: Timeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -4,7 +4,7 @@ import { OtelSpan, OtelSpanSchema } from '../../../traces/schemas/schemas'
 
 /**
  * Get Spans - Type Workflow
- * Fetches all spans where the type is a junjo workflow for a specific service.
+ * Fetches the most recent page of spans where the type is a junjo workflow for a specific service.
  * @param serviceName - The service name to filter by
  * @returns
  */
@@ -18,6 +18,11 @@ export async function getSpansTypeWorkflow(serviceName: string): Promise<OtelSpa
   }
 
   const data = await response.json()
-  const validatedData = z.array(OtelSpanSchema).parse(data)
-  return validatedData
+  const validatedData = z
+    .object({
+      spans: z.array(OtelSpanSchema),
+      next_cursor: z.string().nullable(),
+    })
+    .parse(data)
+  return validatedData.spans
 }
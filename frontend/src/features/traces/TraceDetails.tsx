@@ -1,7 +1,7 @@
 import { Link, useParams, useNavigate } from 'react-router'
 import { useEffect, useState } from 'react'
 import { OtelSpan } from '../traces/schemas/schemas'
-import { API_HOST } from '../../config'
+import { fetchTraceSpans } from './fetch/get-trace-spans'
 import NestedOtelSpans from './NestedOtelSpans'
 import SpanAttributesPanel from './SpanAttributesPanel'
 
@@ -22,13 +22,7 @@ export default function TraceDetails() {
       try {
         setLoading(true)
         setError(false)
-        const response = await fetch(`${API_HOST}/otel/trace/${traceId}/nested-spans`, {
-          credentials: 'include',
-        })
-        if (!response.ok) {
-          throw new Error('Failed to fetch spans')
-        }
-        const data = await response.json()
+        const data = (await fetchTraceSpans(traceId!)) as OtelSpan[]
         setSpans(data)
         console.log(data)
       } catch (error) {
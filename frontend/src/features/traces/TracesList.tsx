@@ -9,23 +9,32 @@ export default function TracesList({ filterLLM }: { filterLLM: boolean }) {
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState(false)
   const [traces, setTraces] = useState<OtelSpan[]>([])
+  const [nextCursor, setNextCursor] = useState<string | null>(null)
+  const [loadingMore, setLoadingMore] = useState(false)
+
+  const endpoint = filterLLM
+    ? `${API_HOST}/otel/service/${serviceName}/root-spans-filtered`
+    : `${API_HOST}/otel/service/${serviceName}/root-spans`
+
+  const fetchPage = async (cursor: string | null) => {
+    const url = cursor ? `${endpoint}?cursor=${encodeURIComponent(cursor)}` : endpoint
+    const response = await fetch(url, {
+      credentials: 'include',
+    })
+    if (!response.ok) {
+      throw new Error('Failed to fetch traces')
+    }
+    return (await response.json()) as { spans: OtelSpan[]; next_cursor: string | null }
+  }
 
   useEffect(() => {
     const fetchTraces = async () => {
       try {
         setLoading(true)
         setError(false)
-        const endpoint = filterLLM
-          ? `${API_HOST}/otel/service/${serviceName}/root-spans-filtered`
-          : `${API_HOST}/otel/service/${serviceName}/root-spans`
-        const response = await fetch(endpoint, {
-          credentials: 'include',
-        })
-        if (!response.ok) {
-          throw new Error('Failed to fetch traces')
-        }
-        const data = await response.json()
-        setTraces(data)
+        const data = await fetchPage(null)
+        setTraces(data.spans)
+        setNextCursor(data.next_cursor)
       } catch (error) {
         setError(true)
       } finally {
@@ -36,6 +45,20 @@ export default function TracesList({ filterLLM }: { filterLLM: boolean }) {
     fetchTraces()
   }, [serviceName, filterLLM])
 
+  const loadMore = async () => {
+    if (!nextCursor) return
+    try {
+      setLoadingMore(true)
+      const data = await fetchPage(nextCursor)
+      setTraces((prev) => [...prev, ...data.spans])
+      setNextCursor(data.next_cursor)
+    } catch (error) {
+      setError(true)
+    } finally {
+      setLoadingMore(false)
+    }
+  }
+
   if (loading) {
     return <div>Loading...</div>
   }
@@ -45,20 +68,27 @@ export default function TracesList({ filterLLM }: { filterLLM: boolean }) {
   }
 
   return (
-    <table className="text-left text-sm">
-      <thead>
-        <tr>
-          <th className={'px-4 py-1'}>Name</th>
-          <th className={'px-4 py-1'}>Trace ID</th>
-          <th className={'px-4 py-1'}>Start Time</th>
-          <th className={'px-4 py-1 text-right'}>Duration</th>
-        </tr>
-      </thead>
-      <tbody>
-        {traces.map((trace) => (
-          <TraceListItem key={trace.span_id} trace={trace} />
-        ))}
-      </tbody>
-    </table>
+    <>
+      <table className="text-left text-sm">
+        <thead>
+          <tr>
+            <th className={'px-4 py-1'}>Name</th>
+            <th className={'px-4 py-1'}>Trace ID</th>
+            <th className={'px-4 py-1'}>Start Time</th>
+            <th className={'px-4 py-1 text-right'}>Duration</th>
+          </tr>
+        </thead>
+        <tbody>
+          {traces.map((trace) => (
+            <TraceListItem key={trace.span_id} trace={trace} />
+          ))}
+        </tbody>
+      </table>
+      {nextCursor && (
+        <button className={'px-4 py-2 text-sm'} onClick={loadMore} disabled={loadingMore}>
+          {loadingMore ? 'Loading...' : 'Load more'}
+        </button>
+      )}
+    </>
   )
 }
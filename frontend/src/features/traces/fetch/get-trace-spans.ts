@@ -4,16 +4,33 @@ import { API_HOST } from '../../../config'
 
 const GetTraceSpansResponseSchema = z.array(OtelSpanSchema)
 
-export async function getTraceSpans(traceId: string): Promise<OtelSpan[]> {
-  const response = await fetch(`${API_HOST}/otel/trace/${traceId}/nested-spans`, {
-    credentials: 'include',
-  })
+/**
+ * Fetches every span of a trace. Responses over the server's size limit are truncated, with a
+ * continuation token in the X-Continuation-Token header to fetch the remainder.
+ */
+export async function fetchTraceSpans(traceId: string): Promise<unknown[]> {
+  const spans: unknown[] = []
+  let continuation: string | null = null
+
+  do {
+    const params: string = continuation ? `?continuation=${encodeURIComponent(continuation)}` : ''
+    const response = await fetch(`${API_HOST}/otel/trace/${traceId}/nested-spans${params}`, {
+      credentials: 'include',
+    })
+
+    if (!response.ok) {
+      throw new Error('Failed to fetch spans')
+    }
 
-  if (!response.ok) {
-    throw new Error('Failed to fetch spans')
-  }
+    spans.push(...(await response.json()))
+    continuation = response.headers.get('X-Continuation-Token')
+  } while (continuation)
 
-  const data = await response.json()
+  return spans
+}
+
+export async function getTraceSpans(traceId: string): Promise<OtelSpan[]> {
+  const data = await fetchTraceSpans(traceId)
   const validatedData = GetTraceSpansResponseSchema.parse(data)
   return validatedData
 }
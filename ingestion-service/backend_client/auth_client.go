@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	pb "junjo-server/ingestion-service/proto_gen"
+	"junjo-server/ingestion-service/requestid"
 	"log"
 	"time"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// readinessRetryInterval is how long WaitUntilReady waits between health
+// checks of a backend that is reachable but not serving.
+const readinessRetryInterval = time.Second
+
 // AuthClient provides a client for the internal auth service on the backend.
 type AuthClient struct {
 	conn   *grpc.ClientConn
@@ -19,22 +24,29 @@ type AuthClient struct {
 
 // NewAuthClient creates a new gRPC client for the backend's auth service.
 // The connection will automatically reconnect if the backend becomes unavailable.
-func NewAuthClient() (*AuthClient, error) {
-	addr := "junjo-server-backend:50053"
+func NewAuthClient(cfg Config) (*AuthClient, error) {
+	creds, err := cfg.transportCredentials()
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a persistent gRPC connection.
 	// gRPC automatically manages connection state and reconnects if the backend restarts.
 	// The WaitForReady(true) option on individual calls controls whether to wait for
 	// the connection to be ready before sending the RPC.
-	conn, err := grpc.NewClient(
-		addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	opts := append(cfg.GRPC.DialOptions(),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(requestid.UnaryClientInterceptor()),
 	)
+	if cfg.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(cfg.Token)))
+	}
+	conn, err := grpc.NewClient(cfg.Addr, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("Created gRPC client for backend auth service at %s", addr)
+	log.Printf("Created gRPC client for backend auth service at %s (TLS: %t)", cfg.Addr, cfg.TLS)
 
 	return &AuthClient{
 		conn:   conn,
@@ -76,27 +88,35 @@ func (c *AuthClient) ValidateApiKey(ctx context.Context, apiKey string) (bool, e
 	return res.IsValid, nil
 }
 
-// WaitUntilReady blocks until the backend is reachable or the context is cancelled.
-// This should be called once at startup to ensure the backend is ready before
-// accepting traffic.
+// WaitUntilReady blocks until the backend's gRPC health service reports
+// SERVING or the context is cancelled. This should be called once at startup
+// to ensure the backend is ready before accepting traffic.
 func (c *AuthClient) WaitUntilReady(ctx context.Context) error {
 	log.Println("Waiting for backend gRPC server to be ready...")
 
-	// Try to validate a dummy key - we don't care about the result,
-	// just that the backend responds
-	req := &pb.ValidateApiKeyRequest{
-		ApiKey: "startup-readiness-check",
-	}
-
-	_, err := c.client.ValidateApiKey(
-		ctx,
-		req,
-		grpc.WaitForReady(true), // Block until backend is ready
-	)
-	if err != nil {
-		return fmt.Errorf("backend did not become ready: %w", err)
+	healthClient := healthpb.NewHealthClient(c.conn)
+	for {
+		res, err := healthClient.Check(
+			ctx,
+			&healthpb.HealthCheckRequest{},
+			grpc.WaitForReady(true), // Block until backend is reachable
+		)
+		if err == nil && res.Status == healthpb.HealthCheckResponse_SERVING {
+			log.Println("Backend gRPC server is ready!")
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("backend is %s", res.Status)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("backend did not become ready: %w", err)
+		}
+
+		log.Printf("Backend gRPC server is not ready yet: %v", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("backend did not become ready: %w", ctx.Err())
+		case <-time.After(readinessRetryInterval):
+		}
 	}
-
-	log.Println("Backend gRPC server is ready!")
-	return nil
 }
@@ -0,0 +1,86 @@
+package backend_client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"junjo-server/ingestion-service/grpc_options"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Config configures the connection to the backend's internal auth service.
+type Config struct {
+	// Addr is the host:port address of the backend's internal gRPC server.
+	Addr string
+
+	// TLS enables TLS. The server certificate is verified against CAFile, or
+	// the system roots when it is empty, for ServerName, or the host of Addr.
+	TLS        bool
+	CAFile     string
+	ServerName string
+
+	// CertFile and KeyFile are the client certificate presented to a backend
+	// that requires mutual TLS.
+	CertFile string
+	KeyFile  string
+
+	// Token is the shared secret sent with every call to a backend that
+	// requires caller authentication.
+	Token string
+
+	// GRPC tunes the connection's message sizes, keepalive and timeouts.
+	GRPC grpc_options.Config
+}
+
+// transportCredentials returns the credentials of the connection: TLS when
+// enabled, and plaintext otherwise.
+func (c Config) transportCredentials() (credentials.TransportCredentials, error) {
+	if !c.TLS {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read BACKEND_GRPC_TLS_CA_FILE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("BACKEND_GRPC_TLS_CA_FILE %s holds no PEM certificates", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load INTERNAL_TLS_CERT_FILE and INTERNAL_TLS_KEY_FILE: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// tokenMetadataKey is the metadata key the backend reads the caller token
+// from.
+const tokenMetadataKey = "x-junjo-internal-token"
+
+// tokenCredentials sends the caller token with every call. The token is also
+// sent over plaintext connections, which are limited to the internal network.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{tokenMetadataKey: string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
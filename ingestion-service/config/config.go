@@ -0,0 +1,293 @@
+// Package config loads the configuration of the ingestion service from the
+// environment and an optional YAML file, and validates it at startup.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"junjo-server/ingestion-service/grpc_options"
+	"junjo-server/ingestion-service/storage"
+)
+
+// EnvProduction is the production running environment.
+const EnvProduction = "production"
+
+// Config is the configuration of the ingestion service. It is read from the
+// YAML file at INGESTION_CONFIG_FILE, when set, and from the environment
+// variable named by each field's env tag, which takes precedence over the
+// file.
+type Config struct {
+	// Env is the running environment, such as development or production.
+	Env string `yaml:"env" env:"JUNJO_ENV"`
+
+	WAL WALConfig `yaml:"wal"`
+
+	Sampling SamplingConfig `yaml:"sampling"`
+
+	Listen ListenConfig `yaml:"listen"`
+
+	PublicTLS PublicTLSConfig `yaml:"public_tls"`
+
+	InternalTLS InternalTLSConfig `yaml:"internal_tls"`
+
+	// Reflection registers gRPC reflection on the servers. It defaults to
+	// true everywhere but in production, where it would advertise the
+	// services on the public port.
+	Reflection *bool `yaml:"grpc_reflection" env:"GRPC_REFLECTION"`
+
+	Admin AdminConfig `yaml:"admin"`
+
+	Backend BackendConfig `yaml:"backend"`
+
+	GRPC grpc_options.Config `yaml:"grpc"`
+}
+
+// WALConfig configures the write-ahead log spans are stored in until the
+// backend reads them.
+type WALConfig struct {
+	// Backend is the storage engine, badger or segment.
+	Backend string `yaml:"backend" env:"WAL_BACKEND"`
+
+	// Path is the directory of the WAL. It defaults to ~/.junjo/ingestion-wal.
+	Path string `yaml:"path" env:"WAL_PATH"`
+
+	// BadgerPath is the former name of Path.
+	BadgerPath string `yaml:"-" env:"BADGERDB_PATH"`
+}
+
+// SamplingConfig holds the files sampling rules are persisted to. They
+// default to files next to the WAL directory.
+type SamplingConfig struct {
+	ExemptionsPath string `yaml:"exemptions_path" env:"SAMPLING_EXEMPTIONS_PATH"`
+	RulesPath      string `yaml:"rules_path" env:"SAMPLING_RULES_PATH"`
+}
+
+// ListenConfig holds the host:port addresses the servers bind to. A port
+// binds every interface, and is only used when the address is not set.
+type ListenConfig struct {
+	// PublicGRPC serves OTLP exports, by default on :50051.
+	PublicGRPC     string `yaml:"public_grpc" env:"GRPC_ADDR"`
+	PublicGRPCPort string `yaml:"public_grpc_port" env:"GRPC_PORT"`
+
+	// InternalGRPC serves the WAL to the backend, by default on :50052.
+	InternalGRPC     string `yaml:"internal_grpc" env:"INTERNAL_GRPC_ADDR"`
+	InternalGRPCPort string `yaml:"internal_grpc_port" env:"INTERNAL_GRPC_PORT"`
+
+	// AdminHTTP serves metrics and settings, by default on :50054.
+	AdminHTTP     string `yaml:"admin_http" env:"ADMIN_HTTP_ADDR"`
+	AdminHTTPPort string `yaml:"admin_http_port" env:"ADMIN_HTTP_PORT"`
+}
+
+// PublicTLSConfig is the certificate the public gRPC server serves TLS with.
+// TLS is disabled unless both files are set.
+type PublicTLSConfig struct {
+	CertFile string `yaml:"cert_file" env:"GRPC_TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"GRPC_TLS_KEY_FILE"`
+}
+
+// InternalTLSConfig configures mutual TLS between the ingestion service and
+// the backend. The certificate is served by the internal gRPC server and
+// presented to the backend; peers must present a certificate signed by the
+// CA. It is disabled unless every file is set.
+type InternalTLSConfig struct {
+	CertFile string `yaml:"cert_file" env:"INTERNAL_TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"INTERNAL_TLS_KEY_FILE"`
+	CAFile   string `yaml:"ca_file" env:"INTERNAL_TLS_CA_FILE"`
+}
+
+// AdminConfig holds the bearer tokens of the admin HTTP server.
+type AdminConfig struct {
+	// Token is required by requests reading or changing settings, and by
+	// changes to the drain. They are rejected when it is not set.
+	Token string `yaml:"token" env:"ADMIN_TOKEN"`
+
+	// DebugToken is required by the debug endpoints, which are not served
+	// when it is not set.
+	DebugToken string `yaml:"debug_token" env:"ADMIN_DEBUG_TOKEN"`
+}
+
+// BackendConfig configures the connection to the backend's internal auth
+// service.
+type BackendConfig struct {
+	// Addr is the host:port address of the backend's internal gRPC server.
+	Addr string `yaml:"addr" env:"BACKEND_GRPC_ADDR"`
+
+	// TLS enables TLS. It is enabled by a CA file or an internal TLS
+	// certificate. The server certificate is verified against CAFile, which
+	// defaults to the internal TLS CA, or the system roots, for ServerName,
+	// or the host of Addr.
+	TLS        bool   `yaml:"tls" env:"BACKEND_GRPC_TLS"`
+	CAFile     string `yaml:"ca_file" env:"BACKEND_GRPC_TLS_CA_FILE"`
+	ServerName string `yaml:"server_name" env:"BACKEND_GRPC_TLS_SERVER_NAME"`
+
+	// Token is sent with every call to a backend that requires caller
+	// authentication.
+	Token string `yaml:"token" env:"INTERNAL_AUTH_TOKEN"`
+}
+
+// Default returns the configuration used for settings that are not set.
+func Default() *Config {
+	return &Config{
+		WAL: WALConfig{
+			Backend: storage.BackendBadger,
+		},
+		Backend: BackendConfig{
+			Addr: "junjo-server-backend:50053",
+		},
+		GRPC: grpc_options.Config{
+			KeepaliveMinTime: 10 * time.Second,
+		},
+	}
+}
+
+// Load reads the configuration from the INGESTION_CONFIG_FILE YAML file, when
+// set, and the environment, and validates it.
+func Load() (*Config, error) {
+	cfg := Default()
+	if path := os.Getenv("INGESTION_CONFIG_FILE"); path != "" {
+		if err := readFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := readEnv(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.resolve(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// resolve sets the defaults that depend on other settings.
+func (c *Config) resolve() error {
+	if c.WAL.Path == "" {
+		c.WAL.Path = c.WAL.BadgerPath
+	}
+	if c.WAL.Path == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get user home directory for the default WAL_PATH: %w", err)
+		}
+		c.WAL.Path = filepath.Join(homeDir, ".junjo", "ingestion-wal")
+	}
+	if c.Sampling.ExemptionsPath == "" {
+		c.Sampling.ExemptionsPath = filepath.Join(filepath.Dir(c.WAL.Path), "sampling_exemptions.json")
+	}
+	if c.Sampling.RulesPath == "" {
+		c.Sampling.RulesPath = filepath.Join(filepath.Dir(c.WAL.Path), "sampling_rules.json")
+	}
+
+	c.Listen.PublicGRPC = listenAddr(c.Listen.PublicGRPC, c.Listen.PublicGRPCPort, ":50051")
+	c.Listen.InternalGRPC = listenAddr(c.Listen.InternalGRPC, c.Listen.InternalGRPCPort, ":50052")
+	c.Listen.AdminHTTP = listenAddr(c.Listen.AdminHTTP, c.Listen.AdminHTTPPort, ":50054")
+
+	if c.Backend.CAFile == "" {
+		c.Backend.CAFile = c.InternalTLS.CAFile
+	}
+	if c.Backend.CAFile != "" || c.InternalTLS.CertFile != "" {
+		c.Backend.TLS = true
+	}
+	return nil
+}
+
+// Validate reports every invalid setting of the configuration.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.WAL.Backend != storage.BackendBadger && c.WAL.Backend != storage.BackendSegment {
+		invalid("WAL_BACKEND (wal.backend) must be %s or %s, got %q", storage.BackendBadger, storage.BackendSegment, c.WAL.Backend)
+	}
+	for _, addr := range []struct{ name, addr string }{
+		{"GRPC_ADDR or GRPC_PORT (listen.public_grpc)", c.Listen.PublicGRPC},
+		{"INTERNAL_GRPC_ADDR or INTERNAL_GRPC_PORT (listen.internal_grpc)", c.Listen.InternalGRPC},
+		{"ADMIN_HTTP_ADDR or ADMIN_HTTP_PORT (listen.admin_http)", c.Listen.AdminHTTP},
+		{"BACKEND_GRPC_ADDR (backend.addr)", c.Backend.Addr},
+	} {
+		if !isHostPort(addr.addr) {
+			invalid("%s must be a host:port address, got %q", addr.name, addr.addr)
+		}
+	}
+	if c.Listen.PublicGRPC == c.Listen.InternalGRPC || c.Listen.PublicGRPC == c.Listen.AdminHTTP || c.Listen.InternalGRPC == c.Listen.AdminHTTP {
+		invalid("the public gRPC, internal gRPC and admin HTTP addresses must differ, got %q, %q and %q", c.Listen.PublicGRPC, c.Listen.InternalGRPC, c.Listen.AdminHTTP)
+	}
+	if (c.PublicTLS.CertFile == "") != (c.PublicTLS.KeyFile == "") {
+		invalid("GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE (public_tls) must be set together")
+	}
+	if !c.InternalTLS.Enabled() && (c.InternalTLS.CertFile != "" || c.InternalTLS.KeyFile != "" || c.InternalTLS.CAFile != "") {
+		invalid("INTERNAL_TLS_CERT_FILE, INTERNAL_TLS_KEY_FILE and INTERNAL_TLS_CA_FILE (internal_tls) must be set together")
+	}
+	if c.GRPC.MaxRecvMsgSize < 0 || c.GRPC.MaxSendMsgSize < 0 {
+		invalid("GRPC_MAX_RECV_MSG_SIZE and GRPC_MAX_SEND_MSG_SIZE (grpc) must not be negative")
+	}
+	if c.GRPC.KeepaliveTime < 0 || c.GRPC.KeepaliveTimeout < 0 || c.GRPC.KeepaliveMinTime < 0 || c.GRPC.ConnectTimeout < 0 {
+		invalid("GRPC_KEEPALIVE_TIME, GRPC_KEEPALIVE_TIMEOUT, GRPC_KEEPALIVE_MIN_TIME and GRPC_CONNECT_TIMEOUT (grpc) must not be negative")
+	}
+	for _, file := range []struct{ name, path string }{
+		{"GRPC_TLS_CERT_FILE (public_tls.cert_file)", c.PublicTLS.CertFile},
+		{"GRPC_TLS_KEY_FILE (public_tls.key_file)", c.PublicTLS.KeyFile},
+		{"INTERNAL_TLS_CERT_FILE (internal_tls.cert_file)", c.InternalTLS.CertFile},
+		{"INTERNAL_TLS_KEY_FILE (internal_tls.key_file)", c.InternalTLS.KeyFile},
+		{"INTERNAL_TLS_CA_FILE (internal_tls.ca_file)", c.InternalTLS.CAFile},
+		{"BACKEND_GRPC_TLS_CA_FILE (backend.ca_file)", c.Backend.CAFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			invalid("%s: %v", file.name, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// ReflectionEnabled reports whether gRPC reflection is registered on the
+// servers.
+func (c *Config) ReflectionEnabled() bool {
+	if c.Reflection != nil {
+		return *c.Reflection
+	}
+	return c.Env != EnvProduction
+}
+
+// Enabled reports whether mutual TLS is configured.
+func (c InternalTLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != "" && c.CAFile != ""
+}
+
+// listenAddr returns addr, every interface on port, or fallback.
+func listenAddr(addr string, port string, fallback string) string {
+	if addr != "" {
+		return addr
+	}
+	if port != "" {
+		return ":" + port
+	}
+	return fallback
+}
+
+// isHostPort reports whether raw is a host:port address with a valid port.
+// The host may be empty.
+func isHostPort(raw string) bool {
+	_, port, err := net.SplitHostPort(raw)
+	if err != nil {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile reads a YAML configuration file into cfg. Unknown keys are
+// rejected so that typos do not go unnoticed.
+func readFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read INGESTION_CONFIG_FILE: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid INGESTION_CONFIG_FILE %s: %w", path, err)
+	}
+	return nil
+}
+
+// readEnv sets every field of cfg whose env tag names a set, non-empty
+// environment variable.
+func readEnv(cfg *Config) error {
+	return readEnvInto(reflect.ValueOf(cfg).Elem())
+}
+
+// durationType is the type of time.Duration fields, which are parsed with
+// time.ParseDuration.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// boolPointerType is the type of *bool fields, whose default depends on other
+// settings when they are not set.
+var boolPointerType = reflect.TypeOf((*bool)(nil))
+
+func readEnvInto(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, structField := v.Field(i), v.Type().Field(i)
+		if structField.Type.Kind() == reflect.Struct {
+			if err := readEnvInto(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := structField.Tag.Get("env")
+		raw := os.Getenv(name)
+		if name == "" || raw == "" {
+			continue
+		}
+		if err := setField(field, raw); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, raw, err)
+		}
+	}
+	return nil
+}
+
+// setField parses an environment variable value into a field.
+func setField(field reflect.Value, raw string) error {
+	switch {
+	case field.Type() == durationType:
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(value))
+	case field.Type() == boolPointerType:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(&value))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(value)
+	case field.Kind() == reflect.Int || field.Kind() == reflect.Int64:
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(value)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
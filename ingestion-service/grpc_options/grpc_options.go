@@ -0,0 +1,92 @@
+// Package grpc_options configures the message size limits, keepalive and
+// connection timeouts of the ingestion service's gRPC servers and clients.
+package grpc_options
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Config holds the gRPC tuning settings, loaded by the config package. A
+// zero value keeps gRPC's default.
+type Config struct {
+	// MaxRecvMsgSize and MaxSendMsgSize are the largest messages, in bytes,
+	// received and sent. gRPC receives up to 4 MiB by default, which large
+	// span batches can exceed.
+	MaxRecvMsgSize int `yaml:"max_recv_msg_size" env:"GRPC_MAX_RECV_MSG_SIZE"`
+	MaxSendMsgSize int `yaml:"max_send_msg_size" env:"GRPC_MAX_SEND_MSG_SIZE"`
+
+	// KeepaliveTime is how long a connection is idle before it is pinged, and
+	// KeepaliveTimeout how long to wait for the ping's acknowledgement before
+	// closing it.
+	KeepaliveTime    time.Duration `yaml:"keepalive_time" env:"GRPC_KEEPALIVE_TIME"`
+	KeepaliveTimeout time.Duration `yaml:"keepalive_timeout" env:"GRPC_KEEPALIVE_TIMEOUT"`
+
+	// KeepaliveMinTime is the shortest ping interval servers accept from
+	// clients before closing the connection.
+	KeepaliveMinTime time.Duration `yaml:"keepalive_min_time" env:"GRPC_KEEPALIVE_MIN_TIME"`
+
+	// ConnectTimeout bounds the handshake of connections accepted by servers
+	// and each connection attempt of clients.
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"GRPC_CONNECT_TIMEOUT"`
+}
+
+// ServerOptions returns the options of a gRPC server.
+func (c Config) ServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
+	}
+	if c.KeepaliveTime > 0 || c.KeepaliveTimeout > 0 {
+		opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    c.KeepaliveTime,
+			Timeout: c.KeepaliveTimeout,
+		}))
+	}
+	if c.KeepaliveMinTime > 0 {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             c.KeepaliveMinTime,
+			PermitWithoutStream: true,
+		}))
+	}
+	if c.ConnectTimeout > 0 {
+		opts = append(opts, grpc.ConnectionTimeout(c.ConnectTimeout))
+	}
+	return opts
+}
+
+// DialOptions returns the options of a gRPC client. Clients ping at most
+// every 10 seconds, whatever the keepalive time.
+func (c Config) DialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	var callOpts []grpc.CallOption
+	if c.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(c.MaxSendMsgSize))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	if c.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                c.KeepaliveTime,
+			Timeout:             c.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+	if c.ConnectTimeout > 0 {
+		opts = append(opts, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: c.ConnectTimeout,
+		}))
+	}
+	return opts
+}
@@ -4,37 +4,40 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
 
 	"junjo-server/ingestion-service/backend_client"
+	"junjo-server/ingestion-service/config"
+	"junjo-server/ingestion-service/requestid"
+	"junjo-server/ingestion-service/sampling"
 	"junjo-server/ingestion-service/server"
 	"junjo-server/ingestion-service/storage"
 )
 
 func main() {
+	// Add request IDs to slog records
+	requestid.SetDefaultLogger()
+
 	fmt.Println("Starting ingestion service...")
 
-	// --- BadgerDB Setup ---
-	dbPath := os.Getenv("BADGERDB_PATH")
-	if dbPath == "" {
-		// Default to a local directory for development
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			log.Fatalf("Failed to get user home directory: %v", err)
-		}
-		dbPath = filepath.Join(homeDir, ".junjo", "ingestion-wal")
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 
+	// --- WAL Setup ---
+	dbPath := cfg.WAL.Path
+
 	// Ensure the directory exists
 	if err := os.MkdirAll(dbPath, 0755); err != nil {
 		log.Fatalf("Failed to create database directory at %s: %v", dbPath, err)
 	}
 
-	log.Printf("Initializing BadgerDB at: %s", dbPath)
-	store, err := storage.NewStorage(dbPath)
+	log.Printf("Initializing %s WAL at: %s", cfg.WAL.Backend, dbPath)
+	store, err := storage.NewStorage(cfg.WAL.Backend, dbPath)
 	if err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
@@ -42,13 +45,37 @@ func main() {
 
 	log.Println("Storage initialized successfully.")
 
+	// --- Sampling Setup ---
+	// Exemption rules ("always capture") are evaluated before sampling and can be
+	// changed at runtime through the admin settings API.
+	exemptions, err := sampling.LoadExemptions(cfg.Sampling.ExemptionsPath)
+	if err != nil {
+		log.Fatalf("Failed to load sampling exemptions: %v", err)
+	}
+	// Head sampling rules down-sample spans by service, span name or attribute
+	// before they are written to the WAL, and can also be changed at runtime.
+	sampler, err := sampling.LoadRuleSampler(cfg.Sampling.RulesPath)
+	if err != nil {
+		log.Fatalf("Failed to load sampling rules: %v", err)
+	}
+	policy := sampling.NewPolicy(exemptions, sampler)
+
 	// --- Dependency Injection Setup ---
 	// The main function acts as the injector, creating and wiring together the
 	// components of the application.
 
 	// 1. Create the AuthClient: This client is responsible for communicating with
 	//    the backend's internal authentication service.
-	authClient, err := backend_client.NewAuthClient()
+	authClient, err := backend_client.NewAuthClient(backend_client.Config{
+		Addr:       cfg.Backend.Addr,
+		TLS:        cfg.Backend.TLS,
+		CAFile:     cfg.Backend.CAFile,
+		ServerName: cfg.Backend.ServerName,
+		CertFile:   cfg.InternalTLS.CertFile,
+		KeyFile:    cfg.InternalTLS.KeyFile,
+		Token:      cfg.Backend.Token,
+		GRPC:       cfg.GRPC,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create backend auth client: %v", err)
 	}
@@ -67,7 +94,24 @@ func main() {
 	// 3. Create the Public gRPC Server: This server handles all incoming public
 	//    requests. It is injected with the components it depends on, such as the
 	//    storage layer and the AuthClient.
-	publicGRPCServer, publicLis, err := server.NewGRPCServer(store, authClient)
+	//    The drain is shared with the internal and admin servers so exports can be
+	//    stopped while the backend finishes reading the WAL during a deployment.
+	drain := server.NewDrain()
+	serverConfig := server.Config{
+		PublicAddr:       cfg.Listen.PublicGRPC,
+		InternalAddr:     cfg.Listen.InternalGRPC,
+		AdminAddr:        cfg.Listen.AdminHTTP,
+		PublicCertFile:   cfg.PublicTLS.CertFile,
+		PublicKeyFile:    cfg.PublicTLS.KeyFile,
+		InternalCertFile: cfg.InternalTLS.CertFile,
+		InternalKeyFile:  cfg.InternalTLS.KeyFile,
+		InternalCAFile:   cfg.InternalTLS.CAFile,
+		Reflection:       cfg.ReflectionEnabled(),
+		AdminToken:       cfg.Admin.Token,
+		DebugToken:       cfg.Admin.DebugToken,
+		GRPC:             cfg.GRPC,
+	}
+	publicGRPCServer, publicLis, err := server.NewGRPCServer(store, authClient, policy, drain, serverConfig)
 	if err != nil {
 		log.Fatalf("Failed to create public gRPC server: %v", err)
 	}
@@ -80,7 +124,7 @@ func main() {
 	}()
 
 	// --- Internal gRPC Server Setup ---
-	internalGRPCServer, internalLis, err := server.NewInternalGRPCServer(store)
+	internalGRPCServer, internalLis, err := server.NewInternalGRPCServer(store, drain, serverConfig)
 	if err != nil {
 		log.Fatalf("Failed to create internal gRPC server: %v", err)
 	}
@@ -92,6 +136,15 @@ func main() {
 		}
 	}()
 
+	// --- Admin HTTP Server Setup ---
+	adminServer := server.NewAdminHTTPServer(exemptions, sampler, drain, store, serverConfig)
+	go func() {
+		log.Printf("Admin HTTP server listening at %v", adminServer.Addr)
+		if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to serve admin HTTP: %v", err)
+		}
+	}()
+
 	// --- Graceful Shutdown ---
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -102,6 +155,10 @@ func main() {
 	internalGRPCServer.GracefulStop()
 	log.Println("gRPC servers stopped.")
 
+	if err := adminServer.Shutdown(context.Background()); err != nil {
+		log.Printf("Warning: failed to shut down admin HTTP server: %v", err)
+	}
+
 	log.Println("Attempting to sync database to disk...")
 	if err := store.Sync(); err != nil {
 		// Log this as a warning, but still attempt to close.
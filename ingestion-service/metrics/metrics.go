@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a monotonically increasing metric.
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Uint64
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add increments the counter by n.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current counter value.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []*Counter
+)
+
+// NewCounter creates and registers a new counter.
+func NewCounter(name, help string) *Counter {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	c := &Counter{name: name, help: help}
+	registry = append(registry, c)
+	return c
+}
+
+// Handler serves all registered metrics in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryMu.Lock()
+		counters := append([]*Counter(nil), registry...)
+		registryMu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, c := range counters {
+			fmt.Fprintf(w, "# HELP %s %s\n", c.name, c.help)
+			fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+			fmt.Fprintf(w, "%s %d\n", c.name, c.Value())
+		}
+	})
+}
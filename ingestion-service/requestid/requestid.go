@@ -0,0 +1,90 @@
+// Package requestid carries the ID of each gRPC call, received from the
+// caller in the x-request-id metadata or generated, returned in the response
+// header, added to log records, and forwarded to the backend, so a reported
+// failure can be followed across both services.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// MetadataKey is the gRPC metadata key request IDs are forwarded in.
+const MetadataKey = "x-request-id"
+
+// maxLength bounds the length of request IDs accepted from callers.
+const maxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a context carrying a request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID of a context, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New generates a random request ID.
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether an ID received from a caller can be reused: it must
+// be at most 128 printable ASCII characters, so it is safe to log and echo.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// logHandler adds the request ID of the context to log records.
+type logHandler struct {
+	slog.Handler
+}
+
+// NewLogHandler wraps a slog handler to add a request_id attribute to the
+// records logged with a context carrying a request ID.
+func NewLogHandler(h slog.Handler) slog.Handler {
+	return logHandler{h}
+}
+
+func (h logHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return logHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h logHandler) WithGroup(name string) slog.Handler {
+	return logHandler{h.Handler.WithGroup(name)}
+}
+
+// SetDefaultLogger makes the default slog logger add request IDs. The log
+// package, which the default logger writes through, keeps writing to stderr
+// with its flags rather than back into the wrapped handler.
+func SetDefaultLogger() {
+	flags := log.Flags()
+	slog.SetDefault(slog.New(NewLogHandler(slog.Default().Handler())))
+	log.SetOutput(os.Stderr)
+	log.SetFlags(flags)
+}
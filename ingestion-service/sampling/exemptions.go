@@ -0,0 +1,152 @@
+package sampling
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// ExemptionRule marks spans that must always be captured, regardless of the
+// sampling decision. A rule matches when the span or its resource has the
+// attribute and, if Values is non-empty, its value equals one of Values.
+type ExemptionRule struct {
+	Attribute string   `json:"attribute"`
+	Values    []string `json:"values,omitempty"`
+}
+
+// Exemptions is the "always capture" rule set. Rules are persisted as JSON so
+// changes made through the settings API survive restarts.
+type Exemptions struct {
+	mu    sync.RWMutex
+	rules []ExemptionRule
+	path  string
+}
+
+// LoadExemptions reads the rule set from path. A missing file yields an empty
+// rule set.
+func LoadExemptions(path string) (*Exemptions, error) {
+	e := &Exemptions{path: path, rules: []ExemptionRule{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sampling exemptions: %w", err)
+	}
+
+	var rules []ExemptionRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse sampling exemptions %s: %w", path, err)
+	}
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+	e.rules = rules
+	return e, nil
+}
+
+// Rules returns a copy of the current rule set.
+func (e *Exemptions) Rules() []ExemptionRule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return append([]ExemptionRule{}, e.rules...)
+}
+
+// SetRules validates, persists, and applies a new rule set.
+func (e *Exemptions) SetRules(rules []ExemptionRule) error {
+	if rules == nil {
+		rules = []ExemptionRule{}
+	}
+	if err := validateRules(rules); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(e.path), 0755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	// Write to a temporary file first so a crash never leaves a partial rule set.
+	tmp := e.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sampling exemptions: %w", err)
+	}
+	if err := os.Rename(tmp, e.path); err != nil {
+		return fmt.Errorf("failed to write sampling exemptions: %w", err)
+	}
+
+	e.rules = rules
+	return nil
+}
+
+// Match reports whether any rule matches the span or its resource.
+func (e *Exemptions) Match(span *tracepb.Span, resource *resourcepb.Resource) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, rule := range e.rules {
+		if rule.matches(span.Attributes) || (resource != nil && rule.matches(resource.Attributes)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r ExemptionRule) matches(attributes []*commonpb.KeyValue) bool {
+	for _, attr := range attributes {
+		if attr.Key != r.Attribute {
+			continue
+		}
+		if len(r.Values) == 0 {
+			return true
+		}
+		value := attributeString(attr.Value)
+		for _, v := range r.Values {
+			if v == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// attributeString renders scalar attribute values as strings so rules can
+// match ids sent as either strings or integers.
+func attributeString(value *commonpb.AnyValue) string {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_IntValue:
+		return fmt.Sprintf("%d", v.IntValue)
+	case *commonpb.AnyValue_BoolValue:
+		return fmt.Sprintf("%t", v.BoolValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return fmt.Sprintf("%g", v.DoubleValue)
+	default:
+		return ""
+	}
+}
+
+func validateRules(rules []ExemptionRule) error {
+	for i, rule := range rules {
+		if rule.Attribute == "" {
+			return fmt.Errorf("exemption rule %d: attribute is required", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package sampling
+
+import (
+	"encoding/hex"
+	"sync"
+
+	"junjo-server/ingestion-service/metrics"
+
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// Sampler decides whether a span that is not exempt should be kept.
+type Sampler interface {
+	ShouldSample(span *tracepb.Span, resource *resourcepb.Resource) bool
+}
+
+// AlwaysSample keeps every span.
+type AlwaysSample struct{}
+
+// ShouldSample implements Sampler.
+func (AlwaysSample) ShouldSample(*tracepb.Span, *resourcepb.Resource) bool { return true }
+
+// maxExemptTraces bounds the number of remembered exempt trace ids.
+const maxExemptTraces = 10000
+
+var (
+	// ExemptSpans counts spans kept because of an exemption rule.
+	ExemptSpans = metrics.NewCounter("junjo_sampling_exempt_spans_total", "Spans kept because they matched a sampling exemption rule.")
+	// DroppedSpans counts spans dropped by the sampler.
+	DroppedSpans = metrics.NewCounter("junjo_sampling_dropped_spans_total", "Spans dropped by sampling.")
+)
+
+// Policy evaluates the exemption rules before delegating to the sampler.
+// Once a span matches a rule, every later span of the same trace is kept too,
+// so exempt traces are captured whole.
+type Policy struct {
+	Exemptions *Exemptions
+	Sampler    Sampler
+
+	mu           sync.Mutex
+	exemptTraces map[string]struct{}
+	exemptOrder  []string
+}
+
+// NewPolicy creates a Policy.
+func NewPolicy(exemptions *Exemptions, sampler Sampler) *Policy {
+	return &Policy{
+		Exemptions:   exemptions,
+		Sampler:      sampler,
+		exemptTraces: make(map[string]struct{}),
+	}
+}
+
+// Keep reports whether the span should be written to the WAL.
+func (p *Policy) Keep(span *tracepb.Span, resource *resourcepb.Resource) bool {
+	traceID := hex.EncodeToString(span.TraceId)
+	if p.isExemptTrace(traceID) {
+		ExemptSpans.Inc()
+		return true
+	}
+	if p.Exemptions != nil && p.Exemptions.Match(span, resource) {
+		p.markExemptTrace(traceID)
+		ExemptSpans.Inc()
+		return true
+	}
+
+	if p.Sampler == nil || p.Sampler.ShouldSample(span, resource) {
+		return true
+	}
+	DroppedSpans.Inc()
+	return false
+}
+
+func (p *Policy) isExemptTrace(traceID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.exemptTraces[traceID]
+	return ok
+}
+
+func (p *Policy) markExemptTrace(traceID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.exemptTraces[traceID]; ok {
+		return
+	}
+	if len(p.exemptOrder) >= maxExemptTraces {
+		delete(p.exemptTraces, p.exemptOrder[0])
+		p.exemptOrder = p.exemptOrder[1:]
+	}
+	p.exemptTraces[traceID] = struct{}{}
+	p.exemptOrder = append(p.exemptOrder, traceID)
+}
@@ -0,0 +1,204 @@
+package sampling
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// SamplingRule sets the fraction of spans kept for the spans it matches. Every
+// non-empty condition must hold: ServiceName equals the service.name resource
+// attribute, SpanName equals the span name (or, when it ends in *, prefixes
+// it), and the span or its resource has Attribute with one of Values, if any.
+type SamplingRule struct {
+	ServiceName string   `json:"service_name,omitempty"`
+	SpanName    string   `json:"span_name,omitempty"`
+	Attribute   string   `json:"attribute,omitempty"`
+	Values      []string `json:"values,omitempty"`
+	Rate        float64  `json:"rate"`
+}
+
+// SamplingConfig is the head sampling configuration. The first matching rule
+// sets the rate of a span; spans matching no rule are kept at DefaultRate,
+// which is 1 when omitted.
+type SamplingConfig struct {
+	DefaultRate float64        `json:"default_rate"`
+	Rules       []SamplingRule `json:"rules"`
+}
+
+// RuleSampler is a Sampler applying probabilistic, rule-based head sampling.
+// The decision is derived from the trace id, so the spans of a trace are kept
+// or dropped together. The configuration is persisted as JSON so changes made
+// through the settings API survive restarts.
+type RuleSampler struct {
+	mu     sync.RWMutex
+	config SamplingConfig
+	path   string
+}
+
+// LoadRuleSampler reads the sampling configuration from path. A missing file
+// keeps every span.
+func LoadRuleSampler(path string) (*RuleSampler, error) {
+	s := &RuleSampler{path: path, config: SamplingConfig{DefaultRate: 1, Rules: []SamplingRule{}}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sampling rules: %w", err)
+	}
+
+	config := SamplingConfig{DefaultRate: 1}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse sampling rules %s: %w", path, err)
+	}
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+	if config.Rules == nil {
+		config.Rules = []SamplingRule{}
+	}
+	s.config = config
+	return s, nil
+}
+
+// Config returns a copy of the current configuration.
+func (s *RuleSampler) Config() SamplingConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return SamplingConfig{
+		DefaultRate: s.config.DefaultRate,
+		Rules:       append([]SamplingRule{}, s.config.Rules...),
+	}
+}
+
+// SetConfig validates, persists, and applies a new configuration.
+func (s *RuleSampler) SetConfig(config SamplingConfig) error {
+	if config.Rules == nil {
+		config.Rules = []SamplingRule{}
+	}
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	// Write to a temporary file first so a crash never leaves a partial configuration.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sampling rules: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write sampling rules: %w", err)
+	}
+
+	s.config = config
+	return nil
+}
+
+// ShouldSample implements Sampler.
+func (s *RuleSampler) ShouldSample(span *tracepb.Span, resource *resourcepb.Resource) bool {
+	rate := s.rate(span, resource)
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	return traceRatio(span.TraceId) < rate
+}
+
+// rate returns the sampling rate of the first rule matching the span.
+func (s *RuleSampler) rate(span *tracepb.Span, resource *resourcepb.Resource) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, rule := range s.config.Rules {
+		if rule.matches(span, resource) {
+			return rule.Rate
+		}
+	}
+	return s.config.DefaultRate
+}
+
+func (r SamplingRule) matches(span *tracepb.Span, resource *resourcepb.Resource) bool {
+	if r.ServiceName != "" && serviceName(resource) != r.ServiceName {
+		return false
+	}
+	if r.SpanName != "" {
+		if prefix, ok := strings.CutSuffix(r.SpanName, "*"); ok {
+			if !strings.HasPrefix(span.Name, prefix) {
+				return false
+			}
+		} else if span.Name != r.SpanName {
+			return false
+		}
+	}
+	if r.Attribute != "" {
+		attribute := ExemptionRule{Attribute: r.Attribute, Values: r.Values}
+		if !attribute.matches(span.Attributes) && (resource == nil || !attribute.matches(resource.Attributes)) {
+			return false
+		}
+	}
+	return true
+}
+
+// serviceName returns the service.name resource attribute.
+func serviceName(resource *resourcepb.Resource) string {
+	for _, attr := range resource.GetAttributes() {
+		if attr.Key == "service.name" {
+			return attributeString(attr.Value)
+		}
+	}
+	return ""
+}
+
+// traceRatio maps a trace id to [0, 1) using its lower 8 bytes, which are
+// random in W3C trace ids, like the OpenTelemetry TraceIdRatioBased sampler.
+// Malformed trace ids are sampled at random.
+func traceRatio(traceID []byte) float64 {
+	if len(traceID) != 16 {
+		return rand.Float64()
+	}
+	return float64(binary.BigEndian.Uint64(traceID[8:])>>11) / (1 << 53)
+}
+
+func validateConfig(config SamplingConfig) error {
+	if !validRate(config.DefaultRate) {
+		return fmt.Errorf("default_rate must be between 0 and 1")
+	}
+	for i, rule := range config.Rules {
+		if !validRate(rule.Rate) {
+			return fmt.Errorf("sampling rule %d: rate must be between 0 and 1", i)
+		}
+		if len(rule.Values) > 0 && rule.Attribute == "" {
+			return fmt.Errorf("sampling rule %d: values require an attribute", i)
+		}
+	}
+	return nil
+}
+
+func validRate(rate float64) bool {
+	return !math.IsNaN(rate) && rate >= 0 && rate <= 1
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"junjo-server/ingestion-service/metrics"
+	"junjo-server/ingestion-service/requestid"
+	"junjo-server/ingestion-service/sampling"
+	"junjo-server/ingestion-service/storage"
+)
+
+// NewAdminHTTPServer creates the internal HTTP server for operational
+// endpoints such as metrics and settings. It must not be exposed publicly.
+// It serves debug endpoints when the debug token is set. Settings, read or
+// changed, and changes to the drain require the admin bearer token, and are
+// rejected when it is not set.
+func NewAdminHTTPServer(exemptions *sampling.Exemptions, sampler *sampling.RuleSampler, drain *Drain, store storage.Storage, cfg Config) *http.Server {
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /settings/sampling/exemptions", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, exemptions.Rules())
+	})
+	mux.HandleFunc("PUT /settings/sampling/exemptions", func(w http.ResponseWriter, r *http.Request) {
+		var rules []sampling.ExemptionRule
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		if err := exemptions.SetRules(rules); err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, exemptions.Rules())
+	})
+	mux.HandleFunc("GET /settings/sampling/rules", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, sampler.Config())
+	})
+	mux.HandleFunc("PUT /settings/sampling/rules", func(w http.ResponseWriter, r *http.Request) {
+		config := sampling.SamplingConfig{DefaultRate: 1}
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		if err := sampler.SetConfig(config); err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, sampler.Config())
+	})
+
+	// Drain: POST stops accepting exports before a deployment, GET reports
+	// whether the backend has finished reading the WAL, DELETE resumes.
+	mux.HandleFunc("GET /drain", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, drain.Status())
+	})
+	mux.HandleFunc("POST /drain", func(w http.ResponseWriter, r *http.Request) {
+		drain.Start()
+		log.Println("Drain started: rejecting new exports.")
+		writeJSON(w, http.StatusAccepted, drain.Status())
+	})
+	mux.HandleFunc("DELETE /drain", func(w http.ResponseWriter, r *http.Request) {
+		drain.Stop()
+		log.Println("Drain stopped: accepting exports.")
+		writeJSON(w, http.StatusOK, drain.Status())
+	})
+
+	if registerDebugRoutes(mux, store, cfg.DebugToken) {
+		log.Println("Admin debug endpoints enabled under /debug")
+	}
+
+	if cfg.AdminToken == "" {
+		log.Println("Warning: ADMIN_TOKEN is not set. Admin settings can't be read or changed, nor the drain.")
+	}
+
+	return &http.Server{
+		Addr:    cfg.AdminAddr,
+		Handler: requireAdminToken(cfg.AdminToken, mux),
+	}
+}
+
+// requireAdminToken rejects requests without the bearer token, or all of them
+// when the token is empty, except reads of /metrics and the drain status,
+// which scrapers and deployment scripts poll. Sampling settings name customer
+// attributes, so reading them requires the token too. Debug endpoints check
+// their own token.
+func requireAdminToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isOpenAdminRead(r) || strings.HasPrefix(r.URL.Path, "/debug/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if token == "" {
+			writeError(w, r, http.StatusForbidden, "ADMIN_TOKEN is not set")
+			return
+		}
+		if !hasBearerToken(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, http.StatusUnauthorized, "invalid or missing admin token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isOpenAdminRead reports whether the request reads an endpoint served without
+// the admin token.
+func isOpenAdminRead(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	return r.URL.Path == "/metrics" || r.URL.Path == "/drain"
+}
+
+// hasBearerToken reports whether the request is authorized with the token.
+func hasBearerToken(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// errorResponse is the body of error responses, shared with the backend's
+// API: {"code": "bad_request", "message": "...", "request_id": "..."}.
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeError writes an error response with the caller's X-Request-ID, or a
+// new request ID.
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	id := r.Header.Get("X-Request-ID")
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+	w.Header().Set("X-Request-ID", id)
+	writeJSON(w, status, errorResponse{Code: errorCode(status), Message: message, RequestID: id})
+}
+
+// errorCode returns the code of an HTTP status: its status text in snake
+// case, such as "bad_request".
+func errorCode(status int) string {
+	if status == http.StatusInternalServerError {
+		return "internal_error"
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
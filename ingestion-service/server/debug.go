@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"junjo-server/ingestion-service/storage"
+)
+
+// startedAt is when the ingestion service started.
+var startedAt = time.Now()
+
+// registerDebugRoutes serves the net/http/pprof profiles under /debug/pprof/
+// and the runtime and WAL stats at /debug/runtime on the admin server. The
+// routes require the debug bearer token, and are not registered when it is
+// empty.
+func registerDebugRoutes(mux *http.ServeMux, store storage.Storage, token string) bool {
+	if token == "" {
+		return false
+	}
+	authorized := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !hasBearerToken(r, token) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, r, http.StatusUnauthorized, "invalid or missing debug token")
+				return
+			}
+			handler(w, r)
+		}
+	}
+
+	mux.HandleFunc("GET /debug/runtime", authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"runtime": runtimeStats(),
+			"wal":     store.Stats(),
+		})
+	}))
+	// Index also serves the named profiles such as heap, goroutine and allocs
+	mux.HandleFunc("GET /debug/pprof/", authorized(pprof.Index))
+	mux.HandleFunc("GET /debug/pprof/cmdline", authorized(pprof.Cmdline))
+	mux.HandleFunc("GET /debug/pprof/profile", authorized(pprof.Profile))
+	mux.HandleFunc("GET /debug/pprof/symbol", authorized(pprof.Symbol))
+	mux.HandleFunc("POST /debug/pprof/symbol", authorized(pprof.Symbol))
+	mux.HandleFunc("GET /debug/pprof/trace", authorized(pprof.Trace))
+	return true
+}
+
+// runtimeStats reads the Go runtime stats. It briefly stops the world to read
+// the memory stats.
+func runtimeStats() map[string]any {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var lastGC *time.Time
+	if mem.LastGC > 0 {
+		t := time.Unix(0, int64(mem.LastGC)).UTC()
+		lastGC = &t
+	}
+	return map[string]any{
+		"go_version": runtime.Version(),
+		"started_at": startedAt.UTC(),
+		"uptime":     time.Since(startedAt).Round(time.Second).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"num_cpu":    runtime.NumCPU(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"heap": map[string]uint64{
+			"alloc_bytes":    mem.HeapAlloc,
+			"inuse_bytes":    mem.HeapInuse,
+			"idle_bytes":     mem.HeapIdle,
+			"released_bytes": mem.HeapReleased,
+			"sys_bytes":      mem.HeapSys,
+			"objects":        mem.HeapObjects,
+		},
+		"gc": map[string]any{
+			"num_gc":        mem.NumGC,
+			"pause_total":   time.Duration(mem.PauseTotalNs).String(),
+			"last_gc":       lastGC,
+			"next_gc_bytes": mem.NextGC,
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// drainRetryDelay is the retry hint sent to exporters while draining.
+const drainRetryDelay = 30 * time.Second
+
+// Drain coordinates a graceful drain before a deployment: while draining, the
+// public server rejects OTLP exports with UNAVAILABLE and a retry hint, and the
+// backend keeps reading the WAL until it has caught up.
+type Drain struct {
+	mu             sync.Mutex
+	draining       bool
+	startedAt      time.Time
+	inFlight       int
+	lastExportDone time.Time
+	caughtUpAt     time.Time // Start of the latest read that reached the end of the WAL
+}
+
+// DrainStatus reports the drain state. Drained is true once no exports are in
+// flight and the backend has read every span written to the WAL.
+type DrainStatus struct {
+	Draining  bool       `json:"draining"`
+	StartedAt *time.Time `json:"started_at"`
+	InFlight  int        `json:"in_flight"`
+	Drained   bool       `json:"drained"`
+}
+
+// NewDrain creates a Drain that accepts exports.
+func NewDrain() *Drain {
+	return &Drain{}
+}
+
+// Start stops accepting new exports.
+func (d *Drain) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.draining {
+		d.draining = true
+		d.startedAt = time.Now()
+	}
+}
+
+// Stop accepts exports again.
+func (d *Drain) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.draining = false
+}
+
+// Status returns the current drain state.
+func (d *Drain) Status() DrainStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := DrainStatus{Draining: d.draining, InFlight: d.inFlight}
+	if d.draining {
+		startedAt := d.startedAt
+		s.StartedAt = &startedAt
+		s.Drained = d.inFlight == 0 && d.caughtUpAt.After(d.startedAt) && d.caughtUpAt.After(d.lastExportDone)
+	}
+	return s
+}
+
+// ReadFinished records a WAL read that started at startedAt. caughtUp is true
+// when the read returned every remaining span.
+func (d *Drain) ReadFinished(startedAt time.Time, caughtUp bool) {
+	if !caughtUp {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if startedAt.After(d.caughtUpAt) {
+		d.caughtUpAt = startedAt
+	}
+}
+
+// UnaryInterceptor rejects requests while draining, and tracks the requests in
+// flight otherwise.
+func (d *Drain) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		d.mu.Lock()
+		if d.draining {
+			d.mu.Unlock()
+			return nil, unavailableWhileDraining()
+		}
+		d.inFlight++
+		d.mu.Unlock()
+
+		defer func() {
+			d.mu.Lock()
+			d.inFlight--
+			d.lastExportDone = time.Now()
+			d.mu.Unlock()
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// unavailableWhileDraining builds the UNAVAILABLE error returned while
+// draining. OTLP exporters honor the RetryInfo detail as a backoff hint.
+func unavailableWhileDraining() error {
+	st := status.New(codes.Unavailable, "ingestion service is draining for a deployment, retry later")
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(drainRetryDelay)})
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"log"
 
+	"junjo-server/ingestion-service/sampling"
 	"junjo-server/ingestion-service/storage"
 
 	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
@@ -12,13 +13,15 @@ import (
 
 type OtelTraceService struct {
 	coltracepb.UnimplementedTraceServiceServer
-	store *storage.Storage
+	store  storage.Storage
+	policy *sampling.Policy
 }
 
 // NewOtelTraceService creates a new trace service.
-func NewOtelTraceService(store *storage.Storage) *OtelTraceService {
+func NewOtelTraceService(store storage.Storage, policy *sampling.Policy) *OtelTraceService {
 	return &OtelTraceService{
-		store: store,
+		store:  store,
+		policy: policy,
 	}
 }
 
@@ -31,6 +34,10 @@ func (s *OtelTraceService) Export(ctx context.Context, req *coltracepb.ExportTra
 				spanID := hex.EncodeToString(span.SpanId)
 				log.Printf("Received Span ID: %s, Trace ID: %s, Name: %s", spanID, traceID, span.Name)
 
+				if !s.policy.Keep(span, resource) {
+					continue
+				}
+
 				// Write the span to the WAL
 				if err := s.store.WriteSpan(span, resource); err != nil {
 					log.Printf("Error writing span to WAL: %v", err)
@@ -2,13 +2,17 @@ package server
 
 import (
 	"fmt"
+	"log"
 	"net"
-	"os"
 
 	"junjo-server/ingestion-service/backend_client"
+	"junjo-server/ingestion-service/grpc_options"
+	"junjo-server/ingestion-service/requestid"
+	"junjo-server/ingestion-service/sampling"
 	"junjo-server/ingestion-service/storage"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
@@ -21,48 +25,105 @@ import (
 	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
 )
 
+// Config configures the servers.
+type Config struct {
+	// PublicAddr, InternalAddr and AdminAddr are the host:port addresses the
+	// public gRPC, internal gRPC and admin HTTP servers bind to.
+	PublicAddr   string
+	InternalAddr string
+	AdminAddr    string
+
+	// PublicCertFile and PublicKeyFile are the certificate the public server
+	// serves TLS with, when set.
+	PublicCertFile string
+	PublicKeyFile  string
+
+	// InternalCertFile, InternalKeyFile and InternalCAFile are the
+	// certificate of the internal server and the CA its clients must be
+	// signed by, when set.
+	InternalCertFile string
+	InternalKeyFile  string
+	InternalCAFile   string
+
+	// Reflection registers gRPC reflection on both gRPC servers.
+	Reflection bool
+
+	// AdminToken is required by admin requests changing state, and
+	// DebugToken by the debug endpoints.
+	AdminToken string
+	DebugToken string
+
+	// GRPC tunes the message sizes, keepalive and timeouts of the gRPC
+	// servers.
+	GRPC grpc_options.Config
+}
+
 // NewGRPCServer creates and configures the gRPC server for the ingestion service.
-func NewGRPCServer(store *storage.Storage, authClient *backend_client.AuthClient) (*grpc.Server, net.Listener, error) {
-	listenAddr := ":50051"
-	if port := os.Getenv("GRPC_PORT"); port != "" {
-		listenAddr = ":" + port
+// It serves TLS when the public certificate is configured.
+func NewGRPCServer(store storage.Storage, authClient *backend_client.AuthClient, policy *sampling.Policy, drain *Drain, cfg Config) (*grpc.Server, net.Listener, error) {
+	tlsConfig, err := serverTLSConfig(cfg.PublicCertFile, cfg.PublicKeyFile)
+	if err != nil {
+		return nil, nil, err
 	}
-	lis, err := net.Listen("tcp", listenAddr)
+
+	lis, err := net.Listen("tcp", cfg.PublicAddr)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to listen: %v", err)
 	}
 
 	// --- Initialize Services ---
-	otelTraceSvc := NewOtelTraceService(store)
+	otelTraceSvc := NewOtelTraceService(store, policy)
 	otelLogsSvc := NewOtelLogsService()
 	otelMetricSvc := NewOtelMetricService()
 
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(ApiKeyAuthInterceptor(authClient)),
-	)
+	opts := append(cfg.GRPC.ServerOptions(), grpc.ChainUnaryInterceptor(
+		requestid.UnaryServerInterceptor(),
+		drain.UnaryInterceptor(),
+		ApiKeyAuthInterceptor(authClient),
+	))
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		log.Println("Public gRPC server serves TLS")
+	}
+	grpcServer := grpc.NewServer(opts...)
 
 	// Register services
 	coltracepb.RegisterTraceServiceServer(grpcServer, otelTraceSvc)
 	colmetricpb.RegisterMetricsServiceServer(grpcServer, otelMetricSvc)
 	collogspb.RegisterLogsServiceServer(grpcServer, otelLogsSvc)
 
-	reflection.Register(grpcServer)
+	if cfg.Reflection {
+		reflection.Register(grpcServer)
+	}
 
 	return grpcServer, lis, nil
 }
 
 // NewInternalGRPCServer creates a new gRPC server for internal services.
-func NewInternalGRPCServer(store *storage.Storage) (*grpc.Server, net.Listener, error) {
-	listenAddr := ":50052" // Default internal port
-	lis, err := net.Listen("tcp", listenAddr)
+// It requires mutual TLS when the internal certificate and CA are configured.
+func NewInternalGRPCServer(store storage.Storage, drain *Drain, cfg Config) (*grpc.Server, net.Listener, error) {
+	tlsConfig, err := mutualTLSConfig(cfg.InternalCertFile, cfg.InternalKeyFile, cfg.InternalCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lis, err := net.Listen("tcp", cfg.InternalAddr)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to listen on internal port: %v", err)
 	}
 
 	// --- Initialize Internal Services ---
-	walReaderSvc := NewWALReaderService(store)
+	walReaderSvc := NewWALReaderService(store, drain)
 
-	grpcServer := grpc.NewServer()
+	opts := append(cfg.GRPC.ServerOptions(),
+		grpc.ChainUnaryInterceptor(requestid.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(requestid.StreamServerInterceptor()),
+	)
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		log.Println("Internal gRPC server requires mutual TLS")
+	}
+	grpcServer := grpc.NewServer(opts...)
 
 	// Register Internal services
 	pb.RegisterInternalIngestionServiceServer(grpcServer, walReaderSvc)
@@ -71,7 +132,9 @@ func NewInternalGRPCServer(store *storage.Storage) (*grpc.Server, net.Listener,
 	healthServer := health.NewServer()
 	healthpb.RegisterHealthServer(grpcServer, healthServer)
 
-	reflection.Register(grpcServer)
+	if cfg.Reflection {
+		reflection.Register(grpcServer)
+	}
 
 	return grpcServer, lis, nil
 }
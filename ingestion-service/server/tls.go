@@ -0,0 +1,127 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloadInterval is how often the certificate files are checked for
+// changes while serving.
+var certReloadInterval = 10 * time.Second
+
+// certReloader serves a certificate and key pair from disk, reloading it when
+// either file changes so that rotated certificates are picked up without a
+// restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTimes  [2]time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader loads a certificate and key pair.
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.lastCheck = time.Now()
+	return r, nil
+}
+
+// load reads the certificate and key pair from disk.
+func (r *certReloader) load() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate %s and key %s: %w", r.certFile, r.keyFile, err)
+	}
+	r.cert = &cert
+	r.modTimes = modTimes
+	return nil
+}
+
+// stat returns the modification times of the certificate and key files.
+func (r *certReloader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// GetCertificate returns the current certificate, reloading it first when the
+// files changed since the last check. A failed reload keeps serving the
+// previous certificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := time.Now(); now.Sub(r.lastCheck) >= certReloadInterval {
+		r.lastCheck = now
+		if modTimes, err := r.stat(); err != nil {
+			log.Printf("Warning: keeping the current TLS certificate: %v", err)
+		} else if modTimes != r.modTimes {
+			if err := r.load(); err != nil {
+				log.Printf("Warning: keeping the current TLS certificate: %v", err)
+			} else {
+				log.Printf("Reloaded TLS certificate %s", r.certFile)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// serverTLSConfig returns the TLS configuration of a server with the
+// certificate and key in certFile and keyFile, or nil when they are not set.
+func serverTLSConfig(certFile string, keyFile string) (*tls.Config, error) {
+	if certFile == "" {
+		return nil, nil
+	}
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}, nil
+}
+
+// mutualTLSConfig returns the TLS configuration of a server that requires
+// clients to present a certificate signed by the CA in caFile, or nil when
+// the files are not set.
+func mutualTLSConfig(certFile string, keyFile string, caFile string) (*tls.Config, error) {
+	tlsConfig, err := serverTLSConfig(certFile, keyFile)
+	if err != nil || tlsConfig == nil {
+		return nil, err
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("TLS CA file %s holds no PEM certificates", caFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsConfig, nil
+}
@@ -5,23 +5,26 @@ import (
 	pb "junjo-server/ingestion-service/proto_gen"
 	"junjo-server/ingestion-service/storage"
 	"log"
+	"time"
 )
 
 // WALReaderService implements the gRPC server for reading from the WAL.
 type WALReaderService struct {
 	pb.UnimplementedInternalIngestionServiceServer
-	Store *storage.Storage
+	Store storage.Storage
+	Drain *Drain
 }
 
 // NewWALReaderService creates a new WALReaderService.
-func NewWALReaderService(store *storage.Storage) *WALReaderService {
-	return &WALReaderService{Store: store}
+func NewWALReaderService(store storage.Storage, drain *Drain) *WALReaderService {
+	return &WALReaderService{Store: store, Drain: drain}
 }
 
 // ReadSpans streams spans from the BadgerDB WAL to the client.
 func (s *WALReaderService) ReadSpans(req *pb.ReadSpansRequest, stream pb.InternalIngestionService_ReadSpansServer) error {
 	log.Printf("Received ReadSpans request. StartKey: %x, BatchSize: %d", req.StartKeyUlid, req.BatchSize)
 
+	startedAt := time.Now()
 	var spansStreamed int32
 	sendFunc := func(key, spanBytes, resourceBytes []byte) error {
 		res := &pb.ReadSpansResponse{
@@ -44,6 +47,9 @@ func (s *WALReaderService) ReadSpans(req *pb.ReadSpansRequest, stream pb.Interna
 		return err
 	}
 
+	// A partial batch means the reader has caught up with the WAL.
+	s.Drain.ReadFinished(startedAt, uint32(spansStreamed) < req.BatchSize)
+
 	if spansStreamed == 0 {
 		log.Printf("No spans found in storage for request. StartKey: %x, BatchSize: %d", req.StartKeyUlid, req.BatchSize)
 	} else {
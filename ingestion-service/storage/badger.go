@@ -1,78 +1,70 @@
 package storage
 
 import (
-	"crypto/rand"
+	"bytes"
+	"errors"
 	"log"
-	"sync"
-	"time"
 
 	badger "github.com/dgraph-io/badger/v4"
-	"github.com/oklog/ulid/v2"
 	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 	"google.golang.org/protobuf/proto"
 )
 
-// --- Monotonic ULID Generator ---
-
-var (
-	// ulidGenerator is a single, shared monotonic entropy source protected by a mutex.
-	// This ensures that even if multiple goroutines call for a new ID in the same
-	// millisecond, each call will produce a unique and strictly increasing ULID.
-	ulidGenerator = struct {
-		sync.Mutex
-		*ulid.MonotonicEntropy
-	}{
-		// We pass crypto/rand.Reader as the initial entropy source, and 0 for the increment.
-		MonotonicEntropy: ulid.Monotonic(rand.Reader, 0),
-	}
-)
-
-// newULID generates a new, monotonic ULID in a thread-safe manner.
-func newULID() (ulid.ULID, error) {
-	ulidGenerator.Lock()
-	defer ulidGenerator.Unlock()
-
-	// ulid.New requires the time in milliseconds and an entropy source.
-	// We provide the current time and our locked monotonic entropy source.
-	// The MonotonicEntropy will ensure the random part of the ULID is incremented
-	// if we are in the same millisecond as the previous call.
-	return ulid.New(ulid.Timestamp(time.Now()), &ulidGenerator)
-}
+// --- BadgerDB Implementation ---
 
-// --- Storage Implementation ---
-
-// Storage provides an interface for interacting with the BadgerDB instance.
-type Storage struct {
+// BadgerStorage is a Storage implementation backed by a BadgerDB instance.
+type BadgerStorage struct {
 	db *badger.DB
 }
 
-// NewStorage initializes a new BadgerDB instance at the specified path.
-func NewStorage(path string) (*Storage, error) {
+// NewBadgerStorage initializes a new BadgerDB instance at the specified path.
+func NewBadgerStorage(path string) (*BadgerStorage, error) {
 	opts := badger.DefaultOptions(path)
 	db, err := badger.Open(opts)
 	if err != nil {
 		return nil, err
 	}
 	log.Printf("BadgerDB opened successfully at path: %s", path)
-	return &Storage{db: db}, nil
+	return &BadgerStorage{db: db}, nil
 }
 
 // Close safely closes the BadgerDB connection.
-func (s *Storage) Close() error {
+func (s *BadgerStorage) Close() error {
 	log.Println("Closing BadgerDB...")
 	return s.db.Close()
 }
 
+// Stats reports the sizes of the LSM tree and value log and the tables of
+// each LSM level.
+func (s *BadgerStorage) Stats() map[string]any {
+	lsmSize, vlogSize := s.db.Size()
+	levels := []map[string]any{}
+	for _, level := range s.db.Levels() {
+		levels = append(levels, map[string]any{
+			"level":             level.Level,
+			"tables":            level.NumTables,
+			"size_bytes":        level.Size,
+			"target_size_bytes": level.TargetSize,
+		})
+	}
+	return map[string]any{
+		"backend":         BackendBadger,
+		"lsm_size_bytes":  lsmSize,
+		"vlog_size_bytes": vlogSize,
+		"levels":          levels,
+	}
+}
+
 // Sync flushes all pending writes to disk.
-func (s *Storage) Sync() error {
+func (s *BadgerStorage) Sync() error {
 	log.Println("Syncing BadgerDB to disk...")
 	return s.db.Sync()
 }
 
 // WriteSpan serializes a SpanData struct and writes it to BadgerDB.
 // The key is a monotonic ULID to ensure chronological order and prevent collisions.
-func (s *Storage) WriteSpan(span *tracepb.Span, resource *resourcepb.Resource) error {
+func (s *BadgerStorage) WriteSpan(span *tracepb.Span, resource *resourcepb.Resource) error {
 	// Create a SpanData struct
 	spanData := &SpanData{
 		Span:     span,
@@ -93,15 +85,20 @@ func (s *Storage) WriteSpan(span *tracepb.Span, resource *resourcepb.Resource) e
 
 	// Perform the write within a transaction
 	return s.db.Update(func(txn *badger.Txn) error {
-		// The key is the binary representation of the ULID.
-		return txn.Set(key[:], dataBytes)
+		// The key is the binary representation of the ULID. The value carries a
+		// checksum so corruption can be detected on read.
+		return txn.Set(key[:], encodeValue(dataBytes))
 	})
 }
 
 // ReadSpans iterates through the database and sends key-value pairs to the provided channel.
 // It uses prefetching to optimize for sequential reads.
-func (s *Storage) ReadSpans(startKey []byte, batchSize uint32, sendFunc func(key, spanBytes, resourceBytes []byte) error) error {
-	return s.db.View(func(txn *badger.Txn) error {
+// Entries that fail checksum verification or decoding are skipped. They are
+// counted and copied to the quarantine prefix the first time they are read.
+func (s *BadgerStorage) ReadSpans(startKey []byte, batchSize uint32, sendFunc func(key, spanBytes, resourceBytes []byte) error) error {
+	var corrupt []corruptEntry
+
+	err := s.db.View(func(txn *badger.Txn) error {
 		// Enable prefetching for faster iteration. The default prefetch size is 100.
 		opts := badger.DefaultIteratorOptions
 		opts.PrefetchValues = true
@@ -124,6 +121,11 @@ func (s *Storage) ReadSpans(startKey []byte, batchSize uint32, sendFunc func(key
 			item := it.Item()
 			key := item.Key()
 
+			// Quarantined entries sort after every ULID key, so the WAL ends here.
+			if bytes.HasPrefix(key, quarantinePrefix) {
+				break
+			}
+
 			// ValueCopy is used here because we need to send the value over the stream.
 			// The callback-based Value() is more for cases where the value might be discarded.
 			val, err := item.ValueCopy(nil)
@@ -131,11 +133,15 @@ func (s *Storage) ReadSpans(startKey []byte, batchSize uint32, sendFunc func(key
 				return err
 			}
 
-			// Unmarshal the value to SpanData
-			spanData, err := UnmarshalSpanData(val)
+			// Verify the checksum and unmarshal the value to SpanData
+			spanData, err := decodeSpanData(val)
 			if err != nil {
-				log.Printf("Error unmarshaling span data: %v", err)
-				// Skip corrupted data
+				// A corrupt entry at the tail is read again by every poll.
+				if _, getErr := txn.Get(quarantineKey(key)); errors.Is(getErr, badger.ErrKeyNotFound) {
+					log.Printf("Skipping corrupt WAL entry %x: %v", key, err)
+					CorruptEntries.Inc()
+					corrupt = append(corrupt, corruptEntry{key: item.KeyCopy(nil), value: val})
+				}
 				count++
 				it.Next()
 				continue
@@ -167,4 +173,56 @@ func (s *Storage) ReadSpans(startKey []byte, batchSize uint32, sendFunc func(key
 		}
 		return nil
 	})
+
+	// Quarantine after the read-only transaction has finished.
+	if len(corrupt) > 0 {
+		s.quarantine(corrupt)
+	}
+
+	return err
+}
+
+// decodeSpanData verifies a WAL value's checksum and unmarshals it.
+func decodeSpanData(value []byte) (*SpanData, error) {
+	payload, err := decodeValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalSpanData(payload)
+}
+
+// --- Quarantine ---
+
+// quarantinePrefix namespaces copies of corrupt entries. The leading 0xff byte
+// sorts it after every ULID key, keeping quarantined entries out of the WAL
+// read path.
+var quarantinePrefix = []byte("\xffquarantine/")
+
+// quarantineKey returns the key a corrupt entry is copied to.
+func quarantineKey(key []byte) []byte {
+	return append(append([]byte(nil), quarantinePrefix...), key...)
+}
+
+type corruptEntry struct {
+	key   []byte
+	value []byte
+}
+
+// quarantine copies corrupt entries under quarantinePrefix so they can be
+// inspected later instead of being lost.
+func (s *BadgerStorage) quarantine(entries []corruptEntry) {
+	err := s.db.Update(func(txn *badger.Txn) error {
+		for _, entry := range entries {
+			if err := txn.Set(quarantineKey(entry.key), entry.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to quarantine %d corrupt WAL entries: %v", len(entries), err)
+		return
+	}
+	QuarantinedEntries.Add(uint64(len(entries)))
+	log.Printf("Quarantined %d corrupt WAL entries", len(entries))
 }
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+
+	"junjo-server/ingestion-service/metrics"
+)
+
+// --- Value Checksums ---
+//
+// WAL values are framed as:
+//
+//	[0x00 marker][1 byte version][4 byte CRC32 of payload][payload]
+//
+// A serialized protobuf message can never start with 0x00 (field number 0 is
+// invalid), so values written before checksums were introduced are detected by
+// their first byte and read without verification.
+
+const (
+	checksumMarker     byte = 0x00
+	checksumVersion    byte = 1
+	checksumHeaderSize      = 6
+)
+
+var errChecksumMismatch = errors.New("WAL value checksum mismatch")
+
+var (
+	// CorruptEntries counts WAL entries skipped by ReadSpans because they
+	// failed checksum verification or could not be decoded.
+	CorruptEntries = metrics.NewCounter("junjo_wal_corrupt_entries_total", "WAL entries skipped because they failed checksum verification or decoding.")
+
+	// QuarantinedEntries counts corrupt WAL entries copied to quarantine.
+	QuarantinedEntries = metrics.NewCounter("junjo_wal_quarantined_entries_total", "Corrupt WAL entries copied to quarantine for later inspection.")
+)
+
+// encodeValue prepends the checksum header to a payload.
+func encodeValue(payload []byte) []byte {
+	value := make([]byte, checksumHeaderSize+len(payload))
+	value[0] = checksumMarker
+	value[1] = checksumVersion
+	binary.BigEndian.PutUint32(value[2:6], crc32.ChecksumIEEE(payload))
+	copy(value[checksumHeaderSize:], payload)
+	return value
+}
+
+// decodeValue verifies and strips the checksum header from a value.
+func decodeValue(value []byte) ([]byte, error) {
+	if len(value) == 0 || value[0] != checksumMarker {
+		// Legacy value written without a checksum.
+		return value, nil
+	}
+	if len(value) < checksumHeaderSize || value[1] != checksumVersion {
+		return nil, errChecksumMismatch
+	}
+
+	payload := value[checksumHeaderSize:]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(value[2:6]) {
+		return nil, errChecksumMismatch
+	}
+	return payload, nil
+}
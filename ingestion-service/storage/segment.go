@@ -0,0 +1,482 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	containerpb "junjo-server/ingestion-service/proto_gen"
+
+	"github.com/oklog/ulid/v2"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// --- File-Segment Implementation ---
+//
+// SegmentStorage is an append-only WAL made of fixed-size segment files. It
+// avoids the LSM compaction overhead of BadgerDB, which makes it a better fit
+// for very high throughput deployments where spans are written once and read
+// sequentially.
+//
+// Each segment is named after the ULID of its first record. Records are laid
+// out as:
+//
+//	[16 byte ULID key][4 byte payload length][4 byte CRC32 of payload][payload]
+//
+// where the payload is a serialized SpanDataContainer. Records whose checksum
+// doesn't match are copied to the quarantine subdirectory and skipped. A record
+// with an implausible length can't be skipped, so the rest of its segment is
+// quarantined instead.
+
+const (
+	segmentExt            = ".seg"
+	defaultMaxSegmentSize = 64 << 20 // 64 MiB
+	recordHeaderSize      = 16 + 4 + 4
+)
+
+var (
+	// errCorruptRecord is returned for a record whose payload doesn't match its
+	// checksum. Its length is intact, so the record can be skipped.
+	errCorruptRecord = errors.New("corrupt WAL record")
+	// errInvalidLength is returned for a record whose length can't be trusted,
+	// so nothing after it in the segment can be read.
+	errInvalidLength = errors.New("invalid WAL record length")
+)
+
+// segment describes a single segment file.
+type segment struct {
+	path     string
+	firstKey ulid.ULID
+	size     int64 // Bytes of complete records in the file.
+}
+
+// readCursor remembers where the previous ReadSpans call stopped, so the next
+// poll for the following batch doesn't have to scan from the segment start.
+type readCursor struct {
+	key     []byte
+	segment int
+	offset  int64
+}
+
+// SegmentStorage is a Storage implementation backed by append-only segment files.
+type SegmentStorage struct {
+	mu             sync.Mutex
+	dir            string
+	maxSegmentSize int64
+	segments       []*segment
+	active         *os.File
+
+	cursorMu sync.Mutex
+	cursor   readCursor
+}
+
+// NewSegmentStorage opens (or creates) a segment WAL in the given directory.
+// A partially written record at the tail of the last segment, left behind by
+// a crash, is truncated away, and corrupt records in it are quarantined.
+func NewSegmentStorage(dir string) (*SegmentStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create segment directory: %w", err)
+	}
+
+	s := &SegmentStorage{
+		dir:            dir,
+		maxSegmentSize: defaultMaxSegmentSize,
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+	// ULIDs sort lexicographically in time order, and so do the file names.
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), segmentExt)
+		firstKey, err := ulid.ParseStrict(name)
+		if err != nil {
+			log.Printf("Ignoring unexpected file in segment directory: %s", path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, &segment{path: path, firstKey: firstKey, size: info.Size()})
+	}
+
+	if len(s.segments) > 0 {
+		last := s.segments[len(s.segments)-1]
+		if err := s.recoverSegment(last); err != nil {
+			return nil, err
+		}
+		s.active, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open active segment: %w", err)
+		}
+	}
+
+	log.Printf("Segment WAL opened successfully at path: %s (%d segments)", dir, len(s.segments))
+	return s, nil
+}
+
+// recoverSegment truncates an incomplete record at the tail of the segment.
+// Records failing their checksum are quarantined and kept, so the records
+// after them stay readable. After a record with an invalid length, the rest of
+// the segment is unreadable: it is quarantined and truncated.
+func (s *SegmentStorage) recoverSegment(seg *segment) error {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return fmt.Errorf("failed to open segment for recovery: %w", err)
+	}
+	defer f.Close()
+
+	var valid int64
+	r := bufio.NewReader(f)
+	for valid < seg.size {
+		_, _, n, err := readRecord(r)
+		if errors.Is(err, errCorruptRecord) {
+			if s.quarantine(*seg, valid, n) {
+				log.Printf("Skipping corrupt record in segment %s at offset %d", seg.path, valid)
+				CorruptEntries.Inc()
+			}
+			valid += n
+			continue
+		}
+		if errors.Is(err, errInvalidLength) {
+			if s.quarantine(*seg, valid, seg.size-valid) {
+				CorruptEntries.Inc()
+			}
+			break
+		}
+		if err != nil {
+			// io.EOF or io.ErrUnexpectedEOF: the record was partially written.
+			break
+		}
+		valid += n
+	}
+
+	if valid < seg.size {
+		log.Printf("Truncating %d trailing bytes from segment %s", seg.size-valid, seg.path)
+		if err := os.Truncate(seg.path, valid); err != nil {
+			return fmt.Errorf("failed to truncate segment: %w", err)
+		}
+		seg.size = valid
+	}
+	return nil
+}
+
+// Close syncs and closes the active segment.
+func (s *SegmentStorage) Close() error {
+	log.Println("Closing segment WAL...")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	if err := s.active.Sync(); err != nil {
+		return err
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+// Stats reports the segment files and where the last read stopped.
+func (s *SegmentStorage) Stats() map[string]any {
+	s.mu.Lock()
+	segments := len(s.segments)
+	var size int64
+	for _, seg := range s.segments {
+		size += seg.size
+	}
+	s.mu.Unlock()
+
+	s.cursorMu.Lock()
+	cursor := s.cursor
+	s.cursorMu.Unlock()
+
+	return map[string]any{
+		"backend":                BackendSegment,
+		"dir":                    s.dir,
+		"segments":               segments,
+		"size_bytes":             size,
+		"max_segment_size_bytes": s.maxSegmentSize,
+		"read_cursor_segment":    cursor.segment,
+		"read_cursor_offset":     cursor.offset,
+	}
+}
+
+// Sync flushes the active segment to disk.
+func (s *SegmentStorage) Sync() error {
+	log.Println("Syncing segment WAL to disk...")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	return s.active.Sync()
+}
+
+// WriteSpan serializes the span and its resource and appends it to the active segment.
+func (s *SegmentStorage) WriteSpan(span *tracepb.Span, resource *resourcepb.Resource) error {
+	dataBytes, err := MarshalSpanData(&SpanData{
+		Span:     span,
+		Resource: resource,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The key is generated under the lock so records are appended in key order.
+	key, err := newULID()
+	if err != nil {
+		return err
+	}
+	record := encodeRecord(key, dataBytes)
+
+	if s.active == nil || s.segments[len(s.segments)-1].size+int64(len(record)) > s.maxSegmentSize {
+		if err := s.rollSegment(key); err != nil {
+			return err
+		}
+	}
+
+	current := s.segments[len(s.segments)-1]
+	if _, err := s.active.Write(record); err != nil {
+		// Drop any partial write so the segment stays readable.
+		if truncErr := s.active.Truncate(current.size); truncErr != nil {
+			log.Printf("Failed to truncate segment after write error: %v", truncErr)
+		}
+		return fmt.Errorf("failed to append to segment: %w", err)
+	}
+	current.size += int64(len(record))
+	return nil
+}
+
+// rollSegment closes the active segment and starts a new one whose name is
+// the key of its first record. Must be called with the lock held.
+func (s *SegmentStorage) rollSegment(firstKey ulid.ULID) error {
+	if s.active != nil {
+		if err := s.active.Sync(); err != nil {
+			return fmt.Errorf("failed to sync segment: %w", err)
+		}
+		if err := s.active.Close(); err != nil {
+			return fmt.Errorf("failed to close segment: %w", err)
+		}
+	}
+
+	path := filepath.Join(s.dir, firstKey.String()+segmentExt)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %w", err)
+	}
+
+	s.active = f
+	s.segments = append(s.segments, &segment{path: path, firstKey: firstKey})
+	return nil
+}
+
+// ReadSpans streams up to batchSize spans written after startKey to sendFunc.
+func (s *SegmentStorage) ReadSpans(startKey []byte, batchSize uint32, sendFunc func(key, spanBytes, resourceBytes []byte) error) error {
+	// Snapshot the segment list so reads only see complete records.
+	s.mu.Lock()
+	segments := make([]segment, len(s.segments))
+	for i, seg := range s.segments {
+		segments[i] = *seg
+	}
+	s.mu.Unlock()
+
+	if len(segments) == 0 || batchSize == 0 {
+		return nil
+	}
+
+	segIndex, offset := s.seek(segments, startKey)
+
+	var count uint32
+	var lastKey []byte
+	cursorKey := startKey
+	for ; segIndex < len(segments) && count < batchSize; segIndex, offset = segIndex+1, 0 {
+		seg := segments[segIndex]
+		if offset >= seg.size {
+			continue
+		}
+
+		f, err := os.Open(seg.path)
+		if err != nil {
+			return fmt.Errorf("failed to open segment: %w", err)
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return err
+		}
+
+		r := bufio.NewReader(io.LimitReader(f, seg.size-offset))
+		for offset < seg.size && count < batchSize {
+			key, payload, n, err := readRecord(r)
+			if errors.Is(err, errCorruptRecord) {
+				if s.quarantine(seg, offset, n) {
+					log.Printf("Skipping corrupt record in segment %s at offset %d", seg.path, offset)
+					CorruptEntries.Inc()
+				}
+				offset += n
+				// Move the cursor past the record so the next poll doesn't read it again.
+				s.setCursor(readCursor{key: cursorKey, segment: segIndex, offset: offset})
+				continue
+			}
+			if err != nil {
+				// The record length can't be trusted, so the rest of the segment is unreadable.
+				if s.quarantine(seg, offset, seg.size-offset) {
+					log.Printf("Error reading segment %s at offset %d: %v. Skipping the rest of the segment.", seg.path, offset, err)
+					CorruptEntries.Inc()
+				}
+				break
+			}
+			offset += n
+
+			if len(startKey) > 0 && bytes.Compare(key[:], startKey) <= 0 {
+				continue
+			}
+
+			var container containerpb.SpanDataContainer
+			if err := proto.Unmarshal(payload, &container); err != nil {
+				if s.quarantine(seg, offset-n, n) {
+					log.Printf("Skipping corrupt WAL entry %x: %v", key[:], err)
+					CorruptEntries.Inc()
+				}
+				count++
+				s.setCursor(readCursor{key: cursorKey, segment: segIndex, offset: offset})
+				continue
+			}
+
+			if err := sendFunc(key[:], container.SpanBytes, container.ResourceBytes); err != nil {
+				f.Close()
+				return err // Propagate error from the send function (e.g., client disconnected)
+			}
+
+			count++
+			lastKey = append([]byte(nil), key[:]...)
+			cursorKey = lastKey
+			s.setCursor(readCursor{key: lastKey, segment: segIndex, offset: offset})
+		}
+		f.Close()
+	}
+
+	return nil
+}
+
+// quarantine copies length bytes of a segment, starting at offset, into the
+// quarantine directory so they can be inspected later. It reports whether the
+// data is seen for the first time, as a record re-read by later polls is only
+// quarantined once.
+func (s *SegmentStorage) quarantine(seg segment, offset, length int64) bool {
+	dir := filepath.Join(s.dir, "quarantine")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Failed to create quarantine directory: %v", err)
+		return true
+	}
+
+	name := fmt.Sprintf("%s-%d.bin", strings.TrimSuffix(filepath.Base(seg.path), segmentExt), offset)
+	dst, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return false // Already quarantined by an earlier read.
+		}
+		log.Printf("Failed to create quarantine file: %v", err)
+		return true
+	}
+	defer dst.Close()
+
+	src, err := os.Open(seg.path)
+	if err != nil {
+		log.Printf("Failed to open segment for quarantine: %v", err)
+		return true
+	}
+	defer src.Close()
+
+	if _, err := io.Copy(dst, io.NewSectionReader(src, offset, length)); err != nil {
+		log.Printf("Failed to quarantine corrupt segment data: %v", err)
+		return true
+	}
+	QuarantinedEntries.Inc()
+	log.Printf("Quarantined corrupt data from %s at offset %d to %s", seg.path, offset, name)
+	return true
+}
+
+// seek finds the segment and byte offset to start reading after startKey.
+func (s *SegmentStorage) seek(segments []segment, startKey []byte) (int, int64) {
+	if len(startKey) == 0 {
+		return 0, 0
+	}
+
+	s.cursorMu.Lock()
+	cursor := s.cursor
+	s.cursorMu.Unlock()
+	if cursor.key != nil && bytes.Equal(cursor.key, startKey) && cursor.segment < len(segments) {
+		return cursor.segment, cursor.offset
+	}
+
+	// Otherwise start from the last segment whose first key is <= startKey.
+	i := sort.Search(len(segments), func(i int) bool {
+		return bytes.Compare(segments[i].firstKey[:], startKey) > 0
+	})
+	return max(i-1, 0), 0
+}
+
+func (s *SegmentStorage) setCursor(cursor readCursor) {
+	s.cursorMu.Lock()
+	s.cursor = cursor
+	s.cursorMu.Unlock()
+}
+
+// encodeRecord frames a payload with its key, length, and checksum.
+func encodeRecord(key ulid.ULID, payload []byte) []byte {
+	record := make([]byte, recordHeaderSize+len(payload))
+	copy(record[0:16], key[:])
+	binary.BigEndian.PutUint32(record[16:20], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[20:24], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+	return record
+}
+
+// readRecord reads the next record, returning its key, payload, and total size.
+// For a record failing its checksum, it returns errCorruptRecord with the size
+// of the record, so that it can be skipped.
+func readRecord(r io.Reader) (ulid.ULID, []byte, int64, error) {
+	var key ulid.ULID
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return key, nil, 0, err
+	}
+	copy(key[:], header[0:16])
+	length := binary.BigEndian.Uint32(header[16:20])
+	checksum := binary.BigEndian.Uint32(header[20:24])
+	if length > defaultMaxSegmentSize {
+		return key, nil, 0, errInvalidLength
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return key, nil, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return key, nil, int64(recordHeaderSize) + int64(length), errCorruptRecord
+	}
+
+	return key, payload, int64(recordHeaderSize) + int64(length), nil
+}
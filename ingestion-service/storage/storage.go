@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"fmt"
+
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// Storage is the Write-Ahead Log that buffers spans between the public OTel
+// endpoint and the backend's indexer.
+//
+// Implementations must key every span with a monotonic ULID so that keys sort
+// in arrival order, and ReadSpans must return spans strictly after startKey.
+type Storage interface {
+	// WriteSpan appends a span and its resource to the WAL.
+	WriteSpan(span *tracepb.Span, resource *resourcepb.Resource) error
+
+	// ReadSpans calls sendFunc for up to batchSize spans that were written after
+	// startKey, in key order. An empty startKey reads from the oldest span.
+	ReadSpans(startKey []byte, batchSize uint32, sendFunc func(key, spanBytes, resourceBytes []byte) error) error
+
+	// Sync flushes all pending writes to disk.
+	Sync() error
+
+	// Close releases the underlying resources.
+	Close() error
+
+	// Stats reports the backend's internals for debugging.
+	Stats() map[string]any
+}
+
+// Supported WAL backends.
+const (
+	BackendBadger  = "badger"
+	BackendSegment = "segment"
+)
+
+// NewStorage opens the WAL backend identified by name at the given path.
+func NewStorage(backend string, path string) (Storage, error) {
+	switch backend {
+	case "", BackendBadger:
+		return NewBadgerStorage(path)
+	case BackendSegment:
+		return NewSegmentStorage(path)
+	default:
+		return nil, fmt.Errorf("unknown WAL backend %q (expected %q or %q)", backend, BackendBadger, BackendSegment)
+	}
+}
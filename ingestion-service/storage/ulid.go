@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// --- Monotonic ULID Generator ---
+
+var (
+	// ulidGenerator is a single, shared monotonic entropy source protected by a mutex.
+	// This ensures that even if multiple goroutines call for a new ID in the same
+	// millisecond, each call will produce a unique and strictly increasing ULID.
+	ulidGenerator = struct {
+		sync.Mutex
+		*ulid.MonotonicEntropy
+	}{
+		// We pass crypto/rand.Reader as the initial entropy source, and 0 for the increment.
+		MonotonicEntropy: ulid.Monotonic(rand.Reader, 0),
+	}
+)
+
+// newULID generates a new, monotonic ULID in a thread-safe manner.
+func newULID() (ulid.ULID, error) {
+	ulidGenerator.Lock()
+	defer ulidGenerator.Unlock()
+
+	// ulid.New requires the time in milliseconds and an entropy source.
+	// We provide the current time and our locked monotonic entropy source.
+	// The MonotonicEntropy will ensure the random part of the ULID is incremented
+	// if we are in the same millisecond as the previous call.
+	return ulid.New(ulid.Timestamp(time.Now()), &ulidGenerator)
+}